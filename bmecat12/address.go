@@ -0,0 +1,202 @@
+package bmecat12
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// country is a country known to NormalizeCountry, Address.Format, and
+// Address.ValidateZip.
+type country struct {
+	code   string         // ISO 3166-1 alpha-2
+	alpha3 string         // ISO 3166-1 alpha-3
+	name   string         // English name, as printed in the country line
+	names  []string       // other names, e.g. in German and the local language
+	zip    *regexp.Regexp // format of the postal code, if any
+	layout addressLayout
+	box    string // term for a post office box, if not "PO Box"
+}
+
+// addressLayout is the order of postal code and city in an address.
+type addressLayout int
+
+const (
+	// layoutZipCity is "12345 City", e.g. in Germany.
+	layoutZipCity addressLayout = iota
+	// layoutCityStateZip is "City, ST 12345", e.g. in the US.
+	layoutCityStateZip
+	// layoutCityZipLines is the city and the postal code on lines of
+	// their own, e.g. in the UK.
+	layoutCityZipLines
+)
+
+var countries = []*country{
+	{code: "DE", alpha3: "DEU", name: "Germany", names: []string{"Deutschland", "Bundesrepublik Deutschland", "Allemagne", "Germania", "BRD", "D"}, zip: regexp.MustCompile(`^\d{5}$`), box: "Postfach"},
+	{code: "AT", alpha3: "AUT", name: "Austria", names: []string{"Österreich", "Oesterreich", "Autriche", "A"}, zip: regexp.MustCompile(`^\d{4}$`), box: "Postfach"},
+	{code: "CH", alpha3: "CHE", name: "Switzerland", names: []string{"Schweiz", "Suisse", "Svizzera", "Confoederatio Helvetica"}, zip: regexp.MustCompile(`^\d{4}$`), box: "Postfach"},
+	{code: "LI", alpha3: "LIE", name: "Liechtenstein", names: []string{"FL"}, zip: regexp.MustCompile(`^94(8[5-9]|9[0-8])$`), box: "Postfach"},
+	{code: "LU", alpha3: "LUX", name: "Luxembourg", names: []string{"Luxemburg", "L"}, zip: regexp.MustCompile(`^(L-)?\d{4}$`)},
+	{code: "BE", alpha3: "BEL", name: "Belgium", names: []string{"Belgien", "Belgique", "België", "B"}, zip: regexp.MustCompile(`^\d{4}$`)},
+	{code: "NL", alpha3: "NLD", name: "Netherlands", names: []string{"Niederlande", "Nederland", "Holland", "Pays-Bas"}, zip: regexp.MustCompile(`^\d{4} ?[A-Z]{2}$`)},
+	{code: "FR", alpha3: "FRA", name: "France", names: []string{"Frankreich", "F"}, zip: regexp.MustCompile(`^\d{5}$`)},
+	{code: "IT", alpha3: "ITA", name: "Italy", names: []string{"Italien", "Italia", "Italie", "I"}, zip: regexp.MustCompile(`^\d{5}$`)},
+	{code: "ES", alpha3: "ESP", name: "Spain", names: []string{"Spanien", "España", "Espagne", "E"}, zip: regexp.MustCompile(`^\d{5}$`)},
+	{code: "PT", alpha3: "PRT", name: "Portugal", names: []string{"P"}, zip: regexp.MustCompile(`^\d{4}-\d{3}$`)},
+	{code: "DK", alpha3: "DNK", name: "Denmark", names: []string{"Dänemark", "Danmark"}, zip: regexp.MustCompile(`^\d{4}$`)},
+	{code: "SE", alpha3: "SWE", name: "Sweden", names: []string{"Schweden", "Sverige", "S"}, zip: regexp.MustCompile(`^\d{3} ?\d{2}$`)},
+	{code: "NO", alpha3: "NOR", name: "Norway", names: []string{"Norwegen", "Norge", "N"}, zip: regexp.MustCompile(`^\d{4}$`)},
+	{code: "FI", alpha3: "FIN", name: "Finland", names: []string{"Finnland", "Suomi"}, zip: regexp.MustCompile(`^\d{5}$`)},
+	{code: "PL", alpha3: "POL", name: "Poland", names: []string{"Polen", "Polska"}, zip: regexp.MustCompile(`^\d{2}-\d{3}$`)},
+	{code: "CZ", alpha3: "CZE", name: "Czech Republic", names: []string{"Czechia", "Tschechien", "Tschechische Republik", "Česko"}, zip: regexp.MustCompile(`^\d{3} ?\d{2}$`)},
+	{code: "SK", alpha3: "SVK", name: "Slovakia", names: []string{"Slowakei", "Slovensko"}, zip: regexp.MustCompile(`^\d{3} ?\d{2}$`)},
+	{code: "HU", alpha3: "HUN", name: "Hungary", names: []string{"Ungarn", "Magyarország", "H"}, zip: regexp.MustCompile(`^\d{4}$`)},
+	{code: "GB", alpha3: "GBR", name: "United Kingdom", names: []string{"Great Britain", "Großbritannien", "Grossbritannien", "Vereinigtes Königreich", "England", "UK"}, zip: regexp.MustCompile(`^[A-Z]{1,2}\d[A-Z\d]? ?\d[A-Z]{2}$`), layout: layoutCityZipLines},
+	{code: "IE", alpha3: "IRL", name: "Ireland", names: []string{"Irland", "Éire"}, zip: regexp.MustCompile(`^[A-Z]\d[\dW] ?[A-Z\d]{4}$`), layout: layoutCityZipLines},
+	{code: "US", alpha3: "USA", name: "United States", names: []string{"United States of America", "Vereinigte Staaten", "Vereinigte Staaten von Amerika", "U.S.A.", "U.S."}, zip: regexp.MustCompile(`^\d{5}(-\d{4})?$`), layout: layoutCityStateZip},
+	{code: "CA", alpha3: "CAN", name: "Canada", names: []string{"Kanada"}, zip: regexp.MustCompile(`^[A-Z]\d[A-Z] ?\d[A-Z]\d$`), layout: layoutCityStateZip},
+	{code: "AU", alpha3: "AUS", name: "Australia", names: []string{"Australien"}, zip: regexp.MustCompile(`^\d{4}$`), layout: layoutCityStateZip},
+	{code: "CN", alpha3: "CHN", name: "China", names: []string{"Volksrepublik China", "People's Republic of China"}, zip: regexp.MustCompile(`^\d{6}$`)},
+	{code: "JP", alpha3: "JPN", name: "Japan", zip: regexp.MustCompile(`^\d{3}-?\d{4}$`)},
+}
+
+// countryByName maps the normalized codes and names of countries.
+var countryByName = func() map[string]*country {
+	m := make(map[string]*country)
+	for _, c := range countries {
+		for _, name := range append([]string{c.code, c.alpha3, c.name}, c.names...) {
+			m[countryKey(name)] = c
+		}
+	}
+	return m
+}()
+
+// countryKey normalizes a country name for lookups: upper case, without
+// diacritics, and without dots and spaces.
+func countryKey(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r), r == '.', unicode.IsSpace(r):
+		default:
+			b.WriteRune(unicode.ToUpper(r))
+		}
+	}
+	return b.String()
+}
+
+func lookupCountry(s string) *country {
+	return countryByName[countryKey(s)]
+}
+
+// NormalizeCountry returns the ISO 3166-1 alpha-2 code of a country given
+// by its code, its ISO 3166-1 alpha-3 code, or its name in English,
+// German, or the local language, e.g. "DE" for "Deutschland", "DEU", or
+// "Germany". The comparison ignores case and diacritics. The second
+// return value is false if the country is unknown.
+func NormalizeCountry(s string) (string, bool) {
+	if c := lookupCountry(s); c != nil {
+		return c.code, true
+	}
+	return "", false
+}
+
+// CountryCode returns the ISO 3166-1 alpha-2 code of the country of the
+// address, or an empty string if it is unknown. See NormalizeCountry.
+func (a *Address) CountryCode() string {
+	code, _ := NormalizeCountry(a.Country)
+	return code
+}
+
+// ValidateZip checks the postal code of the address against the format
+// of its country. It returns an error wrapping ErrInvalidZip if the
+// postal code, or the postal code of the post office box in ZipBox, has
+// an invalid format. Addresses of unknown countries are not checked.
+func (a *Address) ValidateZip() error {
+	c := lookupCountry(a.Country)
+	if c == nil || c.zip == nil {
+		return nil
+	}
+	for _, zip := range []string{a.Zip, a.ZipBox} {
+		if zip == "" {
+			continue
+		}
+		if !c.zip.MatchString(strings.ToUpper(strings.TrimSpace(zip))) {
+			return fmt.Errorf("%w: %q in %s", ErrInvalidZip, zip, c.code)
+		}
+	}
+	return nil
+}
+
+// Format returns the address in the postal layout of its country, one
+// line per row, e.g. with the postal code before the city in Germany and
+// after the state in the US. The country is added as the last line, in
+// upper case, unless it is the country given by from, e.g. "DE" for
+// domestic mail from Germany. Pass an empty from to always add the
+// country. Addresses of unknown countries use the layout of Germany.
+func (a *Address) Format(from string) string {
+	c := lookupCountry(a.Country)
+	layout, box := layoutZipCity, "PO Box"
+	if c != nil {
+		layout = c.layout
+		if c.box != "" {
+			box = c.box
+		}
+	}
+
+	var lines []string
+	add := func(parts ...string) {
+		var line []string
+		for _, p := range parts {
+			if p = strings.TrimSpace(p); p != "" {
+				line = append(line, p)
+			}
+		}
+		if len(line) > 0 {
+			lines = append(lines, strings.Join(line, " "))
+		}
+	}
+	add(a.Name)
+	add(a.Name2)
+	add(a.Name3)
+	add(a.Contact)
+
+	// Prefer the post office box over the street, if any
+	zip := a.Zip
+	if a.BoxNo != "" {
+		add(box, a.BoxNo)
+		if a.ZipBox != "" {
+			zip = a.ZipBox
+		}
+	} else {
+		add(a.Street)
+	}
+
+	switch layout {
+	case layoutCityStateZip:
+		city := strings.TrimSpace(a.City)
+		if city != "" && (a.State != "" || zip != "") {
+			city += ","
+		}
+		add(city, a.State, zip)
+	case layoutCityZipLines:
+		add(strings.ToUpper(a.City))
+		add(a.State)
+		add(strings.ToUpper(zip))
+	default:
+		add(zip, a.City)
+	}
+
+	if country := strings.TrimSpace(a.Country); country != "" {
+		switch {
+		case c == nil:
+			add(strings.ToUpper(country))
+		case c != lookupCountry(from):
+			add(strings.ToUpper(c.name))
+		}
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,172 @@
+package bmecat12_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestNormalizeCountry(t *testing.T) {
+	tests := []struct {
+		Input string
+		Code  string
+		OK    bool
+	}{
+		{"DE", "DE", true},
+		{"de", "DE", true},
+		{"DEU", "DE", true},
+		{"Deutschland", "DE", true},
+		{"Germany", "DE", true},
+		{" germany ", "DE", true},
+		{"Österreich", "AT", true},
+		{"Oesterreich", "AT", true},
+		{"Osterreich", "AT", true},
+		{"Schweiz", "CH", true},
+		{"U.S.A.", "US", true},
+		{"United States of America", "US", true},
+		{"Großbritannien", "GB", true},
+		{"Atlantis", "", false},
+		{"", "", false},
+	}
+	for _, tt := range tests {
+		code, ok := bmecat12.NormalizeCountry(tt.Input)
+		if want, have := tt.OK, ok; want != have {
+			t.Fatalf("%q: want ok=%v, have %v", tt.Input, want, have)
+		}
+		if want, have := tt.Code, code; want != have {
+			t.Fatalf("%q: want %q, have %q", tt.Input, want, have)
+		}
+	}
+
+	a := &bmecat12.Address{Country: "Deutschland"}
+	if want, have := "DE", a.CountryCode(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
+func TestAddressValidateZip(t *testing.T) {
+	tests := []struct {
+		Country string
+		Zip     string
+		ZipBox  string
+		Valid   bool
+	}{
+		{"Deutschland", "80331", "", true},
+		{"DE", "8033", "", false},
+		{"DE", "80331", "8000", false},
+		{"AT", "1010", "", true},
+		{"NL", "1012 AB", "", true},
+		{"NL", "1012ab", "", true},
+		{"NL", "AB 1012", "", false},
+		{"GB", "SW1A 1AA", "", true},
+		{"US", "10001-1234", "", true},
+		{"US", "1000", "", false},
+		{"CA", "K1A 0B1", "", true},
+		{"PL", "00-950", "", true},
+		{"Atlantis", "whatever", "", true},
+		{"DE", "", "", true},
+	}
+	for _, tt := range tests {
+		a := &bmecat12.Address{Country: tt.Country, Zip: tt.Zip, ZipBox: tt.ZipBox}
+		err := a.ValidateZip()
+		if want, have := tt.Valid, err == nil; want != have {
+			t.Fatalf("%s %q/%q: want valid=%v, have %v", tt.Country, tt.Zip, tt.ZipBox, want, err)
+		}
+		if err != nil && !errors.Is(err, bmecat12.ErrInvalidZip) {
+			t.Fatalf("want ErrInvalidZip, have %v", err)
+		}
+	}
+}
+
+func TestAddressFormat(t *testing.T) {
+	tests := []struct {
+		Name    string
+		Address bmecat12.Address
+		From    string
+		Want    string
+	}{
+		{
+			Name: "Domestic",
+			Address: bmecat12.Address{
+				Name:    "Muster GmbH",
+				Contact: "Max Mustermann",
+				Street:  "Hauptstraße 1",
+				Zip:     "80331",
+				City:    "München",
+				Country: "Deutschland",
+				Phone:   "+49 89 123456",
+			},
+			From: "DE",
+			Want: "Muster GmbH\nMax Mustermann\nHauptstraße 1\n80331 München",
+		},
+		{
+			Name: "International",
+			Address: bmecat12.Address{
+				Name:    "Muster GmbH",
+				Street:  "Hauptstraße 1",
+				Zip:     "80331",
+				City:    "München",
+				Country: "DE",
+			},
+			From: "AT",
+			Want: "Muster GmbH\nHauptstraße 1\n80331 München\nGERMANY",
+		},
+		{
+			Name: "PostOfficeBox",
+			Address: bmecat12.Address{
+				Name:    "Muster GmbH",
+				Street:  "Hauptstraße 1",
+				Zip:     "80331",
+				BoxNo:   "12 34",
+				ZipBox:  "80001",
+				City:    "München",
+				Country: "Germany",
+			},
+			From: "DE",
+			Want: "Muster GmbH\nPostfach 12 34\n80001 München",
+		},
+		{
+			Name: "US",
+			Address: bmecat12.Address{
+				Name:    "Acme Inc.",
+				Street:  "1600 Main Street",
+				City:    "Springfield",
+				State:   "IL",
+				Zip:     "62701",
+				Country: "USA",
+			},
+			Want: "Acme Inc.\n1600 Main Street\nSpringfield, IL 62701\nUNITED STATES",
+		},
+		{
+			Name: "UK",
+			Address: bmecat12.Address{
+				Name:    "Acme Ltd",
+				Street:  "10 Downing Street",
+				City:    "London",
+				Zip:     "sw1a 2aa",
+				Country: "United Kingdom",
+			},
+			From: "DE",
+			Want: "Acme Ltd\n10 Downing Street\nLONDON\nSW1A 2AA\nUNITED KINGDOM",
+		},
+		{
+			Name: "UnknownCountry",
+			Address: bmecat12.Address{
+				Name:    "Acme",
+				Zip:     "12345",
+				City:    "Poseidonis",
+				Country: "Atlantis",
+			},
+			From: "DE",
+			Want: "Acme\n12345 Poseidonis\nATLANTIS",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			if want, have := tt.Want, tt.Address.Format(tt.From); want != have {
+				t.Fatalf("want\n%s\nhave\n%s", want, have)
+			}
+		})
+	}
+}
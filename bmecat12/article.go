@@ -2,6 +2,7 @@ package bmecat12
 
 import (
 	"encoding/xml"
+	"fmt"
 	"strings"
 	"time"
 	"unicode/utf8"
@@ -23,6 +24,51 @@ type Article struct {
 
 	// CatalogGroupIDs is the list of CATALOG_STRUCTURE IDs gathered on the 1st pass of the parser.
 	CatalogGroupIDs []string `xml:"-"`
+	// RawXML is the ARTICLE element as read from the file, if the Reader
+	// is created with WithRawArticles. It is converted to UTF-8 and
+	// entities are not expanded.
+	RawXML []byte `xml:"-"`
+
+	// pooled is true if the article is taken from the pool, see WithArticlePool.
+	pooled bool
+	// holds and released defer Release while a MultiHandler dispatches
+	// the article, see hold.
+	holds    int
+	released bool
+	// spares are the nested structs kept by Release for reuse.
+	spares *articleSpares
+}
+
+// Modes of an ARTICLE in T_UPDATE_PRODUCTS.
+const (
+	ArticleModeNew    = "new"
+	ArticleModeUpdate = "update"
+	ArticleModeDelete = "delete"
+)
+
+// DeleteArticle returns an article that deletes the article with the
+// given SUPPLIER_AID in T_UPDATE_PRODUCTS.
+func DeleteArticle(supplierAID string) *Article {
+	return &Article{Mode: ArticleModeDelete, SupplierAID: supplierAID}
+}
+
+// ValidateMode returns an error wrapping ErrInvalidMode if the mode of a
+// is not allowed in tx: T_UPDATE_PRODUCTS requires one of the ArticleMode
+// constants, and the other transactions allow no mode.
+func (a *Article) ValidateMode(tx Transaction) error {
+	if tx == UpdateProducts {
+		switch a.Mode {
+		case ArticleModeNew, ArticleModeUpdate, ArticleModeDelete:
+			return nil
+		case "":
+			return fmt.Errorf("%w: mode is required in %s", ErrInvalidMode, tx)
+		}
+		return fmt.Errorf("%w: %q", ErrInvalidMode, a.Mode)
+	}
+	if a.Mode != "" {
+		return fmt.Errorf("%w: mode %q is only allowed in %s", ErrInvalidMode, a.Mode, UpdateProducts)
+	}
+	return nil
 }
 
 const (
@@ -114,13 +160,16 @@ type FeatureVariant struct {
 	SupplierAIDSupplement string `xml:"SUPPLIER_AID_SUPPLEMENT"`
 }
 
+// ArticleOrderDetails are the ordering details of an article. The
+// numeric elements are pointers, so that a value of 0 is written, while
+// nil omits the element.
 type ArticleOrderDetails struct {
-	OrderUnit        string  `xml:"ORDER_UNIT"`
-	ContentUnit      string  `xml:"CONTENT_UNIT,omitempty"`
-	NoCuPerOu        float64 `xml:"NO_CU_PER_OU,omitempty"`
-	PriceQuantity    float64 `xml:"PRICE_QUANTITY,omitempty"`
-	QuantityMin      float64 `xml:"QUANTITY_MIN,omitempty"`
-	QuantityInterval float64 `xml:"QUANTITY_INTERVAL,omitempty"`
+	OrderUnit        string   `xml:"ORDER_UNIT"`
+	ContentUnit      string   `xml:"CONTENT_UNIT,omitempty"`
+	NoCuPerOu        *float64 `xml:"NO_CU_PER_OU,omitempty"`
+	PriceQuantity    *float64 `xml:"PRICE_QUANTITY,omitempty"`
+	QuantityMin      *float64 `xml:"QUANTITY_MIN,omitempty"`
+	QuantityInterval *float64 `xml:"QUANTITY_INTERVAL,omitempty"`
 }
 
 const (
@@ -181,6 +230,33 @@ func (apd ArticlePriceDetails) IsDailyPrice() bool {
 	return value == "TRUE" || value == "1" || value == "T"
 }
 
+// PriceForQuantity returns the price of the given type for an order of
+// qty units, i.e. the price of the graduated price tier with the highest
+// LOWER_BOUND not above qty. A price without LOWER_BOUND starts at 1, the
+// default of BMEcat. If several prices have the same LOWER_BOUND, e.g.
+// for different territories, the first one is returned. It returns nil
+// if there is no price of that type for qty.
+func (apd *ArticlePriceDetails) PriceForQuantity(qty float64, priceType string) *ArticlePrice {
+	var found *ArticlePrice
+	var foundBound float64
+	for _, p := range apd.Prices {
+		if p == nil || p.Type != priceType {
+			continue
+		}
+		bound := 1.0
+		if p.LowerBound != nil {
+			bound = *p.LowerBound
+		}
+		if bound > qty {
+			continue
+		}
+		if found == nil || bound > foundBound {
+			found, foundBound = p, bound
+		}
+	}
+	return found
+}
+
 const (
 	ArticlePriceTypeNetList        = "net_list"
 	ArticlePriceTypeGrosList       = "gros_list"
@@ -189,16 +265,29 @@ const (
 	ArticlePriceTypeNetCustomerExp = "net_customer_exp"
 )
 
+// ArticlePrice is a price of an article. PRICE_AMOUNT, TAX, and
+// PRICE_FACTOR are Decimals, so they are read and written exactly.
+// PRICE_AMOUNT is always written; the optional numeric elements are
+// pointers, so that e.g. a TAX of 0 is written, while nil omits the
+// element.
 type ArticlePrice struct {
 	Type       string   `xml:"price_type,attr,omitempty"`
-	Amount     float64  `xml:"PRICE_AMOUNT"`
+	Amount     Decimal  `xml:"PRICE_AMOUNT"`
 	Currency   string   `xml:"PRICE_CURRENCY,omitempty"`
-	Tax        float64  `xml:"TAX,omitempty"`
-	Factor     float64  `xml:"PRICE_FACTOR,omitempty"`
-	LowerBound float64  `xml:"LOWER_BOUND,omitempty"`
+	Tax        *Decimal `xml:"TAX,omitempty"`
+	Factor     *Decimal `xml:"PRICE_FACTOR,omitempty"`
+	LowerBound *float64 `xml:"LOWER_BOUND,omitempty"`
 	Territory  []string `xml:"TERRITORY,omitempty"`
 }
 
+// floatValue returns the value of v, or 0 if v is nil.
+func floatValue(v *float64) float64 {
+	if v == nil {
+		return 0
+	}
+	return *v
+}
+
 const (
 	ArticleReferenceTypeSparepart     = "sparepart"
 	ArticleReferenceTypeSimilar       = "similar"
@@ -0,0 +1,238 @@
+package bmecat12
+
+import (
+	"bufio"
+	"crypto/cipher"
+	"encoding/gob"
+	"io"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+)
+
+// ArticleBuffer collects articles for tools that need more than one
+// pass over them, e.g. for sorting or deduplication. Articles are kept
+// in memory up to a budget; the articles beyond it are spilled to a
+// temporary file. Use Articles to iterate over all buffered articles
+// in the order they were added, and Close to remove the temporary file.
+//
+// ArticleBuffer implements ArticleHandler, so it can be passed to
+// Reader.Do, possibly together with other handlers via MultiHandler.
+type ArticleBuffer struct {
+	budget int64
+	dir    string
+
+	mem     []*Article
+	memSize int64
+	sizer   *gob.Encoder
+	counter countingWriter
+
+	spill    *os.File
+	spillBuf flushWriter
+	spillEnc *gob.Encoder
+	spilled  int
+
+	// encrypt enables WithSpillEncryption; aead encrypts the spill file.
+	encrypt bool
+	aead    cipher.AEAD
+}
+
+// flushWriter is a buffered writer.
+type flushWriter interface {
+	io.Writer
+	Flush() error
+}
+
+// ArticleBufferOption is the signature of options to pass into
+// NewArticleBuffer.
+type ArticleBufferOption func(*ArticleBuffer)
+
+// WithSpillDir sets the directory for the temporary file. By default,
+// the directory returned by os.TempDir is used.
+func WithSpillDir(dir string) ArticleBufferOption {
+	return func(b *ArticleBuffer) {
+		b.dir = dir
+	}
+}
+
+// NewArticleBuffer creates a new ArticleBuffer that keeps up to budget
+// bytes of articles in memory. The size of an article is estimated from
+// its encoded size, so the actual memory usage is somewhat higher.
+// A budget of 0 spills all articles.
+func NewArticleBuffer(budget int64, options ...ArticleBufferOption) *ArticleBuffer {
+	b := &ArticleBuffer{budget: budget}
+	b.sizer = gob.NewEncoder(&b.counter)
+	for _, o := range options {
+		o(b)
+	}
+	return b
+}
+
+// countingWriter counts and discards the bytes written.
+type countingWriter struct {
+	n int64
+}
+
+func (w *countingWriter) Write(p []byte) (int, error) {
+	w.n += int64(len(p))
+	return len(p), nil
+}
+
+// Add adds an article to the buffer.
+func (b *ArticleBuffer) Add(a *Article) error {
+	if b.spill == nil {
+		before := b.counter.n
+		if err := b.sizer.Encode(a); err != nil {
+			return errors.Wrapf(err, "bmecat: unable to encode ARTICLE %q", a.SupplierAID)
+		}
+		if size := b.counter.n - before; b.memSize+size <= b.budget {
+			b.mem = append(b.mem, a)
+			b.memSize += size
+			return nil
+		}
+		f, err := ioutil.TempFile(b.dir, "bmecat-articles-*.gob")
+		if err != nil {
+			return errors.Wrap(err, "bmecat: unable to create temporary file for articles")
+		}
+		b.spill = f
+		if b.encrypt {
+			aead, err := newSpillCipher()
+			if err != nil {
+				return err
+			}
+			b.aead = aead
+			// Chunks are large enough to write them unbuffered
+			b.spillBuf = newSealWriter(f, aead)
+		} else {
+			b.spillBuf = bufio.NewWriter(f)
+		}
+		b.spillEnc = gob.NewEncoder(b.spillBuf)
+	}
+	if err := b.spillEnc.Encode(a); err != nil {
+		return errors.Wrapf(err, "bmecat: unable to spill ARTICLE %q", a.SupplierAID)
+	}
+	b.spilled++
+	return nil
+}
+
+// HandleArticle implements the ArticleHandler interface.
+func (b *ArticleBuffer) HandleArticle(a *Article) error {
+	return b.Add(a)
+}
+
+// Len returns the number of buffered articles.
+func (b *ArticleBuffer) Len() int {
+	return len(b.mem) + b.spilled
+}
+
+// Spilled returns the number of articles spilled to the temporary file.
+func (b *ArticleBuffer) Spilled() int {
+	return b.spilled
+}
+
+// Articles returns an iterator over the buffered articles. Articles may
+// be added after iterating, and Articles may be called more than once.
+// The iterator must be closed after use.
+func (b *ArticleBuffer) Articles() (*ArticleIterator, error) {
+	it := &ArticleIterator{mem: b.mem, spilled: b.spilled}
+	if b.spill != nil {
+		if err := b.spillBuf.Flush(); err != nil {
+			return nil, errors.Wrap(err, "bmecat: unable to flush spilled articles")
+		}
+		f, err := os.Open(b.spill.Name())
+		if err != nil {
+			return nil, errors.Wrap(err, "bmecat: unable to open spilled articles")
+		}
+		it.f = f
+		var r io.Reader = bufio.NewReader(f)
+		if b.aead != nil {
+			r = bufio.NewReader(newOpenReader(r, b.aead))
+		}
+		it.dec = gob.NewDecoder(r)
+	}
+	return it, nil
+}
+
+// Close removes the temporary file, if any. The buffer must not be used
+// after Close.
+func (b *ArticleBuffer) Close() error {
+	b.mem = nil
+	if b.spill == nil {
+		return nil
+	}
+	name := b.spill.Name()
+	err := b.spill.Close()
+	if rerr := os.Remove(name); err == nil {
+		err = rerr
+	}
+	b.spill = nil
+	return err
+}
+
+// ArticleIterator iterates over the articles of an ArticleBuffer.
+//
+//	it, err := buf.Articles()
+//	if err != nil { ... }
+//	defer it.Close()
+//	for it.Next() {
+//		a := it.Article()
+//		...
+//	}
+//	if err := it.Err(); err != nil { ... }
+type ArticleIterator struct {
+	mem     []*Article
+	spilled int
+	f       *os.File
+	dec     *gob.Decoder
+	article *Article
+	err     error
+}
+
+// Next advances to the next article. It returns false when there are
+// no more articles or an error occurred.
+func (it *ArticleIterator) Next() bool {
+	if it.err != nil {
+		return false
+	}
+	if len(it.mem) > 0 {
+		it.article, it.mem = it.mem[0], it.mem[1:]
+		return true
+	}
+	if it.dec == nil || it.spilled == 0 {
+		it.article = nil
+		return false
+	}
+	var a Article
+	if err := it.dec.Decode(&a); err != nil {
+		if err == io.EOF {
+			err = io.ErrUnexpectedEOF
+		}
+		it.err = errors.Wrap(err, "bmecat: unable to read spilled article")
+		it.article = nil
+		return false
+	}
+	it.spilled--
+	it.article = &a
+	return true
+}
+
+// Article returns the current article.
+func (it *ArticleIterator) Article() *Article {
+	return it.article
+}
+
+// Err returns the first error that occurred while iterating, if any.
+func (it *ArticleIterator) Err() error {
+	return it.err
+}
+
+// Close releases the resources of the iterator.
+func (it *ArticleIterator) Close() error {
+	if it.f == nil {
+		return nil
+	}
+	err := it.f.Close()
+	it.f = nil
+	return err
+}
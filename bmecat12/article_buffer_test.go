@@ -0,0 +1,152 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestArticleBuffer(t *testing.T) {
+	tests := []struct {
+		Budget  int64
+		Spilled int
+	}{
+		{Budget: 0, Spilled: 2},
+		{Budget: 1 << 20, Spilled: 0},
+	}
+	for _, tt := range tests {
+		dir, err := ioutil.TempDir("", "bmecat-test")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer os.RemoveAll(dir)
+
+		f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		h := &testHandler{}
+		buf := bmecat12.NewArticleBuffer(tt.Budget, bmecat12.WithSpillDir(dir))
+		if err := bmecat12.NewReader(f).Do(context.Background(), bmecat12.MultiHandler(h, buf)); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 2, buf.Len(); want != have {
+			t.Fatalf("Budget=%d: want Len=%d, have %d", tt.Budget, want, have)
+		}
+		if want, have := tt.Spilled, buf.Spilled(); want != have {
+			t.Fatalf("Budget=%d: want Spilled=%d, have %d", tt.Budget, want, have)
+		}
+
+		// Iterate twice to make sure the buffer can be reused
+		for pass := 0; pass < 2; pass++ {
+			it, err := buf.Articles()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var i int
+			for it.Next() {
+				if i >= len(h.articles) {
+					t.Fatalf("Budget=%d: too many articles", tt.Budget)
+				}
+				want, err := xml.Marshal(h.articles[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				have, err := xml.Marshal(it.Article())
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(want) != string(have) {
+					diffStrings(t, string(want), string(have))
+					t.Fatalf("Budget=%d: article %d differs", tt.Budget, i)
+				}
+				i++
+			}
+			if err := it.Err(); err != nil {
+				t.Fatal(err)
+			}
+			if err := it.Close(); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := 2, i; want != have {
+				t.Fatalf("Budget=%d: want %d articles, have %d", tt.Budget, want, have)
+			}
+		}
+
+		if err := buf.Close(); err != nil {
+			t.Fatal(err)
+		}
+		files, err := ioutil.ReadDir(dir)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 0, len(files); want != have {
+			t.Fatalf("Budget=%d: want %d files after Close, have %d", tt.Budget, want, have)
+		}
+	}
+}
+
+func TestArticleBufferWithSpillEncryption(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	buf := bmecat12.NewArticleBuffer(0, bmecat12.WithSpillDir(dir), bmecat12.WithSpillEncryption())
+	defer buf.Close()
+	// Enough articles to span several chunks
+	const n = 5000
+	for i := 0; i < n; i++ {
+		a := &bmecat12.Article{
+			SupplierAID: fmt.Sprintf("%05d", i),
+			Details:     &bmecat12.ArticleDetails{DescriptionShort: fmt.Sprintf("Secret price %d", i)},
+		}
+		if err := buf.Add(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	it, err := buf.Articles()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer it.Close()
+	var i int
+	for it.Next() {
+		if want, have := fmt.Sprintf("%05d", i), it.Article().SupplierAID; want != have {
+			t.Fatalf("want SupplierAID=%q, have %q", want, have)
+		}
+		i++
+	}
+	if err := it.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := n, i; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(files); want != have {
+		t.Fatalf("want %d temporary file, have %d", want, have)
+	}
+	data, err := ioutil.ReadFile(filepath.Join(dir, files[0].Name()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Contains(data, []byte("Secret price")) {
+		t.Fatal("want spilled articles to be encrypted")
+	}
+}
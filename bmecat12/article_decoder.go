@@ -0,0 +1,429 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"strconv"
+	"strings"
+)
+
+// WithFastDecoder makes the Reader decode ARTICLE elements with a
+// hand-written decoder instead of the reflection of encoding/xml. The
+// result is the same as with xml.Decoder.DecodeElement, except that a
+// repeated element that is not a list, e.g. a second ARTICLE_DETAILS,
+// replaces the first one instead of being merged into it. The gain
+// depends on the Go version and the articles, as most of the time is
+// spent tokenizing the XML; use "bmecat perf -fast" to measure it.
+func WithFastDecoder() ReaderOption {
+	return func(r *Reader) {
+		r.fastDecoder = true
+	}
+}
+
+// articleDecoder decodes ARTICLE elements token by token.
+type articleDecoder struct {
+	dec  *xml.Decoder
+	skip SkipSection
+}
+
+// article decodes the ARTICLE element started by se into a.
+func (d *articleDecoder) article(se *xml.StartElement, a *Article) error {
+	a.XMLName = se.Name
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "mode" {
+			a.Mode = attr.Value
+		}
+	}
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "SUPPLIER_AID":
+			return d.text(&a.SupplierAID)
+		case "ARTICLE_DETAILS":
+			a.Details = a.reuseDetails()
+			return d.details(a.Details)
+		case "ARTICLE_FEATURES":
+			if d.skip&SkipFeatures != 0 {
+				break
+			}
+			return d.features(a.nextFeatures())
+		case "ARTICLE_ORDER_DETAILS":
+			a.OrderDetails = a.reuseOrderDetails()
+			return d.orderDetails(a.OrderDetails)
+		case "ARTICLE_PRICE_DETAILS":
+			return d.priceDetails(a.nextPriceDetails())
+		case "MIME_INFO":
+			if d.skip&SkipMime != 0 {
+				break
+			}
+			a.MimeInfo = a.reuseMimeInfo()
+			a.MimeInfo.XMLName = se.Name
+			return d.mimeInfo(a.MimeInfo)
+		case "USER_DEFINED_EXTENSIONS":
+			if d.skip&SkipUDX != 0 {
+				break
+			}
+			a.UDX = a.reuseUDX()
+			return d.dec.DecodeElement(a.UDX, se)
+		case "ARTICLE_REFERENCE":
+			if d.skip&SkipReferences != 0 {
+				break
+			}
+			return d.reference(se, a.nextReference())
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) details(v *ArticleDetails) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "DESCRIPTION_SHORT":
+			return d.text(&v.DescriptionShort)
+		case "DESCRIPTION_LONG":
+			return d.text(&v.DescriptionLong)
+		case "EAN":
+			return d.text(&v.EAN)
+		case "SUPPLIER_ALT_AID":
+			return d.text(&v.SupplierAltAID)
+		case "BUYER_AID":
+			id := &BuyerAID{Type: attrValue(se, "type")}
+			v.BuyerAIDs = append(v.BuyerAIDs, id)
+			return d.text(&id.Value)
+		case "MANUFACTURER_AID":
+			return d.text(&v.ManufacturerAID)
+		case "MANUFACTURER_NAME":
+			return d.text(&v.ManufacturerName)
+		case "MANUFACTURER_TYPE_DESCR":
+			return d.text(&v.ManufacturerTypeDescr)
+		case "ERP_GROUP_BUYER":
+			return d.text(&v.ERPGroupBuyer)
+		case "ERP_GROUP_SUPPLIER":
+			return d.text(&v.ERPGroupSupplier)
+		case "DELIVERY_TIME":
+			var f float64
+			if err := d.float(&f, 32); err != nil {
+				return err
+			}
+			v.DeliveryTime = float32(f)
+			return nil
+		case "SPECIAL_TREATMENT_CLASS":
+			c := &ArticleSpecialTreatmentClass{Type: attrValue(se, "type")}
+			v.SpecialTreatmentClasses = append(v.SpecialTreatmentClasses, c)
+			return d.text(&c.Value)
+		case "KEYWORD":
+			v.Keywords = append(v.Keywords, "")
+			return d.text(&v.Keywords[len(v.Keywords)-1])
+		case "REMARKS":
+			return d.text(&v.Remarks)
+		case "SEGMENT":
+			v.Segments = append(v.Segments, "")
+			return d.text(&v.Segments[len(v.Segments)-1])
+		case "ARTICLE_ORDER":
+			return d.int(&v.ArticleOrder)
+		case "ARTICLE_STATUS":
+			s := &ArticleStatus{Type: attrValue(se, "type")}
+			v.ArticleStatus = append(v.ArticleStatus, s)
+			return d.text(&s.Value)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) features(v *ArticleFeatures) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "REFERENCE_FEATURE_SYSTEM_NAME":
+			return d.text(&v.FeatureSystemName)
+		case "REFERENCE_FEATURE_GROUP_ID":
+			return d.text(&v.FeatureGroupID)
+		case "REFERENCE_FEATURE_GROUP_NAME":
+			return d.text(&v.FeatureGroupName)
+		case "FEATURE":
+			f := &Feature{}
+			v.Features = append(v.Features, f)
+			return d.feature(f)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) feature(v *Feature) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "FNAME":
+			return d.text(&v.Name)
+		case "VARIANTS":
+			vs := &FeatureVariants{}
+			v.Variants = append(v.Variants, vs)
+			return d.variants(vs)
+		case "FVALUE":
+			v.Values = append(v.Values, "")
+			return d.text(&v.Values[len(v.Values)-1])
+		case "FUNIT":
+			return d.text(&v.Unit)
+		case "FORDER":
+			return d.int(&v.Order)
+		case "FDESCR":
+			return d.text(&v.Descr)
+		case "FVALUE_DETAILS":
+			return d.text(&v.ValueDetails)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) variants(v *FeatureVariants) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "VARIANT":
+			fv := &FeatureVariant{}
+			v.Variants = append(v.Variants, fv)
+			return d.children(func(se *xml.StartElement) error {
+				switch se.Name.Local {
+				case "FVALUE":
+					return d.text(&fv.Value)
+				case "SUPPLIER_AID_SUPPLEMENT":
+					return d.text(&fv.SupplierAIDSupplement)
+				}
+				return d.dec.Skip()
+			}, nil)
+		case "VORDER":
+			return d.int(&v.Order)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) orderDetails(v *ArticleOrderDetails) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "ORDER_UNIT":
+			return d.text(&v.OrderUnit)
+		case "CONTENT_UNIT":
+			return d.text(&v.ContentUnit)
+		case "NO_CU_PER_OU":
+			return d.optionalFloat(&v.NoCuPerOu)
+		case "PRICE_QUANTITY":
+			return d.optionalFloat(&v.PriceQuantity)
+		case "QUANTITY_MIN":
+			return d.optionalFloat(&v.QuantityMin)
+		case "QUANTITY_INTERVAL":
+			return d.optionalFloat(&v.QuantityInterval)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) priceDetails(v *ArticlePriceDetails) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "DATETIME":
+			dt := &DateTime{Type: attrValue(se, "type")}
+			v.Dates = append(v.Dates, dt)
+			return d.children(func(se *xml.StartElement) error {
+				switch se.Name.Local {
+				case "DATE":
+					return d.text(&dt.DateString)
+				case "TIME":
+					return d.text(&dt.TimeString)
+				case "TIMEZONE":
+					return d.text(&dt.TimeZoneString)
+				}
+				return d.dec.Skip()
+			}, nil)
+		case "DAILY_PRICE":
+			return d.text(&v.DailyPriceString)
+		case "ARTICLE_PRICE":
+			p := &ArticlePrice{Type: attrValue(se, "price_type")}
+			v.Prices = append(v.Prices, p)
+			return d.price(p)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) price(v *ArticlePrice) error {
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "PRICE_AMOUNT":
+			return d.decimal(&v.Amount)
+		case "PRICE_CURRENCY":
+			return d.text(&v.Currency)
+		case "TAX":
+			return d.optionalDecimal(&v.Tax)
+		case "PRICE_FACTOR":
+			return d.optionalDecimal(&v.Factor)
+		case "LOWER_BOUND":
+			return d.optionalFloat(&v.LowerBound)
+		case "TERRITORY":
+			v.Territory = append(v.Territory, "")
+			return d.text(&v.Territory[len(v.Territory)-1])
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+func (d *articleDecoder) mimeInfo(v *MimeInfo) error {
+	return d.children(func(se *xml.StartElement) error {
+		if se.Name.Local != "MIME" {
+			return d.dec.Skip()
+		}
+		m := &Mime{XMLName: se.Name}
+		v.Mimes = append(v.Mimes, m)
+		return d.children(func(se *xml.StartElement) error {
+			switch se.Name.Local {
+			case "MIME_TYPE":
+				return d.text(&m.Type)
+			case "MIME_SOURCE":
+				return d.text(&m.Source)
+			case "MIME_DESCR":
+				return d.text(&m.Descr)
+			case "MIME_ALT":
+				return d.text(&m.Alt)
+			case "MIME_PURPOSE":
+				return d.text(&m.Purpose)
+			case "MIME_ORDER":
+				return d.int(&m.Order)
+			}
+			return d.dec.Skip()
+		}, nil)
+	}, nil)
+}
+
+func (d *articleDecoder) reference(se *xml.StartElement, v *ArticleReference) error {
+	v.Type = attrValue(se, "type")
+	if s := attrValue(se, "quantity"); s != "" {
+		if err := parseFloat(s, &v.Quantity, 64); err != nil {
+			return err
+		}
+	}
+	return d.children(func(se *xml.StartElement) error {
+		switch se.Name.Local {
+		case "ART_ID_TO":
+			return d.text(&v.ArtIDTo)
+		case "CATALOG_ID":
+			return d.text(&v.CatalogID)
+		case "CATALOG_VERSION":
+			return d.text(&v.CatalogVersion)
+		}
+		return d.dec.Skip()
+	}, nil)
+}
+
+// children reads the content of the current element up to its end,
+// calling child for each child element, which must consume it, and
+// appending the character data to text unless it is nil.
+func (d *articleDecoder) children(child func(*xml.StartElement) error, text *[]byte) error {
+	for {
+		t, err := d.dec.Token()
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			if err := child(&t); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			return nil
+		case xml.CharData:
+			if text != nil {
+				*text = append(*text, t...)
+			}
+		}
+	}
+}
+
+// text sets s to the character data of the current element. Child
+// elements are skipped, as by xml.Decoder.
+func (d *articleDecoder) text(s *string) error {
+	var data []byte
+	if err := d.children(func(*xml.StartElement) error { return d.dec.Skip() }, &data); err != nil {
+		return err
+	}
+	*s = string(data)
+	return nil
+}
+
+// int sets v to the integer in the current element.
+func (d *articleDecoder) int(v *int) error {
+	var s string
+	if err := d.text(&s); err != nil {
+		return err
+	}
+	if s == "" {
+		*v = 0
+		return nil
+	}
+	i, err := strconv.ParseInt(strings.TrimSpace(s), 10, strconv.IntSize)
+	if err != nil {
+		return err
+	}
+	*v = int(i)
+	return nil
+}
+
+// float sets v to the number in the current element.
+func (d *articleDecoder) float(v *float64, bitSize int) error {
+	var s string
+	if err := d.text(&s); err != nil {
+		return err
+	}
+	return parseFloat(s, v, bitSize)
+}
+
+// optionalFloat reads the text of the element as a number into a new
+// float64, and sets v to it.
+func (d *articleDecoder) optionalFloat(v **float64) error {
+	f := new(float64)
+	if err := d.float(f, 64); err != nil {
+		return err
+	}
+	*v = f
+	return nil
+}
+
+// decimal sets v to the Decimal in the current element.
+func (d *articleDecoder) decimal(v *Decimal) error {
+	var s string
+	if err := d.text(&s); err != nil {
+		return err
+	}
+	return v.UnmarshalText([]byte(s))
+}
+
+// optionalDecimal reads the text of the element as a Decimal into a new
+// Decimal, and sets v to it.
+func (d *articleDecoder) optionalDecimal(v **Decimal) error {
+	dec := new(Decimal)
+	if err := d.decimal(dec); err != nil {
+		return err
+	}
+	*v = dec
+	return nil
+}
+
+// parseFloat sets v to the number in s, as xml.Decoder does.
+func parseFloat(s string, v *float64, bitSize int) error {
+	if s == "" {
+		*v = 0
+		return nil
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), bitSize)
+	if err != nil {
+		return err
+	}
+	*v = f
+	return nil
+}
+
+// attrValue returns the value of the last attribute of se with the given
+// local name.
+func attrValue(se *xml.StartElement, name string) string {
+	var value string
+	for _, attr := range se.Attr {
+		if attr.Name.Local == name {
+			value = attr.Value
+		}
+	}
+	return value
+}
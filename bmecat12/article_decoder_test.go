@@ -0,0 +1,165 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const fastDecoderDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2" xmlns="http://www.bmecat.org/XMLSchema/1.2/bmecat_new_catalog">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE mode="new" xmlns:acme="http://example.com/acme">
+      <SUPPLIER_AID> 1000 </SUPPLIER_AID>
+      <ARTICLE_DETAILS>
+        <DESCRIPTION_SHORT>Kurz<!-- comment --> &amp; <![CDATA[<b>bündig</b>]]></DESCRIPTION_SHORT>
+        <DESCRIPTION_LONG>Text <acme:B>nested</acme:B>more</DESCRIPTION_LONG>
+        <BUYER_AID type="KMF">1</BUYER_AID>
+        <BUYER_AID>2</BUYER_AID>
+        <DELIVERY_TIME> 1.1 </DELIVERY_TIME>
+        <KEYWORD>a</KEYWORD><KEYWORD></KEYWORD><KEYWORD>b</KEYWORD>
+        <ARTICLE_ORDER>
+          7
+        </ARTICLE_ORDER>
+        <ARTICLE_STATUS type="new">Neu</ARTICLE_STATUS>
+        <acme:UNKNOWN><X>1</X></acme:UNKNOWN>
+      </ARTICLE_DETAILS>
+      <ARTICLE_FEATURES>
+        <REFERENCE_FEATURE_SYSTEM_NAME>ECLASS-5.1</REFERENCE_FEATURE_SYSTEM_NAME>
+        <FEATURE>
+          <FNAME>Farbe</FNAME>
+          <VARIANTS><VARIANT><FVALUE>rot</FVALUE><SUPPLIER_AID_SUPPLEMENT>-R</SUPPLIER_AID_SUPPLEMENT></VARIANT><VORDER>2</VORDER></VARIANTS>
+          <FVALUE>x</FVALUE><FVALUE>y</FVALUE>
+          <FORDER>1</FORDER>
+        </FEATURE>
+      </ARTICLE_FEATURES>
+      <ARTICLE_FEATURES/>
+      <ARTICLE_ORDER_DETAILS><ORDER_UNIT>C62</ORDER_UNIT><NO_CU_PER_OU></NO_CU_PER_OU><QUANTITY_MIN>1e3</QUANTITY_MIN></ARTICLE_ORDER_DETAILS>
+      <ARTICLE_PRICE_DETAILS>
+        <DATETIME type="valid_start_date"><DATE>2001-01-01</DATE><TIME>10:00:00</TIME></DATETIME>
+        <DAILY_PRICE>TRUE</DAILY_PRICE>
+        <ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>1499.50</PRICE_AMOUNT><PRICE_CURRENCY>EUR</PRICE_CURRENCY><TAX>0.19</TAX><TERRITORY>DE</TERRITORY><TERRITORY>AT</TERRITORY></ARTICLE_PRICE>
+        <ARTICLE_PRICE><PRICE_AMOUNT>1</PRICE_AMOUNT><LOWER_BOUND>10</LOWER_BOUND></ARTICLE_PRICE>
+      </ARTICLE_PRICE_DETAILS>
+      <MIME_INFO><MIME><MIME_TYPE>image/jpeg</MIME_TYPE><MIME_SOURCE>a.jpg</MIME_SOURCE><MIME_ORDER>1</MIME_ORDER></MIME></MIME_INFO>
+      <USER_DEFINED_EXTENSIONS><UDX.COLOR>rot</UDX.COLOR><UDX.NESTED><A>1</A></UDX.NESTED></USER_DEFINED_EXTENSIONS>
+      <ARTICLE_REFERENCE type="consists_of" quantity=" 2.5 "><ART_ID_TO>2000</ART_ID_TO><CATALOG_ID>CAT</CATALOG_ID></ARTICLE_REFERENCE>
+    </ARTICLE>
+    <ARTICLE><SUPPLIER_AID>2000</SUPPLIER_AID></ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+func TestReadWithFastDecoder(t *testing.T) {
+	docs := map[string][]byte{"handcrafted": []byte(fastDecoderDoc)}
+	for _, name := range []string{"new_catalog", "update_products", "update_prices"} {
+		data, err := ioutil.ReadFile(filepath.Join("testdata", name+".golden.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		docs[name] = data
+	}
+	var buf bytes.Buffer
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: directTestArticles(),
+	}
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithSkipModeValidation()).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	docs["written"] = buf.Bytes()
+
+	read := func(data []byte, options ...bmecat12.ReaderOption) []*bmecat12.Article {
+		h := &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(data), options...).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		return h.articles
+	}
+	for name, data := range docs {
+		for _, skip := range []bmecat12.SkipSection{0, bmecat12.SkipFeatures | bmecat12.SkipUDX} {
+			want := read(data, bmecat12.WithSkipSections(skip))
+			have := read(data, bmecat12.WithSkipSections(skip), bmecat12.WithFastDecoder())
+			if len(want) == 0 {
+				t.Fatalf("%s: want articles", name)
+			}
+			if !reflect.DeepEqual(want, have) {
+				t.Fatalf("%s, skip %d: want the same articles with WithFastDecoder", name, skip)
+			}
+			// The raw XML decoded on the workers has no namespace context
+			want = read(data, bmecat12.WithSkipSections(skip), bmecat12.WithConcurrency(2))
+			have = read(data, bmecat12.WithSkipSections(skip), bmecat12.WithFastDecoder(), bmecat12.WithConcurrency(2))
+			if !reflect.DeepEqual(want, have) {
+				t.Fatalf("%s, skip %d: want the same articles with WithFastDecoder and WithConcurrency", name, skip)
+			}
+		}
+	}
+}
+
+func TestReadWithFastDecoderInvalidNumber(t *testing.T) {
+	doc := strings.Replace(fastDecoderDoc, "<PRICE_AMOUNT>1499.50</PRICE_AMOUNT>", "<PRICE_AMOUNT>1.499,50</PRICE_AMOUNT>", 1)
+	read := func(options ...bmecat12.ReaderOption) error {
+		return bmecat12.NewReader(strings.NewReader(doc), options...).Do(context.Background(), &testHandler{})
+	}
+	want, have := read(), read(bmecat12.WithFastDecoder())
+	if want == nil || have == nil {
+		t.Fatalf("want errors, have %v and %v", want, have)
+	}
+	if want.Error() != have.Error() {
+		t.Fatalf("want error %q, have %q", want, have)
+	}
+}
+
+func BenchmarkReaderWithFastDecoder(b *testing.B) {
+	article := directTestArticles()[1]
+	var articles []*bmecat12.Article
+	for i := 0; i < 1000; i++ {
+		a := *article
+		a.SupplierAID = fmt.Sprint(i)
+		articles = append(articles, &a)
+	}
+	var buf bytes.Buffer
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: articles,
+	}
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		b.Fatal(err)
+	}
+	buffer := bytes.NewReader(buf.Bytes())
+	h := bmecat12.HandlerFuncs{}
+
+	for _, fast := range []bool{false, true} {
+		name := "DecodeElement"
+		var options []bmecat12.ReaderOption
+		if fast {
+			name = "fast"
+			options = append(options, bmecat12.WithFastDecoder())
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+					b.Fatal(err)
+				}
+				if err := bmecat12.NewReader(buffer, options...).Do(context.Background(), h); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,512 @@
+package bmecat12
+
+import (
+	"strconv"
+	"unicode/utf8"
+)
+
+// directFlushSize is the size of the articles encoded directly that are
+// buffered before they are written to the output.
+const directFlushSize = 64 << 10
+
+// WithDirectEncoding specifies whether the Writer encodes articles with
+// a hand-written encoder instead of the reflection of encoding/xml,
+// which dominates the time to write large catalogs. The output is the
+// same. It is enabled by default; articles are encoded with
+// encoding/xml anyway if WithExtension or WithCDATA apply to them.
+func WithDirectEncoding(enabled bool) WriterOption {
+	return func(w *Writer) {
+		w.noDirect = !enabled
+	}
+}
+
+// canEncodeDirect returns true if a can be encoded with writeArticleDirect.
+func (w *Writer) canEncodeDirect(a *Article) bool {
+	if w.noDirect || w.txStarted || len(w.extensions[ExtensionArticle]) > 0 || len(w.cdata) > 0 {
+		return false
+	}
+	return directArticle(a)
+}
+
+// directArticle returns true if the direct encoding of a is the same as
+// with encoding/xml.
+func directArticle(a *Article) bool {
+	// encoding/xml adds xmlns attributes for elements with a namespace
+	if a.XMLName.Space != "" {
+		return false
+	}
+	// encoding/xml reports invalid dates, see DateTime.MarshalXML
+	for _, pd := range a.PriceDetails {
+		if pd == nil {
+			continue
+		}
+		for _, dt := range pd.Dates {
+			if dt != nil && dt.Validate() != nil {
+				return false
+			}
+		}
+	}
+	if a.MimeInfo != nil {
+		if a.MimeInfo.XMLName.Space != "" {
+			return false
+		}
+		for _, m := range a.MimeInfo.Mimes {
+			if m != nil && m.XMLName.Space != "" {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// writeArticleDirect encodes a as the xml.Encoder would, and buffers it
+// until flushRaw is called or the buffer is full. The xml.Encoder must
+// not be used before flushRaw, see flush.
+func (w *Writer) writeArticleDirect(a *Article) error {
+	if len(w.raw) == 0 {
+		if err := w.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	w.raw = appendArticle(w.raw, a, w.indent, w.numbers)
+	if len(w.raw) >= directFlushSize {
+		return w.flushRaw()
+	}
+	return nil
+}
+
+// writeRaw writes an encoded article like writeArticleDirect.
+func (w *Writer) writeRaw(data []byte) error {
+	if len(w.raw) == 0 {
+		if err := w.enc.Flush(); err != nil {
+			return err
+		}
+	}
+	w.raw = append(w.raw, data...)
+	if len(w.raw) >= directFlushSize {
+		return w.flushRaw()
+	}
+	return nil
+}
+
+// appendArticle appends the XML of a to b, indented as a child of the
+// transaction element, with the numbers formatted as in numbers.
+func appendArticle(b []byte, a *Article, indent string, numbers map[string]numberFormat) []byte {
+	// The ARTICLE elements are children of BMECAT and the transaction
+	e := directEncoder{b: b, indent: indent, depth: 2, numbers: numbers}
+	e.article(a)
+	return e.b
+}
+
+// flushRaw writes the articles encoded directly to the output.
+func (w *Writer) flushRaw() error {
+	if len(w.raw) == 0 {
+		return nil
+	}
+	_, err := w.out.Write(w.raw)
+	w.raw = w.raw[:0]
+	return err
+}
+
+// flush writes the output buffered by the xml.Encoder and the articles
+// encoded directly.
+func (w *Writer) flush() error {
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	return w.flushRaw()
+}
+
+// directEncoder appends the XML of an article to b. It mirrors the
+// output of xml.Encoder, including its indentation: the start and end
+// elements are indented by depth, except for the end element of an
+// element that has no children.
+type directEncoder struct {
+	b          []byte
+	indent     string
+	depth      int
+	indentedIn bool
+	// numbers are the formats of WithNumberFormat.
+	numbers map[string]numberFormat
+}
+
+func (e *directEncoder) article(a *Article) {
+	e.open("ARTICLE")
+	if a.Mode != "" {
+		e.attr("mode", a.Mode)
+	}
+	e.b = append(e.b, '>')
+	e.element("SUPPLIER_AID", a.SupplierAID)
+	if d := a.Details; d != nil {
+		e.details(d)
+	}
+	for _, af := range a.Features {
+		if af != nil {
+			e.features(af)
+		}
+	}
+	if od := a.OrderDetails; od != nil {
+		e.start("ARTICLE_ORDER_DETAILS")
+		e.element("ORDER_UNIT", od.OrderUnit)
+		e.optional("CONTENT_UNIT", od.ContentUnit)
+		e.optionalFloat("NO_CU_PER_OU", od.NoCuPerOu)
+		e.optionalFloat("PRICE_QUANTITY", od.PriceQuantity)
+		e.optionalFloat("QUANTITY_MIN", od.QuantityMin)
+		e.optionalFloat("QUANTITY_INTERVAL", od.QuantityInterval)
+		e.end("ARTICLE_ORDER_DETAILS")
+	}
+	for _, pd := range a.PriceDetails {
+		if pd != nil {
+			e.priceDetails(pd)
+		}
+	}
+	if mi := a.MimeInfo; mi != nil {
+		e.start("MIME_INFO")
+		for _, m := range mi.Mimes {
+			if m == nil {
+				continue
+			}
+			e.start("MIME")
+			e.optional("MIME_TYPE", m.Type)
+			e.element("MIME_SOURCE", m.Source)
+			e.optional("MIME_DESCR", m.Descr)
+			e.optional("MIME_ALT", m.Alt)
+			e.optional("MIME_PURPOSE", m.Purpose)
+			e.int("MIME_ORDER", m.Order)
+			e.end("MIME")
+		}
+		e.end("MIME_INFO")
+	}
+	if a.UDX != nil {
+		e.udx(a.UDX)
+	}
+	for _, ref := range a.References {
+		if ref == nil {
+			continue
+		}
+		e.open("ARTICLE_REFERENCE")
+		e.attr("type", ref.Type)
+		if ref.Quantity != 0 {
+			e.attr("quantity", strconv.FormatFloat(ref.Quantity, 'g', -1, 64))
+		}
+		e.b = append(e.b, '>')
+		e.element("ART_ID_TO", ref.ArtIDTo)
+		e.optional("CATALOG_ID", ref.CatalogID)
+		e.optional("CATALOG_VERSION", ref.CatalogVersion)
+		e.end("ARTICLE_REFERENCE")
+	}
+	e.end("ARTICLE")
+}
+
+func (e *directEncoder) details(d *ArticleDetails) {
+	e.start("ARTICLE_DETAILS")
+	e.element("DESCRIPTION_SHORT", d.DescriptionShort)
+	e.optional("DESCRIPTION_LONG", d.DescriptionLong)
+	e.optional("EAN", d.EAN)
+	e.optional("SUPPLIER_ALT_AID", d.SupplierAltAID)
+	for _, id := range d.BuyerAIDs {
+		if id != nil {
+			e.typed("BUYER_AID", id.Type, id.Value)
+		}
+	}
+	e.optional("MANUFACTURER_AID", d.ManufacturerAID)
+	e.optional("MANUFACTURER_NAME", d.ManufacturerName)
+	e.optional("MANUFACTURER_TYPE_DESCR", d.ManufacturerTypeDescr)
+	e.optional("ERP_GROUP_BUYER", d.ERPGroupBuyer)
+	e.optional("ERP_GROUP_SUPPLIER", d.ERPGroupSupplier)
+	e.float("DELIVERY_TIME", float64(d.DeliveryTime), 32)
+	for _, c := range d.SpecialTreatmentClasses {
+		if c != nil {
+			e.typed("SPECIAL_TREATMENT_CLASS", c.Type, c.Value)
+		}
+	}
+	e.list("KEYWORD", d.Keywords)
+	e.optional("REMARKS", d.Remarks)
+	e.list("SEGMENT", d.Segments)
+	e.int("ARTICLE_ORDER", d.ArticleOrder)
+	for _, s := range d.ArticleStatus {
+		if s != nil {
+			e.typed("ARTICLE_STATUS", s.Type, s.Value)
+		}
+	}
+	e.end("ARTICLE_DETAILS")
+}
+
+func (e *directEncoder) features(af *ArticleFeatures) {
+	e.start("ARTICLE_FEATURES")
+	e.optional("REFERENCE_FEATURE_SYSTEM_NAME", af.FeatureSystemName)
+	e.optional("REFERENCE_FEATURE_GROUP_ID", af.FeatureGroupID)
+	e.optional("REFERENCE_FEATURE_GROUP_NAME", af.FeatureGroupName)
+	for _, f := range af.Features {
+		if f == nil {
+			continue
+		}
+		e.start("FEATURE")
+		e.element("FNAME", f.Name)
+		for _, vs := range f.Variants {
+			if vs == nil {
+				continue
+			}
+			e.start("VARIANTS")
+			for _, v := range vs.Variants {
+				if v == nil {
+					continue
+				}
+				e.start("VARIANT")
+				e.element("FVALUE", v.Value)
+				e.element("SUPPLIER_AID_SUPPLEMENT", v.SupplierAIDSupplement)
+				e.end("VARIANT")
+			}
+			e.int("VORDER", vs.Order)
+			e.end("VARIANTS")
+		}
+		e.list("FVALUE", f.Values)
+		e.optional("FUNIT", f.Unit)
+		e.int("FORDER", f.Order)
+		e.optional("FDESCR", f.Descr)
+		e.optional("FVALUE_DETAILS", f.ValueDetails)
+		e.end("FEATURE")
+	}
+	e.end("ARTICLE_FEATURES")
+}
+
+func (e *directEncoder) priceDetails(pd *ArticlePriceDetails) {
+	e.start("ARTICLE_PRICE_DETAILS")
+	for _, dt := range pd.Dates {
+		if dt == nil {
+			continue
+		}
+		e.open("DATETIME")
+		e.attr("type", dt.Type)
+		e.b = append(e.b, '>')
+		e.element("DATE", dt.DateString)
+		if !dt.DateOnly {
+			e.optional("TIME", dt.TimeString)
+			e.optional("TIMEZONE", dt.TimeZoneString)
+		}
+		e.end("DATETIME")
+	}
+	e.optional("DAILY_PRICE", pd.DailyPriceString)
+	for _, p := range pd.Prices {
+		if p == nil {
+			continue
+		}
+		e.open("ARTICLE_PRICE")
+		if p.Type != "" {
+			e.attr("price_type", p.Type)
+		}
+		e.b = append(e.b, '>')
+		e.number("PRICE_AMOUNT", p.Amount.String())
+		e.optional("PRICE_CURRENCY", p.Currency)
+		e.optionalDecimal("TAX", p.Tax)
+		e.optionalDecimal("PRICE_FACTOR", p.Factor)
+		e.optionalFloat("LOWER_BOUND", p.LowerBound)
+		e.list("TERRITORY", p.Territory)
+		e.end("ARTICLE_PRICE")
+	}
+	e.end("ARTICLE_PRICE_DETAILS")
+}
+
+// udx mirrors UserDefinedExtensions.MarshalXML.
+func (e *directEncoder) udx(x *UserDefinedExtensions) {
+	e.start("USER_DEFINED_EXTENSIONS")
+	for _, field := range x.Fields {
+		local := "UDX." + field.Name
+		e.start(local)
+		switch {
+		case field.Raw:
+			e.b = append(e.b, field.Value...)
+		case field.passInnerXML():
+			e.b = append(e.b, field.InnerXML...)
+		default:
+			// xml.CharData tokens keep newlines as they are
+			e.escape(field.Value, false)
+		}
+		e.end(local)
+	}
+	e.end("USER_DEFINED_EXTENSIONS")
+}
+
+func (e *directEncoder) writeIndent(depthDelta int) {
+	if e.indent == "" {
+		return
+	}
+	if depthDelta < 0 {
+		e.depth--
+		if e.indentedIn {
+			e.indentedIn = false
+			return
+		}
+	}
+	e.b = append(e.b, '\n')
+	for i := 0; i < e.depth; i++ {
+		e.b = append(e.b, e.indent...)
+	}
+	if depthDelta > 0 {
+		e.depth++
+		e.indentedIn = true
+	}
+}
+
+// open writes the start element up to its attributes.
+func (e *directEncoder) open(name string) {
+	e.writeIndent(1)
+	e.b = append(e.b, '<')
+	e.b = append(e.b, name...)
+}
+
+func (e *directEncoder) attr(name, value string) {
+	e.b = append(e.b, ' ')
+	e.b = append(e.b, name...)
+	e.b = append(e.b, `="`...)
+	e.escape(value, true)
+	e.b = append(e.b, '"')
+}
+
+func (e *directEncoder) start(name string) {
+	e.open(name)
+	e.b = append(e.b, '>')
+}
+
+func (e *directEncoder) end(name string) {
+	e.writeIndent(-1)
+	e.b = append(e.b, "</"...)
+	e.b = append(e.b, name...)
+	e.b = append(e.b, '>')
+}
+
+// element writes an element with text.
+func (e *directEncoder) element(name, value string) {
+	e.start(name)
+	e.escape(value, true)
+	e.end(name)
+}
+
+// optional writes an element with text, unless value is empty, as for
+// fields with omitempty.
+func (e *directEncoder) optional(name, value string) {
+	if value != "" {
+		e.element(name, value)
+	}
+}
+
+// list writes an element for each value that is not empty, as for
+// slices with omitempty.
+func (e *directEncoder) list(name string, values []string) {
+	for _, value := range values {
+		e.optional(name, value)
+	}
+}
+
+// typed writes an element with a type attribute and text.
+func (e *directEncoder) typed(name, typ, value string) {
+	e.open(name)
+	e.attr("type", typ)
+	e.b = append(e.b, '>')
+	e.escape(value, true)
+	e.end(name)
+}
+
+// int writes an element with an integer, unless it is zero.
+func (e *directEncoder) int(name string, value int) {
+	if value == 0 {
+		return
+	}
+	e.start(name)
+	start := len(e.b)
+	e.b = strconv.AppendInt(e.b, int64(value), 10)
+	e.formatNumber(name, start)
+	e.end(name)
+}
+
+// float writes an element with a number, unless it is zero.
+func (e *directEncoder) float(name string, value float64, bitSize int) {
+	if value == 0 {
+		return
+	}
+	e.start(name)
+	start := len(e.b)
+	e.b = strconv.AppendFloat(e.b, value, 'g', -1, bitSize)
+	e.formatNumber(name, start)
+	e.end(name)
+}
+
+// optionalFloat writes an element with a number, unless value is nil,
+// as for pointer fields with omitempty.
+func (e *directEncoder) optionalFloat(name string, value *float64) {
+	if value == nil {
+		return
+	}
+	e.start(name)
+	start := len(e.b)
+	e.b = strconv.AppendFloat(e.b, *value, 'g', -1, 64)
+	e.formatNumber(name, start)
+	e.end(name)
+}
+
+// optionalDecimal writes an element with a Decimal, unless value is nil.
+func (e *directEncoder) optionalDecimal(name string, value *Decimal) {
+	if value != nil {
+		e.number(name, value.String())
+	}
+}
+
+// number writes an element with the number s.
+func (e *directEncoder) number(name, s string) {
+	e.start(name)
+	start := len(e.b)
+	e.b = append(e.b, s...)
+	e.formatNumber(name, start)
+	e.end(name)
+}
+
+// formatNumber formats the number appended to b at start as specified
+// for the element with WithNumberFormat. Numbers need no escaping.
+func (e *directEncoder) formatNumber(name string, start int) {
+	if f, ok := e.numbers[name]; ok {
+		s := f.format(string(e.b[start:]))
+		e.b = append(e.b[:start], s...)
+	}
+}
+
+// escape writes s as escaped text, as xml.EscapeText does. Newlines are
+// only escaped if escapeNewline is true.
+func (e *directEncoder) escape(s string, escapeNewline bool) {
+	last := 0
+	for i := 0; i < len(s); {
+		r, width := utf8.DecodeRuneInString(s[i:])
+		i += width
+		var esc string
+		switch r {
+		case '"':
+			esc = "&#34;"
+		case '\'':
+			esc = "&#39;"
+		case '&':
+			esc = "&amp;"
+		case '<':
+			esc = "&lt;"
+		case '>':
+			esc = "&gt;"
+		case '\t':
+			esc = "&#x9;"
+		case '\n':
+			if !escapeNewline {
+				continue
+			}
+			esc = "&#xA;"
+		case '\r':
+			esc = "&#xD;"
+		default:
+			if !isXMLChar(r) || (r == utf8.RuneError && width == 1) {
+				esc = "\uFFFD"
+				break
+			}
+			continue
+		}
+		e.b = append(e.b, s[last:i-width]...)
+		e.b = append(e.b, esc...)
+		last = i
+	}
+	e.b = append(e.b, s[last:]...)
+}
@@ -0,0 +1,166 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func directTestArticles() []*bmecat12.Article {
+	udx := &bmecat12.UserDefinedExtensions{}
+	udx.Fields.Add("COLOR", "rot\nblau & \"grün\"")
+	udx.Fields.AddRaw("RAW", "<A>1</A><B/>")
+	udx.Fields = append(udx.Fields, &bmecat12.UserDefinedExtensionField{Name: "NESTED", InnerXML: "<X>y</X>"})
+	udx.Fields.Add("EMPTY", "")
+	return []*bmecat12.Article{
+		{SupplierAID: "1000"},
+		{
+			Mode:        "update",
+			SupplierAID: "2000 <&>",
+			Details: &bmecat12.ArticleDetails{
+				DescriptionShort:      "Kurz\ttext 'quoted'",
+				DescriptionLong:       "Zeile 1\nZeile 2\r\nInvalid \xff and \x01 and �",
+				EAN:                   "4006381333931",
+				BuyerAIDs:             []*bmecat12.BuyerAID{nil, {Type: "KMF", Value: "78787\n"}, {Value: "no type"}},
+				ManufacturerTypeDescr: "Type",
+				ERPGroupBuyer:         "G1",
+				ERPGroupSupplier:      "G2",
+				DeliveryTime:          1.1,
+				SpecialTreatmentClasses: []*bmecat12.ArticleSpecialTreatmentClass{
+					{Type: "GGVS", Value: "1201"},
+				},
+				Keywords:      []string{"a", "", "b"},
+				Segments:      []string{"", "S"},
+				ArticleOrder:  -3,
+				ArticleStatus: []*bmecat12.ArticleStatus{{Type: bmecat12.ArticleStatusNew}},
+			},
+			Features: []*bmecat12.ArticleFeatures{
+				nil,
+				{},
+				{
+					FeatureSystemName: "ECLASS-5.1",
+					FeatureGroupName:  "Group",
+					Features: []*bmecat12.Feature{
+						{Name: "Empty"},
+						{
+							Name: "Farbe",
+							Variants: []*bmecat12.FeatureVariants{
+								{Variants: []*bmecat12.FeatureVariant{{Value: "rot", SupplierAIDSupplement: "-R"}, nil, {}}, Order: 2},
+								{},
+							},
+							Values:       []string{"", "x"},
+							Unit:         "C62",
+							Order:        1,
+							Descr:        "Descr",
+							ValueDetails: "Details",
+						},
+					},
+				},
+			},
+			OrderDetails: &bmecat12.ArticleOrderDetails{
+				OrderUnit:        "C62",
+				NoCuPerOu:        float64Ptr(0.1),
+				QuantityInterval: float64Ptr(1e21),
+			},
+			PriceDetails: []*bmecat12.ArticlePriceDetails{
+				{},
+				{
+					Dates: []*bmecat12.DateTime{
+						bmecat12.NewDateTime(bmecat12.DateTimeValidStartDate, time.Date(2001, 1, 1, 10, 30, 0, 0, time.UTC)),
+						{Type: bmecat12.DateTimeValidEndDate, DateString: "2001-07-31"},
+					},
+					DailyPriceString: "TRUE",
+					Prices: []*bmecat12.ArticlePrice{
+						{},
+						{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("1499.5"), Currency: "EUR", Tax: decimalPtr("0.19"), Factor: decimalPtr("1"), LowerBound: float64Ptr(100), Territory: []string{"DE", "", "AT"}},
+					},
+				},
+			},
+			MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+				nil,
+				{},
+				{Type: bmecat12.MimeTypeJPEG, Source: "a.jpg?x=1&y=2", Descr: "D", Alt: "A", Purpose: bmecat12.MimePurposeNormal, Order: 1},
+			}},
+			UDX: udx,
+			References: []*bmecat12.ArticleReference{
+				nil,
+				{Type: bmecat12.ArticleReferenceTypeSimilar, ArtIDTo: "1000"},
+				{Type: bmecat12.ArticleReferenceTypeConsistsOf, Quantity: 2.5, ArtIDTo: "3000", CatalogID: "CAT", CatalogVersion: "1.0"},
+			},
+		},
+		{SupplierAID: "3000", UDX: &bmecat12.UserDefinedExtensions{}, MimeInfo: &bmecat12.MimeInfo{}},
+	}
+}
+
+func TestWriteDirectEncoding(t *testing.T) {
+	articles := directTestArticles()
+	for _, tx := range []bmecat12.Transaction{bmecat12.NewCatalog, bmecat12.UpdateProducts, bmecat12.UpdatePrices} {
+		for _, indent := range []string{"  ", "\t", ""} {
+			for _, groups := range []bool{false, true} {
+				var as []*bmecat12.Article
+				for _, a := range articles {
+					copied := *a
+					if groups {
+						copied.CatalogGroupIDs = []string{"1"}
+					}
+					as = append(as, &copied)
+				}
+				cw := catalogWriter{
+					tx:          tx,
+					language:    "deu",
+					prevVersion: 1,
+					header:      testHeader,
+					articles:    as,
+				}
+				write := func(direct bool) string {
+					var buf bytes.Buffer
+					w := bmecat12.NewWriter(&buf, bmecat12.WithIndent(indent), bmecat12.WithDirectEncoding(direct), bmecat12.WithSkipModeValidation())
+					if err := w.Do(context.Background(), cw); err != nil {
+						t.Fatal(err)
+					}
+					return buf.String()
+				}
+				if want, have := write(false), write(true); want != have {
+					t.Errorf("%v, indent %q, groups %v:", tx, indent, groups)
+					diffStrings(t, want, have)
+				}
+			}
+		}
+	}
+}
+
+func BenchmarkWriter(b *testing.B) {
+	article := directTestArticles()[1]
+	var articles []*bmecat12.Article
+	for i := 0; i < 1000; i++ {
+		a := *article
+		a.SupplierAID = fmt.Sprint(i)
+		articles = append(articles, &a)
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: articles,
+	}
+	for _, direct := range []bool{false, true} {
+		name := "encoding/xml"
+		if direct {
+			name = "direct"
+		}
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				w := bmecat12.NewWriter(ioutil.Discard, bmecat12.WithDirectEncoding(direct))
+				if err := w.Do(context.Background(), cw); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
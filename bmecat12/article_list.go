@@ -0,0 +1,114 @@
+package bmecat12
+
+import (
+	"bufio"
+	"io"
+	"path"
+	"strings"
+)
+
+// ArticleList is a list of article identifiers, i.e. SUPPLIER_AIDs or
+// EANs, that is used to filter articles. Entries may contain glob
+// patterns as supported by path.Match, e.g. "ABC-*" to match all
+// identifiers with the prefix "ABC-".
+type ArticleList struct {
+	ids      map[string]struct{}
+	prefixes []string
+	patterns []string
+}
+
+// NewArticleList creates a new ArticleList with the given entries.
+func NewArticleList(entries ...string) *ArticleList {
+	l := &ArticleList{ids: make(map[string]struct{})}
+	for _, entry := range entries {
+		l.Add(entry)
+	}
+	return l
+}
+
+// ReadArticleList reads an ArticleList from r. It expects one entry
+// per line. Blank lines and lines starting with # are ignored.
+func ReadArticleList(r io.Reader) (*ArticleList, error) {
+	l := NewArticleList()
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		l.Add(line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+// Add an entry to the list.
+func (l *ArticleList) Add(entry string) {
+	switch {
+	case !strings.ContainsAny(entry, `*?[\`):
+		l.ids[entry] = struct{}{}
+	case strings.HasSuffix(entry, "*") && !strings.ContainsAny(entry[:len(entry)-1], `*?[\`):
+		l.prefixes = append(l.prefixes, entry[:len(entry)-1])
+	default:
+		l.patterns = append(l.patterns, entry)
+	}
+}
+
+// Len returns the number of entries in the list.
+func (l *ArticleList) Len() int {
+	return len(l.ids) + len(l.prefixes) + len(l.patterns)
+}
+
+// Match returns true if id matches one of the entries in the list.
+func (l *ArticleList) Match(id string) bool {
+	if id == "" {
+		return false
+	}
+	if _, ok := l.ids[id]; ok {
+		return true
+	}
+	for _, prefix := range l.prefixes {
+		if strings.HasPrefix(id, prefix) {
+			return true
+		}
+	}
+	for _, pattern := range l.patterns {
+		if ok, _ := path.Match(pattern, id); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchArticle returns true if either the SUPPLIER_AID or the EAN of
+// the article matches one of the entries in the list.
+func (l *ArticleList) MatchArticle(a *Article) bool {
+	if l.Match(a.SupplierAID) {
+		return true
+	}
+	return a.Details != nil && l.Match(a.Details.EAN)
+}
+
+// AllowArticles returns a Transformer that only keeps the articles
+// that match the list.
+func AllowArticles(l *ArticleList) Transformer {
+	return TransformerFunc(func(a *Article) (*Article, error) {
+		if l.MatchArticle(a) {
+			return a, nil
+		}
+		return nil, nil
+	})
+}
+
+// DenyArticles returns a Transformer that drops the articles that
+// match the list.
+func DenyArticles(l *ArticleList) Transformer {
+	return TransformerFunc(func(a *Article) (*Article, error) {
+		if l.MatchArticle(a) {
+			return nil, nil
+		}
+		return a, nil
+	})
+}
@@ -0,0 +1,237 @@
+package bmecat12
+
+import "sync"
+
+// WithArticlePool makes the Reader take articles from a pool instead of
+// allocating a new Article for each ARTICLE element. This reduces the
+// pressure on the garbage collector when reading catalogs with millions
+// of articles: a released article keeps its nested structs, e.g.
+// ArticleDetails and ArticlePrice, and the capacity of its slices, and
+// the Reader decodes the next ARTICLE element into them.
+//
+// The handler must call Release on an article when it is done with it,
+// i.e. it must not keep a reference to the article or any of its fields
+// afterwards. Articles that are not released are simply garbage
+// collected. Articles that are not passed to the handler, e.g. because
+// of WithArticleFilter, are released by the Reader. The slices of a
+// pooled article may be empty instead of nil.
+func WithArticlePool() ReaderOption {
+	return func(r *Reader) {
+		r.pooled = true
+	}
+}
+
+// articleFreeList holds the released articles.
+var articleFreeList = sync.Pool{
+	New: func() interface{} {
+		return new(Article)
+	},
+}
+
+// articleSpares are the nested structs of a released article, which the
+// decoders reuse for the next ARTICLE element, see reuseDetails.
+type articleSpares struct {
+	details      *ArticleDetails
+	orderDetails *ArticleOrderDetails
+	mimeInfo     *MimeInfo
+	udx          *UserDefinedExtensions
+}
+
+// newArticle returns a new or, with WithArticlePool, a pooled article.
+func (r *Reader) newArticle() *Article {
+	if !r.pooled {
+		return &Article{}
+	}
+	a := articleFreeList.Get().(*Article)
+	a.pooled = true
+	return a
+}
+
+// Release returns an article read with WithArticlePool to the pool, so
+// the Reader can reuse it and its nested structs. Neither the article
+// nor any of its fields must be used afterwards. Release is a no-op for
+// other articles and for articles already released.
+//
+// If the article is passed to the handlers of a MultiHandler, Release
+// takes effect after the last handler has returned, so each handler
+// sees the article as read.
+func (a *Article) Release() {
+	if a == nil || !a.pooled {
+		return
+	}
+	if a.holds > 0 {
+		a.released = true
+		return
+	}
+	spares := a.spares
+	if spares == nil {
+		spares = &articleSpares{}
+	}
+	if a.Details != nil {
+		spares.details = a.Details
+	}
+	if a.OrderDetails != nil {
+		spares.orderDetails = a.OrderDetails
+	}
+	if a.MimeInfo != nil {
+		spares.mimeInfo = a.MimeInfo
+	}
+	if a.UDX != nil {
+		spares.udx = a.UDX
+	}
+	// The elements of the slices are reused by nextFeatures etc.
+	*a = Article{
+		Features:     a.Features[:0],
+		PriceDetails: a.PriceDetails[:0],
+		References:   a.References[:0],
+		spares:       spares,
+	}
+	articleFreeList.Put(a)
+}
+
+// hold defers Release of a pooled article until unhold is called as
+// often as hold.
+func (a *Article) hold() {
+	if a.pooled {
+		a.holds++
+	}
+}
+
+// unhold ends a hold, and releases the article if Release was called
+// during the hold.
+func (a *Article) unhold() {
+	if !a.pooled || a.holds == 0 {
+		return
+	}
+	a.holds--
+	if a.holds == 0 && a.released {
+		a.released = false
+		a.Release()
+	}
+}
+
+// The reuse and next functions below reset the structs of a released
+// article for the next ARTICLE element. Slices of pointers are cleared
+// before they are truncated, because encoding/xml decodes into a stale
+// pointee it finds beyond the length of a slice instead of a new one.
+
+// reuseDetails returns the ArticleDetails of a released article, reset,
+// or a new one.
+func (a *Article) reuseDetails() *ArticleDetails {
+	if a.spares == nil || a.spares.details == nil {
+		return &ArticleDetails{}
+	}
+	d := a.spares.details
+	a.spares.details = nil
+	for i := range d.BuyerAIDs {
+		d.BuyerAIDs[i] = nil
+	}
+	for i := range d.SpecialTreatmentClasses {
+		d.SpecialTreatmentClasses[i] = nil
+	}
+	for i := range d.ArticleStatus {
+		d.ArticleStatus[i] = nil
+	}
+	*d = ArticleDetails{
+		BuyerAIDs:               d.BuyerAIDs[:0],
+		SpecialTreatmentClasses: d.SpecialTreatmentClasses[:0],
+		Keywords:                d.Keywords[:0],
+		Segments:                d.Segments[:0],
+		ArticleStatus:           d.ArticleStatus[:0],
+	}
+	return d
+}
+
+// reuseOrderDetails returns the ArticleOrderDetails of a released
+// article, reset, or a new one.
+func (a *Article) reuseOrderDetails() *ArticleOrderDetails {
+	if a.spares == nil || a.spares.orderDetails == nil {
+		return &ArticleOrderDetails{}
+	}
+	od := a.spares.orderDetails
+	a.spares.orderDetails = nil
+	*od = ArticleOrderDetails{}
+	return od
+}
+
+// reuseMimeInfo returns the MimeInfo of a released article, reset, or a
+// new one.
+func (a *Article) reuseMimeInfo() *MimeInfo {
+	if a.spares == nil || a.spares.mimeInfo == nil {
+		return &MimeInfo{}
+	}
+	mi := a.spares.mimeInfo
+	a.spares.mimeInfo = nil
+	for i := range mi.Mimes {
+		mi.Mimes[i] = nil
+	}
+	*mi = MimeInfo{Mimes: mi.Mimes[:0]}
+	return mi
+}
+
+// reuseUDX returns the UserDefinedExtensions of a released article,
+// reset, or a new one.
+func (a *Article) reuseUDX() *UserDefinedExtensions {
+	if a.spares == nil || a.spares.udx == nil {
+		return &UserDefinedExtensions{}
+	}
+	x := a.spares.udx
+	a.spares.udx = nil
+	*x = UserDefinedExtensions{}
+	return x
+}
+
+// nextFeatures appends an ArticleFeatures to a, reusing the one of a
+// released article beyond the length of the slice, if any.
+func (a *Article) nextFeatures() *ArticleFeatures {
+	n := len(a.Features)
+	if n < cap(a.Features) {
+		if af := a.Features[:n+1][n]; af != nil {
+			a.Features = a.Features[:n+1]
+			for i := range af.Features {
+				af.Features[i] = nil
+			}
+			*af = ArticleFeatures{Features: af.Features[:0]}
+			return af
+		}
+	}
+	af := &ArticleFeatures{}
+	a.Features = append(a.Features, af)
+	return af
+}
+
+// nextPriceDetails appends an ArticlePriceDetails to a, see nextFeatures.
+func (a *Article) nextPriceDetails() *ArticlePriceDetails {
+	n := len(a.PriceDetails)
+	if n < cap(a.PriceDetails) {
+		if pd := a.PriceDetails[:n+1][n]; pd != nil {
+			a.PriceDetails = a.PriceDetails[:n+1]
+			for i := range pd.Dates {
+				pd.Dates[i] = nil
+			}
+			for i := range pd.Prices {
+				pd.Prices[i] = nil
+			}
+			*pd = ArticlePriceDetails{Dates: pd.Dates[:0], Prices: pd.Prices[:0]}
+			return pd
+		}
+	}
+	pd := &ArticlePriceDetails{}
+	a.PriceDetails = append(a.PriceDetails, pd)
+	return pd
+}
+
+// nextReference appends an ArticleReference to a, see nextFeatures.
+func (a *Article) nextReference() *ArticleReference {
+	n := len(a.References)
+	if n < cap(a.References) {
+		if ref := a.References[:n+1][n]; ref != nil {
+			a.References = a.References[:n+1]
+			*ref = ArticleReference{}
+			return ref
+		}
+	}
+	ref := &ArticleReference{}
+	a.References = append(a.References, ref)
+	return ref
+}
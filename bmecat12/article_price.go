@@ -0,0 +1,209 @@
+package bmecat12
+
+import (
+	"math/big"
+	"strings"
+	"time"
+)
+
+// IsGross reports whether the amount of p includes TAX, i.e. whether p is
+// a gros_list or nrp price. The other price types are net prices.
+func (p *ArticlePrice) IsGross() bool {
+	return p.Type == ArticlePriceTypeGrosList || p.Type == ArticlePriceTypeNRP
+}
+
+// NetAmount returns the net amount of p, i.e. PRICE_AMOUNT times
+// PRICE_FACTOR, divided by 1+TAX if p is a gross price. The result is
+// rounded half away from zero to the given number of decimal places. A
+// missing PRICE_FACTOR is 1, and a missing TAX is 0.
+func (p *ArticlePrice) NetAmount(places int32) Decimal {
+	r := p.factorAmount()
+	if p.IsGross() {
+		r.Quo(r, p.taxRate())
+	}
+	return ratDecimal(r, places)
+}
+
+// GrossAmount returns the gross amount of p, i.e. PRICE_AMOUNT times
+// PRICE_FACTOR, times 1+TAX if p is a net price. The result is rounded
+// as with NetAmount.
+func (p *ArticlePrice) GrossAmount(places int32) Decimal {
+	r := p.factorAmount()
+	if !p.IsGross() {
+		r.Mul(r, p.taxRate())
+	}
+	return ratDecimal(r, places)
+}
+
+// factorAmount returns PRICE_AMOUNT times PRICE_FACTOR.
+func (p *ArticlePrice) factorAmount() *big.Rat {
+	r := p.Amount.Rat()
+	if p.Factor != nil {
+		r.Mul(r, p.Factor.Rat())
+	}
+	return r
+}
+
+// taxRate returns 1+TAX.
+func (p *ArticlePrice) taxRate() *big.Rat {
+	r := big.NewRat(1, 1)
+	if p.Tax != nil {
+		r.Add(r, p.Tax.Rat())
+	}
+	return r
+}
+
+// UnitPrice returns the price of one content unit for amount, the price
+// of PRICE_QUANTITY order units, e.g. the result of NetAmount: amount is
+// divided by PRICE_QUANTITY and NO_CU_PER_OU. Both are 1 if they are
+// missing or not positive, or if od is nil. The result is rounded half
+// away from zero to the given number of decimal places.
+func (od *ArticleOrderDetails) UnitPrice(amount Decimal, places int32) Decimal {
+	r := amount.Rat()
+	if od != nil {
+		for _, v := range []*float64{od.PriceQuantity, od.NoCuPerOu} {
+			if v == nil || *v <= 0 {
+				continue
+			}
+			if q := new(big.Rat).SetFloat64(*v); q != nil {
+				r.Quo(r, q)
+			}
+		}
+	}
+	return ratDecimal(r, places)
+}
+
+// ratDecimal returns r rounded half away from zero to the given number of
+// decimal places. If the result exceeds the range of a Decimal, it is
+// rounded to fewer decimal places.
+func ratDecimal(r *big.Rat, places int32) Decimal {
+	ten := big.NewInt(10)
+	for ; ; places-- {
+		scale := new(big.Int).Exp(ten, big.NewInt(int64(abs32(places))), nil)
+		num := new(big.Int).Set(r.Num())
+		denom := new(big.Int).Set(r.Denom())
+		if places >= 0 {
+			num.Mul(num, scale)
+		} else {
+			denom.Mul(denom, scale)
+		}
+		// round half away from zero: (2*num + sign*denom) / (2*denom)
+		num.Mul(num, big.NewInt(2))
+		if num.Sign() < 0 {
+			num.Sub(num, denom)
+		} else {
+			num.Add(num, denom)
+		}
+		q := num.Quo(num, denom.Mul(denom, big.NewInt(2)))
+		if q.IsInt64() {
+			return Decimal{unscaled: q.Int64(), scale: places}
+		}
+	}
+}
+
+// abs32 returns the absolute value of v.
+func abs32(v int32) int32 {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// PriceDetailsAt returns the ARTICLE_PRICE_DETAILS of a that are valid at
+// t and have a price for the given territory, in the order of a. Price
+// details are valid from valid_start_date up to and including
+// valid_end_date; a date without TIME includes the whole day, and a
+// missing date imposes no limit. A price without TERRITORY applies to all
+// territories, and an empty territory matches all prices.
+func (a *Article) PriceDetailsAt(t time.Time, territory string) []*ArticlePriceDetails {
+	var details []*ArticlePriceDetails
+	for _, pd := range a.PriceDetails {
+		if pd == nil || !pd.validAt(t) {
+			continue
+		}
+		for _, p := range pd.Prices {
+			if p != nil && p.inTerritory(territory) {
+				details = append(details, pd)
+				break
+			}
+		}
+	}
+	return details
+}
+
+// PriceAt returns the price of the given type for an order of qty units
+// at t in the given territory, or nil if there is none. It looks at the
+// price details returned by PriceDetailsAt in order, and returns the
+// price of the first one that has a price for qty, see PriceForQuantity.
+// Prices that list the territory take precedence over prices without
+// TERRITORY.
+func (a *Article) PriceAt(t time.Time, territory string, qty float64, priceType string) *ArticlePrice {
+	for _, pd := range a.PriceDetailsAt(t, territory) {
+		if territory == "" {
+			if p := pd.PriceForQuantity(qty, priceType); p != nil {
+				return p
+			}
+			continue
+		}
+		var listed, unlisted ArticlePriceDetails
+		for _, p := range pd.Prices {
+			switch {
+			case p == nil || !p.inTerritory(territory):
+			case len(p.Territory) == 0:
+				unlisted.Prices = append(unlisted.Prices, p)
+			default:
+				listed.Prices = append(listed.Prices, p)
+			}
+		}
+		if p := listed.PriceForQuantity(qty, priceType); p != nil {
+			return p
+		}
+		if p := unlisted.PriceForQuantity(qty, priceType); p != nil {
+			return p
+		}
+	}
+	return nil
+}
+
+// validAt reports whether apd is valid at t, see PriceDetailsAt. Dates
+// that cannot be parsed impose no limit.
+func (apd *ArticlePriceDetails) validAt(t time.Time) bool {
+	for _, dt := range apd.Dates {
+		if dt == nil {
+			continue
+		}
+		v, err := dt.Time()
+		if err != nil {
+			continue
+		}
+		switch dt.Type {
+		case DateTimeValidStartDate:
+			if t.Before(v) {
+				return false
+			}
+		case DateTimeValidEndDate:
+			if dt.DateOnly || dt.TimeString == "" {
+				v = v.AddDate(0, 0, 1)
+				if !t.Before(v) {
+					return false
+				}
+			} else if t.After(v) {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// inTerritory reports whether p applies to territory, see PriceDetailsAt.
+func (p *ArticlePrice) inTerritory(territory string) bool {
+	if territory == "" || len(p.Territory) == 0 {
+		return true
+	}
+	for _, s := range p.Territory {
+		if strings.EqualFold(s, territory) {
+			return true
+		}
+	}
+	return false
+}
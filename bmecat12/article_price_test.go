@@ -0,0 +1,166 @@
+package bmecat12_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestPriceForQuantity(t *testing.T) {
+	pd := &bmecat12.ArticlePriceDetails{
+		Prices: []*bmecat12.ArticlePrice{
+			{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("10.00")},
+			{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("8.00"), LowerBound: float64Ptr(100)},
+			{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("9.00"), LowerBound: float64Ptr(10)},
+			{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("8.50"), LowerBound: float64Ptr(10), Territory: []string{"AT"}},
+			{Type: bmecat12.ArticlePriceTypeNRP, Amount: bmecat12.MustParseDecimal("15.00"), LowerBound: float64Ptr(5)},
+			nil,
+		},
+	}
+	tests := []struct {
+		Qty  float64
+		Type string
+		Want string
+	}{
+		{Qty: 1, Type: bmecat12.ArticlePriceTypeNetList, Want: "10.00"},
+		{Qty: 9.5, Type: bmecat12.ArticlePriceTypeNetList, Want: "10.00"},
+		{Qty: 10, Type: bmecat12.ArticlePriceTypeNetList, Want: "9.00"},
+		{Qty: 99, Type: bmecat12.ArticlePriceTypeNetList, Want: "9.00"},
+		{Qty: 100, Type: bmecat12.ArticlePriceTypeNetList, Want: "8.00"},
+		{Qty: 1000, Type: bmecat12.ArticlePriceTypeNetList, Want: "8.00"},
+		{Qty: 0.5, Type: bmecat12.ArticlePriceTypeNetList, Want: ""},
+		{Qty: 4, Type: bmecat12.ArticlePriceTypeNRP, Want: ""},
+		{Qty: 5, Type: bmecat12.ArticlePriceTypeNRP, Want: "15.00"},
+		{Qty: 10, Type: bmecat12.ArticlePriceTypeNetCustomer, Want: ""},
+	}
+	for _, tt := range tests {
+		var have string
+		if p := pd.PriceForQuantity(tt.Qty, tt.Type); p != nil {
+			have = p.Amount.String()
+		}
+		if want := tt.Want; want != have {
+			t.Fatalf("%v of %s: want %q, have %q", tt.Qty, tt.Type, want, have)
+		}
+	}
+}
+
+func TestArticlePriceNetAndGrossAmount(t *testing.T) {
+	tests := []struct {
+		Price *bmecat12.ArticlePrice
+		Net   string
+		Gross string
+	}{
+		{
+			Price: &bmecat12.ArticlePrice{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("100.00"), Tax: decimalPtr("0.19")},
+			Net:   "100.00",
+			Gross: "119.00",
+		},
+		{
+			Price: &bmecat12.ArticlePrice{Type: bmecat12.ArticlePriceTypeGrosList, Amount: bmecat12.MustParseDecimal("1499.50"), Tax: decimalPtr("0.19")},
+			Net:   "1260.08",
+			Gross: "1499.50",
+		},
+		{
+			Price: &bmecat12.ArticlePrice{Type: bmecat12.ArticlePriceTypeNRP, Amount: bmecat12.MustParseDecimal("119"), Tax: decimalPtr("0.19"), Factor: decimalPtr("0.5")},
+			Net:   "50.00",
+			Gross: "59.50",
+		},
+		{
+			Price: &bmecat12.ArticlePrice{Type: bmecat12.ArticlePriceTypeNetCustomer, Amount: bmecat12.MustParseDecimal("-10.005")},
+			Net:   "-10.01",
+			Gross: "-10.01",
+		},
+	}
+	for _, tt := range tests {
+		p := tt.Price
+		if want, have := tt.Net, p.NetAmount(2).String(); want != have {
+			t.Fatalf("%s %s: want net %s, have %s", p.Type, p.Amount, want, have)
+		}
+		if want, have := tt.Gross, p.GrossAmount(2).String(); want != have {
+			t.Fatalf("%s %s: want gross %s, have %s", p.Type, p.Amount, want, have)
+		}
+	}
+}
+
+func TestArticleOrderDetailsUnitPrice(t *testing.T) {
+	amount := bmecat12.MustParseDecimal("12.00")
+	tests := []struct {
+		OrderDetails *bmecat12.ArticleOrderDetails
+		Want         string
+	}{
+		{OrderDetails: nil, Want: "12.0000"},
+		{OrderDetails: &bmecat12.ArticleOrderDetails{}, Want: "12.0000"},
+		{OrderDetails: &bmecat12.ArticleOrderDetails{PriceQuantity: float64Ptr(100)}, Want: "0.1200"},
+		{OrderDetails: &bmecat12.ArticleOrderDetails{NoCuPerOu: float64Ptr(6)}, Want: "2.0000"},
+		{OrderDetails: &bmecat12.ArticleOrderDetails{PriceQuantity: float64Ptr(10), NoCuPerOu: float64Ptr(7)}, Want: "0.1714"},
+		{OrderDetails: &bmecat12.ArticleOrderDetails{PriceQuantity: float64Ptr(0), NoCuPerOu: float64Ptr(0.5)}, Want: "24.0000"},
+	}
+	for i, tt := range tests {
+		if want, have := tt.Want, tt.OrderDetails.UnitPrice(amount, 4).String(); want != have {
+			t.Fatalf("#%d: want %s, have %s", i, want, have)
+		}
+	}
+}
+
+func TestArticlePriceAt(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2001, 1, d, 0, 0, 0, 0, time.UTC)
+	}
+	a := &bmecat12.Article{
+		SupplierAID: "1000",
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{
+				// January 1st to 10th, whole days
+				Dates: []*bmecat12.DateTime{
+					bmecat12.NewDate(bmecat12.DateTimeValidStartDate, day(1)),
+					bmecat12.NewDate(bmecat12.DateTimeValidEndDate, day(10)),
+				},
+				Prices: []*bmecat12.ArticlePrice{
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("10.00")},
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("11.00"), Territory: []string{"AT"}},
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("9.00"), LowerBound: float64Ptr(10)},
+				},
+			},
+			{
+				// from January 11th, 12:00 UTC
+				Dates: []*bmecat12.DateTime{
+					bmecat12.NewDateTime(bmecat12.DateTimeValidStartDate, day(11).Add(12*time.Hour)),
+				},
+				Prices: []*bmecat12.ArticlePrice{
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("12.00"), Territory: []string{"DE"}},
+				},
+			},
+		},
+	}
+
+	tests := []struct {
+		Time      time.Time
+		Territory string
+		Qty       float64
+		Details   int
+		Want      string
+	}{
+		{Time: day(1), Territory: "", Qty: 1, Details: 1, Want: "10.00"},
+		{Time: day(10).Add(23 * time.Hour), Territory: "DE", Qty: 1, Details: 1, Want: "10.00"},
+		{Time: day(5), Territory: "AT", Qty: 1, Details: 1, Want: "11.00"},
+		{Time: day(5), Territory: "AT", Qty: 10, Details: 1, Want: "11.00"},
+		{Time: day(5), Territory: "DE", Qty: 10, Details: 1, Want: "9.00"},
+		{Time: day(11), Territory: "DE", Qty: 1, Details: 0, Want: ""},
+		{Time: day(11).Add(12 * time.Hour), Territory: "de", Qty: 1, Details: 1, Want: "12.00"},
+		{Time: day(20), Territory: "AT", Qty: 1, Details: 0, Want: ""},
+		{Time: day(1).Add(-time.Second), Territory: "", Qty: 1, Details: 0, Want: ""},
+	}
+	for i, tt := range tests {
+		if want, have := tt.Details, len(a.PriceDetailsAt(tt.Time, tt.Territory)); want != have {
+			t.Fatalf("#%d: want %d price details, have %d", i, want, have)
+		}
+		var have string
+		if p := a.PriceAt(tt.Time, tt.Territory, tt.Qty, bmecat12.ArticlePriceTypeNetList); p != nil {
+			have = p.Amount.String()
+		}
+		if want := tt.Want; want != have {
+			t.Fatalf("#%d: want price %q, have %q", i, want, have)
+		}
+	}
+}
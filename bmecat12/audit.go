@@ -0,0 +1,86 @@
+package bmecat12
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+	"time"
+)
+
+// Actions of an AuditEvent.
+const (
+	// AuditSkip records an element that has been left out, e.g. an
+	// article exceeding WithMaxArticleSize or rejected by a filter.
+	AuditSkip = "skip"
+	// AuditCoerce records a value that could not be read as is and has
+	// been replaced, e.g. an invalid number.
+	AuditCoerce = "coerce"
+	// AuditOverride records a value that has been replaced on purpose,
+	// e.g. by a Transformer.
+	AuditOverride = "override"
+)
+
+// AuditEvent records an automated modification of the catalog data.
+type AuditEvent struct {
+	// Time of the event. AuditLog sets it if it is zero.
+	Time time.Time `json:"time"`
+	// Action is one of AuditSkip, AuditCoerce, or AuditOverride.
+	Action string `json:"action"`
+	// Element is the element concerned, e.g. "ARTICLE".
+	Element string `json:"element"`
+	// SupplierAID is the SUPPLIER_AID of the article concerned, if any.
+	SupplierAID string `json:"supplier_aid,omitempty"`
+	// Field is the name of the value modified, if any, e.g. "prev_version".
+	Field string `json:"field,omitempty"`
+	// Before and After are the values before and after the modification.
+	Before string `json:"before,omitempty"`
+	After  string `json:"after,omitempty"`
+	// Reason describes why the data has been modified.
+	Reason string `json:"reason,omitempty"`
+	// Offset is the byte offset of the element, if known.
+	Offset int64 `json:"offset,omitempty"`
+}
+
+// AuditHandler, if implemented by a handler, is called for every article
+// or element the Reader skips and every value it coerces.
+type AuditHandler interface {
+	HandleAudit(*AuditEvent) error
+}
+
+// AuditLog writes AuditEvents as newline-delimited JSON, e.g. as evidence
+// of automated data modifications for compliance. It implements
+// AuditHandler, so it can be passed to Reader.Do via MultiHandler.
+// Transformers can record their overrides by calling HandleAudit. It is
+// safe for concurrent use.
+type AuditLog struct {
+	mu  sync.Mutex
+	enc *json.Encoder
+	n   int
+}
+
+// NewAuditLog creates a new AuditLog that writes to w.
+func NewAuditLog(w io.Writer) *AuditLog {
+	return &AuditLog{enc: json.NewEncoder(w)}
+}
+
+// HandleAudit implements the AuditHandler interface. It writes the event
+// as a line of JSON.
+func (l *AuditLog) HandleAudit(e *AuditEvent) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if err := l.enc.Encode(e); err != nil {
+		return err
+	}
+	l.n++
+	return nil
+}
+
+// Len returns the number of events written.
+func (l *AuditLog) Len() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.n
+}
@@ -0,0 +1,52 @@
+package bmecat12_test
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestAuditLog(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	doc = strings.Replace(doc, `prev_version="1"`, `prev_version="x"`, 1)
+
+	var buf bytes.Buffer
+	log := bmecat12.NewAuditLog(&buf)
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+		bmecat12.WithArticleFilter(func(a *bmecat12.Article) bool { return a.SupplierAID != "4000" }),
+	)
+	if err := r.Do(context.Background(), bmecat12.MultiHandler(&testHandler{}, log)); err != nil {
+		t.Fatal(err)
+	}
+
+	var have []string
+	sc := bufio.NewScanner(&buf)
+	for sc.Scan() {
+		var e bmecat12.AuditEvent
+		if err := json.Unmarshal(sc.Bytes(), &e); err != nil {
+			t.Fatalf("invalid NDJSON line %q: %v", sc.Text(), err)
+		}
+		if e.Time.IsZero() {
+			t.Fatalf("want Time to be set, have %q", sc.Text())
+		}
+		have = append(have, e.Action+":"+e.Element+":"+e.SupplierAID+":"+e.Before)
+	}
+	want := []string{
+		"coerce:T_UPDATE_PRICES::x",
+		"skip:ARTICLE:2000:",
+		"skip:ARTICLE:3000:",
+		"skip:ARTICLE:4000:",
+	}
+	if want, have := strings.Join(want, "\n"), strings.Join(have, "\n"); want != have {
+		t.Fatalf("want\n%s\nhave\n%s", want, have)
+	}
+	if want, have := 4, log.Len(); want != have {
+		t.Fatalf("want Len=%d, have %d", want, have)
+	}
+}
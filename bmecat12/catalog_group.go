@@ -2,18 +2,34 @@ package bmecat12
 
 import "encoding/xml"
 
+// CatalogGroupSystem represents the CATALOG_GROUP_SYSTEM element from the
+// BMEcat specification, i.e. the catalog structure of a catalog.
+type CatalogGroupSystem struct {
+	XMLName xml.Name `xml:"CATALOG_GROUP_SYSTEM"`
+
+	ID          string          `xml:"GROUP_SYSTEM_ID,omitempty"`
+	Name        string          `xml:"GROUP_SYSTEM_NAME,omitempty"`
+	Description string          `xml:"GROUP_SYSTEM_DESCRIPTION,omitempty"`
+	Groups      []*CatalogGroup `xml:"CATALOG_STRUCTURE,omitempty"`
+}
+
+// IsBlank returns true if there are no groups in the catalog group system.
+func (cgs *CatalogGroupSystem) IsBlank() bool {
+	return cgs == nil || len(cgs.Groups) == 0
+}
+
 type CatalogGroup struct {
 	XMLName xml.Name `xml:"CATALOG_STRUCTURE"`
 
-	Type        string  `xml:"type,attr,omitempty"`
-	ID          string  `xml:"GROUP_ID"`
-	Name        string  `xml:"GROUP_NAME"`
-	Description string  `xml:"GROUP_DESCRIPTION,omitempty"`
-	ParentID    *string `xml:"PARENT_ID,omitempty"`
-	Order       int     `xml:"GROUP_ORDER,omitempty"`
-	// MIME_INFO
-	// USER_DEFINED_EXTENSIONS
-	Keywords []string `xml:"KEYWORD,omitempty"`
+	Type        string                 `xml:"type,attr,omitempty"`
+	ID          string                 `xml:"GROUP_ID"`
+	Name        string                 `xml:"GROUP_NAME"`
+	Description string                 `xml:"GROUP_DESCRIPTION,omitempty"`
+	ParentID    *string                `xml:"PARENT_ID,omitempty"`
+	Order       int                    `xml:"GROUP_ORDER,omitempty"`
+	MimeInfo    *MimeInfo              `xml:"MIME_INFO,omitempty"`
+	UDX         *UserDefinedExtensions `xml:"USER_DEFINED_EXTENSIONS,omitempty"`
+	Keywords    []string               `xml:"KEYWORD,omitempty"`
 }
 
 func (cg *CatalogGroup) IsRoot() bool {
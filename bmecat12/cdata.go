@@ -0,0 +1,60 @@
+package bmecat12
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// WithCDATA makes the Writer write the text of the given elements as
+// CDATA sections instead of escaping it, e.g. for legacy parsers that
+// cannot handle escaped HTML in DESCRIPTION_LONG. If no elements are
+// given, DESCRIPTION_LONG and REMARKS are written as CDATA. The option
+// applies to the elements of HEADER, ARTICLE, and the catalog structure,
+// e.g. GROUP_DESCRIPTION, and may be given more than once.
+func WithCDATA(elements ...string) WriterOption {
+	return func(w *Writer) {
+		if len(elements) == 0 {
+			elements = []string{"DESCRIPTION_LONG", "REMARKS"}
+		}
+		if w.cdata == nil {
+			w.cdata = make(map[string]bool)
+		}
+		for _, name := range elements {
+			w.cdata[name] = true
+		}
+	}
+}
+
+// writeCDATA writes text as a CDATA section. A "]]>" in text is split
+// into two sections. Characters that cannot be represented in the
+// encoding of WithEncoding are written outside of the section, so the
+// UnmappablePolicy applies to them as to all other text. Characters
+// outside the XML 1.0 character range are written as U+FFFD, as by
+// xml.Encoder.
+func (w *Writer) writeCDATA(text string) error {
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	var b strings.Builder
+	b.WriteString("<![CDATA[")
+	for i, r := range text {
+		switch {
+		case !isXMLChar(r):
+			b.WriteRune(utf8.RuneError)
+		case r == '>' && strings.HasSuffix(text[:i], "]]"):
+			b.WriteString("]]><![CDATA[>")
+		case r >= utf8.RuneSelf && w.charmap != nil:
+			if _, ok := w.charmap.EncodeRune(r); ok {
+				b.WriteRune(r)
+			} else {
+				fmt.Fprintf(&b, "]]>%c<![CDATA[", r)
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	b.WriteString("]]>")
+	_, err := fmt.Fprint(w.out, b.String())
+	return err
+}
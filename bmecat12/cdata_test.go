@@ -0,0 +1,83 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithCDATA(t *testing.T) {
+	details := &bmecat12.ArticleDetails{
+		DescriptionShort: "<i>Kurz</i>",
+		DescriptionLong:  "<b>Fett</b> & ]]> 5 €",
+		Remarks:          "<br>",
+	}
+	tests := []struct {
+		Name    string
+		Options []bmecat12.WriterOption
+		Want    []string
+	}{
+		{
+			Name:    "Default",
+			Options: []bmecat12.WriterOption{bmecat12.WithCDATA()},
+			Want: []string{
+				"<DESCRIPTION_SHORT>&lt;i&gt;Kurz&lt;/i&gt;</DESCRIPTION_SHORT>",
+				"<DESCRIPTION_LONG><![CDATA[<b>Fett</b> & ]]]]><![CDATA[> 5 €]]></DESCRIPTION_LONG>",
+				"<REMARKS><![CDATA[<br>]]></REMARKS>",
+			},
+		},
+		{
+			Name:    "Elements",
+			Options: []bmecat12.WriterOption{bmecat12.WithCDATA("DESCRIPTION_SHORT")},
+			Want: []string{
+				"<DESCRIPTION_SHORT><![CDATA[<i>Kurz</i>]]></DESCRIPTION_SHORT>",
+				"<DESCRIPTION_LONG>&lt;b&gt;Fett&lt;/b&gt; &amp; ]]&gt; 5 €</DESCRIPTION_LONG>",
+			},
+		},
+		{
+			Name:    "Encoding",
+			Options: []bmecat12.WriterOption{bmecat12.WithCDATA(), bmecat12.WithEncoding("ISO-8859-1")},
+			Want: []string{
+				"<DESCRIPTION_LONG><![CDATA[<b>Fett</b> & ]]]]><![CDATA[> 5 ]]>&#8364;<![CDATA[]]></DESCRIPTION_LONG>",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			cw := catalogWriter{
+				tx:       bmecat12.NewCatalog,
+				language: "de",
+				header:   testHeader,
+				articles: []*bmecat12.Article{{SupplierAID: "1000", Details: details}},
+			}
+			var buf bytes.Buffer
+			if err := bmecat12.NewWriter(&buf, tt.Options...).Do(context.Background(), cw); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			for _, want := range tt.Want {
+				if !strings.Contains(out, want) {
+					t.Fatalf("want %s in\n%s", want, out)
+				}
+			}
+
+			h := &testHandler{}
+			if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := 1, len(h.articles); want != have {
+				t.Fatalf("want %d articles, have %d", want, have)
+			}
+			have := h.articles[0].Details
+			if want, have := details.DescriptionLong, have.DescriptionLong; want != have {
+				t.Fatalf("want DESCRIPTION_LONG %q, have %q", want, have)
+			}
+			if want, have := details.Remarks, have.Remarks; want != have {
+				t.Fatalf("want REMARKS %q, have %q", want, have)
+			}
+		})
+	}
+}
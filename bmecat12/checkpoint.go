@@ -0,0 +1,140 @@
+package bmecat12
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"io/ioutil"
+	"strings"
+)
+
+// Checkpoint is the state of a Reader after an article has been passed
+// to the handler. Save it, e.g. as JSON, and pass it to WithCheckpoint
+// to resume a long-running import after a crash, without having to read
+// the file from the start.
+type Checkpoint struct {
+	// Pass is the pass of the Reader, which is always 2.
+	Pass int `json:"pass"`
+	// Offset is the byte offset after the last article that has been
+	// passed to the handler.
+	Offset int64 `json:"offset"`
+	// Articles is the 1-based position of that article in the file.
+	Articles int `json:"articles"`
+	// Handled is the number of articles passed to the handler so far.
+	Handled int `json:"handled"`
+	// LastSupplierAID is the SUPPLIER_AID of that article.
+	LastSupplierAID string `json:"last_supplier_aid,omitempty"`
+	// Transaction and PreviousVersion of the catalog.
+	Transaction     Transaction `json:"transaction"`
+	PreviousVersion int         `json:"previous_version,omitempty"`
+	// TransactionElement is the name of the transaction element,
+	// e.g. T_NEW_CATALOG.
+	TransactionElement string `json:"transaction_element"`
+	// Encoding is the encoding declared in the XML declaration.
+	Encoding string `json:"encoding,omitempty"`
+	// CatalogGroups are the catalog groups of the articles, as found in
+	// the ARTICLE_TO_CATALOGGROUP_MAP elements in the 1st pass.
+	CatalogGroups map[string][]string `json:"catalog_groups,omitempty"`
+	// SkippedArticles are the articles exceeding WithMaxArticleSize,
+	// by their 1-based position.
+	SkippedArticles map[int]SkippedArticle `json:"skipped_articles,omitempty"`
+}
+
+// SkippedArticle is an article skipped due to WithMaxArticleSize, as
+// recorded in a Checkpoint.
+type SkippedArticle struct {
+	SupplierAID string `json:"supplier_aid"`
+	Size        int64  `json:"size"`
+}
+
+// WithCheckpoint resumes reading after the article recorded in cp. The
+// 1st pass is skipped, as its state is part of the checkpoint, and the
+// 2nd pass starts at the byte offset of the checkpoint. Handlers for
+// elements preceding the checkpoint, e.g. HEADER or FEATURE_SYSTEM, are
+// not called again. The line and column of errors are relative to the
+// checkpoint.
+//
+// The file must be the same as the one the checkpoint has been taken
+// from, and the options of the Reader should be the same.
+func WithCheckpoint(cp *Checkpoint) ReaderOption {
+	return func(r *Reader) {
+		r.resume = cp
+	}
+}
+
+// Checkpoint returns the state of the Reader after the last article that
+// has been passed to the handler. It is meant to be called from within
+// a handler, e.g. every 10,000 articles in HandleArticle after the
+// article has been committed. It returns nil if no article has been
+// passed to the handler yet, outside of Do, and with
+// WithUnorderedArticles, as the order of articles is undefined then.
+func (r *Reader) Checkpoint() *Checkpoint {
+	if r.checkpoint == nil {
+		return nil
+	}
+	return r.checkpoint()
+}
+
+// resumeDecoder returns a decoder that continues at the offset of cp,
+// along with the base to add to its input offset. The decoder starts
+// with a synthetic prefix that opens the BMECAT and transaction elements,
+// so it accepts their end elements.
+func (r *Reader) resumeDecoder(cp *Checkpoint) (*xml.Decoder, *rawCapture, int64, error) {
+	var src io.Reader
+	if enc := strings.ToLower(cp.Encoding); enc == "" || enc == "utf-8" || enc == "utf8" {
+		if _, err := r.r.Seek(cp.Offset, io.SeekStart); err != nil {
+			return nil, nil, 0, &InputError{Op: "seek to checkpoint", Err: err}
+		}
+		src = r.r
+	} else {
+		// Offsets refer to the input converted to UTF-8, so convert the
+		// input from the start, behind the XML declaration
+		if _, err := r.r.Seek(0, io.SeekStart); err != nil {
+			return nil, nil, 0, &InputError{Op: "seek to checkpoint", Err: err}
+		}
+		br := bufio.NewReader(r.r)
+		decl, err := readXMLDecl(br)
+		if err != nil {
+			return nil, nil, 0, &InputError{Op: "seek to checkpoint", Err: err}
+		}
+		rd, err := r.charsetReader(cp.Encoding, br)
+		if err != nil {
+			return nil, nil, 0, &InputError{Op: "seek to checkpoint", Err: err}
+		}
+		if _, err := io.CopyN(ioutil.Discard, rd, cp.Offset-int64(len(decl))); err != nil {
+			return nil, nil, 0, &InputError{Op: "seek to checkpoint", Err: err}
+		}
+		src = rd
+	}
+
+	prefix := "<BMECAT><" + cp.TransactionElement + ">"
+	dec, capture := r.newDecoder(io.MultiReader(strings.NewReader(prefix), src))
+	for i := 0; i < 2; i++ {
+		if _, err := dec.Token(); err != nil {
+			return nil, nil, 0, &InputError{Op: "resume from checkpoint", Err: err}
+		}
+	}
+	return dec, capture, cp.Offset - int64(len(prefix)), nil
+}
+
+// readXMLDecl reads the XML declaration, up to and including "?>".
+func readXMLDecl(br *bufio.Reader) ([]byte, error) {
+	var decl []byte
+	for !bytes.HasSuffix(decl, []byte("?>")) {
+		b, err := br.ReadByte()
+		if err != nil {
+			return decl, err
+		}
+		decl = append(decl, b)
+	}
+	return decl, nil
+}
+
+// procInstEncoding returns the encoding of an XML declaration.
+func procInstEncoding(inst []byte) string {
+	if m := xmlEncodingRe.FindSubmatch(inst); m != nil {
+		return string(m[1])
+	}
+	return ""
+}
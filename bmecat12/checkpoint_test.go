@@ -0,0 +1,126 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// crashAfterFirstArticle reads src, takes a checkpoint after the first
+// article, and aborts the import. It returns the checkpoint as it would
+// have been saved, i.e. serialized to JSON and back.
+func crashAfterFirstArticle(t *testing.T, src io.ReadSeeker, options ...bmecat12.ReaderOption) *bmecat12.Checkpoint {
+	t.Helper()
+	errCrash := errors.New("crash")
+	var saved []byte
+	r := bmecat12.NewReader(src, options...)
+	if cp := r.Checkpoint(); cp != nil {
+		t.Fatalf("want no checkpoint before Do, have %+v", cp)
+	}
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			cp := r.Checkpoint()
+			if cp == nil {
+				t.Fatal("want checkpoint, have nil")
+			}
+			var err error
+			if saved, err = json.Marshal(cp); err != nil {
+				t.Fatal(err)
+			}
+			return errCrash
+		},
+	}
+	if err := r.Do(context.Background(), h); !errors.Is(err, errCrash) {
+		t.Fatalf("want crash, have %v", err)
+	}
+	var cp bmecat12.Checkpoint
+	if err := json.Unmarshal(saved, &cp); err != nil {
+		t.Fatal(err)
+	}
+	return &cp
+}
+
+func TestReadWithCheckpoint(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, concurrency := range []int{1, 4} {
+		cp := crashAfterFirstArticle(t, bytes.NewReader(data), bmecat12.WithConcurrency(concurrency))
+		if want, have := 2, cp.Pass; want != have {
+			t.Fatalf("want Pass=%d, have %d", want, have)
+		}
+		if want, have := 1, cp.Articles; want != have {
+			t.Fatalf("want Articles=%d, have %d", want, have)
+		}
+		if want, have := "1000", cp.LastSupplierAID; want != have {
+			t.Fatalf("want LastSupplierAID=%q, have %q", want, have)
+		}
+		if want, have := "T_UPDATE_PRODUCTS", cp.TransactionElement; want != have {
+			t.Fatalf("want TransactionElement=%q, have %q", want, have)
+		}
+
+		var aids []string
+		var header bool
+		var complete bool
+		h := bmecat12.HandlerFuncs{
+			OnHeader: func(*bmecat12.Header) error {
+				header = true
+				return nil
+			},
+			OnArticle: func(a *bmecat12.Article) error {
+				aids = append(aids, a.SupplierAID)
+				return nil
+			},
+			OnComplete: func() {
+				complete = true
+			},
+		}
+		r := bmecat12.NewReader(bytes.NewReader(data), bmecat12.WithCheckpoint(cp), bmecat12.WithConcurrency(concurrency))
+		if err := r.Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "2000", strings.Join(aids, ","); want != have {
+			t.Fatalf("want articles %s, have %s", want, have)
+		}
+		if header {
+			t.Fatal("expected HEADER to be skipped on resume")
+		}
+		if !complete {
+			t.Fatal("expected OnComplete to be called")
+		}
+	}
+}
+
+func TestReadWithCheckpointAndEncoding(t *testing.T) {
+	data := indexCatalog(t)
+	cp := crashAfterFirstArticle(t, bytes.NewReader(data))
+	if want, have := "ISO-8859-1", cp.Encoding; want != have {
+		t.Fatalf("want Encoding=%q, have %q", want, have)
+	}
+	if want, have := "10,20", strings.Join(cp.CatalogGroups["Ä&2000"], ","); want != have {
+		t.Fatalf("want catalog groups %s, have %s", want, have)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(data), bmecat12.WithCheckpoint(cp)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := "Ä&2000", h.articles[0].SupplierAID; want != have {
+		t.Fatalf("want SupplierAID=%q, have %q", want, have)
+	}
+	if want, have := "10,20", strings.Join(h.articles[0].CatalogGroupIDs, ","); want != have {
+		t.Fatalf("want CatalogGroupIDs=%s, have %s", want, have)
+	}
+}
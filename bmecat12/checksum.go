@@ -0,0 +1,49 @@
+package bmecat12
+
+import (
+	"hash"
+	"io"
+)
+
+// WithChecksum makes the Writer compute a checksum of the output with
+// the hash created by newHash, e.g. sha256.New or md5.New, as required
+// by many upload APIs. Get it with Writer.Checksum after Do, or with
+// ShardedWriter.Checksums for the files of a ShardedWriter.
+func WithChecksum(newHash func() hash.Hash) WriterOption {
+	return func(w *Writer) {
+		w.newHash = newHash
+	}
+}
+
+// Checksum returns the checksum of the output of the last call to Do,
+// as computed by the hash of WithChecksum, or nil if the option is not
+// set or Do failed. Use hex.EncodeToString to get it as a string.
+func (w *Writer) Checksum() []byte {
+	return w.checksum
+}
+
+// Checksums returns the checksums of the files written by the last call
+// to Do, by index, see WithChecksum. It returns nil if the option is not
+// set.
+func (s *ShardedWriter) Checksums() [][]byte {
+	return s.checksums
+}
+
+// startChecksum returns out, teed through a new hash if WithChecksum is
+// set.
+func (w *Writer) startChecksum(out io.Writer) io.Writer {
+	w.checksum = nil
+	w.hash = nil
+	if w.newHash == nil {
+		return out
+	}
+	w.hash = w.newHash()
+	return io.MultiWriter(out, w.hash)
+}
+
+// finishChecksum computes the checksum once the output is complete.
+func (w *Writer) finishChecksum() {
+	if w.hash != nil {
+		w.checksum = w.hash.Sum(nil)
+	}
+}
@@ -0,0 +1,84 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"crypto/md5"
+	"crypto/sha256"
+	"fmt"
+	"hash"
+	"io"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithChecksum(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{{SupplierAID: "1000"}},
+	}
+	tests := []struct {
+		Name    string
+		NewHash func() hash.Hash
+	}{
+		{"SHA-256", sha256.New},
+		{"MD5", md5.New},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		w := bmecat12.NewWriter(&buf, bmecat12.WithChecksum(tt.NewHash), bmecat12.WithEncoding("ISO-8859-1"))
+		if err := w.Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		h := tt.NewHash()
+		h.Write(buf.Bytes())
+		if want, have := fmt.Sprintf("%x", h.Sum(nil)), fmt.Sprintf("%x", w.Checksum()); want != have {
+			t.Fatalf("%s: want checksum %s, have %s", tt.Name, want, have)
+		}
+	}
+
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf)
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if have := w.Checksum(); have != nil {
+		t.Fatalf("want no checksum, have %x", have)
+	}
+}
+
+func TestShardedWriterWithChecksum(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+	}
+	for i := 1; i <= 3; i++ {
+		cw.articles = append(cw.articles, &bmecat12.Article{SupplierAID: fmt.Sprintf("%d000", i)})
+	}
+	var files []*bytes.Buffer
+	create := func(index int) (io.WriteCloser, error) {
+		buf := &bytes.Buffer{}
+		files = append(files, buf)
+		return nopCloser{buf}, nil
+	}
+	s := bmecat12.NewShardedWriter(create,
+		bmecat12.WithShardMaxArticles(2),
+		bmecat12.WithWriterOptions(bmecat12.WithChecksum(sha256.New)),
+	)
+	if err := s.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	checksums := s.Checksums()
+	if want, have := len(files), len(checksums); want != have {
+		t.Fatalf("want %d checksums, have %d", want, have)
+	}
+	for i, f := range files {
+		if want, have := fmt.Sprintf("%x", sha256.Sum256(f.Bytes())), fmt.Sprintf("%x", checksums[i]); want != have {
+			t.Fatalf("file %d: want checksum %s, have %s", i, want, have)
+		}
+	}
+}
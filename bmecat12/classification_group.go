@@ -7,21 +7,98 @@ import (
 type ClassificationSystem struct {
 	XMLName xml.Name `xml:"CLASSIFICATION_SYSTEM"`
 
-	Name        string                           `xml:"CLASSIFICATION_SYSTEM_NAME"`
-	FullName    string                           `xml:"CLASSIFICATION_SYSTEM_FULLNAME,omitempty"`
-	Version     string                           `xml:"CLASSIFICATION_SYSTEM_VERSION,omitempty"`
-	Description string                           `xml:"CLASSIFICATION_SYSTEM_DESCR,omitempty"`
-	Levels      int                              `xml:"CLASSIFICATION_SYSTEM_LEVELS,omitempty"`
-	LevelNames  []*ClassificationSystemLevelName `xml:"CLASSIFICATION_SYSTEM_LEVEL_NAMES,omitempty"`
+	Name        string                         `xml:"CLASSIFICATION_SYSTEM_NAME"`
+	FullName    string                         `xml:"CLASSIFICATION_SYSTEM_FULLNAME,omitempty"`
+	Version     string                         `xml:"CLASSIFICATION_SYSTEM_VERSION,omitempty"`
+	Description string                         `xml:"CLASSIFICATION_SYSTEM_DESCR,omitempty"`
+	Levels      int                            `xml:"CLASSIFICATION_SYSTEM_LEVELS,omitempty"`
+	LevelNames  ClassificationSystemLevelNames `xml:"CLASSIFICATION_SYSTEM_LEVEL_NAMES,omitempty"`
 	// ALLOWED_VALUES
 	// UNITS
-	// CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES
-	Groups []*ClassificationGroup `xml:"CLASSIFICATION_GROUPS>CLASSIFICATION_GROUP,omitempty"`
+	FeatureTemplates ClassificationFeatureTemplates `xml:"CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES,omitempty"`
+	Groups           []*ClassificationGroup         `xml:"CLASSIFICATION_GROUPS>CLASSIFICATION_GROUP,omitempty"`
 }
 
-// IsBlank returns true if there are no groups in the classification system.
+// IsBlank returns true if there are no groups and no feature templates
+// in the classification system.
 func (cs *ClassificationSystem) IsBlank() bool {
-	return cs == nil || len(cs.Groups) == 0
+	return cs == nil || (len(cs.Groups) == 0 && len(cs.FeatureTemplates) == 0)
+}
+
+// ClassificationFeatureTemplates represents the CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES
+// element, i.e. a list of CLASSIFICATION_SYSTEM_FEATURE_TEMPLATE elements.
+type ClassificationFeatureTemplates []*ClassificationFeatureTemplate
+
+// MarshalXML encodes the feature templates, wrapped into the start element.
+func (x ClassificationFeatureTemplates) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, ft := range x {
+		if err := e.Encode(ft); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes the feature templates.
+func (x *ClassificationFeatureTemplates) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		FeatureTemplates []*ClassificationFeatureTemplate `xml:"CLASSIFICATION_SYSTEM_FEATURE_TEMPLATE"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*x = append(*x, v.FeatureTemplates...)
+	return nil
+}
+
+// ClassificationFeatureTemplate represents a CLASSIFICATION_SYSTEM_FEATURE_
+// TEMPLATE element, i.e. a feature defined once for the whole
+// classification system, as in ETIM-style systems. AllowedValueIDs
+// reference the ALLOWED_VALUE elements of the classification system.
+type ClassificationFeatureTemplate struct {
+	XMLName xml.Name `xml:"CLASSIFICATION_SYSTEM_FEATURE_TEMPLATE"`
+
+	ID              string   `xml:"FT_ID"`
+	Name            string   `xml:"FT_NAME"`
+	ShortName       string   `xml:"FT_SHORTNAME,omitempty"`
+	Description     string   `xml:"FT_DESCR,omitempty"`
+	Version         string   `xml:"FT_VERSION,omitempty"`
+	GroupID         string   `xml:"FT_GROUP_ID,omitempty"`
+	Unit            string   `xml:"FT_UNIT,omitempty"`
+	Order           int      `xml:"FT_ORDER,omitempty"`
+	AllowedValueIDs []string `xml:"FT_ALLOWED_VALUES>ALLOWED_VALUE_IDREF,omitempty"`
+}
+
+// ClassificationSystemLevelNames represents the CLASSIFICATION_SYSTEM_LEVEL_NAMES
+// element, i.e. a list of CLASSIFICATION_SYSTEM_LEVEL_NAME elements.
+type ClassificationSystemLevelNames []*ClassificationSystemLevelName
+
+// MarshalXML encodes the level names, wrapped into the start element.
+func (x ClassificationSystemLevelNames) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, ln := range x {
+		if err := e.Encode(ln); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// UnmarshalXML decodes the level names.
+func (x *ClassificationSystemLevelNames) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	var v struct {
+		LevelNames []*ClassificationSystemLevelName `xml:"CLASSIFICATION_SYSTEM_LEVEL_NAME"`
+	}
+	if err := d.DecodeElement(&v, &start); err != nil {
+		return err
+	}
+	*x = append(*x, v.LevelNames...)
+	return nil
 }
 
 type ClassificationSystemLevelName struct {
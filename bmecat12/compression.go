@@ -0,0 +1,165 @@
+package bmecat12
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/internal"
+)
+
+// Compression is the compression of the input of a Reader.
+type Compression int
+
+const (
+	// CompressionAuto detects gzip-compressed input by its magic bytes.
+	// This is the default.
+	CompressionAuto Compression = iota
+	// CompressionNone reads the input as is.
+	CompressionNone
+	// CompressionGzip decompresses the input with gzip.
+	CompressionGzip
+)
+
+// gzipMagic are the first bytes of a gzip file.
+var gzipMagic = []byte{0x1f, 0x8b}
+
+// WithCompression specifies the compression of the input. By default,
+// the Reader detects gzip-compressed input, e.g. a .xml.gz file, and
+// decompresses it transparently. Progress is reported in terms of the
+// compressed input then, i.e. the offset passed to WithReaderProgress
+// is the number of compressed bytes read so far. The offsets passed to
+// handlers and in errors refer to the decompressed XML.
+func WithCompression(c Compression) ReaderOption {
+	return func(r *Reader) {
+		r.compression = c
+	}
+}
+
+// readPrefix reads up to n bytes from the start of rs, and rewinds rs to
+// the start.
+func readPrefix(rs io.ReadSeeker, n int) ([]byte, error) {
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	prefix := make([]byte, n)
+	n, err := io.ReadFull(rs, prefix)
+	if err != nil && err != io.EOF && err != io.ErrUnexpectedEOF {
+		return nil, err
+	}
+	if _, err := rs.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	return prefix[:n], nil
+}
+
+// decodeInput returns the XML of src as encoding/xml expects it, i.e.
+// decompressed if compressed is true, and converted from UTF-16 to UTF-8
+// if it starts with a byte order mark. prefix are the first bytes of src,
+// see readPrefix. compressedOffset is updated as with newGzipReader.
+func decodeInput(src io.ReadSeeker, prefix []byte, compressed bool, compressedOffset *int64) (io.ReadSeeker, error) {
+	if compressed {
+		gz, err := newGzipReader(src, compressedOffset)
+		if err != nil {
+			return nil, err
+		}
+		if prefix, err = readPrefix(gz, 4); err != nil {
+			return nil, &InputError{Op: "decompress input", Err: err}
+		}
+		src = gz
+	}
+	if internal.NeedsBOMDecoding(prefix) {
+		// Convert UTF-16 to UTF-8, which encoding/xml expects
+		utf16 := src
+		bom, err := newRewindReader(func() (io.Reader, error) {
+			if _, err := utf16.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return internal.DecodeBOM(utf16), nil
+		}, -1)
+		if err != nil {
+			return nil, &InputError{Op: "read input", Err: err}
+		}
+		src = bom
+	}
+	return src, nil
+}
+
+// detectInput returns the XML of r like the Reader does by default, see
+// decodeInput, for functions that read a catalog without a Reader.
+func detectInput(r io.ReadSeeker) (io.ReadSeeker, error) {
+	prefix, err := readPrefix(r, 4)
+	if err != nil {
+		return nil, &InputError{Op: "read input", Err: err}
+	}
+	var compressedOffset int64
+	return decodeInput(r, prefix, bytes.HasPrefix(prefix, gzipMagic), &compressedOffset)
+}
+
+// newGzipReader returns a reader for the decompressed content of src,
+// which is seekable by means of a rewindReader. compressed is updated
+// with the number of bytes read from src.
+func newGzipReader(src io.ReadSeeker, compressed *int64) (*rewindReader, error) {
+	var zr *gzip.Reader
+	open := func() (io.Reader, error) {
+		if _, err := src.Seek(0, io.SeekStart); err != nil {
+			return nil, err
+		}
+		*compressed = 0
+		cr := &countingReader{r: src, n: compressed}
+		if zr == nil {
+			var err error
+			if zr, err = gzip.NewReader(cr); err != nil {
+				return nil, &InputError{Op: "decompress input", Err: err}
+			}
+			return zr, nil
+		}
+		if err := zr.Reset(cr); err != nil {
+			return nil, &InputError{Op: "decompress input", Err: err}
+		}
+		return zr, nil
+	}
+	return newRewindReader(open, -1)
+}
+
+// countingReader counts the bytes read from r.
+type countingReader struct {
+	r io.Reader
+	n *int64
+}
+
+func (c *countingReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	*c.n += int64(n)
+	return n, err
+}
+
+// WithGzip makes the Writer compress the output with gzip at the given
+// level, e.g. gzip.DefaultCompression or gzip.BestCompression, to write
+// a .xml.gz file directly. The Writer flushes and closes the compressed
+// stream at the end of Do; the underlying io.Writer is not closed. The
+// progress reported with WithWriterProgressInfo refers to the
+// uncompressed XML, whereas the checksum of WithChecksum is computed
+// over the compressed output.
+func WithGzip(level int) WriterOption {
+	return func(w *Writer) {
+		w.gzip = true
+		w.gzipLevel = level
+	}
+}
+
+// gzipWriter returns out, compressed with gzip if WithGzip is set. The
+// returned gzip.Writer must be closed to flush it; it is nil if the
+// output is not compressed.
+func (w *Writer) gzipWriter(out io.Writer) (*gzip.Writer, error) {
+	if !w.gzip {
+		return nil, nil
+	}
+	zw, err := gzip.NewWriterLevel(out, w.gzipLevel)
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat/writer: unable to compress output")
+	}
+	return zw, nil
+}
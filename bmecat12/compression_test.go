@@ -0,0 +1,139 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func gzipped(t *testing.T, data []byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	if _, err := zw.Write(data); err != nil {
+		t.Fatal(err)
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadGzip(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzipped(t, data)
+
+	var maxOffset int64
+	h := &testHandler{}
+	r := bmecat12.NewReader(bytes.NewReader(compressed),
+		bmecat12.WithReaderProgress(func(pass int, offset int64) {
+			if offset > maxOffset {
+				maxOffset = offset
+			}
+		}),
+	)
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	var aids []string
+	for _, a := range h.articles {
+		aids = append(aids, a.SupplierAID)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if maxOffset > int64(len(compressed)) {
+		t.Fatalf("want progress offsets <= %d, have %d", len(compressed), maxOffset)
+	}
+}
+
+func TestReadGzipWithCheckpoint(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	compressed := gzipped(t, data)
+	cp := crashAfterFirstArticle(t, bytes.NewReader(compressed))
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(compressed), bmecat12.WithCheckpoint(cp)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := "2000", h.articles[0].SupplierAID; want != have {
+		t.Fatalf("want SupplierAID=%q, have %q", want, have)
+	}
+}
+
+func TestReadWithCompressionNone(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bmecat12.NewReader(bytes.NewReader(gzipped(t, data)), bmecat12.WithCompression(bmecat12.CompressionNone))
+	if err := r.Do(context.Background(), &testHandler{}); err == nil {
+		t.Fatal("want error reading compressed input as XML")
+	}
+}
+
+func TestWriteGzip(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: []*bmecat12.Article{{SupplierAID: "1000"}, {SupplierAID: "2000"}},
+	}
+	var plain bytes.Buffer
+	if err := bmecat12.NewWriter(&plain).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf, bmecat12.WithGzip(gzip.BestCompression))
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := gzip.NewReader(bytes.NewReader(buf.Bytes()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := ioutil.ReadAll(zr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := plain.String(), string(data); want != have {
+		t.Fatalf("want decompressed output\n%s\nhave\n%s", want, have)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+}
+
+func TestWriteGzipInvalidLevel(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+	}
+	var buf bytes.Buffer
+	err := bmecat12.NewWriter(&buf, bmecat12.WithGzip(42)).Do(context.Background(), cw)
+	if err == nil {
+		t.Fatal("want error for invalid compression level")
+	}
+}
@@ -0,0 +1,159 @@
+package bmecat12
+
+import (
+	"bytes"
+	"encoding/xml"
+	"sync"
+	"time"
+)
+
+// WithConcurrency decodes articles on n goroutines. The Reader extracts
+// the raw XML of each ARTICLE element and passes it to the workers; the
+// handler is still called from a single goroutine. By default, articles
+// are passed to the handler in the order of the file; see
+// WithUnorderedArticles. Values of n less than 2 disable concurrency.
+func WithConcurrency(n int) ReaderOption {
+	return func(r *Reader) {
+		r.concurrency = n
+	}
+}
+
+// WithUnorderedArticles passes articles to the handler as soon as they
+// are decoded when used with WithConcurrency, which may differ from the
+// order of the file. This avoids waiting for large articles.
+func WithUnorderedArticles() ReaderOption {
+	return func(r *Reader) {
+		r.unordered = true
+	}
+}
+
+// articleJob is an ARTICLE element to decode.
+type articleJob struct {
+	seq    int
+	index  int // 1-based position of the article in the file
+	raw    []byte
+	offset int64
+	end    int64
+	line   int
+	column int
+}
+
+// articleResult is a decoded ARTICLE element.
+type articleResult struct {
+	*articleJob
+	article *Article
+	err     error
+	// took is the time it took to decode the article.
+	took time.Duration
+}
+
+// articlePool decodes articles on a number of workers.
+type articlePool struct {
+	jobs      chan *articleJob
+	results   chan *articleResult
+	closeOnce sync.Once
+
+	window   int // maximum number of jobs in flight
+	pending  int // number of jobs in flight
+	seq      int // sequence number of the next job
+	ordered  bool
+	next     int // sequence number of the next result to deliver, if ordered
+	buffered map[int]*articleResult
+}
+
+// newArticlePool starts the workers.
+func (r *Reader) newArticlePool() *articlePool {
+	window := 2 * r.concurrency
+	p := &articlePool{
+		// The buffers can hold all jobs in flight, so neither the
+		// Reader nor the workers block on them
+		jobs:     make(chan *articleJob, window),
+		results:  make(chan *articleResult, window),
+		window:   window,
+		ordered:  !r.unordered,
+		buffered: make(map[int]*articleResult),
+	}
+	for i := 0; i < r.concurrency; i++ {
+		go func() {
+			for job := range p.jobs {
+				a := r.newArticle()
+				started := time.Now()
+				err := decodeRawArticle(job.raw, a, r.entities, r.skipSections, r.fastDecoder)
+				took := time.Since(started)
+				if r.rawArticles {
+					a.RawXML = job.raw
+				}
+				p.results <- &articleResult{articleJob: job, article: a, err: err, took: took}
+			}
+		}()
+	}
+	return p
+}
+
+// submit passes the job to the workers. If the maximum number of jobs
+// is in flight, it first waits for a result and delivers it.
+func (p *articlePool) submit(job *articleJob, deliver func(*articleResult) error) error {
+	if p.pending >= p.window {
+		if err := p.receive(deliver); err != nil {
+			return err
+		}
+	}
+	job.seq = p.seq
+	p.seq++
+	p.pending++
+	p.jobs <- job
+	return nil
+}
+
+// receive waits for the next result and delivers it, plus the buffered
+// results that follow it if ordered.
+func (p *articlePool) receive(deliver func(*articleResult) error) error {
+	res := <-p.results
+	p.pending--
+	if !p.ordered {
+		return deliver(res)
+	}
+	p.buffered[res.seq] = res
+	for {
+		res, found := p.buffered[p.next]
+		if !found {
+			return nil
+		}
+		delete(p.buffered, p.next)
+		p.next++
+		if err := deliver(res); err != nil {
+			return err
+		}
+	}
+}
+
+// drain waits for all jobs in flight and delivers their results.
+func (p *articlePool) drain(deliver func(*articleResult) error) error {
+	for p.pending > 0 {
+		if err := p.receive(deliver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops the workers.
+func (p *articlePool) close() {
+	p.closeOnce.Do(func() { close(p.jobs) })
+}
+
+// decodeRawArticle decodes the raw XML of an ARTICLE element into a.
+func decodeRawArticle(raw []byte, a *Article, entities map[string]string, skip SkipSection, fast bool) error {
+	// The raw XML is already converted to UTF-8, so no CharsetReader is needed
+	dec := xml.NewDecoder(bytes.NewReader(raw))
+	dec.Entity = entities
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			return decodeArticle(dec, &se, a, skip, fast)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// concurrencyCatalog returns a catalog with n articles.
+func concurrencyCatalog(t *testing.T, n int) []byte {
+	t.Helper()
+	var articles []*bmecat12.Article
+	for i := 0; i < n; i++ {
+		articles = append(articles, &bmecat12.Article{
+			SupplierAID: fmt.Sprintf("%05d", i),
+			Details: &bmecat12.ArticleDetails{
+				DescriptionShort: strings.Repeat("Article ", 1+i%17),
+			},
+		})
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: articles,
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestReadWithConcurrency(t *testing.T) {
+	doc := concurrencyCatalog(t, 500)
+
+	read := func(options ...bmecat12.ReaderOption) []string {
+		var aids []string
+		var complete bool
+		h := bmecat12.HandlerFuncs{
+			OnArticle: func(a *bmecat12.Article) error {
+				if a.Details == nil || !strings.HasPrefix(a.Details.DescriptionShort, "Article") {
+					t.Fatalf("article %s: not fully decoded", a.SupplierAID)
+				}
+				aids = append(aids, a.SupplierAID)
+				return nil
+			},
+			OnComplete: func() {
+				complete = true
+			},
+		}
+		if err := bmecat12.NewReader(bytes.NewReader(doc), options...).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if !complete {
+			t.Fatal("expected OnComplete to be called")
+		}
+		return aids
+	}
+
+	want := read()
+	if len(want) != 500 {
+		t.Fatalf("want %d articles, have %d", 500, len(want))
+	}
+
+	ordered := read(bmecat12.WithConcurrency(4))
+	if want, have := strings.Join(want, ","), strings.Join(ordered, ","); want != have {
+		t.Fatal("expected articles in the order of the file")
+	}
+
+	unordered := read(bmecat12.WithConcurrency(4), bmecat12.WithUnorderedArticles())
+	sort.Strings(unordered)
+	if want, have := strings.Join(want, ","), strings.Join(unordered, ","); want != have {
+		t.Fatal("expected all articles to be read")
+	}
+
+	limited := read(bmecat12.WithConcurrency(4), bmecat12.WithMaxArticles(10))
+	if want, have := strings.Join(want[:10], ","), strings.Join(limited, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+}
+
+func TestReadWithConcurrencyContinueOnError(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+
+	var aids []string
+	var errs int
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			aids = append(aids, a.SupplierAID)
+			return nil
+		},
+	}
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithConcurrency(2),
+		bmecat12.WithContinueOnError(func(err error, offset int64, raw []byte) bool {
+			errs++
+			return true
+		}),
+	)
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1000,4000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if want, have := 2, errs; want != have {
+		t.Fatalf("want %d errors, have %d", want, have)
+	}
+}
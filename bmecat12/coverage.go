@@ -0,0 +1,321 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// ElementCoverage is an element or attribute found in a BMEcat file, see
+// Coverage.
+type ElementCoverage struct {
+	// Path of the element from the root, e.g. "BMECAT/T_NEW_CATALOG/ARTICLE/
+	// ARTICLE_DETAILS/EAN". The path of an attribute ends in "@" and the
+	// attribute name, e.g. "BMECAT/T_NEW_CATALOG/ARTICLE@mode".
+	Path string `json:"path"`
+	// Count is the number of occurrences in the file.
+	Count int `json:"count"`
+	// Mapped is true if the Reader decodes the element or attribute, and
+	// false if it silently ignores it.
+	Mapped bool `json:"mapped"`
+	// InSpec is true if the element or attribute is declared in the
+	// BMEcat 1.2 DTD.
+	InSpec bool `json:"in_spec"`
+}
+
+// CoverageReport lists the elements and attributes of a BMEcat file and
+// whether the package maps them, see Coverage.
+type CoverageReport struct {
+	// Elements are the elements and attributes found, sorted by path.
+	Elements []*ElementCoverage `json:"elements"`
+}
+
+// Unmapped returns the elements and attributes ignored by the Reader.
+func (c *CoverageReport) Unmapped() []*ElementCoverage {
+	var unmapped []*ElementCoverage
+	for _, e := range c.Elements {
+		if !e.Mapped {
+			unmapped = append(unmapped, e)
+		}
+	}
+	return unmapped
+}
+
+// WriteText writes the report as a table, one element or attribute per
+// line, followed by a summary.
+func (c *CoverageReport) WriteText(w io.Writer) error {
+	var mapped int
+	for _, e := range c.Elements {
+		status := "mapped"
+		if !e.Mapped {
+			status = "IGNORED"
+		} else {
+			mapped++
+		}
+		if !e.InSpec {
+			status += " (not in spec)"
+		}
+		if _, err := fmt.Fprintf(w, "%-22s %9d  %s\n", status, e.Count, e.Path); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "\n%d of %d elements and attributes mapped\n", mapped, len(c.Elements))
+	return err
+}
+
+// Coverage reports which elements and attributes of the BMEcat file in r
+// are mapped by the package, i.e. decoded by the Reader into the model,
+// and which are silently ignored. The mapping is derived from the xml
+// struct tags of the model, e.g. Article, so it stays in sync with it.
+// Everything below an element with a custom decoding, e.g. USER_DEFINED_
+// EXTENSIONS, counts as mapped. Only WithCharsetReader and WithEntityMap
+// are applied of the options.
+func Coverage(r io.Reader, options ...ReaderOption) (*CoverageReport, error) {
+	dec := newValidationDecoder(r, options)
+	dec.Strict = false
+
+	counts := make(map[string]*ElementCoverage)
+	count := func(path string, mapped, inSpec bool) {
+		e, ok := counts[path]
+		if !ok {
+			e = &ElementCoverage{Path: path, Mapped: mapped, InSpec: inSpec}
+			counts[path] = e
+		}
+		e.Count++
+	}
+
+	type frame struct {
+		path string
+		node *coverageNode // nil if unmapped
+	}
+	var stack []frame
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bmecat: unable to compute coverage: %w", err)
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			var parent *coverageNode
+			path := name
+			if len(stack) == 0 {
+				parent = coverageRoot
+			} else {
+				top := stack[len(stack)-1]
+				parent, path = top.node, top.path+"/"+name
+			}
+			node := parent.child(name)
+			count(path, node != nil, dtdDeclaresElement(name))
+			for _, attr := range t.Attr {
+				if attr.Name.Space == "xmlns" || attr.Name.Local == "xmlns" {
+					continue
+				}
+				attrName := attr.Name.Local
+				if attr.Name.Space == "xml" || attr.Name.Space == "http://www.w3.org/XML/1998/namespace" {
+					attrName = "xml:" + attrName
+				}
+				count(path+"@"+attrName, node.hasAttr(attrName), dtdDeclaresAttr(name, attrName))
+			}
+			stack = append(stack, frame{path: path, node: node})
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		}
+	}
+
+	report := &CoverageReport{}
+	for _, e := range counts {
+		report.Elements = append(report.Elements, e)
+	}
+	sort.Slice(report.Elements, func(i, j int) bool {
+		return report.Elements[i].Path < report.Elements[j].Path
+	})
+	return report, nil
+}
+
+// coverageNode is an element that the Reader decodes, with its children
+// and attributes.
+type coverageNode struct {
+	children map[string]*coverageNode
+	attrs    map[string]bool
+	// any is true if everything below the element is decoded.
+	any bool
+}
+
+func newCoverageNode() *coverageNode {
+	return &coverageNode{
+		children: make(map[string]*coverageNode),
+		attrs:    make(map[string]bool),
+	}
+}
+
+// child returns the node of the child element with the given name, or
+// nil if it is not decoded. It may be called on a nil node.
+func (n *coverageNode) child(name string) *coverageNode {
+	if n == nil {
+		return nil
+	}
+	if n.any {
+		return n
+	}
+	return n.children[name]
+}
+
+// hasAttr returns true if the attribute is decoded. It may be called on
+// a nil node.
+func (n *coverageNode) hasAttr(name string) bool {
+	return n != nil && (n.any || n.attrs[name])
+}
+
+// coverageRoot is the BMECAT element, i.e. the registry of the elements
+// decoded by the Reader. It mirrors the elements the Reader looks for
+// in Do, with the model types they are decoded into.
+var coverageRoot = func() *coverageNode {
+	types := make(map[reflect.Type]*coverageNode)
+
+	catalogGroupSystem := newCoverageNode()
+	catalogGroupSystem.children["CATALOG_STRUCTURE"] = coverageOf(reflect.TypeOf(CatalogGroup{}), types)
+
+	articleToCatalogGroupMap := coverageOf(reflect.TypeOf(ArticleToCatalogGroupMap{}), types)
+	tx := newCoverageNode()
+	tx.attrs["prev_version"] = true
+	tx.children["FEATURE_SYSTEM"] = coverageOf(reflect.TypeOf(FeatureSystem{}), types)
+	tx.children["CLASSIFICATION_SYSTEM"] = coverageOf(reflect.TypeOf(ClassificationSystem{}), types)
+	tx.children["CATALOG_GROUP_SYSTEM"] = catalogGroupSystem
+	tx.children["ARTICLE"] = coverageOf(reflect.TypeOf(Article{}), types)
+	tx.children["ARTICLE_TO_CATALOGGROUP_MAP"] = articleToCatalogGroupMap
+
+	bmecat := newCoverageNode()
+	bmecat.children["HEADER"] = coverageOf(reflect.TypeOf(Header{}), types)
+	for _, name := range []string{"T_NEW_CATALOG", "T_UPDATE_PRODUCTS", "T_UPDATE_PRICES"} {
+		bmecat.children[name] = tx
+	}
+
+	root := newCoverageNode()
+	root.children["BMECAT"] = bmecat
+	return root
+}()
+
+var xmlUnmarshalerType = reflect.TypeOf((*xml.Unmarshaler)(nil)).Elem()
+
+// coverageOf returns the node for an element decoded into a value of
+// type t, as derived from its xml struct tags. types caches the nodes of
+// struct types, which makes recursive types terminate.
+func coverageOf(t reflect.Type, types map[reflect.Type]*coverageNode) *coverageNode {
+	for t.Kind() == reflect.Ptr || t.Kind() == reflect.Slice {
+		if reflect.PtrTo(t).Implements(xmlUnmarshalerType) {
+			break
+		}
+		t = t.Elem()
+	}
+	if n, ok := types[t]; ok {
+		return n
+	}
+	n := newCoverageNode()
+	types[t] = n
+	if t.Implements(xmlUnmarshalerType) || reflect.PtrTo(t).Implements(xmlUnmarshalerType) {
+		n.any = true
+		return n
+	}
+	if t.Kind() != reflect.Struct {
+		return n
+	}
+	addCoverageFields(n, t, types)
+	return n
+}
+
+// addCoverageFields adds the fields of struct type t to n.
+func addCoverageFields(n *coverageNode, t reflect.Type, types map[reflect.Type]*coverageNode) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" && !f.Anonymous {
+			continue // unexported
+		}
+		tag := f.Tag.Get("xml")
+		if tag == "-" || f.Name == "XMLName" {
+			continue
+		}
+		name, flags := tag, ""
+		if i := strings.Index(tag, ","); i >= 0 {
+			name, flags = tag[:i], tag[i+1:]
+		}
+		hasFlag := func(flag string) bool {
+			for _, f := range strings.Split(flags, ",") {
+				if f == flag {
+					return true
+				}
+			}
+			return false
+		}
+		switch {
+		case hasFlag("attr"):
+			if name == "" {
+				name = f.Name
+			}
+			n.attrs[name] = true
+			continue
+		case hasFlag("innerxml"), hasFlag("any"):
+			n.any = true
+			continue
+		case hasFlag("chardata"), hasFlag("cdata"), hasFlag("comment"):
+			continue
+		}
+		if f.Anonymous && name == "" {
+			ft := f.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				addCoverageFields(n, ft, types)
+				continue
+			}
+		}
+		if name == "" {
+			name = f.Name
+		}
+		// Nested elements, e.g. "CLASSIFICATION_GROUPS>CLASSIFICATION_GROUP"
+		parent := n
+		names := strings.Split(name, ">")
+		for _, name := range names[:len(names)-1] {
+			child, ok := parent.children[name]
+			if !ok {
+				child = newCoverageNode()
+				parent.children[name] = child
+			}
+			parent = child
+		}
+		parent.children[names[len(names)-1]] = coverageOf(f.Type, types)
+	}
+}
+
+// dtdDeclaresElement returns true if any BMEcat 1.2 DTD declares the
+// element.
+func dtdDeclaresElement(name string) bool {
+	for _, d := range []*DTD{newCatalogDTD, updateProductsDTD, updatePricesDTD} {
+		if _, ok := d.elements[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// dtdDeclaresAttr returns true if any BMEcat 1.2 DTD declares the
+// attribute of the element.
+func dtdDeclaresAttr(element, attr string) bool {
+	for _, d := range []*DTD{newCatalogDTD, updateProductsDTD, updatePricesDTD} {
+		for _, a := range d.attlists[element] {
+			if a.name == attr {
+				return true
+			}
+		}
+	}
+	return false
+}
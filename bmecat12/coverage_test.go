@@ -0,0 +1,56 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestCoverage(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	data = bytes.Replace(data,
+		[]byte("<SUPPLIER_AID>1000</SUPPLIER_AID>"),
+		[]byte("<SUPPLIER_AID>1000</SUPPLIER_AID><X_CUSTOM>1</X_CUSTOM>"), 1)
+	report, err := bmecat12.Coverage(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	byPath := make(map[string]*bmecat12.ElementCoverage)
+	for _, e := range report.Elements {
+		byPath[e.Path] = e
+	}
+	aid := byPath["BMECAT/T_UPDATE_PRODUCTS/ARTICLE/SUPPLIER_AID"]
+	if aid == nil || !aid.Mapped || !aid.InSpec || aid.Count != 2 {
+		t.Fatalf("want SUPPLIER_AID mapped, in spec, and found twice, have %+v", aid)
+	}
+	mode := byPath["BMECAT/T_UPDATE_PRODUCTS/ARTICLE@mode"]
+	if mode == nil || !mode.Mapped {
+		t.Fatalf("want ARTICLE@mode mapped, have %+v", mode)
+	}
+	var unmapped []string
+	for _, e := range report.Unmapped() {
+		unmapped = append(unmapped, e.Path)
+	}
+	if want, have := "BMECAT/T_UPDATE_PRODUCTS/ARTICLE/X_CUSTOM,BMECAT@version,BMECAT@xml:lang", strings.Join(unmapped, ","); want != have {
+		t.Fatalf("want unmapped %s, have %s", want, have)
+	}
+	if custom := byPath["BMECAT/T_UPDATE_PRODUCTS/ARTICLE/X_CUSTOM"]; custom.InSpec {
+		t.Fatal("want X_CUSTOM not in spec")
+	}
+
+	var buf bytes.Buffer
+	if err := report.WriteText(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "IGNORED (not in spec)          1  BMECAT/T_UPDATE_PRODUCTS/ARTICLE/X_CUSTOM\n"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("want text to contain %q, have:\n%s", want, buf.String())
+	}
+}
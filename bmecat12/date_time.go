@@ -1,6 +1,12 @@
 package bmecat12
 
-import "time"
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
 
 var (
 	DefaultStartDate time.Time
@@ -17,15 +23,143 @@ type DateTime struct {
 	DateString     string `xml:"DATE"`
 	TimeString     string `xml:"TIME,omitempty"`
 	TimeZoneString string `xml:"TIMEZONE,omitempty"`
+
+	// DateOnly specifies to write only DATE, without TIME and TIMEZONE,
+	// as some receivers reject times on e.g. agreement and validity dates.
+	DateOnly bool `xml:"-"`
+}
+
+// MarshalXML encodes dt with DATE, TIME, and TIMEZONE in the order of
+// the DTD, without TIME and TIMEZONE if DateOnly is set. It returns an
+// error wrapping ErrInvalidDateTime if dt is invalid, see Validate, so
+// the Writer fails instead of writing an invalid catalog.
+func (dt DateTime) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	if dt.DateOnly {
+		dt.TimeString, dt.TimeZoneString = "", ""
+	}
+	if err := dt.Validate(); err != nil {
+		return err
+	}
+	start.Attr = append(start.Attr, xml.Attr{Name: xml.Name{Local: "type"}, Value: dt.Type})
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	if err := e.EncodeElement(dt.DateString, xml.StartElement{Name: xml.Name{Local: "DATE"}}); err != nil {
+		return err
+	}
+	if dt.TimeString != "" {
+		if err := e.EncodeElement(dt.TimeString, xml.StartElement{Name: xml.Name{Local: "TIME"}}); err != nil {
+			return err
+		}
+	}
+	if dt.TimeZoneString != "" {
+		if err := e.EncodeElement(dt.TimeZoneString, xml.StartElement{Name: xml.Name{Local: "TIMEZONE"}}); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// Validate returns an error wrapping ErrInvalidDateTime if the type of
+// dt is not one of the DateTime constants, e.g. DateTimeValidStartDate,
+// if DATE is not formatted as YYYY-MM-DD, if TIME is not formatted as
+// hh:mm:ss, or if TIMEZONE is invalid.
+func (dt DateTime) Validate() error {
+	switch dt.Type {
+	case DateTimeGenerationDate,
+		DateTimeAgreementStartDate,
+		DateTimeAgreementEndDate,
+		DateTimeValidStartDate,
+		DateTimeValidEndDate:
+	default:
+		return fmt.Errorf("%w: type %q", ErrInvalidDateTime, dt.Type)
+	}
+	if _, err := time.Parse("2006-01-02", dt.DateString); err != nil {
+		return fmt.Errorf("%w: DATE %q", ErrInvalidDateTime, dt.DateString)
+	}
+	if dt.DateOnly {
+		return nil
+	}
+	if dt.TimeString != "" {
+		if _, err := time.Parse("15:04:05", dt.TimeString); err != nil {
+			return fmt.Errorf("%w: TIME %q", ErrInvalidDateTime, dt.TimeString)
+		}
+	}
+	if _, err := parseTimeZone(dt.TimeZoneString); err != nil {
+		return fmt.Errorf("%w: TIMEZONE %q", ErrInvalidDateTime, dt.TimeZoneString)
+	}
+	return nil
 }
 
+// Time returns the date and time of dt. TIMEZONE is either "Z" for UTC
+// or an offset like "+02:00", "-0400", or "+01", and the time is returned
+// in a fixed zone with that offset. Without TIMEZONE, or with a TIMEZONE
+// that cannot be parsed, e.g. "CET", the time is in UTC; see Validate to
+// check TIMEZONE. If DateOnly is set, Time returns midnight UTC of DATE.
 func (dt DateTime) Time() (time.Time, error) {
+	if dt.DateOnly {
+		dt.TimeString, dt.TimeZoneString = "", ""
+	}
 	ts := dt.TimeString
 	if ts == "" {
 		ts = "00:00:00"
 	}
-	// TODO time zone support
-	return time.Parse("2006-01-02 15:04:05", dt.DateString+" "+ts)
+	loc, err := parseTimeZone(dt.TimeZoneString)
+	if err != nil {
+		loc = time.UTC
+	}
+	return time.ParseInLocation("2006-01-02 15:04:05", dt.DateString+" "+ts, loc)
+}
+
+// parseTimeZone returns the location of a TIMEZONE element.
+func parseTimeZone(s string) (*time.Location, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "Z" || s == "z" {
+		return time.UTC, nil
+	}
+	if s[0] != '+' && s[0] != '-' {
+		return nil, fmt.Errorf("bmecat: invalid TIMEZONE %q", s)
+	}
+	offset := strings.Replace(s[1:], ":", "", 1)
+	if strings.Trim(offset, "0123456789") != "" {
+		return nil, fmt.Errorf("bmecat: invalid TIMEZONE %q", s)
+	}
+	var hh, mm string
+	switch len(offset) {
+	case 2:
+		hh, mm = offset, "00"
+	case 4:
+		hh, mm = offset[:2], offset[2:]
+	default:
+		return nil, fmt.Errorf("bmecat: invalid TIMEZONE %q", s)
+	}
+	h, err := strconv.Atoi(hh)
+	if err != nil || h > 14 {
+		return nil, fmt.Errorf("bmecat: invalid TIMEZONE %q", s)
+	}
+	m, err := strconv.Atoi(mm)
+	if err != nil || m > 59 {
+		return nil, fmt.Errorf("bmecat: invalid TIMEZONE %q", s)
+	}
+	secs := (h*60 + m) * 60
+	if s[0] == '-' {
+		secs = -secs
+	}
+	return time.FixedZone(s, secs), nil
+}
+
+// NewDate returns a DateTime of the given type with only the date of t,
+// i.e. it is written with DATE but without TIME and TIMEZONE. It returns
+// nil if t is zero.
+func NewDate(typ string, t time.Time) *DateTime {
+	if t.IsZero() {
+		return nil
+	}
+	return &DateTime{
+		Type:       typ,
+		DateString: t.Format("2006-01-02"),
+		DateOnly:   true,
+	}
 }
 
 func NewDateTime(typ string, dt time.Time) *DateTime {
@@ -1,6 +1,7 @@
 package bmecat12
 
 import (
+	"encoding/xml"
 	"testing"
 	"time"
 )
@@ -70,3 +71,118 @@ func TestNewDateTime(t *testing.T) {
 		}
 	}
 }
+
+func TestDateTimeTime(t *testing.T) {
+	tests := []struct {
+		TimeZone string
+		Offset   int
+	}{
+		{TimeZone: "", Offset: 0},
+		{TimeZone: "Z", Offset: 0},
+		{TimeZone: "+02:00", Offset: 2 * 3600},
+		{TimeZone: "-04:00", Offset: -4 * 3600},
+		{TimeZone: "+0530", Offset: 5*3600 + 30*60},
+		{TimeZone: "-01", Offset: -3600},
+	}
+	for _, tt := range tests {
+		dt := DateTime{DateString: "2017-08-01", TimeString: "09:12:59", TimeZoneString: tt.TimeZone}
+		have, err := dt.Time()
+		if err != nil {
+			t.Fatalf("%q: %v", tt.TimeZone, err)
+		}
+		want := time.Date(2017, 8, 1, 9, 12, 59, 0, time.UTC).Add(-time.Duration(tt.Offset) * time.Second)
+		if !want.Equal(have) {
+			t.Fatalf("%q: want %v, have %v", tt.TimeZone, want, have)
+		}
+		if _, offset := have.Zone(); offset != tt.Offset {
+			t.Fatalf("%q: want offset %d, have %d", tt.TimeZone, tt.Offset, offset)
+		}
+		if want, have := "09:12:59", have.Format("15:04:05"); want != have {
+			t.Fatalf("%q: want %s, have %s", tt.TimeZone, want, have)
+		}
+	}
+
+	// A TIMEZONE that cannot be parsed falls back to UTC
+	for _, tz := range []string{"CET", "+2:00", "+02:0", "+15:00", "+02:60", "2:00", "+-1:00"} {
+		dt := DateTime{DateString: "2017-08-01", TimeString: "09:12:59", TimeZoneString: tz}
+		have, err := dt.Time()
+		if err != nil {
+			t.Fatalf("%q: %v", tz, err)
+		}
+		if want := time.Date(2017, 8, 1, 9, 12, 59, 0, time.UTC); !want.Equal(have) {
+			t.Fatalf("%q: want %v, have %v", tz, want, have)
+		}
+		if err := dt.Validate(); err == nil {
+			t.Fatalf("%q: want Validate to fail, have nil", tz)
+		}
+	}
+}
+
+func TestArticlePriceDetailsValidDatesWithUnknownTimeZone(t *testing.T) {
+	apd := &ArticlePriceDetails{
+		Dates: []*DateTime{
+			{Type: DateTimeValidStartDate, DateString: "2020-01-01", TimeString: "00:00:00", TimeZoneString: "CET"},
+			{Type: DateTimeValidEndDate, DateString: "2020-12-31", TimeString: "23:59:59", TimeZoneString: "CET"},
+		},
+	}
+	if want, have := time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC), apd.ValidStartDate(); !want.Equal(have) {
+		t.Fatalf("want ValidStartDate=%v, have %v", want, have)
+	}
+	if want, have := time.Date(2020, 12, 31, 23, 59, 59, 0, time.UTC), apd.ValidEndDate(); !want.Equal(have) {
+		t.Fatalf("want ValidEndDate=%v, have %v", want, have)
+	}
+}
+
+func TestDateTimeRoundTrip(t *testing.T) {
+	nyc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, in := range []time.Time{
+		time.Date(2000, 10, 24, 20, 38, 0, 0, time.UTC),
+		time.Date(2017, 8, 1, 9, 12, 59, 0, nyc),
+	} {
+		have, err := NewDateTime(DateTimeValidStartDate, in).Time()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !in.Equal(have) {
+			t.Fatalf("want %v, have %v", in, have)
+		}
+	}
+}
+
+func TestNewDate(t *testing.T) {
+	if dt := NewDate(DateTimeValidStartDate, time.Time{}); dt != nil {
+		t.Fatalf("want nil, have %#v", dt)
+	}
+	dt := NewDate(DateTimeValidStartDate, time.Date(2017, 8, 1, 9, 12, 59, 0, time.UTC))
+	data, err := xml.Marshal(struct {
+		XMLName xml.Name  `xml:"ARTICLE_PRICE_DETAILS"`
+		Dates   *DateTime `xml:"DATETIME"`
+	}{Dates: dt})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `<ARTICLE_PRICE_DETAILS><DATETIME type="valid_start_date"><DATE>2017-08-01</DATE></DATETIME></ARTICLE_PRICE_DETAILS>`, string(data); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+
+	// DateOnly omits TIME and TIMEZONE even if they are set
+	dt = NewDateTime(DateTimeValidEndDate, time.Date(2017, 8, 1, 9, 12, 59, 0, time.UTC))
+	dt.DateOnly = true
+	data, err = xml.Marshal(dt)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `<DateTime type="valid_end_date"><DATE>2017-08-01</DATE></DateTime>`, string(data); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+	have, err := dt.Time()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2017, 8, 1, 0, 0, 0, 0, time.UTC); !want.Equal(have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+}
@@ -0,0 +1,280 @@
+package bmecat12
+
+import (
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Decimal is an exact decimal number for monetary amounts, e.g. of
+// PRICE_AMOUNT, which float64 cannot represent exactly. A Decimal is an
+// integer times a power of ten, so the number of decimal places of the
+// input is preserved: "1499.50" is read and written as 1499.50. The zero
+// value is 0.
+//
+// The integer is an int64, i.e. a Decimal holds up to 18 significant
+// digits, which is plenty for prices. ParseDecimal reports numbers with
+// more digits as out of range instead of rounding them, as well as
+// numbers whose exponent exceeds the digits by more than 18 places,
+// e.g. 1E2000000000.
+type Decimal struct {
+	unscaled int64
+	scale    int32
+}
+
+// maxDecimalDigits is the number of decimal digits of an int64 that
+// ParseDecimal accepts.
+const maxDecimalDigits = 18
+
+// NewDecimal returns the Decimal unscaled * 10^-scale, e.g.
+// NewDecimal(149950, 2) for 1499.50.
+func NewDecimal(unscaled int64, scale int32) Decimal {
+	return Decimal{unscaled: unscaled, scale: scale}
+}
+
+// ParseDecimal parses a decimal number, e.g. "1499.50", "-0.19", or
+// "1.5E3", as used in BMEcat. Errors are of type *strconv.NumError.
+func ParseDecimal(s string) (Decimal, error) {
+	d, ok, inRange := parseDecimal(s)
+	if !ok {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrSyntax}
+	}
+	if !inRange {
+		return Decimal{}, &strconv.NumError{Func: "ParseDecimal", Num: s, Err: strconv.ErrRange}
+	}
+	return d, nil
+}
+
+// MustParseDecimal is like ParseDecimal, but panics if s cannot be
+// parsed. It simplifies the initialization of Decimals from literals.
+func MustParseDecimal(s string) Decimal {
+	d, err := ParseDecimal(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// parseDecimal parses s, and reports whether s has a valid syntax and
+// whether the number is in range.
+func parseDecimal(s string) (d Decimal, ok bool, inRange bool) {
+	i := 0
+	neg := false
+	if i < len(s) && (s[i] == '+' || s[i] == '-') {
+		neg = s[i] == '-'
+		i++
+	}
+	var unscaled uint64
+	var scale int64
+	digits, overflow := 0, false
+	point := false
+	for ; i < len(s); i++ {
+		c := s[i]
+		if c == '.' && !point {
+			point = true
+			continue
+		}
+		if c < '0' || c > '9' {
+			break
+		}
+		digits++
+		if point {
+			scale++
+		}
+		if unscaled > (math.MaxInt64-uint64(c-'0'))/10 {
+			overflow = true
+			continue
+		}
+		unscaled = 10*unscaled + uint64(c-'0')
+	}
+	if digits == 0 {
+		return Decimal{}, false, false
+	}
+	if i < len(s) && (s[i] == 'e' || s[i] == 'E') {
+		exp, err := strconv.ParseInt(s[i+1:], 10, 32)
+		if err != nil {
+			return Decimal{}, false, false
+		}
+		scale -= exp
+		i = len(s)
+	}
+	if i < len(s) {
+		return Decimal{}, false, false
+	}
+	// Limit the exponent to the digits, so e.g. 1E2000000000 cannot make
+	// String or Rat allocate gigabytes
+	if limit := int64(maxDecimalDigits + digits); overflow || scale < -limit || scale > limit {
+		return Decimal{}, true, false
+	}
+	d = Decimal{unscaled: int64(unscaled), scale: int32(scale)}
+	if neg {
+		d.unscaled = -d.unscaled
+	}
+	return d, true, true
+}
+
+// String returns d with all decimal places, e.g. "1499.50".
+func (d Decimal) String() string {
+	digits := strconv.FormatInt(d.unscaled, 10)
+	if d.scale <= 0 {
+		if d.unscaled == 0 {
+			return "0"
+		}
+		return digits + strings.Repeat("0", int(-d.scale))
+	}
+	sign := ""
+	if d.unscaled < 0 {
+		sign, digits = "-", digits[1:]
+	}
+	if n := int(d.scale) + 1 - len(digits); n > 0 {
+		digits = strings.Repeat("0", n) + digits
+	}
+	point := len(digits) - int(d.scale)
+	return sign + digits[:point] + "." + digits[point:]
+}
+
+// Round returns d rounded half away from zero to the given number of
+// decimal places, e.g. 2 to round 1499.495 to 1499.50. If d has fewer
+// decimal places, it is returned unchanged.
+func (d Decimal) Round(places int32) Decimal {
+	if d.scale <= places {
+		return d
+	}
+	diff := int64(d.scale) - int64(places)
+	if diff > 18 {
+		// |d| < 10^(18-scale) <= 0.5 * 10^-places
+		return Decimal{scale: places}
+	}
+	pow := int64(1)
+	for i := int64(0); i < diff; i++ {
+		pow *= 10
+	}
+	q, r := d.unscaled/pow, d.unscaled%pow
+	if r < 0 {
+		r = -r
+	}
+	if 2*r >= pow {
+		if d.unscaled < 0 {
+			q--
+		} else {
+			q++
+		}
+	}
+	return Decimal{unscaled: q, scale: places}
+}
+
+// Float64 returns the float64 closest to d.
+func (d Decimal) Float64() float64 {
+	f, _ := d.Rat().Float64()
+	return f
+}
+
+// Rat returns d as a big.Rat, e.g. for calculations.
+func (d Decimal) Rat() *big.Rat {
+	r := new(big.Rat).SetInt64(d.unscaled)
+	if d.scale == 0 {
+		return r
+	}
+	scale := int64(d.scale)
+	if scale < 0 {
+		scale = -scale
+	}
+	pow := new(big.Int).Exp(big.NewInt(10), big.NewInt(scale), nil)
+	if d.scale < 0 {
+		return r.Mul(r, new(big.Rat).SetInt(pow))
+	}
+	return r.Quo(r, new(big.Rat).SetInt(pow))
+}
+
+// IsZero reports whether d is 0.
+func (d Decimal) IsZero() bool {
+	return d.unscaled == 0
+}
+
+// Sign returns -1, 0, or +1 if d is negative, 0, or positive.
+func (d Decimal) Sign() int {
+	switch {
+	case d.unscaled < 0:
+		return -1
+	case d.unscaled > 0:
+		return 1
+	}
+	return 0
+}
+
+// Cmp compares d and e by value, and returns -1 if d < e, 0 if d == e,
+// and +1 if d > e. Use Cmp rather than == to compare Decimals, as e.g.
+// 1.5 and 1.50 are different Decimals with the same value.
+func (d Decimal) Cmp(e Decimal) int {
+	if d.scale == e.scale {
+		switch {
+		case d.unscaled < e.unscaled:
+			return -1
+		case d.unscaled > e.unscaled:
+			return 1
+		}
+		return 0
+	}
+	return d.Rat().Cmp(e.Rat())
+}
+
+// MarshalText implements encoding.TextMarshaler, so a Decimal is written
+// as an XML element with the result of String.
+func (d Decimal) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler. Surrounding spaces
+// are ignored, and empty text is read as 0, as for a float64.
+func (d *Decimal) UnmarshalText(text []byte) error {
+	s := strings.TrimSpace(string(text))
+	if s == "" {
+		*d = Decimal{}
+		return nil
+	}
+	v, err := ParseDecimal(s)
+	if err != nil {
+		return err
+	}
+	*d = v
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler. A Decimal is written as a JSON
+// number with all decimal places.
+func (d Decimal) MarshalJSON() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+// UnmarshalJSON implements json.Unmarshaler. It accepts a JSON number or
+// a string with a number.
+func (d *Decimal) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+	if len(s) >= 2 && s[0] == '"' && s[len(s)-1] == '"' {
+		s = s[1 : len(s)-1]
+	}
+	return d.UnmarshalText([]byte(s))
+}
+
+// MarshalBinary implements encoding.BinaryMarshaler, e.g. for gob, which
+// the ArticleBuffer uses to spill articles.
+func (d Decimal) MarshalBinary() ([]byte, error) {
+	return d.MarshalText()
+}
+
+// UnmarshalBinary implements encoding.BinaryUnmarshaler.
+func (d *Decimal) UnmarshalBinary(data []byte) error {
+	return d.UnmarshalText(data)
+}
+
+// decimalFloat returns the value of d as a float64, or 0 if d is nil.
+func decimalFloat(d *Decimal) float64 {
+	if d == nil {
+		return 0
+	}
+	return d.Float64()
+}
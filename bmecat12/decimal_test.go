@@ -0,0 +1,172 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestParseDecimal(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  string
+		Float float64
+	}{
+		{Input: "0", Want: "0", Float: 0},
+		{Input: "1499.50", Want: "1499.50", Float: 1499.5},
+		{Input: "+0.19", Want: "0.19", Float: 0.19},
+		{Input: "-0.05", Want: "-0.05", Float: -0.05},
+		{Input: ".5", Want: "0.5", Float: 0.5},
+		{Input: "3.", Want: "3", Float: 3},
+		{Input: "1.5E3", Want: "1500", Float: 1500},
+		{Input: "25e-3", Want: "0.025", Float: 0.025},
+		{Input: "0.1", Want: "0.1", Float: 0.1},
+		{Input: "999999999999999999", Want: "999999999999999999", Float: 999999999999999999},
+		{Input: "1E19", Want: "10000000000000000000", Float: 1e19},
+		{Input: "5E-19", Want: "0.0000000000000000005", Float: 5e-19},
+	}
+	for _, tt := range tests {
+		d, err := bmecat12.ParseDecimal(tt.Input)
+		if err != nil {
+			t.Fatalf("%q: %v", tt.Input, err)
+		}
+		if want, have := tt.Want, d.String(); want != have {
+			t.Fatalf("%q: want %s, have %s", tt.Input, want, have)
+		}
+		if want, have := tt.Float, d.Float64(); want != have {
+			t.Fatalf("%q: want %v, have %v", tt.Input, want, have)
+		}
+	}
+}
+
+func TestParseDecimalErrors(t *testing.T) {
+	tests := []struct {
+		Input string
+		Want  error
+	}{
+		{Input: "", Want: strconv.ErrSyntax},
+		{Input: "-", Want: strconv.ErrSyntax},
+		{Input: "1.499,50", Want: strconv.ErrSyntax},
+		{Input: "1.2.3", Want: strconv.ErrSyntax},
+		{Input: "1e", Want: strconv.ErrSyntax},
+		{Input: "EUR 5", Want: strconv.ErrSyntax},
+		{Input: "10000000000000000000", Want: strconv.ErrRange},
+		{Input: "1E2000000000", Want: strconv.ErrRange},
+		{Input: "1E3000000", Want: strconv.ErrRange},
+		{Input: "1E-3000000", Want: strconv.ErrRange},
+		{Input: "0.5E-20", Want: strconv.ErrRange},
+	}
+	for _, tt := range tests {
+		_, err := bmecat12.ParseDecimal(tt.Input)
+		if !errors.Is(err, tt.Want) {
+			t.Fatalf("%q: want %v, have %v", tt.Input, tt.Want, err)
+		}
+	}
+}
+
+func TestDecimalCmp(t *testing.T) {
+	tests := []struct {
+		A, B string
+		Want int
+	}{
+		{A: "1.5", B: "1.50", Want: 0},
+		{A: "1.49", B: "1.5", Want: -1},
+		{A: "2", B: "1.99", Want: 1},
+		{A: "-1", B: "0", Want: -1},
+		{A: "1E2", B: "100.00", Want: 0},
+	}
+	for _, tt := range tests {
+		a, b := bmecat12.MustParseDecimal(tt.A), bmecat12.MustParseDecimal(tt.B)
+		if want, have := tt.Want, a.Cmp(b); want != have {
+			t.Fatalf("%s <=> %s: want %d, have %d", tt.A, tt.B, want, have)
+		}
+	}
+	if want, have := "1499.50", bmecat12.NewDecimal(149950, 2).String(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
+
+func TestDecimalJSON(t *testing.T) {
+	d := bmecat12.MustParseDecimal("1499.50")
+	data, err := json.Marshal(struct{ Amount bmecat12.Decimal }{d})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `{"Amount":1499.50}`, string(data); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+	var v struct{ Amount, Tax bmecat12.Decimal }
+	if err := json.Unmarshal([]byte(`{"Amount":1499.50,"Tax":"0.19"}`), &v); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1499.50 0.19", v.Amount.String()+" "+v.Tax.String(); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
+
+func TestWriteAndReadDecimalPrices(t *testing.T) {
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		articles: []*bmecat12.Article{
+			{
+				SupplierAID: "1000",
+				PriceDetails: []*bmecat12.ArticlePriceDetails{
+					{Prices: []*bmecat12.ArticlePrice{
+						{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("1499.50"), Tax: decimalPtr("0.190"), Factor: decimalPtr("1.00")},
+					}},
+				},
+			},
+		},
+	}
+	for _, direct := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, bmecat12.WithDirectEncoding(direct)).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, want := range []string{"<PRICE_AMOUNT>1499.50</PRICE_AMOUNT>", "<TAX>0.190</TAX>", "<PRICE_FACTOR>1.00</PRICE_FACTOR>"} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("direct=%v: want %s in\n%s", direct, want, out)
+			}
+		}
+
+		for _, options := range [][]bmecat12.ReaderOption{nil, {bmecat12.WithFastDecoder()}} {
+			var th testHandler
+			if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes()), options...).Do(context.Background(), &th); err != nil {
+				t.Fatal(err)
+			}
+			p := th.articles[0].PriceDetails[0].Prices[0]
+			if want, have := "1499.50 0.190 1.00", p.Amount.String()+" "+p.Tax.String()+" "+p.Factor.String(); want != have {
+				t.Fatalf("want %s, have %s", want, have)
+			}
+		}
+	}
+}
+
+func TestDecimalRound(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Places int32
+		Want   string
+	}{
+		{Input: "1499.495", Places: 2, Want: "1499.50"},
+		{Input: "1499.494", Places: 2, Want: "1499.49"},
+		{Input: "-0.125", Places: 2, Want: "-0.13"},
+		{Input: "-0.001", Places: 2, Want: "0.00"},
+		{Input: "2.5", Places: 0, Want: "3"},
+		{Input: "1.5", Places: 3, Want: "1.5"},
+		{Input: "0.0000000000000000000001", Places: 2, Want: "0.00"},
+	}
+	for _, tt := range tests {
+		if want, have := tt.Want, bmecat12.MustParseDecimal(tt.Input).Round(tt.Places).String(); want != have {
+			t.Fatalf("%s rounded to %d: want %s, have %s", tt.Input, tt.Places, want, have)
+		}
+	}
+}
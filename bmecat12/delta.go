@@ -0,0 +1,257 @@
+package bmecat12
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Hash returns a hex-encoded SHA-256 hash of the article. The hash covers
+// everything that is written for the article, including the catalog group
+// mapping, but not the mode attribute. Two articles with the same hash
+// are considered unchanged. DATETIME elements that would not pass
+// DateTime.Validate are hashed as they are, so articles that were read
+// can always be hashed.
+func (a *Article) Hash() (string, error) {
+	b := *a
+	b.Mode = ""
+	invalid := stripInvalidDates(&b)
+	data, err := xml.Marshal(&b)
+	if err != nil {
+		return "", err
+	}
+	h := sha256.New()
+	h.Write(data)
+	for _, id := range a.CatalogGroupIDs {
+		h.Write([]byte{0})
+		io.WriteString(h, id)
+	}
+	for _, dt := range invalid {
+		for _, s := range []string{dt.Type, dt.DateString, dt.TimeString, dt.TimeZoneString} {
+			h.Write([]byte{1})
+			io.WriteString(h, s)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// stripInvalidDates removes the DATETIME elements that cannot be
+// marshaled from the price details of a, which must be a copy, and
+// returns them. The price details are copied as necessary.
+func stripInvalidDates(a *Article) []*DateTime {
+	var invalid []*DateTime
+	var details []*ArticlePriceDetails
+	for i, pd := range a.PriceDetails {
+		if pd == nil {
+			continue
+		}
+		var valid []*DateTime
+		var stripped bool
+		for j, dt := range pd.Dates {
+			if dt == nil || dt.Validate() == nil {
+				if stripped {
+					valid = append(valid, dt)
+				}
+				continue
+			}
+			if !stripped {
+				stripped = true
+				valid = append([]*DateTime(nil), pd.Dates[:j]...)
+			}
+			invalid = append(invalid, dt)
+		}
+		if !stripped {
+			continue
+		}
+		if details == nil {
+			details = append([]*ArticlePriceDetails(nil), a.PriceDetails...)
+			a.PriceDetails = details
+		}
+		copied := *pd
+		copied.Dates = valid
+		details[i] = &copied
+	}
+	return invalid
+}
+
+// HashStore keeps the article hashes of a catalog delivery by SUPPLIER_AID.
+// It can be persisted with WriteTo and loaded with ReadHashStore, so the
+// next delivery can be compared against it without the old catalog file.
+// It also keeps the version of the delivery, see Version.
+type HashStore struct {
+	hashes  map[string]string
+	version int
+}
+
+// hashStoreVersionKey is the key of the version in the persisted store,
+// in place of a SUPPLIER_AID.
+const hashStoreVersionKey = "# VERSION"
+
+// NewHashStore creates a new, empty HashStore.
+func NewHashStore() *HashStore {
+	return &HashStore{hashes: make(map[string]string)}
+}
+
+// ReadHashStore reads a HashStore as written by WriteTo, i.e. one
+// SUPPLIER_AID and hash per line, separated by a tab, preceded by the
+// version, if any.
+func ReadHashStore(r io.Reader) (*HashStore, error) {
+	s := NewHashStore()
+	scanner := bufio.NewScanner(r)
+	line := 0
+	for scanner.Scan() {
+		line++
+		text := scanner.Text()
+		if text == "" {
+			continue
+		}
+		i := strings.LastIndexByte(text, '\t')
+		if i < 0 {
+			return nil, fmt.Errorf("bmecat: invalid hash store entry in line %d", line)
+		}
+		if key := text[:i]; key == hashStoreVersionKey {
+			v, err := strconv.Atoi(text[i+1:])
+			if err != nil {
+				return nil, fmt.Errorf("bmecat: invalid hash store version in line %d", line)
+			}
+			s.version = v
+		} else {
+			s.hashes[key] = text[i+1:]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// Get returns the hash of the article with the given SUPPLIER_AID.
+func (s *HashStore) Get(supplierAID string) (string, bool) {
+	hash, ok := s.hashes[supplierAID]
+	return hash, ok
+}
+
+// Set sets the hash of the article with the given SUPPLIER_AID.
+func (s *HashStore) Set(supplierAID, hash string) {
+	s.hashes[supplierAID] = hash
+}
+
+// Version returns the version of the delivery the hashes are taken from,
+// i.e. the number of T_UPDATE_PRODUCTS transactions emitted so far. It is
+// the PREV_VERSION of the next T_UPDATE_PRODUCTS, see WithTransaction.
+// The version is 0 if it has never been set.
+func (s *HashStore) Version() int {
+	return s.version
+}
+
+// SetVersion sets the version of the delivery, see Version.
+func (s *HashStore) SetVersion(v int) {
+	s.version = v
+}
+
+// Len returns the number of articles in the store.
+func (s *HashStore) Len() int {
+	return len(s.hashes)
+}
+
+// WriteTo writes the store to w, sorted by SUPPLIER_AID. The version is
+// written first, unless it is 0.
+func (s *HashStore) WriteTo(w io.Writer) (int64, error) {
+	aids := make([]string, 0, len(s.hashes))
+	for aid := range s.hashes {
+		aids = append(aids, aid)
+	}
+	sort.Strings(aids)
+
+	bw := bufio.NewWriter(w)
+	var n int64
+	if s.version != 0 {
+		m, err := fmt.Fprintf(bw, "%s\t%d\n", hashStoreVersionKey, s.version)
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	for _, aid := range aids {
+		m, err := fmt.Fprintf(bw, "%s\t%s\n", aid, s.hashes[aid])
+		n += int64(m)
+		if err != nil {
+			return n, err
+		}
+	}
+	return n, bw.Flush()
+}
+
+// Delta is a Transformer that only passes articles that are new or have
+// changed compared to a previous delivery. New articles get mode "new",
+// changed articles get mode "update". Articles of the previous delivery
+// that are missing in the current one are emitted with mode "delete" when
+// the Pipeline finishes. Use it with WithTransaction(UpdateProducts, ...)
+// to create a T_UPDATE_PRODUCTS catalog.
+//
+// The hashes of all articles of the current delivery are recorded in the
+// next HashStore, which should be persisted for the next run.
+type Delta struct {
+	prev *HashStore
+	next *HashStore
+	seen map[string]struct{}
+}
+
+// NewDelta creates a new Delta that compares against prev and records
+// the hashes of the current delivery in next. If prev is nil, all
+// articles are considered new.
+func NewDelta(prev, next *HashStore) *Delta {
+	if prev == nil {
+		prev = NewHashStore()
+	}
+	return &Delta{
+		prev: prev,
+		next: next,
+		seen: make(map[string]struct{}),
+	}
+}
+
+// TransformArticle implements the Transformer interface.
+func (d *Delta) TransformArticle(a *Article) (*Article, error) {
+	hash, err := a.Hash()
+	if err != nil {
+		return nil, err
+	}
+	d.seen[a.SupplierAID] = struct{}{}
+	if d.next != nil {
+		d.next.Set(a.SupplierAID, hash)
+	}
+	prevHash, found := d.prev.Get(a.SupplierAID)
+	switch {
+	case !found:
+		a.Mode = ArticleModeNew
+	case prevHash != hash:
+		a.Mode = ArticleModeUpdate
+	default:
+		return nil, nil
+	}
+	return a, nil
+}
+
+// FinishArticles returns the articles of the previous delivery that
+// have not been seen in the current one, with mode "delete".
+func (d *Delta) FinishArticles() ([]*Article, error) {
+	var aids []string
+	for aid := range d.prev.hashes {
+		if _, found := d.seen[aid]; !found {
+			aids = append(aids, aid)
+		}
+	}
+	sort.Strings(aids)
+	articles := make([]*Article, 0, len(aids))
+	for _, aid := range aids {
+		articles = append(articles, DeleteArticle(aid))
+	}
+	return articles, nil
+}
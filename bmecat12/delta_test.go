@@ -0,0 +1,162 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestHashStoreRoundTrip(t *testing.T) {
+	s := bmecat12.NewHashStore()
+	s.Set("2000", "def")
+	s.Set("1000", "abc")
+
+	var buf bytes.Buffer
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1000\tabc\n2000\tdef\n", buf.String(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+
+	s, err := bmecat12.ReadHashStore(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, s.Len(); want != have {
+		t.Fatalf("want Len = %d, have %d", want, have)
+	}
+	if hash, _ := s.Get("2000"); hash != "def" {
+		t.Fatalf("want hash %q, have %q", "def", hash)
+	}
+	if want, have := 0, s.Version(); want != have {
+		t.Fatalf("want Version = %d, have %d", want, have)
+	}
+
+	s.SetVersion(3)
+	buf.Reset()
+	if _, err := s.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "# VERSION\t3\n1000\tabc\n2000\tdef\n", buf.String(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	s, err = bmecat12.ReadHashStore(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, s.Version(); want != have {
+		t.Fatalf("want Version = %d, have %d", want, have)
+	}
+	if want, have := 2, s.Len(); want != have {
+		t.Fatalf("want Len = %d, have %d", want, have)
+	}
+}
+
+func TestPipelineWithDelta(t *testing.T) {
+	article := func(aid, descr string) *bmecat12.Article {
+		return &bmecat12.Article{
+			SupplierAID: aid,
+			Details:     &bmecat12.ArticleDetails{DescriptionShort: descr},
+		}
+	}
+	catalog := func(articles ...*bmecat12.Article) []byte {
+		var buf bytes.Buffer
+		cw := catalogWriter{tx: bmecat12.NewCatalog, header: testHeader, articles: articles}
+		if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		return buf.Bytes()
+	}
+	run := func(data []byte, prev *bmecat12.HashStore) (*testHandler, *bmecat12.HashStore) {
+		next := bmecat12.NewHashStore()
+		var buf bytes.Buffer
+		r := bmecat12.NewReader(bytes.NewReader(data))
+		w := bmecat12.NewWriter(&buf)
+		p := bmecat12.NewPipeline(r, w,
+			bmecat12.WithTransformer(bmecat12.NewDelta(prev, next)),
+			bmecat12.WithTransaction(bmecat12.UpdateProducts, 1),
+		)
+		if err := p.Do(context.Background()); err != nil {
+			t.Fatal(err)
+		}
+		h := &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		return h, next
+	}
+
+	// First run: all articles are new
+	h, hashes := run(catalog(
+		article("1000", "Notebook"),
+		article("2000", "Desktop"),
+		article("3000", "Tablet"),
+	), nil)
+	if want, have := 3, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := 3, hashes.Len(); want != have {
+		t.Fatalf("want %d hashes, have %d", want, have)
+	}
+
+	// Second run: 1000 is unchanged, 2000 changed, 3000 removed, 4000 added
+	h, hashes = run(catalog(
+		article("1000", "Notebook"),
+		article("2000", "Desktop PC"),
+		article("4000", "Phone"),
+	), hashes)
+	if want, have := bmecat12.UpdateProducts, h.tx; want != have {
+		t.Fatalf("want transaction %v, have %v", want, have)
+	}
+	var modes []string
+	for _, a := range h.articles {
+		modes = append(modes, a.SupplierAID+":"+a.Mode)
+	}
+	if want, have := "2000:update,4000:new,3000:delete", strings.Join(modes, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if want, have := 3, hashes.Len(); want != have {
+		t.Fatalf("want %d hashes, have %d", want, have)
+	}
+}
+
+func TestArticleHashWithInvalidDate(t *testing.T) {
+	newArticle := func(date string) *bmecat12.Article {
+		return &bmecat12.Article{
+			SupplierAID: "1000",
+			PriceDetails: []*bmecat12.ArticlePriceDetails{{
+				Dates: []*bmecat12.DateTime{
+					{Type: bmecat12.DateTimeValidStartDate, DateString: "2020-01-01"},
+					{Type: bmecat12.DateTimeValidEndDate, DateString: date, TimeString: "23:59:59", TimeZoneString: "CET"},
+				},
+				Prices: []*bmecat12.ArticlePrice{{Type: bmecat12.ArticlePriceTypeNetList}},
+			}},
+		}
+	}
+	a := newArticle("2020-12-31")
+	h1, err := a.Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(a.PriceDetails[0].Dates); want != have {
+		t.Fatalf("want article to be unchanged with %d dates, have %d", want, have)
+	}
+	h2, err := newArticle("2020-12-30").Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 == h2 {
+		t.Fatal("want different hashes for different dates")
+	}
+	h3, err := newArticle("2020-12-31").Hash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h1 != h3 {
+		t.Fatal("want same hashes for the same dates")
+	}
+}
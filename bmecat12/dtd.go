@@ -0,0 +1,256 @@
+package bmecat12
+
+// The BMEcat 1.2 specification comes with one DTD per transaction, i.e.
+// bmecat_new_catalog.dtd, bmecat_update_products.dtd, and
+// bmecat_update_prices.dtd. They only differ in the declarations of the
+// transaction element and the ARTICLE element.
+//
+// The official DTDs are not embedded in this package. The declarations
+// below are a structural model of the elements of this package, with
+// the sub-elements of classification systems and USER_DEFINED_EXTENSIONS
+// declared as ANY. They are used by Coverage and by ValidateDTD for a
+// quick offline check, but a document that passes them may still be
+// invalid according to the official DTD, and vice versa. To validate
+// against the official DTDs, use ValidateDTDDir, or ParseDTD and
+// DTD.ValidateFile. There is no XSD support.
+
+// NewCatalogDTD is the structural model of T_NEW_CATALOG documents, see
+// above. It is not the official DTD.
+const NewCatalogDTD = dtdCommon + `
+<!ELEMENT T_NEW_CATALOG (FEATURE_SYSTEM*, CLASSIFICATION_SYSTEM*, CATALOG_GROUP_SYSTEM?, ARTICLE+, ARTICLE_TO_CATALOGGROUP_MAP*)>
+
+<!ELEMENT ARTICLE (SUPPLIER_AID, ARTICLE_DETAILS, ARTICLE_FEATURES*, ARTICLE_ORDER_DETAILS, ARTICLE_PRICE_DETAILS+, MIME_INFO?, USER_DEFINED_EXTENSIONS?, ARTICLE_REFERENCE*)>
+`
+
+// UpdateProductsDTD is the structural model of T_UPDATE_PRODUCTS
+// documents. It is not the official DTD.
+const UpdateProductsDTD = dtdCommon + `
+<!ELEMENT T_UPDATE_PRODUCTS (ARTICLE+, ARTICLE_TO_CATALOGGROUP_MAP*)>
+<!ATTLIST T_UPDATE_PRODUCTS
+	prev_version CDATA #REQUIRED>
+
+<!ELEMENT ARTICLE (SUPPLIER_AID, ARTICLE_DETAILS?, ARTICLE_FEATURES*, ARTICLE_ORDER_DETAILS?, ARTICLE_PRICE_DETAILS*, MIME_INFO?, USER_DEFINED_EXTENSIONS?, ARTICLE_REFERENCE*)>
+<!ATTLIST ARTICLE
+	mode (new|update|delete) #REQUIRED>
+`
+
+// UpdatePricesDTD is the structural model of T_UPDATE_PRICES documents.
+// It is not the official DTD.
+const UpdatePricesDTD = dtdCommon + `
+<!ELEMENT T_UPDATE_PRICES (ARTICLE+)>
+<!ATTLIST T_UPDATE_PRICES
+	prev_version CDATA #REQUIRED>
+
+<!ELEMENT ARTICLE (SUPPLIER_AID, ARTICLE_PRICE_DETAILS+, USER_DEFINED_EXTENSIONS?)>
+`
+
+// dtdCommon contains the declarations shared by all transactions.
+const dtdCommon = `
+<!ELEMENT BMECAT (HEADER, (T_NEW_CATALOG | T_UPDATE_PRODUCTS | T_UPDATE_PRICES))>
+<!ATTLIST BMECAT
+	version CDATA #REQUIRED
+	xmlns CDATA #IMPLIED>
+
+<!-- HEADER -->
+<!ELEMENT HEADER (GENERATOR_INFO?, CATALOG, BUYER, AGREEMENT*, LEGAL_INFO?, SUPPLIER, USER_DEFINED_EXTENSIONS?)>
+<!ELEMENT GENERATOR_INFO (#PCDATA)>
+<!ELEMENT CATALOG (LANGUAGE, CATALOG_ID, CATALOG_VERSION, CATALOG_NAME?, DATETIME?, TERRITORY*, CURRENCY?, MIME_ROOT?, PRICE_FLAG*)>
+<!ELEMENT LANGUAGE (#PCDATA)>
+<!ELEMENT CATALOG_ID (#PCDATA)>
+<!ELEMENT CATALOG_VERSION (#PCDATA)>
+<!ELEMENT CATALOG_NAME (#PCDATA)>
+<!ELEMENT TERRITORY (#PCDATA)>
+<!ELEMENT CURRENCY (#PCDATA)>
+<!ELEMENT MIME_ROOT (#PCDATA)>
+<!ELEMENT PRICE_FLAG (#PCDATA)>
+<!ATTLIST PRICE_FLAG
+	type (incl_freight|incl_packing|incl_assurance|incl_duty) #REQUIRED>
+
+<!ELEMENT DATETIME (DATE, TIME?, TIMEZONE?)>
+<!ATTLIST DATETIME
+	type CDATA #REQUIRED>
+<!ELEMENT DATE (#PCDATA)>
+<!ELEMENT TIME (#PCDATA)>
+<!ELEMENT TIMEZONE (#PCDATA)>
+
+<!ELEMENT BUYER (BUYER_ID*, BUYER_NAME, ADDRESS?)>
+<!ELEMENT BUYER_ID (#PCDATA)>
+<!ATTLIST BUYER_ID
+	type CDATA #IMPLIED>
+<!ELEMENT BUYER_NAME (#PCDATA)>
+
+<!ELEMENT AGREEMENT (AGREEMENT_ID, DATETIME+)>
+<!ELEMENT AGREEMENT_ID (#PCDATA)>
+
+<!ELEMENT LEGAL_INFO (#PCDATA)>
+
+<!ELEMENT SUPPLIER (SUPPLIER_ID*, SUPPLIER_NAME, ADDRESS?, MIME_INFO?)>
+<!ELEMENT SUPPLIER_ID (#PCDATA)>
+<!ATTLIST SUPPLIER_ID
+	type CDATA #IMPLIED>
+<!ELEMENT SUPPLIER_NAME (#PCDATA)>
+
+<!ELEMENT ADDRESS (NAME?, NAME2?, NAME3?, CONTACT?, STREET?, ZIP?, BOXNO?, ZIPBOX?, CITY?, STATE?, COUNTRY?, PHONE?, FAX?, EMAIL?, PUBLIC_KEY*, URL?, ADDRESS_REMARKS?)>
+<!ATTLIST ADDRESS
+	type (buyer|supplier) #REQUIRED>
+<!ELEMENT NAME (#PCDATA)>
+<!ELEMENT NAME2 (#PCDATA)>
+<!ELEMENT NAME3 (#PCDATA)>
+<!ELEMENT CONTACT (#PCDATA)>
+<!ELEMENT STREET (#PCDATA)>
+<!ELEMENT ZIP (#PCDATA)>
+<!ELEMENT BOXNO (#PCDATA)>
+<!ELEMENT ZIPBOX (#PCDATA)>
+<!ELEMENT CITY (#PCDATA)>
+<!ELEMENT STATE (#PCDATA)>
+<!ELEMENT COUNTRY (#PCDATA)>
+<!ELEMENT PHONE (#PCDATA)>
+<!ELEMENT FAX (#PCDATA)>
+<!ELEMENT EMAIL (#PCDATA)>
+<!ELEMENT PUBLIC_KEY (#PCDATA)>
+<!ATTLIST PUBLIC_KEY
+	type CDATA #REQUIRED>
+<!ELEMENT URL (#PCDATA)>
+<!ELEMENT ADDRESS_REMARKS (#PCDATA)>
+
+<!ELEMENT USER_DEFINED_EXTENSIONS ANY>
+
+<!-- MIME -->
+<!ELEMENT MIME_INFO (MIME+)>
+<!ELEMENT MIME (MIME_TYPE?, MIME_SOURCE, MIME_DESCR?, MIME_ALT?, MIME_PURPOSE?, MIME_ORDER?)>
+<!ELEMENT MIME_TYPE (#PCDATA)>
+<!ELEMENT MIME_SOURCE (#PCDATA)>
+<!ELEMENT MIME_DESCR (#PCDATA)>
+<!ELEMENT MIME_ALT (#PCDATA)>
+<!ELEMENT MIME_PURPOSE (#PCDATA)>
+<!ELEMENT MIME_ORDER (#PCDATA)>
+
+<!-- FEATURE_SYSTEM -->
+<!ELEMENT FEATURE_SYSTEM (FEATURE_SYSTEM_NAME, FEATURE_SYSTEM_DESCR?, FEATURE_GROUP*)>
+<!ELEMENT FEATURE_SYSTEM_NAME (#PCDATA)>
+<!ELEMENT FEATURE_SYSTEM_DESCR (#PCDATA)>
+<!ELEMENT FEATURE_GROUP (FEATURE_GROUP_ID, FEATURE_GROUP_NAME, FEATURE_TEMPLATE+, FEATURE_GROUP_PARENT_ID*)>
+<!ELEMENT FEATURE_GROUP_ID (#PCDATA)>
+<!ELEMENT FEATURE_GROUP_NAME (#PCDATA)>
+<!ELEMENT FEATURE_GROUP_PARENT_ID (#PCDATA)>
+<!ELEMENT FEATURE_TEMPLATE (FT_NAME, FT_UNIT?, FT_ORDER?)>
+<!ELEMENT FT_NAME (#PCDATA)>
+<!ELEMENT FT_UNIT (#PCDATA)>
+<!ELEMENT FT_ORDER (#PCDATA)>
+
+<!-- CLASSIFICATION_SYSTEM -->
+<!ELEMENT CLASSIFICATION_SYSTEM (CLASSIFICATION_SYSTEM_NAME, CLASSIFICATION_SYSTEM_FULLNAME?, CLASSIFICATION_SYSTEM_VERSION?, CLASSIFICATION_SYSTEM_DESCR?, CLASSIFICATION_SYSTEM_LEVELS?, CLASSIFICATION_SYSTEM_LEVEL_NAMES?, ALLOWED_VALUES?, UNITS?, CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES?, CLASSIFICATION_GROUPS?)>
+<!ELEMENT CLASSIFICATION_SYSTEM_NAME (#PCDATA)>
+<!ELEMENT CLASSIFICATION_SYSTEM_FULLNAME (#PCDATA)>
+<!ELEMENT CLASSIFICATION_SYSTEM_VERSION (#PCDATA)>
+<!ELEMENT CLASSIFICATION_SYSTEM_DESCR (#PCDATA)>
+<!ELEMENT CLASSIFICATION_SYSTEM_LEVELS (#PCDATA)>
+<!ELEMENT CLASSIFICATION_SYSTEM_LEVEL_NAMES (CLASSIFICATION_SYSTEM_LEVEL_NAME+)>
+<!ELEMENT CLASSIFICATION_SYSTEM_LEVEL_NAME (#PCDATA)>
+<!ATTLIST CLASSIFICATION_SYSTEM_LEVEL_NAME
+	level CDATA #REQUIRED>
+<!ELEMENT ALLOWED_VALUES ANY>
+<!ELEMENT UNITS ANY>
+<!ELEMENT CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES ANY>
+<!ELEMENT CLASSIFICATION_GROUPS (CLASSIFICATION_GROUP+)>
+<!ELEMENT CLASSIFICATION_GROUP (CLASSIFICATION_GROUP_ID, CLASSIFICATION_GROUP_NAME, CLASSIFICATION_GROUP_DESCR?, CLASSIFICATION_GROUP_SYNONYMS?, CLASSIFICATION_GROUP_FEATURE_TEMPLATES?, CLASSIFICATION_GROUP_PARENT_ID?)>
+<!ATTLIST CLASSIFICATION_GROUP
+	type (leaf|node) #REQUIRED
+	level CDATA #IMPLIED>
+<!ELEMENT CLASSIFICATION_GROUP_ID (#PCDATA)>
+<!ELEMENT CLASSIFICATION_GROUP_NAME (#PCDATA)>
+<!ELEMENT CLASSIFICATION_GROUP_DESCR (#PCDATA)>
+<!ELEMENT CLASSIFICATION_GROUP_SYNONYMS (SYNONYM+)>
+<!ELEMENT SYNONYM (#PCDATA)>
+<!ELEMENT CLASSIFICATION_GROUP_FEATURE_TEMPLATES ANY>
+<!ELEMENT CLASSIFICATION_GROUP_PARENT_ID (#PCDATA)>
+
+<!-- CATALOG_GROUP_SYSTEM -->
+<!ELEMENT CATALOG_GROUP_SYSTEM (GROUP_SYSTEM_ID?, GROUP_SYSTEM_NAME?, GROUP_SYSTEM_DESCRIPTION?, CATALOG_STRUCTURE+)>
+<!ELEMENT GROUP_SYSTEM_ID (#PCDATA)>
+<!ELEMENT GROUP_SYSTEM_NAME (#PCDATA)>
+<!ELEMENT GROUP_SYSTEM_DESCRIPTION (#PCDATA)>
+<!ELEMENT CATALOG_STRUCTURE (GROUP_ID, GROUP_NAME, GROUP_DESCRIPTION?, PARENT_ID, GROUP_ORDER?, MIME_INFO?, USER_DEFINED_EXTENSIONS?, KEYWORD*)>
+<!ATTLIST CATALOG_STRUCTURE
+	type (root|node|leaf) #REQUIRED>
+<!ELEMENT GROUP_ID (#PCDATA)>
+<!ELEMENT GROUP_NAME (#PCDATA)>
+<!ELEMENT GROUP_DESCRIPTION (#PCDATA)>
+<!ELEMENT PARENT_ID (#PCDATA)>
+<!ELEMENT GROUP_ORDER (#PCDATA)>
+
+<!-- ARTICLE -->
+<!ELEMENT SUPPLIER_AID (#PCDATA)>
+<!ELEMENT ARTICLE_DETAILS (DESCRIPTION_SHORT, DESCRIPTION_LONG?, EAN?, SUPPLIER_ALT_AID?, BUYER_AID*, MANUFACTURER_AID?, MANUFACTURER_NAME?, MANUFACTURER_TYPE_DESCR?, ERP_GROUP_BUYER?, ERP_GROUP_SUPPLIER?, DELIVERY_TIME?, SPECIAL_TREATMENT_CLASS*, KEYWORD*, REMARKS?, SEGMENT*, ARTICLE_ORDER?, ARTICLE_STATUS*)>
+<!ELEMENT DESCRIPTION_SHORT (#PCDATA)>
+<!ELEMENT DESCRIPTION_LONG (#PCDATA)>
+<!ELEMENT EAN (#PCDATA)>
+<!ELEMENT SUPPLIER_ALT_AID (#PCDATA)>
+<!ELEMENT BUYER_AID (#PCDATA)>
+<!ATTLIST BUYER_AID
+	type CDATA #REQUIRED>
+<!ELEMENT MANUFACTURER_AID (#PCDATA)>
+<!ELEMENT MANUFACTURER_NAME (#PCDATA)>
+<!ELEMENT MANUFACTURER_TYPE_DESCR (#PCDATA)>
+<!ELEMENT ERP_GROUP_BUYER (#PCDATA)>
+<!ELEMENT ERP_GROUP_SUPPLIER (#PCDATA)>
+<!ELEMENT DELIVERY_TIME (#PCDATA)>
+<!ELEMENT SPECIAL_TREATMENT_CLASS (#PCDATA)>
+<!ATTLIST SPECIAL_TREATMENT_CLASS
+	type CDATA #REQUIRED>
+<!ELEMENT KEYWORD (#PCDATA)>
+<!ELEMENT REMARKS (#PCDATA)>
+<!ELEMENT SEGMENT (#PCDATA)>
+<!ELEMENT ARTICLE_ORDER (#PCDATA)>
+<!ELEMENT ARTICLE_STATUS (#PCDATA)>
+<!ATTLIST ARTICLE_STATUS
+	type (bargain|new_article|old_article|new|used|refurbished|core_article|others) #REQUIRED>
+
+<!ELEMENT ARTICLE_FEATURES ((REFERENCE_FEATURE_SYSTEM_NAME, (REFERENCE_FEATURE_GROUP_ID | REFERENCE_FEATURE_GROUP_NAME))?, FEATURE*)>
+<!ELEMENT REFERENCE_FEATURE_SYSTEM_NAME (#PCDATA)>
+<!ELEMENT REFERENCE_FEATURE_GROUP_ID (#PCDATA)>
+<!ELEMENT REFERENCE_FEATURE_GROUP_NAME (#PCDATA)>
+<!ELEMENT FEATURE (FNAME, (VARIANTS | FVALUE+), FUNIT?, FORDER?, FDESCR?, FVALUE_DETAILS?)>
+<!ELEMENT FNAME (#PCDATA)>
+<!ELEMENT FVALUE (#PCDATA)>
+<!ELEMENT FUNIT (#PCDATA)>
+<!ELEMENT FORDER (#PCDATA)>
+<!ELEMENT FDESCR (#PCDATA)>
+<!ELEMENT FVALUE_DETAILS (#PCDATA)>
+<!ELEMENT VARIANTS (VARIANT+, VORDER)>
+<!ELEMENT VARIANT (FVALUE, SUPPLIER_AID_SUPPLEMENT)>
+<!ELEMENT SUPPLIER_AID_SUPPLEMENT (#PCDATA)>
+<!ELEMENT VORDER (#PCDATA)>
+
+<!ELEMENT ARTICLE_ORDER_DETAILS (ORDER_UNIT, CONTENT_UNIT?, NO_CU_PER_OU?, PRICE_QUANTITY?, QUANTITY_MIN?, QUANTITY_INTERVAL?)>
+<!ELEMENT ORDER_UNIT (#PCDATA)>
+<!ELEMENT CONTENT_UNIT (#PCDATA)>
+<!ELEMENT NO_CU_PER_OU (#PCDATA)>
+<!ELEMENT PRICE_QUANTITY (#PCDATA)>
+<!ELEMENT QUANTITY_MIN (#PCDATA)>
+<!ELEMENT QUANTITY_INTERVAL (#PCDATA)>
+
+<!ELEMENT ARTICLE_PRICE_DETAILS (DATETIME*, DAILY_PRICE?, ARTICLE_PRICE+)>
+<!ELEMENT DAILY_PRICE (#PCDATA)>
+<!ELEMENT ARTICLE_PRICE (PRICE_AMOUNT, PRICE_CURRENCY?, TAX?, PRICE_FACTOR?, LOWER_BOUND?, TERRITORY*)>
+<!ATTLIST ARTICLE_PRICE
+	price_type CDATA #REQUIRED>
+<!ELEMENT PRICE_AMOUNT (#PCDATA)>
+<!ELEMENT PRICE_CURRENCY (#PCDATA)>
+<!ELEMENT TAX (#PCDATA)>
+<!ELEMENT PRICE_FACTOR (#PCDATA)>
+<!ELEMENT LOWER_BOUND (#PCDATA)>
+
+<!ELEMENT ARTICLE_REFERENCE (ART_ID_TO, CATALOG_ID?, CATALOG_VERSION?)>
+<!ATTLIST ARTICLE_REFERENCE
+	type (sparepart|similar|followup|mandatory|select|diff_orderunit|accessories|consists_of|others) #REQUIRED
+	quantity CDATA #IMPLIED>
+<!ELEMENT ART_ID_TO (#PCDATA)>
+
+<!ELEMENT ARTICLE_TO_CATALOGGROUP_MAP (ART_ID, CATALOG_GROUP_ID, ARTICLE_TO_CATALOGGROUP_MAP_ORDER?)>
+<!ATTLIST ARTICLE_TO_CATALOGGROUP_MAP
+	mode (new|delete) #IMPLIED>
+<!ELEMENT ART_ID (#PCDATA)>
+<!ELEMENT CATALOG_GROUP_ID (#PCDATA)>
+<!ELEMENT ARTICLE_TO_CATALOGGROUP_MAP_ORDER (#PCDATA)>
+`
@@ -0,0 +1,122 @@
+package bmecat12
+
+import (
+	"fmt"
+	"strings"
+)
+
+// EclassMapper rewrites the ECLASS features of an article, e.g. to map the
+// feature group IDs of an older ECLASS version to a newer one.
+type EclassMapper interface {
+	// MapEclass is called for every ArticleFeatures element that refers
+	// to an ECLASS feature system. It may modify af in place.
+	MapEclass(af *ArticleFeatures) error
+}
+
+// EclassMapperFunc is an adapter to allow the use of ordinary functions
+// as an EclassMapper.
+type EclassMapperFunc func(af *ArticleFeatures) error
+
+// MapEclass calls f(af).
+func (f EclassMapperFunc) MapEclass(af *ArticleFeatures) error {
+	return f(af)
+}
+
+// WithEclassMapper registers an EclassMapper that is invoked for the
+// ECLASS features of each article before it is passed to the handler.
+func WithEclassMapper(m EclassMapper) ReaderOption {
+	return func(r *Reader) {
+		r.eclassMapper = m
+	}
+}
+
+// MapEclassFeatures invokes m for all ECLASS features of the article.
+func MapEclassFeatures(a *Article, m EclassMapper) error {
+	for _, af := range a.Features {
+		if af == nil || !af.IsEclass() {
+			continue
+		}
+		if err := m.MapEclass(af); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// EclassMappingTable maps the group IDs and, optionally, the feature
+// names of one ECLASS version to another version.
+type EclassMappingTable struct {
+	// From is the source version, e.g. "5.1".
+	From string
+	// To is the target version, e.g. "7.1".
+	To string
+	// Groups maps the group IDs of version From to version To.
+	Groups map[string]string
+	// Features maps the feature names of version From to version To.
+	// Features not found in the map are kept unchanged.
+	Features map[string]string
+}
+
+// EclassUpgrader upgrades ECLASS features to a target version by applying
+// mapping tables. Tables are chained, e.g. 5.1 is upgraded to 7.1 with
+// tables for 5.1→6.1 and 6.1→7.1. It can be used with the Reader via
+// WithEclassMapper, or as a Transformer in a Pipeline.
+type EclassUpgrader struct {
+	target string
+	tables map[string]*EclassMappingTable
+}
+
+// NewEclassUpgrader creates an EclassUpgrader that upgrades to the
+// target version, e.g. "7.1", using the given mapping tables.
+func NewEclassUpgrader(target string, tables ...*EclassMappingTable) *EclassUpgrader {
+	u := &EclassUpgrader{
+		target: target,
+		tables: make(map[string]*EclassMappingTable),
+	}
+	for _, t := range tables {
+		u.tables[t.From] = t
+	}
+	return u
+}
+
+// MapEclass implements the EclassMapper interface. It returns an error if
+// there is no chain of mapping tables from the version of af to the target
+// version, or if a group ID is missing in one of the tables.
+func (u *EclassUpgrader) MapEclass(af *ArticleFeatures) error {
+	version := af.Version()
+	seen := make(map[string]bool)
+	for version != u.target {
+		if seen[version] {
+			return fmt.Errorf("bmecat: cycle in ECLASS mapping tables at version %s", version)
+		}
+		seen[version] = true
+		t, found := u.tables[version]
+		if !found {
+			return fmt.Errorf("bmecat: no ECLASS mapping table from version %s", version)
+		}
+		if af.FeatureGroupID != "" {
+			id, found := t.Groups[af.FeatureGroupID]
+			if !found {
+				return fmt.Errorf("bmecat: ECLASS %s group %s has no mapping to version %s", version, af.FeatureGroupID, t.To)
+			}
+			af.FeatureGroupID = id
+		}
+		for _, f := range af.Features {
+			if name, found := t.Features[f.Name]; found {
+				f.Name = name
+			}
+		}
+		version = t.To
+	}
+	prefix := strings.SplitN(af.FeatureSystemName, "-", 2)[0]
+	af.FeatureSystemName = prefix + "-" + version
+	return nil
+}
+
+// TransformArticle implements the Transformer interface.
+func (u *EclassUpgrader) TransformArticle(a *Article) (*Article, error) {
+	if err := MapEclassFeatures(a, u); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
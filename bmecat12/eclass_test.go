@@ -0,0 +1,85 @@
+package bmecat12_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+var testEclassTables = []*bmecat12.EclassMappingTable{
+	{
+		From:     "5.1",
+		To:       "6.1",
+		Groups:   map[string]string{"19010203": "19010290"},
+		Features: map[string]string{"Netzspannung": "Nennspannung"},
+	},
+	{
+		From:   "6.1",
+		To:     "7.1",
+		Groups: map[string]string{"19010290": "19010201"},
+	},
+}
+
+func TestEclassUpgrader(t *testing.T) {
+	u := bmecat12.NewEclassUpgrader("7.1", testEclassTables...)
+	af := &bmecat12.ArticleFeatures{
+		FeatureSystemName: "ECLASS-5.1",
+		FeatureGroupID:    "19010203",
+		Features: []*bmecat12.Feature{
+			{Name: "Netzspannung", Values: []string{"230"}},
+			{Name: "Gewicht", Values: []string{"2"}},
+		},
+	}
+	if err := u.MapEclass(af); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "ECLASS-7.1", af.FeatureSystemName; want != have {
+		t.Fatalf("want FeatureSystemName = %q, have %q", want, have)
+	}
+	if want, have := "19010201", af.FeatureGroupID; want != have {
+		t.Fatalf("want FeatureGroupID = %q, have %q", want, have)
+	}
+	if want, have := "Nennspannung", af.Features[0].Name; want != have {
+		t.Fatalf("want Features[0].Name = %q, have %q", want, have)
+	}
+	if want, have := "Gewicht", af.Features[1].Name; want != have {
+		t.Fatalf("want Features[1].Name = %q, have %q", want, have)
+	}
+
+	// No mapping table
+	af = &bmecat12.ArticleFeatures{FeatureSystemName: "ECLASS-4.0", FeatureGroupID: "1"}
+	if err := u.MapEclass(af); err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
+
+func TestReadWithEclassMapper(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	h := &testHandler{}
+	u := bmecat12.NewEclassUpgrader("7.1", testEclassTables...)
+	if err := bmecat12.NewReader(f, bmecat12.WithEclassMapper(u)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	features := h.articles[0].Features
+	if want, have := "ECLASS-7.1", features[0].FeatureSystemName; want != have {
+		t.Fatalf("want FeatureSystemName = %q, have %q", want, have)
+	}
+	if want, have := "19010201", features[0].FeatureGroupID; want != have {
+		t.Fatalf("want FeatureGroupID = %q, have %q", want, have)
+	}
+	// Other feature systems are left alone
+	if want, have := "udf_Supplier-1.0", features[1].FeatureSystemName; want != have {
+		t.Fatalf("want FeatureSystemName = %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,134 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/transform"
+
+	"github.com/olivere/bmecat/internal"
+)
+
+// UnmappablePolicy specifies how the Writer handles characters that
+// cannot be represented in the encoding set with WithEncoding, see
+// WithUnmappable.
+type UnmappablePolicy int
+
+const (
+	// UnmappableCharRef writes a numeric character reference instead,
+	// e.g. "&#8364;" for the euro sign in ISO-8859-1. The text is kept
+	// as is for XML parsers. This is the default.
+	UnmappableCharRef UnmappablePolicy = iota
+	// UnmappableReplace writes a question mark instead.
+	UnmappableReplace
+	// UnmappableError makes the Writer fail with an error wrapping
+	// ErrUnmappable.
+	UnmappableError
+)
+
+// WithEncoding makes the Writer encode the output in the given encoding,
+// e.g. "ISO-8859-1" or "windows-1252", instead of UTF-8, and declare it
+// in the XML declaration. Characters that cannot be represented in the
+// encoding are handled as specified with WithUnmappable. Only
+// single-byte encodings are supported; Do returns an error for other
+// encodings.
+func WithEncoding(name string) WriterOption {
+	return func(w *Writer) {
+		w.encoding = name
+	}
+}
+
+// WithUnmappable sets how the Writer handles characters that cannot be
+// represented in the encoding set with WithEncoding. The default is
+// UnmappableCharRef.
+func WithUnmappable(policy UnmappablePolicy) WriterOption {
+	return func(w *Writer) {
+		w.unmappable = policy
+	}
+}
+
+// xmlHeader returns the XML declaration for the encoding of the output.
+func (w *Writer) xmlHeader() string {
+	if w.encoding == "" {
+		return xml.Header
+	}
+	return fmt.Sprintf("<?xml version=\"1.0\" encoding=%q?>\n", w.encoding)
+}
+
+// encodingWriter returns out, transcoded to the encoding set with
+// WithEncoding. The returned io.WriteCloser must be closed to flush it.
+// It returns nil if the output is UTF-8.
+func (w *Writer) encodingWriter(out io.Writer) (io.WriteCloser, error) {
+	w.charmap = nil
+	switch strings.ToLower(w.encoding) {
+	case "", "utf-8", "utf8":
+		return nil, nil
+	}
+	cm, ok := internal.Charmap(w.encoding)
+	if !ok {
+		return nil, fmt.Errorf("bmecat: unsupported output encoding: %s", w.encoding)
+	}
+	w.charmap = cm
+	t := &charmapEncoder{cm: cm, name: w.encoding, policy: w.unmappable}
+	return transform.NewWriter(out, t), nil
+}
+
+// charmapEncoder transforms UTF-8 into a single-byte encoding, handling
+// unmappable characters according to policy.
+type charmapEncoder struct {
+	transform.NopResetter
+	cm     *charmap.Charmap
+	name   string
+	policy UnmappablePolicy
+}
+
+// Transform implements the transform.Transformer interface.
+func (e *charmapEncoder) Transform(dst, src []byte, atEOF bool) (nDst, nSrc int, err error) {
+	for nSrc < len(src) {
+		r, size := rune(src[nSrc]), 1
+		if r >= utf8.RuneSelf {
+			if !atEOF && !utf8.FullRune(src[nSrc:]) {
+				return nDst, nSrc, transform.ErrShortSrc
+			}
+			r, size = utf8.DecodeRune(src[nSrc:])
+		}
+		if b, ok := e.encodeRune(r); ok {
+			if nDst >= len(dst) {
+				return nDst, nSrc, transform.ErrShortDst
+			}
+			dst[nDst] = b
+			nDst++
+			nSrc += size
+			continue
+		}
+		var repl string
+		switch e.policy {
+		case UnmappableReplace:
+			repl = "?"
+		case UnmappableError:
+			return nDst, nSrc, fmt.Errorf("%w: %U in %s", ErrUnmappable, r, e.name)
+		default:
+			repl = fmt.Sprintf("&#%d;", r)
+		}
+		if nDst+len(repl) > len(dst) {
+			return nDst, nSrc, transform.ErrShortDst
+		}
+		nDst += copy(dst[nDst:], repl)
+		nSrc += size
+	}
+	return nDst, nSrc, nil
+}
+
+func (e *charmapEncoder) encodeRune(r rune) (byte, bool) {
+	if r < utf8.RuneSelf {
+		return byte(r), true
+	}
+	if r == utf8.RuneError {
+		return 0, false
+	}
+	return e.cm.EncodeRune(r)
+}
@@ -0,0 +1,94 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func encodingCatalog(descr string) catalogWriter {
+	return catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: descr}},
+		},
+	}
+}
+
+func TestWriteWithEncoding(t *testing.T) {
+	const descr = "Größe 5 € Ω"
+	tests := []struct {
+		Encoding string
+		Options  []bmecat12.WriterOption
+		Want     string // DESCRIPTION_SHORT in the output
+		Read     string // DESCRIPTION_SHORT when read back
+	}{
+		{
+			Encoding: "ISO-8859-1",
+			Want:     "Gr\xf6\xdfe 5 &#8364; &#937;",
+			Read:     descr,
+		},
+		{
+			Encoding: "ISO-8859-1",
+			Options:  []bmecat12.WriterOption{bmecat12.WithUnmappable(bmecat12.UnmappableReplace)},
+			Want:     "Gr\xf6\xdfe 5 ? ?",
+			Read:     "Größe 5 ? ?",
+		},
+		{
+			Encoding: "windows-1252",
+			Want:     "Gr\xf6\xdfe 5 \x80 &#937;",
+			Read:     descr,
+		},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		options := append([]bmecat12.WriterOption{bmecat12.WithEncoding(tt.Encoding)}, tt.Options...)
+		if err := bmecat12.NewWriter(&buf, options...).Do(context.Background(), encodingCatalog(descr)); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if want := `<?xml version="1.0" encoding="` + tt.Encoding + `"?>`; !strings.HasPrefix(out, want) {
+			t.Fatalf("%s: want XML declaration %s, have %q", tt.Encoding, want, out[:strings.Index(out, "\n")])
+		}
+		if want := "<DESCRIPTION_SHORT>" + tt.Want + "</DESCRIPTION_SHORT>"; !strings.Contains(out, want) {
+			t.Fatalf("%s: want %q in\n%q", tt.Encoding, want, out)
+		}
+
+		h := &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 1, len(h.articles); want != have {
+			t.Fatalf("%s: want %d articles, have %d", tt.Encoding, want, have)
+		}
+		if want, have := tt.Read, h.articles[0].Details.DescriptionShort; want != have {
+			t.Fatalf("%s: want DESCRIPTION_SHORT %q, have %q", tt.Encoding, want, have)
+		}
+	}
+}
+
+func TestWriteWithEncodingUnmappableError(t *testing.T) {
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf,
+		bmecat12.WithEncoding("ISO-8859-1"),
+		bmecat12.WithUnmappable(bmecat12.UnmappableError),
+	)
+	err := w.Do(context.Background(), encodingCatalog("5 €"))
+	if !errors.Is(err, bmecat12.ErrUnmappable) {
+		t.Fatalf("want %v, have %v", bmecat12.ErrUnmappable, err)
+	}
+}
+
+func TestWriteWithUnsupportedEncoding(t *testing.T) {
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf, bmecat12.WithEncoding("Shift_JIS"))
+	if err := w.Do(context.Background(), encodingCatalog("")); err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
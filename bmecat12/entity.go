@@ -0,0 +1,32 @@
+package bmecat12
+
+import "encoding/xml"
+
+// HTMLEntityMap returns a map of the HTML 4 entity names, e.g. "uuml" or
+// "szlig", to their characters. It covers the Latin-1 entities commonly found in
+// BMEcat files that were generated from HTML content.
+// Use it with WithEntityMap. It returns a new map on every call, so
+// callers may modify it.
+func HTMLEntityMap() map[string]string {
+	m := make(map[string]string, len(xml.HTMLEntity))
+	for name, text := range xml.HTMLEntity {
+		m[name] = text
+	}
+	return m
+}
+
+// WithEntityMap specifies a map of non-standard entity names to their
+// replacement text, e.g. HTMLEntityMap(). The XML decoder rejects entities
+// other than the five predefined by XML, so files with e.g. "&uuml;"
+// cannot be read without it. Passing WithEntityMap more than once merges
+// the maps, with later entries taking precedence.
+func WithEntityMap(m map[string]string) ReaderOption {
+	return func(r *Reader) {
+		if r.entities == nil {
+			r.entities = make(map[string]string, len(m))
+		}
+		for name, text := range m {
+			r.entities[name] = text
+		}
+	}
+}
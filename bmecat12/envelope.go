@@ -0,0 +1,110 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"fmt"
+)
+
+// Namespaces of the BMEcat 1.2 transactions.
+const (
+	NamespaceNewCatalog     = "http://www.bmecat.org/bmecat/1.2/bmecat_new_catalog"
+	NamespaceUpdateProducts = "http://www.bmecat.org/bmecat/1.2/bmecat_update_products"
+	NamespaceUpdatePrices   = "http://www.bmecat.org/bmecat/1.2/bmecat_update_prices"
+)
+
+// Namespace returns the XML namespace of the transaction, e.g.
+// NamespaceUpdatePrices for UpdatePrices.
+func (t Transaction) Namespace() string {
+	switch t {
+	case UpdateProducts:
+		return NamespaceUpdateProducts
+	case UpdatePrices:
+		return NamespaceUpdatePrices
+	default:
+		return NamespaceNewCatalog
+	}
+}
+
+// System identifiers of the DTDs of the BMEcat 1.2 transactions, as used
+// in the DOCTYPE.
+const (
+	DocTypeNewCatalog     = "bmecat_new_catalog.dtd"
+	DocTypeUpdateProducts = "bmecat_update_products.dtd"
+	DocTypeUpdatePrices   = "bmecat_update_prices.dtd"
+)
+
+// DocType returns the system identifier of the DTD of the transaction,
+// e.g. DocTypeUpdatePrices for UpdatePrices.
+func (t Transaction) DocType() string {
+	switch t {
+	case UpdateProducts:
+		return DocTypeUpdateProducts
+	case UpdatePrices:
+		return DocTypeUpdatePrices
+	default:
+		return DocTypeNewCatalog
+	}
+}
+
+// WithDocType makes the Writer use systemID as the system identifier of
+// the DOCTYPE instead of the one of the transaction, e.g. the URL of the
+// DTD, as in `<!DOCTYPE BMECAT SYSTEM "http://example.com/bmecat.dtd">`.
+// It replaces the DOCTYPE of a prolog given by a PrologWriter.
+func WithDocType(systemID string) WriterOption {
+	return func(w *Writer) {
+		w.docType = systemID
+		w.noDocType = false
+	}
+}
+
+// WithoutDocType makes the Writer omit the DOCTYPE, including the one of
+// a prolog given by a PrologWriter.
+func WithoutDocType() WriterOption {
+	return func(w *Writer) {
+		w.docType = ""
+		w.noDocType = true
+	}
+}
+
+// WithStrictEnvelope makes the Reader verify the envelope of the BMEcat
+// file in the 1st pass, i.e. before any element is passed to the
+// handler: the root element must be BMECAT with version "1.2", the
+// namespace, if any, must be the one of the transaction, and there must
+// be a transaction element. Files in a different format, e.g. BMEcat
+// 2005 or an unrelated XML file, are rejected with an error wrapping
+// ErrInvalidEnvelope instead of silently producing no articles.
+func WithStrictEnvelope() ReaderOption {
+	return func(r *Reader) {
+		r.strictEnvelope = true
+	}
+}
+
+// checkRootElement verifies the root element of a BMEcat 1.2 file.
+func checkRootElement(se xml.StartElement) error {
+	if se.Name.Local != "BMECAT" {
+		return fmt.Errorf("%w: root element is %s, want BMECAT", ErrInvalidEnvelope, se.Name.Local)
+	}
+	var version string
+	for _, attr := range se.Attr {
+		if attr.Name.Space == "" && attr.Name.Local == "version" {
+			version = attr.Value
+		}
+	}
+	switch version {
+	case "1.2":
+		return nil
+	case "":
+		return fmt.Errorf("%w: BMECAT has no version attribute, want 1.2", ErrInvalidEnvelope)
+	default:
+		return fmt.Errorf("%w: BMECAT version is %s, want 1.2", ErrInvalidEnvelope, version)
+	}
+}
+
+// checkTransactionNamespace verifies the namespace of the transaction
+// element se. Files without a namespace are accepted.
+func checkTransactionNamespace(se xml.StartElement, tx Transaction) error {
+	if space := se.Name.Space; space != "" && space != tx.Namespace() {
+		return fmt.Errorf("%w: namespace %s does not match %s, want %s", ErrInvalidEnvelope, space, se.Name.Local, tx.Namespace())
+	}
+	return nil
+}
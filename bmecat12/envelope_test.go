@@ -0,0 +1,213 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestReadWithStrictEnvelope(t *testing.T) {
+	const body = `<HEADER><CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG></HEADER>`
+	tests := []struct {
+		Name string
+		Doc  string
+		Err  string // empty if valid
+	}{
+		{
+			Name: "WithoutNamespace",
+			Doc:  `<BMECAT version="1.2">` + body + `<T_UPDATE_PRICES prev_version="1"></T_UPDATE_PRICES></BMECAT>`,
+		},
+		{
+			Name: "WithNamespace",
+			Doc:  `<BMECAT version="1.2" xmlns="` + bmecat12.NamespaceUpdatePrices + `">` + body + `<T_UPDATE_PRICES prev_version="1"></T_UPDATE_PRICES></BMECAT>`,
+		},
+		{
+			Name: "RootElement",
+			Doc:  `<rss version="2.0"><channel></channel></rss>`,
+			Err:  "root element is rss, want BMECAT",
+		},
+		{
+			Name: "MissingVersion",
+			Doc:  `<BMECAT>` + body + `<T_NEW_CATALOG></T_NEW_CATALOG></BMECAT>`,
+			Err:  "BMECAT has no version attribute",
+		},
+		{
+			Name: "BMEcat2005",
+			Doc:  `<BMECAT version="2005" xmlns="http://www.bmecat.org/bmecat/2005">` + body + `<T_NEW_CATALOG></T_NEW_CATALOG></BMECAT>`,
+			Err:  "BMECAT version is 2005, want 1.2",
+		},
+		{
+			Name: "NamespaceMismatch",
+			Doc:  `<BMECAT version="1.2" xmlns="` + bmecat12.NamespaceNewCatalog + `">` + body + `<T_UPDATE_PRICES prev_version="1"></T_UPDATE_PRICES></BMECAT>`,
+			Err:  "does not match T_UPDATE_PRICES",
+		},
+		{
+			Name: "MissingTransaction",
+			Doc:  `<BMECAT version="1.2">` + body + `</BMECAT>`,
+			Err:  "no transaction element",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			h := &testHandler{}
+			r := bmecat12.NewReader(strings.NewReader(tt.Doc), bmecat12.WithStrictEnvelope())
+			err := r.Do(context.Background(), h)
+			if tt.Err == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if !errors.Is(err, bmecat12.ErrInvalidEnvelope) {
+				t.Fatalf("want ErrInvalidEnvelope, have %v", err)
+			}
+			if !strings.Contains(err.Error(), tt.Err) {
+				t.Fatalf("want error to contain %q, have %q", tt.Err, err.Error())
+			}
+			if h.header != nil {
+				t.Fatal("want no header to be passed to the handler")
+			}
+		})
+	}
+}
+
+func TestReadWithStrictEnvelopeAndGoldenFiles(t *testing.T) {
+	for _, name := range []string{"new_catalog", "update_products", "update_prices"} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open("testdata/" + name + ".golden.xml")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if err := bmecat12.NewReader(f, bmecat12.WithStrictEnvelope()).Do(context.Background(), &testHandler{}); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReadWithoutStrictEnvelope(t *testing.T) {
+	doc := `<BMECAT version="2005"><T_NEW_CATALOG></T_NEW_CATALOG></BMECAT>`
+	if err := bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), &testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+type prologCatalogWriter struct {
+	catalogWriter
+	prolog *bmecat12.Prolog
+}
+
+func (w prologCatalogWriter) Prolog() *bmecat12.Prolog {
+	return w.prolog
+}
+
+func TestWriteDocType(t *testing.T) {
+	prolog := &bmecat12.Prolog{Tokens: []xml.Token{
+		xml.Directive(`DOCTYPE BMECAT SYSTEM "legacy.dtd"`),
+		xml.Comment(" Exported by ERP "),
+	}}
+	tests := []struct {
+		Name    string
+		Tx      bmecat12.Transaction
+		Prolog  *bmecat12.Prolog
+		Options []bmecat12.WriterOption
+		Want    string // empty if there is no DOCTYPE
+	}{
+		{Name: "NewCatalog", Tx: bmecat12.NewCatalog, Want: `<!DOCTYPE BMECAT SYSTEM "bmecat_new_catalog.dtd">`},
+		{Name: "UpdateProducts", Tx: bmecat12.UpdateProducts, Want: `<!DOCTYPE BMECAT SYSTEM "bmecat_update_products.dtd">`},
+		{Name: "UpdatePrices", Tx: bmecat12.UpdatePrices, Want: `<!DOCTYPE BMECAT SYSTEM "bmecat_update_prices.dtd">`},
+		{
+			Name:    "WithDocType",
+			Tx:      bmecat12.UpdatePrices,
+			Options: []bmecat12.WriterOption{bmecat12.WithDocType("http://example.com/bmecat.dtd")},
+			Want:    `<!DOCTYPE BMECAT SYSTEM "http://example.com/bmecat.dtd">`,
+		},
+		{
+			Name:    "WithoutDocType",
+			Tx:      bmecat12.NewCatalog,
+			Options: []bmecat12.WriterOption{bmecat12.WithoutDocType()},
+		},
+		{Name: "Prolog", Tx: bmecat12.NewCatalog, Prolog: prolog, Want: `<!DOCTYPE BMECAT SYSTEM "legacy.dtd">`},
+		{
+			Name:    "PrologWithDocType",
+			Tx:      bmecat12.NewCatalog,
+			Prolog:  prolog,
+			Options: []bmecat12.WriterOption{bmecat12.WithDocType(bmecat12.DocTypeNewCatalog)},
+			Want:    `<!DOCTYPE BMECAT SYSTEM "bmecat_new_catalog.dtd">`,
+		},
+		{
+			Name:    "PrologWithoutDocType",
+			Tx:      bmecat12.NewCatalog,
+			Prolog:  prolog,
+			Options: []bmecat12.WriterOption{bmecat12.WithoutDocType()},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			var cw bmecat12.CatalogWriter = catalogWriter{tx: tt.Tx, language: "de", header: testHeader}
+			if tt.Prolog != nil {
+				cw = prologCatalogWriter{catalogWriter: cw.(catalogWriter), prolog: tt.Prolog}
+			}
+			var buf bytes.Buffer
+			if err := bmecat12.NewWriter(&buf, tt.Options...).Do(context.Background(), cw); err != nil {
+				t.Fatal(err)
+			}
+			out := buf.String()
+			if want, have := 1, strings.Count(out, "<!DOCTYPE"); tt.Want != "" && want != have {
+				t.Fatalf("want %d DOCTYPE, have %d in\n%s", want, have, out)
+			}
+			if tt.Want == "" && strings.Contains(out, "<!DOCTYPE") {
+				t.Fatalf("want no DOCTYPE, have\n%s", out)
+			}
+			if tt.Want != "" && !strings.Contains(out, tt.Want) {
+				t.Fatalf("want %s, have\n%s", tt.Want, out)
+			}
+			if tt.Prolog != nil && !strings.Contains(out, "<!-- Exported by ERP -->") {
+				t.Fatalf("want comment of prolog, have\n%s", out)
+			}
+		})
+	}
+}
+
+func TestParseTransaction(t *testing.T) {
+	tests := []struct {
+		Name      string
+		Want      bmecat12.Transaction
+		Namespace string
+		DocType   string
+	}{
+		{Name: "T_NEW_CATALOG", Want: bmecat12.NewCatalog, Namespace: bmecat12.NamespaceNewCatalog, DocType: bmecat12.DocTypeNewCatalog},
+		{Name: "T_UPDATE_PRODUCTS", Want: bmecat12.UpdateProducts, Namespace: bmecat12.NamespaceUpdateProducts, DocType: bmecat12.DocTypeUpdateProducts},
+		{Name: "T_UPDATE_PRICES", Want: bmecat12.UpdatePrices, Namespace: bmecat12.NamespaceUpdatePrices, DocType: bmecat12.DocTypeUpdatePrices},
+	}
+	for _, tt := range tests {
+		tx, err := bmecat12.ParseTransaction(tt.Name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if tx != tt.Want {
+			t.Fatalf("%s: want %v, have %v", tt.Name, tt.Want, tx)
+		}
+		if want, have := tt.Name, tx.String(); want != have {
+			t.Fatalf("want String = %s, have %s", want, have)
+		}
+		if want, have := tt.Namespace, tx.Namespace(); want != have {
+			t.Fatalf("%s: want Namespace = %s, have %s", tt.Name, want, have)
+		}
+		if want, have := tt.DocType, tx.DocType(); want != have {
+			t.Fatalf("%s: want DocType = %s, have %s", tt.Name, want, have)
+		}
+	}
+	for _, name := range []string{"", "T_UPDATE", "t_new_catalog", "NEW_CATALOG"} {
+		if _, err := bmecat12.ParseTransaction(name); err == nil {
+			t.Fatalf("%q: want error, have nil", name)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package bmecat12
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+)
+
+var (
+	// ErrDuplicateHeader is returned, wrapped in a ParseError, when a
+	// BMEcat file contains more than one HEADER element.
+	ErrDuplicateHeader = errors.New("duplicate HEADER element")
+	// ErrMultipleTransactions is returned, wrapped in a ParseError, when
+	// a BMEcat file contains more than one transaction element, e.g. both
+	// T_NEW_CATALOG and T_UPDATE_PRICES.
+	ErrMultipleTransactions = errors.New("multiple transaction elements")
+	// ErrLimitExceeded is returned, wrapped in a LimitError, when a
+	// BMEcat file exceeds one of the limits set with WithLimits.
+	ErrLimitExceeded = errors.New("limit exceeded")
+	// ErrInvalidZip is returned by Address.ValidateZip when a postal code
+	// does not match the format of the country of the address.
+	ErrInvalidZip = errors.New("invalid zip code")
+	// ErrInvalidEnvelope is returned when the root element, its version,
+	// or the namespace of a BMEcat file are invalid, see
+	// WithStrictEnvelope.
+	ErrInvalidEnvelope = errors.New("invalid envelope")
+	// ErrUnmappable is returned, wrapped in an EncodeError, when the
+	// Writer cannot represent a character in the encoding set with
+	// WithEncoding and the policy is UnmappableError.
+	ErrUnmappable = errors.New("unmappable character")
+	// ErrSkipArticle is returned by a validator of WithArticleValidator
+	// to make the Writer skip the article instead of failing.
+	ErrSkipArticle = errors.New("skip article")
+	// ErrInvalidHeader is returned, wrapped in a HeaderError, when the
+	// Writer is passed a HEADER without the mandatory fields.
+	ErrInvalidHeader = errors.New("invalid header")
+	// ErrWriterState is returned when the methods to write a catalog
+	// incrementally are called out of order, e.g. WriteArticle before
+	// Begin.
+	ErrWriterState = errors.New("invalid writer state")
+	// ErrInvalidDateTime is returned, wrapped in an EncodeError, when the
+	// Writer is passed a DATETIME with an unknown type or a malformed
+	// DATE, TIME, or TIMEZONE, see DateTime.Validate.
+	ErrInvalidDateTime = errors.New("invalid DATETIME")
+	// ErrInvalidMode is returned, wrapped in an EncodeError, when the
+	// Writer is passed an article whose mode is not allowed in the
+	// transaction, see Article.ValidateMode.
+	ErrInvalidMode = errors.New("invalid mode")
+)
+
+// HeaderError is returned by the Writer, wrapped in an EncodeError, when
+// the HEADER lacks mandatory fields, see WithSkipValidation.
+type HeaderError struct {
+	// Missing are the paths of the missing fields, e.g.
+	// "HEADER/CATALOG/CATALOG_ID".
+	Missing []string
+}
+
+// Error returns a string representation of the error.
+func (e *HeaderError) Error() string {
+	return fmt.Sprintf("missing mandatory fields: %s", strings.Join(e.Missing, ", "))
+}
+
+// Unwrap returns ErrInvalidHeader.
+func (e *HeaderError) Unwrap() error {
+	return ErrInvalidHeader
+}
+
+// LimitError is returned by the Reader, wrapped in a ParseError, when a
+// BMEcat file exceeds one of the limits set with WithLimits.
+type LimitError struct {
+	// Limit is the name of the limit, e.g. "MaxDepth".
+	Limit string
+	// Max is the value of the limit.
+	Max int64
+}
+
+// Error returns a string representation of the error.
+func (e *LimitError) Error() string {
+	return fmt.Sprintf("%s of %d exceeded", e.Limit, e.Max)
+}
+
+// Unwrap returns ErrLimitExceeded.
+func (e *LimitError) Unwrap() error {
+	return ErrLimitExceeded
+}
+
+// ParseError is returned by the Reader when the BMEcat file is not
+// well-formed or an element cannot be decoded. Line and Column refer to
+// the position where the Reader stopped, which is at or shortly after
+// the actual problem.
+type ParseError struct {
+	// Element is the name of the element that could not be decoded,
+	// e.g. "ARTICLE". It is empty if the document is not well-formed
+	// outside of a decoded element.
+	Element string
+	// SupplierAID is the SUPPLIER_AID of the enclosing ARTICLE, if known.
+	SupplierAID string
+	// PreviousSupplierAID is the SUPPLIER_AID of the last ARTICLE that
+	// was read successfully, if any. It helps to locate the problem if
+	// SupplierAID is unknown.
+	PreviousSupplierAID string
+	// Offset is the byte offset into the (UTF-8 converted) input.
+	Offset int64
+	// Line is the 1-based line number.
+	Line int
+	// Column is the 1-based column, counted in characters.
+	Column int
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *ParseError) Error() string {
+	var b strings.Builder
+	if e.Element != "" {
+		fmt.Fprintf(&b, "bmecat/reader: unable to decode %s", e.Element)
+	} else {
+		b.WriteString("bmecat/reader: unable to parse document")
+	}
+	switch {
+	case e.SupplierAID != "":
+		fmt.Fprintf(&b, " with SUPPLIER_AID %q", e.SupplierAID)
+	case e.PreviousSupplierAID != "":
+		fmt.Fprintf(&b, " after SUPPLIER_AID %q", e.PreviousSupplierAID)
+	}
+	fmt.Fprintf(&b, " at line %d, column %d (byte offset %d): %v", e.Line, e.Column, e.Offset, e.Err)
+	return b.String()
+}
+
+// Unwrap returns the underlying error.
+func (e *ParseError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error, for use with errors.Cause of
+// github.com/pkg/errors.
+func (e *ParseError) Cause() error {
+	return e.Err
+}
+
+// HandlerError is returned by the Reader when a handler, or a hook like
+// an EclassMapper, returned an error. It allows callers to distinguish
+// errors of their own handlers from errors in the BMEcat file.
+type HandlerError struct {
+	// Element is the name of the element passed to the handler,
+	// e.g. "ARTICLE" or "HEADER".
+	Element string
+	// SupplierAID is the SUPPLIER_AID of the article passed to the
+	// handler, if any.
+	SupplierAID string
+	// ID identifies other elements passed to the handler, e.g. the
+	// GROUP_ID of a CATALOG_STRUCTURE or the name of a FEATURE_SYSTEM.
+	ID string
+	// Offset is the byte offset into the (UTF-8 converted) input.
+	Offset int64
+	// Err is the error returned by the handler.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *HandlerError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "bmecat/reader: handler for %s", e.Element)
+	switch {
+	case e.SupplierAID != "":
+		fmt.Fprintf(&b, " with SUPPLIER_AID %q", e.SupplierAID)
+	case e.ID != "":
+		fmt.Fprintf(&b, " %q", e.ID)
+	}
+	fmt.Fprintf(&b, " returned an error around byte offset %d: %v", e.Offset, e.Err)
+	return b.String()
+}
+
+// Unwrap returns the error returned by the handler.
+func (e *HandlerError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the error returned by the handler, for use with
+// errors.Cause of github.com/pkg/errors.
+func (e *HandlerError) Cause() error {
+	return e.Err
+}
+
+// InputError is returned by the Reader when it cannot read the input
+// itself, e.g. when it cannot decompress the input or seek back to the
+// start for the second pass.
+type InputError struct {
+	// Op describes what the Reader tried to do, e.g. "seek back to start".
+	Op string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *InputError) Error() string {
+	return fmt.Sprintf("bmecat/reader: unable to %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *InputError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error, for use with errors.Cause of
+// github.com/pkg/errors.
+func (e *InputError) Cause() error {
+	return e.Err
+}
+
+// MapStoreError is returned by the Reader when the MapStore failed to
+// store or return catalog group mappings, see WithMapStore.
+type MapStoreError struct {
+	// Op describes what the Reader tried to do, e.g. "store catalog
+	// group mapping".
+	Op string
+	// SupplierAID is the SUPPLIER_AID of the mapping, if any.
+	SupplierAID string
+	// Err is the error returned by the MapStore.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *MapStoreError) Error() string {
+	if e.SupplierAID != "" {
+		return fmt.Sprintf("bmecat/reader: unable to %s of SUPPLIER_AID %q: %v", e.Op, e.SupplierAID, e.Err)
+	}
+	return fmt.Sprintf("bmecat/reader: unable to %s: %v", e.Op, e.Err)
+}
+
+// Unwrap returns the error returned by the MapStore.
+func (e *MapStoreError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the error returned by the MapStore, for use with
+// errors.Cause of github.com/pkg/errors.
+func (e *MapStoreError) Cause() error {
+	return e.Err
+}
+
+// EncodeError is returned by the Writer when an element cannot be
+// written.
+type EncodeError struct {
+	// Element is the name of the element that could not be written,
+	// e.g. "ARTICLE" or "HEADER".
+	Element string
+	// SupplierAID is the SUPPLIER_AID of the article, if any.
+	SupplierAID string
+	// Err is the underlying error.
+	Err error
+}
+
+// Error returns a string representation of the error.
+func (e *EncodeError) Error() string {
+	if e.SupplierAID != "" {
+		return fmt.Sprintf("bmecat/v12: unable to write %s with SUPPLIER_AID %q: %v", e.Element, e.SupplierAID, e.Err)
+	}
+	return fmt.Sprintf("bmecat/v12: unable to write %s: %v", e.Element, e.Err)
+}
+
+// Unwrap returns the underlying error.
+func (e *EncodeError) Unwrap() error {
+	return e.Err
+}
+
+// Cause returns the underlying error, for use with errors.Cause of
+// github.com/pkg/errors.
+func (e *EncodeError) Cause() error {
+	return e.Err
+}
@@ -0,0 +1,191 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestReadReturnsHandlerError(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	errStop := errors.New("stop")
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			if a.SupplierAID == "2000" {
+				return errStop
+			}
+			return nil
+		},
+	}
+	err = bmecat12.NewReader(f).Do(context.Background(), h)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("want error %v, have %v", errStop, err)
+	}
+	var herr *bmecat12.HandlerError
+	if !errors.As(err, &herr) {
+		t.Fatalf("want HandlerError, have %T", err)
+	}
+	if want, have := "ARTICLE", herr.Element; want != have {
+		t.Errorf("want Element=%q, have %q", want, have)
+	}
+	if want, have := "2000", herr.SupplierAID; want != have {
+		t.Errorf("want SupplierAID=%q, have %q", want, have)
+	}
+	if herr.Offset <= 0 {
+		t.Errorf("want Offset > 0, have %d", herr.Offset)
+	}
+	var perr *bmecat12.ParseError
+	if errors.As(err, &perr) {
+		t.Errorf("want no ParseError, have %v", perr)
+	}
+}
+
+func TestReadHandlerErrorOffsetWithConcurrency(t *testing.T) {
+	doc := concurrencyCatalog(t, 100)
+	i := bytes.Index(doc, []byte("<SUPPLIER_AID>00042<"))
+	want := int64(bytes.LastIndex(doc[:i], []byte("<ARTICLE")))
+
+	errStop := errors.New("stop")
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			if a.SupplierAID == "00042" {
+				return errStop
+			}
+			return nil
+		},
+	}
+	for _, concurrency := range []int{1, 4} {
+		err := bmecat12.NewReader(bytes.NewReader(doc), bmecat12.WithConcurrency(concurrency)).Do(context.Background(), h)
+		var herr *bmecat12.HandlerError
+		if !errors.As(err, &herr) {
+			t.Fatalf("concurrency %d: want HandlerError, have %v", concurrency, err)
+		}
+		if have := herr.Offset; want != have {
+			t.Errorf("concurrency %d: want Offset=%d, have %d", concurrency, want, have)
+		}
+	}
+}
+
+// failingMapStore is a MapStore that fails to store mappings.
+type failingMapStore struct {
+	bmecat12.MapStore
+}
+
+var errStoreFailed = errors.New("store failed")
+
+func (s failingMapStore) Add(articleID, catalogGroupID string) error {
+	return errStoreFailed
+}
+
+// failingSeeker fails to seek once the input was read completely, i.e.
+// back to the start after the 1st pass.
+type failingSeeker struct {
+	*bytes.Reader
+}
+
+var errSeekFailed = errors.New("seek failed")
+
+func (s *failingSeeker) Seek(offset int64, whence int) (int64, error) {
+	if s.Len() == 0 {
+		return 0, errSeekFailed
+	}
+	return s.Reader.Seek(offset, whence)
+}
+
+func TestReadReturnsInputAndMapStoreErrors(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>
+    <ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>G1</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+	r := bmecat12.NewReader(strings.NewReader(doc), bmecat12.WithMapStore(failingMapStore{}))
+	err := r.Do(context.Background(), bmecat12.HandlerFuncs{})
+	if !errors.Is(err, errStoreFailed) {
+		t.Fatalf("want error %v, have %v", errStoreFailed, err)
+	}
+	var serr *bmecat12.MapStoreError
+	if !errors.As(err, &serr) {
+		t.Fatalf("want MapStoreError, have %T", err)
+	}
+	if want, have := "1000", serr.SupplierAID; want != have {
+		t.Errorf("want SupplierAID=%q, have %q", want, have)
+	}
+
+	r = bmecat12.NewReader(&failingSeeker{Reader: bytes.NewReader([]byte(doc))})
+	err = r.Do(context.Background(), bmecat12.HandlerFuncs{})
+	if !errors.Is(err, errSeekFailed) {
+		t.Fatalf("want error %v, have %v", errSeekFailed, err)
+	}
+	var ierr *bmecat12.InputError
+	if !errors.As(err, &ierr) {
+		t.Fatalf("want InputError, have %T", err)
+	}
+	if want, have := "seek back to start", ierr.Op; want != have {
+		t.Errorf("want Op=%q, have %q", want, have)
+	}
+	var perr *bmecat12.ParseError
+	if errors.As(err, &perr) {
+		t.Errorf("want no ParseError, have %v", perr)
+	}
+}
+
+// failingWriter fails after n bytes have been written.
+type failingWriter struct {
+	n int
+}
+
+var errWriteFailed = errors.New("write failed")
+
+func (w *failingWriter) Write(p []byte) (int, error) {
+	if len(p) > w.n {
+		n := w.n
+		w.n = 0
+		return n, errWriteFailed
+	}
+	w.n -= len(p)
+	return len(p), nil
+}
+
+func TestWriteReturnsEncodeError(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: strings.Repeat("x", 8192)}},
+		},
+	}
+	// Fail while writing the article, which is larger than the header
+	w := bmecat12.NewWriter(&failingWriter{n: 4096})
+	err := w.Do(context.Background(), cw)
+	if !errors.Is(err, errWriteFailed) {
+		t.Fatalf("want error %v, have %v", errWriteFailed, err)
+	}
+	var eerr *bmecat12.EncodeError
+	if !errors.As(err, &eerr) {
+		t.Fatalf("want EncodeError, have %T", err)
+	}
+	if want, have := "ARTICLE", eerr.Element; want != have {
+		t.Errorf("want Element=%q, have %q", want, have)
+	}
+	if want, have := "1000", eerr.SupplierAID; want != have {
+		t.Errorf("want SupplierAID=%q, have %q", want, have)
+	}
+}
@@ -0,0 +1,265 @@
+package bmecat12
+
+import (
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+)
+
+// ExtensionPoint is a position in the output of the Writer where custom
+// elements can be embedded with WithExtension.
+type ExtensionPoint int
+
+const (
+	// ExtensionHeader is the end of the HEADER element, after USER_DEFINED_
+	// EXTENSIONS. The value passed to the Extension is the *Header.
+	ExtensionHeader ExtensionPoint = iota
+	// ExtensionArticle is the end of each ARTICLE element, after USER_
+	// DEFINED_EXTENSIONS. The value passed to the Extension is the
+	// *Article.
+	ExtensionArticle
+	// ExtensionTransactionStart is the start of the transaction element,
+	// e.g. right after T_NEW_CATALOG opens, before the catalog structure
+	// and the articles. The value passed to the Extension is the
+	// Transaction.
+	ExtensionTransactionStart
+	// ExtensionTransactionEnd is the end of the transaction element,
+	// after the articles and the catalog group mappings. The value passed
+	// to the Extension is the Transaction.
+	ExtensionTransactionEnd
+)
+
+// Extension returns the XML to embed at an extension point for v, e.g.
+// `<acme:LABEL>Eco</acme:LABEL>`, or nil to embed nothing. The XML must
+// be well-formed and may consist of several elements. Declare the
+// prefixes of its namespaces with WithNamespace.
+type Extension func(v interface{}) ([]byte, error)
+
+// EncoderExtension returns an Extension that lets hook write the XML to
+// embed with an xml.Encoder, token by token or with Encode, instead of
+// returning it as bytes. The encoder writes to a buffer, so hook cannot
+// corrupt the output of the Writer: the XML is checked as for any other
+// Extension before it is embedded. Write prefixed names as local names,
+// e.g. xml.Name{Local: "acme:LABEL"}, and declare the prefixes with
+// WithNamespace.
+func EncoderExtension(hook func(enc *xml.Encoder, v interface{}) error) Extension {
+	return func(v interface{}) ([]byte, error) {
+		var buf bytes.Buffer
+		enc := xml.NewEncoder(&buf)
+		if err := hook(enc, v); err != nil {
+			return nil, err
+		}
+		if err := enc.Flush(); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+}
+
+// WithExtension makes the Writer embed the XML returned by ext at the
+// given extension point, e.g. to add elements required by a partner that
+// don't fit into UDX. If the option is given more than once for a point,
+// the extensions are embedded in the order given. Notice that the output
+// is no longer valid according to the BMEcat DTD; the Reader ignores the
+// elements.
+func WithExtension(point ExtensionPoint, ext Extension) WriterOption {
+	return func(w *Writer) {
+		if w.extensions == nil {
+			w.extensions = make(map[ExtensionPoint][]Extension)
+		}
+		w.extensions[point] = append(w.extensions[point], ext)
+	}
+}
+
+// WithNamespace declares a namespace prefix on the BMECAT element, e.g.
+// WithNamespace("acme", "http://example.com/acme") for the elements
+// embedded with WithExtension.
+func WithNamespace(prefix, uri string) WriterOption {
+	return func(w *Writer) {
+		if w.namespaces == nil {
+			w.namespaces = make(map[string]string)
+		}
+		w.namespaces[prefix] = uri
+	}
+}
+
+// namespaceAttrs returns the attributes declaring the namespaces of
+// WithNamespace, sorted by prefix.
+func (w *Writer) namespaceAttrs() []xml.Attr {
+	prefixes := make([]string, 0, len(w.namespaces))
+	for prefix := range w.namespaces {
+		prefixes = append(prefixes, prefix)
+	}
+	sort.Strings(prefixes)
+	attrs := make([]xml.Attr, 0, len(prefixes))
+	for _, prefix := range prefixes {
+		attrs = append(attrs, xml.Attr{
+			Name:  xml.Name{Local: "xmlns:" + prefix},
+			Value: w.namespaces[prefix],
+		})
+	}
+	return attrs
+}
+
+// encodeWithExtensions encodes v, which is the element of the extension
+// point, with the extensions of the point embedded before its end.
+func (w *Writer) encodeWithExtensions(v interface{}, point ExtensionPoint) error {
+	return w.encodeElement(v, w.extensions[point])
+}
+
+// encodeElement encodes v with exts embedded before its end, the
+// elements of WithCDATA written as CDATA sections, and the numbers of
+// WithNumberFormat formatted. If there is nothing to embed, no CDATA,
+// and no number format, v is encoded as is.
+func (w *Writer) encodeElement(v interface{}, exts []Extension) error {
+	if err := w.flushRaw(); err != nil {
+		return err
+	}
+	w.txStarted = false
+	if len(exts) == 0 && len(w.cdata) == 0 && len(w.numbers) == 0 {
+		return w.enc.Encode(v)
+	}
+
+	// Encode v separately and replay its tokens, in order to splice the
+	// extensions in before the end element
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return err
+	}
+	dec := xml.NewDecoder(&buf)
+	var depth int
+	// text collects the text of an element written as CDATA or with a
+	// number format
+	var text *strings.Builder
+	for {
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			depth++
+			_, number := w.numbers[t.Name.Local]
+			if w.cdata[t.Name.Local] || number {
+				text = &strings.Builder{}
+			}
+		case xml.CharData:
+			if text != nil {
+				text.Write(t)
+				continue
+			}
+		case xml.EndElement:
+			depth--
+			if text != nil {
+				if err := w.writeText(t.Name.Local, text.String()); err != nil {
+					return err
+				}
+				text = nil
+			}
+			if depth == 0 {
+				for _, ext := range exts {
+					if err := w.encodeExtension(ext, v); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		if err := w.enc.EncodeToken(rawToken(t)); err != nil {
+			return err
+		}
+	}
+}
+
+// writeText writes the text of the element with the given name that was
+// collected by encodeElement, formatted with WithNumberFormat and as
+// CDATA with WithCDATA.
+func (w *Writer) writeText(name, text string) error {
+	if f, ok := w.numbers[name]; ok {
+		text = f.format(text)
+	}
+	if w.cdata[name] {
+		return w.writeCDATA(text)
+	}
+	return w.enc.EncodeToken(xml.CharData(text))
+}
+
+// encodeExtensions encodes the XML returned by the extensions of point
+// for v.
+func (w *Writer) encodeExtensions(point ExtensionPoint, v interface{}) error {
+	for _, ext := range w.extensions[point] {
+		if err := w.encodeExtension(ext, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// encodeExtension encodes the XML returned by ext for v.
+func (w *Writer) encodeExtension(ext Extension, v interface{}) error {
+	data, err := ext(v)
+	if err != nil || len(data) == 0 {
+		return err
+	}
+	dec := xml.NewDecoder(bytes.NewReader(data))
+	var depth int
+	for {
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("bmecat: invalid extension: %w", err)
+		}
+		switch t.(type) {
+		case xml.StartElement:
+			depth++
+		case xml.EndElement:
+			depth--
+		case xml.ProcInst, xml.Directive:
+			return fmt.Errorf("bmecat: invalid extension: unexpected %T", t)
+		}
+		if depth < 0 {
+			return fmt.Errorf("bmecat: invalid extension: unexpected end element")
+		}
+		if err := w.enc.EncodeToken(rawToken(t)); err != nil {
+			return err
+		}
+	}
+	if depth != 0 {
+		return fmt.Errorf("bmecat: invalid extension: unclosed element")
+	}
+	return nil
+}
+
+// rawToken prepares a token returned by RawToken for EncodeToken: the
+// namespace prefixes are kept as part of the names, as the encoder would
+// otherwise take them as namespace URLs.
+func rawToken(t xml.Token) xml.Token {
+	switch t := t.(type) {
+	case xml.StartElement:
+		t.Name = rawName(t.Name)
+		attrs := make([]xml.Attr, len(t.Attr))
+		for i, attr := range t.Attr {
+			attrs[i] = xml.Attr{Name: rawName(attr.Name), Value: attr.Value}
+		}
+		t.Attr = attrs
+		return t
+	case xml.EndElement:
+		t.Name = rawName(t.Name)
+		return t
+	}
+	return t
+}
+
+func rawName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
@@ -0,0 +1,157 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithExtensions(t *testing.T) {
+	w := catalogWriter{
+		tx:       bmecat12.UpdateProducts,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Mode: "update", Details: &bmecat12.ArticleDetails{DescriptionShort: "Eco"}},
+			{SupplierAID: "2000", Mode: "update", Details: &bmecat12.ArticleDetails{DescriptionShort: "Other"}},
+		},
+	}
+	var buf bytes.Buffer
+	bw := bmecat12.NewWriter(&buf,
+		bmecat12.WithNamespace("acme", "http://example.com/acme"),
+		bmecat12.WithExtension(bmecat12.ExtensionHeader, func(v interface{}) ([]byte, error) {
+			h := v.(*bmecat12.Header)
+			return []byte(fmt.Sprintf(`<acme:PARTNER id="7">%s</acme:PARTNER>`, h.Catalog.ID)), nil
+		}),
+		bmecat12.WithExtension(bmecat12.ExtensionArticle, func(v interface{}) ([]byte, error) {
+			a := v.(*bmecat12.Article)
+			if a.SupplierAID != "1000" {
+				return nil, nil
+			}
+			return []byte(`<acme:LABEL acme:kind="eco">Eco &amp; Fair</acme:LABEL><acme:SCORE>9</acme:SCORE>`), nil
+		}),
+	)
+	if err := bw.Do(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		`xmlns:acme="http://example.com/acme"`,
+		`<acme:PARTNER id="7">` + testHeader.Catalog.ID + `</acme:PARTNER>` + "\n  </HEADER>",
+		`<acme:LABEL acme:kind="eco">Eco &amp; Fair</acme:LABEL>`,
+		"<acme:SCORE>9</acme:SCORE>\n    </ARTICLE>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("want output to contain %q, have\n%s", want, out)
+		}
+	}
+	if want, have := 1, strings.Count(out, "<acme:LABEL"); want != have {
+		t.Fatalf("want %d labels, have %d", want, have)
+	}
+
+	// The Reader ignores the extensions
+	h := &testHandler{}
+	if err := bmecat12.NewReader(strings.NewReader(out)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	if want, have := "Eco", h.articles[0].Details.DescriptionShort; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
+func TestWriteWithInvalidExtension(t *testing.T) {
+	w := catalogWriter{
+		tx:       bmecat12.UpdateProducts,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{{SupplierAID: "1000", Mode: "update"}},
+	}
+	for _, ext := range []string{`<acme:LABEL>`, `</acme:LABEL>`, `<a><b></a>`} {
+		bw := bmecat12.NewWriter(&bytes.Buffer{},
+			bmecat12.WithExtension(bmecat12.ExtensionArticle, func(interface{}) ([]byte, error) {
+				return []byte(ext), nil
+			}),
+		)
+		err := bw.Do(context.Background(), w)
+		if err == nil {
+			t.Fatalf("%s: want error, have nil", ext)
+		}
+		var eerr *bmecat12.EncodeError
+		if !errors.As(err, &eerr) {
+			t.Fatalf("%s: want EncodeError, have %T", ext, err)
+		}
+	}
+}
+
+func TestWriteWithEncoderExtensions(t *testing.T) {
+	w := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Eco"}, CatalogGroupIDs: []string{"1"}},
+		},
+	}
+	transaction := func(name string) bmecat12.Extension {
+		return bmecat12.EncoderExtension(func(enc *xml.Encoder, v interface{}) error {
+			start := xml.StartElement{
+				Name: xml.Name{Local: "acme:" + name},
+				Attr: []xml.Attr{{Name: xml.Name{Local: "tx"}, Value: v.(bmecat12.Transaction).String()}},
+			}
+			return enc.EncodeElement("x", start)
+		})
+	}
+	var buf bytes.Buffer
+	bw := bmecat12.NewWriter(&buf,
+		bmecat12.WithCatalogGroupMaps(),
+		bmecat12.WithNamespace("acme", "http://example.com/acme"),
+		bmecat12.WithExtension(bmecat12.ExtensionTransactionStart, transaction("OPENED")),
+		bmecat12.WithExtension(bmecat12.ExtensionTransactionEnd, transaction("CLOSED")),
+		bmecat12.WithExtension(bmecat12.ExtensionHeader, bmecat12.EncoderExtension(func(enc *xml.Encoder, v interface{}) error {
+			return enc.EncodeElement(v.(*bmecat12.Header).Catalog.ID, xml.StartElement{Name: xml.Name{Local: "acme:PARTNER"}})
+		})),
+	)
+	if err := bw.Do(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, want := range []string{
+		"<T_NEW_CATALOG>\n    <acme:OPENED tx=\"T_NEW_CATALOG\">x</acme:OPENED>\n    <ARTICLE>",
+		"</ARTICLE_TO_CATALOGGROUP_MAP>\n    <acme:CLOSED tx=\"T_NEW_CATALOG\">x</acme:CLOSED>\n  </T_NEW_CATALOG>",
+		"<acme:PARTNER>" + testHeader.Catalog.ID + "</acme:PARTNER>\n  </HEADER>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("want output to contain %q, have\n%s", want, out)
+		}
+	}
+
+	// The Reader ignores the extensions
+	h := &testHandler{}
+	if err := bmecat12.NewReader(strings.NewReader(out)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+
+	// The hook cannot leave elements open
+	bw = bmecat12.NewWriter(&bytes.Buffer{},
+		bmecat12.WithExtension(bmecat12.ExtensionTransactionStart, bmecat12.EncoderExtension(func(enc *xml.Encoder, v interface{}) error {
+			return enc.EncodeToken(xml.StartElement{Name: xml.Name{Local: "OPEN"}})
+		})),
+	)
+	if err := bw.Do(context.Background(), w); err == nil {
+		t.Fatal("want an error for an unclosed element")
+	}
+}
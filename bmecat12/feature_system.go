@@ -0,0 +1,66 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+)
+
+// FeatureSystem represents a FEATURE_SYSTEM element, i.e. a proprietary
+// feature system that describes the features of the articles by
+// feature groups and their templates.
+type FeatureSystem struct {
+	XMLName xml.Name `xml:"FEATURE_SYSTEM"`
+
+	Name        string          `xml:"FEATURE_SYSTEM_NAME"`
+	Description string          `xml:"FEATURE_SYSTEM_DESCR,omitempty"`
+	Groups      []*FeatureGroup `xml:"FEATURE_GROUP,omitempty"`
+}
+
+// IsBlank returns true if the feature system has no name.
+func (fs *FeatureSystem) IsBlank() bool {
+	return fs == nil || fs.Name == ""
+}
+
+// FeatureGroup represents a FEATURE_GROUP element of a feature system.
+type FeatureGroup struct {
+	ID        string             `xml:"FEATURE_GROUP_ID"`
+	Name      string             `xml:"FEATURE_GROUP_NAME"`
+	Templates []*FeatureTemplate `xml:"FEATURE_TEMPLATE"`
+	ParentIDs []string           `xml:"FEATURE_GROUP_PARENT_ID,omitempty"`
+}
+
+// FeatureTemplate represents a FEATURE_TEMPLATE element, i.e. the
+// definition of a feature within a feature group.
+//
+// Mandatory and Values are not part of BMEcat 1.2, but are read from the
+// FT_MANDATORY and FT_VALUES elements of BMEcat 2005 found in some files.
+// They are only written if set. See FeatureTemplateValidator.
+type FeatureTemplate struct {
+	Name      string   `xml:"FT_NAME"`
+	Unit      string   `xml:"FT_UNIT,omitempty"`
+	Order     int      `xml:"FT_ORDER,omitempty"`
+	Mandatory bool     `xml:"FT_MANDATORY,omitempty"`
+	Values    []string `xml:"FT_VALUES>FT_VALUE,omitempty"`
+}
+
+// MarshalXML omits the FT_VALUES element if there are no values.
+func (ft FeatureTemplate) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	type featureTemplateValues struct {
+		Values []string `xml:"FT_VALUE"`
+	}
+	v := struct {
+		Name      string                 `xml:"FT_NAME"`
+		Unit      string                 `xml:"FT_UNIT,omitempty"`
+		Order     int                    `xml:"FT_ORDER,omitempty"`
+		Mandatory bool                   `xml:"FT_MANDATORY,omitempty"`
+		Values    *featureTemplateValues `xml:"FT_VALUES,omitempty"`
+	}{
+		Name:      ft.Name,
+		Unit:      ft.Unit,
+		Order:     ft.Order,
+		Mandatory: ft.Mandatory,
+	}
+	if len(ft.Values) > 0 {
+		v.Values = &featureTemplateValues{Values: ft.Values}
+	}
+	return e.EncodeElement(v, start)
+}
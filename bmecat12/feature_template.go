@@ -0,0 +1,189 @@
+package bmecat12
+
+import (
+	"fmt"
+	"sort"
+)
+
+// FeatureTemplateGap describes a feature of an article that does not
+// conform to the FEATURE_TEMPLATE of the feature group it references,
+// e.g. a missing mandatory feature or a value that is not allowed.
+type FeatureTemplateGap struct {
+	// SupplierAID of the article.
+	SupplierAID string
+	// FeatureSystemName is the REFERENCE_FEATURE_SYSTEM_NAME of the article.
+	FeatureSystemName string
+	// FeatureGroupID is the REFERENCE_FEATURE_GROUP_ID of the article.
+	FeatureGroupID string
+	// Feature is the name of the feature, i.e. FT_NAME.
+	Feature string
+	// Message describes the gap.
+	Message string
+}
+
+// String returns a string representation of the gap.
+func (g *FeatureTemplateGap) String() string {
+	return fmt.Sprintf("%s/%s: %s: %s (SUPPLIER_AID %q)", g.FeatureSystemName, g.FeatureGroupID, g.Feature, g.Message, g.SupplierAID)
+}
+
+// FeatureTemplateValidator checks that articles provide the features of
+// the feature groups they reference, as defined by the FEATURE_TEMPLATE
+// elements of the feature systems: Mandatory features must be present,
+// values must be one of the template's Values, if any, and units must
+// match the template's unit, if any. This is what audits of ECLASS
+// conformance check.
+//
+// FeatureTemplateValidator implements FeatureSystemHandler and
+// ArticleHandler, so it can be passed to Reader.Do, e.g. via
+// MultiHandler, to collect the gaps of a whole catalog. Articles that
+// reference an unknown feature group are not checked.
+type FeatureTemplateValidator struct {
+	groups map[featureGroupKey]*FeatureGroup
+	gaps   map[string][]*FeatureTemplateGap
+}
+
+// featureGroupKey identifies a feature group across feature systems.
+type featureGroupKey struct {
+	system string
+	group  string
+}
+
+// NewFeatureTemplateValidator creates a new FeatureTemplateValidator with
+// the given feature systems. More feature systems are added as they are
+// passed to HandleFeatureSystem.
+func NewFeatureTemplateValidator(systems ...*FeatureSystem) *FeatureTemplateValidator {
+	v := &FeatureTemplateValidator{
+		groups: make(map[featureGroupKey]*FeatureGroup),
+		gaps:   make(map[string][]*FeatureTemplateGap),
+	}
+	for _, fs := range systems {
+		v.AddFeatureSystem(fs)
+	}
+	return v
+}
+
+// AddFeatureSystem adds the feature groups of fs.
+func (v *FeatureTemplateValidator) AddFeatureSystem(fs *FeatureSystem) {
+	if fs == nil {
+		return
+	}
+	for _, g := range fs.Groups {
+		if g == nil {
+			continue
+		}
+		v.groups[featureGroupKey{system: fs.Name, group: g.ID}] = g
+	}
+}
+
+// HandleFeatureSystem implements the FeatureSystemHandler interface.
+func (v *FeatureTemplateValidator) HandleFeatureSystem(fs *FeatureSystem) error {
+	v.AddFeatureSystem(fs)
+	return nil
+}
+
+// HandleArticle implements the ArticleHandler interface. It records the
+// gaps of the article; see Gaps.
+func (v *FeatureTemplateValidator) HandleArticle(a *Article) error {
+	for _, gap := range v.ValidateArticle(a) {
+		v.gaps[gap.FeatureGroupID] = append(v.gaps[gap.FeatureGroupID], gap)
+	}
+	return nil
+}
+
+// Gaps returns the gaps recorded by HandleArticle, by feature group ID.
+func (v *FeatureTemplateValidator) Gaps() map[string][]*FeatureTemplateGap {
+	return v.gaps
+}
+
+// GroupIDs returns the IDs of the feature groups with gaps, sorted.
+func (v *FeatureTemplateValidator) GroupIDs() []string {
+	ids := make([]string, 0, len(v.gaps))
+	for id := range v.gaps {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// ValidateArticle returns the gaps of the article, without recording them.
+func (v *FeatureTemplateValidator) ValidateArticle(a *Article) []*FeatureTemplateGap {
+	var gaps []*FeatureTemplateGap
+	for _, af := range a.Features {
+		if af == nil {
+			continue
+		}
+		g, found := v.groups[featureGroupKey{system: af.FeatureSystemName, group: af.FeatureGroupID}]
+		if !found {
+			continue
+		}
+		features := make(map[string]*Feature, len(af.Features))
+		for _, f := range af.Features {
+			if f != nil {
+				features[f.Name] = f
+			}
+		}
+		gap := func(feature, format string, args ...interface{}) {
+			gaps = append(gaps, &FeatureTemplateGap{
+				SupplierAID:       a.SupplierAID,
+				FeatureSystemName: af.FeatureSystemName,
+				FeatureGroupID:    af.FeatureGroupID,
+				Feature:           feature,
+				Message:           fmt.Sprintf(format, args...),
+			})
+		}
+		for _, ft := range g.Templates {
+			if ft == nil {
+				continue
+			}
+			f, found := features[ft.Name]
+			if !found || len(featureValues(f)) == 0 {
+				if ft.Mandatory {
+					gap(ft.Name, "mandatory feature is missing")
+				}
+				continue
+			}
+			if ft.Unit != "" && f.Unit != "" && f.Unit != ft.Unit {
+				gap(ft.Name, "unit %q does not match %q", f.Unit, ft.Unit)
+			}
+			if len(ft.Values) > 0 {
+				for _, value := range featureValues(f) {
+					if !containsString(ft.Values, value) {
+						gap(ft.Name, "value %q is not allowed", value)
+					}
+				}
+			}
+		}
+	}
+	return gaps
+}
+
+// featureValues returns the values of f, including those of its variants.
+func featureValues(f *Feature) []string {
+	var values []string
+	for _, value := range f.Values {
+		if value != "" {
+			values = append(values, value)
+		}
+	}
+	for _, vs := range f.Variants {
+		if vs == nil {
+			continue
+		}
+		for _, variant := range vs.Variants {
+			if variant != nil && variant.Value != "" {
+				values = append(values, variant.Value)
+			}
+		}
+	}
+	return values
+}
+
+// containsString returns true if s is in list.
+func containsString(list []string, s string) bool {
+	for _, x := range list {
+		if x == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,114 @@
+package bmecat12_test
+
+import (
+	"context"
+	"encoding/xml"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const featureTemplateDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <FEATURE_SYSTEM>
+      <FEATURE_SYSTEM_NAME>ECLASS-5.1</FEATURE_SYSTEM_NAME>
+      <FEATURE_GROUP>
+        <FEATURE_GROUP_ID>24-01-01-01</FEATURE_GROUP_ID>
+        <FEATURE_GROUP_NAME>Notebook</FEATURE_GROUP_NAME>
+        <FEATURE_TEMPLATE>
+          <FT_NAME>Color</FT_NAME>
+          <FT_MANDATORY>true</FT_MANDATORY>
+          <FT_VALUES><FT_VALUE>black</FT_VALUE><FT_VALUE>silver</FT_VALUE></FT_VALUES>
+        </FEATURE_TEMPLATE>
+        <FEATURE_TEMPLATE>
+          <FT_NAME>Weight</FT_NAME>
+          <FT_UNIT>KGM</FT_UNIT>
+          <FT_MANDATORY>true</FT_MANDATORY>
+        </FEATURE_TEMPLATE>
+        <FEATURE_TEMPLATE>
+          <FT_NAME>Comment</FT_NAME>
+        </FEATURE_TEMPLATE>
+      </FEATURE_GROUP>
+    </FEATURE_SYSTEM>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_FEATURES>
+        <REFERENCE_FEATURE_SYSTEM_NAME>ECLASS-5.1</REFERENCE_FEATURE_SYSTEM_NAME>
+        <REFERENCE_FEATURE_GROUP_ID>24-01-01-01</REFERENCE_FEATURE_GROUP_ID>
+        <FEATURE><FNAME>Color</FNAME><FVALUE>black</FVALUE></FEATURE>
+        <FEATURE><FNAME>Weight</FNAME><FVALUE>1.5</FVALUE><FUNIT>KGM</FUNIT></FEATURE>
+      </ARTICLE_FEATURES>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>2000</SUPPLIER_AID>
+      <ARTICLE_FEATURES>
+        <REFERENCE_FEATURE_SYSTEM_NAME>ECLASS-5.1</REFERENCE_FEATURE_SYSTEM_NAME>
+        <REFERENCE_FEATURE_GROUP_ID>24-01-01-01</REFERENCE_FEATURE_GROUP_ID>
+        <FEATURE><FNAME>Color</FNAME><FVALUE>pink</FVALUE></FEATURE>
+        <FEATURE><FNAME>Weight</FNAME><FVALUE>1500</FVALUE><FUNIT>GRM</FUNIT></FEATURE>
+      </ARTICLE_FEATURES>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>3000</SUPPLIER_AID>
+      <ARTICLE_FEATURES>
+        <REFERENCE_FEATURE_SYSTEM_NAME>ECLASS-5.1</REFERENCE_FEATURE_SYSTEM_NAME>
+        <REFERENCE_FEATURE_GROUP_ID>24-01-01-01</REFERENCE_FEATURE_GROUP_ID>
+        <FEATURE><FNAME>Comment</FNAME><FVALUE>No color</FVALUE></FEATURE>
+      </ARTICLE_FEATURES>
+      <ARTICLE_FEATURES>
+        <REFERENCE_FEATURE_SYSTEM_NAME>ECLASS-5.1</REFERENCE_FEATURE_SYSTEM_NAME>
+        <REFERENCE_FEATURE_GROUP_ID>99-99-99-99</REFERENCE_FEATURE_GROUP_ID>
+        <FEATURE><FNAME>Unknown</FNAME><FVALUE>Group</FVALUE></FEATURE>
+      </ARTICLE_FEATURES>
+    </ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+func TestFeatureTemplateValidator(t *testing.T) {
+	v := bmecat12.NewFeatureTemplateValidator()
+	if err := bmecat12.NewReader(strings.NewReader(featureTemplateDoc)).Do(context.Background(), v); err != nil {
+		t.Fatal(err)
+	}
+
+	if want, have := "24-01-01-01", strings.Join(v.GroupIDs(), ","); want != have {
+		t.Fatalf("want groups %s, have %s", want, have)
+	}
+	var gaps []string
+	for _, gap := range v.Gaps()["24-01-01-01"] {
+		gaps = append(gaps, gap.SupplierAID+" "+gap.Feature+": "+gap.Message)
+	}
+	want := []string{
+		`2000 Color: value "pink" is not allowed`,
+		`2000 Weight: unit "GRM" does not match "KGM"`,
+		`3000 Color: mandatory feature is missing`,
+		`3000 Weight: mandatory feature is missing`,
+	}
+	if want, have := strings.Join(want, "\n"), strings.Join(gaps, "\n"); want != have {
+		t.Fatalf("want gaps\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestFeatureTemplateMarshalOnlyIfSet(t *testing.T) {
+	data, err := xml.Marshal(&bmecat12.FeatureTemplate{Name: "Color", Unit: "KGM"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "<FeatureTemplate><FT_NAME>Color</FT_NAME><FT_UNIT>KGM</FT_UNIT></FeatureTemplate>", string(data); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
+
+func TestFeatureTemplateMarshalValues(t *testing.T) {
+	data, err := xml.Marshal(&bmecat12.FeatureTemplate{Name: "Color", Mandatory: true, Values: []string{"black", "silver"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "<FeatureTemplate><FT_NAME>Color</FT_NAME><FT_MANDATORY>true</FT_MANDATORY><FT_VALUES><FT_VALUE>black</FT_VALUE><FT_VALUE>silver</FT_VALUE></FT_VALUES></FeatureTemplate>", string(data); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,255 @@
+package bmecat12
+
+import (
+	"strings"
+)
+
+// GroupIndex looks up catalog groups by their GROUP_ID, e.g. to resolve
+// the category path of an article. It implements CatalogGroupHandler, so
+// it can be filled while reading a catalog.
+type GroupIndex map[string]*CatalogGroup
+
+// NewGroupIndex creates a new GroupIndex with the given groups.
+func NewGroupIndex(groups ...*CatalogGroup) GroupIndex {
+	idx := make(GroupIndex, len(groups))
+	for _, g := range groups {
+		idx.Add(g)
+	}
+	return idx
+}
+
+// Add adds the group to the index.
+func (idx GroupIndex) Add(g *CatalogGroup) {
+	if g != nil {
+		idx[g.ID] = g
+	}
+}
+
+// HandleCatalogGroup implements the CatalogGroupHandler interface.
+func (idx GroupIndex) HandleCatalogGroup(g *CatalogGroup) error {
+	idx.Add(g)
+	return nil
+}
+
+// Path returns the groups from the root to the group with the given ID.
+// The root group, i.e. the group of type "root", is not included. Path
+// returns nil if the group is unknown.
+func (idx GroupIndex) Path(id string) []*CatalogGroup {
+	var path []*CatalogGroup
+	seen := make(map[string]bool)
+	for {
+		g, found := idx[id]
+		if !found || seen[id] {
+			// Unknown parent or cycle
+			return path
+		}
+		seen[id] = true
+		if !g.IsRoot() {
+			path = append([]*CatalogGroup{g}, path...)
+		}
+		if g.ParentID == nil {
+			return path
+		}
+		id = *g.ParentID
+	}
+}
+
+// PathNames returns the names of the groups of Path, joined by sep, e.g.
+// "Hardware > Notebooks".
+func (idx GroupIndex) PathNames(id, sep string) string {
+	var names []string
+	for _, g := range idx.Path(id) {
+		names = append(names, g.Name)
+	}
+	return strings.Join(names, sep)
+}
+
+// FlatArticle is a de-normalized view of an article, with the most
+// commonly used fields in a single flat struct, e.g. for exports to CSV
+// or JSON. See Flatten.
+type FlatArticle struct {
+	SupplierAID      string `json:"supplier_aid"`
+	Mode             string `json:"mode,omitempty"`
+	DescriptionShort string `json:"description_short,omitempty"`
+	DescriptionLong  string `json:"description_long,omitempty"`
+	EAN              string `json:"ean,omitempty"`
+	ManufacturerAID  string `json:"manufacturer_aid,omitempty"`
+	ManufacturerName string `json:"manufacturer_name,omitempty"`
+	// Keywords are the keywords, joined by ", ".
+	Keywords    string `json:"keywords,omitempty"`
+	OrderUnit   string `json:"order_unit,omitempty"`
+	ContentUnit string `json:"content_unit,omitempty"`
+	// PriceType, PriceAmount, PriceCurrency, and PriceTax are those of the
+	// first price. PriceCurrency defaults to the currency of the catalog.
+	PriceType     string   `json:"price_type,omitempty"`
+	PriceAmount   *Decimal `json:"price_amount,omitempty"`
+	PriceCurrency string   `json:"price_currency,omitempty"`
+	PriceTax      *Decimal `json:"price_tax,omitempty"`
+	// CatalogGroupID is the first catalog group of the article, and
+	// CategoryPath the names of the groups from the root to it, joined
+	// by " > ".
+	CatalogGroupID string `json:"catalog_group_id,omitempty"`
+	CategoryPath   string `json:"category_path,omitempty"`
+	// ThumbnailURL and ImageURL are the sources of the thumbnail and the
+	// normal image, resolved against the MIME_ROOT of the catalog.
+	ThumbnailURL string `json:"thumbnail_url,omitempty"`
+	ImageURL     string `json:"image_url,omitempty"`
+	// Features maps the feature names to their values, joined by ", ",
+	// followed by the unit, if any.
+	Features map[string]string `json:"features,omitempty"`
+}
+
+// Flatten returns the flat view of the article. h and groups are used to
+// resolve the currency, the URLs of images, and the category path; both
+// may be nil.
+func Flatten(a *Article, h *Header, groups GroupIndex) FlatArticle {
+	f := FlatArticle{
+		SupplierAID: a.SupplierAID,
+		Mode:        a.Mode,
+	}
+	var catalog *Catalog
+	if h != nil {
+		catalog = h.Catalog
+	}
+	if d := a.Details; d != nil {
+		f.DescriptionShort = d.DescriptionShort
+		f.DescriptionLong = d.DescriptionLong
+		f.EAN = d.EAN
+		f.ManufacturerAID = d.ManufacturerAID
+		f.ManufacturerName = d.ManufacturerName
+		f.Keywords = strings.Join(d.Keywords, ", ")
+	}
+	if od := a.OrderDetails; od != nil {
+		f.OrderUnit = od.OrderUnit
+		f.ContentUnit = od.ContentUnit
+	}
+	if p := firstPrice(a); p != nil {
+		f.PriceType = p.Type
+		amount := p.Amount
+		f.PriceAmount = &amount
+		f.PriceCurrency = p.Currency
+		f.PriceTax = p.Tax
+	}
+	if f.PriceCurrency == "" && f.PriceType != "" && catalog != nil {
+		f.PriceCurrency = catalog.Currency
+	}
+	if len(a.CatalogGroupIDs) > 0 {
+		f.CatalogGroupID = a.CatalogGroupIDs[0]
+		if groups != nil {
+			f.CategoryPath = groups.PathNames(f.CatalogGroupID, " > ")
+		}
+	}
+	if a.MimeInfo != nil {
+		var mimeRoot string
+		if catalog != nil {
+			mimeRoot = catalog.MimeRoot
+		}
+		f.ThumbnailURL = resolveMimeSource(mimeRoot, a.MimeInfo.ThumbnailSource())
+		f.ImageURL = resolveMimeSource(mimeRoot, a.MimeInfo.NormalSource())
+	}
+	for _, af := range a.Features {
+		if af == nil {
+			continue
+		}
+		for _, feature := range af.Features {
+			if feature == nil {
+				continue
+			}
+			values := featureValues(feature)
+			if len(values) == 0 {
+				continue
+			}
+			if f.Features == nil {
+				f.Features = make(map[string]string)
+			}
+			value := strings.Join(values, ", ")
+			if feature.Unit != "" {
+				value += " " + feature.Unit
+			}
+			f.Features[feature.Name] = value
+		}
+	}
+	return f
+}
+
+// FlatArticleColumns are the column names of FlatArticle.Record.
+var FlatArticleColumns = []string{
+	"SUPPLIER_AID",
+	"MODE",
+	"DESCRIPTION_SHORT",
+	"DESCRIPTION_LONG",
+	"EAN",
+	"MANUFACTURER_AID",
+	"MANUFACTURER_NAME",
+	"KEYWORDS",
+	"ORDER_UNIT",
+	"CONTENT_UNIT",
+	"PRICE_TYPE",
+	"PRICE_AMOUNT",
+	"PRICE_CURRENCY",
+	"PRICE_TAX",
+	"CATALOG_GROUP_ID",
+	"CATEGORY_PATH",
+	"THUMBNAIL_URL",
+	"IMAGE_URL",
+}
+
+// Record returns the fields of f in the order of FlatArticleColumns,
+// followed by the values of the given features, e.g. for a CSV file.
+func (f FlatArticle) Record(features ...string) []string {
+	record := []string{
+		f.SupplierAID,
+		f.Mode,
+		f.DescriptionShort,
+		f.DescriptionLong,
+		f.EAN,
+		f.ManufacturerAID,
+		f.ManufacturerName,
+		f.Keywords,
+		f.OrderUnit,
+		f.ContentUnit,
+		f.PriceType,
+		formatFlatDecimal(f.PriceAmount),
+		f.PriceCurrency,
+		formatFlatDecimal(f.PriceTax),
+		f.CatalogGroupID,
+		f.CategoryPath,
+		f.ThumbnailURL,
+		f.ImageURL,
+	}
+	for _, name := range features {
+		record = append(record, f.Features[name])
+	}
+	return record
+}
+
+// formatFlatDecimal formats d, or returns an empty string if d is nil.
+func formatFlatDecimal(d *Decimal) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+// firstPrice returns the first price of the article, if any.
+func firstPrice(a *Article) *ArticlePrice {
+	for _, pd := range a.PriceDetails {
+		if pd == nil {
+			continue
+		}
+		for _, p := range pd.Prices {
+			if p != nil {
+				return p
+			}
+		}
+	}
+	return nil
+}
+
+// resolveMimeSource resolves a relative MIME_SOURCE against the MIME_ROOT.
+func resolveMimeSource(mimeRoot, source string) string {
+	if source == "" || mimeRoot == "" || isAbsoluteURL(source) {
+		return source
+	}
+	return strings.TrimRight(mimeRoot, "/") + "/" + strings.TrimLeft(source, "/")
+}
@@ -0,0 +1,86 @@
+package bmecat12_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestFlatten(t *testing.T) {
+	root, hardware := "1", "2"
+	groups := bmecat12.NewGroupIndex(
+		&bmecat12.CatalogGroup{Type: "root", ID: "1", Name: "Catalog"},
+		&bmecat12.CatalogGroup{Type: "node", ID: "2", Name: "Hardware", ParentID: &root},
+		&bmecat12.CatalogGroup{Type: "leaf", ID: "3", Name: "Notebooks", ParentID: &hardware},
+	)
+	h := &bmecat12.Header{
+		Catalog: &bmecat12.Catalog{Currency: "EUR", MimeRoot: "https://example.com/images/"},
+	}
+	a := &bmecat12.Article{
+		SupplierAID: "1000",
+		Details: &bmecat12.ArticleDetails{
+			DescriptionShort: "Notebook",
+			Keywords:         []string{"laptop", "mobile"},
+		},
+		OrderDetails: &bmecat12.ArticleOrderDetails{OrderUnit: "C62"},
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{Prices: []*bmecat12.ArticlePrice{
+				{Type: "net_list", Amount: bmecat12.MustParseDecimal("999.5"), Tax: decimalPtr("0.19")},
+				{Type: "gros_list", Amount: bmecat12.MustParseDecimal("1299")},
+			}},
+		},
+		Features: []*bmecat12.ArticleFeatures{
+			{Features: []*bmecat12.Feature{
+				{Name: "Weight", Values: []string{"1.5"}, Unit: "KGM"},
+				{Name: "Color", Values: []string{"black", "silver"}},
+				{Name: "Empty"},
+			}},
+		},
+		MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+			{Source: "1000_thumb.jpg", Purpose: bmecat12.MimePurposeThumbnail},
+			{Source: "https://cdn.example.com/1000.jpg", Purpose: bmecat12.MimePurposeNormal},
+		}},
+		CatalogGroupIDs: []string{"3"},
+	}
+
+	f := bmecat12.Flatten(a, h, groups)
+	if want, have := "Hardware > Notebooks", f.CategoryPath; want != have {
+		t.Errorf("want CategoryPath %q, have %q", want, have)
+	}
+	if want, have := "https://example.com/images/1000_thumb.jpg", f.ThumbnailURL; want != have {
+		t.Errorf("want ThumbnailURL %q, have %q", want, have)
+	}
+	if want, have := "https://cdn.example.com/1000.jpg", f.ImageURL; want != have {
+		t.Errorf("want ImageURL %q, have %q", want, have)
+	}
+	if want, have := "EUR", f.PriceCurrency; want != have {
+		t.Errorf("want PriceCurrency %q, have %q", want, have)
+	}
+	if want, have := 2, len(f.Features); want != have {
+		t.Errorf("want %d features, have %d", want, have)
+	}
+
+	record := f.Record("Weight", "Color", "Unknown")
+	if want, have := len(bmecat12.FlatArticleColumns)+3, len(record); want != have {
+		t.Fatalf("want %d columns, have %d", want, have)
+	}
+	want := "1000,,Notebook,,,,,laptop, mobile,C62,,net_list,999.5,EUR,0.19,3,Hardware > Notebooks,https://example.com/images/1000_thumb.jpg,https://cdn.example.com/1000.jpg,1.5 KGM,black, silver,"
+	if have := strings.Join(record, ","); want != have {
+		t.Fatalf("want record\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestGroupIndexPathWithCycle(t *testing.T) {
+	a, b := "A", "B"
+	groups := bmecat12.NewGroupIndex(
+		&bmecat12.CatalogGroup{ID: "A", Name: "A", ParentID: &b},
+		&bmecat12.CatalogGroup{ID: "B", Name: "B", ParentID: &a},
+	)
+	if want, have := "B/A", groups.PathNames("A", "/"); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	if have := groups.Path("unknown"); have != nil {
+		t.Fatalf("want nil, have %v", have)
+	}
+}
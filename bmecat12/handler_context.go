@@ -0,0 +1,114 @@
+package bmecat12
+
+import "context"
+
+// HeaderHandlerContext is like HeaderHandler, but gets the context passed
+// to Reader.Do, e.g. to observe cancellation or to get request-scoped
+// values. If a handler implements both, only HandleHeaderContext is
+// called.
+type HeaderHandlerContext interface {
+	HandleHeaderContext(ctx context.Context, header *Header) error
+}
+
+// TransactionHandlerContext is like TransactionHandler, but gets the
+// context passed to Reader.Do.
+type TransactionHandlerContext interface {
+	HandleTransactionContext(ctx context.Context, tx Transaction, prevVersion int) error
+}
+
+// FeatureSystemHandlerContext is like FeatureSystemHandler, but gets the
+// context passed to Reader.Do.
+type FeatureSystemHandlerContext interface {
+	HandleFeatureSystemContext(ctx context.Context, fs *FeatureSystem) error
+}
+
+// CatalogGroupHandlerContext is like CatalogGroupHandler, but gets the
+// context passed to Reader.Do.
+type CatalogGroupHandlerContext interface {
+	HandleCatalogGroupContext(ctx context.Context, cg *CatalogGroup) error
+}
+
+// ClassificationSystemHandlerContext is like ClassificationSystemHandler,
+// but gets the context passed to Reader.Do.
+type ClassificationSystemHandlerContext interface {
+	HandleClassificationSystemContext(ctx context.Context, cs *ClassificationSystem) error
+}
+
+// ClassificationGroupHandlerContext is like ClassificationGroupHandler,
+// but gets the context passed to Reader.Do.
+type ClassificationGroupHandlerContext interface {
+	HandleClassificationGroupContext(ctx context.Context, cg *ClassificationGroup) error
+}
+
+// ArticleHandlerContext is like ArticleHandler, but gets the context
+// passed to Reader.Do. If a handler implements both, only
+// HandleArticleContext is called.
+type ArticleHandlerContext interface {
+	HandleArticleContext(ctx context.Context, a *Article) error
+}
+
+// The adapters below bind a context to a handler with context, so the
+// Reader can call it like a handler without context.
+
+type headerHandlerContext struct {
+	ctx context.Context
+	h   HeaderHandlerContext
+}
+
+func (a headerHandlerContext) HandleHeader(header *Header) error {
+	return a.h.HandleHeaderContext(a.ctx, header)
+}
+
+type transactionHandlerContext struct {
+	ctx context.Context
+	h   TransactionHandlerContext
+}
+
+func (a transactionHandlerContext) HandleTransaction(tx Transaction, prevVersion int) error {
+	return a.h.HandleTransactionContext(a.ctx, tx, prevVersion)
+}
+
+type featureSystemHandlerContext struct {
+	ctx context.Context
+	h   FeatureSystemHandlerContext
+}
+
+func (a featureSystemHandlerContext) HandleFeatureSystem(fs *FeatureSystem) error {
+	return a.h.HandleFeatureSystemContext(a.ctx, fs)
+}
+
+type catalogGroupHandlerContext struct {
+	ctx context.Context
+	h   CatalogGroupHandlerContext
+}
+
+func (a catalogGroupHandlerContext) HandleCatalogGroup(cg *CatalogGroup) error {
+	return a.h.HandleCatalogGroupContext(a.ctx, cg)
+}
+
+type classificationSystemHandlerContext struct {
+	ctx context.Context
+	h   ClassificationSystemHandlerContext
+}
+
+func (a classificationSystemHandlerContext) HandleClassificationSystem(cs *ClassificationSystem) error {
+	return a.h.HandleClassificationSystemContext(a.ctx, cs)
+}
+
+type classificationGroupHandlerContext struct {
+	ctx context.Context
+	h   ClassificationGroupHandlerContext
+}
+
+func (a classificationGroupHandlerContext) HandleClassificationGroup(cg *ClassificationGroup) error {
+	return a.h.HandleClassificationGroupContext(a.ctx, cg)
+}
+
+type articleHandlerContext struct {
+	ctx context.Context
+	h   ArticleHandlerContext
+}
+
+func (a articleHandlerContext) HandleArticle(article *Article) error {
+	return a.h.HandleArticleContext(a.ctx, article)
+}
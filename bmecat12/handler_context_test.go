@@ -0,0 +1,127 @@
+package bmecat12_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+type ctxKey struct{}
+
+type articleContextHandler struct {
+	values  []interface{}
+	plain   int
+	cancel  context.CancelFunc
+	stopped bool
+}
+
+func (h *articleContextHandler) HandleArticle(a *bmecat12.Article) error {
+	h.plain++
+	return nil
+}
+
+func (h *articleContextHandler) HandleArticleContext(ctx context.Context, a *bmecat12.Article) error {
+	if h.cancel != nil {
+		h.cancel()
+		h.cancel = nil
+	}
+	if err := ctx.Err(); err != nil {
+		h.stopped = true
+		return err
+	}
+	h.values = append(h.values, ctx.Value(ctxKey{}))
+	return nil
+}
+
+func TestHandlerContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-1")
+	h := &articleContextHandler{}
+	r := bmecat12.NewReader(multiArticles("T_NEW_CATALOG",
+		`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+		`<ARTICLE><SUPPLIER_AID>2000</SUPPLIER_AID></ARTICLE>`,
+	))
+	if err := r.Do(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.values); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	for i, v := range h.values {
+		if want, have := "request-1", v; want != have {
+			t.Fatalf("article %d: want context value %v, have %v", i, want, have)
+		}
+	}
+	if want, have := 0, h.plain; want != have {
+		t.Fatalf("want HandleArticle to be called %d times, have %d", want, have)
+	}
+}
+
+func TestHandlerContextCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	h := &articleContextHandler{cancel: cancel}
+	r := bmecat12.NewReader(multiArticles("T_NEW_CATALOG",
+		`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+	))
+	err := r.Do(ctx, h)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("want %v, have %v", context.Canceled, err)
+	}
+	if !h.stopped {
+		t.Fatal("want handler to observe cancellation")
+	}
+}
+
+func TestHandlerFuncsContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-2")
+	var values []interface{}
+	var plain int
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			plain++
+			return nil
+		},
+		OnArticleContext: func(ctx context.Context, a *bmecat12.Article) error {
+			values = append(values, ctx.Value(ctxKey{}))
+			return nil
+		},
+	}
+	r := bmecat12.NewReader(multiArticles("T_NEW_CATALOG",
+		`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+	))
+	if err := r.Do(ctx, h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(values); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	if want, have := "request-2", values[0]; want != have {
+		t.Fatalf("want context value %v, have %v", want, have)
+	}
+	if want, have := 0, plain; want != have {
+		t.Fatalf("want OnArticle to be called %d times, have %d", want, have)
+	}
+}
+
+func TestMultiHandlerContext(t *testing.T) {
+	ctx := context.WithValue(context.Background(), ctxKey{}, "request-3")
+	withContext := &articleContextHandler{}
+	plain := &testHandler{}
+	r := bmecat12.NewReader(multiArticles("T_NEW_CATALOG",
+		`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+	))
+	if err := r.Do(ctx, bmecat12.MultiHandler(withContext, plain)); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(withContext.values); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	if want, have := "request-3", withContext.values[0]; want != have {
+		t.Fatalf("want context value %v, have %v", want, have)
+	}
+	if want, have := 1, len(plain.articles); want != have {
+		t.Fatalf("want %d articles in plain handler, have %d", want, have)
+	}
+}
@@ -0,0 +1,212 @@
+package bmecat12
+
+import "context"
+
+// HandlerFuncs is an adapter that allows the use of ordinary functions
+// as a handler for the Reader. It implements all handler interfaces;
+// events for which no function is set are ignored. If both a function
+// with and without context is set for an event, e.g. OnArticleContext
+// and OnArticle, only the one with context is called.
+//
+// Example:
+//
+//	var n int
+//	err := r.Do(ctx, bmecat12.HandlerFuncs{
+//		OnArticle: func(a *bmecat12.Article) error {
+//			n++
+//			return nil
+//		},
+//	})
+type HandlerFuncs struct {
+	OnProlog               func(*Prolog) error
+	OnHeader               func(*Header) error
+	OnTransaction          func(tx Transaction, prevVersion int) error
+	OnFeatureSystem        func(*FeatureSystem) error
+	OnCatalogGroup         func(*CatalogGroup) error
+	OnClassificationSystem func(*ClassificationSystem) error
+	OnClassificationGroup  func(*ClassificationGroup) error
+	OnArticle              func(*Article) error
+	OnArticleOffset        func(supplierAID string, start, end int64) error
+	OnSkippedArticle       func(supplierAID string, size int64) error
+	OnWarning              func(*Warning) error
+	OnAudit                func(*AuditEvent) error
+	OnComplete             func()
+	OnCompleteStats        func(*CompletionStats)
+
+	OnHeaderContext               func(context.Context, *Header) error
+	OnTransactionContext          func(ctx context.Context, tx Transaction, prevVersion int) error
+	OnFeatureSystemContext        func(context.Context, *FeatureSystem) error
+	OnCatalogGroupContext         func(context.Context, *CatalogGroup) error
+	OnClassificationSystemContext func(context.Context, *ClassificationSystem) error
+	OnClassificationGroupContext  func(context.Context, *ClassificationGroup) error
+	OnArticleContext              func(context.Context, *Article) error
+}
+
+// HandleProlog implements the DocumentHandler interface.
+func (h HandlerFuncs) HandleProlog(p *Prolog) error {
+	if h.OnProlog != nil {
+		return h.OnProlog(p)
+	}
+	return nil
+}
+
+// HandleHeader implements the HeaderHandler interface.
+func (h HandlerFuncs) HandleHeader(header *Header) error {
+	if h.OnHeader != nil {
+		return h.OnHeader(header)
+	}
+	return nil
+}
+
+// HandleTransaction implements the TransactionHandler interface.
+func (h HandlerFuncs) HandleTransaction(tx Transaction, prevVersion int) error {
+	if h.OnTransaction != nil {
+		return h.OnTransaction(tx, prevVersion)
+	}
+	return nil
+}
+
+// HandleFeatureSystem implements the FeatureSystemHandler interface.
+func (h HandlerFuncs) HandleFeatureSystem(fs *FeatureSystem) error {
+	if h.OnFeatureSystem != nil {
+		return h.OnFeatureSystem(fs)
+	}
+	return nil
+}
+
+// HandleCatalogGroup implements the CatalogGroupHandler interface.
+func (h HandlerFuncs) HandleCatalogGroup(cg *CatalogGroup) error {
+	if h.OnCatalogGroup != nil {
+		return h.OnCatalogGroup(cg)
+	}
+	return nil
+}
+
+// HandleClassificationSystem implements the ClassificationSystemHandler interface.
+func (h HandlerFuncs) HandleClassificationSystem(cs *ClassificationSystem) error {
+	if h.OnClassificationSystem != nil {
+		return h.OnClassificationSystem(cs)
+	}
+	return nil
+}
+
+// HandleClassificationGroup implements the ClassificationGroupHandler interface.
+func (h HandlerFuncs) HandleClassificationGroup(cg *ClassificationGroup) error {
+	if h.OnClassificationGroup != nil {
+		return h.OnClassificationGroup(cg)
+	}
+	return nil
+}
+
+// HandleArticle implements the ArticleHandler interface.
+func (h HandlerFuncs) HandleArticle(a *Article) error {
+	if h.OnArticle != nil {
+		return h.OnArticle(a)
+	}
+	return nil
+}
+
+// HandleArticleOffset implements the ArticleOffsetHandler interface.
+func (h HandlerFuncs) HandleArticleOffset(supplierAID string, start, end int64) error {
+	if h.OnArticleOffset != nil {
+		return h.OnArticleOffset(supplierAID, start, end)
+	}
+	return nil
+}
+
+// HandleSkippedArticle implements the SkippedArticleHandler interface.
+func (h HandlerFuncs) HandleSkippedArticle(supplierAID string, size int64) error {
+	if h.OnSkippedArticle != nil {
+		return h.OnSkippedArticle(supplierAID, size)
+	}
+	return nil
+}
+
+// HandleWarning implements the WarningHandler interface.
+func (h HandlerFuncs) HandleWarning(w *Warning) error {
+	if h.OnWarning != nil {
+		return h.OnWarning(w)
+	}
+	return nil
+}
+
+// HandleAudit implements the AuditHandler interface.
+func (h HandlerFuncs) HandleAudit(e *AuditEvent) error {
+	if h.OnAudit != nil {
+		return h.OnAudit(e)
+	}
+	return nil
+}
+
+// HandleComplete implements the CompletionHandler interface.
+func (h HandlerFuncs) HandleComplete() {
+	if h.OnComplete != nil {
+		h.OnComplete()
+	}
+}
+
+// HandleCompleteStats implements the CompletionStatsHandler interface.
+// It calls OnComplete if OnCompleteStats is not set.
+func (h HandlerFuncs) HandleCompleteStats(stats *CompletionStats) {
+	if h.OnCompleteStats != nil {
+		h.OnCompleteStats(stats)
+		return
+	}
+	h.HandleComplete()
+}
+
+// HandleHeaderContext implements the HeaderHandlerContext interface.
+func (h HandlerFuncs) HandleHeaderContext(ctx context.Context, header *Header) error {
+	if h.OnHeaderContext != nil {
+		return h.OnHeaderContext(ctx, header)
+	}
+	return h.HandleHeader(header)
+}
+
+// HandleTransactionContext implements the TransactionHandlerContext interface.
+func (h HandlerFuncs) HandleTransactionContext(ctx context.Context, tx Transaction, prevVersion int) error {
+	if h.OnTransactionContext != nil {
+		return h.OnTransactionContext(ctx, tx, prevVersion)
+	}
+	return h.HandleTransaction(tx, prevVersion)
+}
+
+// HandleFeatureSystemContext implements the FeatureSystemHandlerContext interface.
+func (h HandlerFuncs) HandleFeatureSystemContext(ctx context.Context, fs *FeatureSystem) error {
+	if h.OnFeatureSystemContext != nil {
+		return h.OnFeatureSystemContext(ctx, fs)
+	}
+	return h.HandleFeatureSystem(fs)
+}
+
+// HandleCatalogGroupContext implements the CatalogGroupHandlerContext interface.
+func (h HandlerFuncs) HandleCatalogGroupContext(ctx context.Context, cg *CatalogGroup) error {
+	if h.OnCatalogGroupContext != nil {
+		return h.OnCatalogGroupContext(ctx, cg)
+	}
+	return h.HandleCatalogGroup(cg)
+}
+
+// HandleClassificationSystemContext implements the ClassificationSystemHandlerContext interface.
+func (h HandlerFuncs) HandleClassificationSystemContext(ctx context.Context, cs *ClassificationSystem) error {
+	if h.OnClassificationSystemContext != nil {
+		return h.OnClassificationSystemContext(ctx, cs)
+	}
+	return h.HandleClassificationSystem(cs)
+}
+
+// HandleClassificationGroupContext implements the ClassificationGroupHandlerContext interface.
+func (h HandlerFuncs) HandleClassificationGroupContext(ctx context.Context, cg *ClassificationGroup) error {
+	if h.OnClassificationGroupContext != nil {
+		return h.OnClassificationGroupContext(ctx, cg)
+	}
+	return h.HandleClassificationGroup(cg)
+}
+
+// HandleArticleContext implements the ArticleHandlerContext interface.
+func (h HandlerFuncs) HandleArticleContext(ctx context.Context, a *Article) error {
+	if h.OnArticleContext != nil {
+		return h.OnArticleContext(ctx, a)
+	}
+	return h.HandleArticle(a)
+}
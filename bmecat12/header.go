@@ -23,6 +23,10 @@ type Header struct {
 	NumberOfCatalogGroups             int `xml:"-"`
 	NumberOfClassificationGroups      int `xml:"-"`
 	NumberOfArticleToCatalogGroupMaps int `xml:"-"`
+
+	// Transaction and PreviousVersion are gathered on the 1st pass of the parser.
+	Transaction     Transaction `xml:"-"`
+	PreviousVersion int         `xml:"-"`
 }
 
 type Catalog struct {
@@ -150,3 +154,35 @@ type Supplier struct {
 	Address  *Address  `xml:"ADDRESS,omitempty"`
 	MimeInfo *MimeInfo `xml:"MIME_INFO,omitempty"`
 }
+
+// checkHeader returns a HeaderError listing the mandatory fields that
+// are missing in h, or nil.
+func checkHeader(h *Header) error {
+	if h == nil {
+		return &HeaderError{Missing: []string{"HEADER"}}
+	}
+	var missing []string
+	if c := h.Catalog; c == nil {
+		missing = append(missing, "HEADER/CATALOG")
+	} else {
+		if c.Language == "" {
+			missing = append(missing, "HEADER/CATALOG/LANGUAGE")
+		}
+		if c.ID == "" {
+			missing = append(missing, "HEADER/CATALOG/CATALOG_ID")
+		}
+		if c.Version == "" {
+			missing = append(missing, "HEADER/CATALOG/CATALOG_VERSION")
+		}
+	}
+	if h.Buyer != nil && h.Buyer.Name == "" {
+		missing = append(missing, "HEADER/BUYER/BUYER_NAME")
+	}
+	if h.Supplier != nil && h.Supplier.Name == "" {
+		missing = append(missing, "HEADER/SUPPLIER/SUPPLIER_NAME")
+	}
+	if len(missing) > 0 {
+		return &HeaderError{Missing: missing}
+	}
+	return nil
+}
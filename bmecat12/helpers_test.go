@@ -3,6 +3,8 @@ package bmecat12_test
 import (
 	"strings"
 	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
 )
 
 func diffStrings(t testing.TB, want, have string) {
@@ -25,3 +27,12 @@ func diffStrings(t testing.TB, want, have string) {
 	}
 	t.Fail()
 }
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+func decimalPtr(s string) *bmecat12.Decimal {
+	d := bmecat12.MustParseDecimal(s)
+	return &d
+}
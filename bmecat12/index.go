@@ -0,0 +1,652 @@
+package bmecat12
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+
+	"github.com/olivere/bmecat/internal"
+)
+
+// ErrArticleNotFound is returned by RandomAccessReader if the index has
+// no article with the given SUPPLIER_AID.
+var ErrArticleNotFound = errors.New("bmecat: article not found")
+
+// IndexEntry is the position of an ARTICLE element in a catalog file.
+type IndexEntry struct {
+	// Start is the byte offset of the start tag.
+	Start int64
+	// End is the byte offset just after the end tag.
+	End int64
+	// CatalogGroupIDs are the catalog groups of the article, as found in
+	// the ARTICLE_TO_CATALOGGROUP_MAP elements.
+	CatalogGroupIDs []string
+}
+
+// Index maps the SUPPLIER_AID of each article of a catalog file to the
+// byte range of its ARTICLE element. Use BuildIndex to create it, and
+// WriteTo and ReadIndex to store it in a sidecar file next to the
+// catalog. See RandomAccessReader.
+type Index struct {
+	// Size is the size of the catalog file indexed.
+	Size int64
+	// ModTime is the modification time of the catalog file indexed, in
+	// nanoseconds since the epoch, if known. OpenRandomAccess uses Size
+	// and ModTime to detect stale indexes.
+	ModTime int64
+	// Encoding is the encoding of the catalog, as declared in its XML
+	// declaration.
+	Encoding string
+
+	entries map[string]*IndexEntry
+}
+
+// Len returns the number of articles in the index.
+func (ix *Index) Len() int {
+	return len(ix.entries)
+}
+
+// Lookup returns the position of the article with the given SUPPLIER_AID.
+func (ix *Index) Lookup(supplierAID string) (IndexEntry, bool) {
+	e, found := ix.entries[supplierAID]
+	if !found {
+		return IndexEntry{}, false
+	}
+	return *e, true
+}
+
+var xmlEncodingRe = regexp.MustCompile(`encoding\s*=\s*["']([^"']+)["']`)
+
+// BuildIndex reads the catalog from r and returns its index. It scans the
+// raw bytes instead of decoding the whole document, so the offsets are
+// offsets into the file for all ASCII-compatible encodings, like UTF-8 and
+// ISO-8859-1. BuildIndex returns an error for gzip-compressed and UTF-16
+// input, whose offsets cannot be read at random. Only WithCharsetReader
+// and WithEntityMap are applied of the options, to decode the
+// SUPPLIER_AIDs. If an article occurs more than once, the last one wins.
+func BuildIndex(r io.Reader, options ...ReaderOption) (*Index, error) {
+	rd := &Reader{charsetReader: internal.AutoCharsetReader}
+	for _, o := range options {
+		o(rd)
+	}
+	ix := &Index{entries: make(map[string]*IndexEntry)}
+	s := &indexScanner{r: bufio.NewReaderSize(r, 64*1024)}
+	prefix, _ := s.r.Peek(4)
+	switch {
+	case bytes.HasPrefix(prefix, gzipMagic):
+		return nil, errors.New("bmecat: unable to index gzip-compressed input")
+	case internal.NeedsBOMDecoding(prefix) && !bytes.HasPrefix(prefix, []byte("\xef\xbb\xbf")):
+		return nil, errors.New("bmecat: unable to index UTF-16 input")
+	}
+	groups := make(map[string][]string)
+
+	var article *IndexEntry
+	var supplierAID string
+	for {
+		b, err := s.readByte()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		if b != '<' {
+			continue
+		}
+		start := s.offset - 1
+		b, err = s.readByte()
+		if err != nil {
+			return nil, s.unexpected(err)
+		}
+		switch b {
+		case '?':
+			pi, err := s.readUntil("?>", true)
+			if err != nil {
+				return nil, s.unexpected(err)
+			}
+			if ix.Encoding == "" && bytes.HasPrefix(pi, []byte("xml")) {
+				if m := xmlEncodingRe.FindSubmatch(pi); m != nil {
+					ix.Encoding = string(m[1])
+				}
+			}
+		case '!':
+			if err := s.skipDeclaration(); err != nil {
+				return nil, s.unexpected(err)
+			}
+		case '/':
+			name, err := s.readName()
+			if err != nil {
+				return nil, s.unexpected(err)
+			}
+			if _, err := s.readUntil(">", false); err != nil {
+				return nil, s.unexpected(err)
+			}
+			if name == "ARTICLE" && article != nil {
+				article.End = s.offset
+				if supplierAID != "" {
+					ix.entries[supplierAID] = article
+				}
+				article = nil
+			}
+		default:
+			if err := s.unreadByte(); err != nil {
+				return nil, err
+			}
+			name, err := s.readName()
+			if err != nil {
+				return nil, s.unexpected(err)
+			}
+			empty, err := s.readTag()
+			if err != nil {
+				return nil, s.unexpected(err)
+			}
+			if empty {
+				break
+			}
+			switch name {
+			case "ARTICLE":
+				article = &IndexEntry{Start: start}
+				supplierAID = ""
+			case "SUPPLIER_AID":
+				if article == nil || supplierAID != "" {
+					break
+				}
+				text, err := s.readUntil("</SUPPLIER_AID>", true)
+				if err != nil {
+					return nil, s.unexpected(err)
+				}
+				fragment := append([]byte("<SUPPLIER_AID>"), text...)
+				if err := ix.decodeFragment(rd, append(fragment, "</SUPPLIER_AID>"...), &supplierAID); err != nil {
+					return nil, fmt.Errorf("bmecat: unable to decode SUPPLIER_AID around byte offset %d: %v", s.offset, err)
+				}
+			case "ARTICLE_TO_CATALOGGROUP_MAP":
+				content, err := s.readUntil("</ARTICLE_TO_CATALOGGROUP_MAP>", true)
+				if err != nil {
+					return nil, s.unexpected(err)
+				}
+				fragment := append([]byte("<ARTICLE_TO_CATALOGGROUP_MAP>"), content...)
+				var m ArticleToCatalogGroupMap
+				if err := ix.decodeFragment(rd, append(fragment, "</ARTICLE_TO_CATALOGGROUP_MAP>"...), &m); err != nil {
+					return nil, fmt.Errorf("bmecat: unable to decode ARTICLE_TO_CATALOGGROUP_MAP around byte offset %d: %v", s.offset, err)
+				}
+				groups[m.ArticleID] = append(groups[m.ArticleID], m.CatalogGroupID)
+			}
+		}
+	}
+	for aid, ids := range groups {
+		if e, found := ix.entries[aid]; found {
+			e.CatalogGroupIDs = ids
+		}
+	}
+	ix.Size = s.offset
+	return ix, nil
+}
+
+// decodeFragment decodes a fragment of the catalog into v.
+func (ix *Index) decodeFragment(rd *Reader, fragment []byte, v interface{}) error {
+	var buf bytes.Buffer
+	if ix.Encoding != "" {
+		fmt.Fprintf(&buf, `<?xml version="1.0" encoding="%s"?>`, ix.Encoding)
+	}
+	buf.Write(fragment)
+	dec := xml.NewDecoder(&buf)
+	dec.CharsetReader = rd.charsetReader
+	dec.Entity = rd.entities
+	return dec.Decode(v)
+}
+
+// indexScanner reads a catalog byte by byte, keeping track of the offset.
+type indexScanner struct {
+	r      *bufio.Reader
+	offset int64
+}
+
+func (s *indexScanner) readByte() (byte, error) {
+	b, err := s.r.ReadByte()
+	if err == nil {
+		s.offset++
+	}
+	return b, err
+}
+
+func (s *indexScanner) unreadByte() error {
+	if err := s.r.UnreadByte(); err != nil {
+		return err
+	}
+	s.offset--
+	return nil
+}
+
+// unexpected turns io.EOF into io.ErrUnexpectedEOF.
+func (s *indexScanner) unexpected(err error) error {
+	if err == io.EOF {
+		err = io.ErrUnexpectedEOF
+	}
+	return fmt.Errorf("bmecat: unable to index catalog at byte offset %d: %v", s.offset, err)
+}
+
+// readName reads an element name.
+func (s *indexScanner) readName() (string, error) {
+	var name []byte
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return "", err
+		}
+		if b == ' ' || b == '\t' || b == '\r' || b == '\n' || b == '>' || b == '/' {
+			return string(name), s.unreadByte()
+		}
+		name = append(name, b)
+	}
+}
+
+// readTag reads the rest of a start tag, including the closing '>'. It
+// returns true if the element is empty, i.e. the tag ends with "/>".
+func (s *indexScanner) readTag() (bool, error) {
+	var quote, prev byte
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return false, err
+		}
+		switch {
+		case quote != 0:
+			if b == quote {
+				quote = 0
+			}
+		case b == '"' || b == '\'':
+			quote = b
+		case b == '>':
+			return prev == '/', nil
+		}
+		prev = b
+	}
+}
+
+// readUntil reads up to and including delim. If collect is true, it
+// returns the bytes read before delim.
+func (s *indexScanner) readUntil(delim string, collect bool) ([]byte, error) {
+	var buf []byte
+	var tail []byte
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return nil, err
+		}
+		if collect {
+			buf = append(buf, b)
+		}
+		tail = append(tail, b)
+		if len(tail) > len(delim) {
+			tail = tail[1:]
+		}
+		if string(tail) == delim {
+			if collect {
+				return buf[:len(buf)-len(delim)], nil
+			}
+			return nil, nil
+		}
+	}
+}
+
+// skipDeclaration skips a comment, a CDATA section, or a declaration like
+// DOCTYPE, after the "<!" has been read.
+func (s *indexScanner) skipDeclaration() error {
+	prefix, err := s.r.Peek(2)
+	if err == nil && string(prefix) == "--" {
+		_, err = s.readUntil("-->", false)
+		return err
+	}
+	prefix, err = s.r.Peek(7)
+	if err == nil && string(prefix) == "[CDATA[" {
+		_, err = s.readUntil("]]>", false)
+		return err
+	}
+	// DOCTYPE, possibly with an internal subset
+	var depth int
+	for {
+		b, err := s.readByte()
+		if err != nil {
+			return err
+		}
+		switch b {
+		case '[':
+			depth++
+		case ']':
+			depth--
+		case '>':
+			if depth <= 0 {
+				return nil
+			}
+		}
+	}
+}
+
+// indexMagic identifies the sidecar index format.
+const indexMagic = "BMECATIX\x01"
+
+// WriteTo writes the index in a compact binary format. It implements the
+// io.WriterTo interface.
+func (ix *Index) WriteTo(w io.Writer) (int64, error) {
+	cw := &indexWriter{w: bufio.NewWriter(w)}
+	cw.writeString(indexMagic)
+	cw.writeUvarint(uint64(ix.Size))
+	cw.writeVarint(ix.ModTime)
+	cw.writeString(ix.Encoding)
+	cw.writeUvarint(uint64(len(ix.entries)))
+
+	// Sort by offset, so the offsets can be delta-encoded
+	aids := make([]string, 0, len(ix.entries))
+	for aid := range ix.entries {
+		aids = append(aids, aid)
+	}
+	sort.Slice(aids, func(i, j int) bool {
+		return ix.entries[aids[i]].Start < ix.entries[aids[j]].Start
+	})
+	var prev int64
+	for _, aid := range aids {
+		e := ix.entries[aid]
+		cw.writeString(aid)
+		cw.writeUvarint(uint64(e.Start - prev))
+		cw.writeUvarint(uint64(e.End - e.Start))
+		cw.writeUvarint(uint64(len(e.CatalogGroupIDs)))
+		for _, id := range e.CatalogGroupIDs {
+			cw.writeString(id)
+		}
+		prev = e.Start
+	}
+	if cw.err == nil {
+		cw.err = cw.w.Flush()
+	}
+	return cw.n, cw.err
+}
+
+// indexWriter writes the binary index format, keeping the first error.
+type indexWriter struct {
+	w   *bufio.Writer
+	n   int64
+	err error
+	buf [binary.MaxVarintLen64]byte
+}
+
+func (w *indexWriter) write(p []byte) {
+	if w.err != nil {
+		return
+	}
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	w.err = err
+}
+
+func (w *indexWriter) writeUvarint(x uint64) {
+	w.write(w.buf[:binary.PutUvarint(w.buf[:], x)])
+}
+
+func (w *indexWriter) writeVarint(x int64) {
+	w.write(w.buf[:binary.PutVarint(w.buf[:], x)])
+}
+
+func (w *indexWriter) writeString(s string) {
+	w.writeUvarint(uint64(len(s)))
+	w.write([]byte(s))
+}
+
+// ReadIndex reads an index written with Index.WriteTo. It returns an
+// error if the number of entries exceeds what the remaining input can
+// hold, so a corrupt index is not trusted.
+func ReadIndex(r io.Reader) (*Index, error) {
+	remaining, sized := remainingSize(r)
+	br := bufio.NewReader(r)
+	ir := &indexReader{r: br}
+	if magic := ir.readString(); ir.err == nil && magic != indexMagic {
+		return nil, errors.New("bmecat: invalid index format")
+	}
+	ix := &Index{}
+	ix.Size = int64(ir.readUvarint())
+	ix.ModTime = ir.readVarint()
+	ix.Encoding = ir.readString()
+	n := ir.readUvarint()
+	if ir.err != nil {
+		return nil, fmt.Errorf("bmecat: unable to read index: %v", ir.err)
+	}
+	hint := n
+	switch {
+	case sized && n > uint64(remaining)/minIndexEntrySize:
+		return nil, fmt.Errorf("bmecat: unable to read index: %d entries exceed the size of the index", n)
+	case !sized && hint > maxIndexEntriesHint:
+		hint = maxIndexEntriesHint
+	}
+	ix.entries = make(map[string]*IndexEntry, hint)
+	var prev int64
+	for i := uint64(0); i < n && ir.err == nil; i++ {
+		aid := ir.readString()
+		e := &IndexEntry{Start: prev + int64(ir.readUvarint())}
+		e.End = e.Start + int64(ir.readUvarint())
+		for j := ir.readUvarint(); j > 0 && ir.err == nil; j-- {
+			e.CatalogGroupIDs = append(e.CatalogGroupIDs, ir.readString())
+		}
+		ix.entries[aid] = e
+		prev = e.Start
+	}
+	if ir.err != nil {
+		return nil, fmt.Errorf("bmecat: unable to read index: %v", ir.err)
+	}
+	return ix, nil
+}
+
+const (
+	// minIndexEntrySize is the minimum size of an entry in the binary
+	// index format: the length of SUPPLIER_AID, the start, the length,
+	// and the number of catalog groups take at least one byte each.
+	minIndexEntrySize = 4
+
+	// maxIndexEntriesHint limits the entries allocated in advance if the
+	// size of the index is unknown.
+	maxIndexEntriesHint = 1 << 16
+)
+
+// remainingSize returns the number of bytes left in r, if r knows it.
+func remainingSize(r io.Reader) (int64, bool) {
+	switch r := r.(type) {
+	case interface{ Len() int }:
+		return int64(r.Len()), true
+	case *os.File:
+		fi, err := r.Stat()
+		if err != nil || !fi.Mode().IsRegular() {
+			return 0, false
+		}
+		pos, err := r.Seek(0, io.SeekCurrent)
+		if err != nil {
+			return 0, false
+		}
+		return fi.Size() - pos, true
+	}
+	return 0, false
+}
+
+// indexReader reads the binary index format, keeping the first error.
+type indexReader struct {
+	r   *bufio.Reader
+	err error
+}
+
+func (r *indexReader) readUvarint() uint64 {
+	if r.err != nil {
+		return 0
+	}
+	var x uint64
+	x, r.err = binary.ReadUvarint(r.r)
+	return x
+}
+
+func (r *indexReader) readVarint() int64 {
+	if r.err != nil {
+		return 0
+	}
+	var x int64
+	x, r.err = binary.ReadVarint(r.r)
+	return x
+}
+
+func (r *indexReader) readString() string {
+	n := r.readUvarint()
+	if r.err != nil {
+		return ""
+	}
+	if n > 1<<20 {
+		r.err = errors.New("string too long")
+		return ""
+	}
+	buf := make([]byte, n)
+	_, r.err = io.ReadFull(r.r, buf)
+	return string(buf)
+}
+
+// RandomAccessReader reads single articles from a catalog by their
+// SUPPLIER_AID, using an Index. It is safe for concurrent use.
+type RandomAccessReader struct {
+	r      io.ReaderAt
+	ix     *Index
+	rd     *Reader
+	closer io.Closer
+}
+
+// NewRandomAccessReader creates a RandomAccessReader for the catalog r
+// with the index ix. Reader options like WithCharsetReader,
+// WithEntityMap, WithSkipSections, and WithEclassMapper are applied.
+func NewRandomAccessReader(r io.ReaderAt, ix *Index, options ...ReaderOption) *RandomAccessReader {
+	rd := &Reader{charsetReader: internal.AutoCharsetReader}
+	for _, o := range options {
+		o(rd)
+	}
+	return &RandomAccessReader{r: r, ix: ix, rd: rd}
+}
+
+// IndexFilename returns the name of the sidecar index file of a catalog.
+func IndexFilename(filename string) string {
+	return filename + ".idx"
+}
+
+// OpenRandomAccess opens the catalog file for random access. It uses the
+// sidecar index file, see IndexFilename, if it is up to date. Otherwise
+// it builds the index and writes the sidecar file. Close the
+// RandomAccessReader when done.
+func OpenRandomAccess(filename string, options ...ReaderOption) (*RandomAccessReader, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	fi, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	ix, err := readIndexFile(IndexFilename(filename))
+	if err != nil || ix.Size != fi.Size() || ix.ModTime != fi.ModTime().UnixNano() {
+		ix, err = BuildIndex(f, options...)
+		if err != nil {
+			f.Close()
+			return nil, err
+		}
+		ix.ModTime = fi.ModTime().UnixNano()
+		if err := writeIndexFile(IndexFilename(filename), ix); err != nil {
+			f.Close()
+			return nil, err
+		}
+	}
+	r := NewRandomAccessReader(f, ix, options...)
+	r.closer = f
+	return r, nil
+}
+
+// readIndexFile reads the index from a sidecar file.
+func readIndexFile(filename string) (*Index, error) {
+	f, err := os.Open(filename)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadIndex(f)
+}
+
+// writeIndexFile writes the index to a sidecar file. It writes to a
+// temporary file first, so readers never see a partial index.
+func writeIndexFile(filename string, ix *Index) error {
+	tmp := filename + ".tmp"
+	f, err := os.Create(tmp)
+	if err != nil {
+		return err
+	}
+	if _, err := ix.WriteTo(f); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return os.Rename(tmp, filename)
+}
+
+// Index returns the index of the catalog.
+func (r *RandomAccessReader) Index() *Index {
+	return r.ix
+}
+
+// Article reads the article with the given SUPPLIER_AID. It returns
+// ErrArticleNotFound if the index has no such article.
+func (r *RandomAccessReader) Article(supplierAID string) (*Article, error) {
+	e, found := r.ix.entries[supplierAID]
+	if !found {
+		return nil, ErrArticleNotFound
+	}
+	fragment := make([]byte, e.End-e.Start)
+	if _, err := r.r.ReadAt(fragment, e.Start); err != nil {
+		return nil, &ParseError{Element: "ARTICLE", SupplierAID: supplierAID, Offset: e.Start, Err: err}
+	}
+	var buf bytes.Buffer
+	if r.ix.Encoding != "" {
+		fmt.Fprintf(&buf, `<?xml version="1.0" encoding="%s"?>`, r.ix.Encoding)
+	}
+	buf.Write(fragment)
+	dec := xml.NewDecoder(&buf)
+	dec.CharsetReader = r.rd.charsetReader
+	dec.Entity = r.rd.entities
+	a := &Article{}
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return nil, &ParseError{Element: "ARTICLE", SupplierAID: supplierAID, Offset: e.Start, Err: err}
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			if err := decodeArticle(dec, &se, a, r.rd.skipSections, r.rd.fastDecoder); err != nil {
+				return nil, &ParseError{Element: "ARTICLE", SupplierAID: supplierAID, Offset: e.Start, Err: err}
+			}
+			break
+		}
+	}
+	a.CatalogGroupIDs = e.CatalogGroupIDs
+	if r.rd.eclassMapper != nil {
+		if err := MapEclassFeatures(a, r.rd.eclassMapper); err != nil {
+			return nil, err
+		}
+	}
+	return a, nil
+}
+
+// Close closes the catalog file if the RandomAccessReader has been
+// opened with OpenRandomAccess.
+func (r *RandomAccessReader) Close() error {
+	if r.closer != nil {
+		return r.closer.Close()
+	}
+	return nil
+}
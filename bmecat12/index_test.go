@@ -0,0 +1,221 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const indexDoc = `<?xml version="1.0" encoding="ISO-8859-1"?>
+<!DOCTYPE BMECAT SYSTEM "bmecat_new_catalog.dtd">
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <!-- <ARTICLE><SUPPLIER_AID>comment</SUPPLIER_AID></ARTICLE> -->
+    <ARTICLE mode="new">
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Schraube</DESCRIPTION_SHORT></ARTICLE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>Ä&amp;2000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT><![CDATA[Mutter </ARTICLE>]]></DESCRIPTION_SHORT></ARTICLE_DETAILS>
+    </ARTICLE>
+    <ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>Ä&amp;2000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>
+    <ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>Ä&amp;2000</ART_ID><CATALOG_GROUP_ID>20</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+func indexCatalog(t *testing.T) []byte {
+	t.Helper()
+	data, err := charmap.ISO8859_1.NewEncoder().Bytes([]byte(indexDoc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func TestBuildIndex(t *testing.T) {
+	data := indexCatalog(t)
+	ix, err := bmecat12.BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, ix.Len(); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	if want, have := int64(len(data)), ix.Size; want != have {
+		t.Fatalf("want Size=%d, have %d", want, have)
+	}
+	if want, have := "ISO-8859-1", ix.Encoding; want != have {
+		t.Fatalf("want Encoding=%q, have %q", want, have)
+	}
+	e, found := ix.Lookup("Ä&2000")
+	if !found {
+		t.Fatal("want article Ä&2000")
+	}
+	if raw := string(data[e.Start:e.End]); !strings.HasPrefix(raw, "<ARTICLE>") || !strings.HasSuffix(raw, "</ARTICLE>") {
+		t.Fatalf("want ARTICLE element at offsets %d-%d, have %q", e.Start, e.End, raw)
+	}
+	if want, have := "10,20", strings.Join(e.CatalogGroupIDs, ","); want != have {
+		t.Fatalf("want CatalogGroupIDs %s, have %s", want, have)
+	}
+
+	// Round-trip
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	ix2, err := bmecat12.ReadIndex(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e2, found := ix2.Lookup("Ä&2000")
+	if !found {
+		t.Fatal("want article Ä&2000 after round-trip")
+	}
+	if e.Start != e2.Start || e.End != e2.End || strings.Join(e2.CatalogGroupIDs, ",") != "10,20" {
+		t.Fatalf("want %+v after round-trip, have %+v", e, e2)
+	}
+	if want, have := ix.Encoding, ix2.Encoding; want != have {
+		t.Fatalf("want Encoding=%q after round-trip, have %q", want, have)
+	}
+}
+
+func TestBuildIndexEncodings(t *testing.T) {
+	data := []byte(strings.Replace(indexDoc, `encoding="ISO-8859-1"`, `encoding="UTF-16"`, 1))
+	utf16, err := unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"gzip", gzipped(t, indexCatalog(t))},
+		{"UTF-16LE with BOM", utf16},
+	}
+	for _, tt := range tests {
+		if _, err := bmecat12.BuildIndex(bytes.NewReader(tt.data)); err == nil {
+			t.Fatalf("%s: want error, have nil", tt.name)
+		}
+	}
+
+	// A UTF-8 byte order mark keeps the offsets valid
+	data = append([]byte("\xef\xbb\xbf"), strings.Replace(indexDoc, `encoding="ISO-8859-1"`, `encoding="UTF-8"`, 1)...)
+	ix, err := bmecat12.BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, ix.Len(); want != have {
+		t.Fatalf("want %d entries, have %d", want, have)
+	}
+}
+
+func TestReadIndexCorrupt(t *testing.T) {
+	ix, err := bmecat12.BuildIndex(strings.NewReader("<BMECAT/>"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if _, err := ix.WriteTo(&buf); err != nil {
+		t.Fatal(err)
+	}
+	// Replace the number of entries, the last byte, with 1<<40
+	data := buf.Bytes()[:buf.Len()-1]
+	data = append(data, 0x80, 0x80, 0x80, 0x80, 0x80, 0x20)
+	if _, err := bmecat12.ReadIndex(bytes.NewReader(data)); err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
+
+func TestRandomAccessReader(t *testing.T) {
+	data := indexCatalog(t)
+	ix, err := bmecat12.BuildIndex(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := bmecat12.NewRandomAccessReader(bytes.NewReader(data), ix)
+
+	// Compare with the sequential Reader
+	var want []*bmecat12.Article
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			want = append(want, a)
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(bytes.NewReader(data)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	for _, w := range want {
+		a, err := r.Article(w.SupplierAID)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := w.Details.DescriptionShort, a.Details.DescriptionShort; want != have {
+			t.Errorf("article %s: want DESCRIPTION_SHORT %q, have %q", w.SupplierAID, want, have)
+		}
+		if want, have := strings.Join(w.CatalogGroupIDs, ","), strings.Join(a.CatalogGroupIDs, ","); want != have {
+			t.Errorf("article %s: want CatalogGroupIDs %q, have %q", w.SupplierAID, want, have)
+		}
+	}
+
+	if _, err := r.Article("unknown"); !errors.Is(err, bmecat12.ErrArticleNotFound) {
+		t.Fatalf("want ErrArticleNotFound, have %v", err)
+	}
+}
+
+func TestOpenRandomAccess(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-index")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	filename := filepath.Join(dir, "catalog.xml")
+	if err := ioutil.WriteFile(filename, indexCatalog(t), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := bmecat12.OpenRandomAccess(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r.Close()
+	fi, err := os.Stat(bmecat12.IndexFilename(filename))
+	if err != nil {
+		t.Fatalf("want sidecar index, have %v", err)
+	}
+
+	// Use the sidecar index
+	r, err = bmecat12.OpenRandomAccess(filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+	fi2, err := os.Stat(bmecat12.IndexFilename(filename))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !fi.ModTime().Equal(fi2.ModTime()) {
+		t.Fatal("want sidecar index to be reused")
+	}
+	a, err := r.Article("1000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Schraube", a.Details.DescriptionShort; want != have {
+		t.Fatalf("want DESCRIPTION_SHORT %q, have %q", want, have)
+	}
+}
@@ -0,0 +1,118 @@
+package bmecat12
+
+import (
+	"io"
+	"time"
+)
+
+// Instrumentation receives measurements from a Reader or Writer while it
+// runs, e.g. to back them with Prometheus counters and histograms in a
+// long-running import service. Pass it to WithReaderInstrumentation or
+// WithWriterInstrumentation. The methods are called from the goroutine
+// that runs Do, so they must return quickly.
+//
+// Use Metrics instead to get a summary after a run.
+type Instrumentation interface {
+	// ArticleRead is called for every article decoded by the Reader.
+	ArticleRead()
+	// ArticleWritten is called for every article written by the Writer.
+	ArticleWritten()
+	// DecodeDuration is called with the time it took to decode an ARTICLE
+	// element.
+	DecodeDuration(d time.Duration)
+	// BytesRead is called with the number of bytes read from the input,
+	// in both passes.
+	BytesRead(n int)
+	// BytesWritten is called with the number of bytes written to the
+	// output.
+	BytesWritten(n int)
+}
+
+// InstrumentationFuncs implements Instrumentation with a func per
+// measurement. Funcs that are nil are skipped.
+type InstrumentationFuncs struct {
+	OnArticleRead    func()
+	OnArticleWritten func()
+	OnDecodeDuration func(time.Duration)
+	OnBytesRead      func(int)
+	OnBytesWritten   func(int)
+}
+
+// ArticleRead implements the Instrumentation interface.
+func (f InstrumentationFuncs) ArticleRead() {
+	if f.OnArticleRead != nil {
+		f.OnArticleRead()
+	}
+}
+
+// ArticleWritten implements the Instrumentation interface.
+func (f InstrumentationFuncs) ArticleWritten() {
+	if f.OnArticleWritten != nil {
+		f.OnArticleWritten()
+	}
+}
+
+// DecodeDuration implements the Instrumentation interface.
+func (f InstrumentationFuncs) DecodeDuration(d time.Duration) {
+	if f.OnDecodeDuration != nil {
+		f.OnDecodeDuration(d)
+	}
+}
+
+// BytesRead implements the Instrumentation interface.
+func (f InstrumentationFuncs) BytesRead(n int) {
+	if f.OnBytesRead != nil {
+		f.OnBytesRead(n)
+	}
+}
+
+// BytesWritten implements the Instrumentation interface.
+func (f InstrumentationFuncs) BytesWritten(n int) {
+	if f.OnBytesWritten != nil {
+		f.OnBytesWritten(n)
+	}
+}
+
+// WithReaderInstrumentation reports the articles and bytes read and the
+// time spent decoding articles to instr.
+func WithReaderInstrumentation(instr Instrumentation) ReaderOption {
+	return func(r *Reader) {
+		r.instr = instr
+	}
+}
+
+// WithWriterInstrumentation reports the articles and bytes written to
+// instr.
+func WithWriterInstrumentation(instr Instrumentation) WriterOption {
+	return func(w *Writer) {
+		w.instr = instr
+	}
+}
+
+// instrumentedReader reports the bytes read from an io.ReadSeeker.
+type instrumentedReader struct {
+	io.ReadSeeker
+	instr Instrumentation
+}
+
+func (r *instrumentedReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	if n > 0 {
+		r.instr.BytesRead(n)
+	}
+	return n, err
+}
+
+// instrumentedWriter reports the bytes written to an io.Writer.
+type instrumentedWriter struct {
+	w     io.Writer
+	instr Instrumentation
+}
+
+func (w *instrumentedWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	if n > 0 {
+		w.instr.BytesWritten(n)
+	}
+	return n, err
+}
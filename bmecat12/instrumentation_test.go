@@ -0,0 +1,67 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestReaderAndWriterInstrumentation(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, concurrency := range []int{1, 4} {
+		var read, written, decoded, bytesRead, bytesWritten int
+		instr := bmecat12.InstrumentationFuncs{
+			OnArticleRead:    func() { read++ },
+			OnArticleWritten: func() { written++ },
+			OnDecodeDuration: func(time.Duration) { decoded++ },
+			OnBytesRead:      func(n int) { bytesRead += n },
+			OnBytesWritten:   func(n int) { bytesWritten += n },
+		}
+
+		h := &testHandler{}
+		r := bmecat12.NewReader(bytes.NewReader(data),
+			bmecat12.WithConcurrency(concurrency),
+			bmecat12.WithReaderInstrumentation(instr),
+		)
+		if err := r.Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 2, read; want != have {
+			t.Fatalf("want %d articles read, have %d", want, have)
+		}
+		if want, have := 2, decoded; want != have {
+			t.Fatalf("want %d decode durations, have %d", want, have)
+		}
+		// Both passes read the whole file
+		if want, have := 2*len(data), bytesRead; want != have {
+			t.Fatalf("want %d bytes read, have %d", want, have)
+		}
+
+		var buf bytes.Buffer
+		cw := catalogWriter{
+			tx:       h.tx,
+			language: "deu",
+			header:   h.header,
+			articles: h.articles,
+		}
+		w := bmecat12.NewWriter(&buf, bmecat12.WithWriterInstrumentation(instr))
+		if err := w.Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 2, written; want != have {
+			t.Fatalf("want %d articles written, have %d", want, have)
+		}
+		if want, have := buf.Len(), bytesWritten; want != have {
+			t.Fatalf("want %d bytes written, have %d", want, have)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+//go:build go1.23
+// +build go1.23
+
+package bmecat12
+
+import (
+	"context"
+	"errors"
+	"iter"
+)
+
+// errStopIteration stops the Reader when the loop over Articles breaks.
+var errStopIteration = errors.New("iteration stopped")
+
+// Articles returns an iterator over the articles of the catalog, as an
+// alternative to passing a handler to Do:
+//
+//	for a, err := range r.Articles(ctx) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// The iterator runs Do and yields the articles passed to its handler.
+// If Do fails, the iterator yields the error with a nil article and
+// stops. Breaking out of the loop stops the Reader. All other elements,
+// e.g. the HEADER, are skipped; use Do to handle them. With
+// WithArticlePool, an article may be released once the loop body for
+// it has completed.
+func (r *Reader) Articles(ctx context.Context) iter.Seq2[*Article, error] {
+	return func(yield func(*Article, error) bool) {
+		articles := make(chan *Article)
+		resume := make(chan bool)
+		errc := make(chan error, 1)
+		h := HandlerFuncs{
+			OnArticle: func(a *Article) error {
+				articles <- a
+				if !<-resume {
+					return errStopIteration
+				}
+				return nil
+			},
+		}
+		go func() {
+			errc <- r.Do(ctx, h)
+			close(articles)
+		}()
+
+		// waiting is true while the handler waits for the loop body,
+		// so the Reader can be stopped if the body breaks or panics
+		var waiting bool
+		defer func() {
+			if waiting {
+				resume <- false
+			}
+			for range articles {
+			}
+		}()
+		for a := range articles {
+			waiting = true
+			if !yield(a, nil) {
+				return
+			}
+			waiting = false
+			resume <- true
+		}
+		if err := <-errc; err != nil {
+			yield(nil, err)
+		}
+	}
+}
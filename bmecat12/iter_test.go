@@ -0,0 +1,71 @@
+//go:build go1.23
+// +build go1.23
+
+package bmecat12_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestReaderArticles(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var aids []string
+	for a, err := range bmecat12.NewReader(f).Articles(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		aids = append(aids, a.SupplierAID)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+}
+
+func TestReaderArticlesBreak(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var aids []string
+	for a, err := range bmecat12.NewReader(f).Articles(context.Background()) {
+		if err != nil {
+			t.Fatal(err)
+		}
+		aids = append(aids, a.SupplierAID)
+		break
+	}
+	if want, have := "1000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+}
+
+func TestReaderArticlesError(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	var errs []error
+	for _, err := range bmecat12.NewReader(strings.NewReader(doc)).Articles(context.Background()) {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if want, have := 1, len(errs); want != have {
+		t.Fatalf("want %d error, have %d", want, have)
+	}
+	var perr *bmecat12.ParseError
+	if !errors.As(errs[0], &perr) {
+		t.Fatalf("want ParseError, have %T", errs[0])
+	}
+}
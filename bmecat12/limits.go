@@ -0,0 +1,222 @@
+package bmecat12
+
+import "io"
+
+// ReaderLimits protects the Reader against documents from untrusted
+// sources, e.g. catalog uploads, that would exhaust memory or CPU. A
+// zero value means no limit. A document exceeding a limit is rejected
+// with a ParseError wrapping a LimitError. The limits on depth and
+// articles are checked in the 1st pass, i.e. before any element has
+// been passed to the handler.
+type ReaderLimits struct {
+	// MaxTokenSize is the maximum size of a token in bytes, e.g. of a
+	// start element with its attributes or of character data. It is
+	// checked as the input is read, before the token is buffered.
+	MaxTokenSize int
+	// MaxDepth is the maximum nesting depth of elements, with BMECAT at
+	// depth 1.
+	MaxDepth int
+	// MaxEntityExpansion is the maximum total size in bytes of the
+	// replacement text of the entities in WithEntityMap, as found in the
+	// document.
+	MaxEntityExpansion int64
+	// MaxArticles is the maximum number of ARTICLE elements. Unlike
+	// WithMaxArticles, which stops reading after n articles, documents
+	// with more articles are rejected.
+	MaxArticles int
+}
+
+// WithLimits rejects documents exceeding the given limits, which makes
+// the Reader safe for server-side ingestion of untrusted documents.
+// Consider combining it with WithMaxArticleSize.
+func WithLimits(l ReaderLimits) ReaderOption {
+	return func(r *Reader) {
+		r.limits = l
+	}
+}
+
+// maxEntityNameLen is the longest entity name recognized by limitReader.
+const maxEntityNameLen = 64
+
+// lexState is the state of the lexer of limitReader.
+type lexState int
+
+const (
+	lexText      lexState = iota // character data
+	lexLt                        // after '<'
+	lexStartTag                  // in a start tag
+	lexAttrValue                 // in a quoted attribute value
+	lexEndTag                    // in an end tag
+	lexProcInst                  // in a processing instruction
+	lexBang                      // after "<!"
+	lexComment                   // in a comment
+	lexCDATA                     // in a CDATA section
+	lexDirective                 // in a directive, e.g. DOCTYPE
+)
+
+// limitReader enforces the limits on token size, depth, and entity
+// expansion as the input is read, i.e. before the XML decoder buffers a
+// token or pushes an element onto its stack. It does so with a minimal
+// lexer that only tells markup from character data.
+type limitReader struct {
+	io.ReadSeeker
+	limits   ReaderLimits
+	entities map[string]string
+
+	state    lexState
+	run      int    // size of the current token
+	depth    int    // nesting depth of elements
+	quote    byte   // quote of the attribute value in lexAttrValue
+	prev     byte   // previous byte in markup
+	count    int    // consecutive '-' or ']', or nesting in lexDirective
+	expanded int64  // size of the entities expanded so far
+	entity   []byte // name of the entity being read, after '&'
+	inEntity bool
+}
+
+func (r *limitReader) Read(p []byte) (int, error) {
+	n, err := r.ReadSeeker.Read(p)
+	for i, c := range p[:n] {
+		if lerr := r.next(c); lerr != nil {
+			return i, lerr
+		}
+	}
+	return n, err
+}
+
+// next advances the lexer by c.
+func (r *limitReader) next(c byte) error {
+	r.run++
+	if max := r.limits.MaxTokenSize; max > 0 && r.run > max {
+		return &LimitError{Limit: "MaxTokenSize", Max: int64(max)}
+	}
+	switch r.state {
+	case lexText:
+		if c == '<' {
+			r.state, r.run = lexLt, 1
+			return nil
+		}
+		return r.expand(c)
+	case lexLt:
+		switch c {
+		case '/':
+			r.state = lexEndTag
+			r.depth--
+		case '!':
+			r.state, r.count = lexBang, 0
+		case '?':
+			r.state = lexProcInst
+		default:
+			r.state = lexStartTag
+			r.depth++
+			if max := r.limits.MaxDepth; max > 0 && r.depth > max {
+				return &LimitError{Limit: "MaxDepth", Max: int64(max)}
+			}
+		}
+	case lexStartTag:
+		switch c {
+		case '"', '\'':
+			r.state, r.quote = lexAttrValue, c
+		case '>':
+			if r.prev == '/' {
+				r.depth--
+			}
+			r.state, r.run = lexText, 0
+		}
+	case lexAttrValue:
+		if c == r.quote {
+			r.state = lexStartTag
+			break
+		}
+		return r.expand(c)
+	case lexEndTag:
+		if c == '>' {
+			r.state, r.run = lexText, 0
+		}
+	case lexProcInst:
+		if c == '>' && r.prev == '?' {
+			r.state, r.run = lexText, 0
+		}
+	case lexBang:
+		switch c {
+		case '-':
+			r.state, r.count = lexComment, 0
+		case '[':
+			r.state, r.count = lexCDATA, 0
+		default:
+			r.state, r.count = lexDirective, 0
+		}
+	case lexComment:
+		switch {
+		case c == '>' && r.count >= 2:
+			r.state, r.run = lexText, 0
+		case c == '-':
+			r.count++
+		default:
+			r.count = 0
+		}
+	case lexCDATA:
+		switch {
+		case c == '>' && r.count >= 2:
+			r.state, r.run = lexText, 0
+		case c == ']':
+			r.count++
+		default:
+			r.count = 0
+		}
+	case lexDirective:
+		switch c {
+		case '<':
+			r.count++
+		case '>':
+			if r.count == 0 {
+				r.state, r.run = lexText, 0
+			} else {
+				r.count--
+			}
+		}
+	}
+	r.prev = c
+	return nil
+}
+
+// expand counts the expansion of the entities in WithEntityMap.
+func (r *limitReader) expand(c byte) error {
+	if r.limits.MaxEntityExpansion <= 0 || len(r.entities) == 0 {
+		return nil
+	}
+	switch {
+	case c == '&':
+		r.inEntity, r.entity = true, r.entity[:0]
+	case !r.inEntity:
+	case c == ';':
+		r.inEntity = false
+		r.expanded += int64(len(r.entities[string(r.entity)]))
+		if r.expanded > r.limits.MaxEntityExpansion {
+			return &LimitError{Limit: "MaxEntityExpansion", Max: r.limits.MaxEntityExpansion}
+		}
+	case len(r.entity) >= maxEntityNameLen:
+		r.inEntity = false
+	default:
+		r.entity = append(r.entity, c)
+	}
+	return nil
+}
+
+func (r *limitReader) Seek(offset int64, whence int) (int64, error) {
+	pos, err := r.ReadSeeker.Seek(offset, whence)
+	if err != nil || (whence == io.SeekCurrent && offset == 0) {
+		return pos, err
+	}
+	// The Reader seeks to the start or, when resuming from a Checkpoint,
+	// to the end of an ARTICLE element
+	r.state, r.run, r.prev, r.inEntity = lexText, 0, 0, false
+	r.depth = 0
+	if pos > 0 {
+		r.depth = 2 // BMECAT and the transaction element
+	}
+	if pos == 0 {
+		r.expanded = 0
+	}
+	return pos, err
+}
@@ -0,0 +1,151 @@
+package bmecat12_test
+
+import (
+	"context"
+	"errors"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const limitsDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+  </HEADER>
+  <T_UPDATE_PRODUCTS prev_version="1">
+    <ARTICLE mode="update">
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS>
+        <DESCRIPTION_SHORT>%s</DESCRIPTION_SHORT>
+      </ARTICLE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRODUCTS>
+</BMECAT>`
+
+func TestReadWithLimits(t *testing.T) {
+	nested := strings.Repeat("<X>", 50) + strings.Repeat("</X>", 50)
+	tests := []struct {
+		Name   string
+		Short  string
+		Limits bmecat12.ReaderLimits
+		Opts   []bmecat12.ReaderOption
+		Limit  string
+	}{
+		{
+			Name:   "MaxTokenSize",
+			Short:  strings.Repeat("A", 1000),
+			Limits: bmecat12.ReaderLimits{MaxTokenSize: 512},
+			Limit:  "MaxTokenSize",
+		},
+		{
+			Name:   "MaxTokenSizeInAttribute",
+			Short:  `<X a="` + strings.Repeat("A", 1000) + `"/>`,
+			Limits: bmecat12.ReaderLimits{MaxTokenSize: 512},
+			Limit:  "MaxTokenSize",
+		},
+		{
+			Name:   "MaxDepth",
+			Short:  nested,
+			Limits: bmecat12.ReaderLimits{MaxDepth: 32},
+			Limit:  "MaxDepth",
+		},
+		{
+			Name:   "MaxDepthSelfClosing",
+			Short:  strings.Repeat(`<X a="/>"/>`, 100) + "<!-- <X><X><X> -->" + "<![CDATA[<X><X><X>]]>",
+			Limits: bmecat12.ReaderLimits{MaxDepth: 6},
+		},
+		{
+			Name:   "MaxEntityExpansion",
+			Short:  strings.Repeat("&big;", 100),
+			Limits: bmecat12.ReaderLimits{MaxEntityExpansion: 10000},
+			Opts: []bmecat12.ReaderOption{
+				bmecat12.WithEntityMap(map[string]string{"big": strings.Repeat("B", 1000)}),
+			},
+			Limit: "MaxEntityExpansion",
+		},
+		{
+			Name:   "WithinLimits",
+			Short:  "Apple MacBook Pro 13&#34; " + nested,
+			Limits: bmecat12.ReaderLimits{MaxTokenSize: 512, MaxDepth: 64, MaxEntityExpansion: 10000, MaxArticles: 1},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.Name, func(t *testing.T) {
+			doc := strings.Replace(limitsDoc, "%s", tt.Short, 1)
+			h := &testHandler{}
+			opts := append([]bmecat12.ReaderOption{bmecat12.WithLimits(tt.Limits)}, tt.Opts...)
+			r := bmecat12.NewReader(strings.NewReader(doc), opts...)
+			err := r.Do(context.Background(), h)
+			if tt.Limit == "" {
+				if err != nil {
+					t.Fatal(err)
+				}
+				if want, have := 1, len(h.articles); want != have {
+					t.Fatalf("want %d articles, have %d", want, have)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("want error, have nil")
+			}
+			if !errors.Is(err, bmecat12.ErrLimitExceeded) {
+				t.Fatalf("want ErrLimitExceeded, have %v", err)
+			}
+			var lerr *bmecat12.LimitError
+			if !errors.As(err, &lerr) {
+				t.Fatalf("want LimitError, have %T", err)
+			}
+			if want, have := tt.Limit, lerr.Limit; want != have {
+				t.Fatalf("want limit %q, have %q", want, have)
+			}
+			var perr *bmecat12.ParseError
+			if !errors.As(err, &perr) {
+				t.Fatalf("want ParseError, have %T", err)
+			}
+			if want, have := 0, len(h.articles); want != have {
+				t.Fatalf("want %d articles, have %d", want, have)
+			}
+		})
+	}
+}
+
+func TestReadWithMaxArticlesLimit(t *testing.T) {
+	f, err := os.Open("testdata/update_products.golden.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	h := &testHandler{}
+	r := bmecat12.NewReader(f, bmecat12.WithLimits(bmecat12.ReaderLimits{MaxArticles: 1}))
+	err = r.Do(context.Background(), h)
+	var lerr *bmecat12.LimitError
+	if !errors.As(err, &lerr) {
+		t.Fatalf("want LimitError, have %v", err)
+	}
+	if want, have := "MaxArticles of 1 exceeded", lerr.Error(); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	if want, have := 0, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+}
+
+func TestReadWithLimitsIsNotRecoveredWithContinueOnError(t *testing.T) {
+	doc := strings.Replace(limitsDoc, "%s", strings.Repeat("<X>", 50)+strings.Repeat("</X>", 50), 1)
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithLimits(bmecat12.ReaderLimits{MaxDepth: 32}),
+		bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+	)
+	err := r.Do(context.Background(), &testHandler{})
+	if !errors.Is(err, bmecat12.ErrLimitExceeded) {
+		t.Fatalf("want ErrLimitExceeded, have %v", err)
+	}
+}
@@ -0,0 +1,24 @@
+package bmecat12
+
+// logger receives the log events of a Reader or Writer. It is satisfied
+// by *slog.Logger, see WithLogger.
+type logger interface {
+	Debug(msg string, args ...interface{})
+	Info(msg string, args ...interface{})
+	Warn(msg string, args ...interface{})
+}
+
+// nopLogger discards all log events.
+type nopLogger struct{}
+
+func (nopLogger) Debug(string, ...interface{}) {}
+func (nopLogger) Info(string, ...interface{})  {}
+func (nopLogger) Warn(string, ...interface{})  {}
+
+// logOrNop returns l, or a nopLogger if l is nil.
+func logOrNop(l logger) logger {
+	if l == nil {
+		return nopLogger{}
+	}
+	return l
+}
@@ -0,0 +1,28 @@
+//go:build go1.21
+// +build go1.21
+
+package bmecat12
+
+import "log/slog"
+
+// WithLogger makes the Reader log its progress to l: the start and end
+// of each pass with the element counts at Info level, recoverable issues
+// like skipped articles at Warn level, and specification warnings and
+// audit events at Debug level. By default, the Reader logs nothing.
+func WithLogger(l *slog.Logger) ReaderOption {
+	return func(r *Reader) {
+		if l != nil {
+			r.logger = l
+		}
+	}
+}
+
+// WithWriterLogger makes the Writer log its progress to l, i.e. the
+// start and end of writing a catalog at Info level.
+func WithWriterLogger(l *slog.Logger) WriterOption {
+	return func(w *Writer) {
+		if l != nil {
+			w.logger = l
+		}
+	}
+}
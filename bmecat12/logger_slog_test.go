@@ -0,0 +1,58 @@
+//go:build go1.21
+// +build go1.21
+
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestReadWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{Level: slog.LevelDebug}))
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithLogger(logger),
+		bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+	)
+	if err := r.Do(context.Background(), &testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`msg="bmecat: 1st pass completed" transaction=T_UPDATE_PRICES articles=4`,
+		`msg="bmecat: 2nd pass started"`,
+		`level=WARN msg="bmecat: skipped ARTICLE that cannot be decoded" supplier_aid=2000`,
+		`msg="bmecat: 2nd pass completed" articles_handled=2`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("want log to contain %q, have:\n%s", want, out)
+		}
+	}
+}
+
+func TestWriteWithLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+	w := bmecat12.NewWriter(&bytes.Buffer{}, bmecat12.WithWriterLogger(logger))
+	cw := &catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000"},
+			{SupplierAID: "2000"},
+		},
+	}
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `msg="bmecat: writing catalog completed" articles=2`, buf.String(); !strings.Contains(have, want) {
+		t.Fatalf("want log to contain %q, have:\n%s", want, have)
+	}
+}
@@ -0,0 +1,509 @@
+package bmecat12
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"sync"
+
+	"github.com/pkg/errors"
+)
+
+// MapStore keeps the catalog group mappings of the articles, as found in
+// the ARTICLE_TO_CATALOGGROUP_MAP elements in the 1st pass, until the
+// articles are passed to the handler in the 2nd pass. By default, the
+// Reader keeps them in memory. Use WithMapStore with a FileMapStore to
+// bound the memory usage for huge catalogs. Implementations must be safe
+// for concurrent use.
+type MapStore interface {
+	// Add adds a catalog group to the mappings of an article.
+	Add(articleID, catalogGroupID string) error
+	// Get returns the catalog groups of an article, in the order they
+	// were added, or nil if there are none.
+	Get(articleID string) ([]string, error)
+	// Len returns the number of articles with mappings.
+	Len() (int, error)
+	// Range calls f for each article with mappings, until f returns an
+	// error.
+	Range(f func(articleID string, catalogGroupIDs []string) error) error
+}
+
+// WithMapStore makes the Reader keep the catalog group mappings in s
+// instead of in memory. The caller owns s, i.e. the Reader neither
+// resets nor closes it. See also WithoutCatalogGroupMapping.
+func WithMapStore(s MapStore) ReaderOption {
+	return func(r *Reader) {
+		r.mappings = s
+	}
+}
+
+// memoryMapStore is a MapStore that keeps the mappings in memory.
+type memoryMapStore struct {
+	mu sync.Mutex
+	m  map[string][]string
+}
+
+func newMemoryMapStore() *memoryMapStore {
+	return &memoryMapStore{m: make(map[string][]string)}
+}
+
+func (s *memoryMapStore) Add(articleID, catalogGroupID string) error {
+	s.mu.Lock()
+	s.m[articleID] = append(s.m[articleID], catalogGroupID)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *memoryMapStore) Get(articleID string) ([]string, error) {
+	s.mu.Lock()
+	ids := s.m[articleID]
+	s.mu.Unlock()
+	return ids, nil
+}
+
+func (s *memoryMapStore) Len() (int, error) {
+	s.mu.Lock()
+	n := len(s.m)
+	s.mu.Unlock()
+	return n, nil
+}
+
+func (s *memoryMapStore) Range(f func(articleID string, catalogGroupIDs []string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for id, groups := range s.m {
+		if err := f(id, groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fileMapStoreIndexInterval is the number of articles per block of the
+// table of a FileMapStore, i.e. between two entries of its index.
+const fileMapStoreIndexInterval = 64
+
+// FileMapStore is a MapStore that keeps the mappings in temporary files.
+// Mappings are collected in memory up to a budget and then written to a
+// file as a sorted run. The first lookup merges the runs into a table
+// sorted by article, of which only a sparse index is kept in memory.
+// Use Close to remove the temporary files.
+type FileMapStore struct {
+	budget int
+	dir    string
+
+	mu      sync.Mutex
+	mem     map[string][]string
+	memSize int
+	runs    []*os.File
+
+	// table holds the merged runs, if any, with an index entry for every
+	// fileMapStoreIndexInterval-th article
+	table *os.File
+	index []mapIndexEntry
+	count int
+	// block is the last block read from table, for lookups of articles
+	// in order
+	block      map[string][]string
+	blockIndex int
+}
+
+// mapIndexEntry is the first article of a block of the table.
+type mapIndexEntry struct {
+	articleID string
+	offset    int64
+}
+
+// FileMapStoreOption is the signature of options to pass into
+// NewFileMapStore.
+type FileMapStoreOption func(*FileMapStore)
+
+// WithMapStoreDir sets the directory for the temporary files. By default,
+// the directory returned by os.TempDir is used.
+func WithMapStoreDir(dir string) FileMapStoreOption {
+	return func(s *FileMapStore) {
+		s.dir = dir
+	}
+}
+
+// NewFileMapStore creates a new FileMapStore that keeps up to budget
+// mappings in memory before writing them to a temporary file. A budget
+// of 0 uses a default of a million mappings.
+func NewFileMapStore(budget int, options ...FileMapStoreOption) *FileMapStore {
+	if budget <= 0 {
+		budget = 1 << 20
+	}
+	s := &FileMapStore{budget: budget, mem: make(map[string][]string), blockIndex: -1}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// Add adds a catalog group to the mappings of an article.
+func (s *FileMapStore) Add(articleID, catalogGroupID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.table != nil {
+		// Merge the table with the mappings added from here on
+		s.runs = append([]*os.File{s.table}, s.runs...)
+		s.table, s.index, s.count, s.block, s.blockIndex = nil, nil, 0, nil, -1
+	}
+	s.mem[articleID] = append(s.mem[articleID], catalogGroupID)
+	s.memSize++
+	if s.memSize >= s.budget {
+		return s.spill()
+	}
+	return nil
+}
+
+// Get returns the catalog groups of an article.
+func (s *FileMapStore) Get(articleID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.seal(); err != nil {
+		return nil, err
+	}
+	if s.table == nil {
+		return s.mem[articleID], nil
+	}
+	// The last block starting at or before articleID
+	i := sort.Search(len(s.index), func(i int) bool {
+		return s.index[i].articleID > articleID
+	}) - 1
+	if i < 0 {
+		return nil, nil
+	}
+	if i != s.blockIndex {
+		block, err := s.readBlock(i)
+		if err != nil {
+			return nil, err
+		}
+		s.block, s.blockIndex = block, i
+	}
+	return s.block[articleID], nil
+}
+
+// Len returns the number of articles with mappings.
+func (s *FileMapStore) Len() (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.seal(); err != nil {
+		return 0, err
+	}
+	if s.table == nil {
+		return len(s.mem), nil
+	}
+	return s.count, nil
+}
+
+// Range calls f for each article with mappings, in the order of the
+// article IDs.
+func (s *FileMapStore) Range(f func(articleID string, catalogGroupIDs []string) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if err := s.seal(); err != nil {
+		return err
+	}
+	if s.table == nil {
+		for _, id := range sortedKeys(s.mem) {
+			if err := f(id, s.mem[id]); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if _, err := s.table.Seek(0, io.SeekStart); err != nil {
+		return errors.Wrap(err, "bmecat: unable to read catalog group mappings")
+	}
+	br := bufio.NewReader(s.table)
+	for i := 0; i < s.count; i++ {
+		id, groups, err := readMapRecord(br)
+		if err != nil {
+			return errors.Wrap(err, "bmecat: unable to read catalog group mappings")
+		}
+		if err := f(id, groups); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Close removes the temporary files.
+func (s *FileMapStore) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	files := s.runs
+	if s.table != nil {
+		files = append(files, s.table)
+	}
+	var err error
+	for _, f := range files {
+		if cerr := removeTempFile(f); err == nil {
+			err = cerr
+		}
+	}
+	s.mem, s.memSize, s.runs = make(map[string][]string), 0, nil
+	s.table, s.index, s.count, s.block, s.blockIndex = nil, nil, 0, nil, -1
+	return err
+}
+
+// spill writes the mappings in memory to a new run.
+func (s *FileMapStore) spill() error {
+	f, err := ioutil.TempFile(s.dir, "bmecat-mappings-*.run")
+	if err != nil {
+		return errors.Wrap(err, "bmecat: unable to create file for catalog group mappings")
+	}
+	bw := bufio.NewWriter(f)
+	for _, id := range sortedKeys(s.mem) {
+		if err := writeMapRecord(bw, id, s.mem[id]); err != nil {
+			removeTempFile(f)
+			return errors.Wrap(err, "bmecat: unable to write catalog group mappings")
+		}
+	}
+	if err := bw.Flush(); err != nil {
+		removeTempFile(f)
+		return errors.Wrap(err, "bmecat: unable to write catalog group mappings")
+	}
+	s.runs = append(s.runs, f)
+	s.mem, s.memSize = make(map[string][]string), 0
+	return nil
+}
+
+// seal merges the runs and the mappings in memory into the table. If
+// nothing has been spilled, the mappings stay in memory.
+func (s *FileMapStore) seal() error {
+	if len(s.runs) == 0 {
+		return nil
+	}
+	if s.memSize > 0 {
+		if err := s.spill(); err != nil {
+			return err
+		}
+	}
+	table, err := ioutil.TempFile(s.dir, "bmecat-mappings-*.table")
+	if err != nil {
+		return errors.Wrap(err, "bmecat: unable to create file for catalog group mappings")
+	}
+	index, count, err := mergeMapRuns(table, s.runs)
+	if err != nil {
+		removeTempFile(table)
+		return errors.Wrap(err, "bmecat: unable to merge catalog group mappings")
+	}
+	for _, f := range s.runs {
+		removeTempFile(f)
+	}
+	s.runs = nil
+	s.table, s.index, s.count, s.block, s.blockIndex = table, index, count, nil, -1
+	return nil
+}
+
+// readBlock reads the i-th block of the table.
+func (s *FileMapStore) readBlock(i int) (map[string][]string, error) {
+	n := fileMapStoreIndexInterval
+	if rest := s.count - i*fileMapStoreIndexInterval; rest < n {
+		n = rest
+	}
+	br := bufio.NewReader(io.NewSectionReader(s.table, s.index[i].offset, 1<<62))
+	block := make(map[string][]string, n)
+	for j := 0; j < n; j++ {
+		id, groups, err := readMapRecord(br)
+		if err != nil {
+			return nil, errors.Wrap(err, "bmecat: unable to read catalog group mappings")
+		}
+		block[id] = groups
+	}
+	return block, nil
+}
+
+// mergeMapRuns merges the sorted runs into w and returns the index and
+// the number of articles. The mappings of an article found in more than
+// one run are concatenated in the order of the runs.
+func mergeMapRuns(w io.Writer, runs []*os.File) ([]mapIndexEntry, int, error) {
+	h := &mapRunHeap{}
+	for i, f := range runs {
+		if _, err := f.Seek(0, io.SeekStart); err != nil {
+			return nil, 0, err
+		}
+		c := &mapRunCursor{run: i, r: bufio.NewReader(f)}
+		ok, err := c.next()
+		if err != nil {
+			return nil, 0, err
+		}
+		if ok {
+			heap.Push(h, c)
+		}
+	}
+
+	cw := &offsetWriter{w: bufio.NewWriter(w)}
+	var index []mapIndexEntry
+	var count int
+	for h.Len() > 0 {
+		c := (*h)[0]
+		id, groups := c.id, c.groups
+		// Collect the mappings of the article from all runs
+		for {
+			ok, err := c.next()
+			if err != nil {
+				return nil, 0, err
+			}
+			if ok {
+				heap.Fix(h, 0)
+			} else {
+				heap.Pop(h)
+			}
+			if h.Len() == 0 || (*h)[0].id != id {
+				break
+			}
+			c = (*h)[0]
+			groups = append(groups, c.groups...)
+		}
+		if count%fileMapStoreIndexInterval == 0 {
+			index = append(index, mapIndexEntry{articleID: id, offset: cw.n})
+		}
+		if err := writeMapRecord(cw, id, groups); err != nil {
+			return nil, 0, err
+		}
+		count++
+	}
+	if err := cw.w.Flush(); err != nil {
+		return nil, 0, err
+	}
+	return index, count, nil
+}
+
+// mapRunCursor is the current record of a run.
+type mapRunCursor struct {
+	run    int
+	r      *bufio.Reader
+	id     string
+	groups []string
+}
+
+// next reads the next record. It returns false at the end of the run.
+func (c *mapRunCursor) next() (bool, error) {
+	id, groups, err := readMapRecord(c.r)
+	if err == io.EOF {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	c.id, c.groups = id, groups
+	return true, nil
+}
+
+// mapRunHeap orders the cursors by article ID, then by run.
+type mapRunHeap []*mapRunCursor
+
+func (h mapRunHeap) Len() int { return len(h) }
+func (h mapRunHeap) Less(i, j int) bool {
+	if h[i].id != h[j].id {
+		return h[i].id < h[j].id
+	}
+	return h[i].run < h[j].run
+}
+func (h mapRunHeap) Swap(i, j int)       { h[i], h[j] = h[j], h[i] }
+func (h *mapRunHeap) Push(x interface{}) { *h = append(*h, x.(*mapRunCursor)) }
+func (h *mapRunHeap) Pop() interface{} {
+	old := *h
+	c := old[len(old)-1]
+	*h = old[:len(old)-1]
+	return c
+}
+
+// offsetWriter counts the bytes written.
+type offsetWriter struct {
+	w *bufio.Writer
+	n int64
+}
+
+func (w *offsetWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	w.n += int64(n)
+	return n, err
+}
+
+// writeMapRecord writes the mappings of an article as the article ID,
+// the number of catalog groups, and the catalog group IDs, with the
+// strings prefixed by their length.
+func writeMapRecord(w io.Writer, articleID string, groups []string) error {
+	var buf [binary.MaxVarintLen64]byte
+	writeString := func(s string) error {
+		n := binary.PutUvarint(buf[:], uint64(len(s)))
+		if _, err := w.Write(buf[:n]); err != nil {
+			return err
+		}
+		_, err := io.WriteString(w, s)
+		return err
+	}
+	if err := writeString(articleID); err != nil {
+		return err
+	}
+	n := binary.PutUvarint(buf[:], uint64(len(groups)))
+	if _, err := w.Write(buf[:n]); err != nil {
+		return err
+	}
+	for _, g := range groups {
+		if err := writeString(g); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// readMapRecord reads a record written by writeMapRecord. It returns
+// io.EOF if there are no more records.
+func readMapRecord(r *bufio.Reader) (string, []string, error) {
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			return "", err
+		}
+		b := make([]byte, n)
+		if _, err := io.ReadFull(r, b); err != nil {
+			return "", err
+		}
+		return string(b), nil
+	}
+	id, err := readString()
+	if err != nil {
+		return "", nil, err
+	}
+	n, err := binary.ReadUvarint(r)
+	if err != nil {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	groups := make([]string, n)
+	for i := range groups {
+		if groups[i], err = readString(); err != nil {
+			if err == io.EOF {
+				err = io.ErrUnexpectedEOF
+			}
+			return "", nil, err
+		}
+	}
+	return id, groups, nil
+}
+
+func sortedKeys(m map[string][]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// removeTempFile closes and removes a temporary file.
+func removeTempFile(f *os.File) error {
+	err := f.Close()
+	if rerr := os.Remove(f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
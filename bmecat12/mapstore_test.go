@@ -0,0 +1,151 @@
+package bmecat12_test
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestFileMapStore(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-mapstore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// A small budget spills to many runs
+	s := bmecat12.NewFileMapStore(7, bmecat12.WithMapStoreDir(dir))
+	defer s.Close()
+
+	const numArticles = 1000
+	want := make(map[string][]string)
+	for round := 0; round < 3; round++ {
+		for i := 0; i < numArticles; i++ {
+			if i%(round+1) != 0 {
+				continue
+			}
+			id := fmt.Sprintf("A%04d", (i*7919)%numArticles)
+			group := fmt.Sprintf("G%d", round)
+			if err := s.Add(id, group); err != nil {
+				t.Fatal(err)
+			}
+			want[id] = append(want[id], group)
+		}
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("want mappings to be spilled to disk")
+	}
+
+	for id, groups := range want {
+		have, err := s.Get(id)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(groups, have) {
+			t.Fatalf("%s: want %v, have %v", id, groups, have)
+		}
+	}
+	if have, err := s.Get("unknown"); err != nil || have != nil {
+		t.Fatalf("want nil, have %v (err=%v)", have, err)
+	}
+	n, err := s.Len()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := len(want), n; want != have {
+		t.Fatalf("want Len=%d, have %d", want, have)
+	}
+
+	// Range returns the articles in order
+	var last string
+	var ranged int
+	err = s.Range(func(id string, groups []string) error {
+		if id <= last {
+			t.Fatalf("want %q after %q", id, last)
+		}
+		if !reflect.DeepEqual(want[id], groups) {
+			t.Fatalf("%s: want %v, have %v", id, want[id], groups)
+		}
+		last = id
+		ranged++
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := len(want), ranged; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+
+	// Adding after a lookup merges with the existing mappings
+	if err := s.Add("A0000", "G9"); err != nil {
+		t.Fatal(err)
+	}
+	have, err := s.Get("A0000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := append(append([]string{}, want["A0000"]...), "G9"); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+
+	if err := s.Close(); err != nil {
+		t.Fatal(err)
+	}
+	files, err = ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, len(files); want != have {
+		t.Fatalf("want %d files after Close, have %d", want, have)
+	}
+}
+
+func TestReadWithMapStore(t *testing.T) {
+	var b strings.Builder
+	b.WriteString(`<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+`)
+	const numArticles = 100
+	for i := 0; i < numArticles; i++ {
+		fmt.Fprintf(&b, "<ARTICLE><SUPPLIER_AID>%d</SUPPLIER_AID></ARTICLE>\n", i)
+	}
+	for i := 0; i < numArticles; i++ {
+		fmt.Fprintf(&b, "<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>%d</ART_ID><CATALOG_GROUP_ID>G%d</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>\n", i, i%10)
+		fmt.Fprintf(&b, "<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>%d</ART_ID><CATALOG_GROUP_ID>X</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>\n", i)
+	}
+	b.WriteString("</T_NEW_CATALOG>\n</BMECAT>")
+
+	s := bmecat12.NewFileMapStore(16)
+	defer s.Close()
+	h := &testHandler{}
+	r := bmecat12.NewReader(strings.NewReader(b.String()), bmecat12.WithMapStore(s))
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := numArticles, h.header.NumberOfArticleToCatalogGroupMaps; want != have {
+		t.Fatalf("want NumberOfArticleToCatalogGroupMaps=%d, have %d", want, have)
+	}
+	if want, have := numArticles, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	for i, a := range h.articles {
+		if want, have := []string{fmt.Sprintf("G%d", i%10), "X"}, a.CatalogGroupIDs; !reflect.DeepEqual(want, have) {
+			t.Fatalf("%s: want %v, have %v", a.SupplierAID, want, have)
+		}
+	}
+}
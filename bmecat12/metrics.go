@@ -0,0 +1,137 @@
+package bmecat12
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Metrics collects a summary of a catalog run for monitoring, e.g. the
+// number of articles and warnings, and the duration. Pass it as a handler
+// to Reader.Do, or via WithHandler to a Pipeline. When it is also added
+// as the last Transformer of a Pipeline, it counts the articles written.
+//
+// Use WritePrometheus or WriteJSON to export the summary after the run.
+type Metrics struct {
+	CatalogID            string        `json:"catalog_id,omitempty"`
+	Transaction          string        `json:"transaction,omitempty"`
+	Started              time.Time     `json:"started"`
+	Finished             time.Time     `json:"finished"`
+	Duration             time.Duration `json:"-"`
+	Articles             int64         `json:"articles"`
+	ArticlesWritten      int64         `json:"articles_written"`
+	SkippedArticles      int64         `json:"skipped_articles"`
+	CatalogGroups        int64         `json:"catalog_groups"`
+	ClassificationGroups int64         `json:"classification_groups"`
+	Warnings             int64         `json:"warnings"`
+}
+
+// NewMetrics creates a new Metrics, starting the clock.
+func NewMetrics() *Metrics {
+	return &Metrics{Started: time.Now()}
+}
+
+// HandleHeader implements the HeaderHandler interface.
+func (m *Metrics) HandleHeader(h *Header) error {
+	if h.Catalog != nil {
+		m.CatalogID = h.Catalog.ID
+	}
+	m.Transaction = h.Transaction.String()
+	return nil
+}
+
+// HandleCatalogGroup implements the CatalogGroupHandler interface.
+func (m *Metrics) HandleCatalogGroup(*CatalogGroup) error {
+	m.CatalogGroups++
+	return nil
+}
+
+// HandleClassificationGroup implements the ClassificationGroupHandler interface.
+func (m *Metrics) HandleClassificationGroup(*ClassificationGroup) error {
+	m.ClassificationGroups++
+	return nil
+}
+
+// HandleArticle implements the ArticleHandler interface.
+func (m *Metrics) HandleArticle(*Article) error {
+	m.Articles++
+	return nil
+}
+
+// HandleSkippedArticle implements the SkippedArticleHandler interface.
+func (m *Metrics) HandleSkippedArticle(string, int64) error {
+	m.SkippedArticles++
+	return nil
+}
+
+// HandleWarning implements the WarningHandler interface.
+func (m *Metrics) HandleWarning(*Warning) error {
+	m.Warnings++
+	return nil
+}
+
+// HandleComplete implements the CompletionHandler interface. It stops
+// the clock.
+func (m *Metrics) HandleComplete() {
+	m.Finished = time.Now()
+	m.Duration = m.Finished.Sub(m.Started)
+}
+
+// TransformArticle implements the Transformer interface. It counts the
+// articles passed and returns them unchanged.
+func (m *Metrics) TransformArticle(a *Article) (*Article, error) {
+	m.ArticlesWritten++
+	return a, nil
+}
+
+// WritePrometheus writes the metrics in the Prometheus text exposition
+// format, e.g. for the textfile collector of the node exporter.
+func (m *Metrics) WritePrometheus(w io.Writer) error {
+	labels := fmt.Sprintf(`{catalog_id="%s",transaction="%s"}`, escapeLabel(m.CatalogID), escapeLabel(m.Transaction))
+	metrics := []struct {
+		name  string
+		typ   string
+		help  string
+		value interface{}
+	}{
+		{"bmecat_articles", "gauge", "Number of articles read.", m.Articles},
+		{"bmecat_articles_written", "gauge", "Number of articles written.", m.ArticlesWritten},
+		{"bmecat_skipped_articles", "gauge", "Number of articles skipped.", m.SkippedArticles},
+		{"bmecat_catalog_groups", "gauge", "Number of catalog groups read.", m.CatalogGroups},
+		{"bmecat_classification_groups", "gauge", "Number of classification groups read.", m.ClassificationGroups},
+		{"bmecat_warnings", "gauge", "Number of warnings.", m.Warnings},
+		{"bmecat_duration_seconds", "gauge", "Duration of the run in seconds.", m.Duration.Seconds()},
+		{"bmecat_last_run_timestamp_seconds", "gauge", "Unix time when the run finished.", m.Finished.Unix()},
+	}
+	for _, metric := range metrics {
+		_, err := fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n%s%s %v\n",
+			metric.name, metric.help,
+			metric.name, metric.typ,
+			metric.name, labels, metric.value)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// WriteJSON writes the metrics as a JSON object.
+func (m *Metrics) WriteJSON(w io.Writer) error {
+	type metricsJSON Metrics
+	return json.NewEncoder(w).Encode(struct {
+		*metricsJSON
+		DurationSeconds float64 `json:"duration_seconds"`
+	}{
+		metricsJSON:     (*metricsJSON)(m),
+		DurationSeconds: m.Duration.Seconds(),
+	})
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+// escapeLabel escapes a Prometheus label value.
+func escapeLabel(s string) string {
+	return labelEscaper.Replace(s)
+}
@@ -0,0 +1,75 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestMetricsInPipeline(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	m := bmecat12.NewMetrics()
+	r := bmecat12.NewReader(f)
+	w := bmecat12.NewWriter(ioutil.Discard)
+	p := bmecat12.NewPipeline(r, w,
+		bmecat12.WithHandler(m),
+		bmecat12.WithTransformer(bmecat12.DenyArticles(bmecat12.NewArticleList("2*"))),
+		bmecat12.WithTransformer(m),
+	)
+	if err := p.Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := int64(2), m.Articles; want != have {
+		t.Errorf("want Articles=%d, have %d", want, have)
+	}
+	if want, have := int64(1), m.ArticlesWritten; want != have {
+		t.Errorf("want ArticlesWritten=%d, have %d", want, have)
+	}
+	if want, have := "T_UPDATE_PRODUCTS", m.Transaction; want != have {
+		t.Errorf("want Transaction=%q, have %q", want, have)
+	}
+	if m.Finished.IsZero() {
+		t.Error("want Finished to be set")
+	}
+
+	var buf bytes.Buffer
+	if err := m.WritePrometheus(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"# TYPE bmecat_articles gauge",
+		`bmecat_articles{catalog_id="` + m.CatalogID + `",transaction="T_UPDATE_PRODUCTS"} 2`,
+		`bmecat_articles_written{catalog_id="` + m.CatalogID + `",transaction="T_UPDATE_PRODUCTS"} 1`,
+	} {
+		if !strings.Contains(buf.String(), line+"\n") {
+			t.Errorf("want line %q in\n%s", line, buf.String())
+		}
+	}
+
+	buf.Reset()
+	if err := m.WriteJSON(&buf); err != nil {
+		t.Fatal(err)
+	}
+	var doc map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := float64(2), doc["articles"]; want != have {
+		t.Errorf("want articles=%v, have %v", want, have)
+	}
+	if _, found := doc["duration_seconds"]; !found {
+		t.Errorf("want duration_seconds in %s", buf.String())
+	}
+}
@@ -0,0 +1,121 @@
+package bmecat12
+
+import (
+	"mime"
+	"path"
+	"strings"
+)
+
+// MimeRule rewrites a MIME element in place, e.g. its MIME_SOURCE.
+type MimeRule func(m *Mime)
+
+// MimeRewriter is a Transformer that applies MimeRules to the MIME
+// elements of each article, e.g. to move images to a different host or
+// to use different image variants for the target shop.
+type MimeRewriter struct {
+	rules []MimeRule
+}
+
+// NewMimeRewriter creates a new MimeRewriter. The rules are applied in
+// the order given.
+func NewMimeRewriter(rules ...MimeRule) *MimeRewriter {
+	return &MimeRewriter{rules: rules}
+}
+
+// TransformArticle implements the Transformer interface.
+func (r *MimeRewriter) TransformArticle(a *Article) (*Article, error) {
+	if a.MimeInfo == nil {
+		return a, nil
+	}
+	for _, m := range a.MimeInfo.Mimes {
+		if m == nil {
+			continue
+		}
+		for _, rule := range r.rules {
+			rule(m)
+		}
+	}
+	return a, nil
+}
+
+// ForMimePurpose applies rule only to MIME elements with the given
+// MIME_PURPOSE, e.g. MimePurposeThumbnail.
+func ForMimePurpose(purpose string, rule MimeRule) MimeRule {
+	return func(m *Mime) {
+		if m.Purpose == purpose {
+			rule(m)
+		}
+	}
+}
+
+// PrefixMimeSource prefixes relative MIME_SOURCE values, i.e. those
+// resolved against the MIME_ROOT of the catalog, with prefix, e.g.
+// "https://cdn.example.com/images". Absolute URLs are kept unchanged.
+func PrefixMimeSource(prefix string) MimeRule {
+	return func(m *Mime) {
+		if m.Source == "" || isAbsoluteURL(m.Source) {
+			return
+		}
+		m.Source = strings.TrimRight(prefix, "/") + "/" + strings.TrimLeft(m.Source, "/")
+	}
+}
+
+// SwapMimeExtension replaces the file extension from, e.g. ".tif", with
+// to, e.g. ".jpg". Extensions are compared case-insensitively. If the
+// MIME_TYPE is a media type, it is updated to match the new extension.
+func SwapMimeExtension(from, to string) MimeRule {
+	return func(m *Mime) {
+		p, rest := splitMimeSource(m.Source)
+		ext := path.Ext(p)
+		if !strings.EqualFold(ext, from) {
+			return
+		}
+		m.Source = p[:len(p)-len(ext)] + to + rest
+		if m.Type != "" && m.Type != MimeTypeURL {
+			if typ := mime.TypeByExtension(to); typ != "" {
+				m.Type = strings.SplitN(typ, ";", 2)[0]
+			}
+		}
+	}
+}
+
+// MimeSizeSuffix adds suffix to the file name of MIME_SOURCE, before the
+// extension, e.g. "_800x600" rewrites "a/b.jpg" to "a/b_800x600.jpg".
+// Use it with ForMimePurpose to pick the image variant per purpose.
+func MimeSizeSuffix(suffix string) MimeRule {
+	return func(m *Mime) {
+		if m.Source == "" {
+			return
+		}
+		p, rest := splitMimeSource(m.Source)
+		ext := path.Ext(p)
+		m.Source = p[:len(p)-len(ext)] + suffix + ext + rest
+	}
+}
+
+// isAbsoluteURL returns true if source has a scheme, e.g. "https:", or is
+// protocol-relative, e.g. "//cdn.example.com/a.jpg".
+func isAbsoluteURL(source string) bool {
+	if strings.HasPrefix(source, "//") {
+		return true
+	}
+	i := strings.Index(source, ":")
+	if i <= 0 {
+		return false
+	}
+	for _, c := range source[:i] {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '+' || c == '-' || c == '.') {
+			return false
+		}
+	}
+	return true
+}
+
+// splitMimeSource splits source into the path and the query string and
+// fragment, if any.
+func splitMimeSource(source string) (p, rest string) {
+	if i := strings.IndexAny(source, "?#"); i >= 0 {
+		return source[:i], source[i:]
+	}
+	return source, ""
+}
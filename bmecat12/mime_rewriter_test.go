@@ -0,0 +1,46 @@
+package bmecat12_test
+
+import (
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestMimeRewriter(t *testing.T) {
+	a := &bmecat12.Article{
+		SupplierAID: "1000",
+		MimeInfo: &bmecat12.MimeInfo{
+			Mimes: []*bmecat12.Mime{
+				{Type: "image/tiff", Source: "products/1000.TIF", Purpose: bmecat12.MimePurposeNormal},
+				{Type: "image/tiff", Source: "/products/1000.tif?v=2", Purpose: bmecat12.MimePurposeThumbnail},
+				{Type: bmecat12.MimeTypeURL, Source: "https://supplier.example.com/1000.pdf", Purpose: bmecat12.MimePurposeDataSheet},
+			},
+		},
+	}
+	r := bmecat12.NewMimeRewriter(
+		bmecat12.SwapMimeExtension(".tif", ".jpg"),
+		bmecat12.ForMimePurpose(bmecat12.MimePurposeThumbnail, bmecat12.MimeSizeSuffix("_150x150")),
+		bmecat12.PrefixMimeSource("https://cdn.example.com/images/"),
+	)
+	out, err := r.TransformArticle(a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		Type   string
+		Source string
+	}{
+		{"image/jpeg", "https://cdn.example.com/images/products/1000.jpg"},
+		{"image/jpeg", "https://cdn.example.com/images/products/1000_150x150.jpg?v=2"},
+		{bmecat12.MimeTypeURL, "https://supplier.example.com/1000.pdf"},
+	}
+	for i, tt := range tests {
+		m := out.MimeInfo.Mimes[i]
+		if want, have := tt.Source, m.Source; want != have {
+			t.Errorf("#%d: want Source=%q, have %q", i, want, have)
+		}
+		if want, have := tt.Type, m.Type; want != have {
+			t.Errorf("#%d: want Type=%q, have %q", i, want, have)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package bmecat12
+
+import "strings"
+
+// MimeSourceMode specifies how the Writer rewrites MIME_SOURCE with
+// respect to the MIME_ROOT of the header, see WithMimeSourceMode.
+type MimeSourceMode int
+
+const (
+	// MimeSourceKeep writes MIME_SOURCE as is. This is the default.
+	MimeSourceKeep MimeSourceMode = iota
+	// MimeSourceRelative rewrites absolute URLs below MIME_ROOT to paths
+	// relative to it, e.g. "https://example.com/images/a.jpg" to "a.jpg"
+	// for the MIME_ROOT "https://example.com/images/". Other sources are
+	// kept as is.
+	MimeSourceRelative
+	// MimeSourceAbsolute resolves relative sources against MIME_ROOT,
+	// e.g. "a.jpg" to "https://example.com/images/a.jpg". Absolute URLs
+	// are kept as is.
+	MimeSourceAbsolute
+)
+
+// WithMimeSourceMode makes the Writer rewrite the MIME_SOURCE of the
+// articles and catalog groups relative to the MIME_ROOT of the header,
+// or vice versa, so a single catalog can be written for portals with
+// different conventions. MIME_ROOT itself is written unchanged, and
+// nothing is rewritten if the header has no MIME_ROOT. The articles and
+// groups are not modified.
+func WithMimeSourceMode(mode MimeSourceMode) WriterOption {
+	return func(w *Writer) {
+		w.mimeSourceMode = mode
+	}
+}
+
+// startMimeSources records the MIME_ROOT of the header of writer for
+// rewriteMimeSources.
+func (w *Writer) startMimeSources(writer CatalogWriter) {
+	w.mimeRoot = ""
+	if w.mimeSourceMode == MimeSourceKeep {
+		return
+	}
+	if h := writer.Header(); h != nil && h.Catalog != nil {
+		w.mimeRoot = h.Catalog.MimeRoot
+	}
+}
+
+// rewriteMimeSources returns a with the MIME_SOURCE rewritten as
+// specified with WithMimeSourceMode. a is not modified; if nothing
+// changes, a is returned as is.
+func (w *Writer) rewriteMimeSources(a *Article) *Article {
+	if w.mimeRoot == "" {
+		return a
+	}
+	mimeInfo := mapMimeSources(a.MimeInfo, w.rewriteMimeSource)
+	if mimeInfo == a.MimeInfo {
+		return a
+	}
+	prepared := *a
+	prepared.MimeInfo = mimeInfo
+	return &prepared
+}
+
+// mapMimeSources returns mi with each MIME_SOURCE replaced by the result
+// of f. mi is not modified; if nothing changes, mi is returned as is.
+func mapMimeSources(mi *MimeInfo, f func(source string) string) *MimeInfo {
+	if mi == nil {
+		return nil
+	}
+	var mimes []*Mime
+	for i, m := range mi.Mimes {
+		if m == nil || m.Source == "" {
+			continue
+		}
+		source := f(m.Source)
+		if source == m.Source {
+			continue
+		}
+		if mimes == nil {
+			mimes = append([]*Mime{}, mi.Mimes...)
+		}
+		rewritten := *m
+		rewritten.Source = source
+		mimes[i] = &rewritten
+	}
+	if mimes == nil {
+		return mi
+	}
+	prepared := *mi
+	prepared.Mimes = mimes
+	return &prepared
+}
+
+// rewriteMimeSource rewrites source as specified with WithMimeSourceMode.
+func (w *Writer) rewriteMimeSource(source string) string {
+	switch w.mimeSourceMode {
+	case MimeSourceRelative:
+		root := strings.TrimRight(w.mimeRoot, "/") + "/"
+		if isAbsoluteURL(source) && strings.HasPrefix(source, root) && len(source) > len(root) {
+			return source[len(root):]
+		}
+	case MimeSourceAbsolute:
+		return resolveMimeSource(w.mimeRoot, source)
+	}
+	return source
+}
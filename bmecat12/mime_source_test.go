@@ -0,0 +1,72 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteMimeSourceMode(t *testing.T) {
+	sources := []string{
+		"a.jpg",
+		"/b/c.png",
+		"https://example.com/images/d.jpg?w=800",
+		"https://cdn.example.com/e.jpg",
+	}
+	tests := []struct {
+		Mode bmecat12.MimeSourceMode
+		Want []string
+	}{
+		{
+			Mode: bmecat12.MimeSourceKeep,
+			Want: sources,
+		},
+		{
+			Mode: bmecat12.MimeSourceRelative,
+			Want: []string{"a.jpg", "/b/c.png", "d.jpg?w=800", "https://cdn.example.com/e.jpg"},
+		},
+		{
+			Mode: bmecat12.MimeSourceAbsolute,
+			Want: []string{
+				"https://example.com/images/a.jpg",
+				"https://example.com/images/b/c.png",
+				"https://example.com/images/d.jpg?w=800",
+				"https://cdn.example.com/e.jpg",
+			},
+		},
+	}
+	for _, tt := range tests {
+		a := &bmecat12.Article{SupplierAID: "1000", MimeInfo: &bmecat12.MimeInfo{}}
+		for _, source := range sources {
+			a.MimeInfo.Mimes = append(a.MimeInfo.Mimes, &bmecat12.Mime{Source: source})
+		}
+		cw := catalogWriter{tx: bmecat12.NewCatalog, header: testHeader, articles: []*bmecat12.Article{a}}
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, bmecat12.WithMimeSourceMode(tt.Mode)).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+
+		var th testHandler
+		if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), &th); err != nil {
+			t.Fatal(err)
+		}
+		mimes := th.articles[0].MimeInfo.Mimes
+		if want, have := len(tt.Want), len(mimes); want != have {
+			t.Fatalf("mode %d: want %d MIME elements, have %d", tt.Mode, want, have)
+		}
+		for i, m := range mimes {
+			if want, have := tt.Want[i], m.Source; want != have {
+				t.Fatalf("mode %d: want MIME_SOURCE %q, have %q", tt.Mode, want, have)
+			}
+		}
+		if want, have := "https://example.com/images", th.header.Catalog.MimeRoot; want != have {
+			t.Fatalf("mode %d: want MIME_ROOT %q, have %q", tt.Mode, want, have)
+		}
+		// The article passed to the Writer is not modified
+		if want, have := sources[0], a.MimeInfo.Mimes[0].Source; want != have {
+			t.Fatalf("mode %d: want MIME_SOURCE %q of the original article, have %q", tt.Mode, want, have)
+		}
+	}
+}
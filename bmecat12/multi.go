@@ -0,0 +1,161 @@
+package bmecat12
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"time"
+)
+
+// MultiReader reads a catalog that is delivered as several BMEcat files,
+// e.g. a file with the HEADER and the catalog structure plus a number of
+// files with the articles, as one logical catalog.
+//
+// The handler sees a single catalog: The prolog and the first HEADER and
+// transaction element are passed to the handler, the ones of the other
+// files are skipped. The counts of the header, e.g. NumberOfArticles,
+// are the totals of all files, and ARTICLE_TO_CATALOGGROUP_MAP elements
+// apply to the articles of all files. The CompletionHandler is invoked
+// once after the last file.
+type MultiReader struct {
+	sources []io.ReadSeeker
+	options []ReaderOption
+	readers []*Reader
+}
+
+// NewMultiReader creates a new MultiReader for the files of a catalog,
+// starting with the one that holds the HEADER. The options are applied
+// to each file, i.e. WithMaxArticles limits the articles per file.
+// WithCheckpoint is not supported.
+func NewMultiReader(sources []io.ReadSeeker, options ...ReaderOption) *MultiReader {
+	return &MultiReader{sources: sources, options: options}
+}
+
+// Do reads all files and passes the elements to handler, just like
+// Reader.Do. The 1st pass reads all files before the 2nd pass starts,
+// so the header counts and catalog group mappings are complete. All
+// files must have the same transaction; otherwise Do returns an error
+// wrapping ErrMultipleTransactions.
+func (m *MultiReader) Do(ctx context.Context, handler interface{}) error {
+	shared := &multiState{started: time.Now()}
+	var mappings MapStore
+	readers := make([]*Reader, len(m.sources))
+	m.readers = readers
+	for i, src := range m.sources {
+		r := NewReader(src, m.options...)
+		r.resume = nil
+		if i == 0 {
+			mappings = r.mappings
+		}
+		r.mappings = mappings
+		r.part = &multiPart{index: i, count: len(m.sources), firstPassOnly: true, shared: shared}
+		readers[i] = r
+	}
+
+	// 1st pass over all files
+	var txName string
+	for i, r := range readers {
+		if err := r.Do(ctx, handler); err != nil {
+			return fmt.Errorf("bmecat/reader: file %d: %w", i+1, err)
+		}
+		st := r.part.state
+		if st.txName == "" {
+			continue
+		}
+		if txName == "" {
+			txName = st.txName
+			shared.totals.tx, shared.totals.prevVersion = st.tx, st.prevVersion
+		} else if st.txName != txName {
+			return fmt.Errorf("bmecat/reader: file %d: %w: %s after %s", i+1, ErrMultipleTransactions, st.txName, txName)
+		}
+		shared.totals.numArticles += st.numArticles
+		shared.totals.numCatalogGroups += st.numCatalogGroups
+		shared.totals.numClassifGroups += st.numClassifGroups
+	}
+
+	// 2nd pass over all files
+	for i, r := range readers {
+		st := r.part.state
+		if st.txName == "" {
+			st.tx, st.prevVersion = shared.totals.tx, shared.totals.prevVersion
+		}
+		r.part.firstPassOnly = false
+		if err := r.Do(ctx, handler); err != nil {
+			return fmt.Errorf("bmecat/reader: file %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// firstPassState is the result of the 1st pass of a Reader.
+type firstPassState struct {
+	numArticles      int
+	numCatalogGroups int
+	numClassifGroups int
+	tx               Transaction
+	prevVersion      int
+	txName           string
+	encoding         string
+	skipped          map[int]skippedArticle
+}
+
+// multiState is shared by the readers of a MultiReader.
+type multiState struct {
+	totals firstPassState
+	// header and transaction are true once the HEADER and transaction
+	// element have been passed to the handler.
+	header      bool
+	transaction bool
+	// started is the time Do was called, and stats are the statistics
+	// of the files completed so far.
+	started time.Time
+	stats   ReaderStats
+}
+
+// multiPart is the state of a Reader that reads one of the files of a
+// MultiReader. Its methods may be called on a nil *multiPart, i.e. for
+// a Reader that is not part of a MultiReader.
+type multiPart struct {
+	index, count int
+	// firstPassOnly makes the Reader return after the 1st pass and
+	// record its result in state.
+	firstPassOnly bool
+	state         *firstPassState
+	shared        *multiState
+}
+
+// restored returns true if the 1st pass can be skipped.
+func (p *multiPart) restored() bool {
+	return p != nil && !p.firstPassOnly && p.state != nil
+}
+
+// first returns true for the first file.
+func (p *multiPart) first() bool {
+	return p == nil || p.index == 0
+}
+
+// last returns true for the last file.
+func (p *multiPart) last() bool {
+	return p == nil || p.index == p.count-1
+}
+
+// claimHeader returns true if the HEADER is to be passed to the handler.
+func (p *multiPart) claimHeader() bool {
+	if p == nil {
+		return true
+	}
+	claimed := !p.shared.header
+	p.shared.header = true
+	return claimed
+}
+
+// claimTransaction returns true if the transaction element is to be
+// passed to the handler.
+func (p *multiPart) claimTransaction() bool {
+	if p == nil {
+		return true
+	}
+	claimed := !p.shared.transaction
+	p.shared.transaction = true
+	return claimed
+}
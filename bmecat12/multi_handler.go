@@ -0,0 +1,252 @@
+package bmecat12
+
+import (
+	"context"
+	"io"
+	"strings"
+)
+
+// MultiError is returned by a MultiHandler when one or more of its
+// handlers returned an error.
+type MultiError []error
+
+// Error returns a string representation of the errors.
+func (e MultiError) Error() string {
+	msgs := make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Unwrap returns the errors, so errors.Is and errors.As match the error
+// of any handler.
+func (e MultiError) Unwrap() []error {
+	return e
+}
+
+// multiHandler dispatches each event of the Reader to all of its handlers.
+type multiHandler struct {
+	handlers []interface{}
+}
+
+// MultiHandler returns a handler for the Reader that dispatches each
+// event to all of the given handlers, in the order passed. Each handler
+// only gets the events for the handler interfaces it implements.
+//
+// All handlers are called for an event, even if one of them returns an
+// error. The errors are returned as a MultiError. HandleHeader only
+// returns io.EOF, i.e. stops the Reader, if all header handlers do.
+func MultiHandler(handlers ...interface{}) interface{} {
+	return &multiHandler{handlers: handlers}
+}
+
+// dispatch calls f for all handlers and collects the errors.
+func (m *multiHandler) dispatch(f func(h interface{}) (bool, error)) error {
+	var errs MultiError
+	for _, h := range m.handlers {
+		if ok, err := f(h); ok && err != nil {
+			errs = append(errs, err)
+		}
+	}
+	switch len(errs) {
+	case 0:
+		return nil
+	case 1:
+		return errs[0]
+	}
+	return errs
+}
+
+func (m *multiHandler) HandleProlog(p *Prolog) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		if f, ok := h.(DocumentHandler); ok {
+			return true, f.HandleProlog(p)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleHeader(header *Header) error {
+	return m.HandleHeaderContext(context.Background(), header)
+}
+
+func (m *multiHandler) HandleHeaderContext(ctx context.Context, header *Header) error {
+	var n, eofs int
+	err := m.dispatch(func(h interface{}) (bool, error) {
+		var err error
+		switch f := h.(type) {
+		case HeaderHandlerContext:
+			err = f.HandleHeaderContext(ctx, header)
+		case HeaderHandler:
+			err = f.HandleHeader(header)
+		default:
+			return false, nil
+		}
+		n++
+		if err == io.EOF {
+			eofs++
+			return true, nil
+		}
+		return true, err
+	})
+	if err == nil && n > 0 && eofs == n {
+		return io.EOF
+	}
+	return err
+}
+
+func (m *multiHandler) HandleTransaction(tx Transaction, prevVersion int) error {
+	return m.HandleTransactionContext(context.Background(), tx, prevVersion)
+}
+
+func (m *multiHandler) HandleTransactionContext(ctx context.Context, tx Transaction, prevVersion int) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case TransactionHandlerContext:
+			return true, f.HandleTransactionContext(ctx, tx, prevVersion)
+		case TransactionHandler:
+			return true, f.HandleTransaction(tx, prevVersion)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleFeatureSystem(fs *FeatureSystem) error {
+	return m.HandleFeatureSystemContext(context.Background(), fs)
+}
+
+func (m *multiHandler) HandleFeatureSystemContext(ctx context.Context, fs *FeatureSystem) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case FeatureSystemHandlerContext:
+			return true, f.HandleFeatureSystemContext(ctx, fs)
+		case FeatureSystemHandler:
+			return true, f.HandleFeatureSystem(fs)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleCatalogGroup(cg *CatalogGroup) error {
+	return m.HandleCatalogGroupContext(context.Background(), cg)
+}
+
+func (m *multiHandler) HandleCatalogGroupContext(ctx context.Context, cg *CatalogGroup) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case CatalogGroupHandlerContext:
+			return true, f.HandleCatalogGroupContext(ctx, cg)
+		case CatalogGroupHandler:
+			return true, f.HandleCatalogGroup(cg)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleClassificationSystem(cs *ClassificationSystem) error {
+	return m.HandleClassificationSystemContext(context.Background(), cs)
+}
+
+func (m *multiHandler) HandleClassificationSystemContext(ctx context.Context, cs *ClassificationSystem) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case ClassificationSystemHandlerContext:
+			return true, f.HandleClassificationSystemContext(ctx, cs)
+		case ClassificationSystemHandler:
+			return true, f.HandleClassificationSystem(cs)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleClassificationGroup(cg *ClassificationGroup) error {
+	return m.HandleClassificationGroupContext(context.Background(), cg)
+}
+
+func (m *multiHandler) HandleClassificationGroupContext(ctx context.Context, cg *ClassificationGroup) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case ClassificationGroupHandlerContext:
+			return true, f.HandleClassificationGroupContext(ctx, cg)
+		case ClassificationGroupHandler:
+			return true, f.HandleClassificationGroup(cg)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleArticle(a *Article) error {
+	return m.HandleArticleContext(context.Background(), a)
+}
+
+func (m *multiHandler) HandleArticleContext(ctx context.Context, a *Article) error {
+	// A handler releasing a pooled article must not pull it away from the
+	// handlers after it, see Article.Release.
+	a.hold()
+	defer a.unhold()
+	return m.dispatch(func(h interface{}) (bool, error) {
+		switch f := h.(type) {
+		case ArticleHandlerContext:
+			return true, f.HandleArticleContext(ctx, a)
+		case ArticleHandler:
+			return true, f.HandleArticle(a)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleArticleOffset(supplierAID string, start, end int64) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		if f, ok := h.(ArticleOffsetHandler); ok {
+			return true, f.HandleArticleOffset(supplierAID, start, end)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleSkippedArticle(supplierAID string, size int64) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		if f, ok := h.(SkippedArticleHandler); ok {
+			return true, f.HandleSkippedArticle(supplierAID, size)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleWarning(w *Warning) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		if f, ok := h.(WarningHandler); ok {
+			return true, f.HandleWarning(w)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleAudit(e *AuditEvent) error {
+	return m.dispatch(func(h interface{}) (bool, error) {
+		if f, ok := h.(AuditHandler); ok {
+			return true, f.HandleAudit(e)
+		}
+		return false, nil
+	})
+}
+
+func (m *multiHandler) HandleComplete() {
+	for _, h := range m.handlers {
+		if f, ok := h.(CompletionHandler); ok {
+			f.HandleComplete()
+		}
+	}
+}
+
+func (m *multiHandler) HandleCompleteStats(stats *CompletionStats) {
+	for _, h := range m.handlers {
+		switch f := h.(type) {
+		case CompletionStatsHandler:
+			f.HandleCompleteStats(stats)
+		case CompletionHandler:
+			f.HandleComplete()
+		}
+	}
+}
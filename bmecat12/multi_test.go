@@ -0,0 +1,161 @@
+package bmecat12_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const multiHeaderDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <CATALOG_GROUP_SYSTEM>
+      <CATALOG_STRUCTURE type="root"><GROUP_ID>1</GROUP_ID><GROUP_NAME>Root</GROUP_NAME><PARENT_ID>0</PARENT_ID></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="leaf"><GROUP_ID>10</GROUP_ID><GROUP_NAME>Schrauben</GROUP_NAME><PARENT_ID>1</PARENT_ID></CATALOG_STRUCTURE>
+    </CATALOG_GROUP_SYSTEM>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+const multiArticlesDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <%[1]s>
+%[2]s
+  </%[1]s>
+</BMECAT>`
+
+func multiArticles(tx string, body ...string) io.ReadSeeker {
+	return strings.NewReader(fmt.Sprintf(multiArticlesDoc, tx, strings.Join(body, "\n")))
+}
+
+func TestMultiReader(t *testing.T) {
+	sources := []io.ReadSeeker{
+		strings.NewReader(multiHeaderDoc),
+		multiArticles("T_NEW_CATALOG",
+			`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+			`<ARTICLE><SUPPLIER_AID>2000</SUPPLIER_AID></ARTICLE>`,
+			`<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>3000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>`,
+		),
+		multiArticles("T_NEW_CATALOG",
+			`<ARTICLE><SUPPLIER_AID>3000</SUPPLIER_AID></ARTICLE>`,
+			`<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>`,
+		),
+	}
+
+	var headers, transactions, groups, completions int
+	var numArticles int
+	var articles []string
+	h := bmecat12.HandlerFuncs{
+		OnHeader: func(hdr *bmecat12.Header) error {
+			headers++
+			numArticles = hdr.NumberOfArticles
+			return nil
+		},
+		OnTransaction: func(bmecat12.Transaction, int) error {
+			transactions++
+			return nil
+		},
+		OnCatalogGroup: func(*bmecat12.CatalogGroup) error {
+			groups++
+			return nil
+		},
+		OnArticle: func(a *bmecat12.Article) error {
+			articles = append(articles, a.SupplierAID+":"+strings.Join(a.CatalogGroupIDs, ","))
+			return nil
+		},
+		OnComplete: func() {
+			completions++
+		},
+	}
+	m := bmecat12.NewMultiReader(sources)
+	if err := m.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, m.Stats().ArticlesHandled; want != have {
+		t.Fatalf("want ArticlesHandled=%d, have %d", want, have)
+	}
+	if want, have := 1, headers; want != have {
+		t.Fatalf("want %d headers, have %d", want, have)
+	}
+	if want, have := 3, numArticles; want != have {
+		t.Fatalf("want NumberOfArticles=%d, have %d", want, have)
+	}
+	if want, have := 1, transactions; want != have {
+		t.Fatalf("want %d transactions, have %d", want, have)
+	}
+	if want, have := 2, groups; want != have {
+		t.Fatalf("want %d catalog groups, have %d", want, have)
+	}
+	if want, have := "1000:10 2000: 3000:10", strings.Join(articles, " "); want != have {
+		t.Fatalf("want articles %q, have %q", want, have)
+	}
+	if want, have := 1, completions; want != have {
+		t.Fatalf("want %d completions, have %d", want, have)
+	}
+}
+
+func TestMultiReaderWithMixedTransactions(t *testing.T) {
+	sources := []io.ReadSeeker{
+		strings.NewReader(multiHeaderDoc),
+		multiArticles("T_UPDATE_PRODUCTS", `<ARTICLE mode="new"><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`),
+	}
+	err := bmecat12.NewMultiReader(sources).Do(context.Background(), &testHandler{})
+	if !errors.Is(err, bmecat12.ErrMultipleTransactions) {
+		t.Fatalf("want ErrMultipleTransactions, have %v", err)
+	}
+}
+
+func TestMultiReaderCompletionStats(t *testing.T) {
+	sources := []io.ReadSeeker{
+		strings.NewReader(multiHeaderDoc),
+		multiArticles("T_NEW_CATALOG",
+			`<ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>`,
+			`<ARTICLE><SUPPLIER_AID>2000</SUPPLIER_AID></ARTICLE>`,
+		),
+		multiArticles("T_NEW_CATALOG",
+			`<ARTICLE><SUPPLIER_AID>3000</SUPPLIER_AID></ARTICLE>`,
+			`<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>`,
+		),
+	}
+	var calls int
+	var stats *bmecat12.CompletionStats
+	h := bmecat12.HandlerFuncs{
+		OnCompleteStats: func(s *bmecat12.CompletionStats) {
+			calls++
+			stats = s
+		},
+	}
+	m := bmecat12.NewMultiReader(sources)
+	if err := m.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, calls; want != have {
+		t.Fatalf("want OnCompleteStats to be called %d times, have %d", want, have)
+	}
+	if want, have := 3, stats.ArticlesHandled; want != have {
+		t.Fatalf("want ArticlesHandled=%d, have %d", want, have)
+	}
+	want := map[string]int{
+		"ARTICLE":                     3,
+		"ARTICLE_TO_CATALOGGROUP_MAP": 1,
+		"CATALOG_STRUCTURE":           2,
+		"CLASSIFICATION_GROUP":        0,
+		"CLASSIFICATION_SYSTEM":       0,
+		"FEATURE_SYSTEM":              0,
+	}
+	for name, n := range want {
+		if have := stats.Elements[name]; n != have {
+			t.Fatalf("want %d %s elements, have %d", n, name, have)
+		}
+	}
+}
@@ -0,0 +1,61 @@
+package bmecat12
+
+import "strings"
+
+// WithNumberFormat specifies the number of decimal places of the numbers
+// in the given elements, e.g. WithNumberFormat(2, 2, "PRICE_AMOUNT") to
+// write all amounts with two decimals, as receivers that validate against
+// a fixed format demand. Numbers with fewer than minDecimals decimal
+// places are padded with zeros; numbers with more than maxDecimals are
+// rounded half away from zero. A negative maxDecimals keeps all decimal
+// places. If no elements are given, the format applies to the numbers of
+// prices and order details: PRICE_AMOUNT, TAX, PRICE_FACTOR,
+// LOWER_BOUND, NO_CU_PER_OU, PRICE_QUANTITY, QUANTITY_MIN, and
+// QUANTITY_INTERVAL. The option may be given more than once, e.g. with
+// WithNumberFormat(0, 4, "PRICE_FACTOR") after the former, and applies
+// to the elements of ARTICLE, HEADER, and the catalog structure.
+func WithNumberFormat(minDecimals, maxDecimals int, elements ...string) WriterOption {
+	return func(w *Writer) {
+		if len(elements) == 0 {
+			elements = []string{
+				"PRICE_AMOUNT", "TAX", "PRICE_FACTOR", "LOWER_BOUND",
+				"NO_CU_PER_OU", "PRICE_QUANTITY", "QUANTITY_MIN", "QUANTITY_INTERVAL",
+			}
+		}
+		if w.numbers == nil {
+			w.numbers = make(map[string]numberFormat)
+		}
+		for _, name := range elements {
+			w.numbers[name] = numberFormat{min: minDecimals, max: maxDecimals}
+		}
+	}
+}
+
+// numberFormat is a format of WithNumberFormat.
+type numberFormat struct {
+	min, max int
+}
+
+// format formats the number s. Text that is not a number is returned
+// unchanged.
+func (f numberFormat) format(s string) string {
+	d, ok, inRange := parseDecimal(strings.TrimSpace(s))
+	if !ok || !inRange {
+		return s
+	}
+	if f.max >= 0 {
+		d = d.Round(int32(f.max))
+	}
+	s = d.String()
+	decimals := 0
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		decimals = len(s) - i - 1
+	}
+	if decimals >= f.min {
+		return s
+	}
+	if decimals == 0 {
+		s += "."
+	}
+	return s + strings.Repeat("0", f.min-decimals)
+}
@@ -0,0 +1,79 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteNumberFormat(t *testing.T) {
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		articles: []*bmecat12.Article{
+			{
+				SupplierAID: "1000",
+				OrderDetails: &bmecat12.ArticleOrderDetails{
+					OrderUnit:     "C62",
+					NoCuPerOu:     float64Ptr(6),
+					QuantityMin:   float64Ptr(1e19),
+					PriceQuantity: float64Ptr(0.5),
+				},
+				PriceDetails: []*bmecat12.ArticlePriceDetails{
+					{Prices: []*bmecat12.ArticlePrice{
+						{
+							Type:       bmecat12.ArticlePriceTypeNetList,
+							Amount:     bmecat12.MustParseDecimal("1499.495"),
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("0.123456"),
+							LowerBound: float64Ptr(1),
+						},
+						{Type: bmecat12.ArticlePriceTypeGrosList, Amount: bmecat12.MustParseDecimal("-3")},
+					}},
+				},
+			},
+		},
+	}
+	want := []string{
+		"<NO_CU_PER_OU>6.000</NO_CU_PER_OU>",
+		"<PRICE_QUANTITY>0.500</PRICE_QUANTITY>",
+		"<QUANTITY_MIN>10000000000000000000.000</QUANTITY_MIN>",
+		"<PRICE_AMOUNT>1499.50</PRICE_AMOUNT>",
+		"<PRICE_AMOUNT>-3.00</PRICE_AMOUNT>",
+		"<TAX>0.19</TAX>",
+		"<PRICE_FACTOR>0.1235</PRICE_FACTOR>",
+		"<LOWER_BOUND>1</LOWER_BOUND>",
+	}
+	tests := map[string][]bmecat12.WriterOption{
+		"direct":      nil,
+		"reflection":  {bmecat12.WithDirectEncoding(false)},
+		"concurrency": {bmecat12.WithWriterConcurrency(4)},
+	}
+	var outputs []string
+	for name, options := range tests {
+		options = append(options,
+			bmecat12.WithNumberFormat(3, -1),
+			bmecat12.WithNumberFormat(2, 2, "PRICE_AMOUNT"),
+			bmecat12.WithNumberFormat(0, 4, "TAX", "PRICE_FACTOR", "LOWER_BOUND"),
+		)
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, options...).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, s := range want {
+			if !strings.Contains(out, s) {
+				t.Fatalf("%s: want %s in\n%s", name, s, out)
+			}
+		}
+		outputs = append(outputs, out)
+	}
+	for _, out := range outputs[1:] {
+		if out != outputs[0] {
+			diffStrings(t, outputs[0], out)
+		}
+	}
+}
@@ -0,0 +1,204 @@
+package bmecat12
+
+import "sort"
+
+// OutputOrder specifies which lists the Writer sorts before encoding, see
+// WithOutputOrder.
+type OutputOrder int
+
+const (
+	// SortKeywords sorts the KEYWORD elements of articles and catalog
+	// groups.
+	SortKeywords OutputOrder = 1 << iota
+	// SortFeatures sorts the FEATURE elements of each ARTICLE_FEATURES by
+	// FORDER, then by FNAME. Features without FORDER come last.
+	SortFeatures
+	// SortPrices sorts the ARTICLE_PRICE elements of each ARTICLE_PRICE_
+	// DETAILS by LOWER_BOUND, then by price type and currency.
+	SortPrices
+	// SortMime sorts the MIME elements of articles by MIME_ORDER, then by
+	// MIME_SOURCE. MIME elements without MIME_ORDER come last.
+	SortMime
+	// SortAll sorts all of the above.
+	SortAll = SortKeywords | SortFeatures | SortPrices | SortMime
+)
+
+// WithOutputOrder makes the Writer sort the lists given by order before
+// encoding, so repeated exports of the same data produce byte-identical
+// files, e.g. when the data comes from maps. The header, the catalog
+// structure, and the articles are not modified.
+func WithOutputOrder(order OutputOrder) WriterOption {
+	return func(w *Writer) {
+		w.outputOrder = order
+	}
+}
+
+// sortArticle returns a with its lists sorted as specified by
+// WithOutputOrder. a is not modified; if nothing changes, a is returned
+// as is.
+func (w *Writer) sortArticle(a *Article) *Article {
+	if w.outputOrder == 0 {
+		return a
+	}
+	prepared := *a
+	var changed bool
+	if w.outputOrder&SortKeywords != 0 && a.Details != nil {
+		if keywords, ok := sortedStrings(a.Details.Keywords); ok {
+			details := *a.Details
+			details.Keywords = keywords
+			prepared.Details = &details
+			changed = true
+		}
+	}
+	if w.outputOrder&SortFeatures != 0 {
+		var features []*ArticleFeatures
+		for i, af := range a.Features {
+			sorted, ok := sortedFeatures(af.Features)
+			if !ok {
+				continue
+			}
+			if features == nil {
+				features = make([]*ArticleFeatures, len(a.Features))
+				copy(features, a.Features)
+			}
+			copied := *af
+			copied.Features = sorted
+			features[i] = &copied
+		}
+		if features != nil {
+			prepared.Features = features
+			changed = true
+		}
+	}
+	if w.outputOrder&SortPrices != 0 {
+		var details []*ArticlePriceDetails
+		for i, d := range a.PriceDetails {
+			sorted, ok := sortedPrices(d.Prices)
+			if !ok {
+				continue
+			}
+			if details == nil {
+				details = make([]*ArticlePriceDetails, len(a.PriceDetails))
+				copy(details, a.PriceDetails)
+			}
+			copied := *d
+			copied.Prices = sorted
+			details[i] = &copied
+		}
+		if details != nil {
+			prepared.PriceDetails = details
+			changed = true
+		}
+	}
+	if w.outputOrder&SortMime != 0 && a.MimeInfo != nil {
+		if mimes, ok := sortedMimes(a.MimeInfo.Mimes); ok {
+			info := *a.MimeInfo
+			info.Mimes = mimes
+			prepared.MimeInfo = &info
+			changed = true
+		}
+	}
+	if !changed {
+		return a
+	}
+	return &prepared
+}
+
+// sortCatalogGroupSystem returns system with the keywords of its groups
+// sorted as specified by WithOutputOrder. system is not modified; if
+// nothing changes, system is returned as is.
+func (w *Writer) sortCatalogGroupSystem(system *CatalogGroupSystem) *CatalogGroupSystem {
+	if w.outputOrder&SortKeywords == 0 {
+		return system
+	}
+	var groups []*CatalogGroup
+	for i, g := range system.Groups {
+		keywords, ok := sortedStrings(g.Keywords)
+		if !ok {
+			continue
+		}
+		if groups == nil {
+			groups = make([]*CatalogGroup, len(system.Groups))
+			copy(groups, system.Groups)
+		}
+		copied := *g
+		copied.Keywords = keywords
+		groups[i] = &copied
+	}
+	if groups == nil {
+		return system
+	}
+	prepared := *system
+	prepared.Groups = groups
+	return &prepared
+}
+
+// sortedStrings returns a sorted copy of s, and true if s was not sorted.
+func sortedStrings(s []string) ([]string, bool) {
+	if sort.StringsAreSorted(s) {
+		return s, false
+	}
+	sorted := append([]string(nil), s...)
+	sort.Strings(sorted)
+	return sorted, true
+}
+
+// sortedFeatures returns a sorted copy of features, and true if features
+// were not sorted.
+func sortedFeatures(features []*Feature) ([]*Feature, bool) {
+	less := func(s []*Feature) func(i, j int) bool {
+		return func(i, j int) bool {
+			if oi, oj := s[i].Order, s[j].Order; oi != oj {
+				return oj == 0 || oi != 0 && oi < oj
+			}
+			return s[i].Name < s[j].Name
+		}
+	}
+	if sort.SliceIsSorted(features, less(features)) {
+		return features, false
+	}
+	sorted := append([]*Feature(nil), features...)
+	sort.SliceStable(sorted, less(sorted))
+	return sorted, true
+}
+
+// sortedPrices returns a sorted copy of prices, and true if prices were
+// not sorted.
+func sortedPrices(prices []*ArticlePrice) ([]*ArticlePrice, bool) {
+	less := func(s []*ArticlePrice) func(i, j int) bool {
+		return func(i, j int) bool {
+			switch {
+			case floatValue(s[i].LowerBound) != floatValue(s[j].LowerBound):
+				return floatValue(s[i].LowerBound) < floatValue(s[j].LowerBound)
+			case s[i].Type != s[j].Type:
+				return s[i].Type < s[j].Type
+			}
+			return s[i].Currency < s[j].Currency
+		}
+	}
+	if sort.SliceIsSorted(prices, less(prices)) {
+		return prices, false
+	}
+	sorted := append([]*ArticlePrice(nil), prices...)
+	sort.SliceStable(sorted, less(sorted))
+	return sorted, true
+}
+
+// sortedMimes returns a sorted copy of mimes, and true if mimes were not
+// sorted.
+func sortedMimes(mimes []*Mime) ([]*Mime, bool) {
+	less := func(s []*Mime) func(i, j int) bool {
+		return func(i, j int) bool {
+			if oi, oj := s[i].Order, s[j].Order; oi != oj {
+				return oj == 0 || oi != 0 && oi < oj
+			}
+			return s[i].Source < s[j].Source
+		}
+	}
+	if sort.SliceIsSorted(mimes, less(mimes)) {
+		return mimes, false
+	}
+	sorted := append([]*Mime(nil), mimes...)
+	sort.SliceStable(sorted, less(sorted))
+	return sorted, true
+}
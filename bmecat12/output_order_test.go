@@ -0,0 +1,97 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithOutputOrder(t *testing.T) {
+	article := func(reverse bool) *bmecat12.Article {
+		a := &bmecat12.Article{
+			SupplierAID: "1000",
+			Details:     &bmecat12.ArticleDetails{Keywords: []string{"Schraube", "Edelstahl", "M8"}},
+			Features: []*bmecat12.ArticleFeatures{{Features: []*bmecat12.Feature{
+				{Name: "Material", Values: []string{"A2"}},
+				{Name: "Länge", Order: 2, Values: []string{"40"}},
+				{Name: "Gewinde", Order: 1, Values: []string{"M8"}},
+				{Name: "Farbe", Values: []string{"silber"}},
+			}}},
+			PriceDetails: []*bmecat12.ArticlePriceDetails{{Prices: []*bmecat12.ArticlePrice{
+				{Type: bmecat12.ArticlePriceTypeNetCustomer, Amount: bmecat12.MustParseDecimal("0.9"), LowerBound: float64Ptr(100)},
+				{Type: bmecat12.ArticlePriceTypeNetCustomer, Amount: bmecat12.MustParseDecimal("1"), LowerBound: float64Ptr(1)},
+				{Type: bmecat12.ArticlePriceTypeNRP, Amount: bmecat12.MustParseDecimal("2"), LowerBound: float64Ptr(1)},
+			}}},
+			MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+				{Source: "c.jpg"},
+				{Source: "b.jpg", Order: 2},
+				{Source: "a.jpg", Order: 1},
+			}},
+		}
+		if reverse {
+			d := a.Details.Keywords
+			d[0], d[2] = d[2], d[0]
+			f := a.Features[0].Features
+			f[0], f[3] = f[3], f[0]
+			p := a.PriceDetails[0].Prices
+			p[0], p[2] = p[2], p[0]
+			m := a.MimeInfo.Mimes
+			m[0], m[2] = m[2], m[0]
+		}
+		return a
+	}
+	write := func(a *bmecat12.Article, options ...bmecat12.WriterOption) string {
+		cw := catalogWriter{
+			tx:       bmecat12.NewCatalog,
+			language: "de",
+			header:   testHeader,
+			articles: []*bmecat12.Article{a},
+		}
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, options...).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+	values := func(out, element string) string {
+		re := regexp.MustCompile(`<` + element + `>([^<]*)</` + element + `>`)
+		var s []string
+		for _, m := range re.FindAllStringSubmatch(out[strings.Index(out, "<ARTICLE>"):], -1) {
+			s = append(s, m[1])
+		}
+		return strings.Join(s, ",")
+	}
+
+	a := article(false)
+	out := write(a, bmecat12.WithOutputOrder(bmecat12.SortAll))
+	tests := []struct {
+		Element string
+		Want    string
+	}{
+		{"KEYWORD", "Edelstahl,M8,Schraube"},
+		{"FNAME", "Gewinde,Länge,Farbe,Material"},
+		{"PRICE_AMOUNT", "1,2,0.9"},
+		{"MIME_SOURCE", "a.jpg,b.jpg,c.jpg"},
+	}
+	for _, tt := range tests {
+		if want, have := tt.Want, values(out, tt.Element); want != have {
+			t.Fatalf("want %s %s, have %s", tt.Element, want, have)
+		}
+	}
+	if want, have := "Schraube", a.Details.Keywords[0]; want != have {
+		t.Fatalf("want article not modified, have KEYWORD %s", have)
+	}
+	if want, have := out, write(article(true), bmecat12.WithOutputOrder(bmecat12.SortAll)); want != have {
+		t.Fatal("want identical output for different input order")
+	}
+
+	// Only the lists given are sorted
+	out = write(article(false), bmecat12.WithOutputOrder(bmecat12.SortKeywords))
+	if want, have := "Material,Länge,Gewinde,Farbe", values(out, "FNAME"); want != have {
+		t.Fatalf("want FNAME %s, have %s", want, have)
+	}
+}
@@ -0,0 +1,254 @@
+package bmecat12
+
+import (
+	"archive/zip"
+	"context"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultPackageCatalogName is the name of the BMEcat XML file in
+	// the archive written by a PackageWriter by default.
+	DefaultPackageCatalogName = "catalog.xml"
+	// DefaultPackageMimeFolder is the folder of the MIME files in the
+	// archive written by a PackageWriter by default.
+	DefaultPackageMimeFolder = "mime"
+)
+
+// MimeFetcher opens the file referenced by a MIME_SOURCE for a
+// PackageWriter. Relative sources are resolved against the MIME_ROOT of
+// the catalog before they are passed to FetchMime.
+type MimeFetcher interface {
+	FetchMime(ctx context.Context, source string) (io.ReadCloser, error)
+}
+
+// MimeFetcherFunc is an adapter to allow the use of ordinary functions
+// as a MimeFetcher, e.g. to download images via HTTP.
+type MimeFetcherFunc func(ctx context.Context, source string) (io.ReadCloser, error)
+
+// FetchMime calls f(ctx, source).
+func (f MimeFetcherFunc) FetchMime(ctx context.Context, source string) (io.ReadCloser, error) {
+	return f(ctx, source)
+}
+
+// MimeDir returns a MimeFetcher that opens the MIME files from the local
+// directory dir, e.g. MIME_SOURCE "images/a.jpg" as dir/images/a.jpg.
+// Absolute URLs cannot be fetched from a directory and return an error.
+func MimeDir(dir string) MimeFetcher {
+	return MimeFetcherFunc(func(ctx context.Context, source string) (io.ReadCloser, error) {
+		if isAbsoluteURL(source) {
+			return nil, errors.Errorf("bmecat: cannot fetch %s from a directory", source)
+		}
+		p, _ := splitMimeSource(source)
+		return os.Open(filepath.Join(dir, filepath.FromSlash(path.Clean("/"+p))))
+	})
+}
+
+// PackageWriter writes a catalog as a zip archive with the BMEcat XML
+// file and the MIME files it references, the delivery format demanded
+// by several procurement portals. The MIME files are fetched with a
+// MimeFetcher and stored in a folder of the archive, and MIME_SOURCE is
+// rewritten to their paths in the archive. MIME_ROOT is removed from
+// the HEADER, as the sources are relative to the archive then. Use
+// OpenZip to read the catalog of the archive.
+type PackageWriter struct {
+	w       io.Writer
+	fetcher MimeFetcher
+	name    string
+	folder  string
+	options []WriterOption
+	assets  []*packageAsset
+}
+
+// PackageOption is the signature of options to pass into
+// NewPackageWriter.
+type PackageOption func(*PackageWriter)
+
+// NewPackageWriter creates a new PackageWriter that writes the zip
+// archive to w and fetches the MIME files with fetcher.
+func NewPackageWriter(w io.Writer, fetcher MimeFetcher, options ...PackageOption) *PackageWriter {
+	p := &PackageWriter{
+		w:       w,
+		fetcher: fetcher,
+		name:    DefaultPackageCatalogName,
+		folder:  DefaultPackageMimeFolder,
+	}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// WithPackageCatalogName specifies the name of the BMEcat XML file in
+// the archive. The default is DefaultPackageCatalogName.
+func WithPackageCatalogName(name string) PackageOption {
+	return func(p *PackageWriter) {
+		p.name = name
+	}
+}
+
+// WithPackageMimeFolder specifies the folder of the MIME files in the
+// archive. The default is DefaultPackageMimeFolder. If folder is empty,
+// the MIME files are stored in the root of the archive.
+func WithPackageMimeFolder(folder string) PackageOption {
+	return func(p *PackageWriter) {
+		p.folder = strings.Trim(folder, "/")
+	}
+}
+
+// WithPackageWriterOptions specifies the options of the Writer used for
+// the BMEcat XML file, e.g. WithEncoding. WithGzip must not be used, as
+// the file is compressed in the archive.
+func WithPackageWriterOptions(options ...WriterOption) PackageOption {
+	return func(p *PackageWriter) {
+		p.options = append(p.options, options...)
+	}
+}
+
+// Do writes the archive. The BMEcat XML file is written first; the MIME
+// files are fetched and added afterwards, each file once, in the order
+// they are first referenced. Do fails if a MIME file cannot be fetched.
+// The underlying io.Writer is not closed.
+func (p *PackageWriter) Do(ctx context.Context, writer CatalogWriter) error {
+	zw := zip.NewWriter(p.w)
+	entry, err := zw.Create(p.name)
+	if err != nil {
+		return errors.Wrap(err, "bmecat: unable to create zip entry")
+	}
+	assets := &packageAssets{folder: p.folder, names: make(map[string]string), taken: make(map[string]bool)}
+	options := append(append([]WriterOption{}, p.options...), func(w *Writer) {
+		w.pkg = assets
+	})
+	if err := NewWriter(entry, options...).Do(ctx, writer); err != nil {
+		return err
+	}
+	for _, asset := range assets.list {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := p.writeAsset(ctx, zw, asset); err != nil {
+			return err
+		}
+	}
+	return zw.Close()
+}
+
+// writeAsset fetches the MIME file of asset and adds it to the archive.
+func (p *PackageWriter) writeAsset(ctx context.Context, zw *zip.Writer, asset *packageAsset) error {
+	rc, err := p.fetcher.FetchMime(ctx, asset.source)
+	if err != nil {
+		return errors.Wrapf(err, "bmecat: unable to fetch %s", asset.source)
+	}
+	defer rc.Close()
+	f, err := zw.Create(asset.name)
+	if err != nil {
+		return errors.Wrap(err, "bmecat: unable to create zip entry")
+	}
+	if _, err := io.Copy(f, rc); err != nil {
+		return errors.Wrapf(err, "bmecat: unable to fetch %s", asset.source)
+	}
+	return nil
+}
+
+// packageAsset is a MIME file of the archive of a PackageWriter.
+type packageAsset struct {
+	// source is the resolved MIME_SOURCE passed to the MimeFetcher.
+	source string
+	// name is the path in the archive.
+	name string
+}
+
+// packageAssets collects the MIME files referenced by the Writer of a
+// PackageWriter.
+type packageAssets struct {
+	folder   string
+	mimeRoot string
+	list     []*packageAsset
+	// names are the paths in the archive by resolved source, and taken
+	// are the paths in use.
+	names map[string]string
+	taken map[string]bool
+}
+
+// packageHeader records the MIME_ROOT of header and removes it, if the
+// Writer belongs to a PackageWriter.
+func (w *Writer) packageHeader(header *Header) *Header {
+	if w.pkg == nil || header == nil || header.Catalog == nil || header.Catalog.MimeRoot == "" {
+		return header
+	}
+	w.pkg.mimeRoot = header.Catalog.MimeRoot
+	catalog := *header.Catalog
+	catalog.MimeRoot = ""
+	prepared := *header
+	prepared.Catalog = &catalog
+	return &prepared
+}
+
+// packageArticle rewrites the MIME_SOURCE of the article to the paths
+// in the archive, if the Writer belongs to a PackageWriter.
+func (w *Writer) packageArticle(a *Article) *Article {
+	if w.pkg == nil {
+		return a
+	}
+	mimeInfo := mapMimeSources(a.MimeInfo, w.pkg.source)
+	if mimeInfo == a.MimeInfo {
+		return a
+	}
+	prepared := *a
+	prepared.MimeInfo = mimeInfo
+	return &prepared
+}
+
+// source records the MIME file with the given MIME_SOURCE, and returns
+// its path in the archive.
+func (p *packageAssets) source(source string) string {
+	return p.add(resolveMimeSource(p.mimeRoot, source))
+}
+
+// add records the MIME file with the resolved source, and returns its
+// path in the archive.
+func (p *packageAssets) add(source string) string {
+	if name, ok := p.names[source]; ok {
+		return name
+	}
+	name := p.path(source)
+	if p.taken[name] {
+		ext := path.Ext(name)
+		for i := 2; ; i++ {
+			candidate := fmt.Sprintf("%s-%d%s", strings.TrimSuffix(name, ext), i, ext)
+			if !p.taken[candidate] {
+				name = candidate
+				break
+			}
+		}
+	}
+	p.names[source] = name
+	p.taken[name] = true
+	p.list = append(p.list, &packageAsset{source: source, name: name})
+	return name
+}
+
+// path returns the path in the archive for the resolved source: the
+// path of a relative source, or the host and path of an absolute URL,
+// in the folder of the MIME files.
+func (p *packageAssets) path(source string) string {
+	s, _ := splitMimeSource(source)
+	if isAbsoluteURL(source) {
+		if u, err := url.Parse(source); err == nil {
+			s = u.Host + "/" + u.Path
+		}
+	}
+	s = strings.TrimPrefix(path.Clean("/"+s), "/")
+	if p.folder == "" {
+		return s
+	}
+	return p.folder + "/" + s
+}
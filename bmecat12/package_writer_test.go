@@ -0,0 +1,226 @@
+package bmecat12_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestPackageWriter(t *testing.T) {
+	files := map[string]string{
+		"https://example.com/images/a.jpg":      "image a",
+		"https://example.com/images/docs/a.pdf": "data sheet a",
+		"https://cdn.example.com/b.jpg":         "image b",
+	}
+	var fetched []string
+	fetcher := bmecat12.MimeFetcherFunc(func(ctx context.Context, source string) (io.ReadCloser, error) {
+		fetched = append(fetched, source)
+		content, ok := files[source]
+		if !ok {
+			return nil, errors.New("not found")
+		}
+		return ioutil.NopCloser(strings.NewReader(content)), nil
+	})
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{
+				SupplierAID: "1000",
+				MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+					{Type: bmecat12.MimeTypeJPEG, Source: "a.jpg", Purpose: bmecat12.MimePurposeNormal},
+					{Type: bmecat12.MimeTypePDF, Source: "docs/a.pdf", Purpose: bmecat12.MimePurposeDataSheet},
+				}},
+			},
+			{
+				SupplierAID: "2000",
+				MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+					{Type: bmecat12.MimeTypeJPEG, Source: "a.jpg", Purpose: bmecat12.MimePurposeNormal},
+					{Type: bmecat12.MimeTypeJPEG, Source: "https://cdn.example.com/b.jpg", Purpose: bmecat12.MimePurposeDetail},
+				}},
+			},
+		},
+	}
+
+	var buf bytes.Buffer
+	p := bmecat12.NewPackageWriter(&buf, fetcher)
+	if err := p.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, len(fetched); want != have {
+		t.Fatalf("want %d fetches, have %d: %v", want, have, fetched)
+	}
+	if want, have := "https://example.com/images", testHeader.Catalog.MimeRoot; want != have {
+		t.Fatalf("want MIME_ROOT of the header unchanged, have %q", have)
+	}
+	if want, have := "a.jpg", cw.articles[0].MimeInfo.Mimes[0].Source; want != have {
+		t.Fatalf("want MIME_SOURCE of the article unchanged, have %q", have)
+	}
+
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	wantEntries := map[string]string{
+		"mime/example.com/images/a.jpg":      "image a",
+		"mime/example.com/images/docs/a.pdf": "data sheet a",
+		"mime/cdn.example.com/b.jpg":         "image b",
+	}
+	if want, have := len(wantEntries)+1, len(zr.File); want != have {
+		t.Fatalf("want %d entries, have %d", want, have)
+	}
+	for _, f := range zr.File {
+		if f.Name == bmecat12.DefaultPackageCatalogName {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		content, err := ioutil.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := wantEntries[f.Name], string(content); want != have {
+			t.Fatalf("%s: want %q, have %q", f.Name, want, have)
+		}
+	}
+
+	entry, err := bmecat12.OpenZip(bytes.NewReader(buf.Bytes()), int64(buf.Len()), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer entry.Close()
+	h := &testHandler{}
+	if err := bmecat12.NewReader(entry).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "", h.header.Catalog.MimeRoot; want != have {
+		t.Fatalf("want MIME_ROOT %q, have %q", want, have)
+	}
+	if want, have := 2, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	wantSources := [][]string{
+		{"mime/example.com/images/a.jpg", "mime/example.com/images/docs/a.pdf"},
+		{"mime/example.com/images/a.jpg", "mime/cdn.example.com/b.jpg"},
+	}
+	for i, a := range h.articles {
+		for j, m := range a.MimeInfo.Mimes {
+			if want, have := wantSources[i][j], m.Source; want != have {
+				t.Fatalf("article %d, MIME %d: want MIME_SOURCE %q, have %q", i, j, want, have)
+			}
+		}
+	}
+}
+
+func TestPackageWriterMimeDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-package")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := os.MkdirAll(filepath.Join(dir, "images"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "images", "a.jpg"), []byte("image a"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	article := func(source string) *bmecat12.Article {
+		return &bmecat12.Article{
+			SupplierAID: "1000",
+			MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+				{Type: bmecat12.MimeTypeJPEG, Source: source, Purpose: bmecat12.MimePurposeNormal},
+			}},
+		}
+	}
+	header := &bmecat12.Header{Catalog: &bmecat12.Catalog{Language: "deu", ID: "CAT1", Version: "1.0"}}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   header,
+		articles: []*bmecat12.Article{article("images/a.jpg")},
+	}
+	var buf bytes.Buffer
+	p := bmecat12.NewPackageWriter(&buf, bmecat12.MimeDir(dir),
+		bmecat12.WithPackageCatalogName("export/bmecat.xml"),
+		bmecat12.WithPackageMimeFolder("assets"),
+	)
+	if err := p.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range zr.File {
+		names = append(names, f.Name)
+	}
+	if want, have := "export/bmecat.xml,assets/images/a.jpg", strings.Join(names, ","); want != have {
+		t.Fatalf("want entries %s, have %s", want, have)
+	}
+
+	cw.articles = []*bmecat12.Article{article("images/missing.jpg")}
+	buf.Reset()
+	if err := bmecat12.NewPackageWriter(&buf, bmecat12.MimeDir(dir)).Do(context.Background(), cw); err == nil {
+		t.Fatal("want error for missing MIME file")
+	}
+}
+
+func TestPackageWriterCatalogGroupMime(t *testing.T) {
+	fetcher := bmecat12.MimeFetcherFunc(func(ctx context.Context, source string) (io.ReadCloser, error) {
+		if source != "https://example.com/images/drills.jpg" {
+			return nil, errors.New("not found")
+		}
+		return ioutil.NopCloser(strings.NewReader("drills")), nil
+	})
+	cw := subtreeCatalogWriter{
+		catalogWriter: catalogWriter{tx: bmecat12.NewCatalog, header: testHeader},
+		groupSystem: &bmecat12.CatalogGroupSystem{
+			Groups: []*bmecat12.CatalogGroup{
+				{Type: "root", ID: "1", Name: "Drills", MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+					{Source: "drills.jpg", Purpose: bmecat12.MimePurposeNormal},
+				}}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewPackageWriter(&buf, fetcher).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	zr, err := zip.NewReader(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(zr.File); want != have {
+		t.Fatalf("want %d entries, have %d", want, have)
+	}
+	if want, have := "mime/example.com/images/drills.jpg", zr.File[1].Name; want != have {
+		t.Fatalf("want entry %q, have %q", want, have)
+	}
+	rc, err := zr.File[0].Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	catalog, err := ioutil.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "<MIME_SOURCE>mime/example.com/images/drills.jpg</MIME_SOURCE>"; !bytes.Contains(catalog, []byte(want)) {
+		t.Fatalf("want %s in\n%s", want, catalog)
+	}
+}
@@ -0,0 +1,306 @@
+package bmecat12
+
+import (
+	"context"
+	"sync"
+)
+
+// Transformer transforms articles while a Pipeline copies them from
+// a Reader to a Writer.
+type Transformer interface {
+	// TransformArticle returns the article to write. It may return the
+	// article unchanged, modify it, or return nil to drop it from the
+	// output. Returning an error stops the Pipeline.
+	TransformArticle(*Article) (*Article, error)
+}
+
+// TransformerFunc is an adapter to allow the use of ordinary functions
+// as a Transformer.
+type TransformerFunc func(*Article) (*Article, error)
+
+// TransformArticle calls f(a).
+func (f TransformerFunc) TransformArticle(a *Article) (*Article, error) {
+	return f(a)
+}
+
+// Finisher can be implemented by a Transformer to emit additional
+// articles after the last article of the catalog has been read. The
+// articles are passed through the Transformers following it.
+type Finisher interface {
+	FinishArticles() ([]*Article, error)
+}
+
+// Pipeline copies a BMEcat catalog from a Reader to a Writer, passing
+// each article through a chain of Transformers. Articles are streamed,
+// so only the catalog structure (classification and catalog groups) is
+// kept in memory.
+type Pipeline struct {
+	r            *Reader
+	w            *Writer
+	transformers []Transformer
+	handlers     []interface{}
+	tx           *Transaction
+	prevVersion  int
+}
+
+// PipelineOption is the signature of options to pass into a NewPipeline.
+type PipelineOption func(*Pipeline)
+
+// WithTransformer adds a Transformer to the Pipeline. Transformers are
+// invoked in the order they are added.
+func WithTransformer(t Transformer) PipelineOption {
+	return func(p *Pipeline) {
+		p.transformers = append(p.transformers, t)
+	}
+}
+
+// WithHandler adds a handler that gets the events of the Reader, in
+// addition to the Pipeline, e.g. a Metrics. See MultiHandler for how
+// events and errors are dispatched.
+func WithHandler(h interface{}) PipelineOption {
+	return func(p *Pipeline) {
+		p.handlers = append(p.handlers, h)
+	}
+}
+
+// WithTransaction overrides the transaction of the written catalog.
+// By default, the transaction and previous version of the catalog read
+// are used.
+func WithTransaction(tx Transaction, prevVersion int) PipelineOption {
+	return func(p *Pipeline) {
+		p.tx = &tx
+		p.prevVersion = prevVersion
+	}
+}
+
+// NewPipeline creates a new Pipeline that reads from r and writes to w.
+func NewPipeline(r *Reader, w *Writer, options ...PipelineOption) *Pipeline {
+	p := &Pipeline{r: r, w: w}
+	for _, o := range options {
+		o(p)
+	}
+	return p
+}
+
+// Do copies the catalog. It returns the first error of either the
+// Reader, the Writer, or one of the Transformers.
+func (p *Pipeline) Do(ctx context.Context) error {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	c := &pipelineCatalog{
+		ctx:            ctx,
+		p:              p,
+		headerReady:    make(chan struct{}),
+		structureReady: make(chan struct{}),
+		readerDone:     make(chan struct{}),
+		articles:       make(chan *Article),
+		errs:           make(chan error, 1),
+	}
+
+	var handler interface{} = c
+	if len(p.handlers) > 0 {
+		handler = MultiHandler(append([]interface{}{c}, p.handlers...)...)
+	}
+
+	readErr := make(chan error, 1)
+	go func() {
+		err := p.r.Do(ctx, handler)
+		if err == nil {
+			err = c.finish()
+		}
+		if err != nil {
+			c.errs <- err
+		}
+		close(c.readerDone)
+		close(c.articles)
+		readErr <- err
+	}()
+
+	err := p.w.Do(ctx, c)
+	cancel()
+	rerr := <-readErr
+	if err != nil {
+		return err
+	}
+	return rerr
+}
+
+// pipelineCatalog gets the events from the Reader and serves them to
+// the Writer as a CatalogWriter.
+type pipelineCatalog struct {
+	ctx context.Context
+	p   *Pipeline
+
+	headerOnce     sync.Once
+	headerReady    chan struct{}
+	structureOnce  sync.Once
+	structureReady chan struct{}
+	readerDone     chan struct{}
+
+	prolog      *Prolog
+	header      *Header
+	featureSys  []*FeatureSystem
+	classifSys  *ClassificationSystem
+	currentSys  *ClassificationSystem
+	groupSystem *CatalogGroupSystem
+
+	articles chan *Article
+	errs     chan error
+}
+
+// wait blocks until ch is closed or the Reader is done.
+func (c *pipelineCatalog) wait(ch <-chan struct{}) {
+	select {
+	case <-ch:
+	case <-c.readerDone:
+	}
+}
+
+func (c *pipelineCatalog) HandleProlog(p *Prolog) error {
+	c.prolog = p
+	return nil
+}
+
+func (c *pipelineCatalog) HandleHeader(h *Header) error {
+	c.header = h
+	c.headerOnce.Do(func() { close(c.headerReady) })
+	return nil
+}
+
+func (c *pipelineCatalog) HandleFeatureSystem(fs *FeatureSystem) error {
+	c.featureSys = append(c.featureSys, fs)
+	return nil
+}
+
+func (c *pipelineCatalog) HandleClassificationSystem(cs *ClassificationSystem) error {
+	c.currentSys = cs
+	if c.classifSys == nil {
+		c.classifSys = cs
+	}
+	return nil
+}
+
+func (c *pipelineCatalog) HandleClassificationGroup(g *ClassificationGroup) error {
+	if c.currentSys == nil {
+		c.currentSys = &ClassificationSystem{}
+		c.classifSys = c.currentSys
+	}
+	c.currentSys.Groups = append(c.currentSys.Groups, g)
+	return nil
+}
+
+func (c *pipelineCatalog) HandleCatalogGroup(g *CatalogGroup) error {
+	if c.groupSystem == nil {
+		c.groupSystem = &CatalogGroupSystem{}
+	}
+	c.groupSystem.Groups = append(c.groupSystem.Groups, g)
+	return nil
+}
+
+func (c *pipelineCatalog) HandleArticle(a *Article) error {
+	c.structureOnce.Do(func() { close(c.structureReady) })
+	return c.emit(a, 0)
+}
+
+// finish asks all Transformers implementing Finisher for their
+// remaining articles.
+func (c *pipelineCatalog) finish() error {
+	c.structureOnce.Do(func() { close(c.structureReady) })
+	for i, t := range c.p.transformers {
+		f, ok := t.(Finisher)
+		if !ok {
+			continue
+		}
+		articles, err := f.FinishArticles()
+		if err != nil {
+			return err
+		}
+		for _, a := range articles {
+			if err := c.emit(a, i+1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// emit passes the article through the Transformers, starting with the
+// one at index from, and sends it to the Writer.
+func (c *pipelineCatalog) emit(a *Article, from int) error {
+	var err error
+	for _, t := range c.p.transformers[from:] {
+		a, err = t.TransformArticle(a)
+		if err != nil {
+			return err
+		}
+		if a == nil {
+			return nil
+		}
+	}
+	select {
+	case c.articles <- a:
+		return nil
+	case <-c.ctx.Done():
+		return c.ctx.Err()
+	}
+}
+
+func (c *pipelineCatalog) Transaction() Transaction {
+	if c.p.tx != nil {
+		return *c.p.tx
+	}
+	c.wait(c.headerReady)
+	if c.header != nil {
+		return c.header.Transaction
+	}
+	return NewCatalog
+}
+
+func (c *pipelineCatalog) Language() string {
+	c.wait(c.headerReady)
+	if c.header != nil && c.header.Catalog != nil {
+		return c.header.Catalog.Language
+	}
+	return ""
+}
+
+func (c *pipelineCatalog) PreviousVersion() int {
+	if c.p.tx != nil {
+		return c.p.prevVersion
+	}
+	c.wait(c.headerReady)
+	if c.header != nil {
+		return c.header.PreviousVersion
+	}
+	return 0
+}
+
+func (c *pipelineCatalog) Prolog() *Prolog {
+	c.wait(c.headerReady)
+	return c.prolog
+}
+
+func (c *pipelineCatalog) Header() *Header {
+	c.wait(c.headerReady)
+	return c.header
+}
+
+func (c *pipelineCatalog) FeatureSystems() []*FeatureSystem {
+	c.wait(c.structureReady)
+	return c.featureSys
+}
+
+func (c *pipelineCatalog) ClassificationSystem() *ClassificationSystem {
+	c.wait(c.structureReady)
+	return c.classifSys
+}
+
+func (c *pipelineCatalog) CatalogGroupSystem() *CatalogGroupSystem {
+	c.wait(c.structureReady)
+	return c.groupSystem
+}
+
+func (c *pipelineCatalog) Articles(ctx context.Context) (<-chan *Article, <-chan error) {
+	return c.articles, c.errs
+}
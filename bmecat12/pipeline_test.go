@@ -0,0 +1,148 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestPipelineCopiesCatalog(t *testing.T) {
+	for _, name := range []string{
+		"new_catalog.golden.xml",
+		"update_products.golden.xml",
+		"update_prices.golden.xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			var buf bytes.Buffer
+			r := bmecat12.NewReader(bytes.NewReader(data))
+			w := bmecat12.NewWriter(&buf)
+			if err := bmecat12.NewPipeline(r, w).Do(context.Background()); err != nil {
+				t.Fatal(err)
+			}
+			want := strings.TrimSpace(string(data))
+			have := strings.TrimSpace(buf.String())
+			if want != have {
+				diffStrings(t, want, have)
+			}
+		})
+	}
+}
+
+func TestPipelineWithTransformers(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var buf bytes.Buffer
+	r := bmecat12.NewReader(f)
+	w := bmecat12.NewWriter(&buf)
+	p := bmecat12.NewPipeline(r, w,
+		bmecat12.WithTransformer(bmecat12.DenyArticles(bmecat12.NewArticleList("2*"))),
+	)
+	if err := p.Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := "1000", h.articles[0].SupplierAID; want != have {
+		t.Fatalf("want SUPPLIER_AID = %q, have %q", want, have)
+	}
+	if want, have := bmecat12.UpdateProducts, h.tx; want != have {
+		t.Fatalf("want transaction %v, have %v", want, have)
+	}
+}
+
+func TestArticleList(t *testing.T) {
+	l, err := bmecat12.ReadArticleList(strings.NewReader("# Allowed articles\n1000\n\nABC-*\n*-X?\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, l.Len(); want != have {
+		t.Fatalf("want Len = %d, have %d", want, have)
+	}
+	tests := []struct {
+		ID    string
+		Match bool
+	}{
+		{"1000", true},
+		{"10000", false},
+		{"ABC-1", true},
+		{"ABD-1", false},
+		{"FOO-XL", true},
+		{"FOO-XXL", false},
+		{"", false},
+	}
+	for i, tt := range tests {
+		if want, have := tt.Match, l.Match(tt.ID); want != have {
+			t.Errorf("#%d: want Match(%q) = %v, have %v", i, tt.ID, want, have)
+		}
+	}
+
+	a := &bmecat12.Article{SupplierAID: "42", Details: &bmecat12.ArticleDetails{EAN: "ABC-4711"}}
+	if !l.MatchArticle(a) {
+		t.Fatal("expected article to match by EAN")
+	}
+}
+
+func TestPipelinePreservesProlog(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_prices.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Replace the DOCTYPE by a comment and a stylesheet
+	doctype := `<!DOCTYPE BMECAT SYSTEM "bmecat_update_prices.dtd">`
+	prolog := "<!-- Exported by ERP -->\n<?xml-stylesheet type=\"text/xsl\" href=\"catalog.xsl\"?>"
+	input := strings.Replace(string(data), doctype, prolog, 1)
+
+	h := &prologHandler{}
+	if err := bmecat12.NewReader(strings.NewReader(input)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if h.prolog == nil {
+		t.Fatal("want Prolog, have nil")
+	}
+	if want, have := "", h.prolog.Doctype(); want != have {
+		t.Fatalf("want Doctype = %q, have %q", want, have)
+	}
+	if want, have := 1, len(h.prolog.ProcInsts()); want != have {
+		t.Fatalf("want %d processing instructions, have %d", want, have)
+	}
+
+	var buf bytes.Buffer
+	r := bmecat12.NewReader(strings.NewReader(input))
+	w := bmecat12.NewWriter(&buf)
+	if err := bmecat12.NewPipeline(r, w).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	want := strings.Replace(string(data), doctype, doctype+"\n"+prolog, 1)
+	if want, have := strings.TrimSpace(want), strings.TrimSpace(buf.String()); want != have {
+		diffStrings(t, want, have)
+	}
+}
+
+type prologHandler struct {
+	prolog *bmecat12.Prolog
+}
+
+func (h *prologHandler) HandleProlog(p *bmecat12.Prolog) error {
+	h.prolog = p
+	return nil
+}
@@ -0,0 +1,130 @@
+package bmecat12
+
+import (
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+
+	"github.com/olivere/bmecat/internal"
+)
+
+// Preview is a quick look at a catalog, as returned by PreviewArticles.
+type Preview struct {
+	// Header of the catalog. The counts, e.g. NumberOfArticles, are not
+	// set, as they require reading the whole file.
+	Header *Header
+	// Transaction of the catalog.
+	Transaction Transaction
+	// PreviousVersion of the catalog, for updates.
+	PreviousVersion int
+	// Articles are the first articles of the catalog. Their
+	// CatalogGroupIDs are not set, as the mappings follow the articles.
+	Articles []*Article
+	// Complete is true if the end of the catalog has been reached,
+	// i.e. Articles contains all articles of the catalog.
+	Complete bool
+}
+
+// PreviewArticles returns the header and the first n articles of the
+// catalog read from r. Unlike Reader, it reads the file only once and
+// stops after n articles, so it is fast enough to show a preview of
+// an uploaded catalog. Reader options like WithCharsetReader,
+// WithEntityMap, WithSkipSections, WithArticleFilter, WithRawArticles,
+// and WithEclassMapper are applied.
+//
+// Use ctx to limit the time spent. If ctx is done before n articles
+// have been read, PreviewArticles returns the preview so far along
+// with the error of the context.
+func PreviewArticles(ctx context.Context, r io.Reader, n int, options ...ReaderOption) (*Preview, error) {
+	rd := &Reader{charsetReader: internal.AutoCharsetReader}
+	for _, o := range options {
+		o(rd)
+	}
+	// Errors in articles are reported, not skipped
+	rd.continueOnError = nil
+
+	p := &Preview{}
+	dec, capture := rd.newDecoder(r)
+	parseError := func(err error, element, supplierAID string) error {
+		line, column := capture.position()
+		return &ParseError{
+			Element:     element,
+			SupplierAID: supplierAID,
+			Offset:      dec.InputOffset(),
+			Line:        line,
+			Column:      column,
+			Err:         err,
+		}
+	}
+	var txName string
+	for len(p.Articles) < n {
+		select {
+		case <-ctx.Done():
+			return p, ctx.Err()
+		default:
+		}
+		if rd.rawArticles {
+			capture.reset()
+		}
+		t, err := dec.Token()
+		if err == io.EOF {
+			p.Complete = true
+			break
+		}
+		if err != nil {
+			return p, parseError(err, "", "")
+		}
+		se, ok := t.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		switch se.Name.Local {
+		case "HEADER":
+			if p.Header != nil {
+				return p, parseError(ErrDuplicateHeader, "HEADER", "")
+			}
+			var hdr Header
+			if err := dec.DecodeElement(&hdr, &se); err != nil {
+				return p, parseError(err, "HEADER", "")
+			}
+			p.Header = &hdr
+		case "T_NEW_CATALOG", "T_UPDATE_PRODUCTS", "T_UPDATE_PRICES":
+			if txName != "" {
+				return p, parseError(fmt.Errorf("%w: %s after %s", ErrMultipleTransactions, se.Name.Local, txName), se.Name.Local, "")
+			}
+			txName = se.Name.Local
+			p.Transaction, p.PreviousVersion = transactionFromElement(se)
+			if p.Header != nil {
+				p.Header.Transaction = p.Transaction
+				p.Header.PreviousVersion = p.PreviousVersion
+			}
+		case "FEATURE_SYSTEM", "CLASSIFICATION_SYSTEM", "CATALOG_GROUP_SYSTEM":
+			if err := dec.Skip(); err != nil {
+				return p, parseError(err, se.Name.Local, "")
+			}
+		case "ARTICLE":
+			a := &Article{}
+			if err := decodeArticle(dec, &se, a, rd.skipSections, rd.fastDecoder); err != nil {
+				return p, parseError(err, "ARTICLE", a.SupplierAID)
+			}
+			if rd.rawArticles {
+				a.RawXML = capture.bytes()
+			}
+			if !rd.acceptArticle(a) {
+				break
+			}
+			if rd.eclassMapper != nil {
+				if err := MapEclassFeatures(a, rd.eclassMapper); err != nil {
+					return p, &HandlerError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Offset: dec.InputOffset(), Err: err}
+				}
+			}
+			p.Articles = append(p.Articles, a)
+		case "ARTICLE_TO_CATALOGGROUP_MAP":
+			// All articles have been read
+			p.Complete = true
+			return p, nil
+		}
+	}
+	return p, nil
+}
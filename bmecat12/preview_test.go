@@ -0,0 +1,71 @@
+package bmecat12_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestPreviewArticles(t *testing.T) {
+	tests := []struct {
+		N        int
+		Articles int
+		Complete bool
+	}{
+		{N: 1, Articles: 1, Complete: false},
+		{N: 10, Articles: 2, Complete: true},
+	}
+	for _, tt := range tests {
+		f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		p, err := bmecat12.PreviewArticles(context.Background(), f, tt.N)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Header == nil || p.Header.Catalog == nil {
+			t.Fatalf("N=%d: want Header, have nil", tt.N)
+		}
+		if want, have := "CAT1", p.Header.Catalog.ID; want != have {
+			t.Errorf("N=%d: want CATALOG_ID=%q, have %q", tt.N, want, have)
+		}
+		if want, have := bmecat12.UpdateProducts, p.Transaction; want != have {
+			t.Errorf("N=%d: want Transaction=%v, have %v", tt.N, want, have)
+		}
+		if want, have := 13, p.PreviousVersion; want != have {
+			t.Errorf("N=%d: want PreviousVersion=%d, have %d", tt.N, want, have)
+		}
+		if want, have := tt.Articles, len(p.Articles); want != have {
+			t.Fatalf("N=%d: want %d articles, have %d", tt.N, want, have)
+		}
+		if want, have := "1000", p.Articles[0].SupplierAID; want != have {
+			t.Errorf("N=%d: want SupplierAID=%q, have %q", tt.N, want, have)
+		}
+		if want, have := tt.Complete, p.Complete; want != have {
+			t.Errorf("N=%d: want Complete=%v, have %v", tt.N, want, have)
+		}
+	}
+}
+
+func TestPreviewArticlesCanceled(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	p, err := bmecat12.PreviewArticles(ctx, f, 10)
+	if err != context.Canceled {
+		t.Fatalf("want error %v, have %v", context.Canceled, err)
+	}
+	if p == nil {
+		t.Fatal("want partial preview, have nil")
+	}
+}
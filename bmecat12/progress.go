@@ -0,0 +1,117 @@
+package bmecat12
+
+import (
+	"io"
+	"os"
+	"time"
+)
+
+// ReaderProgressInfo is a progress report of the Reader, see
+// WithReaderProgressInfo.
+type ReaderProgressInfo struct {
+	// Pass is the current pass of the Reader, 1 or 2.
+	Pass int
+	// Offset is the current byte offset into the XML file.
+	Offset int64
+	// Size of the XML file in bytes, or 0 if it is unknown.
+	Size int64
+	// Percent of the current pass that is complete, from 0 to 100.
+	// It is 0 if the size of the file is unknown.
+	Percent float64
+	// Articles is the number of articles found so far in the 1st pass,
+	// and the number of articles passed to the handler in the 2nd pass.
+	Articles int
+	// TotalArticles is the number of articles in the file, as found in
+	// the 1st pass. It is 0 in the 1st pass and when resuming from a
+	// Checkpoint.
+	TotalArticles int
+	// Elapsed is the time spent in the current pass.
+	Elapsed time.Duration
+	// ETA is the estimated time remaining for the current pass, or 0
+	// if it cannot be estimated yet.
+	ETA time.Duration
+}
+
+// ReaderProgressInfoFunc is the signature of the callback passed to
+// WithReaderProgressInfo.
+type ReaderProgressInfoFunc func(ReaderProgressInfo)
+
+// WithReaderProgressInfo specifies a callback that is invoked periodically
+// to report progress as the BMEcat file is read. Unlike WithReaderProgress,
+// it reports the size of the file, the percentage complete, the number of
+// articles, and an estimate of the time remaining. The size is known if
+// the underlying reader is an *os.File, has a Size method like
+// *bytes.Reader, or can seek to its end.
+func WithReaderProgressInfo(f ReaderProgressInfoFunc) ReaderOption {
+	return func(r *Reader) {
+		r.progressFunc = f
+	}
+}
+
+// progressTracker turns pass and offset into a ReaderProgressInfo.
+type progressTracker struct {
+	f             ReaderProgressInfoFunc
+	size          int64
+	totalArticles int
+
+	pass        int
+	start       time.Time
+	startOffset int64
+}
+
+// newProgressTracker returns a progressTracker for the input rs.
+func newProgressTracker(f ReaderProgressInfoFunc, rs io.ReadSeeker) *progressTracker {
+	return &progressTracker{f: f, size: sourceSize(rs)}
+}
+
+// report passes the progress to the callback. The first report of a pass
+// starts the clock for it.
+func (t *progressTracker) report(pass int, offset int64, articles int) {
+	if pass != t.pass {
+		t.pass = pass
+		t.start = time.Now()
+		t.startOffset = offset
+	}
+	p := ReaderProgressInfo{
+		Pass:     pass,
+		Offset:   offset,
+		Size:     t.size,
+		Articles: articles,
+		Elapsed:  time.Since(t.start),
+	}
+	if pass > 1 {
+		p.TotalArticles = t.totalArticles
+	}
+	if t.size > 0 {
+		p.Percent = 100 * float64(offset) / float64(t.size)
+		if p.Percent > 100 {
+			p.Percent = 100
+		}
+		if done := offset - t.startOffset; done > 0 && offset < t.size {
+			p.ETA = time.Duration(float64(p.Elapsed) * float64(t.size-offset) / float64(done))
+		}
+	}
+	t.f(p)
+}
+
+// sourceSize returns the size of rs in bytes, or 0 if it is unknown.
+func sourceSize(rs io.ReadSeeker) int64 {
+	switch v := rs.(type) {
+	case *os.File:
+		if fi, err := v.Stat(); err == nil && fi.Mode().IsRegular() {
+			return fi.Size()
+		}
+		return 0
+	case interface{ Size() int64 }:
+		return v.Size()
+	}
+	cur, err := rs.Seek(0, io.SeekCurrent)
+	if err != nil {
+		return 0
+	}
+	end, err := rs.Seek(0, io.SeekEnd)
+	if _, serr := rs.Seek(cur, io.SeekStart); serr != nil || err != nil {
+		return 0
+	}
+	return end
+}
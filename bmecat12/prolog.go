@@ -0,0 +1,82 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Prolog contains the tokens of a BMEcat document before the BMECAT
+// element, e.g. the XML declaration, the DOCTYPE, comments, or processing
+// instructions like xml-stylesheet.
+type Prolog struct {
+	// Tokens are the xml.ProcInst, xml.Directive, and xml.Comment tokens
+	// in the order found in the document. Whitespace is not recorded.
+	Tokens []xml.Token
+}
+
+// Doctype returns the DOCTYPE declaration without the surrounding "<!"
+// and ">", e.g. `DOCTYPE BMECAT SYSTEM "bmecat_new_catalog.dtd"`. It
+// returns an empty string if the document has no DOCTYPE.
+func (p *Prolog) Doctype() string {
+	if p == nil {
+		return ""
+	}
+	for _, t := range p.Tokens {
+		if d, ok := t.(xml.Directive); ok && strings.HasPrefix(string(d), "DOCTYPE") {
+			return string(d)
+		}
+	}
+	return ""
+}
+
+// ProcInsts returns the processing instructions of the prolog, excluding
+// the XML declaration.
+func (p *Prolog) ProcInsts() []xml.ProcInst {
+	if p == nil {
+		return nil
+	}
+	var pis []xml.ProcInst
+	for _, t := range p.Tokens {
+		if pi, ok := t.(xml.ProcInst); ok && pi.Target != "xml" {
+			pis = append(pis, pi)
+		}
+	}
+	return pis
+}
+
+// add records a copy of t if it is part of the prolog.
+func (p *Prolog) add(t xml.Token) {
+	switch t := t.(type) {
+	case xml.ProcInst, xml.Directive, xml.Comment:
+		p.Tokens = append(p.Tokens, xml.CopyToken(t))
+	}
+}
+
+// writeTo writes the prolog to w, except for the XML declaration. The
+// Writer always writes its own XML declaration as the output is UTF-8.
+// The DOCTYPE is only written if doctype is true.
+func (p *Prolog) writeTo(w io.Writer, doctype bool) error {
+	for _, t := range p.Tokens {
+		var err error
+		switch t := t.(type) {
+		case xml.ProcInst:
+			if t.Target == "xml" {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "<?%s %s?>\n", t.Target, t.Inst)
+		case xml.Directive:
+			if !doctype && strings.HasPrefix(string(t), "DOCTYPE") {
+				continue
+			}
+			_, err = fmt.Fprintf(w, "<!%s>\n", t)
+		case xml.Comment:
+			_, err = fmt.Fprintf(w, "<!--%s-->\n", t)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,105 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"strings"
+	"time"
+
+	"github.com/pkg/errors"
+)
+
+// ProvenanceUDXField is the name of the UDX field that records the
+// provenance of a catalog, i.e. the UDX.PROVENANCE element.
+const ProvenanceUDXField = "PROVENANCE"
+
+// Provenance describes one hop in the exchange of a catalog, e.g. the
+// export from a PIM system. Each hop is recorded as a HOP element in the
+// UDX.PROVENANCE field of the header and/or the articles, so a catalog
+// passed through several systems keeps the history of all of them.
+type Provenance struct {
+	XMLName xml.Name `xml:"HOP"`
+
+	// SourceSystem is the system that emitted the catalog, e.g. "pim".
+	SourceSystem string `xml:"SOURCE_SYSTEM,omitempty"`
+	// JobID identifies the export job, e.g. a run number.
+	JobID string `xml:"JOB_ID,omitempty"`
+	// Timestamp is the time of the export.
+	Timestamp time.Time `xml:"TIMESTAMP"`
+	// Profile is the name of the export profile, e.g. "shop-de".
+	Profile string `xml:"PROFILE,omitempty"`
+}
+
+// ProvenanceScope specifies where the Writer records the provenance.
+type ProvenanceScope int
+
+const (
+	// ProvenanceHeader records the provenance in the HEADER.
+	ProvenanceHeader ProvenanceScope = 1 << iota
+	// ProvenanceArticles records the provenance in every ARTICLE.
+	ProvenanceArticles
+)
+
+// WithProvenance stamps the catalog written with p, in the HEADER and/or
+// the articles as specified by scope. If p.Timestamp is zero, the time
+// the Writer starts is used. Existing hops are kept; p is appended.
+func WithProvenance(p Provenance, scope ProvenanceScope) WriterOption {
+	return func(w *Writer) {
+		w.provenance = &p
+		w.provenanceScope = scope
+	}
+}
+
+// StampProvenance returns a copy of udx with p appended to its
+// UDX.PROVENANCE field. udx may be nil. The fields of udx are not
+// modified.
+func StampProvenance(udx *UserDefinedExtensions, p *Provenance) (*UserDefinedExtensions, error) {
+	hop, err := xml.Marshal(p)
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat: unable to encode provenance")
+	}
+	stamped := &UserDefinedExtensions{}
+	var hops string
+	if udx != nil {
+		for _, field := range udx.Fields {
+			if field.Name == ProvenanceUDXField {
+				hops = provenanceInnerXML(field)
+				continue
+			}
+			stamped.Fields = append(stamped.Fields, field)
+		}
+	}
+	stamped.Fields.AddRaw(ProvenanceUDXField, hops+string(hop))
+	return stamped, nil
+}
+
+// ReadProvenance returns the hops recorded in the UDX.PROVENANCE field
+// of udx, oldest first. It returns nil if udx has no such field.
+func ReadProvenance(udx *UserDefinedExtensions) ([]*Provenance, error) {
+	if udx == nil {
+		return nil, nil
+	}
+	var hops []*Provenance
+	for _, field := range udx.Fields {
+		if field.Name != ProvenanceUDXField {
+			continue
+		}
+		dec := xml.NewDecoder(strings.NewReader("<PROVENANCE>" + provenanceInnerXML(field) + "</PROVENANCE>"))
+		var v struct {
+			Hops []*Provenance `xml:"HOP"`
+		}
+		if err := dec.Decode(&v); err != nil {
+			return nil, errors.Wrap(err, "bmecat: unable to decode provenance")
+		}
+		hops = append(hops, v.Hops...)
+	}
+	return hops, nil
+}
+
+// provenanceInnerXML returns the HOP elements of the field, both for
+// fields read from a file and for fields added with AddRaw.
+func provenanceInnerXML(field *UserDefinedExtensionField) string {
+	if field.Raw {
+		return field.Value
+	}
+	return strings.TrimSpace(field.InnerXML)
+}
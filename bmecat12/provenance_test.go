@@ -0,0 +1,98 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestProvenanceAcrossHops(t *testing.T) {
+	ts := time.Date(2021, 4, 1, 12, 0, 0, 0, time.UTC)
+	article := &bmecat12.Article{
+		SupplierAID: "1000",
+		UDX:         &bmecat12.UserDefinedExtensions{},
+	}
+	article.UDX.Fields.Add("SYSTEM.CUSTOM_FIELD1", "A")
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: []*bmecat12.Article{article},
+	}
+
+	headerFields := len(testHeader.UDX.Fields)
+
+	// 1st hop: PIM export
+	var first bytes.Buffer
+	w := bmecat12.NewWriter(&first, bmecat12.WithProvenance(bmecat12.Provenance{
+		SourceSystem: "pim",
+		JobID:        "42",
+		Timestamp:    ts,
+		Profile:      "full",
+	}, bmecat12.ProvenanceHeader|bmecat12.ProvenanceArticles))
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if len(testHeader.UDX.Fields) != headerFields || len(article.UDX.Fields) != 1 {
+		t.Fatal("want header and article of the caller unchanged")
+	}
+
+	// 2nd hop: copy for a shop, stamping the header only
+	var second bytes.Buffer
+	r := bmecat12.NewReader(bytes.NewReader(first.Bytes()))
+	w = bmecat12.NewWriter(&second, bmecat12.WithProvenance(bmecat12.Provenance{
+		SourceSystem: "bmecat",
+		Profile:      "shop-de",
+	}, bmecat12.ProvenanceHeader))
+	if err := bmecat12.NewPipeline(r, w).Do(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(second.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+
+	hops, err := bmecat12.ReadProvenance(h.header.UDX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(hops); want != have {
+		t.Fatalf("want %d hops in HEADER, have %d", want, have)
+	}
+	if want, have := headerFields+1, len(h.header.UDX.Fields); want != have {
+		t.Errorf("want %d UDX fields in HEADER, have %d", want, have)
+	}
+	if want, have := "pim", hops[0].SourceSystem; want != have {
+		t.Errorf("want SourceSystem=%q, have %q", want, have)
+	}
+	if want, have := "42", hops[0].JobID; want != have {
+		t.Errorf("want JobID=%q, have %q", want, have)
+	}
+	if !ts.Equal(hops[0].Timestamp) {
+		t.Errorf("want Timestamp=%v, have %v", ts, hops[0].Timestamp)
+	}
+	if want, have := "shop-de", hops[1].Profile; want != have {
+		t.Errorf("want Profile=%q, have %q", want, have)
+	}
+	if hops[1].Timestamp.IsZero() {
+		t.Error("want Timestamp to be set")
+	}
+
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	hops, err = bmecat12.ReadProvenance(h.articles[0].UDX)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(hops); want != have {
+		t.Fatalf("want %d hops in ARTICLE, have %d", want, have)
+	}
+	if v, _ := h.articles[0].UDX.Fields.Get("SYSTEM.CUSTOM_FIELD1"); v != "A" {
+		t.Errorf("want UDX.SYSTEM.CUSTOM_FIELD1=%q, have %q", "A", v)
+	}
+}
@@ -0,0 +1,275 @@
+package bmecat12
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"unicode/utf8"
+)
+
+// QualityCategory is an aspect of the quality of a catalog.
+type QualityCategory string
+
+const (
+	// QualityValidation rates articles by the number of warnings, see
+	// WarningHandler.
+	QualityValidation QualityCategory = "validation"
+	// QualityMedia rates articles by their MIME elements, preferring images.
+	QualityMedia QualityCategory = "media"
+	// QualityFeatures rates articles by their features with values.
+	QualityFeatures QualityCategory = "features"
+	// QualityDescription rates the short and long descriptions.
+	QualityDescription QualityCategory = "description"
+	// QualityPrice rates the plausibility of the prices, e.g. positive
+	// amounts and tax rates.
+	QualityPrice QualityCategory = "price"
+)
+
+// QualityCategories are all categories, in the order reported.
+var QualityCategories = []QualityCategory{
+	QualityValidation,
+	QualityMedia,
+	QualityFeatures,
+	QualityDescription,
+	QualityPrice,
+}
+
+// QualityWeights specifies the weight of each category in the total
+// score. Categories without a weight are not included in the total.
+type QualityWeights map[QualityCategory]float64
+
+// DefaultQualityWeights are the weights used by NewQualityScorer by default.
+var DefaultQualityWeights = QualityWeights{
+	QualityValidation:  30,
+	QualityMedia:       20,
+	QualityFeatures:    20,
+	QualityDescription: 15,
+	QualityPrice:       15,
+}
+
+// QualityScore is the result of a QualityScorer.
+type QualityScore struct {
+	// Articles is the number of articles rated. Deleted articles are
+	// not rated.
+	Articles int `json:"articles"`
+	// Score is the weighted total score from 0 to 100.
+	Score float64 `json:"score"`
+	// Categories are the scores by category.
+	Categories []*QualityCategoryScore `json:"categories"`
+}
+
+// QualityCategoryScore is the score of a single category.
+type QualityCategoryScore struct {
+	Category QualityCategory `json:"category"`
+	// Weight of the category in the total score.
+	Weight float64 `json:"weight"`
+	// Score from 0 to 100.
+	Score float64 `json:"score"`
+	// Articles is the number of articles rated in this category, e.g.
+	// only prices are rated in T_UPDATE_PRICES.
+	Articles int `json:"articles"`
+}
+
+// WriteText writes the score as a table.
+func (s *QualityScore) WriteText(w io.Writer) error {
+	for _, c := range s.Categories {
+		if _, err := fmt.Fprintf(w, "%-24s: %5.1f (weight %g, %d articles)\n", c.Category, c.Score, c.Weight, c.Articles); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "%-24s: %5.1f (%d articles)\n", "Score", s.Score, s.Articles)
+	return err
+}
+
+// QualityScorer rates the quality of a catalog, e.g. to rank suppliers
+// during onboarding. It combines the results of several categories, like
+// media completeness or the coverage of features, into a single weighted
+// score. Pass it as a handler to Reader.Do, then call Score.
+type QualityScorer struct {
+	weights  QualityWeights
+	tx       Transaction
+	articles int
+	sums     map[QualityCategory]float64
+	counts   map[QualityCategory]int
+}
+
+// NewQualityScorer creates a new QualityScorer. If weights is nil,
+// DefaultQualityWeights are used.
+func NewQualityScorer(weights QualityWeights) *QualityScorer {
+	if weights == nil {
+		weights = DefaultQualityWeights
+	}
+	return &QualityScorer{
+		weights: weights,
+		sums:    make(map[QualityCategory]float64),
+		counts:  make(map[QualityCategory]int),
+	}
+}
+
+// HandleTransaction implements the TransactionHandler interface.
+func (s *QualityScorer) HandleTransaction(tx Transaction, prevVersion int) error {
+	s.tx = tx
+	return nil
+}
+
+// HandleArticle implements the ArticleHandler interface.
+func (s *QualityScorer) HandleArticle(a *Article) error {
+	if a.Mode == ArticleModeDelete {
+		return nil
+	}
+	s.articles++
+	s.add(QualityValidation, validationQuality(a, s.tx))
+	s.add(QualityPrice, priceQuality(a))
+	if s.tx == UpdatePrices {
+		// Only prices are transferred
+		return nil
+	}
+	s.add(QualityMedia, mediaQuality(a))
+	s.add(QualityFeatures, featureQuality(a))
+	s.add(QualityDescription, descriptionQuality(a))
+	return nil
+}
+
+// add records the score of an article, from 0 to 1.
+func (s *QualityScorer) add(c QualityCategory, score float64) {
+	s.sums[c] += score
+	s.counts[c]++
+}
+
+// Score returns the score of the articles passed so far.
+func (s *QualityScorer) Score() *QualityScore {
+	qs := &QualityScore{Articles: s.articles}
+	var total, weights float64
+	for _, c := range QualityCategories {
+		weight, found := s.weights[c]
+		if !found {
+			continue
+		}
+		cs := &QualityCategoryScore{Category: c, Weight: weight, Articles: s.counts[c]}
+		if cs.Articles > 0 {
+			cs.Score = 100 * s.sums[c] / float64(cs.Articles)
+			total += weight * cs.Score
+			weights += weight
+		}
+		qs.Categories = append(qs.Categories, cs)
+	}
+	if weights > 0 {
+		qs.Score = total / weights
+	}
+	return qs
+}
+
+// validationQuality reduces the score by a quarter per warning.
+func validationQuality(a *Article, tx Transaction) float64 {
+	score := 1 - 0.25*float64(len(articleWarnings(a, tx, 0)))
+	if score < 0 {
+		return 0
+	}
+	return score
+}
+
+// mediaQuality prefers images over other media, e.g. data sheets.
+func mediaQuality(a *Article) float64 {
+	if a.MimeInfo == nil {
+		return 0
+	}
+	var score float64
+	for _, m := range a.MimeInfo.Mimes {
+		if m == nil || strings.TrimSpace(m.Source) == "" {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(m.Type, "image/"),
+			m.Purpose == MimePurposeNormal,
+			m.Purpose == MimePurposeDetail,
+			m.Purpose == MimePurposeThumbnail:
+			return 1
+		default:
+			score = 0.5
+		}
+	}
+	return score
+}
+
+// featureQuality is the share of features with a value, or 0 if the
+// article has no features.
+func featureQuality(a *Article) float64 {
+	var features, withValue int
+	for _, af := range a.Features {
+		if af == nil {
+			continue
+		}
+		for _, f := range af.Features {
+			if f == nil {
+				continue
+			}
+			features++
+			if len(featureValues(f)) > 0 {
+				withValue++
+			}
+		}
+	}
+	if features == 0 {
+		return 0
+	}
+	return float64(withValue) / float64(features)
+}
+
+// descriptionQuality checks that there is a concise short description
+// and a long description that adds to it.
+func descriptionQuality(a *Article) float64 {
+	d := a.Details
+	if d == nil {
+		return 0
+	}
+	short := strings.TrimSpace(d.DescriptionShort)
+	long := strings.TrimSpace(d.DescriptionLong)
+	checks := []bool{
+		short != "",
+		short != "" && utf8.RuneCountInString(short) <= 80,
+		long != "",
+		utf8.RuneCountInString(long) >= 100,
+		long != "" && long != short,
+	}
+	var passed int
+	for _, ok := range checks {
+		if ok {
+			passed++
+		}
+	}
+	return float64(passed) / float64(len(checks))
+}
+
+// priceQuality is the share of plausible prices, or 0 if the article has
+// no prices.
+func priceQuality(a *Article) float64 {
+	var prices, plausible int
+	for _, pd := range a.PriceDetails {
+		if pd == nil {
+			continue
+		}
+		amounts := make(map[string]Decimal)
+		for _, p := range pd.Prices {
+			if p == nil {
+				continue
+			}
+			prices++
+			// TAX is a factor, e.g. 0.19 for 19%
+			tax, factor := decimalFloat(p.Tax), decimalFloat(p.Factor)
+			if p.Amount.Sign() > 0 && tax >= 0 && tax < 1 && factor >= 0 {
+				plausible++
+			}
+			amounts[p.Type] = p.Amount
+		}
+		// The net list price must not exceed the gross list price
+		net, hasNet := amounts[ArticlePriceTypeNetList]
+		gross, hasGross := amounts[ArticlePriceTypeGrosList]
+		if hasNet && hasGross && net.Cmp(gross) > 0 && plausible > 0 {
+			plausible--
+		}
+	}
+	if prices == 0 {
+		return 0
+	}
+	return float64(plausible) / float64(prices)
+}
@@ -0,0 +1,107 @@
+package bmecat12_test
+
+import (
+	"context"
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestQualityScorer(t *testing.T) {
+	good := &bmecat12.Article{
+		SupplierAID: "1000",
+		Details: &bmecat12.ArticleDetails{
+			DescriptionShort: "Screw",
+			DescriptionLong:  strings.Repeat("A very good screw. ", 10),
+		},
+		OrderDetails: &bmecat12.ArticleOrderDetails{OrderUnit: "C62"},
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{Prices: []*bmecat12.ArticlePrice{
+				{Type: "net_list", Amount: bmecat12.MustParseDecimal("1.5"), Tax: decimalPtr("0.19")},
+				{Type: "gros_list", Amount: bmecat12.MustParseDecimal("1.8"), Tax: decimalPtr("0.19")},
+			}},
+		},
+		Features: []*bmecat12.ArticleFeatures{
+			{Features: []*bmecat12.Feature{{Name: "Length", Values: []string{"10"}}}},
+		},
+		MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+			{Type: "image/jpeg", Source: "1000.jpg", Purpose: bmecat12.MimePurposeNormal},
+		}},
+	}
+	poor := &bmecat12.Article{
+		SupplierAID: "2000",
+		Details:     &bmecat12.ArticleDetails{},
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{Prices: []*bmecat12.ArticlePrice{
+				{Type: "net_list", Amount: bmecat12.MustParseDecimal("2")},
+				{Type: "gros_list", Amount: bmecat12.MustParseDecimal("1"), Tax: decimalPtr("19")},
+			}},
+		},
+	}
+
+	s := bmecat12.NewQualityScorer(nil)
+	if err := s.HandleTransaction(bmecat12.NewCatalog, 0); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range []*bmecat12.Article{good, poor} {
+		if err := s.HandleArticle(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	score := s.Score()
+	if want, have := 2, score.Articles; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	want := map[bmecat12.QualityCategory]float64{
+		bmecat12.QualityValidation:  87.5, // 2nd article: empty DESCRIPTION_SHORT
+		bmecat12.QualityMedia:       50,
+		bmecat12.QualityFeatures:    50,
+		bmecat12.QualityDescription: 50,
+		bmecat12.QualityPrice:       50, // 2nd article: net > gross and TAX=19
+	}
+	var total float64
+	for _, c := range score.Categories {
+		if math.Abs(want[c.Category]-c.Score) > 0.01 {
+			t.Errorf("%s: want %.2f, have %.2f", c.Category, want[c.Category], c.Score)
+		}
+		total += bmecat12.DefaultQualityWeights[c.Category] * want[c.Category]
+	}
+	if want, have := total/100, score.Score; math.Abs(want-have) > 0.01 {
+		t.Fatalf("want total score %.2f, have %.2f", want, have)
+	}
+}
+
+func TestQualityScorerUpdatePrices(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER><CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG></HEADER>
+  <T_UPDATE_PRICES prev_version="1">
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>1.5</PRICE_AMOUNT></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRICES>
+</BMECAT>`
+	s := bmecat12.NewQualityScorer(nil)
+	if err := bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), s); err != nil {
+		t.Fatal(err)
+	}
+	score := s.Score()
+	for _, c := range score.Categories {
+		switch c.Category {
+		case bmecat12.QualityValidation, bmecat12.QualityPrice:
+			if want, have := 1, c.Articles; want != have {
+				t.Errorf("%s: want %d articles, have %d", c.Category, want, have)
+			}
+		default:
+			if want, have := 0, c.Articles; want != have {
+				t.Errorf("%s: want %d articles, have %d", c.Category, want, have)
+			}
+		}
+	}
+	if want, have := 100.0, score.Score; want != have {
+		t.Fatalf("want score %.2f, have %.2f", want, have)
+	}
+}
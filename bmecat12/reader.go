@@ -4,10 +4,10 @@ import (
 	"context"
 	"encoding/xml"
 	"io"
-	"sync"
+	"strconv"
 	"time"
 
-	"github.com/pkg/errors"
+	"golang.org/x/text/encoding"
 	"golang.org/x/time/rate"
 
 	"github.com/olivere/bmecat/internal"
@@ -25,12 +25,43 @@ type HeaderHandler interface {
 	HandleHeader(*Header) error
 }
 
+// DocumentHandler, if implemented by a handler, is called when the
+// Reader passed the opening BMECAT element. It gets the prolog of the
+// document, i.e. everything before the BMECAT element like the DOCTYPE,
+// comments, or processing instructions.
+type DocumentHandler interface {
+	HandleProlog(*Prolog) error
+}
+
+// TransactionHandler, if implemented by a handler, is called when the
+// Reader passed the opening element of the transaction, i.e.
+// T_NEW_CATALOG, T_UPDATE_PRODUCTS, or T_UPDATE_PRICES. The previous
+// version is only set for updates.
+type TransactionHandler interface {
+	HandleTransaction(tx Transaction, prevVersion int) error
+}
+
+// FeatureSystemHandler, if implemented by a handler, is called whenever
+// the Reader passed a FEATURE_SYSTEM element.
+type FeatureSystemHandler interface {
+	HandleFeatureSystem(*FeatureSystem) error
+}
+
 // CatalogGroupHandler, if implemented by a handler, is called whenever
 // the Reader passed a CATALOG_STRUCTURE element with a category.
 type CatalogGroupHandler interface {
 	HandleCatalogGroup(*CatalogGroup) error
 }
 
+// ClassificationSystemHandler, if implemented by a handler, is called
+// whenever the Reader passed the metadata of a CLASSIFICATION_SYSTEM
+// element, i.e. before any of its CLASSIFICATION_GROUP elements are
+// passed to the ClassificationGroupHandler. The Groups of the
+// ClassificationSystem are always empty.
+type ClassificationSystemHandler interface {
+	HandleClassificationSystem(*ClassificationSystem) error
+}
+
 // ClassificationGroupHandler, if implemented by a handler, is called whenever
 // the Reader passed a CLASSIFICATION_GROUP element with a category.
 type ClassificationGroupHandler interface {
@@ -43,12 +74,37 @@ type ArticleHandler interface {
 	HandleArticle(*Article) error
 }
 
+// SkippedArticleHandler, if implemented by a handler, is called whenever
+// the Reader skipped an ARTICLE element because it exceeded the size
+// limit set with WithMaxArticleSize. The size is the number of bytes
+// of the serialized ARTICLE element.
+type SkippedArticleHandler interface {
+	HandleSkippedArticle(supplierAID string, size int64) error
+}
+
+// ArticleOffsetHandler, if implemented by a handler, is called for every
+// article right before it is passed to the ArticleHandler, with the byte
+// offsets of the ARTICLE element in the input: start is the offset of
+// its start tag, end the offset just after its end tag. The offsets
+// refer to the input after conversion to UTF-8, i.e. they are offsets
+// into the file if it is encoded in UTF-8.
+type ArticleOffsetHandler interface {
+	HandleArticleOffset(supplierAID string, start, end int64) error
+}
+
 // CompletionHandler, if implemented by a handler, is called once when
 // the Reader is done parsing the BMEcat document.
 type CompletionHandler interface {
 	HandleComplete()
 }
 
+// CompletionStatsHandler is like CompletionHandler, but gets the final
+// statistics of the run, e.g. for reporting. If a handler implements
+// both, only HandleCompleteStats is called.
+type CompletionStatsHandler interface {
+	HandleCompleteStats(*CompletionStats)
+}
+
 // CharsetReaderFunc typedef's the CharsetReader from the Decoder in encoding/xml.
 type CharsetReaderFunc func(charset string, input io.Reader) (io.Reader, error)
 
@@ -57,9 +113,57 @@ type Reader struct {
 	r             io.ReadSeeker
 	charsetReader CharsetReaderFunc
 	progress      ReaderProgress
-
-	artToCatalogGroupMu sync.Mutex
-	artToCatalogGroup   map[string][]string
+	// progressFunc reports enriched progress, see WithReaderProgressInfo.
+	progressFunc ReaderProgressInfoFunc
+	// entities maps non-standard entity names to their replacement text.
+	entities map[string]string
+	// continueOnError is called for articles that cannot be decoded.
+	continueOnError ContinueOnErrorFunc
+	// eclassMapper rewrites the ECLASS features of articles.
+	eclassMapper EclassMapper
+	// maxArticleSize is the maximum size of an ARTICLE element in bytes.
+	maxArticleSize int64
+	// maxArticles is the maximum number of articles passed to the handler.
+	maxArticles int
+	// skipSections are the sub-elements of ARTICLE that are not decoded.
+	skipSections SkipSection
+	// fastDecoder decodes articles without reflection, see WithFastDecoder.
+	fastDecoder bool
+	// concurrency is the number of goroutines that decode articles.
+	concurrency int
+	// unordered passes articles to the handler as they are decoded.
+	unordered bool
+	// pooled takes articles from a pool, see WithArticlePool.
+	pooled bool
+	// rawArticles attaches the raw XML to the articles.
+	rawArticles bool
+	// resume is the checkpoint to resume from, see WithCheckpoint.
+	resume *Checkpoint
+	// checkpoint returns the current checkpoint while in Do.
+	checkpoint func() *Checkpoint
+	// part is set for the readers of a MultiReader.
+	part *multiPart
+	// stats are the statistics of the last call to Do.
+	stats ReaderStats
+	// instr receives measurements, see WithReaderInstrumentation.
+	instr Instrumentation
+	// logger receives log events, see WithLogger.
+	logger logger
+	// compression of the input, see WithCompression.
+	compression Compression
+	// forcedCharset overrides the encoding of the XML declaration.
+	forcedCharset string
+	// limits rejects oversized documents, see WithLimits.
+	limits ReaderLimits
+	// strictEnvelope verifies the root element, see WithStrictEnvelope.
+	strictEnvelope bool
+	// noCatalogGroupMapping skips ARTICLE_TO_CATALOGGROUP_MAP, see
+	// WithoutCatalogGroupMapping.
+	noCatalogGroupMapping bool
+	// articleFilters decide which articles are passed to the handler.
+	articleFilters []func(*Article) bool
+	// mappings keeps the catalog group mappings, see WithMapStore.
+	mappings MapStore
 }
 
 // NewReader creates a new Reader. It expects an underlying io.ReadSeeker
@@ -67,9 +171,9 @@ type Reader struct {
 // options like WithProgress.
 func NewReader(r io.ReadSeeker, options ...ReaderOption) *Reader {
 	reader := &Reader{
-		r:                 r,
-		charsetReader:     internal.AutoCharsetReader,
-		artToCatalogGroup: make(map[string][]string),
+		r:             r,
+		charsetReader: internal.AutoCharsetReader,
+		mappings:      newMemoryMapStore(),
 	}
 	for _, o := range options {
 		o(reader)
@@ -87,6 +191,28 @@ func WithCharsetReader(f CharsetReaderFunc) ReaderOption {
 	}
 }
 
+// WithForcedCharset decodes the input with the given charset, e.g.
+// "iso-8859-1", regardless of the encoding in the XML declaration. Use
+// it for files that declare UTF-8 but are actually encoded otherwise,
+// which would produce mojibake or fail otherwise. The offsets passed to
+// handlers and in errors refer to the input converted to UTF-8.
+func WithForcedCharset(charset string) ReaderOption {
+	return func(r *Reader) {
+		r.forcedCharset = charset
+	}
+}
+
+// RegisterCharset makes the Reader decode files declaring the encoding
+// with the given name, e.g. "x-mac-cyrillic", with enc. The name is
+// case-insensitive. Besides UTF-8 and UTF-16, the Reader decodes the ISO
+// 8859 family, the windows-1250, -1251, -1252, -1254, and -1257 code
+// pages, KOI8-R, macintosh, and the IBM code pages 437 and 866 out of the
+// box. RegisterCharset has no effect on readers created with
+// WithCharsetReader.
+func RegisterCharset(name string, enc encoding.Encoding) {
+	internal.RegisterCharset(name, enc)
+}
+
 // ReaderProgress is the signature for reporting progress.
 // When set via WithReaderProgress, it returns the current pass of the
 // parser (currently 1 or 2) and the current byte offset into the XML file.
@@ -100,6 +226,57 @@ func WithReaderProgress(f ReaderProgress) ReaderOption {
 	}
 }
 
+// WithMaxArticleSize sets the maximum size of a serialized ARTICLE element
+// in bytes. Larger articles are not decoded, but skipped and reported to
+// the SkippedArticleHandler, if any. This protects against pathological
+// articles, e.g. with hundreds of thousands of feature values. The
+// default of 0 means no limit.
+func WithMaxArticleSize(n int64) ReaderOption {
+	return func(r *Reader) {
+		r.maxArticleSize = n
+	}
+}
+
+// WithMaxArticles stops reading after n articles have been passed to the
+// ArticleHandler, e.g. to show a preview of a catalog. The Reader returns
+// without an error and still invokes the CompletionHandler. Notice that
+// the first pass still reads the whole file, as the header counts and
+// catalog group mappings depend on it. The default of 0 means no limit.
+func WithMaxArticles(n int) ReaderOption {
+	return func(r *Reader) {
+		r.maxArticles = n
+	}
+}
+
+// WithArticleFilter passes only the articles for which f returns true
+// to the handler, e.g. to select articles by SUPPLIER_AID prefix or
+// catalog group. The article passed to f has its CatalogGroupIDs set.
+// Articles that are filtered out are neither checked for warnings nor
+// passed to an EclassMapper. If the option is given more than once, an
+// article must pass all filters.
+func WithArticleFilter(f func(*Article) bool) ReaderOption {
+	return func(r *Reader) {
+		r.articleFilters = append(r.articleFilters, f)
+	}
+}
+
+// WithoutCatalogGroupMapping skips the ARTICLE_TO_CATALOGGROUP_MAP
+// elements in the 1st pass instead of keeping the mappings in memory,
+// which saves a lot of memory for huge catalogs if the caller doesn't
+// need them. The CatalogGroupIDs of the articles are not set, and the
+// NumberOfArticleToCatalogGroupMaps of the header is 0.
+func WithoutCatalogGroupMapping() ReaderOption {
+	return func(r *Reader) {
+		r.noCatalogGroupMapping = true
+	}
+}
+
+// skippedArticle is an ARTICLE element that exceeds the size limit.
+type skippedArticle struct {
+	supplierAID string
+	size        int64
+}
+
 // Do reads the BMEcat file.
 //
 // You must pass a context, which can be canceled to stop reading.
@@ -108,186 +285,103 @@ func WithReaderProgress(f ReaderProgress) ReaderOption {
 // If the articles channel is closed, Do will write the rest of
 // the BMEcat file, and then return.
 func (r *Reader) Do(ctx context.Context, handler interface{}) error {
+	doStarted := time.Now()
 	_, err := r.r.Seek(0, io.SeekStart)
 	if err != nil {
 		return err
 	}
 
-	var h struct {
-		Header       HeaderHandler
-		CatalogGroup CatalogGroupHandler
-		ClassifGroup ClassificationGroupHandler
-		Article      ArticleHandler
-		Complete     CompletionHandler
-	}
-	if f, ok := handler.(HeaderHandler); ok {
-		h.Header = f
+	s := &readState{
+		r:       r,
+		ctx:     ctx,
+		h:       newReadHandlers(ctx, handler),
+		log:     logOrNop(r.logger),
+		lenient: r.continueOnError != nil,
+		record:  r.recordRaw(),
 	}
-	if f, ok := handler.(CatalogGroupHandler); ok {
-		h.CatalogGroup = f
+	if r.maxArticleSize > 0 {
+		s.skipped = make(map[int]skippedArticle)
 	}
-	if f, ok := handler.(ClassificationGroupHandler); ok {
-		h.ClassifGroup = f
-	}
-	if f, ok := handler.(ArticleHandler); ok {
-		h.Article = f
-	}
-	if f, ok := handler.(CompletionHandler); ok {
-		h.Complete = f
+	src, charsetReader := r.r, r.charsetReader
+	defer func() { r.r, r.charsetReader = src, charsetReader }()
+	if err := s.openInput(); err != nil {
+		return err
 	}
 
-	var numArticles int
-	var numCatalogGroups int
-	var numClassifGroups int
-	var rl *rate.Limiter
-
 	// 1st pass
-	if r.progress != nil {
-		r.progress(1, 0)
+	if !r.part.restored() {
+		r.stats = ReaderStats{}
+	}
+	if r.resume == nil && !r.part.restored() {
+		s.log.Info("bmecat: 1st pass started")
+	}
+	if r.progress != nil || s.tracker != nil {
+		s.report(1, 0, 0)
 		// Specify a rate limiter to only report progress once a second
-		rl = rate.NewLimiter(rate.Every(1*time.Second), 1)
+		s.rl = rate.NewLimiter(rate.Every(1*time.Second), 1)
 	}
-	dec := xml.NewDecoder(r.r)
-	dec.CharsetReader = r.charsetReader
-	var stop bool
-	for !stop {
-		t, err := dec.Token()
-		if err == io.EOF {
-			stop = true
-			break
-		}
-		if err != nil {
+	s.dec, s.capture = r.newDecoder(r.r)
+	switch {
+	case r.resume == nil && !r.part.restored():
+		done, err := s.firstPass()
+		if err != nil || done {
 			return err
 		}
-		switch se := t.(type) {
-		case xml.StartElement:
-			switch se.Name.Local {
-			case "ARTICLE":
-				numArticles++
-			case "CATALOG_STRUCTURE":
-				numCatalogGroups++
-			case "CLASSIFICATION_GROUP":
-				numClassifGroups++
-			case "ARTICLE_TO_CATALOGGROUP_MAP":
-				var m ArticleToCatalogGroupMap
-				if err := dec.DecodeElement(&m, &se); err != nil {
-					return errors.Wrapf(err, "bmecat/reader: unable to decode ARTICLE_TO_CATALOGGROUP_MAP around byte offset %d", dec.InputOffset())
-				}
-				r.artToCatalogGroupMu.Lock()
-				if slice, ok := r.artToCatalogGroup[m.ArticleID]; ok {
-					slice = append(slice, m.CatalogGroupID)
-					r.artToCatalogGroup[m.ArticleID] = slice
-				} else {
-					r.artToCatalogGroup[m.ArticleID] = []string{m.CatalogGroupID}
-				}
-				r.artToCatalogGroupMu.Unlock()
-			}
-		}
-		if r.progress != nil && rl.Allow() {
-			r.progress(1, dec.InputOffset())
+	case r.part.restored():
+		if err := s.restorePart(); err != nil {
+			return err
 		}
-		select {
-		default:
-		case <-ctx.Done():
-			return ctx.Err()
+	default:
+		if err := s.restoreCheckpoint(); err != nil {
+			return err
 		}
 	}
 
-	// Seek back to start
-	if _, err := r.r.Seek(0, io.SeekStart); err != nil {
-		return errors.Wrap(err, "bmecat/reader: unable to seek back to start")
-	}
-
 	// 2nd pass
-	if r.progress != nil {
-		r.progress(2, 0)
-	}
-	var lastAID string
-	dec = xml.NewDecoder(r.r)
-	dec.CharsetReader = r.charsetReader
-	stop = false
-	for !stop {
-		t, err := dec.Token()
-		if err == io.EOF {
-			stop = true
-			break
-		}
-		if err != nil {
-			return err
-		}
-		switch se := t.(type) {
-		case xml.StartElement:
-			switch se.Name.Local {
-			case "HEADER":
-				var h Header
-				if err := dec.DecodeElement(&h, &se); err != nil {
-					return errors.Wrapf(err, "bmecat/reader: unable to decode HEADER around byte offset %d", dec.InputOffset())
-				}
-				h.NumberOfArticles = numArticles
-				h.NumberOfCatalogGroups = numCatalogGroups
-				h.NumberOfClassificationGroups = numClassifGroups
-				r.artToCatalogGroupMu.Lock()
-				h.NumberOfArticleToCatalogGroupMaps = len(r.artToCatalogGroup)
-				r.artToCatalogGroupMu.Unlock()
-				if f, ok := handler.(HeaderHandler); ok {
-					if err := f.HandleHeader(&h); err == io.EOF {
-						stop = true
-						break
-					}
-				}
-			case "CATALOG_STRUCTURE":
-				var cg CatalogGroup
-				if err := dec.DecodeElement(&cg, &se); err != nil {
-					return errors.Wrapf(err, "bmecat/reader: unable to decode CATALOG_GROUP around byte offset %d", dec.InputOffset())
-				}
-				if h.CatalogGroup != nil {
-					if err := h.CatalogGroup.HandleCatalogGroup(&cg); err != nil {
-						return errors.Wrapf(err, "bmecat/reader: handler for CATALOG_GROUP %q returned an error around byte offset %d", cg.ID, dec.InputOffset())
-					}
-				}
-			case "CLASSIFICATION_GROUP":
-				var cg ClassificationGroup
-				if err := dec.DecodeElement(&cg, &se); err != nil {
-					return errors.Wrapf(err, "bmecat/reader: unable to decode CLASSIFICATION_GROUP around byte offset %d", dec.InputOffset())
-				}
-				if h.ClassifGroup != nil {
-					if err := h.ClassifGroup.HandleClassificationGroup(&cg); err != nil {
-						return errors.Wrapf(err, "bmecat/reader: handler for CLASSIFICATION_GROUP %q returned an error around byte offset %d", cg.ID, dec.InputOffset())
-					}
-				}
-			case "ARTICLE":
-				var a Article
-				if err := dec.DecodeElement(&a, &se); err != nil {
-					return errors.Wrapf(err, "bmecat/reader: unable to decode ARTICLE after SUPPLIER_AID %q around byte offset %d", lastAID, dec.InputOffset())
-				}
-				if h.Article != nil {
-					// Inject catalog group mappings
-					r.artToCatalogGroupMu.Lock()
-					if ids, ok := r.artToCatalogGroup[a.SupplierAID]; ok {
-						a.CatalogGroupIDs = ids
-					}
-					r.artToCatalogGroupMu.Unlock()
-					// Call handler
-					if err := h.Article.HandleArticle(&a); err != nil {
-						return errors.Wrapf(err, "bmecat/reader: handler for ARTICLE %q returned an error around byte offset %d", a.SupplierAID, dec.InputOffset())
-					}
-				}
-				lastAID = a.SupplierAID
-			}
-		}
-		if r.progress != nil && rl.Allow() {
-			r.progress(2, dec.InputOffset())
-		}
-		select {
-		default:
-		case <-ctx.Done():
-			return ctx.Err()
+	return s.secondPass(doStarted)
+}
+
+// acceptArticle returns true if the article passes all filters.
+func (r *Reader) acceptArticle(a *Article) bool {
+	for _, f := range r.articleFilters {
+		if !f(a) {
+			return false
 		}
 	}
+	return true
+}
 
-	if h.Complete != nil {
-		h.Complete.HandleComplete()
+// decodeClassificationSystemElement decodes a child element with the
+// metadata of a CLASSIFICATION_SYSTEM into cs.
+func decodeClassificationSystemElement(dec *xml.Decoder, se *xml.StartElement, cs *ClassificationSystem) error {
+	switch se.Name.Local {
+	case "CLASSIFICATION_SYSTEM_NAME":
+		return dec.DecodeElement(&cs.Name, se)
+	case "CLASSIFICATION_SYSTEM_FULLNAME":
+		return dec.DecodeElement(&cs.FullName, se)
+	case "CLASSIFICATION_SYSTEM_VERSION":
+		return dec.DecodeElement(&cs.Version, se)
+	case "CLASSIFICATION_SYSTEM_DESCR":
+		return dec.DecodeElement(&cs.Description, se)
+	case "CLASSIFICATION_SYSTEM_LEVELS":
+		return dec.DecodeElement(&cs.Levels, se)
+	case "CLASSIFICATION_SYSTEM_LEVEL_NAMES":
+		return dec.DecodeElement(&cs.LevelNames, se)
+	case "CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES":
+		return dec.DecodeElement(&cs.FeatureTemplates, se)
 	}
+	return dec.Skip()
+}
 
-	return nil
+// transactionFromElement returns the transaction and the previous version
+// of the given transaction element, e.g. T_UPDATE_PRODUCTS.
+func transactionFromElement(se xml.StartElement) (Transaction, int) {
+	tx, _ := ParseTransaction(se.Name.Local)
+	var prevVersion int
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "prev_version" {
+			prevVersion, _ = strconv.Atoi(attr.Value)
+		}
+	}
+	return tx, prevVersion
 }
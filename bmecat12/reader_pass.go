@@ -0,0 +1,1059 @@
+package bmecat12
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"time"
+
+	"github.com/pkg/errors"
+	"golang.org/x/time/rate"
+)
+
+// readHandlers are the handlers passed to Reader.Do, by the interfaces
+// they implement.
+type readHandlers struct {
+	Document     DocumentHandler
+	Header       HeaderHandler
+	Transaction  TransactionHandler
+	FeatureSys   FeatureSystemHandler
+	CatalogGroup CatalogGroupHandler
+	ClassifSys   ClassificationSystemHandler
+	ClassifGroup ClassificationGroupHandler
+	Article      ArticleHandler
+	Offset       ArticleOffsetHandler
+	Skipped      SkippedArticleHandler
+	Warning      WarningHandler
+	Audit        AuditHandler
+	Complete     CompletionHandler
+	Stats        CompletionStatsHandler
+}
+
+// newReadHandlers returns the handlers that handler implements. The
+// context variants of the interfaces take precedence.
+func newReadHandlers(ctx context.Context, handler interface{}) readHandlers {
+	var h readHandlers
+	if f, ok := handler.(DocumentHandler); ok {
+		h.Document = f
+	}
+	if f, ok := handler.(HeaderHandler); ok {
+		h.Header = f
+	}
+	if f, ok := handler.(HeaderHandlerContext); ok {
+		h.Header = headerHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(TransactionHandler); ok {
+		h.Transaction = f
+	}
+	if f, ok := handler.(TransactionHandlerContext); ok {
+		h.Transaction = transactionHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(FeatureSystemHandler); ok {
+		h.FeatureSys = f
+	}
+	if f, ok := handler.(FeatureSystemHandlerContext); ok {
+		h.FeatureSys = featureSystemHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(CatalogGroupHandler); ok {
+		h.CatalogGroup = f
+	}
+	if f, ok := handler.(CatalogGroupHandlerContext); ok {
+		h.CatalogGroup = catalogGroupHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(ClassificationSystemHandler); ok {
+		h.ClassifSys = f
+	}
+	if f, ok := handler.(ClassificationSystemHandlerContext); ok {
+		h.ClassifSys = classificationSystemHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(ClassificationGroupHandler); ok {
+		h.ClassifGroup = f
+	}
+	if f, ok := handler.(ClassificationGroupHandlerContext); ok {
+		h.ClassifGroup = classificationGroupHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(ArticleHandler); ok {
+		h.Article = f
+	}
+	if f, ok := handler.(ArticleHandlerContext); ok {
+		h.Article = articleHandlerContext{ctx: ctx, h: f}
+	}
+	if f, ok := handler.(ArticleOffsetHandler); ok {
+		h.Offset = f
+	}
+	if f, ok := handler.(SkippedArticleHandler); ok {
+		h.Skipped = f
+	}
+	if f, ok := handler.(WarningHandler); ok {
+		h.Warning = f
+	}
+	if f, ok := handler.(AuditHandler); ok {
+		h.Audit = f
+	}
+	if f, ok := handler.(CompletionHandler); ok {
+		h.Complete = f
+	}
+	if f, ok := handler.(CompletionStatsHandler); ok {
+		h.Stats = f
+	}
+	return h
+}
+
+// readState is the state of a call to Reader.Do, shared by both passes.
+type readState struct {
+	r   *Reader
+	ctx context.Context
+	h   readHandlers
+	log logger
+
+	// firstPassState are the results of the 1st pass; skipped holds the
+	// articles exceeding maxArticleSize, by their 1-based position
+	firstPassState
+
+	// compressed is true for gzip input, reported by compressedOffset
+	compressed       bool
+	compressedOffset int64
+	tracker          *progressTracker
+	// rl limits progress reports to one per second
+	rl *rate.Limiter
+
+	dec     *xml.Decoder
+	capture *rawCapture
+	// base is the offset of the decoder's input after recovering from an error
+	base    int64
+	lenient bool
+	record  bool
+	stop    bool
+
+	// State of the 1st pass
+	numHeaders   int
+	rootSeen     bool
+	inArticle    bool
+	articleStart int64
+	articleAID   string
+
+	// State of the 2nd pass
+	articleIndex int
+	// numHandled is the number of articles passed to the handler
+	numHandled int
+	// lastAID is the SUPPLIER_AID of the last article decoded in the 2nd pass
+	lastAID string
+	// lastIndex and lastEnd are the position and end offset of the last
+	// article passed to deliverArticle, for checkpoints
+	lastIndex  int
+	lastEnd    int64
+	classifSys *ClassificationSystem
+	prolog     *Prolog
+	seenHeader bool
+	pool       *articlePool
+}
+
+// openInput sniffs compression and byte order marks, and wraps the input
+// of the Reader as configured. The caller restores the input.
+func (s *readState) openInput() error {
+	r := s.r
+	if r.progressFunc != nil {
+		s.tracker = newProgressTracker(r.progressFunc, r.r)
+	}
+	prefix, err := readPrefix(r.r, 4)
+	if err != nil {
+		return &InputError{Op: "read input", Err: err}
+	}
+	s.compressed = r.compression == CompressionGzip
+	if r.compression == CompressionAuto {
+		s.compressed = bytes.HasPrefix(prefix, gzipMagic)
+	}
+	if r.instr != nil {
+		r.r = &instrumentedReader{ReadSeeker: r.r, instr: r.instr}
+	}
+	in, err := decodeInput(r.r, prefix, s.compressed, &s.compressedOffset)
+	if err != nil {
+		return err
+	}
+	r.r = in
+	if r.forcedCharset != "" {
+		// Convert the input to UTF-8 before decoding, and ignore the
+		// encoding of the XML declaration
+		src, charsetReader := r.r, r.charsetReader
+		forced, err := newRewindReader(func() (io.Reader, error) {
+			if _, err := src.Seek(0, io.SeekStart); err != nil {
+				return nil, err
+			}
+			return charsetReader(r.forcedCharset, src)
+		}, -1)
+		if err != nil {
+			return &InputError{Op: "decode input as " + r.forcedCharset, Err: err}
+		}
+		r.r = forced
+		r.charsetReader = func(_ string, input io.Reader) (io.Reader, error) {
+			return input, nil
+		}
+	}
+	if l := r.limits; l.MaxTokenSize > 0 || l.MaxDepth > 0 || l.MaxEntityExpansion > 0 {
+		r.r = &limitReader{ReadSeeker: r.r, limits: l, entities: r.entities}
+	}
+	return nil
+}
+
+// report reports progress to the callbacks, if any.
+func (s *readState) report(pass int, offset int64, articles int) {
+	if s.compressed {
+		// Report progress in terms of the compressed input
+		offset = s.compressedOffset
+	}
+	if s.r.progress != nil {
+		s.r.progress(pass, offset)
+	}
+	if s.tracker != nil {
+		s.tracker.report(pass, offset, articles)
+	}
+}
+
+// reportProgress reports progress at most once a second.
+func (s *readState) reportProgress(pass int, articles int) {
+	if s.rl != nil && s.rl.Allow() {
+		s.report(pass, s.inputOffset(), articles)
+	}
+}
+
+// inputOffset returns the current offset into the input.
+func (s *readState) inputOffset() int64 {
+	return s.base + s.dec.InputOffset()
+}
+
+// handlerErrorAt returns a HandlerError at the given offset.
+func (s *readState) handlerErrorAt(err error, element, supplierAID, id string, offset int64) error {
+	return &HandlerError{
+		Element:     element,
+		SupplierAID: supplierAID,
+		ID:          id,
+		Offset:      offset,
+		Err:         err,
+	}
+}
+
+// handlerError returns a HandlerError at the current position.
+func (s *readState) handlerError(err error, element, supplierAID, id string) error {
+	return s.handlerErrorAt(err, element, supplierAID, id, s.inputOffset())
+}
+
+// parseError returns a ParseError at the current position.
+func (s *readState) parseError(err error, element, supplierAID string) error {
+	line, column := s.capture.position()
+	return &ParseError{
+		Element:             element,
+		SupplierAID:         supplierAID,
+		PreviousSupplierAID: s.lastAID,
+		Offset:              s.inputOffset(),
+		Line:                line,
+		Column:              column,
+		Err:                 err,
+	}
+}
+
+// recoverArticle skips the rest of the current ARTICLE element and
+// continues with a new decoder.
+func (s *readState) recoverArticle() error {
+	offset := s.inputOffset()
+	n, err := s.capture.skipArticle()
+	if err != nil {
+		return err
+	}
+	var prefixLen int64
+	s.dec, prefixLen = s.capture.decoder("BMECAT", s.txName)
+	s.base = offset + n - prefixLen
+	return nil
+}
+
+// recoverable returns true if the Reader can skip the current ARTICLE
+// after err.
+func (s *readState) recoverable(err error) bool {
+	return s.lenient && s.inArticle && !errors.Is(err, ErrLimitExceeded)
+}
+
+// done returns ctx.Err() if the context of Do has been canceled.
+func (s *readState) done() error {
+	select {
+	default:
+		return nil
+	case <-s.ctx.Done():
+		return s.ctx.Err()
+	}
+}
+
+// firstPass counts the elements of the document, collects the catalog
+// group mappings, and finds the articles exceeding maxArticleSize. It
+// returns true if Do is done, i.e. for the 1st pass of a MultiReader.
+func (s *readState) firstPass() (bool, error) {
+	r := s.r
+	started := time.Now()
+	for !s.stop {
+		if s.record {
+			s.capture.reset()
+		}
+		offset := s.inputOffset()
+		t, err := s.dec.Token()
+		if err == io.EOF {
+			s.stop = true
+			break
+		}
+		if err != nil {
+			if s.recoverable(err) {
+				// Report in 2nd pass
+				if rerr := s.recoverArticle(); rerr == nil {
+					s.inArticle = false
+					continue
+				}
+			}
+			return false, s.parseError(err, "", "")
+		}
+		switch se := t.(type) {
+		case xml.StartElement:
+			if err := s.countStartElement(se, offset); err != nil {
+				return false, err
+			}
+		case xml.ProcInst:
+			if se.Target == "xml" {
+				s.encoding = procInstEncoding(se.Inst)
+			}
+		case xml.EndElement:
+			if se.Name.Local == "ARTICLE" {
+				s.inArticle = false
+			}
+			if se.Name.Local == "ARTICLE" && s.skipped != nil {
+				if size := s.inputOffset() - s.articleStart; size > r.maxArticleSize {
+					s.skipped[s.numArticles] = skippedArticle{supplierAID: s.articleAID, size: size}
+				}
+			}
+		}
+		s.reportProgress(1, s.numArticles)
+		if err := s.done(); err != nil {
+			return false, err
+		}
+	}
+
+	if r.strictEnvelope && s.txName == "" {
+		return false, s.parseError(fmt.Errorf("%w: no transaction element", ErrInvalidEnvelope), "BMECAT", "")
+	}
+
+	r.stats.Articles = s.numArticles
+	r.stats.CatalogGroups = s.numCatalogGroups
+	r.stats.ClassificationGroups = s.numClassifGroups
+	r.stats.FirstPass = time.Since(started)
+	s.log.Info("bmecat: 1st pass completed",
+		"transaction", s.txName,
+		"articles", s.numArticles,
+		"catalog_groups", s.numCatalogGroups,
+		"classification_groups", s.numClassifGroups,
+		"skipped_articles", len(s.skipped),
+		"elapsed", r.stats.FirstPass,
+	)
+	if r.part != nil && r.part.firstPassOnly {
+		st := s.firstPassState
+		r.part.state = &st
+		return true, nil
+	}
+
+	// Seek back to start
+	if _, err := r.r.Seek(0, io.SeekStart); err != nil {
+		return false, &InputError{Op: "seek back to start", Err: err}
+	}
+	return false, nil
+}
+
+// countStartElement handles a start element in the 1st pass.
+func (s *readState) countStartElement(se xml.StartElement, offset int64) error {
+	r := s.r
+	if r.strictEnvelope && !s.rootSeen {
+		s.rootSeen = true
+		if err := checkRootElement(se); err != nil {
+			return s.parseError(err, se.Name.Local, "")
+		}
+	}
+	switch se.Name.Local {
+	case "HEADER":
+		s.numHeaders++
+		if s.numHeaders > 1 {
+			if !s.lenient {
+				return s.parseError(ErrDuplicateHeader, "HEADER", "")
+			}
+			// First wins; warn in 2nd pass
+			if err := s.dec.Skip(); err != nil {
+				return s.parseError(err, "HEADER", "")
+			}
+		}
+	case "T_NEW_CATALOG", "T_UPDATE_PRODUCTS", "T_UPDATE_PRICES":
+		if s.txName != "" {
+			if !s.lenient {
+				return s.parseError(fmt.Errorf("%w: %s after %s", ErrMultipleTransactions, se.Name.Local, s.txName), se.Name.Local, "")
+			}
+			// First wins; skip the whole block and warn in 2nd pass
+			if err := s.dec.Skip(); err != nil {
+				return s.parseError(err, se.Name.Local, "")
+			}
+			return nil
+		}
+		s.tx, s.prevVersion = transactionFromElement(se)
+		s.txName = se.Name.Local
+		if r.strictEnvelope {
+			if err := checkTransactionNamespace(se, s.tx); err != nil {
+				return s.parseError(err, s.txName, "")
+			}
+		}
+	case "ARTICLE":
+		s.numArticles++
+		if max := r.limits.MaxArticles; max > 0 && s.numArticles > max {
+			return s.parseError(&LimitError{Limit: "MaxArticles", Max: int64(max)}, "ARTICLE", "")
+		}
+		s.inArticle = true
+		s.articleStart = offset
+		s.articleAID = ""
+	case "SUPPLIER_AID":
+		if s.skipped != nil && s.articleAID == "" {
+			if err := s.dec.DecodeElement(&s.articleAID, &se); err != nil {
+				if s.recoverable(err) {
+					if rerr := s.recoverArticle(); rerr == nil {
+						s.inArticle = false
+						return nil
+					}
+				}
+				return s.parseError(err, "SUPPLIER_AID", "")
+			}
+		}
+	case "CATALOG_STRUCTURE":
+		s.numCatalogGroups++
+	case "CLASSIFICATION_GROUP":
+		s.numClassifGroups++
+	case "ARTICLE_TO_CATALOGGROUP_MAP":
+		if r.noCatalogGroupMapping {
+			if err := s.dec.Skip(); err != nil {
+				return s.parseError(err, "ARTICLE_TO_CATALOGGROUP_MAP", "")
+			}
+			return nil
+		}
+		var m ArticleToCatalogGroupMap
+		if err := s.dec.DecodeElement(&m, &se); err != nil {
+			return s.parseError(err, "ARTICLE_TO_CATALOGGROUP_MAP", "")
+		}
+		if err := r.mappings.Add(m.ArticleID, m.CatalogGroupID); err != nil {
+			return &MapStoreError{Op: "store catalog group mapping", SupplierAID: m.ArticleID, Err: err}
+		}
+	}
+	return nil
+}
+
+// restorePart restores the state of the 1st pass of a MultiReader.
+func (s *readState) restorePart() error {
+	r := s.r
+	skipped := s.skipped
+	s.firstPassState = *r.part.state
+	if skipped == nil {
+		s.skipped = nil
+	}
+	if _, err := r.r.Seek(0, io.SeekStart); err != nil {
+		return &InputError{Op: "seek back to start", Err: err}
+	}
+	return nil
+}
+
+// restoreCheckpoint restores the state of the 1st pass from the
+// checkpoint of WithCheckpoint.
+func (s *readState) restoreCheckpoint() error {
+	r := s.r
+	cp := r.resume
+	s.tx, s.prevVersion, s.txName, s.encoding = cp.Transaction, cp.PreviousVersion, cp.TransactionElement, cp.Encoding
+	for id, groups := range cp.CatalogGroups {
+		for _, group := range groups {
+			if err := r.mappings.Add(id, group); err != nil {
+				return &MapStoreError{Op: "store catalog group mapping", SupplierAID: id, Err: err}
+			}
+		}
+	}
+	if s.skipped != nil {
+		for i, sa := range cp.SkippedArticles {
+			s.skipped[i] = skippedArticle{supplierAID: sa.SupplierAID, size: sa.Size}
+		}
+	}
+	return nil
+}
+
+// secondPass decodes the elements of the document and passes them to
+// the handlers.
+func (s *readState) secondPass(doStarted time.Time) error {
+	r := s.r
+	started := time.Now()
+	defer func() {
+		r.stats.SecondPass = time.Since(started)
+	}()
+	if r.resume != nil {
+		s.log.Info("bmecat: 2nd pass resumed from checkpoint", "offset", r.resume.Offset, "articles", r.resume.Articles)
+	} else {
+		s.log.Info("bmecat: 2nd pass started")
+	}
+	if s.tracker != nil {
+		s.tracker.totalArticles = s.numArticles
+	}
+	if s.rl != nil {
+		if r.resume != nil {
+			s.report(2, r.resume.Offset, r.resume.Handled)
+		} else {
+			s.report(2, 0, 0)
+		}
+	}
+	if s.h.Document != nil && r.part.first() {
+		s.prolog = &Prolog{}
+	}
+	if r.concurrency > 1 {
+		s.pool = r.newArticlePool()
+		defer s.pool.close()
+	}
+
+	if r.resume != nil {
+		var err error
+		s.dec, s.capture, s.base, err = r.resumeDecoder(r.resume)
+		if err != nil {
+			return err
+		}
+		s.articleIndex = r.resume.Articles
+		s.numHandled = r.resume.Handled
+		s.lastAID = r.resume.LastSupplierAID
+		s.lastIndex, s.lastEnd = s.articleIndex, r.resume.Offset
+		s.seenHeader = true
+	} else {
+		s.dec, s.capture = r.newDecoder(r.r)
+		s.base = 0
+	}
+	r.checkpoint = s.checkpoint
+	defer func() { r.checkpoint = nil }()
+	s.stop = false
+	for !s.stop {
+		if s.record {
+			s.capture.reset()
+		}
+		offset := s.inputOffset()
+		t, err := s.dec.Token()
+		if err == io.EOF {
+			s.stop = true
+			break
+		}
+		if err != nil {
+			return s.parseError(err, "", "")
+		}
+		if s.prolog != nil {
+			if se, ok := t.(xml.StartElement); !ok || se.Name.Local != "BMECAT" {
+				s.prolog.add(t)
+			}
+		}
+		switch se := t.(type) {
+		case xml.StartElement:
+			if err := s.handleStartElement(se, offset); err != nil {
+				return err
+			}
+		case xml.EndElement:
+			switch se.Name.Local {
+			case "CLASSIFICATION_SYSTEM":
+				// Classification system without groups
+				if err := s.handleClassificationSystem(); err != nil {
+					return err
+				}
+			}
+		}
+		s.reportProgress(2, s.numHandled)
+		if err := s.done(); err != nil {
+			return err
+		}
+	}
+
+	if s.pool != nil {
+		if err := s.pool.drain(s.deliverResult); err != nil {
+			return err
+		}
+	}
+
+	r.stats.SecondPass = time.Since(started)
+	s.log.Info("bmecat: 2nd pass completed",
+		"articles_handled", r.stats.ArticlesHandled,
+		"articles_filtered", r.stats.ArticlesFiltered,
+		"articles_skipped", r.stats.ArticlesSkipped,
+		"articles_failed", r.stats.ArticlesFailed,
+		"warnings", r.stats.Warnings,
+		"elapsed", r.stats.SecondPass,
+	)
+	if r.part != nil {
+		r.part.shared.stats.add(r.stats)
+	}
+	switch {
+	case !r.part.last():
+	case s.h.Stats != nil:
+		stats, err := r.completionStats(doStarted)
+		if err != nil {
+			return err
+		}
+		s.h.Stats.HandleCompleteStats(stats)
+	case s.h.Complete != nil:
+		s.h.Complete.HandleComplete()
+	}
+	return nil
+}
+
+// handleStartElement handles a start element in the 2nd pass.
+func (s *readState) handleStartElement(se xml.StartElement, offset int64) error {
+	switch se.Name.Local {
+	case "BMECAT":
+		if s.prolog != nil {
+			if err := s.h.Document.HandleProlog(s.prolog); err != nil {
+				return s.handlerError(err, "BMECAT", "", "")
+			}
+			s.prolog = nil
+		}
+	case "HEADER":
+		return s.handleHeader(se, offset)
+	case "T_NEW_CATALOG", "T_UPDATE_PRODUCTS", "T_UPDATE_PRICES":
+		return s.handleTransaction(se, offset)
+	case "FEATURE_SYSTEM":
+		return s.handleFeatureSystem(se)
+	case "CATALOG_STRUCTURE":
+		return s.handleCatalogGroup(se, offset)
+	case "CLASSIFICATION_SYSTEM":
+		s.r.stats.ClassificationSystems++
+		if s.h.ClassifSys != nil {
+			s.classifSys = &ClassificationSystem{}
+		}
+	case "CLASSIFICATION_SYSTEM_NAME",
+		"CLASSIFICATION_SYSTEM_FULLNAME",
+		"CLASSIFICATION_SYSTEM_VERSION",
+		"CLASSIFICATION_SYSTEM_DESCR",
+		"CLASSIFICATION_SYSTEM_LEVELS",
+		"CLASSIFICATION_SYSTEM_LEVEL_NAMES",
+		"CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES":
+		if s.classifSys != nil {
+			if err := decodeClassificationSystemElement(s.dec, &se, s.classifSys); err != nil {
+				return s.parseError(err, se.Name.Local, "")
+			}
+		}
+	case "CLASSIFICATION_GROUPS":
+		return s.handleClassificationSystem()
+	case "CLASSIFICATION_GROUP":
+		return s.handleClassificationGroup(se)
+	case "ARTICLE":
+		return s.handleArticle(se, offset)
+	}
+	return nil
+}
+
+// handleHeader decodes the HEADER and passes it to the handler, along
+// with the counts of the 1st pass.
+func (s *readState) handleHeader(se xml.StartElement, offset int64) error {
+	r := s.r
+	if s.seenHeader {
+		// Only in lenient mode, see 1st pass
+		if err := s.dec.Skip(); err != nil {
+			return s.parseError(err, "HEADER", "")
+		}
+		r.stats.SkippedElements++
+		msg := "duplicate HEADER element ignored"
+		if err := s.warn(&Warning{Path: "HEADER", Offset: offset, Message: msg}); err != nil {
+			return err
+		}
+		return s.audit(&AuditEvent{Action: AuditSkip, Element: "HEADER", Reason: msg, Offset: offset})
+	}
+	s.seenHeader = true
+	if !r.part.claimHeader() {
+		// The first HEADER of a MultiReader wins
+		if err := s.dec.Skip(); err != nil {
+			return s.parseError(err, "HEADER", "")
+		}
+		return nil
+	}
+	var hdr Header
+	if err := s.dec.DecodeElement(&hdr, &se); err != nil {
+		return s.parseError(err, "HEADER", "")
+	}
+	hdr.NumberOfArticles = s.numArticles
+	hdr.NumberOfCatalogGroups = s.numCatalogGroups
+	hdr.NumberOfClassificationGroups = s.numClassifGroups
+	hdr.Transaction = s.tx
+	hdr.PreviousVersion = s.prevVersion
+	if r.part != nil {
+		// Count all files of a MultiReader
+		totals := r.part.shared.totals
+		hdr.NumberOfArticles = totals.numArticles
+		hdr.NumberOfCatalogGroups = totals.numCatalogGroups
+		hdr.NumberOfClassificationGroups = totals.numClassifGroups
+		hdr.Transaction, hdr.PreviousVersion = totals.tx, totals.prevVersion
+	}
+	numMaps, err := r.mappings.Len()
+	if err != nil {
+		return &MapStoreError{Op: "read catalog group mappings", Err: err}
+	}
+	hdr.NumberOfArticleToCatalogGroupMaps = numMaps
+	if s.h.Header != nil {
+		err := s.h.Header.HandleHeader(&hdr)
+		if err == io.EOF {
+			s.stop = true
+			return nil
+		}
+		if err != nil {
+			return s.handlerError(err, "HEADER", "", "")
+		}
+	}
+	return nil
+}
+
+// handleTransaction passes the transaction to the handler, and skips
+// other transaction elements in lenient mode.
+func (s *readState) handleTransaction(se xml.StartElement, offset int64) error {
+	r := s.r
+	if se.Name.Local != s.txName {
+		// Only in lenient mode, see 1st pass
+		if err := s.dec.Skip(); err != nil {
+			return s.parseError(err, se.Name.Local, "")
+		}
+		r.stats.SkippedElements++
+		msg := fmt.Sprintf("%s element ignored, the catalog is %s", se.Name.Local, s.txName)
+		if err := s.warn(&Warning{Path: se.Name.Local, Offset: offset, Message: msg}); err != nil {
+			return err
+		}
+		return s.audit(&AuditEvent{Action: AuditSkip, Element: se.Name.Local, Reason: msg, Offset: offset})
+	}
+	for _, attr := range se.Attr {
+		if attr.Name.Local != "prev_version" {
+			continue
+		}
+		if _, err := strconv.Atoi(attr.Value); err != nil {
+			e := &AuditEvent{
+				Action:  AuditCoerce,
+				Element: se.Name.Local,
+				Field:   "prev_version",
+				Before:  attr.Value,
+				After:   "0",
+				Reason:  "invalid number",
+				Offset:  offset,
+			}
+			if err := s.audit(e); err != nil {
+				return err
+			}
+		}
+	}
+	if s.h.Transaction != nil && r.part.claimTransaction() {
+		tx, prevVersion := transactionFromElement(se)
+		if err := s.h.Transaction.HandleTransaction(tx, prevVersion); err != nil {
+			return s.handlerError(err, se.Name.Local, "", "")
+		}
+	}
+	return nil
+}
+
+// handleFeatureSystem decodes a FEATURE_SYSTEM and passes it to the
+// handler, if any.
+func (s *readState) handleFeatureSystem(se xml.StartElement) error {
+	s.r.stats.FeatureSystems++
+	if s.h.FeatureSys == nil {
+		if err := s.dec.Skip(); err != nil {
+			return s.parseError(err, "FEATURE_SYSTEM", "")
+		}
+		return nil
+	}
+	var fs FeatureSystem
+	if err := s.dec.DecodeElement(&fs, &se); err != nil {
+		return s.parseError(err, "FEATURE_SYSTEM", "")
+	}
+	if err := s.h.FeatureSys.HandleFeatureSystem(&fs); err != nil {
+		return s.handlerError(err, "FEATURE_SYSTEM", "", fs.Name)
+	}
+	return nil
+}
+
+// handleCatalogGroup decodes a CATALOG_STRUCTURE and passes it and its
+// warnings to the handlers, if any.
+func (s *readState) handleCatalogGroup(se xml.StartElement, offset int64) error {
+	var cg CatalogGroup
+	if err := s.dec.DecodeElement(&cg, &se); err != nil {
+		return s.parseError(err, "CATALOG_GROUP", "")
+	}
+	if s.h.Warning != nil {
+		for _, w := range catalogGroupWarnings(&cg, offset) {
+			s.r.stats.Warnings++
+			if err := s.h.Warning.HandleWarning(w); err != nil {
+				return s.handlerError(err, "CATALOG_STRUCTURE", "", cg.ID)
+			}
+		}
+	}
+	if s.h.CatalogGroup != nil {
+		if err := s.h.CatalogGroup.HandleCatalogGroup(&cg); err != nil {
+			return s.handlerError(err, "CATALOG_STRUCTURE", "", cg.ID)
+		}
+	}
+	return nil
+}
+
+// handleClassificationSystem passes the CLASSIFICATION_SYSTEM read so
+// far to the handler, if any. It is called before the groups, or at the
+// end of a classification system without groups.
+func (s *readState) handleClassificationSystem() error {
+	if s.classifSys == nil {
+		return nil
+	}
+	if err := s.h.ClassifSys.HandleClassificationSystem(s.classifSys); err != nil {
+		return s.handlerError(err, "CLASSIFICATION_SYSTEM", "", s.classifSys.Name)
+	}
+	s.classifSys = nil
+	return nil
+}
+
+// handleClassificationGroup decodes a CLASSIFICATION_GROUP and passes it
+// to the handler, if any.
+func (s *readState) handleClassificationGroup(se xml.StartElement) error {
+	var cg ClassificationGroup
+	if err := s.dec.DecodeElement(&cg, &se); err != nil {
+		return s.parseError(err, "CLASSIFICATION_GROUP", "")
+	}
+	if s.h.ClassifGroup != nil {
+		if err := s.h.ClassifGroup.HandleClassificationGroup(&cg); err != nil {
+			return s.handlerError(err, "CLASSIFICATION_GROUP", "", cg.ID)
+		}
+	}
+	return nil
+}
+
+// handleArticle skips an ARTICLE exceeding maxArticleSize, or decodes it,
+// on a worker if WithConcurrency is set, and delivers it.
+func (s *readState) handleArticle(se xml.StartElement, offset int64) error {
+	r := s.r
+	s.articleIndex++
+	if sa, found := s.skipped[s.articleIndex]; found {
+		return s.skipOversized(sa, offset)
+	}
+	if s.pool != nil {
+		// Decode on a worker
+		line, column := s.capture.position()
+		if err := s.dec.Skip(); err != nil {
+			if !s.lenient {
+				return s.parseError(err, "ARTICLE", "")
+			}
+			perr := s.parseError(err, "ARTICLE", "")
+			if rerr := s.recoverArticle(); rerr != nil {
+				return perr
+			}
+			if !r.continueOnError(err, offset, s.capture.bytes()) {
+				return perr
+			}
+			return s.skipFailed(err, "", offset)
+		}
+		job := &articleJob{raw: s.capture.bytes(), index: s.articleIndex, offset: offset, end: s.inputOffset(), line: line, column: column}
+		return s.pool.submit(job, s.deliverResult)
+	}
+	a := r.newArticle()
+	decodeStarted := time.Now()
+	if err := decodeArticle(s.dec, &se, a, r.skipSections, r.fastDecoder); err != nil {
+		aid := a.SupplierAID
+		perr := s.parseError(err, "ARTICLE", aid)
+		a.Release()
+		if !s.lenient {
+			return perr
+		}
+		if rerr := s.recoverArticle(); rerr != nil {
+			return perr
+		}
+		if !r.continueOnError(err, offset, s.capture.bytes()) {
+			return perr
+		}
+		return s.skipFailed(err, aid, offset)
+	}
+	if r.instr != nil {
+		r.instr.DecodeDuration(time.Since(decodeStarted))
+	}
+	if r.rawArticles {
+		a.RawXML = s.capture.bytes()
+	}
+	return s.deliverArticle(a, s.articleIndex, offset, s.inputOffset())
+}
+
+// skipOversized skips an ARTICLE exceeding maxArticleSize and reports it
+// to the handlers.
+func (s *readState) skipOversized(sa skippedArticle, offset int64) error {
+	r := s.r
+	if err := s.dec.Skip(); err != nil {
+		return s.parseError(err, "ARTICLE", sa.supplierAID)
+	}
+	r.stats.ArticlesSkipped++
+	e := &AuditEvent{
+		Action:      AuditSkip,
+		Element:     "ARTICLE",
+		SupplierAID: sa.supplierAID,
+		Reason:      fmt.Sprintf("size of %d bytes exceeds the maximum of %d bytes", sa.size, r.maxArticleSize),
+		Offset:      offset,
+	}
+	s.log.Warn("bmecat: skipped ARTICLE exceeding the maximum size", "supplier_aid", sa.supplierAID, "size", sa.size, "offset", offset)
+	if err := s.audit(e); err != nil {
+		return err
+	}
+	if s.h.Skipped != nil {
+		if err := s.h.Skipped.HandleSkippedArticle(sa.supplierAID, sa.size); err != nil {
+			return s.handlerError(err, "ARTICLE", sa.supplierAID, "")
+		}
+	}
+	s.lastAID = sa.supplierAID
+	return nil
+}
+
+// audit passes an audit event to the handler, if any.
+func (s *readState) audit(e *AuditEvent) error {
+	s.log.Debug("bmecat: audit", "action", e.Action, "element", e.Element, "supplier_aid", e.SupplierAID, "reason", e.Reason, "offset", e.Offset)
+	if s.h.Audit == nil {
+		return nil
+	}
+	if err := s.h.Audit.HandleAudit(e); err != nil {
+		return s.handlerErrorAt(err, e.Element, e.SupplierAID, "", e.Offset)
+	}
+	return nil
+}
+
+// warn passes a warning to the handler, if any.
+func (s *readState) warn(w *Warning) error {
+	s.log.Warn("bmecat: "+w.Message, "path", w.Path, "offset", w.Offset)
+	if s.h.Warning == nil {
+		return nil
+	}
+	s.r.stats.Warnings++
+	if err := s.h.Warning.HandleWarning(w); err != nil {
+		return s.handlerError(err, w.Path, w.SupplierAID, "")
+	}
+	return nil
+}
+
+// skipFailed records an article that could not be decoded and has been
+// skipped in lenient mode.
+func (s *readState) skipFailed(err error, supplierAID string, offset int64) error {
+	s.log.Warn("bmecat: skipped ARTICLE that cannot be decoded", "supplier_aid", supplierAID, "offset", offset, "error", err)
+	s.r.stats.ArticlesFailed++
+	return s.audit(&AuditEvent{Action: AuditSkip, Element: "ARTICLE", SupplierAID: supplierAID, Reason: err.Error(), Offset: offset})
+}
+
+// deliverArticle passes a decoded article to the handler.
+func (s *readState) deliverArticle(a *Article, index int, offset, end int64) error {
+	r := s.r
+	if r.maxArticles > 0 && s.numHandled >= r.maxArticles {
+		// Articles still decoded by workers after the limit has been reached
+		a.Release()
+		return nil
+	}
+	// Inject catalog group mappings
+	ids, err := r.mappings.Get(a.SupplierAID)
+	if err != nil {
+		return &MapStoreError{Op: "read catalog group mappings", SupplierAID: a.SupplierAID, Err: err}
+	}
+	if ids != nil {
+		a.CatalogGroupIDs = ids
+	}
+	// The article is done with regard to checkpoints from here on
+	s.lastIndex, s.lastEnd = index, end
+	if r.instr != nil {
+		r.instr.ArticleRead()
+	}
+	if !r.acceptArticle(a) {
+		r.stats.ArticlesFiltered++
+		s.lastAID = a.SupplierAID
+		a.Release()
+		return s.audit(&AuditEvent{Action: AuditSkip, Element: "ARTICLE", SupplierAID: s.lastAID, Reason: "rejected by article filter", Offset: offset})
+	}
+	if s.h.Warning != nil {
+		for _, w := range articleWarnings(a, s.tx, offset) {
+			s.log.Debug("bmecat: warning", "path", w.Path, "supplier_aid", w.SupplierAID, "message", w.Message, "offset", w.Offset)
+			r.stats.Warnings++
+			if err := s.h.Warning.HandleWarning(w); err != nil {
+				return s.handlerErrorAt(err, "ARTICLE", a.SupplierAID, "", offset)
+			}
+		}
+	}
+	if s.h.Offset != nil {
+		if err := s.h.Offset.HandleArticleOffset(a.SupplierAID, offset, end); err != nil {
+			return s.handlerErrorAt(err, "ARTICLE", a.SupplierAID, "", offset)
+		}
+	}
+	if s.h.Article != nil {
+		if r.eclassMapper != nil {
+			if err := MapEclassFeatures(a, r.eclassMapper); err != nil {
+				return s.handlerErrorAt(err, "ARTICLE", a.SupplierAID, "", offset)
+			}
+		}
+		// Call handler; it may release the article
+		aid := a.SupplierAID
+		s.numHandled++
+		r.stats.ArticlesHandled = s.numHandled
+		s.lastAID = aid
+		if err := s.h.Article.HandleArticle(a); err != nil {
+			return s.handlerErrorAt(err, "ARTICLE", aid, "", offset)
+		}
+	} else {
+		s.lastAID = a.SupplierAID
+		a.Release()
+	}
+	if r.maxArticles > 0 && s.numHandled >= r.maxArticles {
+		s.stop = true
+	}
+	return nil
+}
+
+// deliverResult passes an article decoded by a worker to the handler.
+func (s *readState) deliverResult(res *articleResult) error {
+	if res.err == nil {
+		if s.r.instr != nil {
+			s.r.instr.DecodeDuration(res.took)
+		}
+		return s.deliverArticle(res.article, res.index, res.offset, res.end)
+	}
+	defer res.article.Release()
+	perr := &ParseError{
+		Element:             "ARTICLE",
+		SupplierAID:         res.article.SupplierAID,
+		PreviousSupplierAID: s.lastAID,
+		Offset:              res.offset,
+		Line:                res.line,
+		Column:              res.column,
+		Err:                 res.err,
+	}
+	if !s.lenient || !s.r.continueOnError(res.err, res.offset, res.raw) {
+		return perr
+	}
+	return s.skipFailed(res.err, perr.SupplierAID, res.offset)
+}
+
+// checkpoint returns the current checkpoint of the 2nd pass, see
+// Reader.Checkpoint.
+func (s *readState) checkpoint() *Checkpoint {
+	r := s.r
+	if s.lastEnd == 0 || (s.pool != nil && r.unordered) {
+		return nil
+	}
+	cp := &Checkpoint{
+		Pass:               2,
+		Offset:             s.lastEnd,
+		Articles:           s.lastIndex,
+		Handled:            s.numHandled,
+		LastSupplierAID:    s.lastAID,
+		Transaction:        s.tx,
+		PreviousVersion:    s.prevVersion,
+		TransactionElement: s.txName,
+		Encoding:           s.encoding,
+		CatalogGroups:      make(map[string][]string),
+	}
+	err := r.mappings.Range(func(id string, groups []string) error {
+		cp.CatalogGroups[id] = groups
+		return nil
+	})
+	if err != nil {
+		// Without the mappings, the checkpoint is useless
+		return nil
+	}
+	if len(s.skipped) > 0 {
+		cp.SkippedArticles = make(map[int]SkippedArticle, len(s.skipped))
+		for i, sa := range s.skipped {
+			cp.SkippedArticles[i] = SkippedArticle{SupplierAID: sa.supplierAID, Size: sa.size}
+		}
+	}
+	return cp
+}
@@ -1,20 +1,31 @@
 package bmecat12_test
 
 import (
+	"bytes"
 	"context"
+	"encoding/xml"
+	"errors"
+	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"reflect"
 	"strings"
 	"testing"
 
+	"golang.org/x/text/encoding"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/encoding/unicode"
+
 	"github.com/olivere/bmecat/bmecat12"
 )
 
 type testHandler struct {
 	firstPassOnly bool
 	header        *bmecat12.Header
+	tx            bmecat12.Transaction
+	prevVersion   int
 	articles      []*bmecat12.Article
 }
 
@@ -26,6 +37,12 @@ func (h *testHandler) HandleHeader(header *bmecat12.Header) error {
 	return nil
 }
 
+func (h *testHandler) HandleTransaction(tx bmecat12.Transaction, prevVersion int) error {
+	h.tx = tx
+	h.prevVersion = prevVersion
+	return nil
+}
+
 func (h *testHandler) HandleArticle(article *bmecat12.Article) error {
 	h.articles = append(h.articles, article)
 	return nil
@@ -71,6 +88,18 @@ func TestReadUpdateProducts(t *testing.T) {
 	if want, have := 2, len(h.articles); want != have {
 		t.Fatalf("want len(articles) = %d, have %d", want, have)
 	}
+	if want, have := bmecat12.UpdateProducts, h.header.Transaction; want != have {
+		t.Fatalf("want Header.Transaction = %v, have %v", want, have)
+	}
+	if want, have := 13, h.header.PreviousVersion; want != have {
+		t.Fatalf("want Header.PreviousVersion = %d, have %d", want, have)
+	}
+	if want, have := bmecat12.UpdateProducts, h.tx; want != have {
+		t.Fatalf("want transaction %v, have %v", want, have)
+	}
+	if want, have := 13, h.prevVersion; want != have {
+		t.Fatalf("want prev_version %d, have %d", want, have)
+	}
 }
 
 func TestReadUpdatePrices(t *testing.T) {
@@ -122,3 +151,1172 @@ func BenchmarkReader(b *testing.B) {
 		}
 	}
 }
+
+type classificationSystemHandler struct {
+	systems []*bmecat12.ClassificationSystem
+	groups  int
+}
+
+func (h *classificationSystemHandler) HandleClassificationSystem(cs *bmecat12.ClassificationSystem) error {
+	if h.groups > 0 {
+		return errors.New("classification system must be passed before its groups")
+	}
+	h.systems = append(h.systems, cs)
+	return nil
+}
+
+func (h *classificationSystemHandler) HandleClassificationGroup(*bmecat12.ClassificationGroup) error {
+	h.groups++
+	return nil
+}
+
+func TestReadClassificationSystem(t *testing.T) {
+	level := 1
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		classificationSystem: &bmecat12.ClassificationSystem{
+			Name:        "udf_Supplier-1.0",
+			FullName:    "SupplyCo Ltd.",
+			Version:     "1.0",
+			Description: "Product groups of SupplyCo",
+			Levels:      2,
+			LevelNames: []*bmecat12.ClassificationSystemLevelName{
+				{Level: 1, Value: "Segment"},
+				{Level: 2, Value: "Class"},
+			},
+			Groups: []*bmecat12.ClassificationGroup{
+				{ID: "1", Name: "Hardware", Type: "node", Level: &level},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &classificationSystemHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.systems); want != have {
+		t.Fatalf("want %d classification systems, have %d", want, have)
+	}
+	if want, have := 1, h.groups; want != have {
+		t.Fatalf("want %d classification groups, have %d", want, have)
+	}
+	cs := h.systems[0]
+	if want, have := "udf_Supplier-1.0", cs.Name; want != have {
+		t.Fatalf("want Name = %q, have %q", want, have)
+	}
+	if want, have := "SupplyCo Ltd.", cs.FullName; want != have {
+		t.Fatalf("want FullName = %q, have %q", want, have)
+	}
+	if want, have := "1.0", cs.Version; want != have {
+		t.Fatalf("want Version = %q, have %q", want, have)
+	}
+	if want, have := "Product groups of SupplyCo", cs.Description; want != have {
+		t.Fatalf("want Description = %q, have %q", want, have)
+	}
+	if want, have := 2, cs.Levels; want != have {
+		t.Fatalf("want Levels = %d, have %d", want, have)
+	}
+	if want, have := 2, len(cs.LevelNames); want != have {
+		t.Fatalf("want len(LevelNames) = %d, have %d", want, have)
+	}
+	if want, have := "Class", cs.LevelNames[1].Value; want != have {
+		t.Fatalf("want LevelNames[1] = %q, have %q", want, have)
+	}
+	if want, have := 0, len(cs.Groups); want != have {
+		t.Fatalf("want len(Groups) = %d, have %d", want, have)
+	}
+}
+
+func TestReadClassificationSystemFeatureTemplates(t *testing.T) {
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		classificationSystem: &bmecat12.ClassificationSystem{
+			Name: "ETIM-7.0",
+			FeatureTemplates: []*bmecat12.ClassificationFeatureTemplate{
+				{ID: "EF000008", Name: "Width", Unit: "MMT", Order: 1},
+				{ID: "EF000007", Name: "Colour", Order: 2, AllowedValueIDs: []string{"EV000080", "EV000081"}},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<CLASSIFICATION_SYSTEM_FEATURE_TEMPLATES>",
+		"<FT_ID>EF000008</FT_ID>",
+		"<ALLOWED_VALUE_IDREF>EV000081</ALLOWED_VALUE_IDREF>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Fatalf("want %s in\n%s", want, out)
+		}
+	}
+
+	h := &classificationSystemHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.systems); want != have {
+		t.Fatalf("want %d classification systems, have %d", want, have)
+	}
+	fts := h.systems[0].FeatureTemplates
+	if want, have := 2, len(fts); want != have {
+		t.Fatalf("want len(FeatureTemplates) = %d, have %d", want, have)
+	}
+	if want, have := "EF000008 Width MMT 1", fmt.Sprintf("%s %s %s %d", fts[0].ID, fts[0].Name, fts[0].Unit, fts[0].Order); want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	if want, have := []string{"EV000080", "EV000081"}, fts[1].AllowedValueIDs; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want AllowedValueIDs = %v, have %v", want, have)
+	}
+}
+
+type featureSystemCatalogWriter struct {
+	catalogWriter
+	featureSystems []*bmecat12.FeatureSystem
+}
+
+func (w featureSystemCatalogWriter) FeatureSystems() []*bmecat12.FeatureSystem {
+	return w.featureSystems
+}
+
+type featureSystemHandler struct {
+	systems []*bmecat12.FeatureSystem
+}
+
+func (h *featureSystemHandler) HandleFeatureSystem(fs *bmecat12.FeatureSystem) error {
+	h.systems = append(h.systems, fs)
+	return nil
+}
+
+func TestReadFeatureSystem(t *testing.T) {
+	cw := featureSystemCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:     bmecat12.NewCatalog,
+			header: testHeader,
+		},
+		featureSystems: []*bmecat12.FeatureSystem{
+			{
+				Name:        "udf_Supplier-1.0",
+				Description: "Features of SupplyCo",
+				Groups: []*bmecat12.FeatureGroup{
+					{
+						ID:   "1",
+						Name: "Notebooks",
+						Templates: []*bmecat12.FeatureTemplate{
+							{Name: "Display", Unit: "inch", Order: 1},
+							{Name: "Weight", Unit: "KGM", Order: 2},
+						},
+					},
+					{
+						ID:        "2",
+						Name:      "Gaming Notebooks",
+						Templates: []*bmecat12.FeatureTemplate{{Name: "GPU"}},
+						ParentIDs: []string{"1"},
+					},
+				},
+			},
+			{Name: "udf_Other-1.0"},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &featureSystemHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.systems); want != have {
+		t.Fatalf("want %d feature systems, have %d", want, have)
+	}
+	fs := h.systems[0]
+	if want, have := "Features of SupplyCo", fs.Description; want != have {
+		t.Fatalf("want Description = %q, have %q", want, have)
+	}
+	if want, have := 2, len(fs.Groups); want != have {
+		t.Fatalf("want len(Groups) = %d, have %d", want, have)
+	}
+	if want, have := "KGM", fs.Groups[0].Templates[1].Unit; want != have {
+		t.Fatalf("want Unit = %q, have %q", want, have)
+	}
+	if want, have := "1", strings.Join(fs.Groups[1].ParentIDs, ","); want != have {
+		t.Fatalf("want ParentIDs = %q, have %q", want, have)
+	}
+}
+
+func TestReadWithHandlerFuncs(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var (
+		header   *bmecat12.Header
+		tx       bmecat12.Transaction
+		aids     []string
+		complete bool
+	)
+	h := bmecat12.HandlerFuncs{
+		OnHeader: func(h *bmecat12.Header) error {
+			header = h
+			return nil
+		},
+		OnTransaction: func(t bmecat12.Transaction, prevVersion int) error {
+			tx = t
+			return nil
+		},
+		OnArticle: func(a *bmecat12.Article) error {
+			aids = append(aids, a.SupplierAID)
+			return nil
+		},
+		OnComplete: func() {
+			complete = true
+		},
+	}
+	if err := bmecat12.NewReader(f).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if header == nil {
+		t.Fatal("want Header, have nil")
+	}
+	if want, have := bmecat12.UpdateProducts, tx; want != have {
+		t.Fatalf("want transaction %v, have %v", want, have)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if !complete {
+		t.Fatal("expected OnComplete to be called")
+	}
+}
+
+func TestReadWithMultiHandler(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	h1 := &testHandler{}
+	var n int
+	h2 := bmecat12.HandlerFuncs{
+		OnArticle: func(*bmecat12.Article) error {
+			n++
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(f).Do(context.Background(), bmecat12.MultiHandler(h1, h2)); err != nil {
+		t.Fatal(err)
+	}
+	if h1.header == nil {
+		t.Fatal("want Header, have nil")
+	}
+	if want, have := 2, len(h1.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := 2, n; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+
+	// Errors of all handlers are aggregated
+	errFail := errors.New("fail")
+	h3 := bmecat12.HandlerFuncs{
+		OnArticle: func(*bmecat12.Article) error { return errFail },
+	}
+	h4 := bmecat12.HandlerFuncs{
+		OnArticle: func(*bmecat12.Article) error { return errFail },
+	}
+	err = bmecat12.NewReader(f).Do(context.Background(), bmecat12.MultiHandler(h3, h4))
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if want, have := "fail; fail", err.Error(); !strings.Contains(have, want) {
+		t.Fatalf("want error to contain %q, have %q", want, have)
+	}
+
+	// The errors of the handlers can be inspected with errors.Is and As
+	h5 := bmecat12.HandlerFuncs{
+		OnArticle: func(*bmecat12.Article) error {
+			return &os.PathError{Op: "open", Path: "image.jpg", Err: os.ErrNotExist}
+		},
+	}
+	err = bmecat12.NewReader(f).Do(context.Background(), bmecat12.MultiHandler(h3, h5))
+	var merr bmecat12.MultiError
+	if !errors.As(err, &merr) {
+		t.Fatalf("want MultiError, have %T", err)
+	}
+	var perr *os.PathError
+	if !errors.As(err, &perr) {
+		t.Fatalf("want PathError in %v", err)
+	}
+	if want, have := "image.jpg", perr.Path; want != have {
+		t.Fatalf("want Path=%q, have %q", want, have)
+	}
+	if !errors.Is(err, errFail) || !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("want errors.Is to match the errors of all handlers, have %v", err)
+	}
+}
+
+func TestReadStopsOnHeaderError(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var n int
+	h := bmecat12.HandlerFuncs{
+		OnHeader: func(*bmecat12.Header) error {
+			return errors.New("invalid header")
+		},
+		OnArticle: func(*bmecat12.Article) error {
+			n++
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(f).Do(context.Background(), h); err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if want, have := 0, n; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+}
+
+func TestReadWithMaxArticleSize(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var (
+		aids    []string
+		skipped []string
+		size    int64
+	)
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			aids = append(aids, a.SupplierAID)
+			return nil
+		},
+		OnSkippedArticle: func(supplierAID string, n int64) error {
+			skipped = append(skipped, supplierAID)
+			size = n
+			return nil
+		},
+	}
+	r := bmecat12.NewReader(f, bmecat12.WithMaxArticleSize(500))
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if want, have := "1000", strings.Join(skipped, ","); want != have {
+		t.Fatalf("want skipped articles %s, have %s", want, have)
+	}
+	if size <= 500 {
+		t.Fatalf("want size > 500, have %d", size)
+	}
+}
+
+func TestReadWithEntityMap(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Gr&uuml;&szlig;e &amp; &brand;</DESCRIPTION_SHORT></ARTICLE_DETAILS>
+    </ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+	err := bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), &testHandler{})
+	if err == nil {
+		t.Fatal("want error without entity map, have nil")
+	}
+
+	var descr string
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			descr = a.Details.DescriptionShort
+			return nil
+		},
+	}
+	r := bmecat12.NewReader(
+		strings.NewReader(doc),
+		bmecat12.WithEntityMap(bmecat12.HTMLEntityMap()),
+		bmecat12.WithEntityMap(map[string]string{"brand": "ACME"}),
+	)
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "Grüße & ACME", descr; want != have {
+		t.Fatalf("want DescriptionShort=%q, have %q", want, have)
+	}
+}
+
+func TestReadParseError(t *testing.T) {
+	wellFormed := strings.Replace(brokenArticlesDoc, "</PRICE_CURRENCY>", "</PRICE_AMOUNT>", 1)
+	tests := []struct {
+		Doc         string
+		Element     string
+		SupplierAID string
+		Line        int
+	}{
+		{
+			// Invalid number
+			Doc:         strings.Replace(wellFormed, "%s", "UTF-8", 1),
+			Element:     "ARTICLE",
+			SupplierAID: "2000",
+			Line:        17,
+		},
+		{
+			// Invalid number in ISO-8859-1 document
+			Doc:         strings.Replace(wellFormed, "%s", "ISO-8859-1", 1),
+			Element:     "ARTICLE",
+			SupplierAID: "2000",
+			Line:        17,
+		},
+		{
+			// Not well-formed
+			Doc:  strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1),
+			Line: 21,
+		},
+	}
+	for i, tt := range tests {
+		err := bmecat12.NewReader(strings.NewReader(tt.Doc)).Do(context.Background(), &testHandler{})
+		if err == nil {
+			t.Fatalf("#%d: want error, have nil", i)
+		}
+		var perr *bmecat12.ParseError
+		if !errors.As(err, &perr) {
+			t.Fatalf("#%d: want ParseError, have %T: %v", i, err, err)
+		}
+		if want, have := tt.Element, perr.Element; want != have {
+			t.Errorf("#%d: want Element=%q, have %q", i, want, have)
+		}
+		if want, have := tt.SupplierAID, perr.SupplierAID; want != have {
+			t.Errorf("#%d: want SupplierAID=%q, have %q", i, want, have)
+		}
+		if want, have := tt.Line, perr.Line; want != have {
+			t.Errorf("#%d: want Line=%d, have %d (%v)", i, want, have, err)
+		}
+		if perr.Column <= 0 {
+			t.Errorf("#%d: want Column > 0, have %d", i, perr.Column)
+		}
+	}
+}
+
+func TestReadWithMaxArticles(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var aids []string
+	var complete bool
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			aids = append(aids, a.SupplierAID)
+			return nil
+		},
+		OnComplete: func() {
+			complete = true
+		},
+	}
+	if err := bmecat12.NewReader(f, bmecat12.WithMaxArticles(1)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if !complete {
+		t.Fatal("expected OnComplete to be called")
+	}
+}
+
+func TestReadWithArticleFilter(t *testing.T) {
+	f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	h := &testHandler{}
+	r := bmecat12.NewReader(f,
+		bmecat12.WithArticleFilter(func(a *bmecat12.Article) bool {
+			return strings.HasPrefix(a.SupplierAID, "2")
+		}),
+	)
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := "2000", h.articles[0].SupplierAID; want != have {
+		t.Fatalf("want SupplierAID=%q, have %q", want, have)
+	}
+	if want, have := 2, h.header.NumberOfArticles; want != have {
+		t.Fatalf("want NumberOfArticles=%d, have %d", want, have)
+	}
+}
+
+func TestReadWithSkipSections(t *testing.T) {
+	for _, name := range []string{
+		"new_catalog.golden.xml",
+		"update_products.golden.xml",
+		"update_prices.golden.xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			data, err := ioutil.ReadFile(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			full := &testHandler{}
+			if err := bmecat12.NewReader(bytes.NewReader(data)).Do(context.Background(), full); err != nil {
+				t.Fatal(err)
+			}
+			skipped := &testHandler{}
+			r := bmecat12.NewReader(bytes.NewReader(data),
+				bmecat12.WithSkipSections(bmecat12.SkipFeatures|bmecat12.SkipMime|bmecat12.SkipUDX|bmecat12.SkipReferences),
+			)
+			if err := r.Do(context.Background(), skipped); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := len(full.articles), len(skipped.articles); want != have {
+				t.Fatalf("want %d articles, have %d", want, have)
+			}
+			for i, a := range full.articles {
+				a.Features = nil
+				a.MimeInfo = nil
+				a.UDX = nil
+				a.References = nil
+				want, err := xml.Marshal(a)
+				if err != nil {
+					t.Fatal(err)
+				}
+				have, err := xml.Marshal(skipped.articles[i])
+				if err != nil {
+					t.Fatal(err)
+				}
+				if string(want) != string(have) {
+					diffStrings(t, string(want), string(have))
+				}
+			}
+		})
+	}
+}
+
+const duplicateHeaderDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <HEADER>
+    <CATALOG><LANGUAGE>eng</LANGUAGE><CATALOG_ID>CAT2</CATALOG_ID><CATALOG_VERSION>2.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE><SUPPLIER_AID>1000</SUPPLIER_AID></ARTICLE>
+  </T_NEW_CATALOG>
+  <T_UPDATE_PRICES prev_version="1">
+    <ARTICLE><SUPPLIER_AID>2000</SUPPLIER_AID></ARTICLE>
+  </T_UPDATE_PRICES>
+</BMECAT>`
+
+func TestReadDuplicateHeaderAndTransactions(t *testing.T) {
+	t.Run("Strict", func(t *testing.T) {
+		var called bool
+		h := bmecat12.HandlerFuncs{
+			OnHeader: func(*bmecat12.Header) error {
+				called = true
+				return nil
+			},
+		}
+		err := bmecat12.NewReader(strings.NewReader(duplicateHeaderDoc)).Do(context.Background(), h)
+		if !errors.Is(err, bmecat12.ErrDuplicateHeader) {
+			t.Fatalf("want ErrDuplicateHeader, have %v", err)
+		}
+		if called {
+			t.Fatal("expected the handler not to be called")
+		}
+
+		doc := strings.Replace(duplicateHeaderDoc, "<HEADER>\n    <CATALOG><LANGUAGE>eng", "<XHEADER>\n    <CATALOG><LANGUAGE>eng", 1)
+		doc = strings.Replace(doc, "</CATALOG>\n  </HEADER>\n  <T_NEW", "</CATALOG>\n  </XHEADER>\n  <T_NEW", 1)
+		err = bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), h)
+		if !errors.Is(err, bmecat12.ErrMultipleTransactions) {
+			t.Fatalf("want ErrMultipleTransactions, have %v", err)
+		}
+		if want, have := "T_UPDATE_PRICES after T_NEW_CATALOG", err.Error(); !strings.Contains(have, want) {
+			t.Fatalf("want error to contain %q, have %q", want, have)
+		}
+	})
+
+	t.Run("Lenient", func(t *testing.T) {
+		var catalogIDs, aids, warnings []string
+		var txs []bmecat12.Transaction
+		h := bmecat12.HandlerFuncs{
+			OnHeader: func(hdr *bmecat12.Header) error {
+				catalogIDs = append(catalogIDs, hdr.Catalog.ID)
+				if want, have := 1, hdr.NumberOfArticles; want != have {
+					t.Errorf("want NumberOfArticles=%d, have %d", want, have)
+				}
+				return nil
+			},
+			OnTransaction: func(tx bmecat12.Transaction, prevVersion int) error {
+				txs = append(txs, tx)
+				return nil
+			},
+			OnArticle: func(a *bmecat12.Article) error {
+				aids = append(aids, a.SupplierAID)
+				return nil
+			},
+			OnWarning: func(w *bmecat12.Warning) error {
+				warnings = append(warnings, w.Path)
+				return nil
+			},
+		}
+		r := bmecat12.NewReader(strings.NewReader(duplicateHeaderDoc),
+			bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+		)
+		if err := r.Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "CAT1", strings.Join(catalogIDs, ","); want != have {
+			t.Fatalf("want headers %s, have %s", want, have)
+		}
+		if want, have := 1, len(txs); want != have || txs[0] != bmecat12.NewCatalog {
+			t.Fatalf("want %d transaction NewCatalog, have %v", want, txs)
+		}
+		if want, have := "1000", strings.Join(aids, ","); want != have {
+			t.Fatalf("want articles %s, have %s", want, have)
+		}
+		if want, have := "HEADER,T_UPDATE_PRICES", strings.Join(warnings, ","); want != have {
+			t.Fatalf("want warnings %s, have %s", want, have)
+		}
+	})
+}
+
+func TestReadWithArticlePool(t *testing.T) {
+	// Alternate full and sparse articles, so that stale fields of a
+	// released article would show up in the next one.
+	full := directTestArticles()[1]
+	sparse := &bmecat12.Article{
+		Details: &bmecat12.ArticleDetails{
+			DescriptionShort: "Sparse",
+			BuyerAIDs:        []*bmecat12.BuyerAID{{Value: "1"}, {Value: "2"}},
+		},
+		Features: []*bmecat12.ArticleFeatures{{}, {}, {Features: []*bmecat12.Feature{{Name: "A"}, {Name: "B"}}}},
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{},
+			{Prices: []*bmecat12.ArticlePrice{{}, {Amount: bmecat12.MustParseDecimal("1")}}},
+		},
+		MimeInfo:   &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{{}, {}, {Source: "b.jpg"}}},
+		References: []*bmecat12.ArticleReference{{}, {ArtIDTo: "1"}, {ArtIDTo: "2"}},
+	}
+	var articles []*bmecat12.Article
+	for i := 0; i < 50; i++ {
+		a := *full
+		if i%2 == 1 {
+			a = *sparse
+		}
+		a.SupplierAID = fmt.Sprint(i)
+		articles = append(articles, &a)
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: articles,
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithSkipModeValidation()).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	doc := buf.Bytes()
+
+	read := func(options ...bmecat12.ReaderOption) string {
+		var articles []string
+		h := bmecat12.HandlerFuncs{
+			OnArticle: func(a *bmecat12.Article) error {
+				buf, err := xml.Marshal(a)
+				if err != nil {
+					return err
+				}
+				articles = append(articles, string(buf))
+				a.Release()
+				return nil
+			},
+		}
+		if err := bmecat12.NewReader(bytes.NewReader(doc), options...).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		return strings.Join(articles, "\n")
+	}
+
+	for name, options := range map[string][]bmecat12.ReaderOption{
+		"Default":     nil,
+		"Concurrency": {bmecat12.WithConcurrency(4)},
+		"Fast":        {bmecat12.WithFastDecoder()},
+		"SkipMime":    {bmecat12.WithSkipSections(bmecat12.SkipMime)},
+	} {
+		want := read(options...)
+		if have := read(append(options, bmecat12.WithArticlePool())...); want != have {
+			t.Fatalf("%s: want the same articles with WithArticlePool", name)
+		}
+	}
+}
+
+func TestReadWithArticlePoolMultiHandler(t *testing.T) {
+	doc := concurrencyCatalog(t, 10)
+
+	var ids []string
+	release := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			a.Release()
+			return nil
+		},
+	}
+	collect := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			ids = append(ids, a.SupplierAID)
+			a.Release()
+			return nil
+		},
+	}
+	h := bmecat12.MultiHandler(release, collect)
+	if err := bmecat12.NewReader(bytes.NewReader(doc), bmecat12.WithArticlePool()).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 10, len(ids); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	for _, id := range ids {
+		if id == "" {
+			t.Fatal("want the article to be released after the last handler")
+		}
+	}
+}
+
+func BenchmarkReaderWithArticlePool(b *testing.B) {
+	b.ReportAllocs()
+
+	buf, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	buffer := strings.NewReader(string(buf))
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			a.Release()
+			return nil
+		},
+	}
+
+	for i := 0; i < b.N; i++ {
+		if _, err := buffer.Seek(0, io.SeekStart); err != nil {
+			b.Fatal(err)
+		}
+		if err := bmecat12.NewReader(buffer, bmecat12.WithArticlePool()).Do(context.Background(), h); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkReaderArticlePool(b *testing.B) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: concurrencyTestArticles(2000),
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithIndent("  "), bmecat12.WithSkipValidation()).Do(context.Background(), cw); err != nil {
+		b.Fatal(err)
+	}
+	doc := buf.Bytes()
+
+	for _, bm := range []struct {
+		Name    string
+		Options []bmecat12.ReaderOption
+	}{
+		{Name: "Default"},
+		{Name: "Pool", Options: []bmecat12.ReaderOption{bmecat12.WithArticlePool()}},
+		{Name: "Fast", Options: []bmecat12.ReaderOption{bmecat12.WithFastDecoder()}},
+		{Name: "FastPool", Options: []bmecat12.ReaderOption{bmecat12.WithFastDecoder(), bmecat12.WithArticlePool()}},
+	} {
+		b.Run(bm.Name, func(b *testing.B) {
+			b.ReportAllocs()
+			h := bmecat12.HandlerFuncs{
+				OnArticle: func(a *bmecat12.Article) error {
+					a.Release()
+					return nil
+				},
+			}
+			for i := 0; i < b.N; i++ {
+				if err := bmecat12.NewReader(bytes.NewReader(doc), bm.Options...).Do(context.Background(), h); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func TestReadWithRawArticles(t *testing.T) {
+	for _, options := range [][]bmecat12.ReaderOption{
+		{bmecat12.WithRawArticles()},
+		{bmecat12.WithRawArticles(), bmecat12.WithConcurrency(2)},
+	} {
+		f, err := os.Open(filepath.Join("testdata", "update_products.golden.xml"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer f.Close()
+
+		var n int
+		h := bmecat12.HandlerFuncs{
+			OnArticle: func(a *bmecat12.Article) error {
+				n++
+				raw := string(a.RawXML)
+				if !strings.HasPrefix(raw, "<ARTICLE") || !strings.HasSuffix(raw, "</ARTICLE>") {
+					t.Fatalf("want raw XML of ARTICLE, have %q", raw)
+				}
+				var b bmecat12.Article
+				if err := xml.Unmarshal(a.RawXML, &b); err != nil {
+					t.Fatal(err)
+				}
+				if want, have := a.SupplierAID, b.SupplierAID; want != have {
+					t.Fatalf("want SUPPLIER_AID %q, have %q", want, have)
+				}
+				return nil
+			},
+		}
+		if err := bmecat12.NewReader(f, options...).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 2, n; want != have {
+			t.Fatalf("want %d articles, have %d", want, have)
+		}
+	}
+}
+
+func TestReadArticleOffsets(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, options := range [][]bmecat12.ReaderOption{
+		nil,
+		{bmecat12.WithConcurrency(2)},
+	} {
+		var aids []string
+		h := bmecat12.HandlerFuncs{
+			OnArticleOffset: func(supplierAID string, start, end int64) error {
+				aids = append(aids, supplierAID)
+				raw := string(data[start:end])
+				if !strings.HasPrefix(raw, "<ARTICLE") || !strings.HasSuffix(raw, "</ARTICLE>") {
+					t.Fatalf("want ARTICLE element at offsets %d-%d, have %q", start, end, raw)
+				}
+				if !strings.Contains(raw, "<SUPPLIER_AID>"+supplierAID+"</SUPPLIER_AID>") {
+					t.Fatalf("want ARTICLE %s at offsets %d-%d, have %q", supplierAID, start, end, raw)
+				}
+				return nil
+			},
+		}
+		if err := bmecat12.NewReader(bytes.NewReader(data), options...).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+			t.Fatalf("want articles %s, have %s", want, have)
+		}
+	}
+}
+
+func TestReadWithReaderProgressInfo(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var reports []bmecat12.ReaderProgressInfo
+	r := bmecat12.NewReader(bytes.NewReader(data), bmecat12.WithReaderProgressInfo(func(p bmecat12.ReaderProgressInfo) {
+		reports = append(reports, p)
+	}))
+	if err := r.Do(context.Background(), &testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	passes := make(map[int]bool)
+	for _, p := range reports {
+		passes[p.Pass] = true
+		if want, have := int64(len(data)), p.Size; want != have {
+			t.Fatalf("want Size=%d, have %d", want, have)
+		}
+		if p.Percent < 0 || p.Percent > 100 {
+			t.Fatalf("want Percent between 0 and 100, have %v", p.Percent)
+		}
+		if p.Pass == 2 {
+			if want, have := 2, p.TotalArticles; want != have {
+				t.Fatalf("want TotalArticles=%d, have %d", want, have)
+			}
+		}
+	}
+	if !passes[1] || !passes[2] {
+		t.Fatalf("want reports for both passes, have %+v", reports)
+	}
+}
+
+func TestReadStats(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+		bmecat12.WithArticleFilter(func(a *bmecat12.Article) bool { return a.SupplierAID != "4000" }),
+	)
+	if err := r.Do(context.Background(), &testHandler{}); err != nil {
+		t.Fatal(err)
+	}
+	stats := r.Stats()
+	if want, have := 4, stats.Articles; want != have {
+		t.Fatalf("want Articles=%d, have %d", want, have)
+	}
+	if want, have := 1, stats.ArticlesHandled; want != have {
+		t.Fatalf("want ArticlesHandled=%d, have %d", want, have)
+	}
+	if want, have := 1, stats.ArticlesFiltered; want != have {
+		t.Fatalf("want ArticlesFiltered=%d, have %d", want, have)
+	}
+	if want, have := 2, stats.ArticlesFailed; want != have {
+		t.Fatalf("want ArticlesFailed=%d, have %d", want, have)
+	}
+	if stats.Elapsed() <= 0 {
+		t.Fatalf("want Elapsed > 0, have %v", stats.Elapsed())
+	}
+}
+
+func TestReadCompletionStats(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	r := bmecat12.NewReader(strings.NewReader(doc),
+		bmecat12.WithContinueOnError(func(error, int64, []byte) bool { return true }),
+	)
+	var stats *bmecat12.CompletionStats
+	var completed bool
+	h := bmecat12.HandlerFuncs{
+		OnComplete: func() {
+			completed = true
+		},
+		OnCompleteStats: func(s *bmecat12.CompletionStats) {
+			stats = s
+		},
+	}
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if stats == nil {
+		t.Fatal("expected OnCompleteStats to be called")
+	}
+	if completed {
+		t.Fatal("expected OnComplete not to be called")
+	}
+	if want, have := 4, stats.Articles; want != have {
+		t.Fatalf("want Articles=%d, have %d", want, have)
+	}
+	if want, have := 2, stats.ArticlesHandled; want != have {
+		t.Fatalf("want ArticlesHandled=%d, have %d", want, have)
+	}
+	if want, have := 2, stats.ArticlesFailed; want != have {
+		t.Fatalf("want ArticlesFailed=%d, have %d", want, have)
+	}
+	if want, have := 4, stats.Elements["ARTICLE"]; want != have {
+		t.Fatalf("want %d ARTICLE elements, have %d", want, have)
+	}
+	if stats.Duration < stats.Elapsed() {
+		t.Fatalf("want Duration >= %v, have %v", stats.Elapsed(), stats.Duration)
+	}
+}
+
+func TestReadWithByteOrderMark(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16 := bytes.Replace(data, []byte(`encoding="UTF-8"`), []byte(`encoding="UTF-16"`), 1)
+	encode := func(e encoding.Encoding, data []byte) []byte {
+		out, err := e.NewEncoder().Bytes(data)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return out
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"UTF-8 with BOM", append([]byte{0xef, 0xbb, 0xbf}, data...)},
+		{"UTF-16LE with BOM", encode(unicode.UTF16(unicode.LittleEndian, unicode.UseBOM), utf16)},
+		{"UTF-16BE with BOM", encode(unicode.UTF16(unicode.BigEndian, unicode.UseBOM), utf16)},
+		{"UTF-16LE", encode(unicode.UTF16(unicode.LittleEndian, unicode.IgnoreBOM), utf16)},
+	}
+	for _, tt := range tests {
+		h := &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(tt.data)).Do(context.Background(), h); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if h.header == nil {
+			t.Fatalf("%s: want HEADER, have nil", tt.name)
+		}
+		var aids []string
+		for _, a := range h.articles {
+			aids = append(aids, a.SupplierAID)
+		}
+		if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+			t.Fatalf("%s: want articles %s, have %s", tt.name, want, have)
+		}
+
+		// Resume after the first article
+		cp := crashAfterFirstArticle(t, bytes.NewReader(tt.data))
+		h = &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(tt.data), bmecat12.WithCheckpoint(cp)).Do(context.Background(), h); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if want, have := 1, len(h.articles); want != have {
+			t.Fatalf("%s: want len(articles) = %d, have %d", tt.name, want, have)
+		}
+		if want, have := "2000", h.articles[0].SupplierAID; want != have {
+			t.Fatalf("%s: want SupplierAID=%q, have %q", tt.name, want, have)
+		}
+	}
+}
+
+func TestReadWithCodePages(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const description = "Дрель аккумуляторная"
+	data = bytes.Replace(data, []byte(`Apple MacBook Pro 13&#34;`), []byte(description), 1)
+
+	bmecat12.RegisterCharset("X-Test-Cyrillic", charmap.ISO8859_5)
+	tests := []struct {
+		name string
+		enc  encoding.Encoding
+	}{
+		{"windows-1251", charmap.Windows1251},
+		{"KOI8-R", charmap.KOI8R},
+		{"x-test-cyrillic", charmap.ISO8859_5},
+	}
+	for _, tt := range tests {
+		doc := bytes.Replace(data, []byte(`encoding="UTF-8"`), []byte(`encoding="`+tt.name+`"`), 1)
+		doc, err := tt.enc.NewEncoder().Bytes(doc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := &testHandler{}
+		if err := bmecat12.NewReader(bytes.NewReader(doc)).Do(context.Background(), h); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if want, have := 2, len(h.articles); want != have {
+			t.Fatalf("%s: want len(articles) = %d, have %d", tt.name, want, have)
+		}
+		if want, have := description, h.articles[0].Details.DescriptionShort; want != have {
+			t.Fatalf("%s: want DescriptionShort=%q, have %q", tt.name, want, have)
+		}
+	}
+}
+
+func TestReadWithForcedCharset(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	const description = "Bohrmaschine für Holz"
+	data = bytes.Replace(data, []byte(`Apple MacBook Pro 13&#34;`), []byte(description), 1)
+	// Declared as UTF-8, but encoded in ISO-8859-1
+	doc, err := charmap.ISO8859_1.NewEncoder().Bytes(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := bmecat12.NewReader(bytes.NewReader(doc)).Do(context.Background(), &testHandler{}); err == nil {
+		t.Fatal("want error without WithForcedCharset")
+	}
+
+	h := &testHandler{}
+	r := bmecat12.NewReader(bytes.NewReader(doc), bmecat12.WithForcedCharset("iso-8859-1"))
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := description, h.articles[0].Details.DescriptionShort; want != have {
+		t.Fatalf("want DescriptionShort=%q, have %q", want, have)
+	}
+
+	r = bmecat12.NewReader(bytes.NewReader(doc), bmecat12.WithForcedCharset("x-unknown"))
+	if err := r.Do(context.Background(), &testHandler{}); err == nil {
+		t.Fatal("want error for unknown charset")
+	}
+}
+
+func TestReadWithoutCatalogGroupMapping(t *testing.T) {
+	const doc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG><LANGUAGE>deu</LANGUAGE><CATALOG_ID>CAT1</CATALOG_ID><CATALOG_VERSION>1.0</CATALOG_VERSION></CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+    </ARTICLE>
+    <ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>
+    <ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>20</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := 2, len(h.articles[0].CatalogGroupIDs); want != have {
+		t.Fatalf("want len(CatalogGroupIDs) = %d, have %d", want, have)
+	}
+	if want, have := 1, h.header.NumberOfArticleToCatalogGroupMaps; want != have {
+		t.Fatalf("want NumberOfArticleToCatalogGroupMaps = %d, have %d", want, have)
+	}
+
+	h = &testHandler{}
+	r := bmecat12.NewReader(strings.NewReader(doc), bmecat12.WithoutCatalogGroupMapping())
+	if err := r.Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want len(articles) = %d, have %d", want, have)
+	}
+	if want, have := 0, len(h.articles[0].CatalogGroupIDs); want != have {
+		t.Fatalf("want len(CatalogGroupIDs) = %d, have %d", want, have)
+	}
+	if want, have := 0, h.header.NumberOfArticleToCatalogGroupMaps; want != have {
+		t.Fatalf("want NumberOfArticleToCatalogGroupMaps = %d, have %d", want, have)
+	}
+}
@@ -0,0 +1,206 @@
+package bmecat12
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/xml"
+	"io"
+	"strings"
+)
+
+// ContinueOnErrorFunc is the signature of the callback that is invoked
+// when the Reader is unable to decode an ARTICLE element. It gets the
+// error, the byte offset of the article, and the raw XML of the article.
+// If it returns true, the Reader skips the article and continues with
+// the next one; otherwise the Reader stops and returns the error.
+type ContinueOnErrorFunc func(err error, offset int64, rawXML []byte) bool
+
+// WithContinueOnError enables a lenient mode where ARTICLE elements that
+// cannot be decoded, e.g. because they contain invalid numbers or are not
+// well-formed XML, are passed to f instead of aborting the whole import.
+//
+// In lenient mode, a second HEADER element or transaction element, e.g.
+// T_UPDATE_PRICES after T_NEW_CATALOG, is skipped and reported to the
+// WarningHandler; the first one wins. Otherwise the Reader returns a
+// ParseError wrapping ErrDuplicateHeader or ErrMultipleTransactions.
+func WithContinueOnError(f ContinueOnErrorFunc) ReaderOption {
+	return func(r *Reader) {
+		r.continueOnError = f
+	}
+}
+
+// captureReader keeps track of the line and column of the bytes read by
+// an xml.Decoder and, if on, records them. As the decoder reads byte by
+// byte from an io.ByteReader, the position and the recorded bytes match
+// the tokens returned by the decoder.
+type captureReader struct {
+	r      *bufio.Reader
+	buf    []byte
+	on     bool
+	line   int
+	column int
+}
+
+func (c *captureReader) ReadByte() (byte, error) {
+	b, err := c.r.ReadByte()
+	if err == nil {
+		if c.on {
+			c.buf = append(c.buf, b)
+		}
+		if b == '\n' {
+			c.line++
+			c.column = 0
+		} else if b&0xC0 != 0x80 {
+			// Count runes, not UTF-8 continuation bytes
+			c.column++
+		}
+	}
+	return b, err
+}
+
+func (c *captureReader) Read(p []byte) (int, error) {
+	n, err := c.r.Read(p)
+	if c.on {
+		c.buf = append(c.buf, p[:n]...)
+	}
+	return n, err
+}
+
+// rawCapture keeps track of the position in the input and, in lenient
+// mode, of the raw XML of the current element, so that it can be reported
+// and skipped if it cannot be decoded.
+type rawCapture struct {
+	active *captureReader
+	entity map[string]string
+}
+
+// reset discards the bytes recorded so far.
+func (c *rawCapture) reset() {
+	c.active.buf = c.active.buf[:0]
+}
+
+// position returns the 1-based line and column of the last byte read.
+func (c *rawCapture) position() (line, column int) {
+	return c.active.line, c.active.column
+}
+
+// bytes returns a copy of the bytes recorded since the last reset.
+func (c *rawCapture) bytes() []byte {
+	raw := bytes.TrimSpace(c.active.buf)
+	if len(raw) > 0 && raw[0] != '<' {
+		// The decoder has consumed the '<' while reading the preceding token
+		raw = append([]byte{'<'}, raw...)
+	}
+	return append([]byte(nil), raw...)
+}
+
+// skipArticle reads the input up to and including the end of the
+// current ARTICLE element. It returns the number of bytes skipped. If the
+// decoder failed on the end of the ARTICLE element, e.g. in
+// "<DESCRIPTION_SHORT>broken</ARTICLE>", the end is already consumed and
+// nothing is skipped.
+func (c *rawCapture) skipArticle() (int64, error) {
+	const end = "/ARTICLE>"
+	if bytes.HasSuffix(c.active.buf, []byte(end)) {
+		return 0, nil
+	}
+	var n int64
+	var matched int
+	for matched < len(end) {
+		b, err := c.active.ReadByte()
+		if err != nil {
+			return n, err
+		}
+		n++
+		switch {
+		case b == end[matched]:
+			matched++
+		case b == end[0]:
+			matched = 1
+		default:
+			matched = 0
+		}
+	}
+	return n, nil
+}
+
+// decoder returns a new decoder that continues after the skipped ARTICLE
+// element, and the length of the synthetic prefix it starts with. The
+// prefix opens the ancestors of the ARTICLE element, so the decoder
+// accepts their end elements.
+func (c *rawCapture) decoder(ancestors ...string) (*xml.Decoder, int64) {
+	var prefix strings.Builder
+	for _, name := range ancestors {
+		prefix.WriteString("<" + name + ">")
+	}
+	// The input is already converted to UTF-8, so no CharsetReader is needed
+	dec := xml.NewDecoder(&prefixReader{prefix: strings.NewReader(prefix.String()), r: c.active})
+	dec.Entity = c.entity
+	for range ancestors {
+		dec.Token()
+	}
+	return dec, int64(prefix.Len())
+}
+
+// prefixReader reads prefix, then r. It implements io.ByteReader so the
+// decoder does not read ahead of the tokens it returns.
+type prefixReader struct {
+	prefix *strings.Reader
+	r      *captureReader
+}
+
+func (p *prefixReader) ReadByte() (byte, error) {
+	if p.prefix.Len() > 0 {
+		return p.prefix.ReadByte()
+	}
+	return p.r.ReadByte()
+}
+
+func (p *prefixReader) Read(b []byte) (int, error) {
+	if p.prefix.Len() > 0 {
+		return p.prefix.Read(b)
+	}
+	return p.r.Read(b)
+}
+
+// WithRawArticles makes the Reader attach the XML of each ARTICLE element
+// to Article.RawXML, e.g. to pass articles through unchanged or to debug
+// differences between the file and the decoded article.
+func WithRawArticles() ReaderOption {
+	return func(r *Reader) {
+		r.rawArticles = true
+	}
+}
+
+// recordRaw returns true if the raw XML of elements must be recorded, i.e.
+// to recover from errors in ARTICLE elements in lenient mode, to pass
+// ARTICLE elements to the workers of WithConcurrency, or for
+// WithRawArticles.
+func (r *Reader) recordRaw() bool {
+	return r.continueOnError != nil || r.concurrency > 1 || r.rawArticles
+}
+
+// newDecoder creates a new decoder for src. The rawCapture keeps track of
+// the position in the input and, if recordRaw returns true, records the
+// raw XML of the current element.
+func (r *Reader) newDecoder(src io.Reader) (*xml.Decoder, *rawCapture) {
+	record := r.recordRaw()
+	c := &rawCapture{
+		active: &captureReader{r: bufio.NewReader(src), on: record, line: 1},
+		entity: r.entities,
+	}
+	dec := xml.NewDecoder(c.active)
+	dec.Entity = r.entities
+	dec.CharsetReader = func(charset string, input io.Reader) (io.Reader, error) {
+		rd, err := r.charsetReader(charset, input)
+		if err != nil {
+			return nil, err
+		}
+		// Continue with the converted input from now on
+		prev := c.active
+		prev.on = false
+		c.active = &captureReader{r: bufio.NewReader(rd), on: record, line: prev.line, column: prev.column}
+		return c.active, nil
+	}
+	return dec, c
+}
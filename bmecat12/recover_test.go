@@ -0,0 +1,163 @@
+package bmecat12_test
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const brokenArticlesDoc = `<?xml version="1.0" encoding="%s"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+  </HEADER>
+  <T_UPDATE_PRICES prev_version="1">
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>1.5</PRICE_AMOUNT></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>2000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>abc</PRICE_AMOUNT></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>3000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>2.5</PRICE_CURRENCY></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>4000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>3.5</PRICE_AMOUNT></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRICES>
+</BMECAT>`
+
+func TestReadWithContinueOnError(t *testing.T) {
+	for _, encoding := range []string{"UTF-8", "ISO-8859-1"} {
+		t.Run(encoding, func(t *testing.T) {
+			doc := strings.Replace(brokenArticlesDoc, "%s", encoding, 1)
+
+			var aids []string
+			var raws []string
+			var complete bool
+			h := bmecat12.HandlerFuncs{
+				OnArticle: func(a *bmecat12.Article) error {
+					aids = append(aids, a.SupplierAID)
+					return nil
+				},
+				OnComplete: func() {
+					complete = true
+				},
+			}
+			f := func(err error, offset int64, rawXML []byte) bool {
+				if want, have := int64(strings.Index(doc, "<ARTICLE>\n      <SUPPLIER_AID>2000")), offset; len(raws) == 0 && want > have {
+					t.Errorf("want offset around %d, have %d", want, have)
+				}
+				raws = append(raws, string(rawXML))
+				return true
+			}
+			r := bmecat12.NewReader(strings.NewReader(doc), bmecat12.WithContinueOnError(f))
+			if err := r.Do(context.Background(), h); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := "1000,4000", strings.Join(aids, ","); want != have {
+				t.Fatalf("want articles %s, have %s", want, have)
+			}
+			if want, have := 2, len(raws); want != have {
+				t.Fatalf("want %d errors, have %d", want, have)
+			}
+			for i, aid := range []string{"2000", "3000"} {
+				raw := raws[i]
+				if !strings.HasPrefix(raw, "<ARTICLE>") || !strings.HasSuffix(raw, "</ARTICLE>") {
+					t.Errorf("#%d: want raw XML of ARTICLE, have %q", i, raw)
+				}
+				if !strings.Contains(raw, "<SUPPLIER_AID>"+aid+"</SUPPLIER_AID>") {
+					t.Errorf("#%d: want raw XML of ARTICLE %s, have %q", i, aid, raw)
+				}
+			}
+			if !complete {
+				t.Fatal("expected OnComplete to be called")
+			}
+		})
+	}
+}
+
+func TestReadWithContinueOnErrorStops(t *testing.T) {
+	doc := strings.Replace(brokenArticlesDoc, "%s", "UTF-8", 1)
+	var n int
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			n++
+			return nil
+		},
+	}
+	f := func(err error, offset int64, rawXML []byte) bool {
+		return false
+	}
+	r := bmecat12.NewReader(strings.NewReader(doc), bmecat12.WithContinueOnError(f))
+	if err := r.Do(context.Background(), h); err == nil {
+		t.Fatal("want error, have nil")
+	}
+	if want, have := 1, n; want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+}
+
+func TestReadWithContinueOnErrorAtArticleEnd(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <ARTICLE>
+      <SUPPLIER_AID>A1</SUPPLIER_AID>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>A2</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>broken</ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>A3</SUPPLIER_AID>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>A4</SUPPLIER_AID>
+    </ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+	for _, concurrency := range []int{1, 4} {
+		var aids []string
+		var raws []string
+		h := bmecat12.HandlerFuncs{
+			OnArticle: func(a *bmecat12.Article) error {
+				aids = append(aids, a.SupplierAID)
+				return nil
+			},
+		}
+		f := func(err error, offset int64, rawXML []byte) bool {
+			raws = append(raws, string(rawXML))
+			return true
+		}
+		r := bmecat12.NewReader(strings.NewReader(doc), bmecat12.WithContinueOnError(f), bmecat12.WithConcurrency(concurrency))
+		if err := r.Do(context.Background(), h); err != nil {
+			t.Fatalf("concurrency %d: %v", concurrency, err)
+		}
+		if want, have := "A1,A3,A4", strings.Join(aids, ","); want != have {
+			t.Fatalf("concurrency %d: want articles %s, have %s", concurrency, want, have)
+		}
+		if want, have := 1, len(raws); want != have {
+			t.Fatalf("concurrency %d: want %d errors, have %d", concurrency, want, have)
+		}
+		if raw := raws[0]; !strings.Contains(raw, "A2") || strings.Contains(raw, "A3") {
+			t.Fatalf("concurrency %d: want raw XML of A2 only, have %q", concurrency, raw)
+		}
+	}
+}
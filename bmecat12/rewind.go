@@ -0,0 +1,92 @@
+package bmecat12
+
+import (
+	"io"
+	"io/ioutil"
+
+	"github.com/pkg/errors"
+)
+
+// rewindReader emulates seeking on a stream that can only be read from
+// the start, e.g. the decompressed content of a file, by opening the
+// stream again and skipping to the offset. This is cheap for the rewinds
+// of the Reader between its passes, but not for random access.
+type rewindReader struct {
+	// open returns the stream, from the start. If it returns an
+	// io.Closer, it is closed before the stream is opened again.
+	open func() (io.Reader, error)
+	// size is the size of the stream, or -1 if it is unknown. Seeking
+	// relative to the end requires the size.
+	size int64
+
+	r   io.Reader
+	off int64
+}
+
+// newRewindReader returns a rewindReader for the stream returned by open.
+func newRewindReader(open func() (io.Reader, error), size int64) (*rewindReader, error) {
+	rr := &rewindReader{open: open, size: size}
+	if err := rr.reset(); err != nil {
+		return nil, err
+	}
+	return rr, nil
+}
+
+// reset opens the stream again.
+func (rr *rewindReader) reset() error {
+	rr.close()
+	r, err := rr.open()
+	if err != nil {
+		return err
+	}
+	rr.r, rr.off = r, 0
+	return nil
+}
+
+// close closes the stream, if it is an io.Closer.
+func (rr *rewindReader) close() error {
+	c, ok := rr.r.(io.Closer)
+	rr.r = nil
+	if !ok {
+		return nil
+	}
+	return c.Close()
+}
+
+func (rr *rewindReader) Read(p []byte) (int, error) {
+	if rr.r == nil {
+		return 0, errors.New("bmecat: read from closed stream")
+	}
+	n, err := rr.r.Read(p)
+	rr.off += int64(n)
+	return n, err
+}
+
+func (rr *rewindReader) Seek(offset int64, whence int) (int64, error) {
+	var target int64
+	switch whence {
+	case io.SeekStart:
+		target = offset
+	case io.SeekCurrent:
+		target = rr.off + offset
+	case io.SeekEnd:
+		if rr.size < 0 {
+			return rr.off, errors.New("bmecat: unable to seek relative to the end of a stream of unknown size")
+		}
+		target = rr.size + offset
+	}
+	if target < 0 {
+		return rr.off, errors.New("bmecat: negative offset")
+	}
+	if target < rr.off || rr.r == nil {
+		if err := rr.reset(); err != nil {
+			return rr.off, err
+		}
+	}
+	if target > rr.off {
+		if _, err := io.CopyN(ioutil.Discard, rr, target-rr.off); err != nil {
+			return rr.off, err
+		}
+	}
+	return rr.off, nil
+}
@@ -0,0 +1,204 @@
+package bmecat12
+
+import (
+	"reflect"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// SanitizeReport lists the fields of an element that contained
+// characters outside the XML 1.0 character range, see WithSanitize.
+type SanitizeReport struct {
+	// Element is the element sanitized, e.g. "ARTICLE" or "HEADER".
+	Element string
+	// SupplierAID is the SUPPLIER_AID of the article, if any.
+	SupplierAID string
+	// Paths are the paths of the fields, e.g. "ARTICLE/ARTICLE_DETAILS/
+	// DESCRIPTION_LONG" or "ARTICLE/USER_DEFINED_EXTENSIONS/UDX.COLOR".
+	Paths []string
+	// Chars is the number of characters stripped or replaced.
+	Chars int
+}
+
+// WithSanitize makes the Writer replace characters outside the XML 1.0
+// character range in all string fields, e.g. the control characters
+// 0x0B and 0x1F often found in data from ERP systems, by replacement.
+// Pass an empty replacement to strip them. Without the option, the
+// characters are written as U+FFFD. The header, the catalog structure,
+// and the articles are not modified.
+//
+// If report is not nil, it is called for each element with such
+// characters, e.g. to log the affected articles.
+func WithSanitize(replacement string, report func(*SanitizeReport)) WriterOption {
+	return func(w *Writer) {
+		w.sanitize = &sanitizer{replacement: replacement, report: report}
+	}
+}
+
+// sanitizer replaces illegal XML characters, see WithSanitize.
+type sanitizer struct {
+	replacement string
+	report      func(*SanitizeReport)
+}
+
+// sanitizeElement returns v, a pointer to the element with the given
+// name, with the illegal characters of its string fields replaced. v is
+// not modified; if nothing changes, v is returned as is.
+func (w *Writer) sanitizeElement(v interface{}, element, supplierAID string) interface{} {
+	if w.sanitize == nil {
+		return v
+	}
+	report := &SanitizeReport{Element: element, SupplierAID: supplierAID}
+	sanitized, changed := w.sanitize.value(reflect.ValueOf(v), element, report)
+	if !changed {
+		return v
+	}
+	if w.sanitize.report != nil {
+		w.sanitize.report(report)
+	}
+	return sanitized.Interface()
+}
+
+var udxFieldType = reflect.TypeOf(UserDefinedExtensionField{})
+
+// value returns v with the illegal characters of its strings replaced,
+// and true if anything changed. Changed structs, slices, and the
+// pointers to them are copied; v is not modified.
+func (s *sanitizer) value(v reflect.Value, path string, report *SanitizeReport) (reflect.Value, bool) {
+	switch v.Kind() {
+	case reflect.String:
+		str, n := sanitizeString(v.String(), s.replacement)
+		if n == 0 {
+			return v, false
+		}
+		report.Paths = append(report.Paths, path)
+		report.Chars += n
+		out := reflect.New(v.Type()).Elem()
+		out.SetString(str)
+		return out, true
+
+	case reflect.Ptr:
+		if v.IsNil() {
+			return v, false
+		}
+		elem, changed := s.value(v.Elem(), path, report)
+		if !changed {
+			return v, false
+		}
+		out := reflect.New(v.Type().Elem())
+		out.Elem().Set(elem)
+		return out, true
+
+	case reflect.Slice:
+		var out reflect.Value
+		for i := 0; i < v.Len(); i++ {
+			elem, changed := s.value(v.Index(i), indexPath(path, i), report)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+				reflect.Copy(out, v)
+			}
+			out.Index(i).Set(elem)
+		}
+		return out, out.IsValid()
+
+	case reflect.Struct:
+		t := v.Type()
+		if t == udxFieldType {
+			path = path[:strings.LastIndex(path, "[")] + "/UDX." + v.FieldByName("Name").String()
+		}
+		var out reflect.Value
+		for i := 0; i < t.NumField(); i++ {
+			f := t.Field(i)
+			if f.PkgPath != "" {
+				continue // unexported
+			}
+			field, changed := s.value(v.Field(i), fieldPath(path, f), report)
+			if !changed {
+				continue
+			}
+			if !out.IsValid() {
+				out = reflect.New(t).Elem()
+				out.Set(v)
+			}
+			out.Field(i).Set(field)
+		}
+		if out.IsValid() && t == udxFieldType {
+			// Keep passing the sanitized InnerXML through
+			if orig := v.Interface().(UserDefinedExtensionField); orig.passInnerXML() {
+				field := out.Addr().Interface().(*UserDefinedExtensionField)
+				field.readValue = field.Value
+			}
+		}
+		return out, out.IsValid()
+	}
+	return v, false
+}
+
+// fieldPath returns the path of the struct field f below path, as given
+// by its xml struct tag.
+func fieldPath(path string, f reflect.StructField) string {
+	tag := f.Tag.Get("xml")
+	name, flags := tag, ""
+	if i := strings.Index(tag, ","); i >= 0 {
+		name, flags = tag[:i], tag[i+1:]
+	}
+	switch {
+	case name == "-", flags == "chardata", flags == "innerxml", flags == "cdata":
+		return path
+	case strings.HasPrefix(flags, "attr"):
+		if name == "" {
+			name = f.Name
+		}
+		return path + "@" + name
+	case name == "":
+		if f.Anonymous {
+			return path
+		}
+		name = f.Name
+	}
+	return path + "/" + strings.Replace(name, ">", "/", -1)
+}
+
+// indexPath returns the path of the i-th element of the slice at path.
+func indexPath(path string, i int) string {
+	return path + "[" + strconv.Itoa(i) + "]"
+}
+
+// sanitizeString returns s with the characters outside the XML 1.0
+// character range and invalid UTF-8 replaced by replacement, and the
+// number of characters replaced.
+func sanitizeString(s, replacement string) (string, int) {
+	var b strings.Builder
+	var n int
+	for i := 0; i < len(s); {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if isXMLChar(r) && (r != utf8.RuneError || size > 1) {
+			if n > 0 {
+				b.WriteString(s[i : i+size])
+			}
+		} else {
+			if n == 0 {
+				b.WriteString(s[:i])
+			}
+			b.WriteString(replacement)
+			n++
+		}
+		i += size
+	}
+	if n == 0 {
+		return s, 0
+	}
+	return b.String(), n
+}
+
+// isXMLChar returns true if r is in the character range of XML 1.0.
+func isXMLChar(r rune) bool {
+	return r == 0x09 || r == 0x0A || r == 0x0D ||
+		r >= 0x20 && r <= 0xD7FF ||
+		r >= 0xE000 && r <= 0xFFFD ||
+		r >= 0x10000 && r <= 0x10FFFF
+}
@@ -0,0 +1,97 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithSanitize(t *testing.T) {
+	a := &bmecat12.Article{
+		SupplierAID: "1000",
+		Details: &bmecat12.ArticleDetails{
+			DescriptionShort: "Kabel\x0b 5m",
+			DescriptionLong:  "Ohne Fehler",
+		},
+		Features: []*bmecat12.ArticleFeatures{
+			{Features: []*bmecat12.Feature{{Name: "Farbe", Values: []string{"rot", "gr\x1fün"}}}},
+		},
+		UDX: &bmecat12.UserDefinedExtensions{Fields: bmecat12.UserDefinedExtensionFields{
+			{Name: "COLOR", Value: "blau\x00"},
+		}},
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{a, {SupplierAID: "2000"}},
+	}
+
+	var reports []*bmecat12.SanitizeReport
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf, bmecat12.WithSanitize(" ", func(r *bmecat12.SanitizeReport) {
+		reports = append(reports, r)
+	}))
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(reports); want != have {
+		t.Fatalf("want %d reports, have %d", want, have)
+	}
+	r := reports[0]
+	if want, have := "1000", r.SupplierAID; want != have {
+		t.Fatalf("want SupplierAID=%q, have %q", want, have)
+	}
+	if want, have := 3, r.Chars; want != have {
+		t.Fatalf("want Chars=%d, have %d", want, have)
+	}
+	wantPaths := strings.Join([]string{
+		"ARTICLE/ARTICLE_DETAILS/DESCRIPTION_SHORT",
+		"ARTICLE/ARTICLE_FEATURES[0]/FEATURE[0]/FVALUE[1]",
+		"ARTICLE/USER_DEFINED_EXTENSIONS/UDX.COLOR",
+	}, ", ")
+	if want, have := wantPaths, strings.Join(r.Paths, ", "); want != have {
+		t.Fatalf("want Paths=%q, have %q", want, have)
+	}
+
+	// The article passed is not modified
+	if want, have := "Kabel\x0b 5m", a.Details.DescriptionShort; want != have {
+		t.Fatalf("want DESCRIPTION_SHORT=%q, have %q", want, have)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	have := h.articles[0]
+	if want, have := "Kabel  5m", have.Details.DescriptionShort; want != have {
+		t.Fatalf("want DESCRIPTION_SHORT=%q, have %q", want, have)
+	}
+	if want, have := "gr ün", have.Features[0].Features[0].Values[1]; want != have {
+		t.Fatalf("want FVALUE=%q, have %q", want, have)
+	}
+}
+
+func TestWriteWithSanitizeStrip(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: "\x01Kabel\xff"}},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithSanitize("", nil)).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<DESCRIPTION_SHORT>Kabel</DESCRIPTION_SHORT>"; !strings.Contains(buf.String(), want) {
+		t.Fatalf("want %s in\n%s", want, buf.String())
+	}
+}
@@ -0,0 +1,292 @@
+package bmecat12
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"time"
+)
+
+// ShardFunc creates the output for the file with the given index. The
+// file with index 0 holds the HEADER and the catalog structure, the
+// files starting with index 1 hold the articles.
+type ShardFunc func(index int) (io.WriteCloser, error)
+
+// ShardHeaderMode specifies which files of a ShardedWriter get a HEADER.
+type ShardHeaderMode int
+
+const (
+	// ShardHeaderOnce writes the HEADER only to the first file, along
+	// with the catalog structure. This is the default.
+	ShardHeaderOnce ShardHeaderMode = iota
+	// ShardHeaderEach repeats the HEADER in every file, so that each
+	// file is a valid BMEcat document on its own.
+	ShardHeaderEach
+)
+
+// ShardedWriter writes a catalog as several BMEcat files: a file with
+// the HEADER and the catalog structure, i.e. FEATURE_SYSTEM,
+// CLASSIFICATION_SYSTEM, and CATALOG_GROUP_SYSTEM, followed by files with
+// a limited number of articles each. With WithCatalogGroupMaps, the
+// ARTICLE_TO_CATALOGGROUP_MAP elements of an article are written to the
+// same file as the article; those of a CatalogGroupMapWriter are written
+// to the last file.
+// Use MultiReader to read the files as one catalog.
+type ShardedWriter struct {
+	create      ShardFunc
+	maxArticles int
+	maxBytes    int64
+	headers     ShardHeaderMode
+	combined    bool
+	options     []WriterOption
+	shards      int
+	checksums   [][]byte
+}
+
+// ShardOption is the signature of options to pass into NewShardedWriter.
+type ShardOption func(*ShardedWriter)
+
+// NewShardedWriter creates a new ShardedWriter that creates its files
+// with create. Without WithShardMaxArticles or WithShardMaxBytes, all
+// articles are written to a single file after the header file.
+func NewShardedWriter(create ShardFunc, options ...ShardOption) *ShardedWriter {
+	s := &ShardedWriter{create: create}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// WithShardMaxArticles limits the number of articles per file.
+func WithShardMaxArticles(n int) ShardOption {
+	return func(s *ShardedWriter) {
+		s.maxArticles = n
+	}
+}
+
+// WithShardMaxBytes limits the size of the article files. A file is
+// closed once it has reached n bytes, so it may exceed n by the size of
+// the last article and the closing elements.
+func WithShardMaxBytes(n int64) ShardOption {
+	return func(s *ShardedWriter) {
+		s.maxBytes = n
+	}
+}
+
+// WithShardHeaders specifies which files get a HEADER.
+func WithShardHeaders(mode ShardHeaderMode) ShardOption {
+	return func(s *ShardedWriter) {
+		s.headers = mode
+	}
+}
+
+// WithShardSeparateStructure specifies whether the HEADER and the
+// catalog structure are written to a file of their own, which is the
+// default. If disabled, the first articles are written to the first
+// file along with them, so that together with ShardHeaderEach every
+// file is a complete delivery, e.g. for receivers that cap the size of
+// uploads.
+func WithShardSeparateStructure(enabled bool) ShardOption {
+	return func(s *ShardedWriter) {
+		s.combined = !enabled
+	}
+}
+
+// ShardFiles returns a ShardFunc that creates numbered files named by
+// pattern, which is formatted with the number of the file starting at
+// 1, e.g. "catalog-%03d.xml" for catalog-001.xml, catalog-002.xml, and
+// so on. Existing files are truncated.
+func ShardFiles(pattern string) ShardFunc {
+	return func(index int) (io.WriteCloser, error) {
+		return os.Create(fmt.Sprintf(pattern, index+1))
+	}
+}
+
+// WithWriterOptions specifies the options of the Writer used for each
+// file, e.g. WithIndent or WithProgress. The progress callback reports
+// the number of articles written to all files.
+func WithWriterOptions(options ...WriterOption) ShardOption {
+	return func(s *ShardedWriter) {
+		s.options = append(s.options, options...)
+	}
+}
+
+// Shards returns the number of files written by the last call to Do.
+func (s *ShardedWriter) Shards() int {
+	return s.shards
+}
+
+// Do writes the catalog, just like Writer.Do.
+func (s *ShardedWriter) Do(ctx context.Context, writer CatalogWriter) error {
+	s.shards = 0
+	s.checksums = nil
+	template := NewWriter(nil, s.options...)
+	if err := template.preflight(writer); err != nil {
+		return err
+	}
+	template.startProgress()
+	if template.provenance != nil && template.provenance.Timestamp.IsZero() {
+		// Use the same timestamp in all files
+		p := *template.provenance
+		p.Timestamp = time.Now().UTC()
+		template.provenance = &p
+	}
+
+	// Header and catalog structure
+	w, out, err := s.open(template, writer, true)
+	if err != nil {
+		return err
+	}
+	if writer.Transaction() == NewCatalog {
+		if err := w.writeStructure(writer, nil); err != nil {
+			out.f.Close()
+			return err
+		}
+	}
+	if !s.combined {
+		if err := s.close(w, out, writer); err != nil {
+			return err
+		}
+		w, out = nil, nil
+	}
+
+	// Articles
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	articlesCh, errCh := articles(ctx, writer)
+	defer func() {
+		if out != nil {
+			// Do returns early
+			out.f.Close()
+			w.discardMaps()
+		}
+	}()
+	var inShard, written int
+	for articlesCh != nil {
+		select {
+		case a, ok := <-articlesCh:
+			if !ok {
+				articlesCh = nil
+				break
+			}
+			if skip, err := template.validateArticle(writer.Transaction(), a); err != nil {
+				return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+			} else if skip {
+				break
+			}
+			if w != nil && s.full(inShard, out.n) {
+				err := s.close(w, out, writer)
+				w, out = nil, nil
+				if err != nil {
+					return err
+				}
+			}
+			if w == nil {
+				if w, out, err = s.open(template, writer, s.headers == ShardHeaderEach); err != nil {
+					return err
+				}
+				inShard = 0
+			}
+			if err := w.writeArticle(a); err != nil {
+				return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+			}
+			if err := w.reportProgress("ARTICLE", true, false); err != nil {
+				return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+			}
+			if s.maxBytes > 0 {
+				// Count the bytes of the article
+				if err := w.flush(); err != nil {
+					return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+				}
+			}
+			if template.instr != nil {
+				template.instr.ArticleWritten()
+			}
+			inShard++
+			written++
+			if template.progress != nil {
+				template.progress(written)
+			}
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+			articlesCh = nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	if w != nil {
+		if err := w.flushRaw(); err != nil {
+			return err
+		}
+		if err := w.writeCatalogGroupMaps(ctx, writer); err != nil {
+			return err
+		}
+		err := s.close(w, out, writer)
+		w, out = nil, nil
+		return err
+	}
+	return nil
+}
+
+// full returns true if the current file has reached its limits.
+func (s *ShardedWriter) full(articles int, bytes int64) bool {
+	if s.maxArticles > 0 && articles >= s.maxArticles {
+		return true
+	}
+	return s.maxBytes > 0 && bytes >= s.maxBytes
+}
+
+// open creates the next file and starts the document.
+func (s *ShardedWriter) open(template *Writer, writer CatalogWriter, withHeader bool) (*Writer, *shardOutput, error) {
+	f, err := s.create(s.shards)
+	if err != nil {
+		return nil, nil, err
+	}
+	s.shards++
+	out := &shardOutput{f: f, bw: bufio.NewWriter(f)}
+	w := *template
+	w.w = out
+	if err := w.begin(writer, withHeader); err != nil {
+		f.Close()
+		return nil, nil, err
+	}
+	return &w, out, nil
+}
+
+// close ends the document and closes the file.
+func (s *ShardedWriter) close(w *Writer, out *shardOutput, writer CatalogWriter) error {
+	if err := w.end(writer); err != nil {
+		out.f.Close()
+		return err
+	}
+	if w.newHash != nil {
+		s.checksums = append(s.checksums, w.checksum)
+	}
+	return out.Close()
+}
+
+// shardOutput counts the bytes written to a file.
+type shardOutput struct {
+	f  io.WriteCloser
+	bw *bufio.Writer
+	n  int64
+}
+
+func (o *shardOutput) Write(p []byte) (int, error) {
+	n, err := o.bw.Write(p)
+	o.n += int64(n)
+	return n, err
+}
+
+// Close flushes the output and closes the file.
+func (o *shardOutput) Close() error {
+	if err := o.bw.Flush(); err != nil {
+		o.f.Close()
+		return err
+	}
+	return o.f.Close()
+}
@@ -0,0 +1,172 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// nopCloser is an in-memory output file for the ShardedWriter.
+type nopCloser struct {
+	*bytes.Buffer
+}
+
+func (nopCloser) Close() error { return nil }
+
+func TestShardedWriter(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+	}
+	for i := 1; i <= 5; i++ {
+		cw.articles = append(cw.articles, &bmecat12.Article{
+			SupplierAID:     fmt.Sprintf("%d000", i),
+			CatalogGroupIDs: []string{"10"},
+		})
+	}
+
+	for _, mode := range []bmecat12.ShardHeaderMode{bmecat12.ShardHeaderOnce, bmecat12.ShardHeaderEach} {
+		var files []*bytes.Buffer
+		create := func(index int) (io.WriteCloser, error) {
+			if want, have := len(files), index; want != have {
+				t.Fatalf("want index %d, have %d", want, have)
+			}
+			buf := &bytes.Buffer{}
+			files = append(files, buf)
+			return nopCloser{buf}, nil
+		}
+		var progress int
+		s := bmecat12.NewShardedWriter(create,
+			bmecat12.WithShardMaxArticles(2),
+			bmecat12.WithShardHeaders(mode),
+			bmecat12.WithWriterOptions(
+				bmecat12.WithProgress(func(written int) { progress = written }),
+				bmecat12.WithCatalogGroupMaps(),
+			),
+		)
+		if err := s.Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 4, s.Shards(); want != have {
+			t.Fatalf("want %d shards, have %d", want, have)
+		}
+		if want, have := 5, progress; want != have {
+			t.Fatalf("want progress %d, have %d", want, have)
+		}
+		for i, f := range files[1:] {
+			if want, have := mode == bmecat12.ShardHeaderEach, strings.Contains(f.String(), "<HEADER>"); want != have {
+				t.Fatalf("file %d: want HEADER=%v, have %v", i+1, want, have)
+			}
+		}
+
+		var sources []io.ReadSeeker
+		for _, f := range files {
+			sources = append(sources, bytes.NewReader(f.Bytes()))
+		}
+		var numArticles int
+		var articles []string
+		h := bmecat12.HandlerFuncs{
+			OnHeader: func(hdr *bmecat12.Header) error {
+				numArticles = hdr.NumberOfArticles
+				return nil
+			},
+			OnArticle: func(a *bmecat12.Article) error {
+				articles = append(articles, a.SupplierAID+":"+strings.Join(a.CatalogGroupIDs, ","))
+				return nil
+			},
+		}
+		if err := bmecat12.NewMultiReader(sources).Do(context.Background(), h); err != nil {
+			t.Fatal(err)
+		}
+		if want, have := 5, numArticles; want != have {
+			t.Fatalf("want NumberOfArticles=%d, have %d", want, have)
+		}
+		if want, have := "1000:10 2000:10 3000:10 4000:10 5000:10", strings.Join(articles, " "); want != have {
+			t.Fatalf("want articles %q, have %q", want, have)
+		}
+	}
+}
+
+func TestShardedWriterWithMaxBytes(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+	}
+	for i := 1; i <= 10; i++ {
+		cw.articles = append(cw.articles, &bmecat12.Article{SupplierAID: fmt.Sprintf("%d", i)})
+	}
+	var shards int
+	create := func(index int) (io.WriteCloser, error) {
+		shards++
+		return nopCloser{&bytes.Buffer{}}, nil
+	}
+	// Every article exceeds the limit
+	s := bmecat12.NewShardedWriter(create, bmecat12.WithShardMaxBytes(1))
+	if err := s.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 11, shards; want != have {
+		t.Fatalf("want %d shards, have %d", want, have)
+	}
+}
+
+func TestShardedWriterWithoutSeparateStructure(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+	}
+	for i := 1; i <= 5; i++ {
+		cw.articles = append(cw.articles, &bmecat12.Article{SupplierAID: fmt.Sprintf("%d000", i)})
+	}
+	dir, err := ioutil.TempDir("", "bmecat-shard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	s := bmecat12.NewShardedWriter(bmecat12.ShardFiles(filepath.Join(dir, "catalog-%03d.xml")),
+		bmecat12.WithShardMaxArticles(2),
+		bmecat12.WithShardHeaders(bmecat12.ShardHeaderEach),
+		bmecat12.WithShardSeparateStructure(false),
+	)
+	if err := s.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, s.Shards(); want != have {
+		t.Fatalf("want %d shards, have %d", want, have)
+	}
+	wantArticles := []string{"1000 2000", "3000 4000", "5000"}
+	for i, want := range wantArticles {
+		f, err := os.Open(filepath.Join(dir, fmt.Sprintf("catalog-%03d.xml", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		h := &testHandler{}
+		err = bmecat12.NewReader(f).Do(context.Background(), h)
+		f.Close()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if h.header == nil {
+			t.Fatalf("file %d: want HEADER", i+1)
+		}
+		var ids []string
+		for _, a := range h.articles {
+			ids = append(ids, a.SupplierAID)
+		}
+		if have := strings.Join(ids, " "); want != have {
+			t.Fatalf("file %d: want articles %q, have %q", i+1, want, have)
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+)
+
+// SkipSection specifies sub-elements of ARTICLE that the Reader skips
+// instead of decoding them. Use WithSkipSections to set them.
+type SkipSection int
+
+const (
+	// SkipFeatures skips the ARTICLE_FEATURES elements.
+	SkipFeatures SkipSection = 1 << iota
+	// SkipMime skips the MIME_INFO element.
+	SkipMime
+	// SkipUDX skips the USER_DEFINED_EXTENSIONS element.
+	SkipUDX
+	// SkipReferences skips the ARTICLE_REFERENCE elements.
+	SkipReferences
+)
+
+// WithSkipSections tells the Reader to skip the given sub-elements of
+// each ARTICLE entirely, e.g. SkipFeatures|SkipMime for an import that
+// only needs prices. The corresponding fields of Article are left empty.
+func WithSkipSections(s SkipSection) ReaderOption {
+	return func(r *Reader) {
+		r.skipSections = s
+	}
+}
+
+// decodeArticle decodes the ARTICLE element started by se into a,
+// skipping the sub-elements specified in skip. If fast is true, the
+// articleDecoder is used, see WithFastDecoder. Pooled articles are always
+// decoded element by element, so they reuse their nested structs, see
+// WithArticlePool.
+func decodeArticle(dec *xml.Decoder, se *xml.StartElement, a *Article, skip SkipSection, fast bool) error {
+	if fast {
+		d := articleDecoder{dec: dec, skip: skip}
+		return d.article(se, a)
+	}
+	if skip == 0 && !a.pooled {
+		return dec.DecodeElement(a, se)
+	}
+	a.XMLName = se.Name
+	for _, attr := range se.Attr {
+		if attr.Name.Local == "mode" {
+			a.Mode = attr.Value
+		}
+	}
+	for {
+		t, err := dec.Token()
+		if err != nil {
+			return err
+		}
+		switch tok := t.(type) {
+		case xml.EndElement:
+			return nil
+		case xml.StartElement:
+			if err := decodeArticleElement(dec, &tok, a, skip); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+// decodeArticleElement decodes a sub-element of ARTICLE into a, or skips it.
+func decodeArticleElement(dec *xml.Decoder, se *xml.StartElement, a *Article, skip SkipSection) error {
+	switch se.Name.Local {
+	case "SUPPLIER_AID":
+		return dec.DecodeElement(&a.SupplierAID, se)
+	case "ARTICLE_DETAILS":
+		a.Details = a.reuseDetails()
+		return dec.DecodeElement(a.Details, se)
+	case "ARTICLE_FEATURES":
+		if skip&SkipFeatures != 0 {
+			break
+		}
+		return dec.DecodeElement(a.nextFeatures(), se)
+	case "ARTICLE_ORDER_DETAILS":
+		a.OrderDetails = a.reuseOrderDetails()
+		return dec.DecodeElement(a.OrderDetails, se)
+	case "ARTICLE_PRICE_DETAILS":
+		return dec.DecodeElement(a.nextPriceDetails(), se)
+	case "MIME_INFO":
+		if skip&SkipMime != 0 {
+			break
+		}
+		a.MimeInfo = a.reuseMimeInfo()
+		return dec.DecodeElement(a.MimeInfo, se)
+	case "USER_DEFINED_EXTENSIONS":
+		if skip&SkipUDX != 0 {
+			break
+		}
+		a.UDX = a.reuseUDX()
+		return dec.DecodeElement(a.UDX, se)
+	case "ARTICLE_REFERENCE":
+		if skip&SkipReferences != 0 {
+			break
+		}
+		return dec.DecodeElement(a.nextReference(), se)
+	}
+	return dec.Skip()
+}
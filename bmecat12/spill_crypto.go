@@ -0,0 +1,127 @@
+package bmecat12
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/binary"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// WithSpillEncryption encrypts the temporary file of an ArticleBuffer
+// with AES-GCM, using a random key that only lives in memory. Spilled
+// articles, e.g. with customer prices, never hit the disk in plaintext,
+// and the file cannot be read after the process has ended.
+func WithSpillEncryption() ArticleBufferOption {
+	return func(b *ArticleBuffer) {
+		b.encrypt = true
+	}
+}
+
+// spillChunkSize is the size of the plaintext chunks sealed by a
+// sealWriter.
+const spillChunkSize = 64 << 10
+
+// newSpillCipher returns an AES-GCM cipher with a new random key.
+func newSpillCipher() (cipher.AEAD, error) {
+	key := make([]byte, 32)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, errors.Wrap(err, "bmecat: unable to create key for spilled articles")
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat: unable to create cipher for spilled articles")
+	}
+	return cipher.NewGCM(block)
+}
+
+// spillNonce returns the nonce for the chunk with the given sequence
+// number. As every file gets its own key, a counter is a safe nonce.
+func spillNonce(aead cipher.AEAD, seq uint64) []byte {
+	nonce := make([]byte, aead.NonceSize())
+	binary.BigEndian.PutUint64(nonce[len(nonce)-8:], seq)
+	return nonce
+}
+
+// sealWriter encrypts the data written to it in chunks. Each chunk is
+// written as its length, as a 4-byte big-endian integer, followed by
+// the sealed chunk.
+type sealWriter struct {
+	w    io.Writer
+	aead cipher.AEAD
+	buf  []byte
+	seq  uint64
+}
+
+func newSealWriter(w io.Writer, aead cipher.AEAD) *sealWriter {
+	return &sealWriter{w: w, aead: aead, buf: make([]byte, 0, spillChunkSize)}
+}
+
+func (s *sealWriter) Write(p []byte) (int, error) {
+	var n int
+	for len(p) > 0 {
+		m := copy(s.buf[len(s.buf):cap(s.buf)], p)
+		s.buf = s.buf[:len(s.buf)+m]
+		p = p[m:]
+		n += m
+		if len(s.buf) == cap(s.buf) {
+			if err := s.Flush(); err != nil {
+				return n, err
+			}
+		}
+	}
+	return n, nil
+}
+
+// Flush seals and writes the buffered data, if any.
+func (s *sealWriter) Flush() error {
+	if len(s.buf) == 0 {
+		return nil
+	}
+	sealed := s.aead.Seal(nil, spillNonce(s.aead, s.seq), s.buf, nil)
+	s.seq++
+	s.buf = s.buf[:0]
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(sealed)))
+	if _, err := s.w.Write(size[:]); err != nil {
+		return err
+	}
+	_, err := s.w.Write(sealed)
+	return err
+}
+
+// openReader decrypts the chunks written by a sealWriter.
+type openReader struct {
+	r    io.Reader
+	aead cipher.AEAD
+	buf  []byte
+	seq  uint64
+}
+
+func newOpenReader(r io.Reader, aead cipher.AEAD) *openReader {
+	return &openReader{r: r, aead: aead}
+}
+
+func (o *openReader) Read(p []byte) (int, error) {
+	for len(o.buf) == 0 {
+		var size [4]byte
+		if _, err := io.ReadFull(o.r, size[:]); err != nil {
+			return 0, err
+		}
+		sealed := make([]byte, binary.BigEndian.Uint32(size[:]))
+		if _, err := io.ReadFull(o.r, sealed); err != nil {
+			return 0, io.ErrUnexpectedEOF
+		}
+		plain, err := o.aead.Open(sealed[:0], spillNonce(o.aead, o.seq), sealed, nil)
+		if err != nil {
+			return 0, errors.Wrap(err, "bmecat: unable to decrypt spilled articles")
+		}
+		o.seq++
+		o.buf = plain
+	}
+	n := copy(p, o.buf)
+	o.buf = o.buf[n:]
+	return n, nil
+}
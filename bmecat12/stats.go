@@ -0,0 +1,120 @@
+package bmecat12
+
+import (
+	"time"
+)
+
+// ReaderStats are the statistics of the last call to Reader.Do.
+type ReaderStats struct {
+	// Articles is the number of ARTICLE elements in the file.
+	Articles int
+	// ArticlesHandled is the number of articles passed to the handler.
+	ArticlesHandled int
+	// ArticlesFiltered is the number of articles rejected by the filters
+	// of WithArticleFilter.
+	ArticlesFiltered int
+	// ArticlesSkipped is the number of articles exceeding the size given
+	// by WithMaxArticleSize.
+	ArticlesSkipped int
+	// ArticlesFailed is the number of articles that could not be decoded
+	// and were skipped by the callback of WithContinueOnError.
+	ArticlesFailed int
+	// CatalogGroups is the number of CATALOG_STRUCTURE elements.
+	CatalogGroups int
+	// ClassificationGroups is the number of CLASSIFICATION_GROUP elements.
+	ClassificationGroups int
+	// FeatureSystems is the number of FEATURE_SYSTEM elements.
+	FeatureSystems int
+	// ClassificationSystems is the number of CLASSIFICATION_SYSTEM
+	// elements.
+	ClassificationSystems int
+	// SkippedElements is the number of duplicate HEADER and transaction
+	// elements skipped in lenient mode.
+	SkippedElements int
+	// Warnings is the number of warnings passed to the WarningHandler.
+	Warnings int
+	// FirstPass and SecondPass are the time spent in each pass.
+	FirstPass  time.Duration
+	SecondPass time.Duration
+}
+
+// Elapsed returns the total time spent in both passes.
+func (s ReaderStats) Elapsed() time.Duration {
+	return s.FirstPass + s.SecondPass
+}
+
+// add adds the counts and times of other to s.
+func (s *ReaderStats) add(other ReaderStats) {
+	s.Articles += other.Articles
+	s.ArticlesHandled += other.ArticlesHandled
+	s.ArticlesFiltered += other.ArticlesFiltered
+	s.ArticlesSkipped += other.ArticlesSkipped
+	s.ArticlesFailed += other.ArticlesFailed
+	s.CatalogGroups += other.CatalogGroups
+	s.ClassificationGroups += other.ClassificationGroups
+	s.FeatureSystems += other.FeatureSystems
+	s.ClassificationSystems += other.ClassificationSystems
+	s.SkippedElements += other.SkippedElements
+	s.Warnings += other.Warnings
+	s.FirstPass += other.FirstPass
+	s.SecondPass += other.SecondPass
+}
+
+// Stats returns the statistics of the last call to Do, so handlers need
+// not count articles themselves. It is safe to call it from within a
+// handler, e.g. the CompletionHandler, or after Do has returned, but
+// not concurrently with Do. When resuming from a Checkpoint, the counts
+// of the 1st pass are not available.
+func (r *Reader) Stats() ReaderStats {
+	return r.stats
+}
+
+// Stats returns the statistics of the last call to Do, summed up over
+// all files.
+func (m *MultiReader) Stats() ReaderStats {
+	var s ReaderStats
+	for _, r := range m.readers {
+		s.add(r.stats)
+	}
+	return s
+}
+
+// CompletionStats are the final statistics passed to the
+// CompletionStatsHandler.
+type CompletionStats struct {
+	// ReaderStats are the totals of the run, summed up over all files
+	// of a MultiReader.
+	ReaderStats
+	// Elements is the number of elements found by element name, e.g.
+	// "ARTICLE" or "CATALOG_STRUCTURE". It contains the elements passed
+	// to handlers, i.e. HEADER is not included.
+	Elements map[string]int
+	// Duration is the time since Do was called.
+	Duration time.Duration
+}
+
+// completionStats returns the statistics for the CompletionStatsHandler,
+// with Duration measured from started.
+func (r *Reader) completionStats(started time.Time) (*CompletionStats, error) {
+	stats := r.stats
+	if r.part != nil {
+		stats = r.part.shared.stats
+		started = r.part.shared.started
+	}
+	numMaps, err := r.mappings.Len()
+	if err != nil {
+		return nil, &MapStoreError{Op: "read catalog group mappings", Err: err}
+	}
+	return &CompletionStats{
+		ReaderStats: stats,
+		Elements: map[string]int{
+			"ARTICLE":                     stats.Articles,
+			"ARTICLE_TO_CATALOGGROUP_MAP": numMaps,
+			"CATALOG_STRUCTURE":           stats.CatalogGroups,
+			"CLASSIFICATION_GROUP":        stats.ClassificationGroups,
+			"CLASSIFICATION_SYSTEM":       stats.ClassificationSystems,
+			"FEATURE_SYSTEM":              stats.FeatureSystems,
+		},
+		Duration: time.Since(started),
+	}, nil
+}
@@ -0,0 +1,178 @@
+package bmecat12
+
+import (
+	"fmt"
+)
+
+// CatalogSubtree is the branch of a catalog group system below a given
+// catalog group. It can be used to extract the part of a catalog that
+// belongs to one category branch into a standalone catalog, e.g. when
+// only a part of a supplier's assortment is sold.
+type CatalogSubtree struct {
+	rootID string
+	groups []*CatalogGroup
+	ids    map[string]bool
+}
+
+// NewCatalogSubtree returns the subtree of groups below (and including)
+// the catalog group with the given rootID. It returns an error if no such
+// group exists in groups.
+func NewCatalogSubtree(groups []*CatalogGroup, rootID string) (*CatalogSubtree, error) {
+	var found bool
+	children := make(map[string][]string)
+	for _, g := range groups {
+		if g.ID == rootID {
+			found = true
+		}
+		if g.ParentID != nil && *g.ParentID != g.ID {
+			children[*g.ParentID] = append(children[*g.ParentID], g.ID)
+		}
+	}
+	if !found {
+		return nil, fmt.Errorf("bmecat: catalog group %q not found", rootID)
+	}
+
+	ids := map[string]bool{rootID: true}
+	queue := []string{rootID}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, child := range children[id] {
+			if !ids[child] {
+				ids[child] = true
+				queue = append(queue, child)
+			}
+		}
+	}
+
+	s := &CatalogSubtree{rootID: rootID, ids: ids}
+	for _, g := range groups {
+		if ids[g.ID] {
+			s.groups = append(s.groups, g)
+		}
+	}
+	return s, nil
+}
+
+// RootID returns the ID of the catalog group the subtree starts with.
+func (s *CatalogSubtree) RootID() string {
+	return s.rootID
+}
+
+// Len returns the number of catalog groups in the subtree.
+func (s *CatalogSubtree) Len() int {
+	return len(s.groups)
+}
+
+// Contains returns true if the catalog group with the given ID is part
+// of the subtree.
+func (s *CatalogSubtree) Contains(groupID string) bool {
+	return s.ids[groupID]
+}
+
+// ContainsArticle returns true if the article is mapped to at least one
+// catalog group of the subtree.
+func (s *CatalogSubtree) ContainsArticle(a *Article) bool {
+	for _, id := range a.CatalogGroupIDs {
+		if s.ids[id] {
+			return true
+		}
+	}
+	return false
+}
+
+// CatalogGroups returns copies of the catalog groups in the subtree.
+// The groups are re-rooted, i.e. the group the subtree starts with
+// becomes the root of the catalog group system.
+func (s *CatalogSubtree) CatalogGroups() []*CatalogGroup {
+	out := make([]*CatalogGroup, 0, len(s.groups))
+	for _, g := range s.groups {
+		cg := *g
+		if cg.ID == s.rootID {
+			root := "0"
+			cg.Type = "root"
+			cg.ParentID = &root
+		}
+		out = append(out, &cg)
+	}
+	return out
+}
+
+// CatalogGroupSystem returns a CatalogGroupSystem with the re-rooted
+// catalog groups of the subtree. The metadata like ID and name are
+// copied from system, which may be nil.
+func (s *CatalogSubtree) CatalogGroupSystem(system *CatalogGroupSystem) *CatalogGroupSystem {
+	out := &CatalogGroupSystem{}
+	if system != nil {
+		out.ID = system.ID
+		out.Name = system.Name
+		out.Description = system.Description
+	}
+	out.Groups = s.CatalogGroups()
+	return out
+}
+
+// Article returns a copy of the article with its catalog group mappings
+// restricted to the subtree. If the article is not mapped to any group
+// of the subtree, nil is returned.
+func (s *CatalogSubtree) Article(a *Article) *Article {
+	var ids []string
+	for _, id := range a.CatalogGroupIDs {
+		if s.ids[id] {
+			ids = append(ids, id)
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+	out := *a
+	out.CatalogGroupIDs = ids
+	return &out
+}
+
+// ClassificationGroupIDs returns the IDs of the classification groups of
+// the classification system with the given name that are referenced by
+// the features of the article.
+func (a *Article) ClassificationGroupIDs(systemName string) []string {
+	var ids []string
+	for _, af := range a.Features {
+		if af.FeatureSystemName == systemName && af.FeatureGroupID != "" {
+			ids = append(ids, af.FeatureGroupID)
+		}
+	}
+	return ids
+}
+
+// PruneClassificationSystem returns a copy of the classification system
+// that only contains the classification groups with the given IDs and
+// their ancestors. It is used to only deliver the classifications that
+// are actually referenced by the articles of a catalog.
+func PruneClassificationSystem(cs *ClassificationSystem, groupIDs []string) *ClassificationSystem {
+	if cs == nil {
+		return nil
+	}
+	byID := make(map[string]*ClassificationGroup, len(cs.Groups))
+	for _, g := range cs.Groups {
+		byID[g.ID] = g
+	}
+	keep := make(map[string]bool)
+	for _, id := range groupIDs {
+		for id != "" && !keep[id] {
+			g, ok := byID[id]
+			if !ok {
+				break
+			}
+			keep[id] = true
+			id = g.ParentID
+		}
+	}
+
+	out := *cs
+	out.Groups = nil
+	for _, g := range cs.Groups {
+		if keep[g.ID] {
+			out.Groups = append(out.Groups, g)
+		}
+	}
+	return &out
+}
@@ -0,0 +1,160 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func testCatalogGroups() []*bmecat12.CatalogGroup {
+	parent := func(id string) *string { return &id }
+	return []*bmecat12.CatalogGroup{
+		{Type: "root", ID: "1", Name: "Catalog", ParentID: parent("0")},
+		{Type: "node", ID: "2", Name: "Hardware", ParentID: parent("1")},
+		{Type: "leaf", ID: "3", Name: "Notebooks", ParentID: parent("2")},
+		{Type: "leaf", ID: "4", Name: "Desktops", ParentID: parent("2")},
+		{Type: "leaf", ID: "5", Name: "Software", ParentID: parent("1")},
+	}
+}
+
+func TestCatalogSubtree(t *testing.T) {
+	s, err := bmecat12.NewCatalogSubtree(testCatalogGroups(), "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 3, s.Len(); want != have {
+		t.Fatalf("want Len = %d, have %d", want, have)
+	}
+	for _, id := range []string{"2", "3", "4"} {
+		if !s.Contains(id) {
+			t.Errorf("expected subtree to contain group %q", id)
+		}
+	}
+	for _, id := range []string{"1", "5"} {
+		if s.Contains(id) {
+			t.Errorf("expected subtree to not contain group %q", id)
+		}
+	}
+
+	groups := s.CatalogGroups()
+	if want, have := 3, len(groups); want != have {
+		t.Fatalf("want len(groups) = %d, have %d", want, have)
+	}
+	if !groups[0].IsRoot() {
+		t.Fatalf("want group %q to be the new root, have type %q", groups[0].ID, groups[0].Type)
+	}
+	if groups[0].ParentID == nil || *groups[0].ParentID != "0" {
+		t.Fatalf("want root to have PARENT_ID 0, have %v", groups[0].ParentID)
+	}
+
+	a := &bmecat12.Article{SupplierAID: "1000", CatalogGroupIDs: []string{"3", "5"}}
+	if !s.ContainsArticle(a) {
+		t.Fatal("expected subtree to contain article")
+	}
+	b := s.Article(a)
+	if b == nil {
+		t.Fatal("want article, have nil")
+	}
+	if want, have := []string{"3"}, b.CatalogGroupIDs; len(have) != 1 || want[0] != have[0] {
+		t.Fatalf("want CatalogGroupIDs = %v, have %v", want, have)
+	}
+	if want, have := 2, len(a.CatalogGroupIDs); want != have {
+		t.Fatalf("want original article to be unchanged, have %v", a.CatalogGroupIDs)
+	}
+	if c := s.Article(&bmecat12.Article{SupplierAID: "2000", CatalogGroupIDs: []string{"5"}}); c != nil {
+		t.Fatalf("want nil, have %v", c)
+	}
+}
+
+func TestCatalogSubtreeNotFound(t *testing.T) {
+	_, err := bmecat12.NewCatalogSubtree(testCatalogGroups(), "42")
+	if err == nil {
+		t.Fatal("want error, have nil")
+	}
+}
+
+func TestPruneClassificationSystem(t *testing.T) {
+	cs := &bmecat12.ClassificationSystem{
+		Name: "udf_Supplier-1.0",
+		Groups: []*bmecat12.ClassificationGroup{
+			{ID: "1", Name: "Hardware", Type: "node"},
+			{ID: "2", Name: "Notebook", ParentID: "1", Type: "node"},
+			{ID: "3", Name: "Desktop", ParentID: "1", Type: "node"},
+			{ID: "4", Name: "PC", ParentID: "2", Type: "leaf"},
+			{ID: "5", Name: "Mac", ParentID: "2", Type: "leaf"},
+		},
+	}
+	a := &bmecat12.Article{
+		SupplierAID: "1000",
+		Features: []*bmecat12.ArticleFeatures{
+			{FeatureSystemName: "ECLASS-5.1", FeatureGroupID: "19010203"},
+			{FeatureSystemName: "udf_Supplier-1.0", FeatureGroupID: "5"},
+		},
+	}
+	pruned := bmecat12.PruneClassificationSystem(cs, a.ClassificationGroupIDs(cs.Name))
+	var ids []string
+	for _, g := range pruned.Groups {
+		ids = append(ids, g.ID)
+	}
+	if want, have := "1,2,5", strings.Join(ids, ","); want != have {
+		t.Fatalf("want groups %s, have %s", want, have)
+	}
+	if want, have := 5, len(cs.Groups); want != have {
+		t.Fatalf("want original system to be unchanged, have %d groups", have)
+	}
+}
+
+type subtreeCatalogWriter struct {
+	catalogWriter
+	groupSystem *bmecat12.CatalogGroupSystem
+}
+
+func (w subtreeCatalogWriter) CatalogGroupSystem() *bmecat12.CatalogGroupSystem {
+	return w.groupSystem
+}
+
+func TestWriteCatalogSubtree(t *testing.T) {
+	s, err := bmecat12.NewCatalogSubtree(testCatalogGroups(), "2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var articles []*bmecat12.Article
+	for _, a := range []*bmecat12.Article{
+		{SupplierAID: "1000", CatalogGroupIDs: []string{"3"}},
+		{SupplierAID: "2000", CatalogGroupIDs: []string{"5"}},
+	} {
+		if b := s.Article(a); b != nil {
+			articles = append(articles, b)
+		}
+	}
+	cw := subtreeCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:       bmecat12.NewCatalog,
+			header:   testHeader,
+			articles: articles,
+		},
+		groupSystem: s.CatalogGroupSystem(&bmecat12.CatalogGroupSystem{ID: "1", Name: "Hardware"}),
+	}
+
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithCatalogGroupMaps()).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		"<CATALOG_GROUP_SYSTEM>",
+		`<CATALOG_STRUCTURE type="root">`,
+		"<ART_ID>1000</ART_ID>",
+		"<CATALOG_GROUP_ID>3</CATALOG_GROUP_ID>",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("expected output to contain %s", want)
+		}
+	}
+	if strings.Contains(out, "<SUPPLIER_AID>2000</SUPPLIER_AID>") {
+		t.Error("expected output to not contain article 2000")
+	}
+}
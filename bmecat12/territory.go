@@ -0,0 +1,137 @@
+package bmecat12
+
+// TerritoryMacros maps a macro used as TERRITORY, e.g. "EU" or "DACH", to
+// the ISO 3166-1 alpha-2 codes of the countries it stands for.
+type TerritoryMacros map[string][]string
+
+// DefaultTerritoryMacros are the macros used by WithTerritoryMacros if
+// none are given. Copy and modify them to add or change macros.
+var DefaultTerritoryMacros = TerritoryMacros{
+	"EU": {
+		"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI",
+		"FR", "GR", "HR", "HU", "IE", "IT", "LT", "LU", "LV", "MT",
+		"NL", "PL", "PT", "RO", "SE", "SI", "SK",
+	},
+	"EEA": {
+		"AT", "BE", "BG", "CY", "CZ", "DE", "DK", "EE", "ES", "FI",
+		"FR", "GR", "HR", "HU", "IE", "IT", "LT", "LU", "LV", "MT",
+		"NL", "PL", "PT", "RO", "SE", "SI", "SK",
+		"IS", "LI", "NO",
+	},
+	"DACH":    {"DE", "AT", "CH"},
+	"BENELUX": {"BE", "NL", "LU"},
+}
+
+// Expand returns the territories with the macros replaced by their
+// countries. Territories that are no macros are kept as is, and each
+// territory is returned only once, at its first position. If nothing
+// changes, territories is returned as is.
+func (m TerritoryMacros) Expand(territories []string) []string {
+	expand := false
+	for _, t := range territories {
+		if _, ok := m[t]; ok {
+			expand = true
+			break
+		}
+	}
+	if !expand {
+		return territories
+	}
+	seen := make(map[string]bool)
+	var expanded []string
+	add := func(t string) {
+		if !seen[t] {
+			seen[t] = true
+			expanded = append(expanded, t)
+		}
+	}
+	for _, t := range territories {
+		if countries, ok := m[t]; ok {
+			for _, c := range countries {
+				add(c)
+			}
+		} else {
+			add(t)
+		}
+	}
+	return expanded
+}
+
+// WithTerritoryMacros makes the Writer expand macros used as TERRITORY
+// in the CATALOG element of the header and in ARTICLE_PRICE, e.g. "EU"
+// to the countries of the European Union. Pass nil to use
+// DefaultTerritoryMacros. The header and the articles are not modified.
+func WithTerritoryMacros(m TerritoryMacros) WriterOption {
+	return func(w *Writer) {
+		if m == nil {
+			m = DefaultTerritoryMacros
+		}
+		w.territoryMacros = m
+	}
+}
+
+// expandHeaderTerritories returns header with the territory macros of
+// the catalog expanded. header is not modified; if nothing changes,
+// header is returned as is.
+func (w *Writer) expandHeaderTerritories(header *Header) *Header {
+	if w.territoryMacros == nil || header.Catalog == nil {
+		return header
+	}
+	territories := w.territoryMacros.Expand(header.Catalog.Territories)
+	if sameStrings(territories, header.Catalog.Territories) {
+		return header
+	}
+	catalog := *header.Catalog
+	catalog.Territories = territories
+	prepared := *header
+	prepared.Catalog = &catalog
+	return &prepared
+}
+
+// expandArticleTerritories returns a with the territory macros of its
+// prices expanded. a is not modified; if nothing changes, a is returned
+// as is.
+func (w *Writer) expandArticleTerritories(a *Article) *Article {
+	if w.territoryMacros == nil {
+		return a
+	}
+	var details []*ArticlePriceDetails
+	for i, d := range a.PriceDetails {
+		var prices []*ArticlePrice
+		for j, p := range d.Prices {
+			territories := w.territoryMacros.Expand(p.Territory)
+			if sameStrings(territories, p.Territory) {
+				continue
+			}
+			if prices == nil {
+				prices = make([]*ArticlePrice, len(d.Prices))
+				copy(prices, d.Prices)
+			}
+			price := *p
+			price.Territory = territories
+			prices[j] = &price
+		}
+		if prices == nil {
+			continue
+		}
+		if details == nil {
+			details = make([]*ArticlePriceDetails, len(a.PriceDetails))
+			copy(details, a.PriceDetails)
+		}
+		detail := *d
+		detail.Prices = prices
+		details[i] = &detail
+	}
+	if details == nil {
+		return a
+	}
+	prepared := *a
+	prepared.PriceDetails = details
+	return &prepared
+}
+
+// sameStrings returns true if a and b share the same backing array and
+// length, i.e. if Expand returned its input.
+func sameStrings(a, b []string) bool {
+	return len(a) == len(b) && (len(a) == 0 || &a[0] == &b[0])
+}
@@ -0,0 +1,87 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestTerritoryMacrosExpand(t *testing.T) {
+	m := bmecat12.TerritoryMacros{"DACH": {"DE", "AT", "CH"}}
+	in := []string{"FR", "DACH", "AT", "IT"}
+	if want, have := []string{"FR", "DE", "AT", "CH", "IT"}, m.Expand(in); !reflect.DeepEqual(want, have) {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	if want, have := []string{"FR", "DACH", "AT", "IT"}, in; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want input %v, have %v", want, have)
+	}
+	if want, have := 27, len(bmecat12.DefaultTerritoryMacros.Expand([]string{"EU"})); want != have {
+		t.Fatalf("want %d countries in the EU, have %d", want, have)
+	}
+}
+
+func TestWriteWithTerritoryMacros(t *testing.T) {
+	header := *testHeader
+	catalog := *header.Catalog
+	catalog.Territories = []string{"DACH"}
+	header.Catalog = &catalog
+	article := &bmecat12.Article{
+		SupplierAID: "1000",
+		Details:     &bmecat12.ArticleDetails{DescriptionShort: "Territories"},
+		PriceDetails: []*bmecat12.ArticlePriceDetails{
+			{
+				Prices: []*bmecat12.ArticlePrice{
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("1.5"), Territory: []string{"DE"}},
+					{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("2.5"), Territory: []string{"EU", "CH"}},
+				},
+			},
+		},
+	}
+	w := catalogWriter{
+		tx:       bmecat12.UpdatePrices,
+		language: "de",
+		header:   &header,
+		articles: []*bmecat12.Article{article},
+	}
+
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithTerritoryMacros(nil)).Do(context.Background(), w); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"DE", "AT", "CH"}, h.header.Catalog.Territories; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want territories %v, have %v", want, have)
+	}
+	if want, have := 1, len(h.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+	prices := h.articles[0].PriceDetails[0].Prices
+	if want, have := []string{"DE"}, prices[0].Territory; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want territories %v, have %v", want, have)
+	}
+	if want, have := 28, len(prices[1].Territory); want != have {
+		t.Fatalf("want %d territories, have %d: %v", want, have, prices[1].Territory)
+	}
+	if want, have := "CH", prices[1].Territory[27]; want != have {
+		t.Fatalf("want last territory %q, have %q", want, have)
+	}
+	if strings.Contains(buf.String(), "<TERRITORY>EU</TERRITORY>") {
+		t.Fatal("want EU to be expanded")
+	}
+
+	// The input is not modified
+	if want, have := []string{"DACH"}, header.Catalog.Territories; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want territories %v, have %v", want, have)
+	}
+	if want, have := []string{"EU", "CH"}, article.PriceDetails[0].Prices[1].Territory; !reflect.DeepEqual(want, have) {
+		t.Fatalf("want territories %v, have %v", want, have)
+	}
+}
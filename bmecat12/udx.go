@@ -18,6 +18,9 @@ type UserDefinedExtensions struct {
 	// Each field is a name/value pair. The name is the UDX field without the
 	// "UDX." prefix. E.g. a UDX with the name "UDX.SYSTEM.CUSTOM_FIELD1" has
 	// a field name of "SYSTEM.CUSTOM_FIELD1".
+	//
+	// Fields are read and written in the order of the slice. See
+	// WithUDXOrder and WithUDXDedup to normalize the output.
 	Fields UserDefinedExtensionFields `xml:"-"`
 }
 
@@ -30,6 +33,20 @@ type UserDefinedExtensionField struct {
 	Value    string `xml:",chardata"`
 	InnerXML string `xml:",innerxml"`
 	Raw      bool   `xml:"-"` // true to marshal Value as raw XML, i.e. not escape it
+
+	readValue string // Value as read, see passInnerXML
+}
+
+// passInnerXML returns true if the field is to be written with its
+// InnerXML, i.e. if it has nested elements, as read from a BMEcat file.
+// The Value of such a field is the whitespace between the elements, if
+// the file is indented. Once the Value was changed, it is written
+// instead.
+func (field *UserDefinedExtensionField) passInnerXML() bool {
+	if field.InnerXML == "" || field.Value != field.readValue {
+		return false
+	}
+	return strings.TrimSpace(field.Value) == "" || strings.Contains(field.InnerXML, "<")
 }
 
 // MarshalXML encodes the contents of the UserDefinedExtensions struct.
@@ -37,12 +54,18 @@ func (x *UserDefinedExtensions) MarshalXML(e *xml.Encoder, start xml.StartElemen
 	e.EncodeToken(xml.StartElement{Name: xml.Name{Local: "USER_DEFINED_EXTENSIONS"}})
 	for _, field := range x.Fields {
 		local := fmt.Sprintf("UDX.%s", field.Name)
-		if field.Raw {
-			// Directly inject the Raw field contents into the XML element
+		if field.Raw || field.passInnerXML() {
+			// Directly inject the Raw field contents into the XML element.
+			// Fields with nested elements, as read from a BMEcat file,
+			// are passed through as they are.
+			value := field.Value
+			if !field.Raw {
+				value = field.InnerXML
+			}
 			raw := struct {
 				Value string `xml:",innerxml"`
 			}{
-				Value: field.Value,
+				Value: value,
 			}
 			e.EncodeElement(raw, xml.StartElement{Name: xml.Name{Local: local}})
 
@@ -73,6 +96,7 @@ func (x *UserDefinedExtensions) UnmarshalXML(d *xml.Decoder, start xml.StartElem
 			if strings.HasPrefix(se.Name.Local, "UDX.") {
 				field := &UserDefinedExtensionField{Name: se.Name.Local[4:]}
 				d.DecodeElement(&field, &se)
+				field.readValue = field.Value
 				fields = append(fields, field)
 			}
 		}
@@ -0,0 +1,214 @@
+package bmecat12
+
+import (
+	"encoding"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// UnmarshalUDX copies the UDX fields of udx into the struct pointed to
+// by v. Struct fields are mapped by their udx tag, which holds the name
+// of the UDX field without the "UDX." prefix, e.g.
+//
+//	type Extensions struct {
+//		Color   string  `udx:"SYSTEM.COLOR"`
+//		Weight  float64 `udx:"SYSTEM.WEIGHT"`
+//		Bulky   bool    `udx:"SYSTEM.BULKY,omitempty"`
+//		Ignored string  `udx:"-"`
+//	}
+//
+// Supported field types are strings, booleans, integers, floats, and
+// types implementing encoding.TextUnmarshaler. Struct fields without a
+// matching UDX field are left unchanged. udx may be nil.
+func UnmarshalUDX(udx *UserDefinedExtensions, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bmecat: UnmarshalUDX expects a pointer to a struct, have %T", v)
+	}
+	if udx == nil {
+		return nil
+	}
+	rv = rv.Elem()
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, _, ok := udxTag(rt.Field(i))
+		if !ok {
+			continue
+		}
+		value, found := udx.Fields.Get(name)
+		if !found {
+			continue
+		}
+		if err := setUDXValue(rv.Field(i), value); err != nil {
+			return fmt.Errorf("bmecat: unable to unmarshal UDX.%s into %s: %v", name, rt.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+// MarshalUDX copies the fields of the struct v, or the struct pointed to
+// by v, into udx, using the udx tags as described in UnmarshalUDX. UDX
+// fields that already exist are replaced; other fields of udx are kept.
+// With the omitempty option, fields with a zero value are removed from
+// udx instead.
+func MarshalUDX(udx *UserDefinedExtensions, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() == reflect.Ptr {
+		if rv.IsNil() {
+			return fmt.Errorf("bmecat: MarshalUDX expects a struct, have nil %T", v)
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bmecat: MarshalUDX expects a struct, have %T", v)
+	}
+	if udx == nil {
+		return fmt.Errorf("bmecat: MarshalUDX expects non-nil UserDefinedExtensions")
+	}
+	rt := rv.Type()
+	for i := 0; i < rt.NumField(); i++ {
+		name, omitempty, ok := udxTag(rt.Field(i))
+		if !ok {
+			continue
+		}
+		fv := rv.Field(i)
+		if omitempty && fv.IsZero() {
+			udx.Fields.remove(name)
+			continue
+		}
+		value, err := udxValue(fv)
+		if err != nil {
+			return fmt.Errorf("bmecat: unable to marshal %s into UDX.%s: %v", rt.Field(i).Name, name, err)
+		}
+		udx.Fields.set(name, value)
+	}
+	return nil
+}
+
+// udxTag returns the UDX field name and options of the struct field.
+func udxTag(f reflect.StructField) (name string, omitempty bool, ok bool) {
+	if f.PkgPath != "" {
+		// Unexported
+		return "", false, false
+	}
+	tag := f.Tag.Get("udx")
+	if tag == "" || tag == "-" {
+		return "", false, false
+	}
+	parts := strings.Split(tag, ",")
+	for _, opt := range parts[1:] {
+		if opt == "omitempty" {
+			omitempty = true
+		}
+	}
+	return parts[0], omitempty, parts[0] != ""
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+var textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+
+// setUDXValue parses value into the field.
+func setUDXValue(fv reflect.Value, value string) error {
+	if fv.CanAddr() && fv.Addr().Type().Implements(textUnmarshalerType) {
+		return fv.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value))
+	}
+	value = strings.TrimSpace(value)
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(value)
+	case reflect.Bool:
+		switch strings.ToLower(value) {
+		case "", "0", "false", "f", "no", "n":
+			fv.SetBool(false)
+		case "1", "true", "t", "yes", "y":
+			fv.SetBool(true)
+		default:
+			return fmt.Errorf("invalid boolean %q", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if value == "" {
+			fv.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if value == "" {
+			fv.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(value, 10, fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if value == "" {
+			fv.SetFloat(0)
+			return nil
+		}
+		// Accept decimal commas, as commonly found in German catalogs
+		f, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), fv.Type().Bits())
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+// udxValue formats the field as a string.
+func udxValue(fv reflect.Value) (string, error) {
+	if fv.Type().Implements(textMarshalerType) {
+		text, err := fv.Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	if fv.CanAddr() && fv.Addr().Type().Implements(textMarshalerType) {
+		text, err := fv.Addr().Interface().(encoding.TextMarshaler).MarshalText()
+		return string(text), err
+	}
+	switch fv.Kind() {
+	case reflect.String:
+		return fv.String(), nil
+	case reflect.Bool:
+		return strconv.FormatBool(fv.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(fv.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(fv.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		return strconv.FormatFloat(fv.Float(), 'f', -1, fv.Type().Bits()), nil
+	}
+	return "", fmt.Errorf("unsupported type %s", fv.Type())
+}
+
+// set sets the value of the UDX field, adding it if necessary.
+func (x *UserDefinedExtensionFields) set(name, value string) {
+	for _, field := range *x {
+		if field.Name == name {
+			field.Value = value
+			field.InnerXML = ""
+			field.Raw = false
+			return
+		}
+	}
+	x.Add(name, value)
+}
+
+// remove removes all UDX fields with the given name.
+func (x *UserDefinedExtensionFields) remove(name string) {
+	fields := (*x)[:0]
+	for _, field := range *x {
+		if field.Name != name {
+			fields = append(fields, field)
+		}
+	}
+	*x = fields
+}
@@ -0,0 +1,113 @@
+package bmecat12
+
+import "sort"
+
+// UDXOrder decides the order in which the Writer emits UDX fields. By
+// default, the Writer emits the fields in the order of
+// UserDefinedExtensions.Fields, i.e. the order in which they were read
+// or added. Use WithUDXOrder to get the same output for the same fields,
+// regardless of how they were collected.
+type UDXOrder interface {
+	// OrderUDX returns the fields in the order to emit. It must not
+	// modify fields.
+	OrderUDX(fields UserDefinedExtensionFields) UserDefinedExtensionFields
+}
+
+// UDXOrderFunc is an adapter to allow the use of ordinary functions as
+// an UDXOrder.
+type UDXOrderFunc func(fields UserDefinedExtensionFields) UserDefinedExtensionFields
+
+// OrderUDX calls f(fields).
+func (f UDXOrderFunc) OrderUDX(fields UserDefinedExtensionFields) UserDefinedExtensionFields {
+	return f(fields)
+}
+
+// UDXSortedByName emits the fields sorted by name. Fields with the same
+// name keep their relative order.
+var UDXSortedByName UDXOrder = UDXFieldOrder()
+
+// UDXFieldOrder emits the fields with the given names first, in the order
+// given, followed by the other fields sorted by name. Fields with the same
+// name keep their relative order.
+func UDXFieldOrder(names ...string) UDXOrder {
+	rank := make(map[string]int, len(names))
+	for i, name := range names {
+		if _, found := rank[name]; !found {
+			rank[name] = i
+		}
+	}
+	return UDXOrderFunc(func(fields UserDefinedExtensionFields) UserDefinedExtensionFields {
+		ordered := make(UserDefinedExtensionFields, len(fields))
+		copy(ordered, fields)
+		sort.SliceStable(ordered, func(i, j int) bool {
+			ri, iRanked := rank[ordered[i].Name]
+			rj, jRanked := rank[ordered[j].Name]
+			switch {
+			case iRanked && jRanked:
+				return ri < rj
+			case iRanked != jRanked:
+				return iRanked
+			}
+			return ordered[i].Name < ordered[j].Name
+		})
+		return ordered
+	})
+}
+
+// WithUDXOrder makes the Writer emit the UDX fields of the header and the
+// articles in the order given by o, e.g. UDXSortedByName.
+func WithUDXOrder(o UDXOrder) WriterOption {
+	return func(w *Writer) {
+		w.udxOrder = o
+	}
+}
+
+// WithUDXDedup makes the Writer emit only one UDX field per name, as
+// returned by UserDefinedExtensionFields.Dedup.
+func WithUDXDedup() WriterOption {
+	return func(w *Writer) {
+		w.udxDedup = true
+	}
+}
+
+// Dedup returns the fields with one field per name. If a name occurs more
+// than once, the last field wins, at the position of the first one. x is
+// not modified.
+func (x UserDefinedExtensionFields) Dedup() UserDefinedExtensionFields {
+	index := make(map[string]int, len(x))
+	fields := make(UserDefinedExtensionFields, 0, len(x))
+	for _, field := range x {
+		if i, found := index[field.Name]; found {
+			fields[i] = field
+			continue
+		}
+		index[field.Name] = len(fields)
+		fields = append(fields, field)
+	}
+	return fields
+}
+
+// prepareUDX returns udx as the Writer emits it, i.e. stamped with the
+// provenance if stamp is true, and with the fields deduplicated and
+// ordered as configured. udx is not modified; if nothing changes, udx is
+// returned as is.
+func (w *Writer) prepareUDX(udx *UserDefinedExtensions, stamp bool) (*UserDefinedExtensions, error) {
+	if stamp && w.stamp != nil {
+		stamped, err := StampProvenance(udx, w.stamp)
+		if err != nil {
+			return nil, err
+		}
+		udx = stamped
+	}
+	if udx == nil || (!w.udxDedup && w.udxOrder == nil) {
+		return udx, nil
+	}
+	fields := udx.Fields
+	if w.udxDedup {
+		fields = fields.Dedup()
+	}
+	if w.udxOrder != nil {
+		fields = w.udxOrder.OrderUDX(fields)
+	}
+	return &UserDefinedExtensions{Fields: fields}, nil
+}
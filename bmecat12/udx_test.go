@@ -2,7 +2,10 @@ package bmecat12
 
 import (
 	"encoding/xml"
+	"io/ioutil"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestMarshalUDX(t *testing.T) {
@@ -71,3 +74,209 @@ func TestUnmarshalUDX(t *testing.T) {
 		t.Fatalf("want %q, have %q", want, have)
 	}
 }
+
+type testExtensions struct {
+	Color    string    `udx:"SYSTEM.COLOR"`
+	Weight   float64   `udx:"SYSTEM.WEIGHT"`
+	Pieces   int       `udx:"SYSTEM.PIECES"`
+	Bulky    bool      `udx:"SYSTEM.BULKY,omitempty"`
+	Since    time.Time `udx:"SYSTEM.SINCE,omitempty"`
+	Ignored  string    `udx:"-"`
+	Untagged string
+}
+
+func TestUnmarshalUDXStruct(t *testing.T) {
+	udx := &UserDefinedExtensions{}
+	udx.Fields.Add("SYSTEM.COLOR", "red")
+	udx.Fields.Add("SYSTEM.WEIGHT", "1,5")
+	udx.Fields.Add("SYSTEM.PIECES", " 12 ")
+	udx.Fields.Add("SYSTEM.BULKY", "true")
+	udx.Fields.Add("SYSTEM.SINCE", "2021-04-01T00:00:00Z")
+
+	var v testExtensions
+	if err := UnmarshalUDX(udx, &v); err != nil {
+		t.Fatal(err)
+	}
+	want := testExtensions{
+		Color:  "red",
+		Weight: 1.5,
+		Pieces: 12,
+		Bulky:  true,
+		Since:  time.Date(2021, 4, 1, 0, 0, 0, 0, time.UTC),
+	}
+	if v != want {
+		t.Fatalf("want %+v, have %+v", want, v)
+	}
+
+	udx = &UserDefinedExtensions{}
+	udx.Fields.Add("SYSTEM.PIECES", "many")
+	if err := UnmarshalUDX(udx, &v); err == nil {
+		t.Fatal("want error for invalid integer, have nil")
+	}
+	if err := UnmarshalUDX(udx, v); err == nil {
+		t.Fatal("want error for non-pointer, have nil")
+	}
+}
+
+func TestMarshalUDXStruct(t *testing.T) {
+	udx := &UserDefinedExtensions{}
+	udx.Fields.Add("SYSTEM.OTHER", "kept")
+	udx.Fields.Add("SYSTEM.COLOR", "blue")
+	udx.Fields.Add("SYSTEM.BULKY", "true")
+
+	v := testExtensions{Color: "red", Weight: 1.5, Pieces: 12, Ignored: "x"}
+	if err := MarshalUDX(udx, &v); err != nil {
+		t.Fatal(err)
+	}
+	out, err := xml.Marshal(udx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<USER_DEFINED_EXTENSIONS><UDX.SYSTEM.OTHER>kept</UDX.SYSTEM.OTHER><UDX.SYSTEM.COLOR>red</UDX.SYSTEM.COLOR><UDX.SYSTEM.WEIGHT>1.5</UDX.SYSTEM.WEIGHT><UDX.SYSTEM.PIECES>12</UDX.SYSTEM.PIECES></USER_DEFINED_EXTENSIONS>`
+	if want, have := expected, string(out); want != have {
+		t.Fatalf("want:\n%v\nhave:\n%v", want, have)
+	}
+}
+
+func TestUDXDedup(t *testing.T) {
+	var fields UserDefinedExtensionFields
+	fields.Add("B", "1")
+	fields.Add("A", "2")
+	fields.Add("B", "3")
+	deduped := fields.Dedup()
+	if want, have := "B=3,A=2", udxFieldsString(deduped); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+	if want, have := "B=1,A=2,B=3", udxFieldsString(fields); want != have {
+		t.Fatalf("want fields unchanged %s, have %s", want, have)
+	}
+}
+
+func TestUDXFieldOrder(t *testing.T) {
+	var fields UserDefinedExtensionFields
+	fields.Add("C", "1")
+	fields.Add("B", "2")
+	fields.Add("A", "3")
+	fields.Add("B", "4")
+	fields.Add("D", "5")
+
+	if want, have := "A=3,B=2,B=4,C=1,D=5", udxFieldsString(UDXSortedByName.OrderUDX(fields)); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+	if want, have := "D=5,B=2,B=4,A=3,C=1", udxFieldsString(UDXFieldOrder("D", "B").OrderUDX(fields)); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+	if want, have := "C=1,B=2,A=3,B=4,D=5", udxFieldsString(fields); want != have {
+		t.Fatalf("want fields unchanged %s, have %s", want, have)
+	}
+}
+
+func TestWriterPrepareUDX(t *testing.T) {
+	udx := &UserDefinedExtensions{}
+	udx.Fields.Add("B", "1")
+	udx.Fields.Add("A", "2")
+	udx.Fields.Add("B", "3")
+
+	w := NewWriter(ioutil.Discard)
+	prepared, err := w.prepareUDX(udx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if prepared != udx {
+		t.Fatal("want UDX unchanged without options")
+	}
+
+	w = NewWriter(ioutil.Discard, WithUDXOrder(UDXSortedByName), WithUDXDedup())
+	prepared, err = w.prepareUDX(udx, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := xml.Marshal(prepared)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := `<USER_DEFINED_EXTENSIONS><UDX.A>2</UDX.A><UDX.B>3</UDX.B></USER_DEFINED_EXTENSIONS>`, string(out); want != have {
+		t.Fatalf("want %s, have %s", want, have)
+	}
+}
+
+func udxFieldsString(fields UserDefinedExtensionFields) string {
+	var parts []string
+	for _, field := range fields {
+		parts = append(parts, field.Name+"="+field.Value)
+	}
+	return strings.Join(parts, ",")
+}
+
+func TestUDXRoundTripIndentedNested(t *testing.T) {
+	input := `<ARTICLE>
+  <SUPPLIER_AID>1000</SUPPLIER_AID>
+  <USER_DEFINED_EXTENSIONS>
+    <UDX.EDXF.MIME_INFO>
+      <UDX.EDXF.MIME>
+        <UDX.EDXF.MIME_SOURCE>a.jpg</UDX.EDXF.MIME_SOURCE>
+      </UDX.EDXF.MIME>
+    </UDX.EDXF.MIME_INFO>
+    <UDX.SYSTEM.COLOR>red</UDX.SYSTEM.COLOR>
+  </USER_DEFINED_EXTENSIONS>
+</ARTICLE>`
+	var a Article
+	if err := xml.Unmarshal([]byte(input), &a); err != nil {
+		t.Fatal(err)
+	}
+	encoded, err := xml.Marshal(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct := appendArticle(nil, &a, "", nil)
+	for _, out := range []string{string(encoded), string(direct)} {
+		for _, want := range []string{
+			"<UDX.EDXF.MIME_SOURCE>a.jpg</UDX.EDXF.MIME_SOURCE>",
+			"<UDX.SYSTEM.COLOR>red</UDX.SYSTEM.COLOR>",
+		} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("want %s in\n%s", want, out)
+			}
+		}
+	}
+	if want, have := string(encoded), string(direct); want != have {
+		t.Fatalf("want\n%s\nhave\n%s", want, have)
+	}
+}
+
+func TestUDXChangedValueIsWritten(t *testing.T) {
+	input := `<ARTICLE>
+  <SUPPLIER_AID>1000</SUPPLIER_AID>
+  <USER_DEFINED_EXTENSIONS>
+    <UDX.SYSTEM.NOTE><![CDATA[a < b]]></UDX.SYSTEM.NOTE>
+    <UDX.SYSTEM.COLOR><b>red</b></UDX.SYSTEM.COLOR>
+  </USER_DEFINED_EXTENSIONS>
+</ARTICLE>`
+	var a Article
+	if err := xml.Unmarshal([]byte(input), &a); err != nil {
+		t.Fatal(err)
+	}
+	for _, field := range a.UDX.Fields {
+		switch field.Name {
+		case "SYSTEM.NOTE":
+			field.Value = "a > b"
+		case "SYSTEM.COLOR":
+			field.Value = "blue"
+		}
+	}
+	encoded, err := xml.Marshal(&a)
+	if err != nil {
+		t.Fatal(err)
+	}
+	direct := appendArticle(nil, &a, "", nil)
+	for _, out := range []string{string(encoded), string(direct)} {
+		for _, want := range []string{
+			"<UDX.SYSTEM.NOTE>a &gt; b</UDX.SYSTEM.NOTE>",
+			"<UDX.SYSTEM.COLOR>blue</UDX.SYSTEM.COLOR>",
+		} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("want %s in\n%s", want, out)
+			}
+		}
+	}
+}
@@ -0,0 +1,464 @@
+package bmecat12
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/olivere/bmecat/internal"
+)
+
+// DTD is a parsed document type definition. It only supports what is
+// required to validate BMEcat documents, i.e. ELEMENT and ATTLIST
+// declarations. Other declarations and comments are ignored.
+type DTD struct {
+	elements map[string]*dtdElement
+	attlists map[string][]*dtdAttribute
+}
+
+type dtdContent int
+
+const (
+	dtdContentChildren dtdContent = iota // element content
+	dtdContentMixed                      // (#PCDATA|...)*
+	dtdContentEmpty                      // EMPTY
+	dtdContentAny                        // ANY
+)
+
+type dtdElement struct {
+	name    string
+	model   string
+	content dtdContent
+	re      *regexp.Regexp      // for dtdContentChildren
+	allowed map[string]struct{} // for dtdContentMixed
+}
+
+type dtdAttribute struct {
+	name     string
+	values   []string // enumerated values, if any
+	required bool
+}
+
+var (
+	dtdDeclRe  = regexp.MustCompile(`(?s)<!(ELEMENT|ATTLIST)\s+(\S+)\s+(.*?)>`)
+	dtdAttrRe  = regexp.MustCompile(`(?s)\s*(\S+)\s+(\([^)]*\)|\S+)\s+(#REQUIRED|#IMPLIED|#FIXED\s+"[^"]*"|"[^"]*")`)
+	dtdNameRe  = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_.\-]*`)
+	dtdComment = regexp.MustCompile(`(?s)<!--.*?-->`)
+	dtdPERe    = regexp.MustCompile(`(?s)<!ENTITY\s+%\s+(\S+)\s+(?:"([^"]*)"|'([^']*)')\s*>`)
+)
+
+// maxDTDEntityDepth limits the nesting of parameter entities.
+const maxDTDEntityDepth = 8
+
+// ParseDTD parses the ELEMENT and ATTLIST declarations of a DTD, e.g. of
+// the official BMEcat 1.2 DTD files. Internal parameter entities, i.e.
+// <!ENTITY % name "...">, are expanded.
+func ParseDTD(s string) (*DTD, error) {
+	d := &DTD{
+		elements: make(map[string]*dtdElement),
+		attlists: make(map[string][]*dtdAttribute),
+	}
+	s = dtdComment.ReplaceAllString(s, "")
+	s, err := expandDTDEntities(s)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range dtdDeclRe.FindAllStringSubmatch(s, -1) {
+		kind, name, body := m[1], m[2], strings.TrimSpace(m[3])
+		switch kind {
+		case "ELEMENT":
+			e, err := parseDTDElement(name, body)
+			if err != nil {
+				return nil, err
+			}
+			d.elements[name] = e
+		case "ATTLIST":
+			for _, am := range dtdAttrRe.FindAllStringSubmatch(body, -1) {
+				attr := &dtdAttribute{
+					name:     am[1],
+					required: am[3] == "#REQUIRED",
+				}
+				if strings.HasPrefix(am[2], "(") {
+					for _, v := range strings.Split(strings.Trim(am[2], "()"), "|") {
+						attr.values = append(attr.values, strings.TrimSpace(v))
+					}
+				}
+				d.attlists[name] = append(d.attlists[name], attr)
+			}
+		}
+	}
+	return d, nil
+}
+
+// expandDTDEntities removes the parameter entity declarations from s and
+// replaces their references with their replacement text.
+func expandDTDEntities(s string) (string, error) {
+	var oldnew []string
+	for _, m := range dtdPERe.FindAllStringSubmatch(s, -1) {
+		oldnew = append(oldnew, "%"+m[1]+";", m[2]+m[3])
+	}
+	if len(oldnew) == 0 {
+		return s, nil
+	}
+	s = dtdPERe.ReplaceAllString(s, "")
+	r := strings.NewReplacer(oldnew...)
+	for i := 0; i < maxDTDEntityDepth; i++ {
+		expanded := r.Replace(s)
+		if expanded == s {
+			return s, nil
+		}
+		s = expanded
+	}
+	return "", fmt.Errorf("bmecat/dtd: parameter entities nested deeper than %d levels", maxDTDEntityDepth)
+}
+
+// MustParseDTD is like ParseDTD but panics if the DTD cannot be parsed.
+func MustParseDTD(s string) *DTD {
+	d, err := ParseDTD(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+// parseDTDElement parses the content model of an ELEMENT declaration.
+func parseDTDElement(name, model string) (*dtdElement, error) {
+	e := &dtdElement{name: name, model: model}
+	compact := strings.Join(strings.Fields(model), "")
+	switch {
+	case compact == "EMPTY":
+		e.content = dtdContentEmpty
+		return e, nil
+	case compact == "ANY":
+		e.content = dtdContentAny
+		return e, nil
+	case strings.HasPrefix(compact, "(#PCDATA"):
+		e.content = dtdContentMixed
+		e.allowed = make(map[string]struct{})
+		inner := strings.TrimSuffix(strings.TrimSuffix(compact, "*"), ")")
+		for _, child := range strings.Split(inner, "|")[1:] {
+			e.allowed[child] = struct{}{}
+		}
+		return e, nil
+	}
+
+	// Translate the content model into a regular expression that matches
+	// the names of the child elements, each followed by a comma.
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(compact); {
+		switch c := compact[i]; c {
+		case '(':
+			sb.WriteString("(?:")
+			i++
+		case ')', '|', '?', '*', '+':
+			sb.WriteByte(c)
+			i++
+		case ',':
+			i++
+		default:
+			child := dtdNameRe.FindString(compact[i:])
+			if child == "" {
+				return nil, fmt.Errorf("bmecat/dtd: invalid content model for element %s: %s", name, model)
+			}
+			sb.WriteString("(?:" + regexp.QuoteMeta(child) + ",)")
+			i += len(child)
+		}
+	}
+	sb.WriteString("$")
+	re, err := regexp.Compile(sb.String())
+	if err != nil {
+		return nil, fmt.Errorf("bmecat/dtd: invalid content model for element %s: %s", name, model)
+	}
+	e.content = dtdContentChildren
+	e.re = re
+	return e, nil
+}
+
+var (
+	newCatalogDTD     = MustParseDTD(NewCatalogDTD)
+	updateProductsDTD = MustParseDTD(UpdateProductsDTD)
+	updatePricesDTD   = MustParseDTD(UpdatePricesDTD)
+)
+
+// DTDForTransaction returns the embedded structural model of BMEcat 1.2
+// for the given transaction, see NewCatalogDTD.
+func DTDForTransaction(tx Transaction) *DTD {
+	switch tx {
+	case UpdateProducts:
+		return updateProductsDTD
+	case UpdatePrices:
+		return updatePricesDTD
+	default:
+		return newCatalogDTD
+	}
+}
+
+// ValidationError describes a violation of the DTD.
+type ValidationError struct {
+	// Element is the name of the element with the violation.
+	Element string
+	// Offset is the byte offset into the document around the violation.
+	Offset int64
+	// Message describes the violation.
+	Message string
+}
+
+// Error returns a string representation of the error.
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("bmecat/dtd: %s: %s around byte offset %d", e.Element, e.Message, e.Offset)
+}
+
+// ValidationErrors is a list of DTD violations.
+type ValidationErrors []*ValidationError
+
+// Error returns a string representation of the errors.
+func (e ValidationErrors) Error() string {
+	switch len(e) {
+	case 0:
+		return "bmecat/dtd: no errors"
+	case 1:
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0].Error(), len(e)-1)
+}
+
+// validationFrame keeps the state of an element while validating.
+type validationFrame struct {
+	e        *dtdElement
+	name     string
+	children strings.Builder
+}
+
+// Validate checks the document read from r against the DTD, i.e. it
+// checks that all elements are declared, that their children appear in
+// the order and with the occurrence declared in their content model,
+// and that required attributes are present. Elements with content ANY,
+// like USER_DEFINED_EXTENSIONS, are not checked further.
+//
+// Validate returns ValidationErrors if the document is valid XML but
+// violates the DTD. Only WithCharsetReader and WithEntityMap are applied
+// of the options, so documents with e.g. "&nbsp;" can be validated.
+func (d *DTD) Validate(r io.Reader, options ...ReaderOption) error {
+	dec := newValidationDecoder(r, options)
+
+	var errs ValidationErrors
+	report := func(element, format string, args ...interface{}) {
+		errs = append(errs, &ValidationError{
+			Element: element,
+			Offset:  dec.InputOffset(),
+			Message: fmt.Sprintf(format, args...),
+		})
+	}
+
+	var stack []*validationFrame
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return err
+		}
+		switch t := t.(type) {
+		case xml.StartElement:
+			name := t.Name.Local
+			if n := len(stack); n > 0 {
+				parent := stack[n-1]
+				parent.children.WriteString(name)
+				parent.children.WriteByte(',')
+				if parent.e != nil && parent.e.content == dtdContentAny {
+					if err := dec.Skip(); err != nil {
+						return err
+					}
+					continue
+				}
+			}
+			e, found := d.elements[name]
+			if !found {
+				report(name, "element is not declared")
+			} else {
+				d.validateAttributes(t, report)
+			}
+			stack = append(stack, &validationFrame{e: e, name: name})
+		case xml.EndElement:
+			n := len(stack)
+			if n == 0 {
+				break
+			}
+			f := stack[n-1]
+			stack = stack[:n-1]
+			if f.e == nil {
+				break
+			}
+			children := f.children.String()
+			switch f.e.content {
+			case dtdContentChildren:
+				if !f.e.re.MatchString(children) {
+					report(f.name, "content (%s) does not match %s", strings.TrimSuffix(children, ","), f.e.model)
+				}
+			case dtdContentEmpty:
+				if children != "" {
+					report(f.name, "element must be empty")
+				}
+			case dtdContentMixed:
+				for _, child := range strings.Split(strings.TrimSuffix(children, ","), ",") {
+					if _, ok := f.e.allowed[child]; child != "" && !ok {
+						report(f.name, "element %s is not allowed here", child)
+					}
+				}
+			}
+		case xml.CharData:
+			if n := len(stack); n > 0 {
+				f := stack[n-1]
+				if f.e == nil || len(strings.TrimSpace(string(t))) == 0 {
+					break
+				}
+				switch f.e.content {
+				case dtdContentChildren, dtdContentEmpty:
+					report(f.name, "character data is not allowed")
+				}
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+// validateAttributes checks the attributes of se.
+func (d *DTD) validateAttributes(se xml.StartElement, report func(string, string, ...interface{})) {
+	for _, attr := range d.attlists[se.Name.Local] {
+		var value string
+		var found bool
+		for _, a := range se.Attr {
+			if a.Name.Local == attr.name && a.Name.Space == "" {
+				value, found = a.Value, true
+				break
+			}
+		}
+		if !found {
+			if attr.required {
+				report(se.Name.Local, "required attribute %s is missing", attr.name)
+			}
+			continue
+		}
+		if len(attr.values) > 0 {
+			var ok bool
+			for _, v := range attr.values {
+				if v == value {
+					ok = true
+					break
+				}
+			}
+			if !ok {
+				report(se.Name.Local, "attribute %s has invalid value %q", attr.name, value)
+			}
+		}
+	}
+}
+
+// ValidateFile is like Validate, but reads gzip-compressed and UTF-16
+// documents like the Reader does.
+func (d *DTD) ValidateFile(r io.ReadSeeker, options ...ReaderOption) error {
+	r, err := detectInput(r)
+	if err != nil {
+		return err
+	}
+	return d.Validate(r, options...)
+}
+
+// ValidateDTD validates a BMEcat document against the embedded DTD of its
+// transaction. See DTD.Validate for details. Like the Reader, it reads
+// gzip-compressed and UTF-16 documents.
+//
+// The embedded DTD is not the official one, see NewCatalogDTD, so the
+// results may differ from a validation against the official DTD. Use
+// ValidateDTDDir for that.
+func ValidateDTD(r io.ReadSeeker, options ...ReaderOption) error {
+	return validateDTD(r, options, func(tx Transaction) (*DTD, error) {
+		return DTDForTransaction(tx), nil
+	})
+}
+
+// ValidateDTDDir validates a BMEcat document against the DTD of its
+// transaction in dir, e.g. the official bmecat_new_catalog.dtd for a
+// T_NEW_CATALOG document; see Transaction.DocType for the file names.
+// Otherwise, it works like ValidateDTD.
+func ValidateDTDDir(r io.ReadSeeker, dir string, options ...ReaderOption) error {
+	return validateDTD(r, options, func(tx Transaction) (*DTD, error) {
+		data, err := ioutil.ReadFile(filepath.Join(dir, tx.DocType()))
+		if err != nil {
+			return nil, err
+		}
+		return ParseDTD(string(data))
+	})
+}
+
+// validateDTD validates a document against the DTD that dtd returns for
+// its transaction.
+func validateDTD(r io.ReadSeeker, options []ReaderOption, dtd func(Transaction) (*DTD, error)) error {
+	r, err := detectInput(r)
+	if err != nil {
+		return err
+	}
+	tx, err := scanTransaction(r, options)
+	if err != nil {
+		return err
+	}
+	d, err := dtd(tx)
+	if err != nil {
+		return err
+	}
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	return d.Validate(r, options...)
+}
+
+// scanTransaction reads r up to the transaction element and returns
+// the transaction of the document.
+func scanTransaction(r io.ReadSeeker, options []ReaderOption) (Transaction, error) {
+	if _, err := r.Seek(0, io.SeekStart); err != nil {
+		return NewCatalog, err
+	}
+	dec := newValidationDecoder(r, options)
+	for {
+		t, err := dec.Token()
+		if err == io.EOF {
+			return NewCatalog, nil
+		}
+		if err != nil {
+			return NewCatalog, err
+		}
+		if se, ok := t.(xml.StartElement); ok {
+			switch se.Name.Local {
+			case "HEADER":
+				if err := dec.Skip(); err != nil {
+					return NewCatalog, err
+				}
+			case "T_NEW_CATALOG", "T_UPDATE_PRODUCTS", "T_UPDATE_PRICES":
+				tx, _ := transactionFromElement(se)
+				return tx, nil
+			}
+		}
+	}
+}
+
+// newValidationDecoder returns a decoder for r that applies the
+// WithCharsetReader and WithEntityMap options.
+func newValidationDecoder(r io.Reader, options []ReaderOption) *xml.Decoder {
+	rd := &Reader{charsetReader: internal.AutoCharsetReader}
+	for _, o := range options {
+		o(rd)
+	}
+	dec := xml.NewDecoder(r)
+	dec.CharsetReader = rd.charsetReader
+	dec.Entity = rd.entities
+	return dec
+}
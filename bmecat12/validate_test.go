@@ -0,0 +1,212 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"golang.org/x/text/encoding/unicode"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestValidateDTDGoldenFiles(t *testing.T) {
+	for _, name := range []string{
+		"new_catalog.golden.xml",
+		"new_catalog_with_blank_classification_system.golden.xml",
+		"update_products.golden.xml",
+		"update_prices.golden.xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if err := bmecat12.ValidateDTD(f); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestValidateDTDDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-dtd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// The DTD of the transaction is read from dir
+	if err := bmecat12.ValidateDTDDir(bytes.NewReader(data), dir); !os.IsNotExist(err) {
+		t.Fatalf("want missing file, have %v", err)
+	}
+	dtd := strings.Replace(bmecat12.UpdateProductsDTD, "<!ELEMENT SUPPLIER_AID (#PCDATA)>", "<!ELEMENT SUPPLIER_AID EMPTY>", 1)
+	if err := ioutil.WriteFile(filepath.Join(dir, bmecat12.DocTypeUpdateProducts), []byte(dtd), 0600); err != nil {
+		t.Fatal(err)
+	}
+	err = bmecat12.ValidateDTDDir(bytes.NewReader(data), dir)
+	var verrs bmecat12.ValidationErrors
+	if !errors.As(err, &verrs) || verrs[0].Element != "SUPPLIER_AID" {
+		t.Fatalf("want violation of SUPPLIER_AID, have %v", err)
+	}
+}
+
+func TestValidateDTDViolations(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+    <BUYER><BUYER_NAME>BuyCo</BUYER_NAME></BUYER>
+    <SUPPLIER><SUPPLIER_NAME>SupplyCo</SUPPLIER_NAME></SUPPLIER>
+    <USER_DEFINED_EXTENSIONS><UDX.FOO><UDX.BAR>1</UDX.BAR></UDX.FOO></USER_DEFINED_EXTENSIONS>
+  </HEADER>
+  <T_UPDATE_PRICES>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_PRICE_DETAILS>
+        <ARTICLE_PRICE><PRICE_AMOUNT>1.5</PRICE_AMOUNT><FOO/></ARTICLE_PRICE>
+      </ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRICES>
+</BMECAT>`
+	err := bmecat12.ValidateDTD(strings.NewReader(doc))
+	errs, ok := err.(bmecat12.ValidationErrors)
+	if !ok {
+		t.Fatalf("want ValidationErrors, have %T: %v", err, err)
+	}
+	var have []string
+	for _, e := range errs {
+		have = append(have, e.Element+": "+e.Message)
+	}
+	want := []string{
+		"CATALOG: content (CATALOG_ID,LANGUAGE,CATALOG_VERSION) does not match (LANGUAGE, CATALOG_ID, CATALOG_VERSION, CATALOG_NAME?, DATETIME?, TERRITORY*, CURRENCY?, MIME_ROOT?, PRICE_FLAG*)",
+		"T_UPDATE_PRICES: required attribute prev_version is missing",
+		"ARTICLE_PRICE: required attribute price_type is missing",
+		"FOO: element is not declared",
+		"ARTICLE_PRICE: content (PRICE_AMOUNT,FOO) does not match (PRICE_AMOUNT, PRICE_CURRENCY?, TAX?, PRICE_FACTOR?, LOWER_BOUND?, TERRITORY*)",
+	}
+	if len(want) != len(have) {
+		t.Fatalf("want %d errors, have %d:\n%s", len(want), len(have), strings.Join(have, "\n"))
+	}
+	for i := range want {
+		if want[i] != have[i] {
+			t.Errorf("#%d: want %q, have %q", i, want[i], have[i])
+		}
+	}
+}
+
+func TestValidateDTDInputEncoding(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	utf16 := bytes.Replace(data, []byte(`encoding="UTF-8"`), []byte(`encoding="UTF-16"`), 1)
+	utf16, err = unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewEncoder().Bytes(utf16)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"gzip", gzipped(t, data)},
+		{"UTF-16LE with BOM", utf16},
+		{"gzip and UTF-16LE with BOM", gzipped(t, utf16)},
+	}
+	for _, tt := range tests {
+		if err := bmecat12.ValidateDTD(bytes.NewReader(tt.data)); err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+	}
+}
+
+func TestValidateDTDWithEntityMap(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+      <CATALOG_NAME>Gr&uuml;&szlig;e</CATALOG_NAME>
+    </CATALOG>
+    <BUYER><BUYER_NAME>BuyCo</BUYER_NAME></BUYER>
+    <SUPPLIER><SUPPLIER_NAME>SupplyCo</SUPPLIER_NAME></SUPPLIER>
+  </HEADER>
+  <T_UPDATE_PRODUCTS prev_version="1">
+    <ARTICLE mode="update">
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS>
+        <DESCRIPTION_SHORT>Short&nbsp;text</DESCRIPTION_SHORT>
+      </ARTICLE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRODUCTS>
+</BMECAT>`
+	if err := bmecat12.ValidateDTD(strings.NewReader(doc)); err == nil {
+		t.Fatal("want error without entity map, have nil")
+	}
+	if err := bmecat12.ValidateDTD(strings.NewReader(doc), bmecat12.WithEntityMap(bmecat12.HTMLEntityMap())); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestValidateDTDHeaderAndSegments(t *testing.T) {
+	doc := `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+    <BUYER><BUYER_NAME>BuyCo</BUYER_NAME></BUYER>
+    <LEGAL_INFO>Terms</LEGAL_INFO>
+    <SUPPLIER><SUPPLIER_NAME>SupplyCo</SUPPLIER_NAME></SUPPLIER>
+  </HEADER>
+  <T_UPDATE_PRODUCTS prev_version="1">
+    <ARTICLE mode="update">
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS>
+        <DESCRIPTION_SHORT>Short</DESCRIPTION_SHORT>
+        <SEGMENT>A</SEGMENT>
+        <SEGMENT>B</SEGMENT>
+      </ARTICLE_DETAILS>
+    </ARTICLE>
+  </T_UPDATE_PRODUCTS>
+</BMECAT>`
+	if err := bmecat12.ValidateDTD(strings.NewReader(doc)); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseDTDParameterEntities(t *testing.T) {
+	d, err := bmecat12.ParseDTD(`
+<!ENTITY % text "(#PCDATA)">
+<!ENTITY % names "FIRST, LAST?">
+<!ELEMENT NAME (%names;)>
+<!ELEMENT FIRST %text;>
+<!ELEMENT LAST %text;>
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Validate(strings.NewReader(`<NAME><FIRST>A</FIRST><LAST>B</LAST></NAME>`)); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.Validate(strings.NewReader(`<NAME><LAST>B</LAST></NAME>`)); err == nil {
+		t.Fatal("want an error for a missing FIRST")
+	}
+}
@@ -0,0 +1,142 @@
+package bmecat12
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Warning describes a non-fatal specification issue found by the Reader,
+// e.g. an unknown price type or an empty ORDER_UNIT.
+type Warning struct {
+	// Path is the path to the element with the issue, e.g.
+	// "ARTICLE/ARTICLE_PRICE_DETAILS[0]/ARTICLE_PRICE[1]".
+	Path string
+	// SupplierAID is the SUPPLIER_AID of the article with the issue, if any.
+	SupplierAID string
+	// Offset is the byte offset of the enclosing top-level element, e.g.
+	// the ARTICLE.
+	Offset int64
+	// Message describes the issue.
+	Message string
+}
+
+// String returns a string representation of the warning.
+func (w *Warning) String() string {
+	if w.SupplierAID != "" {
+		return fmt.Sprintf("%s: %s (SUPPLIER_AID %q, around byte offset %d)", w.Path, w.Message, w.SupplierAID, w.Offset)
+	}
+	return fmt.Sprintf("%s: %s (around byte offset %d)", w.Path, w.Message, w.Offset)
+}
+
+// WarningHandler, if implemented by a handler, is called for each
+// non-fatal specification issue the Reader finds. Warnings for an
+// element are reported before the element is passed to its handler.
+type WarningHandler interface {
+	HandleWarning(*Warning) error
+}
+
+var knownPriceTypes = map[string]bool{
+	ArticlePriceTypeNetList:        true,
+	ArticlePriceTypeGrosList:       true,
+	ArticlePriceTypeNetCustomer:    true,
+	ArticlePriceTypeNRP:            true,
+	ArticlePriceTypeNetCustomerExp: true,
+}
+
+var knownArticleStatus = map[string]bool{
+	ArticleStatusBargain:     true,
+	ArticleStatusNewArticle:  true,
+	ArticleStatusOldArticle:  true,
+	ArticleStatusNew:         true,
+	ArticleStatusUsed:        true,
+	ArticleStatusRefurbished: true,
+	ArticleStatusCoreArticle: true,
+	ArticleStatusOthers:      true,
+}
+
+var knownMimePurposes = map[string]bool{
+	MimePurposeThumbnail: true,
+	MimePurposeNormal:    true,
+	MimePurposeDetail:    true,
+	MimePurposeDataSheet: true,
+	MimePurposeLogo:      true,
+	MimePurposeOthers:    true,
+}
+
+// articleWarnings checks the article for non-fatal specification issues.
+func articleWarnings(a *Article, tx Transaction, offset int64) []*Warning {
+	var warnings []*Warning
+	warn := func(path, format string, args ...interface{}) {
+		warnings = append(warnings, &Warning{
+			Path:        "ARTICLE" + path,
+			SupplierAID: a.SupplierAID,
+			Offset:      offset,
+			Message:     fmt.Sprintf(format, args...),
+		})
+	}
+
+	if strings.TrimSpace(a.SupplierAID) == "" {
+		warn("/SUPPLIER_AID", "empty SUPPLIER_AID")
+	}
+	if err := a.ValidateMode(tx); err != nil {
+		warn("", "%v", err)
+	}
+	if a.Mode == ArticleModeDelete || tx == UpdatePrices {
+		return warnings
+	}
+
+	if d := a.Details; d != nil {
+		if strings.TrimSpace(d.DescriptionShort) == "" {
+			warn("/ARTICLE_DETAILS/DESCRIPTION_SHORT", "empty DESCRIPTION_SHORT")
+		}
+		for i, s := range d.ArticleStatus {
+			if !knownArticleStatus[s.Type] {
+				warn(fmt.Sprintf("/ARTICLE_DETAILS/ARTICLE_STATUS[%d]", i), "unknown type %q", s.Type)
+			}
+		}
+	}
+	if od := a.OrderDetails; od != nil && strings.TrimSpace(od.OrderUnit) == "" {
+		warn("/ARTICLE_ORDER_DETAILS/ORDER_UNIT", "empty ORDER_UNIT")
+	}
+	for i, pd := range a.PriceDetails {
+		for j, p := range pd.Prices {
+			if !knownPriceTypes[p.Type] && !strings.HasPrefix(p.Type, "udp_") {
+				warn(fmt.Sprintf("/ARTICLE_PRICE_DETAILS[%d]/ARTICLE_PRICE[%d]", i, j), "unknown price_type %q", p.Type)
+			}
+		}
+	}
+	if a.MimeInfo != nil {
+		for i, m := range a.MimeInfo.Mimes {
+			if strings.TrimSpace(m.Source) == "" {
+				warn(fmt.Sprintf("/MIME_INFO/MIME[%d]/MIME_SOURCE", i), "empty MIME_SOURCE")
+			}
+			if m.Purpose != "" && !knownMimePurposes[m.Purpose] {
+				warn(fmt.Sprintf("/MIME_INFO/MIME[%d]/MIME_PURPOSE", i), "unknown MIME_PURPOSE %q", m.Purpose)
+			}
+		}
+	}
+	return warnings
+}
+
+// catalogGroupWarnings checks the catalog group for non-fatal
+// specification issues.
+func catalogGroupWarnings(cg *CatalogGroup, offset int64) []*Warning {
+	var warnings []*Warning
+	switch cg.Type {
+	case "root", "node", "leaf":
+	default:
+		warnings = append(warnings, &Warning{
+			Path:    "CATALOG_STRUCTURE",
+			Offset:  offset,
+			Message: fmt.Sprintf("unknown type %q in group %q", cg.Type, cg.ID),
+		})
+	}
+	if strings.TrimSpace(cg.Name) == "" {
+		warnings = append(warnings, &Warning{
+			Path:    "CATALOG_STRUCTURE/GROUP_NAME",
+			Offset:  offset,
+			Message: fmt.Sprintf("empty GROUP_NAME in group %q", cg.ID),
+		})
+	}
+	return warnings
+}
@@ -0,0 +1,147 @@
+package bmecat12_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+const warningsDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+    </CATALOG>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <CATALOG_GROUP_SYSTEM>
+      <CATALOG_STRUCTURE type="branch">
+        <GROUP_ID>1</GROUP_ID>
+        <GROUP_NAME>Root</GROUP_NAME>
+        <PARENT_ID>0</PARENT_ID>
+      </CATALOG_STRUCTURE>
+    </CATALOG_GROUP_SYSTEM>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Good</DESCRIPTION_SHORT></ARTICLE_DETAILS>
+      <ARTICLE_ORDER_DETAILS><ORDER_UNIT>C62</ORDER_UNIT></ARTICLE_ORDER_DETAILS>
+      <ARTICLE_PRICE_DETAILS><ARTICLE_PRICE price_type="udp_special"><PRICE_AMOUNT>1.5</PRICE_AMOUNT></ARTICLE_PRICE></ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>2000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Bad</DESCRIPTION_SHORT></ARTICLE_DETAILS>
+      <ARTICLE_ORDER_DETAILS><ORDER_UNIT></ORDER_UNIT></ARTICLE_ORDER_DETAILS>
+      <ARTICLE_PRICE_DETAILS>
+        <ARTICLE_PRICE price_type="net_list"><PRICE_AMOUNT>1.5</PRICE_AMOUNT></ARTICLE_PRICE>
+        <ARTICLE_PRICE price_type="retail"><PRICE_AMOUNT>2.5</PRICE_AMOUNT></ARTICLE_PRICE>
+      </ARTICLE_PRICE_DETAILS>
+    </ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+func TestReadWithWarningHandler(t *testing.T) {
+	var warnings []*bmecat12.Warning
+	var aids []string
+	h := bmecat12.HandlerFuncs{
+		OnWarning: func(w *bmecat12.Warning) error {
+			warnings = append(warnings, w)
+			return nil
+		},
+		OnArticle: func(a *bmecat12.Article) error {
+			aids = append(aids, a.SupplierAID)
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(strings.NewReader(warningsDoc)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	if want, have := 3, len(warnings); want != have {
+		t.Fatalf("want %d warnings, have %d: %v", want, have, warnings)
+	}
+
+	if want, have := "CATALOG_STRUCTURE", warnings[0].Path; want != have {
+		t.Errorf("want Path=%q, have %q", want, have)
+	}
+	if want, have := int64(strings.Index(warningsDoc, "<CATALOG_STRUCTURE")), warnings[0].Offset; want < have {
+		t.Errorf("want Offset<=%d, have %d", want, have)
+	}
+
+	if want, have := "ARTICLE/ARTICLE_ORDER_DETAILS/ORDER_UNIT", warnings[1].Path; want != have {
+		t.Errorf("want Path=%q, have %q", want, have)
+	}
+	if want, have := "2000", warnings[1].SupplierAID; want != have {
+		t.Errorf("want SupplierAID=%q, have %q", want, have)
+	}
+	if want, have := "empty ORDER_UNIT", warnings[1].Message; want != have {
+		t.Errorf("want Message=%q, have %q", want, have)
+	}
+
+	if want, have := "ARTICLE/ARTICLE_PRICE_DETAILS[0]/ARTICLE_PRICE[1]", warnings[2].Path; want != have {
+		t.Errorf("want Path=%q, have %q", want, have)
+	}
+	if want, have := `unknown price_type "retail"`, warnings[2].Message; want != have {
+		t.Errorf("want Message=%q, have %q", want, have)
+	}
+	articleOffset := int64(strings.Index(warningsDoc, "<ARTICLE>\n      <SUPPLIER_AID>2000"))
+	if warnings[2].Offset > articleOffset || warnings[2].Offset <= warnings[0].Offset {
+		t.Errorf("want Offset around %d, have %d", articleOffset, warnings[2].Offset)
+	}
+}
+
+func TestReadGoldensWithoutWarnings(t *testing.T) {
+	for _, name := range []string{
+		"new_catalog.golden.xml",
+		"update_products.golden.xml",
+		"update_prices.golden.xml",
+	} {
+		t.Run(name, func(t *testing.T) {
+			f, err := os.Open(filepath.Join("testdata", name))
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			h := bmecat12.HandlerFuncs{
+				OnWarning: func(w *bmecat12.Warning) error {
+					t.Errorf("unexpected warning: %v", w)
+					return nil
+				},
+			}
+			if err := bmecat12.NewReader(f).Do(context.Background(), h); err != nil {
+				t.Fatal(err)
+			}
+		})
+	}
+}
+
+func TestReadWithWarningHandlerInvalidMode(t *testing.T) {
+	doc := strings.Replace(warningsDoc, "<ARTICLE>\n      <SUPPLIER_AID>1000", "<ARTICLE mode=\"update\">\n      <SUPPLIER_AID>1000", 1)
+	var warnings []*bmecat12.Warning
+	h := bmecat12.HandlerFuncs{
+		OnWarning: func(w *bmecat12.Warning) error {
+			if w.SupplierAID == "1000" {
+				warnings = append(warnings, w)
+			}
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(strings.NewReader(doc)).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 1, len(warnings); want != have {
+		t.Fatalf("want %d warnings, have %d: %v", want, have, warnings)
+	}
+	want := (&bmecat12.Article{Mode: "update"}).ValidateMode(bmecat12.NewCatalog).Error()
+	if have := warnings[0].Message; want != have {
+		t.Fatalf("want Message=%q, have %q", want, have)
+	}
+}
@@ -1,15 +1,23 @@
 package bmecat12
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
 	"encoding/xml"
+	"errors"
 	"fmt"
+	"hash"
 	"io"
 	"sync/atomic"
+	"time"
 
-	"github.com/pkg/errors"
+	"golang.org/x/text/encoding/charmap"
+	"golang.org/x/text/language"
 )
 
+// Transaction is the mode of a catalog, i.e. one of the BMEcat 1.2
+// transactions.
 type Transaction byte
 
 const (
@@ -18,6 +26,22 @@ const (
 	UpdatePrices
 )
 
+// ParseTransaction returns the transaction with the given element name,
+// e.g. UpdatePrices for "T_UPDATE_PRICES", as returned by String.
+func ParseTransaction(s string) (Transaction, error) {
+	switch s {
+	case "T_NEW_CATALOG":
+		return NewCatalog, nil
+	case "T_UPDATE_PRODUCTS":
+		return UpdateProducts, nil
+	case "T_UPDATE_PRICES":
+		return UpdatePrices, nil
+	}
+	return NewCatalog, fmt.Errorf("bmecat: unknown transaction %q", s)
+}
+
+// String returns the element name of the transaction, e.g.
+// "T_UPDATE_PRICES".
 func (t Transaction) String() string {
 	switch t {
 	default:
@@ -40,6 +64,26 @@ type CatalogWriter interface {
 	Articles(context.Context) (<-chan *Article, <-chan error)
 }
 
+// PrologWriter, if implemented by a CatalogWriter, is used to write the
+// prolog of the document, e.g. to preserve comments and processing
+// instructions when copying a catalog. If the prolog has no DOCTYPE,
+// the DOCTYPE of the transaction is written.
+type PrologWriter interface {
+	Prolog() *Prolog
+}
+
+// FeatureSystemWriter, if implemented by a CatalogWriter, is used to
+// write the FEATURE_SYSTEM elements of a new catalog.
+type FeatureSystemWriter interface {
+	FeatureSystems() []*FeatureSystem
+}
+
+// CatalogGroupSystemWriter, if implemented by a CatalogWriter, is used to
+// write the CATALOG_GROUP_SYSTEM of a new catalog.
+type CatalogGroupSystemWriter interface {
+	CatalogGroupSystem() *CatalogGroupSystem
+}
+
 // Writer allows writing BMEcat 1.2 catalog files.
 type Writer struct {
 	w        io.Writer
@@ -51,6 +95,99 @@ type Writer struct {
 	// Transaction specifies the mode of the catalog, e.g. "T_NEW_CATALOG" (default),
 	// "T_UPDATE_PRODUCTS", or "T_UPDATE_PRICES".
 	transaction Transaction
+	// groupMaps writes the CatalogGroupIDs of the articles, see
+	// WithCatalogGroupMaps, and maps spills them until the end.
+	groupMaps bool
+	maps      *mapSpill
+	// mapsReported is set once the progress of the catalog group
+	// mappings has been reported.
+	mapsReported bool
+	// provenance is recorded in the catalog as specified by provenanceScope.
+	provenance      *Provenance
+	provenanceScope ProvenanceScope
+	// stamp is the provenance of the current run.
+	stamp *Provenance
+	// udxOrder is the order of the UDX fields, if any.
+	udxOrder UDXOrder
+	// udxDedup emits only one UDX field per name.
+	udxDedup bool
+	// instr receives measurements, see WithWriterInstrumentation.
+	instr Instrumentation
+	// out is w, instrumented if necessary.
+	out io.Writer
+	// logger receives log events, see WithWriterLogger.
+	logger logger
+	// territoryMacros are expanded in TERRITORY, see WithTerritoryMacros.
+	territoryMacros TerritoryMacros
+	// extensions are embedded at extension points, see WithExtension.
+	extensions map[ExtensionPoint][]Extension
+	// namespaces are declared on the BMECAT element, see WithNamespace.
+	namespaces map[string]string
+	// docType is the system identifier of the DOCTYPE, see WithDocType,
+	// and noDocType omits the DOCTYPE, see WithoutDocType.
+	docType   string
+	noDocType bool
+	// encoding of the output and the handling of unmappable characters,
+	// see WithEncoding. closer flushes the transcoded output.
+	encoding   string
+	unmappable UnmappablePolicy
+	closer     io.Closer
+	charmap    *charmap.Charmap
+	// cdata are the names of the elements written as CDATA, see
+	// WithCDATA.
+	cdata map[string]bool
+	// numbers are the formats of the numbers by element name, see
+	// WithNumberFormat.
+	numbers map[string]numberFormat
+	// sanitize replaces illegal characters, see WithSanitize.
+	sanitize *sanitizer
+	// noXMLLang omits the xml:lang attribute, see WithXMLLang.
+	noXMLLang bool
+	// validators check the articles before writing them, see
+	// WithArticleValidator.
+	validators []func(*Article) error
+	// outputOrder are the lists sorted before encoding, see
+	// WithOutputOrder.
+	outputOrder OutputOrder
+	// progressFunc reports detailed progress, see WithWriterProgressInfo,
+	// with the state of the current run in progressState.
+	progressFunc  WriterProgressInfoFunc
+	progressState *writerProgress
+	// newHash creates the hash of the output, see WithChecksum, and
+	// checksum is its sum for the last call to Do.
+	newHash  func() hash.Hash
+	hash     hash.Hash
+	checksum []byte
+	// gzip compresses the output at gzipLevel, see WithGzip. zw is the
+	// gzip.Writer of the current document.
+	gzip      bool
+	gzipLevel int
+	zw        *gzip.Writer
+	// pkg collects the MIME files of a PackageWriter.
+	pkg *packageAssets
+	// mimeSourceMode rewrites MIME_SOURCE relative to mimeRoot, the
+	// MIME_ROOT of the header, see WithMimeSourceMode.
+	mimeSourceMode MimeSourceMode
+	mimeRoot       string
+	// stream is the catalog written incrementally, see Begin.
+	stream *writerStream
+	// noDirect disables the direct encoding of articles, see
+	// WithDirectEncoding, and raw buffers the articles encoded directly.
+	// txStarted is true if the last element encoded is the start of the
+	// transaction, as the xml.Encoder would not indent the end of the
+	// transaction if only articles encoded directly follow.
+	noDirect  bool
+	raw       []byte
+	txStarted bool
+	// concurrency is the number of goroutines that encode articles, see
+	// WithWriterConcurrency.
+	concurrency int
+	// skipValidation disables the check of the HEADER, see
+	// WithSkipValidation.
+	skipValidation bool
+	// skipModeValidation disables the check of the article modes, see
+	// WithSkipModeValidation.
+	skipModeValidation bool
 }
 
 // NewWriter creates a new Writer. It expects an underlying io.Writer
@@ -74,6 +211,61 @@ func WithIndent(indent string) WriterOption {
 	}
 }
 
+// WithXMLLang specifies whether to write the language of the catalog,
+// as returned by CatalogWriter.Language, as xml:lang attribute of the
+// BMECAT element, e.g. xml:lang="de" for "deu". It is enabled by
+// default.
+func WithXMLLang(enabled bool) WriterOption {
+	return func(w *Writer) {
+		w.noXMLLang = !enabled
+	}
+}
+
+// WithArticleValidator makes the Writer check each article with f before
+// writing it, e.g. for a missing DESCRIPTION_SHORT or ORDER_UNIT. If f
+// returns an error wrapping ErrSkipArticle, the article is skipped and
+// logged. Any other error stops the Writer; it is returned wrapped in an
+// EncodeError. The option may be given more than once; the validators
+// are called in the order given.
+func WithArticleValidator(f func(*Article) error) WriterOption {
+	return func(w *Writer) {
+		w.validators = append(w.validators, f)
+	}
+}
+
+// WithSkipValidation disables the check of the HEADER before writing. By
+// default, Do fails with an error wrapping a HeaderError before writing
+// anything if the HEADER or one of its mandatory fields, e.g.
+// CATALOG_ID, is missing. The modes of the articles are still checked,
+// see WithSkipModeValidation.
+func WithSkipValidation() WriterOption {
+	return func(w *Writer) {
+		w.skipValidation = true
+	}
+}
+
+// WithSkipModeValidation disables the check of the mode of each article.
+// By default, the Writer fails with an EncodeError wrapping
+// ErrInvalidMode if the mode of an article is not allowed in the
+// transaction, see Article.ValidateMode.
+func WithSkipModeValidation() WriterOption {
+	return func(w *Writer) {
+		w.skipModeValidation = true
+	}
+}
+
+// preflight checks the HEADER of writer, unless WithSkipValidation is
+// set.
+func (w *Writer) preflight(writer CatalogWriter) error {
+	if w.skipValidation {
+		return nil
+	}
+	if err := checkHeader(writer.Header()); err != nil {
+		return &EncodeError{Element: "HEADER", Err: err}
+	}
+	return nil
+}
+
 // WithProgress reports the current number of articles as they are written.
 func WithProgress(f WriteProgress) WriterOption {
 	return func(w *Writer) {
@@ -87,14 +279,7 @@ type WriteProgress func(written int)
 
 // xmlNamespace returns the XML namespace to use for the output.
 func (w *Writer) xmlNamespace(writer CatalogWriter) string {
-	switch writer.Transaction() {
-	case UpdateProducts:
-		return "http://www.bmecat.org/bmecat/1.2/bmecat_update_products"
-	case UpdatePrices:
-		return "http://www.bmecat.org/bmecat/1.2/bmecat_update_prices"
-	default:
-		return "http://www.bmecat.org/bmecat/1.2/bmecat_new_catalog"
-	}
+	return writer.Transaction().Namespace()
 }
 
 // txStartElement returns the XML StartElement for the BMEcat transaction,
@@ -125,76 +310,423 @@ func (w *Writer) txEndElement(writer CatalogWriter) xml.EndElement {
 // If the articles channel is closed, Do will write the rest of
 // the BMEcat file, and then return.
 func (w *Writer) Do(ctx context.Context, writer CatalogWriter) error {
-	w.enc = xml.NewEncoder(w.w)
+	log := logOrNop(w.logger)
+	started := time.Now()
+	log.Info("bmecat: writing catalog started", "transaction", writer.Transaction().String())
+	if err := w.preflight(writer); err != nil {
+		return err
+	}
+	w.startProgress()
+	defer w.discardMaps()
+	if err := w.begin(writer, true); err != nil {
+		return err
+	}
+	if writer.Transaction() == NewCatalog {
+		if err := w.writeStructure(writer, nil); err != nil {
+			return err
+		}
+	}
+
+	// ARTICLE
+	written, err := w.writeArticles(ctx, writer)
+	if err != nil {
+		return err
+	}
+	if err := w.flushRaw(); err != nil {
+		return err
+	}
+	if err := w.writeCatalogGroupMaps(ctx, writer); err != nil {
+		return err
+	}
+	if err := w.end(writer); err != nil {
+		return err
+	}
+	if err := w.reportProgress("", false, true); err != nil {
+		return err
+	}
+	log.Info("bmecat: writing catalog completed", "articles", written, "elapsed", time.Since(started))
+	return nil
+}
+
+// begin starts a new document with the lead-in, the HEADER unless
+// withHeader is false, and the start of the transaction element.
+func (w *Writer) begin(writer CatalogWriter, withHeader bool) error {
+	w.out = w.startChecksum(w.w)
+	zw, err := w.gzipWriter(w.out)
+	if err != nil {
+		return err
+	}
+	w.zw = zw
+	if zw != nil {
+		w.out = zw
+	}
+	if w.instr != nil {
+		w.out = &instrumentedWriter{w: w.out, instr: w.instr}
+	}
+	if w.progressState != nil {
+		w.out = &progressWriter{w: w.out, n: &w.progressState.bytes}
+	}
+	ew, err := w.encodingWriter(w.out)
+	if err != nil {
+		return err
+	}
+	w.closer = nil
+	if ew != nil {
+		w.out, w.closer = ew, ew
+	}
+	w.enc = xml.NewEncoder(w.out)
 	if w.indent != "" {
 		w.enc.Indent("", w.indent)
 	}
+	w.discardMaps()
+	w.mapsReported = false
+	w.raw = w.raw[:0]
+	w.txStarted = false
 	if err := w.writeLeadIn(writer); err != nil {
-		return errors.Wrap(err, "bmecat/v12: unable to write lead in")
+		return &EncodeError{Element: "BMECAT", Err: err}
+	}
+	w.stamp = nil
+	if w.provenance != nil {
+		stamp := *w.provenance
+		if stamp.Timestamp.IsZero() {
+			stamp.Timestamp = time.Now().UTC()
+		}
+		w.stamp = &stamp
+	}
+	w.startMimeSources(writer)
+	var header *Header
+	if withHeader {
+		header = writer.Header()
 	}
-	header := writer.Header()
 	if header != nil {
-		if err := w.enc.Encode(header); err != nil {
-			return errors.Wrap(err, "bmecat/v12: unable to write Header")
+		header = w.sanitizeElement(header, "HEADER", "").(*Header)
+		header = w.expandHeaderTerritories(header)
+		header = w.packageHeader(header)
+		udx, err := w.prepareUDX(header.UDX, w.provenanceScope&ProvenanceHeader != 0)
+		if err != nil {
+			return &EncodeError{Element: "HEADER", Err: err}
+		}
+		if udx != header.UDX {
+			prepared := *header
+			prepared.UDX = udx
+			header = &prepared
+		}
+	}
+	if header != nil {
+		if err := w.reportProgress("HEADER", false, false); err != nil {
+			return &EncodeError{Element: "HEADER", Err: err}
+		}
+		if err := w.encodeWithExtensions(header, ExtensionHeader); err != nil {
+			return &EncodeError{Element: "HEADER", Err: err}
 		}
 	}
 	tx := writer.Transaction().String()
 	if err := w.enc.EncodeToken(w.txStartElement(writer)); err != nil {
-		return errors.Wrapf(err, "bmecat/v12: unable to write opening %s", tx)
+		return &EncodeError{Element: tx, Err: err}
+	}
+	w.txStarted = true
+	if err := w.encodeExtensions(ExtensionTransactionStart, writer.Transaction()); err != nil {
+		return &EncodeError{Element: tx, Err: err}
 	}
+	return nil
+}
 
-	if writer.Transaction() == NewCatalog {
-		// FEATURE_SYSTEM
+// writeStructure writes the FEATURE_SYSTEM, CLASSIFICATION_SYSTEM, and
+// CATALOG_GROUP_SYSTEM elements of a new catalog. groups are written
+// after the catalog groups of writer.
+func (w *Writer) writeStructure(writer CatalogWriter, groups []*CatalogGroup) error {
+	// FEATURE_SYSTEM
+	if fsw, ok := writer.(FeatureSystemWriter); ok {
+		for _, system := range fsw.FeatureSystems() {
+			if system.IsBlank() {
+				continue
+			}
+			system = w.sanitizeElement(system, "FEATURE_SYSTEM", "").(*FeatureSystem)
+			if err := w.reportProgress("FEATURE_SYSTEM", false, false); err != nil {
+				return &EncodeError{Element: "FEATURE_SYSTEM", Err: err}
+			}
+			if err := w.encodeElement(system, nil); err != nil {
+				return &EncodeError{Element: "FEATURE_SYSTEM", Err: err}
+			}
+		}
+	}
 
-		// CLASSIFICATION_SYSTEM
-		if system := writer.ClassificationSystem(); system != nil {
-			if !system.IsBlank() {
-				if err := w.enc.Encode(system); err != nil {
-					return errors.Wrap(err, "bmecat/v12: unable to write CLASSIFICATION_SYSTEM")
-				}
+	nextCatalogGroup, nextClassifGroup, stop := seqGroups(writer)
+	defer stop()
+
+	// CLASSIFICATION_SYSTEM
+	if system := writer.ClassificationSystem(); system != nil {
+		var first *ClassificationGroup
+		var ok bool
+		if nextClassifGroup != nil {
+			first, ok = nextClassifGroup()
+		}
+		if ok {
+			if err := w.streamClassificationSystem(system, first, nextClassifGroup); err != nil {
+				return &EncodeError{Element: "CLASSIFICATION_SYSTEM", Err: err}
+			}
+		} else if !system.IsBlank() {
+			system = w.sanitizeElement(system, "CLASSIFICATION_SYSTEM", "").(*ClassificationSystem)
+			if err := w.reportProgress("CLASSIFICATION_SYSTEM", false, false); err != nil {
+				return &EncodeError{Element: "CLASSIFICATION_SYSTEM", Err: err}
+			}
+			if err := w.encodeElement(system, nil); err != nil {
+				return &EncodeError{Element: "CLASSIFICATION_SYSTEM", Err: err}
 			}
 		}
+	}
 
-		// CATALOG_GROUP_SYSTEM
+	// CATALOG_GROUP_SYSTEM
+	var system *CatalogGroupSystem
+	if cgsw, ok := writer.(CatalogGroupSystemWriter); ok {
+		system = cgsw.CatalogGroupSystem()
+	}
+	var first *CatalogGroup
+	var ok bool
+	if nextCatalogGroup != nil {
+		first, ok = nextCatalogGroup()
+	}
+	if ok {
+		if err := w.streamCatalogGroupSystem(system, first, nextCatalogGroup, groups); err != nil {
+			return &EncodeError{Element: "CATALOG_GROUP_SYSTEM", Err: err}
+		}
+		return nil
+	}
+	if len(groups) > 0 {
+		s := CatalogGroupSystem{}
+		if system != nil {
+			s = *system
+		}
+		s.Groups = append(append([]*CatalogGroup(nil), s.Groups...), groups...)
+		system = &s
+	}
+	if !system.IsBlank() {
+		system = w.sanitizeElement(system, "CATALOG_GROUP_SYSTEM", "").(*CatalogGroupSystem)
+		system = w.sortCatalogGroupSystem(system)
+		system = w.prepareCatalogGroupSystem(system)
+		if err := w.reportProgress("CATALOG_GROUP_SYSTEM", false, false); err != nil {
+			return &EncodeError{Element: "CATALOG_GROUP_SYSTEM", Err: err}
+		}
+		if err := w.encodeElement(system, nil); err != nil {
+			return &EncodeError{Element: "CATALOG_GROUP_SYSTEM", Err: err}
+		}
 	}
+	return nil
+}
 
-	// ARTICLE
-	if err := w.writeArticles(ctx, writer); err != nil {
-		return errors.Wrapf(err, "bmecat/v12: unable to write ARTICLE")
+// streamClassificationSystem writes system with its groups, followed by
+// first and the groups returned by next, one by one, see
+// ClassificationGroupSeqWriter.
+func (w *Writer) streamClassificationSystem(system *ClassificationSystem, first *ClassificationGroup, next func() (*ClassificationGroup, bool)) error {
+	head := *system
+	own := head.Groups
+	head.Groups = nil
+	system = w.sanitizeElement(&head, "CLASSIFICATION_SYSTEM", "").(*ClassificationSystem)
+	if err := w.reportProgress("CLASSIFICATION_SYSTEM", false, false); err != nil {
+		return err
+	}
+	end, err := w.encodeStart(system, "CLASSIFICATION_GROUPS")
+	if err != nil {
+		return err
+	}
+	groupsStart := xml.StartElement{Name: xml.Name{Local: "CLASSIFICATION_GROUPS"}}
+	if err := w.enc.EncodeToken(groupsStart); err != nil {
+		return err
+	}
+	write := func(g *ClassificationGroup) error {
+		if g == nil {
+			return nil
+		}
+		g = w.sanitizeElement(g, "CLASSIFICATION_SYSTEM", "").(*ClassificationGroup)
+		return w.encodeElement(g, nil)
+	}
+	for _, g := range own {
+		if err := write(g); err != nil {
+			return err
+		}
 	}
+	for g, ok := first, true; ok; g, ok = next() {
+		if err := write(g); err != nil {
+			return err
+		}
+	}
+	if err := w.enc.EncodeToken(groupsStart.End()); err != nil {
+		return err
+	}
+	return w.enc.EncodeToken(end)
+}
+
+// streamCatalogGroupSystem writes system with its groups, followed by
+// first, the groups returned by next, one by one, see
+// CatalogGroupSeqWriter, and groups.
+func (w *Writer) streamCatalogGroupSystem(system *CatalogGroupSystem, first *CatalogGroup, next func() (*CatalogGroup, bool), groups []*CatalogGroup) error {
+	var head CatalogGroupSystem
+	if system != nil {
+		head = *system
+	}
+	own := head.Groups
+	head.Groups = nil
+	system = w.sanitizeElement(&head, "CATALOG_GROUP_SYSTEM", "").(*CatalogGroupSystem)
+	if err := w.reportProgress("CATALOG_GROUP_SYSTEM", false, false); err != nil {
+		return err
+	}
+	end, err := w.encodeStart(system, "")
+	if err != nil {
+		return err
+	}
+	write := func(g *CatalogGroup) error {
+		if g == nil {
+			return nil
+		}
+		g = w.sanitizeElement(g, "CATALOG_GROUP_SYSTEM", "").(*CatalogGroup)
+		// Sort and prepare the group as part of a system of its own
+		single := &CatalogGroupSystem{Groups: []*CatalogGroup{g}}
+		single = w.prepareCatalogGroupSystem(w.sortCatalogGroupSystem(single))
+		return w.encodeElement(single.Groups[0], nil)
+	}
+	for _, g := range own {
+		if err := write(g); err != nil {
+			return err
+		}
+	}
+	for g, ok := first, true; ok; g, ok = next() {
+		if err := write(g); err != nil {
+			return err
+		}
+	}
+	for _, g := range groups {
+		if err := write(g); err != nil {
+			return err
+		}
+	}
+	return w.enc.EncodeToken(end)
+}
+
+// encodeStart writes v without its end element, which it returns, so
+// that the last child elements of v can be written one by one. If v
+// ends with an empty element named wrapper, it is not written either:
+// encoding/xml writes the parent of an "a>b,omitempty" field even if the
+// field is empty.
+func (w *Writer) encodeStart(v interface{}, wrapper string) (xml.EndElement, error) {
+	if err := w.flushRaw(); err != nil {
+		return xml.EndElement{}, err
+	}
+	w.txStarted = false
+	var buf bytes.Buffer
+	if err := xml.NewEncoder(&buf).Encode(v); err != nil {
+		return xml.EndElement{}, err
+	}
+	var tokens []xml.Token
+	dec := xml.NewDecoder(&buf)
+	for {
+		t, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return xml.EndElement{}, err
+		}
+		tokens = append(tokens, xml.CopyToken(t))
+	}
+	n := len(tokens)
+	if n == 0 {
+		return xml.EndElement{}, fmt.Errorf("bmecat/writer: %T encodes to nothing", v)
+	}
+	end, ok := tokens[n-1].(xml.EndElement)
+	if !ok {
+		return xml.EndElement{}, fmt.Errorf("bmecat/writer: %T does not encode to an element", v)
+	}
+	tokens = tokens[:n-1]
+	if n := len(tokens); wrapper != "" && n >= 2 {
+		se, isStart := tokens[n-2].(xml.StartElement)
+		ee, isEnd := tokens[n-1].(xml.EndElement)
+		if isStart && isEnd && se.Name.Local == wrapper && ee.Name.Local == wrapper {
+			tokens = tokens[:n-2]
+		}
+	}
+	for _, t := range tokens {
+		if err := w.enc.EncodeToken(t); err != nil {
+			return xml.EndElement{}, err
+		}
+	}
+	return end, nil
+}
 
+// end writes the spilled catalog group mappings, see WithCatalogGroupMaps,
+// closes the document, and flushes the output.
+func (w *Writer) end(writer CatalogWriter) error {
+	if err := w.flushRaw(); err != nil {
+		return err
+	}
 	if writer.Transaction() != UpdatePrices {
-		// ARTICLE_TO_CATALOGROUP_MAP
+		// ARTICLE_TO_CATALOGGROUP_MAP
+		if err := w.writeSpilledMaps(); err != nil {
+			return err
+		}
 	}
 
+	tx := writer.Transaction().String()
+	if err := w.encodeExtensions(ExtensionTransactionEnd, writer.Transaction()); err != nil {
+		return &EncodeError{Element: tx, Err: err}
+	}
 	if err := w.enc.EncodeToken(w.txEndElement(writer)); err != nil {
-		return errors.Wrapf(err, "bmecat/v12: unable to write closing %s", tx)
+		return &EncodeError{Element: tx, Err: err}
 	}
 	if err := w.writeLeadOut(); err != nil {
-		return errors.Wrap(err, "bmecat/v12: unable to write lead out")
+		return &EncodeError{Element: "BMECAT", Err: err}
 	}
-	return w.enc.Flush()
+	if err := w.enc.Flush(); err != nil {
+		return err
+	}
+	if w.closer != nil {
+		if err := w.closer.Close(); err != nil {
+			return err
+		}
+	}
+	if w.zw != nil {
+		if err := w.zw.Close(); err != nil {
+			return err
+		}
+	}
+	w.finishChecksum()
+	return nil
 }
 
 func (w *Writer) writeLeadIn(writer CatalogWriter) error {
-	_, err := fmt.Fprint(w.w, xml.Header)
+	_, err := fmt.Fprint(w.out, w.xmlHeader())
 	if err != nil {
 		return err
 	}
-	_, err = fmt.Fprintln(w.w, `<!DOCTYPE BMECAT SYSTEM "bmecat_new_catalog.dtd">`)
-	if err != nil {
-		return err
+	var prolog *Prolog
+	if pw, ok := writer.(PrologWriter); ok {
+		prolog = pw.Prolog()
+	}
+	// Keep the DOCTYPE of the prolog, unless overridden by options
+	keepDoctype := !w.noDocType && w.docType == "" && prolog.Doctype() != ""
+	if !w.noDocType && !keepDoctype {
+		systemID := w.docType
+		if systemID == "" {
+			systemID = writer.Transaction().DocType()
+		}
+		_, err = fmt.Fprintf(w.out, "<!DOCTYPE BMECAT SYSTEM %q>\n", systemID)
+		if err != nil {
+			return err
+		}
+	}
+	if prolog != nil {
+		if err := prolog.writeTo(w.out, keepDoctype); err != nil {
+			return err
+		}
 	}
 	// <BMECAT version="1.2" xmlns="http://www.bmecat.org/bmecat/1.2/bmecat_new_catalog">`, writer.Language())
 	attr := []xml.Attr{
 		xml.Attr{Name: xml.Name{Local: "xmlns"}, Value: w.xmlNamespace(writer)},
 		xml.Attr{Name: xml.Name{Local: "version"}, Value: "1.2"},
 	}
-	/*
-		if language := writer.Language(); language != "" {
-			attr = append(attr, xml.Attr{Name: xml.Name{Local: "xml:lang"}, Value: language})
-		}
-	*/
+	if language := writer.Language(); language != "" && !w.noXMLLang {
+		attr = append(attr, xml.Attr{Name: xml.Name{Local: "xml:lang"}, Value: xmlLang(language)})
+	}
+	attr = append(attr, w.namespaceAttrs()...)
 	t := xml.StartElement{
 		Name: xml.Name{Local: "BMECAT"},
 		Attr: attr,
@@ -202,18 +734,47 @@ func (w *Writer) writeLeadIn(writer CatalogWriter) error {
 	return w.enc.EncodeToken(t)
 }
 
+// xmlLang returns the language of the catalog as a language tag for
+// xml:lang, e.g. "de" for the ISO 639-2 code "deu" used in LANGUAGE.
+// Languages that are no valid tags are returned as is.
+func xmlLang(lang string) string {
+	tag, err := language.Parse(lang)
+	if err != nil {
+		return lang
+	}
+	return tag.String()
+}
+
 func (w *Writer) writeLeadOut() error {
 	return w.enc.EncodeToken(xml.EndElement{Name: xml.Name{Local: "BMECAT"}})
 }
 
-func (w *Writer) writeArticles(ctx context.Context, writer CatalogWriter) error {
-	articlesCh, errCh := writer.Articles(ctx)
+func (w *Writer) writeArticles(ctx context.Context, writer CatalogWriter) (int, error) {
+	// Stop the producer if writing fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	articlesCh, errCh := articles(ctx, writer)
 	if articlesCh == nil {
-		return nil
+		return 0, nil
 	}
 
-	var stop bool
 	var written uint32
+	articleWritten := func(a *Article) error {
+		return w.articleWritten(a, int(atomic.AddUint32(&written, 1)))
+	}
+	var pool *marshalPool
+	if w.concurrency > 1 && len(w.extensions[ExtensionArticle]) == 0 && len(w.cdata) == 0 {
+		pool = w.newMarshalPool()
+		defer pool.close()
+	}
+	deliver := func(res *marshalResult) error {
+		if err := w.writeMarshaled(res); err != nil {
+			return &EncodeError{Element: "ARTICLE", SupplierAID: res.article.SupplierAID, Err: err}
+		}
+		return articleWritten(res.article)
+	}
+
+	var stop bool
 	for !stop {
 		select {
 		case a, ok := <-articlesCh:
@@ -221,28 +782,151 @@ func (w *Writer) writeArticles(ctx context.Context, writer CatalogWriter) error
 				stop = true
 				break
 			}
+			if skip, err := w.validateArticle(writer.Transaction(), a); err != nil {
+				return int(written), &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+			} else if skip {
+				continue
+			}
+			if pool != nil {
+				prepared, err := w.prepareArticle(a)
+				if err != nil {
+					return int(written), &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+				}
+				if err := pool.submit(prepared, deliver); err != nil {
+					return int(written), err
+				}
+				continue
+			}
 			if err := w.writeArticle(a); err != nil {
-				return errors.Wrapf(err, "unable to write SUPPLIER_AID %q", a.SupplierAID)
+				return int(written), &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
 			}
-			current := atomic.AddUint32(&written, 1)
-			if w.progress != nil {
-				w.progress(int(current))
+			if err := articleWritten(a); err != nil {
+				return int(written), err
 			}
 		case err := <-errCh:
-			return err
+			if err != nil {
+				return int(written), err
+			}
+			stop = true
 		case <-ctx.Done():
-			return ctx.Err()
+			return int(written), ctx.Err()
+		}
+	}
+	if pool != nil {
+		if err := pool.drain(deliver); err != nil {
+			return int(written), err
 		}
 	}
 
+	return int(written), nil
+}
+
+// articleWritten reports the progress after the article a was written
+// as the n-th article.
+func (w *Writer) articleWritten(a *Article, n int) error {
+	if err := w.reportProgress("ARTICLE", true, false); err != nil {
+		return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+	}
+	if w.instr != nil {
+		w.instr.ArticleWritten()
+	}
+	if w.progress != nil {
+		w.progress(n)
+	}
 	return nil
 }
 
+// validateArticle calls the validators of WithArticleValidator for a. It
+// returns true if the article is to be skipped.
+func (w *Writer) validateArticle(tx Transaction, a *Article) (bool, error) {
+	if !w.skipModeValidation {
+		if err := a.ValidateMode(tx); err != nil {
+			return false, err
+		}
+	}
+	for _, f := range w.validators {
+		err := f(a)
+		if err == nil {
+			continue
+		}
+		if errors.Is(err, ErrSkipArticle) {
+			logOrNop(w.logger).Warn("bmecat: skipped invalid ARTICLE", "supplier_aid", a.SupplierAID, "error", err)
+			return true, nil
+		}
+		return false, err
+	}
+	return false, nil
+}
+
 func (w *Writer) writeArticle(a *Article) error {
+	a, err := w.prepareArticle(a)
+	if err != nil {
+		return err
+	}
 	// TODO(oe) Only serialize the part of the article that is required by w.Transaction
-	err := w.enc.Encode(a)
+	if w.canEncodeDirect(a) {
+		err = w.writeArticleDirect(a)
+	} else {
+		err = w.encodeWithExtensions(a, ExtensionArticle)
+	}
 	if err != nil {
 		return err
 	}
-	return nil
+	return w.addMaps(a)
+}
+
+// prepareArticle returns the article as it is to be encoded.
+func (w *Writer) prepareArticle(a *Article) (*Article, error) {
+	a = w.sanitizeElement(a, "ARTICLE", a.SupplierAID).(*Article)
+	udx, err := w.prepareUDX(a.UDX, w.provenanceScope&ProvenanceArticles != 0)
+	if err != nil {
+		return nil, err
+	}
+	if udx != a.UDX {
+		prepared := *a
+		prepared.UDX = udx
+		a = &prepared
+	}
+	a = w.expandArticleTerritories(a)
+	a = w.sortArticle(a)
+	a = w.rewriteMimeSources(a)
+	a = w.packageArticle(a)
+	return a, nil
+}
+
+// prepareCatalogGroupSystem returns the catalog group system with the
+// MIME_SOURCE of the groups rewritten as for the articles, see
+// rewriteMimeSources and packageArticle. system is not modified.
+func (w *Writer) prepareCatalogGroupSystem(system *CatalogGroupSystem) *CatalogGroupSystem {
+	if w.mimeRoot == "" && w.pkg == nil {
+		return system
+	}
+	var groups []*CatalogGroup
+	for i, g := range system.Groups {
+		if g == nil || g.MimeInfo == nil {
+			continue
+		}
+		mimeInfo := g.MimeInfo
+		if w.mimeRoot != "" {
+			mimeInfo = mapMimeSources(mimeInfo, w.rewriteMimeSource)
+		}
+		if w.pkg != nil {
+			mimeInfo = mapMimeSources(mimeInfo, w.pkg.source)
+		}
+		if mimeInfo == g.MimeInfo {
+			continue
+		}
+		if groups == nil {
+			groups = append([]*CatalogGroup(nil), system.Groups...)
+		}
+		prepared := *g
+		prepared.MimeInfo = mimeInfo
+		groups[i] = &prepared
+	}
+	if groups == nil {
+		return system
+	}
+	prepared := *system
+	prepared.Groups = groups
+	return &prepared
 }
@@ -0,0 +1,159 @@
+package bmecat12
+
+import (
+	"bytes"
+	"encoding/xml"
+	"strings"
+	"sync"
+)
+
+// WithWriterConcurrency encodes articles on n goroutines, while the
+// articles are written to the output in order from the goroutine that
+// calls Do, so that encoding overlaps with writing for large exports.
+// Values of n less than 2 disable concurrency. Articles are encoded
+// one by one anyway if WithExtension or WithCDATA apply to them, and by
+// the ShardedWriter, which needs the size of each article.
+func WithWriterConcurrency(n int) WriterOption {
+	return func(w *Writer) {
+		w.concurrency = n
+	}
+}
+
+// marshalJob is a prepared article to encode.
+type marshalJob struct {
+	seq     int
+	article *Article
+}
+
+// marshalResult is an encoded article.
+type marshalResult struct {
+	*marshalJob
+	data []byte
+	err  error
+}
+
+// marshalPool encodes articles on a number of workers.
+type marshalPool struct {
+	jobs      chan *marshalJob
+	results   chan *marshalResult
+	closeOnce sync.Once
+
+	window   int // maximum number of jobs in flight
+	pending  int // number of jobs in flight
+	seq      int // sequence number of the next job
+	next     int // sequence number of the next result to deliver
+	buffered map[int]*marshalResult
+}
+
+// newMarshalPool starts the workers.
+func (w *Writer) newMarshalPool() *marshalPool {
+	window := 2 * w.concurrency
+	p := &marshalPool{
+		// The buffers can hold all jobs in flight, so neither the
+		// Writer nor the workers block on them
+		jobs:     make(chan *marshalJob, window),
+		results:  make(chan *marshalResult, window),
+		window:   window,
+		buffered: make(map[int]*marshalResult),
+	}
+	for i := 0; i < w.concurrency; i++ {
+		go func() {
+			for job := range p.jobs {
+				data, err := w.marshalArticle(job.article)
+				p.results <- &marshalResult{marshalJob: job, data: data, err: err}
+			}
+		}()
+	}
+	return p
+}
+
+// submit passes the article to the workers. If the maximum number of
+// jobs is in flight, it first waits for a result and delivers it.
+func (p *marshalPool) submit(a *Article, deliver func(*marshalResult) error) error {
+	if p.pending >= p.window {
+		if err := p.receive(deliver); err != nil {
+			return err
+		}
+	}
+	p.pending++
+	p.jobs <- &marshalJob{seq: p.seq, article: a}
+	p.seq++
+	return nil
+}
+
+// receive waits for the next result and delivers it, plus the buffered
+// results that follow it.
+func (p *marshalPool) receive(deliver func(*marshalResult) error) error {
+	res := <-p.results
+	p.pending--
+	p.buffered[res.seq] = res
+	for {
+		res, found := p.buffered[p.next]
+		if !found {
+			return nil
+		}
+		delete(p.buffered, p.next)
+		p.next++
+		if err := deliver(res); err != nil {
+			return err
+		}
+	}
+}
+
+// drain waits for all jobs in flight and delivers their results.
+func (p *marshalPool) drain(deliver func(*marshalResult) error) error {
+	for p.pending > 0 {
+		if err := p.receive(deliver); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// close stops the workers.
+func (p *marshalPool) close() {
+	p.closeOnce.Do(func() { close(p.jobs) })
+}
+
+// marshalArticle encodes the prepared article a as writeArticle would.
+// It is called from the workers of a marshalPool, so it must not modify
+// the Writer.
+func (w *Writer) marshalArticle(a *Article) ([]byte, error) {
+	if !w.noDirect && directArticle(a) {
+		return appendArticle(nil, a, w.indent, w.numbers), nil
+	}
+	if len(w.numbers) > 0 {
+		// Leave the article to encodeElement, which formats the numbers
+		return nil, nil
+	}
+	// Indent as a child of the transaction element, as the encoder of
+	// the Writer does
+	var buf bytes.Buffer
+	enc := xml.NewEncoder(&buf)
+	if w.indent != "" {
+		buf.WriteByte('\n')
+		enc.Indent(strings.Repeat(w.indent, 2), w.indent)
+	}
+	if err := enc.Encode(a); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// writeMarshaled writes the article encoded by a marshalPool.
+func (w *Writer) writeMarshaled(res *marshalResult) error {
+	if res.err != nil {
+		return res.err
+	}
+	if w.txStarted || res.data == nil {
+		// Let the encoder write the first article after the start of
+		// the transaction, see txStarted, and the articles that were
+		// not encoded by marshalArticle
+		if err := w.encodeElement(res.article, nil); err != nil {
+			return err
+		}
+	} else if err := w.writeRaw(res.data); err != nil {
+		return err
+	}
+	return w.addMaps(res.article)
+}
@@ -0,0 +1,115 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func concurrencyTestArticles(n int) []*bmecat12.Article {
+	templates := directTestArticles()
+	var articles []*bmecat12.Article
+	for i := 0; i < n; i++ {
+		a := *templates[i%len(templates)]
+		a.SupplierAID = fmt.Sprint(i)
+		a.CatalogGroupIDs = []string{fmt.Sprint(i % 7)}
+		articles = append(articles, &a)
+	}
+	return articles
+}
+
+func TestWriteWithWriterConcurrency(t *testing.T) {
+	articles := concurrencyTestArticles(500)
+	validator := func(a *bmecat12.Article) error {
+		if len(a.SupplierAID) > 1 && a.SupplierAID[1] == '3' {
+			return bmecat12.ErrSkipArticle
+		}
+		return nil
+	}
+	for _, tx := range []bmecat12.Transaction{bmecat12.NewCatalog, bmecat12.UpdateProducts, bmecat12.UpdatePrices} {
+		for _, direct := range []bool{true, false} {
+			cw := catalogWriter{
+				tx:          tx,
+				language:    "deu",
+				prevVersion: 1,
+				header:      testHeader,
+				articles:    articles,
+			}
+			write := func(concurrency int) (string, int) {
+				var buf bytes.Buffer
+				var progress int
+				w := bmecat12.NewWriter(&buf,
+					bmecat12.WithDirectEncoding(direct),
+					bmecat12.WithSkipModeValidation(),
+					bmecat12.WithWriterConcurrency(concurrency),
+					bmecat12.WithArticleValidator(validator),
+					bmecat12.WithProgress(func(written int) { progress = written }),
+				)
+				if err := w.Do(context.Background(), cw); err != nil {
+					t.Fatal(err)
+				}
+				return buf.String(), progress
+			}
+			want, wantProgress := write(0)
+			have, haveProgress := write(4)
+			if want != have {
+				t.Errorf("%v, direct %v:", tx, direct)
+				diffStrings(t, want, have)
+			}
+			if wantProgress != haveProgress {
+				t.Fatalf("%v, direct %v: want progress %d, have %d", tx, direct, wantProgress, haveProgress)
+			}
+		}
+	}
+}
+
+func TestWriteWithWriterConcurrencyError(t *testing.T) {
+	errStop := errors.New("stop")
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: concurrencyTestArticles(100),
+	}
+	w := bmecat12.NewWriter(ioutil.Discard,
+		bmecat12.WithSkipModeValidation(),
+		bmecat12.WithWriterConcurrency(4),
+		bmecat12.WithArticleValidator(func(a *bmecat12.Article) error {
+			if a.SupplierAID == "50" {
+				return errStop
+			}
+			return nil
+		}),
+	)
+	err := w.Do(context.Background(), cw)
+	if !errors.Is(err, errStop) {
+		t.Fatalf("want error %v, have %v", errStop, err)
+	}
+}
+
+func BenchmarkWriterWithConcurrency(b *testing.B) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "deu",
+		header:   testHeader,
+		articles: concurrencyTestArticles(1000),
+	}
+	for _, direct := range []bool{false, true} {
+		for _, n := range []int{1, 4} {
+			b.Run(fmt.Sprintf("direct=%v/n=%d", direct, n), func(b *testing.B) {
+				b.ReportAllocs()
+				for i := 0; i < b.N; i++ {
+					w := bmecat12.NewWriter(ioutil.Discard, bmecat12.WithDirectEncoding(direct), bmecat12.WithWriterConcurrency(n))
+					if err := w.Do(context.Background(), cw); err != nil {
+						b.Fatal(err)
+					}
+				}
+			})
+		}
+	}
+}
@@ -0,0 +1,84 @@
+//go:build go1.23
+// +build go1.23
+
+package bmecat12
+
+import (
+	"context"
+	"iter"
+)
+
+// ArticleSeqWriter, if implemented by a CatalogWriter, is used to get the
+// articles to write as an iterator instead of a channel. The Articles
+// method of the CatalogWriter is not called then, so it may simply
+// return nil channels. If the iterator yields an error, the Writer stops
+// and Do returns the error.
+type ArticleSeqWriter interface {
+	ArticleSeq(context.Context) iter.Seq2[*Article, error]
+}
+
+// CatalogGroupSeqWriter, if implemented by a CatalogWriter, is used to
+// get the CATALOG_STRUCTURE elements of a new catalog as an iterator.
+// They are written after the groups of the CatalogGroupSystemWriter, if
+// any. The groups are written as they are produced, so they need not be
+// kept in memory.
+type CatalogGroupSeqWriter interface {
+	CatalogGroupSeq() iter.Seq[*CatalogGroup]
+}
+
+// ClassificationGroupSeqWriter, if implemented by a CatalogWriter, is
+// used to get the CLASSIFICATION_GROUP elements of a new catalog as an
+// iterator. They are written after the groups of the ClassificationSystem,
+// which must not be nil. The groups are written as they are produced, so
+// they need not be kept in memory.
+type ClassificationGroupSeqWriter interface {
+	ClassificationGroupSeq() iter.Seq[*ClassificationGroup]
+}
+
+// articles returns the articles of writer as a channel.
+func articles(ctx context.Context, writer CatalogWriter) (<-chan *Article, <-chan error) {
+	sw, ok := writer.(ArticleSeqWriter)
+	if !ok {
+		return writer.Articles(ctx)
+	}
+	articlesCh := make(chan *Article)
+	errCh := make(chan error, 1)
+	go func() {
+		for a, err := range sw.ArticleSeq(ctx) {
+			if err != nil {
+				// articlesCh stays open, so the error is not missed
+				errCh <- err
+				return
+			}
+			select {
+			case articlesCh <- a:
+			case <-ctx.Done():
+				return
+			}
+		}
+		close(articlesCh)
+	}()
+	return articlesCh, errCh
+}
+
+// seqGroups returns functions that return the catalog and classification
+// groups that writer produces as iterators one by one, or nil if writer
+// produces none. stop must be called to release the iterators.
+func seqGroups(writer CatalogWriter) (nextCatalogGroup func() (*CatalogGroup, bool), nextClassifGroup func() (*ClassificationGroup, bool), stop func()) {
+	var stops []func()
+	if sw, ok := writer.(CatalogGroupSeqWriter); ok {
+		next, stop := iter.Pull(sw.CatalogGroupSeq())
+		nextCatalogGroup = next
+		stops = append(stops, stop)
+	}
+	if sw, ok := writer.(ClassificationGroupSeqWriter); ok {
+		next, stop := iter.Pull(sw.ClassificationGroupSeq())
+		nextClassifGroup = next
+		stops = append(stops, stop)
+	}
+	return nextCatalogGroup, nextClassifGroup, func() {
+		for _, stop := range stops {
+			stop()
+		}
+	}
+}
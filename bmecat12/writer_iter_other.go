@@ -0,0 +1,17 @@
+//go:build !go1.23
+// +build !go1.23
+
+package bmecat12
+
+import "context"
+
+// articles returns the articles of writer as a channel.
+func articles(ctx context.Context, writer CatalogWriter) (<-chan *Article, <-chan error) {
+	return writer.Articles(ctx)
+}
+
+// seqGroups returns functions that return the catalog and classification
+// groups that writer produces as iterators, which requires Go 1.23.
+func seqGroups(writer CatalogWriter) (func() (*CatalogGroup, bool), func() (*ClassificationGroup, bool), func()) {
+	return nil, nil, func() {}
+}
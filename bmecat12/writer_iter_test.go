@@ -0,0 +1,180 @@
+//go:build go1.23
+// +build go1.23
+
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"iter"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// seqCatalogWriter produces its articles and groups as iterators. If err
+// is set, the article iterator yields it after the articles.
+type seqCatalogWriter struct {
+	catalogWriter
+	catalogGroups []*bmecat12.CatalogGroup
+	classifGroups []*bmecat12.ClassificationGroup
+	err           error
+}
+
+func (w seqCatalogWriter) ArticleSeq(ctx context.Context) iter.Seq2[*bmecat12.Article, error] {
+	return func(yield func(*bmecat12.Article, error) bool) {
+		for _, a := range w.articles {
+			if !yield(a, nil) {
+				return
+			}
+		}
+		if w.err != nil {
+			yield(nil, w.err)
+		}
+	}
+}
+
+func (w seqCatalogWriter) CatalogGroupSeq() iter.Seq[*bmecat12.CatalogGroup] {
+	return slices.Values(w.catalogGroups)
+}
+
+func (w seqCatalogWriter) ClassificationGroupSeq() iter.Seq[*bmecat12.ClassificationGroup] {
+	return slices.Values(w.classifGroups)
+}
+
+func TestWriteWithSeq(t *testing.T) {
+	cw := seqCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:                   bmecat12.NewCatalog,
+			header:               testHeader,
+			classificationSystem: &bmecat12.ClassificationSystem{Name: "udf_Supplier-1.0"},
+			articles: []*bmecat12.Article{
+				{SupplierAID: "1000"},
+				{SupplierAID: "2000"},
+			},
+		},
+		catalogGroups: []*bmecat12.CatalogGroup{{ID: "1", Name: "Root", Type: "root"}},
+		classifGroups: []*bmecat12.ClassificationGroup{{ID: "10", Name: "Tools"}},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+
+	h := &testHandler{}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	var aids []string
+	for _, a := range h.articles {
+		aids = append(aids, a.SupplierAID)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+	for _, want := range []string{"<CATALOG_STRUCTURE type=\"root\">", "<CLASSIFICATION_GROUP>"} {
+		if !strings.Contains(buf.String(), want) {
+			t.Fatalf("want output to contain %s, have:\n%s", want, buf.String())
+		}
+	}
+}
+
+func TestWriteWithSeqError(t *testing.T) {
+	errSeq := errors.New("seq failed")
+	cw := seqCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:       bmecat12.NewCatalog,
+			header:   testHeader,
+			articles: []*bmecat12.Article{{SupplierAID: "1000"}},
+		},
+		err: errSeq,
+	}
+	for i := 0; i < 20; i++ {
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); !errors.Is(err, errSeq) {
+			t.Fatalf("want error %v, have %v", errSeq, err)
+		}
+	}
+}
+
+// groupSystemWriter returns a CATALOG_GROUP_SYSTEM.
+type groupSystemWriter struct {
+	catalogWriter
+	system *bmecat12.CatalogGroupSystem
+}
+
+func (w groupSystemWriter) CatalogGroupSystem() *bmecat12.CatalogGroupSystem {
+	return w.system
+}
+
+// seqGroupSystemWriter returns a CATALOG_GROUP_SYSTEM and produces more
+// groups as iterators.
+type seqGroupSystemWriter struct {
+	seqCatalogWriter
+	system *bmecat12.CatalogGroupSystem
+}
+
+func (w seqGroupSystemWriter) CatalogGroupSystem() *bmecat12.CatalogGroupSystem {
+	return w.system
+}
+
+func TestWriteWithSeqGroupsStreamed(t *testing.T) {
+	root := "1"
+	catalogGroups := []*bmecat12.CatalogGroup{
+		{ID: "1", Name: "Root", Type: "root", Keywords: []string{"b", "a"}},
+		{ID: "2", Name: "Tools", Type: "leaf", ParentID: &root, Keywords: []string{"d", "c"}},
+	}
+	classifGroups := []*bmecat12.ClassificationGroup{{ID: "10", Name: "Tools"}, {ID: "20", Name: "Drills"}}
+	classifSystem := func(groups ...*bmecat12.ClassificationGroup) *bmecat12.ClassificationSystem {
+		return &bmecat12.ClassificationSystem{Name: "udf_Supplier-1.0", Levels: 2, Groups: groups}
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		header:   testHeader,
+		articles: []*bmecat12.Article{{SupplierAID: "1000"}},
+	}
+	write := func(writer bmecat12.CatalogWriter) string {
+		var buf bytes.Buffer
+		w := bmecat12.NewWriter(&buf, bmecat12.WithIndent("  "), bmecat12.WithOutputOrder(bmecat12.SortKeywords))
+		if err := w.Do(context.Background(), writer); err != nil {
+			t.Fatal(err)
+		}
+		return buf.String()
+	}
+
+	all := groupSystemWriter{
+		catalogWriter: cw,
+		system:        &bmecat12.CatalogGroupSystem{Name: "Shop", Groups: catalogGroups},
+	}
+	all.classificationSystem = classifSystem(classifGroups...)
+	want := write(all)
+
+	streamed := seqGroupSystemWriter{
+		seqCatalogWriter: seqCatalogWriter{
+			catalogWriter: cw,
+			catalogGroups: catalogGroups[1:],
+			classifGroups: classifGroups[1:],
+		},
+		system: &bmecat12.CatalogGroupSystem{Name: "Shop", Groups: catalogGroups[:1]},
+	}
+	streamed.classificationSystem = classifSystem(classifGroups[0])
+	if have := write(streamed); want != have {
+		t.Fatalf("want\n%s\nhave\n%s", want, have)
+	}
+	if i, j := strings.Index(want, "<KEYWORD>c<"), strings.Index(want, "<KEYWORD>d<"); i < 0 || j < i {
+		t.Fatalf("want sorted keywords of streamed groups, have:\n%s", want)
+	}
+
+	// Only the iterators produce groups
+	streamed.system = nil
+	streamed.catalogGroups = catalogGroups
+	streamed.classificationSystem = classifSystem()
+	streamed.classifGroups = classifGroups
+	want = strings.Replace(want, "  <GROUP_SYSTEM_NAME>Shop</GROUP_SYSTEM_NAME>\n    ", "", 1)
+	if have := write(streamed); want != have {
+		t.Fatalf("want\n%s\nhave\n%s", want, have)
+	}
+}
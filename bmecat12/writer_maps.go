@@ -0,0 +1,197 @@
+package bmecat12
+
+import (
+	"bufio"
+	"context"
+	"encoding/binary"
+	"io"
+	"io/ioutil"
+	"os"
+)
+
+// CatalogGroupMapWriter, if implemented by a CatalogWriter, is used to
+// write ARTICLE_TO_CATALOGGROUP_MAP elements after the articles. The
+// mappings are written as they are received, so they need not be kept
+// in memory. A ShardedWriter writes them to the last file.
+type CatalogGroupMapWriter interface {
+	CatalogGroupMaps(context.Context) (<-chan *ArticleToCatalogGroupMap, <-chan error)
+}
+
+// WithCatalogGroupMaps writes an ARTICLE_TO_CATALOGGROUP_MAP element for
+// each of the CatalogGroupIDs of the articles written, after the
+// articles. By default, CatalogGroupIDs are not written. The mappings
+// are spilled to a temporary file while the articles are written, so
+// they are not kept in memory.
+func WithCatalogGroupMaps() WriterOption {
+	return func(w *Writer) {
+		w.groupMaps = true
+	}
+}
+
+// addMaps spills the catalog group mappings of the article written, see
+// WithCatalogGroupMaps.
+func (w *Writer) addMaps(a *Article) error {
+	if !w.groupMaps || len(a.CatalogGroupIDs) == 0 {
+		return nil
+	}
+	if w.maps == nil {
+		s, err := newMapSpill()
+		if err != nil {
+			return err
+		}
+		w.maps = s
+	}
+	for _, id := range a.CatalogGroupIDs {
+		if err := w.maps.add(a.SupplierAID, id); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// discardMaps removes the spilled catalog group mappings, if any.
+func (w *Writer) discardMaps() {
+	if w.maps != nil {
+		w.maps.Close()
+		w.maps = nil
+	}
+}
+
+// writeSpilledMaps writes the catalog group mappings of the articles
+// written, see WithCatalogGroupMaps.
+func (w *Writer) writeSpilledMaps() error {
+	if w.maps == nil {
+		return nil
+	}
+	defer w.discardMaps()
+	return w.maps.each(w.writeMap)
+}
+
+// writeCatalogGroupMaps writes the catalog group mappings of writer, if
+// it implements CatalogGroupMapWriter.
+func (w *Writer) writeCatalogGroupMaps(ctx context.Context, writer CatalogWriter) error {
+	mw, ok := writer.(CatalogGroupMapWriter)
+	if !ok || writer.Transaction() == UpdatePrices {
+		return nil
+	}
+	// Stop the producer if writing fails
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	mapsCh, errCh := mw.CatalogGroupMaps(ctx)
+	for mapsCh != nil {
+		select {
+		case m, ok := <-mapsCh:
+			if !ok {
+				mapsCh = nil
+				break
+			}
+			if err := w.writeMap(m); err != nil {
+				return err
+			}
+		case err := <-errCh:
+			if err != nil {
+				return err
+			}
+			// Drain the mappings that are still buffered
+			errCh = nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+// writeMap writes an ARTICLE_TO_CATALOGGROUP_MAP element, reporting the
+// progress before the first one.
+func (w *Writer) writeMap(m *ArticleToCatalogGroupMap) error {
+	if m == nil {
+		return nil
+	}
+	if !w.mapsReported {
+		w.mapsReported = true
+		if err := w.reportProgress("ARTICLE_TO_CATALOGGROUP_MAP", false, false); err != nil {
+			return &EncodeError{Element: "ARTICLE_TO_CATALOGGROUP_MAP", Err: err}
+		}
+	}
+	if err := w.enc.Encode(m); err != nil {
+		return &EncodeError{Element: "ARTICLE_TO_CATALOGGROUP_MAP", SupplierAID: m.ArticleID, Err: err}
+	}
+	return nil
+}
+
+// mapSpill keeps catalog group mappings in a temporary file, as pairs of
+// length-prefixed SUPPLIER_AID and GROUP_ID.
+type mapSpill struct {
+	f   *os.File
+	bw  *bufio.Writer
+	buf [binary.MaxVarintLen64]byte
+}
+
+// newMapSpill creates the temporary file of a mapSpill.
+func newMapSpill() (*mapSpill, error) {
+	f, err := ioutil.TempFile("", "bmecat-maps-*")
+	if err != nil {
+		return nil, err
+	}
+	return &mapSpill{f: f, bw: bufio.NewWriter(f)}, nil
+}
+
+// add adds a mapping.
+func (s *mapSpill) add(aid, id string) error {
+	for _, v := range []string{aid, id} {
+		if _, err := s.bw.Write(s.buf[:binary.PutUvarint(s.buf[:], uint64(len(v)))]); err != nil {
+			return err
+		}
+		if _, err := s.bw.WriteString(v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// each calls f for the mappings in the order they were added.
+func (s *mapSpill) each(f func(*ArticleToCatalogGroupMap) error) error {
+	if err := s.bw.Flush(); err != nil {
+		return err
+	}
+	if _, err := s.f.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	br := bufio.NewReader(s.f)
+	readString := func() (string, error) {
+		n, err := binary.ReadUvarint(br)
+		if err != nil {
+			return "", err
+		}
+		buf := make([]byte, n)
+		if _, err := io.ReadFull(br, buf); err != nil {
+			return "", err
+		}
+		return string(buf), nil
+	}
+	for {
+		aid, err := readString()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		id, err := readString()
+		if err != nil {
+			return err
+		}
+		if err := f(&ArticleToCatalogGroupMap{ArticleID: aid, CatalogGroupID: id}); err != nil {
+			return err
+		}
+	}
+}
+
+// Close removes the temporary file.
+func (s *mapSpill) Close() error {
+	err := s.f.Close()
+	if rerr := os.Remove(s.f.Name()); err == nil {
+		err = rerr
+	}
+	return err
+}
@@ -0,0 +1,167 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+type mapsCatalogWriter struct {
+	catalogWriter
+	maps []*bmecat12.ArticleToCatalogGroupMap
+	err  error
+}
+
+func (w mapsCatalogWriter) CatalogGroupMaps(ctx context.Context) (<-chan *bmecat12.ArticleToCatalogGroupMap, <-chan error) {
+	mapsCh := make(chan *bmecat12.ArticleToCatalogGroupMap, len(w.maps))
+	errCh := make(chan error, 1)
+	for _, m := range w.maps {
+		mapsCh <- m
+	}
+	if w.err != nil {
+		errCh <- w.err
+	} else {
+		close(mapsCh)
+	}
+	close(errCh)
+	return mapsCh, errCh
+}
+
+func TestWriteWithCatalogGroupMaps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-maps")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	tmpdir := os.Getenv("TMPDIR")
+	os.Setenv("TMPDIR", dir)
+	defer os.Setenv("TMPDIR", tmpdir)
+
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", CatalogGroupIDs: []string{"10", "20"}},
+			{SupplierAID: "2000", CatalogGroupIDs: []string{"10"}},
+		},
+	}
+	want := "<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>" +
+		"<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>20</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>" +
+		"<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>2000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>"
+
+	// CatalogGroupIDs are not written by default
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(buf.String(), "ARTICLE_TO_CATALOGGROUP_MAP") {
+		t.Fatalf("want no ARTICLE_TO_CATALOGGROUP_MAP, have\n%s", buf.String())
+	}
+
+	buf.Reset()
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithIndent(""), bmecat12.WithCatalogGroupMaps()).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), want+"</T_NEW_CATALOG>") {
+		t.Fatalf("want output to contain %s, have\n%s", want, buf.String())
+	}
+	files, err := ioutil.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) > 0 {
+		t.Fatalf("want temporary file to be removed, have %s", files[0].Name())
+	}
+}
+
+func TestWriteWithCatalogGroupMapWriter(t *testing.T) {
+	cw := mapsCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:       bmecat12.UpdateProducts,
+			language: "de",
+			header:   testHeader,
+			articles: []*bmecat12.Article{
+				{SupplierAID: "1000", Mode: "update"},
+				{SupplierAID: "2000", Mode: "update"},
+			},
+		},
+		maps: []*bmecat12.ArticleToCatalogGroupMap{
+			{ArticleID: "2000", CatalogGroupID: "10"},
+			{ArticleID: "1000", CatalogGroupID: "20"},
+		},
+	}
+	want := "<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>2000</ART_ID><CATALOG_GROUP_ID>10</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>" +
+		"<ARTICLE_TO_CATALOGGROUP_MAP><ART_ID>1000</ART_ID><CATALOG_GROUP_ID>20</CATALOG_GROUP_ID></ARTICLE_TO_CATALOGGROUP_MAP>" +
+		"</T_UPDATE_PRODUCTS>"
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithIndent("")).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), want) {
+		t.Fatalf("want output to contain %s, have\n%s", want, buf.String())
+	}
+
+	// The incremental API writes the same mappings
+	var have bytes.Buffer
+	w := bmecat12.NewWriter(&have, bmecat12.WithIndent(""))
+	if err := w.Begin(cw); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range cw.articles {
+		if err := w.WriteArticle(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+	if buf.String() != have.String() {
+		diffStrings(t, buf.String(), have.String())
+	}
+
+	// Errors of the producer are returned
+	errFail := errors.New("fail")
+	cw.err = errFail
+	if err := bmecat12.NewWriter(&bytes.Buffer{}).Do(context.Background(), cw); !errors.Is(err, errFail) {
+		t.Fatalf("want %v, have %v", errFail, err)
+	}
+}
+
+func TestShardedWriterWithCatalogGroupMapWriter(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-shard")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cw := mapsCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:       bmecat12.NewCatalog,
+			language: "de",
+			header:   testHeader,
+			articles: []*bmecat12.Article{{SupplierAID: "1000"}, {SupplierAID: "2000"}},
+		},
+		maps: []*bmecat12.ArticleToCatalogGroupMap{{ArticleID: "1000", CatalogGroupID: "10"}},
+	}
+	s := bmecat12.NewShardedWriter(bmecat12.ShardFiles(filepath.Join(dir, "catalog-%03d.xml")), bmecat12.WithShardMaxArticles(1))
+	if err := s.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < s.Shards(); i++ {
+		data, err := ioutil.ReadFile(filepath.Join(dir, fmt.Sprintf("catalog-%03d.xml", i+1)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want, have := i == s.Shards()-1, bytes.Contains(data, []byte("<ART_ID>1000</ART_ID>")); want != have {
+			t.Fatalf("file %d: want mapping=%v, have %v", i, want, have)
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package bmecat12
+
+import (
+	"io"
+	"time"
+)
+
+// WriterProgressInfo is a progress report of the Writer, see
+// WithWriterProgressInfo.
+type WriterProgressInfo struct {
+	// Section is the element being written, e.g. "HEADER",
+	// "CATALOG_GROUP_SYSTEM", "ARTICLE", or "ARTICLE_TO_CATALOGGROUP_MAP".
+	Section string
+	// Articles is the number of articles written so far.
+	Articles int
+	// Bytes is the number of bytes written so far, after the conversion
+	// to the encoding of WithEncoding, and summed up over all files of a
+	// ShardedWriter.
+	Bytes int64
+	// Elapsed is the time since Do was called.
+	Elapsed time.Duration
+	// Complete is true for the last report, after the document has been
+	// written.
+	Complete bool
+}
+
+// WriterProgressInfoFunc is the signature of the callback passed to
+// WithWriterProgressInfo.
+type WriterProgressInfoFunc func(WriterProgressInfo)
+
+// WithWriterProgressInfo specifies a callback that is invoked as the
+// BMEcat file is written. Unlike WithProgress, it reports the bytes
+// written and the current section, e.g. for accurate upload progress
+// bars. It is invoked when a section starts, after each article, and
+// once the document is complete. Notice that the Writer flushes its
+// output for each report.
+func WithWriterProgressInfo(f WriterProgressInfoFunc) WriterOption {
+	return func(w *Writer) {
+		w.progressFunc = f
+	}
+}
+
+// writerProgress is the state of WithWriterProgressInfo, shared by the
+// Writers of the files of a ShardedWriter.
+type writerProgress struct {
+	f        WriterProgressInfoFunc
+	start    time.Time
+	bytes    int64
+	articles int
+}
+
+// startProgress resets the progress state at the start of Do.
+func (w *Writer) startProgress() {
+	w.progressState = nil
+	if w.progressFunc != nil {
+		w.progressState = &writerProgress{f: w.progressFunc, start: time.Now()}
+	}
+}
+
+// reportProgress flushes the output and reports progress for section.
+// article is true if an article has just been written.
+func (w *Writer) reportProgress(section string, article, complete bool) error {
+	p := w.progressState
+	if p == nil {
+		return nil
+	}
+	if err := w.flush(); err != nil {
+		return err
+	}
+	if article {
+		p.articles++
+	}
+	p.f(WriterProgressInfo{
+		Section:  section,
+		Articles: p.articles,
+		Bytes:    p.bytes,
+		Elapsed:  time.Since(p.start),
+		Complete: complete,
+	})
+	return nil
+}
+
+// progressWriter counts the bytes written to an io.Writer.
+type progressWriter struct {
+	w io.Writer
+	n *int64
+}
+
+func (w *progressWriter) Write(p []byte) (int, error) {
+	n, err := w.w.Write(p)
+	*w.n += int64(n)
+	return n, err
+}
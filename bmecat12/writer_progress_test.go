@@ -0,0 +1,59 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriteWithWriterProgressInfo(t *testing.T) {
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", CatalogGroupIDs: []string{"10"}},
+			{SupplierAID: "2000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Größe"}},
+		},
+	}
+	for _, encoding := range []string{"UTF-8", "ISO-8859-1"} {
+		var reports []bmecat12.WriterProgressInfo
+		var buf bytes.Buffer
+		w := bmecat12.NewWriter(&buf,
+			bmecat12.WithCatalogGroupMaps(),
+			bmecat12.WithEncoding(encoding),
+			bmecat12.WithWriterProgressInfo(func(p bmecat12.WriterProgressInfo) {
+				reports = append(reports, p)
+			}),
+		)
+		if err := w.Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+
+		var sections []string
+		for _, p := range reports {
+			sections = append(sections, p.Section)
+		}
+		if want, have := "HEADER,ARTICLE,ARTICLE,ARTICLE_TO_CATALOGGROUP_MAP,", strings.Join(sections, ","); want != have {
+			t.Fatalf("%s: want sections %q, have %q", encoding, want, have)
+		}
+		for i := 1; i < len(reports); i++ {
+			if reports[i].Bytes < reports[i-1].Bytes {
+				t.Fatalf("%s: want Bytes not to decrease, have %d after %d", encoding, reports[i].Bytes, reports[i-1].Bytes)
+			}
+		}
+		last := reports[len(reports)-1]
+		if !last.Complete {
+			t.Fatalf("%s: want last report to be complete", encoding)
+		}
+		if want, have := 2, last.Articles; want != have {
+			t.Fatalf("%s: want Articles=%d, have %d", encoding, want, have)
+		}
+		if want, have := int64(buf.Len()), last.Bytes; want != have {
+			t.Fatalf("%s: want Bytes=%d, have %d", encoding, want, have)
+		}
+	}
+}
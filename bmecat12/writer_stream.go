@@ -0,0 +1,128 @@
+package bmecat12
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// writerStream is the state of a catalog written incrementally, see
+// Begin.
+type writerStream struct {
+	writer  CatalogWriter
+	started time.Time
+	// groups are the catalog groups passed to WriteCatalogGroup, which
+	// are written with the catalog structure before the first article.
+	groups []*CatalogGroup
+	// structure is true once the catalog structure is written.
+	structure bool
+	written   int
+}
+
+// Begin starts to write a catalog incrementally, as an alternative to Do
+// when the articles come e.g. from a database cursor with its own
+// lifecycle. Begin writes the lead-in, the HEADER, and the start of the
+// transaction, taking the transaction, the header, and the catalog
+// structure from writer as Do does; the Articles method of writer is not
+// called. Call WriteCatalogGroup and WriteArticle to add to the catalog,
+// and End to complete it. The Writer must not be used for Do or another
+// catalog until End returns.
+func (w *Writer) Begin(writer CatalogWriter) error {
+	if w.stream != nil {
+		return fmt.Errorf("%w: Begin called twice", ErrWriterState)
+	}
+	logOrNop(w.logger).Info("bmecat: writing catalog started", "transaction", writer.Transaction().String())
+	if err := w.preflight(writer); err != nil {
+		return err
+	}
+	w.startProgress()
+	if err := w.begin(writer, true); err != nil {
+		return err
+	}
+	w.stream = &writerStream{writer: writer, started: time.Now()}
+	return nil
+}
+
+// WriteCatalogGroup adds a CATALOG_STRUCTURE element to the catalog
+// group system of a new catalog. The groups are written after those of
+// the CatalogWriter passed to Begin, when the first article is written
+// or by End. WriteCatalogGroup must not be called after WriteArticle.
+func (w *Writer) WriteCatalogGroup(g *CatalogGroup) error {
+	s := w.stream
+	switch {
+	case s == nil:
+		return fmt.Errorf("%w: WriteCatalogGroup called before Begin", ErrWriterState)
+	case s.writer.Transaction() != NewCatalog:
+		return fmt.Errorf("%w: WriteCatalogGroup called for %s", ErrWriterState, s.writer.Transaction())
+	case s.structure:
+		return fmt.Errorf("%w: WriteCatalogGroup called after WriteArticle", ErrWriterState)
+	}
+	s.groups = append(s.groups, g)
+	return nil
+}
+
+// WriteArticle writes an article, as Do does for each article of the
+// CatalogWriter. Before the first article, the catalog structure of a
+// new catalog is written.
+func (w *Writer) WriteArticle(a *Article) error {
+	s := w.stream
+	if s == nil {
+		return fmt.Errorf("%w: WriteArticle called before Begin", ErrWriterState)
+	}
+	if err := w.writeStructureOnce(s); err != nil {
+		return err
+	}
+	if skip, err := w.validateArticle(s.writer.Transaction(), a); err != nil {
+		return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+	} else if skip {
+		return nil
+	}
+	if err := w.writeArticle(a); err != nil {
+		return &EncodeError{Element: "ARTICLE", SupplierAID: a.SupplierAID, Err: err}
+	}
+	s.written++
+	return w.articleWritten(a, s.written)
+}
+
+// End completes the catalog started with Begin: it writes the catalog
+// structure if no article was written, the catalog group mappings, see
+// WithCatalogGroupMaps and CatalogGroupMapWriter, and the end of the
+// document, and flushes the output.
+func (w *Writer) End() error {
+	s := w.stream
+	if s == nil {
+		return fmt.Errorf("%w: End called before Begin", ErrWriterState)
+	}
+	w.stream = nil
+	defer w.discardMaps()
+	if err := w.writeStructureOnce(s); err != nil {
+		return err
+	}
+	if err := w.flushRaw(); err != nil {
+		return err
+	}
+	if err := w.writeCatalogGroupMaps(context.Background(), s.writer); err != nil {
+		return err
+	}
+	if err := w.end(s.writer); err != nil {
+		return err
+	}
+	if err := w.reportProgress("", false, true); err != nil {
+		return err
+	}
+	logOrNop(w.logger).Info("bmecat: writing catalog completed", "articles", s.written, "elapsed", time.Since(s.started))
+	return nil
+}
+
+// writeStructureOnce writes the catalog structure of a new catalog for s
+// the first time it is called.
+func (w *Writer) writeStructureOnce(s *writerStream) error {
+	if s.structure {
+		return nil
+	}
+	s.structure = true
+	if s.writer.Transaction() != NewCatalog {
+		return nil
+	}
+	return w.writeStructure(s.writer, s.groups)
+}
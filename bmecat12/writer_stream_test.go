@@ -0,0 +1,98 @@
+package bmecat12_test
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func TestWriterBeginWriteArticleEnd(t *testing.T) {
+	root := "1"
+	groups := []*bmecat12.CatalogGroup{
+		{Type: "root", ID: "1", Name: "Catalog"},
+		{Type: "leaf", ID: "2", Name: "Drills", ParentID: &root},
+	}
+	articles := []*bmecat12.Article{
+		{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Drill"}, CatalogGroupIDs: []string{"2"}},
+		{SupplierAID: "2000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Screwdriver"}, CatalogGroupIDs: []string{"2"}},
+	}
+
+	// Do writes the groups and articles of the CatalogWriter
+	var want bytes.Buffer
+	cw := subtreeCatalogWriter{
+		catalogWriter: catalogWriter{tx: bmecat12.NewCatalog, language: "deu", header: testHeader, articles: articles},
+		groupSystem:   &bmecat12.CatalogGroupSystem{ID: "1", Groups: groups[:1]},
+	}
+	if err := bmecat12.NewWriter(&want, bmecat12.WithCatalogGroupMaps()).Do(context.Background(), subtreeCatalogWriter{
+		catalogWriter: cw.catalogWriter,
+		groupSystem:   &bmecat12.CatalogGroupSystem{ID: "1", Groups: groups},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	// The incremental API writes the same catalog
+	var have bytes.Buffer
+	var progress []int
+	w := bmecat12.NewWriter(&have,
+		bmecat12.WithCatalogGroupMaps(),
+		bmecat12.WithProgress(func(n int) { progress = append(progress, n) }),
+	)
+	cw.articles = nil
+	if err := w.Begin(cw); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteCatalogGroup(groups[1]); err != nil {
+		t.Fatal(err)
+	}
+	for _, a := range articles {
+		if err := w.WriteArticle(a); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := w.WriteCatalogGroup(groups[1]); !errors.Is(err, bmecat12.ErrWriterState) {
+		t.Fatalf("want ErrWriterState for a group after the articles, have %v", err)
+	}
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+	if want.String() != have.String() {
+		diffStrings(t, want.String(), have.String())
+	}
+	if want, have := 2, len(progress); want != have {
+		t.Fatalf("want %d progress reports, have %d", want, have)
+	}
+
+	// The methods fail out of order
+	if err := w.WriteArticle(articles[0]); !errors.Is(err, bmecat12.ErrWriterState) {
+		t.Fatalf("want ErrWriterState for WriteArticle after End, have %v", err)
+	}
+	if err := w.End(); !errors.Is(err, bmecat12.ErrWriterState) {
+		t.Fatalf("want ErrWriterState for End after End, have %v", err)
+	}
+}
+
+func TestWriterBeginWithoutArticles(t *testing.T) {
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf)
+	cw := catalogWriter{tx: bmecat12.UpdatePrices, header: testHeader}
+	if err := w.Begin(cw); err != nil {
+		t.Fatal(err)
+	}
+	if err := w.WriteCatalogGroup(&bmecat12.CatalogGroup{ID: "1"}); !errors.Is(err, bmecat12.ErrWriterState) {
+		t.Fatalf("want ErrWriterState for a group of %s, have %v", cw.tx, err)
+	}
+	if err := w.End(); err != nil {
+		t.Fatal(err)
+	}
+
+	var th testHandler
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), &th); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 0, len(th.articles); want != have {
+		t.Fatalf("want %d articles, have %d", want, have)
+	}
+}
@@ -3,7 +3,9 @@ package bmecat12_test
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"strings"
 	"testing"
@@ -205,10 +207,10 @@ func TestWriteNewCatalog(t *testing.T) {
 			},
 			OrderDetails: &bmecat12.ArticleOrderDetails{
 				OrderUnit:     "BOX",
-				NoCuPerOu:     6.0,
+				NoCuPerOu:     float64Ptr(6.0),
 				ContentUnit:   "PCE",
-				PriceQuantity: 1,
-				QuantityMin:   1,
+				PriceQuantity: float64Ptr(1),
+				QuantityMin:   float64Ptr(1),
 			},
 			PriceDetails: []*bmecat12.ArticlePriceDetails{
 				&bmecat12.ArticlePriceDetails{
@@ -219,20 +221,20 @@ func TestWriteNewCatalog(t *testing.T) {
 					Prices: []*bmecat12.ArticlePrice{
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1499.50,
+							Amount:     bmecat12.MustParseDecimal("1499.5"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 1,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(1),
 							Territory:  []string{"DE", "AT"},
 						},
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1300.90,
+							Amount:     bmecat12.MustParseDecimal("1300.9"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 100,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(100),
 							Territory:  []string{"DE", "AT"},
 						},
 					},
@@ -370,10 +372,10 @@ func TestWriteUpdateProducts(t *testing.T) {
 			},
 			OrderDetails: &bmecat12.ArticleOrderDetails{
 				OrderUnit:     "BOX",
-				NoCuPerOu:     6.0,
+				NoCuPerOu:     float64Ptr(6.0),
 				ContentUnit:   "PCE",
-				PriceQuantity: 1,
-				QuantityMin:   1,
+				PriceQuantity: float64Ptr(1),
+				QuantityMin:   float64Ptr(1),
 			},
 			PriceDetails: []*bmecat12.ArticlePriceDetails{
 				&bmecat12.ArticlePriceDetails{
@@ -384,20 +386,20 @@ func TestWriteUpdateProducts(t *testing.T) {
 					Prices: []*bmecat12.ArticlePrice{
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1499.50,
+							Amount:     bmecat12.MustParseDecimal("1499.5"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 1,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(1),
 							Territory:  []string{"DE", "AT"},
 						},
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1300.90,
+							Amount:     bmecat12.MustParseDecimal("1300.9"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 100,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(100),
 							Territory:  []string{"DE", "AT"},
 						},
 					},
@@ -471,20 +473,20 @@ func TestWriteUpdatePrices(t *testing.T) {
 					Prices: []*bmecat12.ArticlePrice{
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1499.50,
+							Amount:     bmecat12.MustParseDecimal("1499.5"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 1,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(1),
 							Territory:  []string{"DE", "AT"},
 						},
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1300.90,
+							Amount:     bmecat12.MustParseDecimal("1300.9"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 100,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(100),
 							Territory:  []string{"DE", "AT"},
 						},
 					},
@@ -569,10 +571,10 @@ func TestWriteNewCatalogWithBlankClassificationSystem(t *testing.T) {
 			},
 			OrderDetails: &bmecat12.ArticleOrderDetails{
 				OrderUnit:     "BOX",
-				NoCuPerOu:     6.0,
+				NoCuPerOu:     float64Ptr(6.0),
 				ContentUnit:   "PCE",
-				PriceQuantity: 1,
-				QuantityMin:   1,
+				PriceQuantity: float64Ptr(1),
+				QuantityMin:   float64Ptr(1),
 			},
 			PriceDetails: []*bmecat12.ArticlePriceDetails{
 				&bmecat12.ArticlePriceDetails{
@@ -583,20 +585,20 @@ func TestWriteNewCatalogWithBlankClassificationSystem(t *testing.T) {
 					Prices: []*bmecat12.ArticlePrice{
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1499.50,
+							Amount:     bmecat12.MustParseDecimal("1499.5"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 1,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(1),
 							Territory:  []string{"DE", "AT"},
 						},
 						&bmecat12.ArticlePrice{
 							Type:       bmecat12.ArticlePriceTypeNetCustomer,
-							Amount:     1300.90,
+							Amount:     bmecat12.MustParseDecimal("1300.9"),
 							Currency:   "EUR",
-							Tax:        0.19,
-							Factor:     1.0,
-							LowerBound: 100,
+							Tax:        decimalPtr("0.19"),
+							Factor:     decimalPtr("1"),
+							LowerBound: float64Ptr(100),
 							Territory:  []string{"DE", "AT"},
 						},
 					},
@@ -651,3 +653,474 @@ func TestWriteNewCatalogWithBlankClassificationSystem(t *testing.T) {
 		t.Fail()
 	}
 }
+
+func TestWriteAndReadGroupDescriptionsAndKeywords(t *testing.T) {
+	root := "1"
+	cw := subtreeCatalogWriter{
+		catalogWriter: catalogWriter{
+			tx:     bmecat12.NewCatalog,
+			header: testHeader,
+			classificationSystem: &bmecat12.ClassificationSystem{
+				Name: "udf_Supplier-1.0",
+				Groups: []*bmecat12.ClassificationGroup{
+					{
+						ID:          "10",
+						Name:        "Tools",
+						Description: "Hand and power tools",
+						Synonyms:    []bmecat12.ClassificationGroupSynonym{{Value: "Equipment"}},
+					},
+				},
+			},
+		},
+		groupSystem: &bmecat12.CatalogGroupSystem{
+			ID: "1",
+			Groups: []*bmecat12.CatalogGroup{
+				{Type: "root", ID: "1", Name: "Catalog"},
+				{
+					Type:        "leaf",
+					ID:          "2",
+					Name:        "Drills",
+					Description: "Cordless and corded drills",
+					ParentID:    &root,
+					Keywords:    []string{"drill", "screwdriver"},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+
+	var catalogGroups []*bmecat12.CatalogGroup
+	var classifGroups []*bmecat12.ClassificationGroup
+	h := bmecat12.HandlerFuncs{
+		OnCatalogGroup: func(g *bmecat12.CatalogGroup) error {
+			catalogGroups = append(catalogGroups, g)
+			return nil
+		},
+		OnClassificationGroup: func(g *bmecat12.ClassificationGroup) error {
+			classifGroups = append(classifGroups, g)
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(catalogGroups); want != have {
+		t.Fatalf("want %d catalog groups, have %d", want, have)
+	}
+	g := catalogGroups[1]
+	if want, have := "Cordless and corded drills", g.Description; want != have {
+		t.Fatalf("want Description=%q, have %q", want, have)
+	}
+	if want, have := "drill,screwdriver", strings.Join(g.Keywords, ","); want != have {
+		t.Fatalf("want Keywords=%s, have %s", want, have)
+	}
+	if want, have := 1, len(classifGroups); want != have {
+		t.Fatalf("want %d classification groups, have %d", want, have)
+	}
+	if want, have := "Hand and power tools", classifGroups[0].Description; want != have {
+		t.Fatalf("want Description=%q, have %q", want, have)
+	}
+	if want, have := 1, len(classifGroups[0].Synonyms); want != have {
+		t.Fatalf("want %d synonyms, have %d", want, have)
+	}
+}
+
+func TestWriteAndReadGroupMimeInfoAndUDX(t *testing.T) {
+	root := "1"
+	udx := &bmecat12.UserDefinedExtensions{}
+	udx.Fields.Add("SYSTEM.BANNER_COLOR", "red")
+	cw := subtreeCatalogWriter{
+		catalogWriter: catalogWriter{tx: bmecat12.NewCatalog, header: testHeader},
+		groupSystem: &bmecat12.CatalogGroupSystem{
+			ID: "1",
+			Groups: []*bmecat12.CatalogGroup{
+				{Type: "root", ID: "1", Name: "Catalog"},
+				{
+					Type:     "leaf",
+					ID:       "2",
+					Name:     "Drills",
+					ParentID: &root,
+					MimeInfo: &bmecat12.MimeInfo{Mimes: []*bmecat12.Mime{
+						{Type: bmecat12.MimeTypeJPEG, Source: "drills.jpg", Purpose: bmecat12.MimePurposeNormal},
+					}},
+					UDX:      udx,
+					Keywords: []string{"drill"},
+				},
+			},
+		},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf, bmecat12.WithMimeSourceMode(bmecat12.MimeSourceAbsolute)).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	mimeInfo := strings.Index(out, "<MIME_INFO>")
+	extensions := strings.Index(out, "<USER_DEFINED_EXTENSIONS>")
+	keyword := strings.Index(out, "<KEYWORD>drill</KEYWORD>")
+	if mimeInfo < 0 || extensions < mimeInfo || keyword < extensions {
+		t.Fatalf("want MIME_INFO, USER_DEFINED_EXTENSIONS, and KEYWORD in order, have\n%s", out)
+	}
+
+	var groups []*bmecat12.CatalogGroup
+	h := bmecat12.HandlerFuncs{
+		OnCatalogGroup: func(g *bmecat12.CatalogGroup) error {
+			groups = append(groups, g)
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes())).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, len(groups); want != have {
+		t.Fatalf("want %d catalog groups, have %d", want, have)
+	}
+	g := groups[1]
+	if g.MimeInfo == nil || len(g.MimeInfo.Mimes) != 1 {
+		t.Fatalf("want 1 MIME element, have %+v", g.MimeInfo)
+	}
+	if want, have := "https://example.com/images/drills.jpg", g.MimeInfo.Mimes[0].Source; want != have {
+		t.Fatalf("want MIME_SOURCE %q, have %q", want, have)
+	}
+	if g.UDX == nil {
+		t.Fatal("want USER_DEFINED_EXTENSIONS")
+	}
+	if value, _ := g.UDX.Fields.Get("SYSTEM.BANNER_COLOR"); value != "red" {
+		t.Fatalf("want UDX.SYSTEM.BANNER_COLOR %q, have %q", "red", value)
+	}
+	if want, have := "drills.jpg", cw.groupSystem.Groups[1].MimeInfo.Mimes[0].Source; want != have {
+		t.Fatalf("want MIME_SOURCE %q of the original group, have %q", want, have)
+	}
+}
+
+func TestWriteXMLLang(t *testing.T) {
+	tests := []struct {
+		Language string
+		Options  []bmecat12.WriterOption
+		Want     string // empty if there is no xml:lang
+	}{
+		{Language: "de", Want: `xml:lang="de"`},
+		{Language: "deu", Want: `xml:lang="de"`},
+		{Language: "eng", Want: `xml:lang="en"`},
+		{Language: "deu", Options: []bmecat12.WriterOption{bmecat12.WithXMLLang(false)}},
+		{Language: ""},
+	}
+	for _, tt := range tests {
+		cw := catalogWriter{tx: bmecat12.NewCatalog, language: tt.Language, header: testHeader}
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, tt.Options...).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		root := out[strings.Index(out, "<BMECAT "):]
+		root = root[:strings.Index(root, ">")]
+		if tt.Want == "" && strings.Contains(root, "xml:lang") {
+			t.Fatalf("%q: want no xml:lang, have %s", tt.Language, root)
+		}
+		if tt.Want != "" && !strings.Contains(root, tt.Want) {
+			t.Fatalf("%q: want %s, have %s", tt.Language, tt.Want, root)
+		}
+	}
+}
+
+func TestWriteWithArticleValidator(t *testing.T) {
+	requireDescription := func(a *bmecat12.Article) error {
+		if a.Details == nil || a.Details.DescriptionShort == "" {
+			return fmt.Errorf("%w: no DESCRIPTION_SHORT", bmecat12.ErrSkipArticle)
+		}
+		return nil
+	}
+	cw := catalogWriter{
+		tx:       bmecat12.NewCatalog,
+		language: "de",
+		header:   testHeader,
+		articles: []*bmecat12.Article{
+			{SupplierAID: "1000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Schraube"}},
+			{SupplierAID: "2000", CatalogGroupIDs: []string{"10"}},
+			{SupplierAID: "3000", Details: &bmecat12.ArticleDetails{DescriptionShort: "Mutter"}},
+		},
+	}
+
+	var written int
+	var buf bytes.Buffer
+	w := bmecat12.NewWriter(&buf,
+		bmecat12.WithCatalogGroupMaps(),
+		bmecat12.WithArticleValidator(requireDescription),
+		bmecat12.WithProgress(func(n int) { written = n }),
+	)
+	if err := w.Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want, have := 2, written; want != have {
+		t.Fatalf("want %d articles written, have %d", want, have)
+	}
+	out := buf.String()
+	if strings.Contains(out, "<SUPPLIER_AID>2000") || strings.Contains(out, "<ART_ID>2000") {
+		t.Fatalf("want article 2000 and its mapping skipped, have\n%s", out)
+	}
+
+	// Abort on other errors
+	errNoPrice := errors.New("no price")
+	buf.Reset()
+	w = bmecat12.NewWriter(&buf, bmecat12.WithArticleValidator(func(a *bmecat12.Article) error {
+		if a.SupplierAID == "3000" {
+			return errNoPrice
+		}
+		return nil
+	}))
+	err := w.Do(context.Background(), cw)
+	if !errors.Is(err, errNoPrice) {
+		t.Fatalf("want %v, have %v", errNoPrice, err)
+	}
+	var encErr *bmecat12.EncodeError
+	if !errors.As(err, &encErr) {
+		t.Fatalf("want EncodeError, have %T", err)
+	}
+	if want, have := "3000", encErr.SupplierAID; want != have {
+		t.Fatalf("want SupplierAID=%q, have %q", want, have)
+	}
+}
+
+func TestWriteHeaderPreflight(t *testing.T) {
+	tests := []struct {
+		Header  *bmecat12.Header
+		Missing string
+	}{
+		{nil, "HEADER"},
+		{&bmecat12.Header{}, "HEADER/CATALOG"},
+		{
+			&bmecat12.Header{Catalog: &bmecat12.Catalog{ID: "CAT1"}},
+			"HEADER/CATALOG/LANGUAGE HEADER/CATALOG/CATALOG_VERSION",
+		},
+		{
+			&bmecat12.Header{
+				Catalog:  &bmecat12.Catalog{Language: "deu", ID: "CAT1", Version: "1.0"},
+				Buyer:    &bmecat12.Buyer{},
+				Supplier: &bmecat12.Supplier{},
+			},
+			"HEADER/BUYER/BUYER_NAME HEADER/SUPPLIER/SUPPLIER_NAME",
+		},
+	}
+	for i, tt := range tests {
+		cw := catalogWriter{
+			tx:       bmecat12.NewCatalog,
+			language: "deu",
+			header:   tt.Header,
+			articles: []*bmecat12.Article{{SupplierAID: "1000"}},
+		}
+		var buf bytes.Buffer
+		err := bmecat12.NewWriter(&buf).Do(context.Background(), cw)
+		if !errors.Is(err, bmecat12.ErrInvalidHeader) {
+			t.Fatalf("#%d: want ErrInvalidHeader, have %v", i, err)
+		}
+		var herr *bmecat12.HeaderError
+		if !errors.As(err, &herr) {
+			t.Fatalf("#%d: want HeaderError, have %T", i, err)
+		}
+		if want, have := tt.Missing, strings.Join(herr.Missing, " "); want != have {
+			t.Fatalf("#%d: want missing %q, have %q", i, want, have)
+		}
+		if buf.Len() != 0 {
+			t.Fatalf("#%d: want no output, have:\n%s", i, buf.String())
+		}
+
+		s := bmecat12.NewShardedWriter(func(int) (io.WriteCloser, error) {
+			t.Fatalf("#%d: want no files", i)
+			return nil, nil
+		})
+		if err := s.Do(context.Background(), cw); !errors.Is(err, bmecat12.ErrInvalidHeader) {
+			t.Fatalf("#%d: want ErrInvalidHeader from ShardedWriter, have %v", i, err)
+		}
+
+		buf.Reset()
+		if err := bmecat12.NewWriter(&buf, bmecat12.WithSkipValidation()).Do(context.Background(), cw); err != nil {
+			t.Fatalf("#%d: want no error with WithSkipValidation, have %v", i, err)
+		}
+	}
+}
+
+func TestWriteAndReadZeroPrices(t *testing.T) {
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		articles: []*bmecat12.Article{
+			{
+				SupplierAID: "1000",
+				OrderDetails: &bmecat12.ArticleOrderDetails{
+					OrderUnit:   "C62",
+					QuantityMin: float64Ptr(0),
+				},
+				PriceDetails: []*bmecat12.ArticlePriceDetails{
+					{Prices: []*bmecat12.ArticlePrice{
+						{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("0"), Tax: decimalPtr("0"), LowerBound: float64Ptr(1)},
+					}},
+				},
+			},
+		},
+	}
+	for _, direct := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, bmecat12.WithDirectEncoding(direct)).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		for _, want := range []string{"<QUANTITY_MIN>0</QUANTITY_MIN>", "<PRICE_AMOUNT>0</PRICE_AMOUNT>", "<TAX>0</TAX>", "<LOWER_BOUND>1</LOWER_BOUND>"} {
+			if !strings.Contains(out, want) {
+				t.Fatalf("direct=%v: want %s in\n%s", direct, want, out)
+			}
+		}
+		for _, unwanted := range []string{"PRICE_QUANTITY", "PRICE_FACTOR"} {
+			if strings.Contains(out, unwanted) {
+				t.Fatalf("direct=%v: want no %s in\n%s", direct, unwanted, out)
+			}
+		}
+
+		for _, options := range [][]bmecat12.ReaderOption{nil, {bmecat12.WithFastDecoder()}} {
+			var th testHandler
+			if err := bmecat12.NewReader(bytes.NewReader(buf.Bytes()), options...).Do(context.Background(), &th); err != nil {
+				t.Fatal(err)
+			}
+			if want, have := 1, len(th.articles); want != have {
+				t.Fatalf("want %d articles, have %d", want, have)
+			}
+			a := th.articles[0]
+			if od := a.OrderDetails; od.QuantityMin == nil || *od.QuantityMin != 0 || od.PriceQuantity != nil {
+				t.Fatalf("want QUANTITY_MIN 0 and no PRICE_QUANTITY, have %+v", od)
+			}
+			p := a.PriceDetails[0].Prices[0]
+			if p.Tax == nil || !p.Tax.IsZero() {
+				t.Fatalf("want TAX 0, have %v", p.Tax)
+			}
+			if p.Factor != nil {
+				t.Fatalf("want no PRICE_FACTOR, have %v", *p.Factor)
+			}
+		}
+	}
+}
+
+func TestWriteDateOnlyPriceDates(t *testing.T) {
+	cw := catalogWriter{
+		tx:     bmecat12.NewCatalog,
+		header: testHeader,
+		articles: []*bmecat12.Article{
+			{
+				SupplierAID: "1000",
+				PriceDetails: []*bmecat12.ArticlePriceDetails{
+					{
+						Dates: []*bmecat12.DateTime{
+							bmecat12.NewDate(bmecat12.DateTimeValidStartDate, time.Date(2001, 1, 1, 12, 0, 0, 0, time.UTC)),
+						},
+						Prices: []*bmecat12.ArticlePrice{
+							{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("1499.50")},
+						},
+					},
+				},
+			},
+		},
+	}
+	for _, direct := range []bool{true, false} {
+		var buf bytes.Buffer
+		if err := bmecat12.NewWriter(&buf, bmecat12.WithDirectEncoding(direct)).Do(context.Background(), cw); err != nil {
+			t.Fatal(err)
+		}
+		out := buf.String()
+		if want := "<DATE>2001-01-01</DATE>"; !strings.Contains(out, want) {
+			t.Fatalf("direct=%v: want %s in\n%s", direct, want, out)
+		}
+		if i := strings.Index(out, "<ARTICLE>"); strings.Contains(out[i:], "<TIME>") {
+			t.Fatalf("direct=%v: want no TIME in\n%s", direct, out)
+		}
+	}
+}
+
+func TestWriteInvalidDateTime(t *testing.T) {
+	tests := []*bmecat12.DateTime{
+		{Type: "valid_from", DateString: "2001-01-01"},
+		{Type: bmecat12.DateTimeValidStartDate, DateString: "01.01.2001"},
+		{Type: bmecat12.DateTimeValidStartDate, DateString: "2001-01-01", TimeString: "10:30"},
+		{Type: bmecat12.DateTimeValidStartDate, DateString: "2001-01-01", TimeString: "10:30:00", TimeZoneString: "CET"},
+	}
+	for _, dt := range tests {
+		cw := catalogWriter{
+			tx:     bmecat12.NewCatalog,
+			header: testHeader,
+			articles: []*bmecat12.Article{
+				{
+					SupplierAID: "1000",
+					PriceDetails: []*bmecat12.ArticlePriceDetails{
+						{
+							Dates: []*bmecat12.DateTime{dt},
+							Prices: []*bmecat12.ArticlePrice{
+								{Type: bmecat12.ArticlePriceTypeNetList, Amount: bmecat12.MustParseDecimal("1499.50")},
+							},
+						},
+					},
+				},
+			},
+		}
+		for _, direct := range []bool{true, false} {
+			err := bmecat12.NewWriter(ioutil.Discard, bmecat12.WithDirectEncoding(direct)).Do(context.Background(), cw)
+			if !errors.Is(err, bmecat12.ErrInvalidDateTime) {
+				t.Fatalf("%+v direct=%v: want ErrInvalidDateTime, have %v", *dt, direct, err)
+			}
+		}
+	}
+}
+
+func TestWriteWithArticleModes(t *testing.T) {
+	tests := []struct {
+		Tx   bmecat12.Transaction
+		Mode string
+		OK   bool
+	}{
+		{Tx: bmecat12.NewCatalog, Mode: "", OK: true},
+		{Tx: bmecat12.NewCatalog, Mode: bmecat12.ArticleModeNew, OK: false},
+		{Tx: bmecat12.UpdateProducts, Mode: bmecat12.ArticleModeNew, OK: true},
+		{Tx: bmecat12.UpdateProducts, Mode: bmecat12.ArticleModeUpdate, OK: true},
+		{Tx: bmecat12.UpdateProducts, Mode: bmecat12.ArticleModeDelete, OK: true},
+		{Tx: bmecat12.UpdateProducts, Mode: "", OK: false},
+		{Tx: bmecat12.UpdateProducts, Mode: "insert", OK: false},
+		{Tx: bmecat12.UpdatePrices, Mode: "", OK: true},
+		{Tx: bmecat12.UpdatePrices, Mode: bmecat12.ArticleModeUpdate, OK: false},
+	}
+	for _, tt := range tests {
+		cw := catalogWriter{
+			tx:          tt.Tx,
+			prevVersion: 1,
+			header:      testHeader,
+			articles:    []*bmecat12.Article{{Mode: tt.Mode, SupplierAID: "1000"}},
+		}
+		err := bmecat12.NewWriter(ioutil.Discard).Do(context.Background(), cw)
+		if tt.OK && err != nil {
+			t.Fatalf("%v, mode %q: want no error, have %v", tt.Tx, tt.Mode, err)
+		}
+		if !tt.OK && !errors.Is(err, bmecat12.ErrInvalidMode) {
+			t.Fatalf("%v, mode %q: want ErrInvalidMode, have %v", tt.Tx, tt.Mode, err)
+		}
+		err = bmecat12.NewWriter(ioutil.Discard, bmecat12.WithSkipValidation()).Do(context.Background(), cw)
+		if !tt.OK && !errors.Is(err, bmecat12.ErrInvalidMode) {
+			t.Fatalf("%v, mode %q: want ErrInvalidMode with WithSkipValidation, have %v", tt.Tx, tt.Mode, err)
+		}
+		if err := bmecat12.NewWriter(ioutil.Discard, bmecat12.WithSkipModeValidation()).Do(context.Background(), cw); err != nil {
+			t.Fatalf("%v, mode %q: want no error with WithSkipModeValidation, have %v", tt.Tx, tt.Mode, err)
+		}
+	}
+}
+
+func TestDeleteArticle(t *testing.T) {
+	cw := catalogWriter{
+		tx:          bmecat12.UpdateProducts,
+		prevVersion: 1,
+		header:      testHeader,
+		articles:    []*bmecat12.Article{bmecat12.DeleteArticle("1000")},
+	}
+	var buf bytes.Buffer
+	if err := bmecat12.NewWriter(&buf).Do(context.Background(), cw); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<ARTICLE mode="delete">`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("want %s in\n%s", want, buf.String())
+	}
+}
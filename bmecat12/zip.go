@@ -0,0 +1,88 @@
+package bmecat12
+
+import (
+	"archive/zip"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// DefaultZipPattern matches the BMEcat XML file in a zip archive by
+// default, see OpenZip.
+const DefaultZipPattern = "*.xml"
+
+// ZipEntry is the BMEcat XML file in a zip archive, as returned by
+// OpenZip. It is an io.ReadSeeker to pass to NewReader. Seeking is
+// emulated by decompressing the entry from the start, so it is only
+// cheap for the rewinds of the Reader between its passes.
+type ZipEntry struct {
+	*rewindReader
+
+	// Name of the entry in the archive, e.g. "export/catalog.xml".
+	Name string
+}
+
+// OpenZip opens the BMEcat XML file in a zip archive, e.g. a delivery
+// with the catalog and folders with the images referenced in MIME_INFO.
+// The entry is read from the archive directly, without extracting it.
+//
+// The entry is the one whose name matches pattern, as in path.Match.
+// A pattern without a slash matches the base name of the entries in all
+// folders of the archive. If pattern is empty, DefaultZipPattern is
+// used. It is an error if no entry or more than one entry matches.
+func OpenZip(ra io.ReaderAt, size int64, pattern string) (*ZipEntry, error) {
+	zr, err := zip.NewReader(ra, size)
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat: unable to open zip archive")
+	}
+	if pattern == "" {
+		pattern = DefaultZipPattern
+	}
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, errors.Wrapf(err, "bmecat: invalid zip pattern %q", pattern)
+	}
+	var found []*zip.File
+	for _, f := range zr.File {
+		if strings.HasSuffix(f.Name, "/") {
+			continue // folder
+		}
+		name := f.Name
+		if !strings.Contains(pattern, "/") {
+			name = path.Base(name)
+		}
+		if ok, _ := path.Match(pattern, name); ok {
+			found = append(found, f)
+		}
+	}
+	switch len(found) {
+	case 0:
+		return nil, errors.Errorf("bmecat: no entry matches %q in zip archive", pattern)
+	case 1:
+	default:
+		return nil, errors.Errorf("bmecat: %d entries match %q in zip archive", len(found), pattern)
+	}
+	f := found[0]
+	rr, err := newRewindReader(func() (io.Reader, error) {
+		rc, err := f.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "bmecat: unable to open %s in zip archive", f.Name)
+		}
+		return rc, nil
+	}, int64(f.UncompressedSize64))
+	if err != nil {
+		return nil, err
+	}
+	return &ZipEntry{rewindReader: rr, Name: f.Name}, nil
+}
+
+// Size returns the uncompressed size of the entry in bytes.
+func (e *ZipEntry) Size() int64 {
+	return e.size
+}
+
+// Close closes the entry.
+func (e *ZipEntry) Close() error {
+	return e.close()
+}
@@ -0,0 +1,85 @@
+package bmecat12_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+func zipArchive(t *testing.T, files map[string][]byte) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, data := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := w.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestOpenZip(t *testing.T) {
+	data, err := ioutil.ReadFile(filepath.Join("testdata", "update_products.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zipArchive(t, map[string][]byte{
+		"export/catalog.xml":  data,
+		"export/images/1.jpg": []byte("JPEG"),
+		"readme.txt":          []byte("README"),
+	})
+
+	e, err := bmecat12.OpenZip(bytes.NewReader(archive), int64(len(archive)), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if want, have := "export/catalog.xml", e.Name; want != have {
+		t.Fatalf("want Name=%q, have %q", want, have)
+	}
+	h := &testHandler{}
+	if err := bmecat12.NewReader(e).Do(context.Background(), h); err != nil {
+		t.Fatal(err)
+	}
+	var aids []string
+	for _, a := range h.articles {
+		aids = append(aids, a.SupplierAID)
+	}
+	if want, have := "1000,2000", strings.Join(aids, ","); want != have {
+		t.Fatalf("want articles %s, have %s", want, have)
+	}
+}
+
+func TestOpenZipPattern(t *testing.T) {
+	archive := zipArchive(t, map[string][]byte{
+		"a/catalog.xml": []byte("<BMECAT/>"),
+		"b/catalog.xml": []byte("<BMECAT/>"),
+	})
+	if _, err := bmecat12.OpenZip(bytes.NewReader(archive), int64(len(archive)), ""); err == nil {
+		t.Fatal("want error for more than one matching entry")
+	}
+	e, err := bmecat12.OpenZip(bytes.NewReader(archive), int64(len(archive)), "b/*.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer e.Close()
+	if want, have := "b/catalog.xml", e.Name; want != have {
+		t.Fatalf("want Name=%q, have %q", want, have)
+	}
+	if _, err := bmecat12.OpenZip(bytes.NewReader(archive), int64(len(archive)), "*.bmecat"); err == nil {
+		t.Fatal("want error for no matching entry")
+	}
+}
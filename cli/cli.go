@@ -0,0 +1,228 @@
+// Package cli implements the commands of the bmecat executable, so they
+// can be embedded into other tools and invoked programmatically:
+//
+//	err := cli.Run(ctx, []string{"info", "catalog.xml"}, os.Stdout, os.Stderr)
+//
+// Additional commands can be registered with RegisterCommand.
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"log"
+	"sort"
+	"sync"
+)
+
+// Name of the executable, as printed in usage messages.
+var Name = "bmecat"
+
+var (
+	commandsMu sync.RWMutex
+	commands   = make(map[string]func(flags *flag.FlagSet) Command)
+)
+
+// ErrUsage is returned when an unknown command is called.
+var ErrUsage = UsageError("invalid command")
+
+// UsageError is used to indicate a problem with invoking the executable,
+// e.g. invalid parameters.
+type UsageError string
+
+func (e UsageError) Error() string {
+	return fmt.Sprintf("Usage error: %s", string(e))
+}
+
+// Env is the environment a command runs in.
+type Env struct {
+	// Stdout receives the output of the command.
+	Stdout io.Writer
+	// Stderr receives usage messages and progress reports.
+	Stderr io.Writer
+}
+
+// Command represents a registered installment of the program.
+type Command interface {
+	Usage(env *Env)
+	Run(ctx context.Context, env *Env, args []string) error
+}
+
+// describer can be implemented by commands to print a description.
+type describer interface {
+	Describe() string
+}
+
+// exampler can be implemented by commands to print an example call.
+type exampler interface {
+	Examples() []string
+}
+
+// RegisterCommand registers a command to run for a given mode. makeCmd
+// is called for every invocation of the command, with a new FlagSet to
+// register the flags of the command with.
+func RegisterCommand(mode string, makeCmd func(flags *flag.FlagSet) Command) {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	if _, dup := commands[mode]; dup {
+		log.Fatalf("duplicate command %q registered", mode)
+	}
+	commands[mode] = makeCmd
+}
+
+// newCommand creates the command for mode along with its flags. It
+// returns nil if there is no such command.
+func newCommand(mode string) (Command, *flag.FlagSet) {
+	commandsMu.RLock()
+	makeCmd, ok := commands[mode]
+	commandsMu.RUnlock()
+	if !ok {
+		return nil, nil
+	}
+	flags := flag.NewFlagSet(mode+"options", flag.ContinueOnError)
+	flags.Usage = func() {}
+	flags.SetOutput(ioutil.Discard)
+	return makeCmd(flags), flags
+}
+
+// Run runs the command given in args, e.g. []string{"info", "catalog.xml"},
+// just like the bmecat executable does. The output of the command is
+// written to stdout, usage messages and progress reports to stderr. Run
+// returns the error of the command, if any; use ExitCode to map it to the
+// exit code of an executable.
+func Run(ctx context.Context, args []string, stdout, stderr io.Writer) error {
+	env := &Env{Stdout: stdout, Stderr: stderr}
+	if len(args) == 0 {
+		usage(env, "No mode given.")
+		return ErrUsage
+	}
+
+	mode := args[0]
+	if mode == "help" && len(args) > 1 {
+		return help(env, args[1])
+	}
+	cmd, flags := newCommand(mode)
+	if cmd == nil {
+		usage(env, fmt.Sprintf("Unknown mode %q", mode))
+		return ErrUsage
+	}
+
+	err := flags.Parse(args[1:])
+	if err != nil {
+		err = ErrUsage
+	} else {
+		err = cmd.Run(ctx, env, flags.Args())
+	}
+	if e, isUsageErr := err.(UsageError); isUsageErr {
+		fmt.Fprintf(stderr, "%s\n", e)
+		cmd.Usage(env)
+		if hasFlags(flags) {
+			fmt.Fprintf(stderr, "\nMode-specific options for mode %q:\n", mode)
+			flags.SetOutput(stderr)
+			flags.PrintDefaults()
+		}
+		return err
+	}
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+	}
+	return err
+}
+
+// ExitCode returns the exit code for the error returned by Run: 0 for
+// no error, 1 for a UsageError, and 2 for all other errors.
+func ExitCode(err error) int {
+	switch err.(type) {
+	case nil:
+		return 0
+	case UsageError:
+		return 1
+	default:
+		return 2
+	}
+}
+
+func hasFlags(flags *flag.FlagSet) bool {
+	any := false
+	flags.VisitAll(func(*flag.Flag) {
+		any = true
+	})
+	return any
+}
+
+// modes returns the registered modes, sorted by name.
+func modes() []string {
+	commandsMu.RLock()
+	defer commandsMu.RUnlock()
+	modes := make([]string, 0, len(commands))
+	for mode := range commands {
+		modes = append(modes, mode)
+	}
+	sort.Strings(modes)
+	return modes
+}
+
+func usage(env *Env, msg string) {
+	if msg != "" {
+		fmt.Fprintf(env.Stderr, "Error: %v\n", msg)
+	}
+	fmt.Fprintf(env.Stderr, `
+Usage: `+Name+` <mode> [commandopts] [commandargs]
+
+Modes:
+
+`)
+
+	for _, mode := range modes() {
+		cmd, _ := newCommand(mode)
+		if des, ok := cmd.(describer); ok {
+			fmt.Fprintf(env.Stderr, "  %-25s %s\n", mode, des.Describe())
+		}
+	}
+
+	fmt.Fprintf(env.Stderr, "\nExamples:\n")
+	for _, mode := range modes() {
+		cmd, _ := newCommand(mode)
+		if ex, ok := cmd.(exampler); ok {
+			exs := ex.Examples()
+			if len(exs) > 0 {
+				fmt.Fprintf(env.Stderr, "\n")
+			}
+			for _, example := range exs {
+				fmt.Fprintf(env.Stderr, "  %s %s %s\n", Name, mode, example)
+			}
+		}
+	}
+
+	fmt.Fprintf(env.Stderr, `
+For mode-specific help:
+
+  `+Name+` help <mode>
+`)
+}
+
+func help(env *Env, mode string) error {
+	cmd, flags := newCommand(mode)
+	if cmd == nil {
+		usage(env, fmt.Sprintf("Unknown mode %q", mode))
+		return ErrUsage
+	}
+	if des, ok := cmd.(describer); ok {
+		fmt.Fprintf(env.Stderr, "%s\n", des.Describe())
+	}
+	fmt.Fprintf(env.Stderr, "\n")
+	cmd.Usage(env)
+	if hasFlags(flags) {
+		flags.SetOutput(env.Stderr)
+		flags.PrintDefaults()
+	}
+	if ex, ok := cmd.(exampler); ok {
+		fmt.Fprintf(env.Stderr, "\nExamples:\n")
+		for _, example := range ex.Examples() {
+			fmt.Fprintf(env.Stderr, "  %s %s %s\n", Name, mode, example)
+		}
+	}
+	return nil
+}
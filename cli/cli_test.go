@@ -0,0 +1,243 @@
+package cli_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/bmecat12"
+	"github.com/olivere/bmecat/cli"
+)
+
+// testdata returns the path to a catalog in the testdata of bmecat12.
+func testdata(name string) string {
+	return filepath.Join("..", "bmecat12", "testdata", name)
+}
+
+// run runs the command in args and returns its stdout and stderr.
+func run(t *testing.T, args ...string) (string, string, error) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	err := cli.Run(context.Background(), args, &stdout, &stderr)
+	return stdout.String(), stderr.String(), err
+}
+
+func TestInfo(t *testing.T) {
+	stdout, _, err := run(t, "info", testdata("new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "Products"; !strings.Contains(stdout, want) {
+		t.Fatalf("want output to contain %q, have:\n%s", want, stdout)
+	}
+}
+
+func TestCopy(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	output := filepath.Join(dir, "copy.xml")
+	metrics := filepath.Join(dir, "metrics.json")
+	if _, _, err := run(t, "copy", "-metrics", metrics, testdata("new_catalog.golden.xml"), output); err != nil {
+		t.Fatal(err)
+	}
+	stdout, _, err := run(t, "validate", output)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "OK (1 articles)\n", stdout; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	data, err := ioutil.ReadFile(metrics)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid(data) {
+		t.Fatalf("want metrics as JSON, have:\n%s", data)
+	}
+}
+
+func TestCopyWithHashes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	hashes := filepath.Join(dir, "catalog.hashes")
+	output := filepath.Join(dir, "delta.xml")
+	for _, tt := range []struct {
+		args []string
+		want string
+	}{
+		{nil, `prev_version="0"`},
+		{nil, `prev_version="1"`},
+		{[]string{"-prev-version", "7"}, `prev_version="7"`},
+		{nil, `prev_version="8"`},
+	} {
+		args := append([]string{"copy", "-hashes", hashes}, tt.args...)
+		if _, _, err := run(t, append(args, testdata("new_catalog.golden.xml"), output)...); err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadFile(output)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !strings.Contains(string(data), tt.want) {
+			t.Fatalf("want output to contain %s, have:\n%s", tt.want, data)
+		}
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	stdout, _, err := run(t, "coverage", "-unmapped", testdata("new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "IGNORED                        1  BMECAT@version\n"; !strings.HasPrefix(stdout, want) {
+		t.Fatalf("want output to start with %q, have:\n%s", want, stdout)
+	}
+}
+
+func TestPerf(t *testing.T) {
+	for _, flags := range [][]string{nil, {"-fast"}} {
+		args := append(append([]string{"perf"}, flags...), testdata("update_products.golden.xml"))
+		stdout, _, err := run(t, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if want := "Products/sec"; !strings.Contains(stdout, want) {
+			t.Fatalf("want output to contain %q, have:\n%s", want, stdout)
+		}
+	}
+}
+
+func TestScore(t *testing.T) {
+	stdout, _, err := run(t, "score", "-json", testdata("new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !json.Valid([]byte(stdout)) {
+		t.Fatalf("want score as JSON, have:\n%s", stdout)
+	}
+}
+
+func TestUDX(t *testing.T) {
+	stdout, _, err := run(t, "udx", "-header", "list", testdata("new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "UDX.SYSTEM.CUSTOM_FIELD1"; !strings.Contains(stdout, want) {
+		t.Fatalf("want output to contain %q, have:\n%s", want, stdout)
+	}
+
+	stdout, _, err = run(t, "udx", "-header", "extract", "SYSTEM.CUSTOM_FIELD1", testdata("new_catalog.golden.xml"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := "SUPPLIER_AID,UDX.SYSTEM.CUSTOM_FIELD1\n,A\n", stdout; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	// The official DTDs are not embedded, so -dtd needs a directory
+	if _, _, err := run(t, "validate", "-dtd", "-dtd-dir", "", testdata("new_catalog.golden.xml")); err == nil {
+		t.Fatal("want error for -dtd without -dtd-dir")
+	}
+
+	// Stand-ins for the official DTDs
+	dir, err := ioutil.TempDir("", "bmecat-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	for tx, dtd := range map[bmecat12.Transaction]string{
+		bmecat12.NewCatalog:     bmecat12.NewCatalogDTD,
+		bmecat12.UpdateProducts: bmecat12.UpdateProductsDTD,
+		bmecat12.UpdatePrices:   bmecat12.UpdatePricesDTD,
+	} {
+		if err := ioutil.WriteFile(filepath.Join(dir, tx.DocType()), []byte(dtd), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, name := range []string{"new_catalog.golden.xml", "update_prices.golden.xml"} {
+		stdout, _, err := run(t, "validate", "-dtd", "-dtd-dir", dir, testdata(name))
+		if err != nil {
+			t.Fatalf("%s: %v", name, err)
+		}
+		if want := "OK ("; !strings.HasPrefix(stdout, want) {
+			t.Fatalf("%s: want output to start with %q, have:\n%s", name, want, stdout)
+		}
+	}
+	if err := os.Remove(filepath.Join(dir, bmecat12.DocTypeUpdatePrices)); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := run(t, "validate", "-dtd", "-dtd-dir", dir, testdata("update_prices.golden.xml")); err == nil {
+		t.Fatal("want error for a missing DTD")
+	}
+}
+
+func TestValidateDTDFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-cli")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	dtd := filepath.Join(dir, "bmecat_new_catalog.dtd")
+	if err := ioutil.WriteFile(dtd, []byte(bmecat12.NewCatalogDTD), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := run(t, "validate", "-dtd-file", dtd, testdata("new_catalog.golden.xml")); err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := run(t, "validate", "-dtd-file", dtd, testdata("update_prices.golden.xml")); err == nil {
+		t.Fatal("want DTD violations for T_UPDATE_PRICES")
+	}
+}
+
+func TestUsageErrors(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{nil, "No mode given."},
+		{[]string{"unknown"}, `Unknown mode "unknown"`},
+		{[]string{"udx", "unknown"}, "Usage error: invalid command"},
+		{[]string{"info", "-unknown"}, "Usage error: invalid command"},
+	}
+	for _, tt := range tests {
+		_, stderr, err := run(t, tt.args...)
+		if want, have := 1, cli.ExitCode(err); want != have {
+			t.Fatalf("%v: want exit code %d, have %d", tt.args, want, have)
+		}
+		if !strings.Contains(stderr, tt.want) {
+			t.Fatalf("%v: want %q, have:\n%s", tt.args, tt.want, stderr)
+		}
+	}
+
+	_, _, err := run(t, "info", testdata("missing.xml"))
+	if want, have := 2, cli.ExitCode(err); want != have {
+		t.Fatalf("want exit code %d, have %d", want, have)
+	}
+}
+
+func TestHelp(t *testing.T) {
+	_, stderr, err := run(t, "help", "copy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{"Copy a BMEcat file", "Usage: bmecat copy", "-allow"} {
+		if !strings.Contains(stderr, want) {
+			t.Fatalf("want help to contain %q, have:\n%s", want, stderr)
+		}
+	}
+}
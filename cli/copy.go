@@ -0,0 +1,198 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// copyCommand reads a BMEcat file and writes it to another file,
+// optionally transforming the articles on the way.
+type copyCommand struct {
+	progress    bool
+	allowFile   string
+	denyFile    string
+	hashFile    string
+	prevVersion int
+	metricsFile string
+	mimePrefix  string
+	mimeExt     string
+}
+
+func init() {
+	RegisterCommand("copy", func(flags *flag.FlagSet) Command {
+		cmd := new(copyCommand)
+		flags.BoolVar(&cmd.progress, "P", false, "Print progress")
+		flags.StringVar(&cmd.allowFile, "allow", "", "Only keep articles whose SUPPLIER_AID or EAN is listed in this file")
+		flags.StringVar(&cmd.denyFile, "deny", "", "Drop articles whose SUPPLIER_AID or EAN is listed in this file")
+		flags.StringVar(&cmd.hashFile, "hashes", "", "Only write articles changed since the hashes stored in this file, as T_UPDATE_PRODUCTS, and update the file")
+		flags.IntVar(&cmd.prevVersion, "prev-version", -1, "PREV_VERSION of the T_UPDATE_PRODUCTS written with -hashes; defaults to the version stored in the hashes file")
+		flags.StringVar(&cmd.mimePrefix, "mime-prefix", "", "Prefix relative MIME_SOURCE values with this URL, e.g. of a CDN")
+		flags.StringVar(&cmd.mimeExt, "mime-ext", "", "Swap the file extension of MIME_SOURCE values, e.g. .tif:.jpg")
+		flags.StringVar(&cmd.metricsFile, "metrics", "", "Write a summary of the run to this file, as JSON if it ends in .json, in Prometheus text format otherwise")
+		return cmd
+	})
+}
+
+func (cmd *copyCommand) Describe() string {
+	return "Copy a BMEcat file, optionally filtering articles"
+}
+
+func (cmd *copyCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s copy [-P] [-allow <file>] [-deny <file>] [-hashes <file> [-prev-version <n>]] [-mime-prefix <url>] [-mime-ext <from>:<to>] [-metrics <file>] <input> [<output>]\n", Name)
+}
+
+func (cmd *copyCommand) Examples() []string {
+	return []string{
+		"-allow assortment.txt catalog.xml filtered.xml",
+		"-hashes catalog.hashes catalog.xml delta.xml",
+		"-mime-prefix https://cdn.example.com/images -mime-ext .tif:.jpg catalog.xml shop.xml",
+		"-metrics /var/lib/node_exporter/catalog.prom catalog.xml copy.xml",
+	}
+}
+
+func (cmd *copyCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing file name")
+	}
+
+	in, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+
+	var out io.Writer = env.Stdout
+	if len(args) > 1 {
+		f, err := os.Create(args[1])
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		out = f
+	}
+
+	var po []bmecat12.PipelineOption
+	if cmd.allowFile != "" {
+		l, err := readArticleList(cmd.allowFile)
+		if err != nil {
+			return err
+		}
+		po = append(po, bmecat12.WithTransformer(bmecat12.AllowArticles(l)))
+	}
+	if cmd.denyFile != "" {
+		l, err := readArticleList(cmd.denyFile)
+		if err != nil {
+			return err
+		}
+		po = append(po, bmecat12.WithTransformer(bmecat12.DenyArticles(l)))
+	}
+
+	var mimeRules []bmecat12.MimeRule
+	if cmd.mimeExt != "" {
+		exts := strings.SplitN(cmd.mimeExt, ":", 2)
+		if len(exts) != 2 {
+			return UsageError("-mime-ext must be of the form <from>:<to>, e.g. .tif:.jpg")
+		}
+		mimeRules = append(mimeRules, bmecat12.SwapMimeExtension(exts[0], exts[1]))
+	}
+	if cmd.mimePrefix != "" {
+		mimeRules = append(mimeRules, bmecat12.PrefixMimeSource(cmd.mimePrefix))
+	}
+	if len(mimeRules) > 0 {
+		po = append(po, bmecat12.WithTransformer(bmecat12.NewMimeRewriter(mimeRules...)))
+	}
+
+	var next *bmecat12.HashStore
+	if cmd.hashFile != "" {
+		prev, err := readHashStore(cmd.hashFile)
+		if err != nil {
+			return err
+		}
+		prevVersion := prev.Version()
+		if cmd.prevVersion >= 0 {
+			prevVersion = cmd.prevVersion
+		}
+		next = bmecat12.NewHashStore()
+		next.SetVersion(prevVersion + 1)
+		po = append(po,
+			bmecat12.WithTransformer(bmecat12.NewDelta(prev, next)),
+			bmecat12.WithTransaction(bmecat12.UpdateProducts, prevVersion),
+		)
+	}
+
+	var metrics *bmecat12.Metrics
+	if cmd.metricsFile != "" {
+		metrics = bmecat12.NewMetrics()
+		po = append(po,
+			bmecat12.WithHandler(metrics),
+			bmecat12.WithTransformer(metrics),
+		)
+	}
+
+	var ro []bmecat12.ReaderOption
+	if cmd.progress {
+		ro = append(ro, bmecat12.WithReaderProgressInfo(printProgress(env.Stderr)))
+	}
+	r := bmecat12.NewReader(in, ro...)
+	w := bmecat12.NewWriter(out, bmecat12.WithCatalogGroupMaps())
+	if err := bmecat12.NewPipeline(r, w, po...).Do(ctx); err != nil {
+		return err
+	}
+	if cmd.progress {
+		fmt.Fprintln(env.Stderr)
+	}
+	if next != nil {
+		if err := writeHashStore(cmd.hashFile, next); err != nil {
+			return err
+		}
+	}
+	if metrics != nil {
+		return writeMetricsFile(cmd.metricsFile, metrics)
+	}
+	return nil
+}
+
+// readArticleList reads a list of SUPPLIER_AIDs or EANs from a file.
+func readArticleList(name string) (*bmecat12.ArticleList, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bmecat12.ReadArticleList(f)
+}
+
+// readHashStore reads the article hashes of the previous run. A missing
+// file means there was no previous run.
+func readHashStore(name string) (*bmecat12.HashStore, error) {
+	f, err := os.Open(name)
+	if os.IsNotExist(err) {
+		return bmecat12.NewHashStore(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return bmecat12.ReadHashStore(f)
+}
+
+// writeHashStore saves the article hashes for the next run.
+func writeHashStore(name string, s *bmecat12.HashStore) error {
+	f, err := os.Create(name)
+	if err != nil {
+		return err
+	}
+	if _, err := s.WriteTo(f); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,69 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// coverageCommand reports which elements of a BMEcat file are mapped by
+// the bmecat12 package and which are ignored.
+type coverageCommand struct {
+	json     bool
+	unmapped bool
+}
+
+func init() {
+	RegisterCommand("coverage", func(flags *flag.FlagSet) Command {
+		cmd := new(coverageCommand)
+		flags.BoolVar(&cmd.json, "json", false, "Print the report as JSON")
+		flags.BoolVar(&cmd.unmapped, "unmapped", false, "Only print the elements and attributes that are ignored")
+		return cmd
+	})
+}
+
+func (cmd *coverageCommand) Describe() string {
+	return "Report which elements of a BMEcat file are mapped or ignored"
+}
+
+func (cmd *coverageCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s coverage [-json] [-unmapped] <file>\n", Name)
+}
+
+func (cmd *coverageCommand) Examples() []string {
+	return []string{
+		"-unmapped catalog.xml",
+	}
+}
+
+func (cmd *coverageCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing file name")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	report, err := bmecat12.Coverage(f)
+	if err != nil {
+		return err
+	}
+	if cmd.unmapped {
+		report.Elements = report.Unmapped()
+	}
+	if cmd.json {
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(report)
+	}
+	return report.WriteText(env.Stdout)
+}
@@ -1,4 +1,4 @@
-package main
+package cli
 
 import (
 	"context"
@@ -30,13 +30,11 @@ func (cmd *infoCommand) Describe() string {
 	return "Print BMEcat information"
 }
 
-func (cmd *infoCommand) Usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s info [-P] <file>\n", os.Args[0])
+func (cmd *infoCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s info [-P] <file>\n", Name)
 }
 
-func (cmd *infoCommand) Run(args []string) error {
-	ctx := context.Background()
-
+func (cmd *infoCommand) Run(ctx context.Context, env *Env, args []string) error {
 	if len(args) == 0 {
 		return errors.New("missing file name")
 	}
@@ -49,26 +47,23 @@ func (cmd *infoCommand) Run(args []string) error {
 
 	var o []bmecat12.ReaderOption
 	if cmd.progress {
-		f := func(pass int, offset int64) {
-			fmt.Printf("Pass %d, Offset %6d kB\r", pass, offset/1024)
-		}
-		o = append(o, bmecat12.WithReaderProgress(f))
+		o = append(o, bmecat12.WithReaderProgressInfo(printProgress(env.Stdout)))
 	}
 	err = bmecat12.NewReader(f, o...).Do(ctx, cmd)
 	if err != nil {
 		return err
 	}
 	if cmd.progress {
-		fmt.Println()
+		fmt.Fprintln(env.Stdout)
 	}
 
 	if cmd.header == nil {
 		return errors.New("did not receive HEADER")
 	}
 
-	fmt.Printf("%-24s: %7d\n", "Products", cmd.header.NumberOfArticles)
-	fmt.Printf("%-24s: %7d\n", "Catalog Groups", cmd.header.NumberOfCatalogGroups)
-	fmt.Printf("%-24s: %7d\n", "Classification Groups", cmd.header.NumberOfClassificationGroups)
+	fmt.Fprintf(env.Stdout, "%-24s: %7d\n", "Products", cmd.header.NumberOfArticles)
+	fmt.Fprintf(env.Stdout, "%-24s: %7d\n", "Catalog Groups", cmd.header.NumberOfCatalogGroups)
+	fmt.Fprintf(env.Stdout, "%-24s: %7d\n", "Classification Groups", cmd.header.NumberOfClassificationGroups)
 
 	return nil
 }
@@ -0,0 +1,40 @@
+package cli
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// writeMetricsFile writes the metrics to the named file. Files ending in
+// ".json" get JSON, all others the Prometheus text format. The file is
+// replaced atomically, so the textfile collector of the node exporter
+// never sees a partial file.
+func writeMetricsFile(name string, m *bmecat12.Metrics) error {
+	f, err := ioutil.TempFile(filepath.Dir(name), "."+filepath.Base(name)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if strings.EqualFold(filepath.Ext(name), ".json") {
+		err = m.WriteJSON(f)
+	} else {
+		err = m.WritePrometheus(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Chmod(0644); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	return os.Rename(f.Name(), name)
+}
@@ -1,4 +1,4 @@
-package main
+package cli
 
 import (
 	"context"
@@ -17,6 +17,7 @@ import (
 type perfCommand struct {
 	header           *bmecat12.Header
 	progress         bool
+	fast             bool
 	numArticles      uint32
 	numCatalogGroups uint32
 	numClassifGroups uint32
@@ -27,6 +28,7 @@ func init() {
 	RegisterCommand("perf", func(flags *flag.FlagSet) Command {
 		cmd := new(perfCommand)
 		flags.BoolVar(&cmd.progress, "P", false, "Print progress")
+		flags.BoolVar(&cmd.fast, "fast", false, "Decode articles with the fast decoder")
 		return cmd
 	})
 }
@@ -35,13 +37,11 @@ func (cmd *perfCommand) Describe() string {
 	return "Performance tester"
 }
 
-func (cmd *perfCommand) Usage() {
-	fmt.Fprintf(os.Stderr, "Usage: %s perf [-P] <file>\n", os.Args[0])
+func (cmd *perfCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s perf [-P] [-fast] <file>\n", Name)
 }
 
-func (cmd *perfCommand) Run(args []string) error {
-	ctx := context.Background()
-
+func (cmd *perfCommand) Run(ctx context.Context, env *Env, args []string) error {
 	if len(args) == 0 {
 		return errors.New("missing file name")
 	}
@@ -55,10 +55,13 @@ func (cmd *perfCommand) Run(args []string) error {
 	var o []bmecat12.ReaderOption
 	if cmd.progress {
 		f := func(pass int, offset int64) {
-			fmt.Printf("Pass %d, Offset %6d kB\r", pass, offset/1024)
+			fmt.Fprintf(env.Stdout, "Pass %d, Offset %6d kB\r", pass, offset/1024)
 		}
 		o = append(o, bmecat12.WithReaderProgress(f))
 	}
+	if cmd.fast {
+		o = append(o, bmecat12.WithFastDecoder())
+	}
 	start := time.Now()
 	err = bmecat12.NewReader(f, o...).Do(ctx, cmd)
 	if err != nil {
@@ -66,17 +69,17 @@ func (cmd *perfCommand) Run(args []string) error {
 	}
 	took := time.Since(start)
 	if cmd.progress {
-		fmt.Println()
+		fmt.Fprintln(env.Stdout)
 	}
 	if cmd.header == nil {
 		return errors.New("did not receive HEADER")
 	}
 
-	fmt.Printf("%-24s: %7d / %7d\n", "Products", cmd.header.NumberOfArticles, cmd.numArticles)
-	fmt.Printf("%-24s: %7d / %7d\n", "Catalog Groups", cmd.header.NumberOfCatalogGroups, cmd.numCatalogGroups)
-	fmt.Printf("%-24s: %7d / %7d\n", "Classification Groups", cmd.header.NumberOfClassificationGroups, cmd.numClassifGroups)
-	fmt.Printf("%-24s: %v\n", "Took", took.String())
-	fmt.Printf("%-24s: %7.2f\n", "Products/sec", float64(cmd.numArticles)/took.Seconds())
+	fmt.Fprintf(env.Stdout, "%-24s: %7d / %7d\n", "Products", cmd.header.NumberOfArticles, cmd.numArticles)
+	fmt.Fprintf(env.Stdout, "%-24s: %7d / %7d\n", "Catalog Groups", cmd.header.NumberOfCatalogGroups, cmd.numCatalogGroups)
+	fmt.Fprintf(env.Stdout, "%-24s: %7d / %7d\n", "Classification Groups", cmd.header.NumberOfClassificationGroups, cmd.numClassifGroups)
+	fmt.Fprintf(env.Stdout, "%-24s: %v\n", "Took", took.String())
+	fmt.Fprintf(env.Stdout, "%-24s: %7.2f\n", "Products/sec", float64(cmd.numArticles)/took.Seconds())
 
 	return nil
 }
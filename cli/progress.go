@@ -0,0 +1,24 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+	"time"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// printProgress returns a progress callback that prints to w.
+func printProgress(w io.Writer) bmecat12.ReaderProgressInfoFunc {
+	return func(p bmecat12.ReaderProgressInfo) {
+		if p.Size == 0 {
+			fmt.Fprintf(w, "Pass %d, Offset %6d kB, %7d articles\r", p.Pass, p.Offset/1024, p.Articles)
+			return
+		}
+		eta := "--"
+		if p.ETA > 0 {
+			eta = p.ETA.Round(time.Second).String()
+		}
+		fmt.Fprintf(w, "Pass %d, %5.1f%% of %d kB, %7d articles, ETA %-10s\r", p.Pass, p.Percent, p.Size/1024, p.Articles, eta)
+	}
+}
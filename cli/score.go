@@ -0,0 +1,65 @@
+package cli
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// scoreCommand rates the quality of a BMEcat file.
+type scoreCommand struct {
+	json bool
+}
+
+func init() {
+	RegisterCommand("score", func(flags *flag.FlagSet) Command {
+		cmd := new(scoreCommand)
+		flags.BoolVar(&cmd.json, "json", false, "Print the score as JSON")
+		return cmd
+	})
+}
+
+func (cmd *scoreCommand) Describe() string {
+	return "Rate the quality of a BMEcat file"
+}
+
+func (cmd *scoreCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s score [-json] <file>\n", Name)
+}
+
+func (cmd *scoreCommand) Examples() []string {
+	return []string{
+		"catalog.xml",
+		"-json catalog.xml",
+	}
+}
+
+func (cmd *scoreCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing file name")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	scorer := bmecat12.NewQualityScorer(nil)
+	if err := bmecat12.NewReader(f).Do(ctx, scorer); err != nil {
+		return err
+	}
+	score := scorer.Score()
+	if cmd.json {
+		enc := json.NewEncoder(env.Stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(score)
+	}
+	return score.WriteText(env.Stdout)
+}
@@ -0,0 +1,87 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net"
+	"net/http"
+	"time"
+
+	"github.com/olivere/bmecat/server"
+)
+
+// serveCommand runs the catalog service, see package server.
+type serveCommand struct {
+	addr      string
+	cacheDir  string
+	maxUpload int64
+	readOnly  bool
+}
+
+func init() {
+	RegisterCommand("serve", func(flags *flag.FlagSet) Command {
+		cmd := new(serveCommand)
+		flags.StringVar(&cmd.addr, "addr", ":8080", "Address to listen on")
+		flags.StringVar(&cmd.cacheDir, "cache", "bmecat-cache", "Directory to keep the catalogs in")
+		flags.Int64Var(&cmd.maxUpload, "max-upload", 0, "Maximum size of uploaded catalogs in bytes (0 = no limit)")
+		flags.BoolVar(&cmd.readOnly, "read-only", false, "Disable uploading and removing catalogs, which are not authenticated")
+		return cmd
+	})
+}
+
+func (cmd *serveCommand) Describe() string {
+	return "Serve catalogs via a REST API"
+}
+
+func (cmd *serveCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s serve [-addr :8080] [-cache dir] [-max-upload bytes] [-read-only]\n", Name)
+	fmt.Fprintln(env.Stderr, "PUT and DELETE requests are not authenticated; use -read-only or an authenticating proxy.")
+}
+
+func (cmd *serveCommand) Examples() []string {
+	return []string{"-addr :8080 -cache /var/lib/bmecat"}
+}
+
+// options returns the options of the server.
+func (cmd *serveCommand) options() []server.Option {
+	options := []server.Option{server.WithMaxUpload(cmd.maxUpload)}
+	if cmd.readOnly {
+		options = append(options, server.WithReadOnly())
+	}
+	return options
+}
+
+func (cmd *serveCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) > 0 {
+		return UsageError("too many arguments")
+	}
+	cache, err := server.OpenCache(cmd.cacheDir)
+	if err != nil {
+		return err
+	}
+	defer cache.Close()
+
+	ln, err := net.Listen("tcp", cmd.addr)
+	if err != nil {
+		return err
+	}
+	srv := &http.Server{
+		Handler:           server.New(cache, cmd.options()...),
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	errc := make(chan error, 1)
+	go func() {
+		errc <- srv.Serve(ln)
+	}()
+	fmt.Fprintf(env.Stderr, "Serving %d catalogs from %s on %s\n", len(cache.Names()), cmd.cacheDir, ln.Addr())
+
+	select {
+	case err := <-errc:
+		return err
+	case <-ctx.Done():
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		return srv.Shutdown(shutdownCtx)
+	}
+}
@@ -0,0 +1,163 @@
+package cli
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// udxCommand lists the UDX fields used in a catalog, or extracts the
+// values of a UDX field across all articles.
+type udxCommand struct {
+	header bool
+}
+
+func init() {
+	RegisterCommand("udx", func(flags *flag.FlagSet) Command {
+		cmd := new(udxCommand)
+		flags.BoolVar(&cmd.header, "header", false, "Include the UDX fields of the HEADER, with an empty SUPPLIER_AID")
+		return cmd
+	})
+}
+
+func (cmd *udxCommand) Describe() string {
+	return "List or extract UDX fields"
+}
+
+func (cmd *udxCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s udx [-header] list <file>\n", Name)
+	fmt.Fprintf(env.Stderr, "       %s udx [-header] extract <field> <file>\n", Name)
+}
+
+func (cmd *udxCommand) Examples() []string {
+	return []string{
+		"list catalog.xml",
+		"extract SYSTEM.CUSTOM_FIELD1 catalog.xml > custom_field1.csv",
+	}
+}
+
+func (cmd *udxCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return ErrUsage
+	}
+	switch args[0] {
+	case "list":
+		if len(args) != 2 {
+			return ErrUsage
+		}
+		return cmd.list(ctx, env, args[1])
+	case "extract":
+		if len(args) != 3 {
+			return ErrUsage
+		}
+		return cmd.extract(ctx, env, args[1], args[2])
+	}
+	return ErrUsage
+}
+
+// list prints the names of the UDX fields with the number of occurrences,
+// most frequent first.
+func (cmd *udxCommand) list(ctx context.Context, env *Env, filename string) error {
+	counts := make(map[string]int)
+	err := cmd.read(ctx, filename, func(supplierAID string, field *bmecat12.UserDefinedExtensionField) error {
+		counts[field.Name]++
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	names := make([]string, 0, len(counts))
+	for name := range counts {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		if counts[names[i]] != counts[names[j]] {
+			return counts[names[i]] > counts[names[j]]
+		}
+		return names[i] < names[j]
+	})
+	for _, name := range names {
+		fmt.Fprintf(env.Stdout, "%7d  UDX.%s\n", counts[name], name)
+	}
+	return nil
+}
+
+// extract writes the values of the named UDX field as CSV with the
+// columns SUPPLIER_AID and the field name. Articles with several fields
+// of that name get several rows.
+func (cmd *udxCommand) extract(ctx context.Context, env *Env, name, filename string) error {
+	name = strings.TrimPrefix(name, "UDX.")
+	w := csv.NewWriter(env.Stdout)
+	if err := w.Write([]string{"SUPPLIER_AID", "UDX." + name}); err != nil {
+		return err
+	}
+	err := cmd.read(ctx, filename, func(supplierAID string, field *bmecat12.UserDefinedExtensionField) error {
+		if field.Name != name {
+			return nil
+		}
+		value := field.Value
+		if value == "" {
+			// Fields with nested elements
+			value = strings.TrimSpace(field.InnerXML)
+		}
+		return w.Write([]string{supplierAID, value})
+	})
+	if err != nil {
+		return err
+	}
+	w.Flush()
+	return w.Error()
+}
+
+// read calls f for every UDX field of the articles and, with -header, of
+// the HEADER.
+func (cmd *udxCommand) read(ctx context.Context, filename string, f func(supplierAID string, field *bmecat12.UserDefinedExtensionField) error) error {
+	file, err := os.Open(filename)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(a *bmecat12.Article) error {
+			if a.UDX == nil {
+				return nil
+			}
+			for _, field := range a.UDX.Fields {
+				if err := f(a.SupplierAID, field); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	if cmd.header {
+		h.OnHeader = func(header *bmecat12.Header) error {
+			if header.UDX == nil {
+				return nil
+			}
+			for _, field := range header.UDX.Fields {
+				if err := f("", field); err != nil {
+					return err
+				}
+			}
+			return nil
+		}
+	}
+	r := bmecat12.NewReader(file,
+		bmecat12.WithSkipSections(bmecat12.SkipFeatures|bmecat12.SkipMime|bmecat12.SkipReferences),
+	)
+	if err := r.Do(ctx, h); err != nil {
+		return errors.Wrapf(err, "unable to read %s", filename)
+	}
+	return nil
+}
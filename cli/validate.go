@@ -0,0 +1,106 @@
+package cli
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// validateCommand checks whether a BMEcat file can be read, and optionally
+// validates it against the official DTD. The official DTDs are not
+// embedded, so they are read from a directory or file.
+type validateCommand struct {
+	dtd     bool
+	dtdDir  string
+	dtdFile string
+}
+
+func init() {
+	RegisterCommand("validate", func(flags *flag.FlagSet) Command {
+		cmd := new(validateCommand)
+		flags.BoolVar(&cmd.dtd, "dtd", false, "Validate element order and occurrence against the BMEcat 1.2 DTD of the transaction in -dtd-dir (offline)")
+		flags.StringVar(&cmd.dtdDir, "dtd-dir", os.Getenv("BMECAT_DTD_DIR"), "Directory with the official BMEcat 1.2 DTDs, e.g. bmecat_new_catalog.dtd (default $BMECAT_DTD_DIR)")
+		flags.StringVar(&cmd.dtdFile, "dtd-file", "", "Validate against the given DTD file, regardless of the transaction")
+		return cmd
+	})
+}
+
+func (cmd *validateCommand) Describe() string {
+	return "Validate a BMEcat file"
+}
+
+func (cmd *validateCommand) Usage(env *Env) {
+	fmt.Fprintf(env.Stderr, "Usage: %s validate [-dtd [-dtd-dir <dir>]] [-dtd-file <dtd>] <file>\n", Name)
+}
+
+func (cmd *validateCommand) Examples() []string {
+	return []string{
+		"-dtd -dtd-dir /usr/share/bmecat catalog.xml",
+		"-dtd-file bmecat_new_catalog.dtd catalog.xml",
+	}
+}
+
+func (cmd *validateCommand) Run(ctx context.Context, env *Env, args []string) error {
+	if len(args) == 0 {
+		return errors.New("missing file name")
+	}
+	if cmd.dtd && cmd.dtdFile == "" && cmd.dtdDir == "" {
+		return UsageError("-dtd requires -dtd-dir or $BMECAT_DTD_DIR, as the official BMEcat DTDs are not embedded")
+	}
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	var numArticles int
+	h := bmecat12.HandlerFuncs{
+		OnArticle: func(*bmecat12.Article) error {
+			numArticles++
+			return nil
+		},
+	}
+	if err := bmecat12.NewReader(f).Do(ctx, h); err != nil {
+		return err
+	}
+
+	if cmd.dtd || cmd.dtdFile != "" {
+		err := cmd.validateDTD(f)
+		if errs, ok := err.(bmecat12.ValidationErrors); ok {
+			for _, e := range errs {
+				fmt.Fprintln(env.Stderr, e)
+			}
+			return errors.Errorf("%d DTD violations", len(errs))
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	fmt.Fprintf(env.Stdout, "OK (%d articles)\n", numArticles)
+	return nil
+}
+
+// validateDTD validates f against the DTD given with -dtd-file, or else
+// against the DTD of its transaction in -dtd-dir.
+func (cmd *validateCommand) validateDTD(f *os.File) error {
+	if cmd.dtdFile == "" {
+		return bmecat12.ValidateDTDDir(f, cmd.dtdDir)
+	}
+	data, err := ioutil.ReadFile(cmd.dtdFile)
+	if err != nil {
+		return err
+	}
+	d, err := bmecat12.ParseDTD(string(data))
+	if err != nil {
+		return err
+	}
+	return d.ValidateFile(f)
+}
@@ -0,0 +1,28 @@
+// Command bmecatd serves BMEcat catalogs via a REST API. It is the same
+// as "bmecat serve".
+package main
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"syscall"
+
+	"github.com/olivere/bmecat/cli"
+)
+
+func main() {
+	cli.Name = filepath.Base(os.Args[0])
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sigc := make(chan os.Signal, 1)
+	signal.Notify(sigc, os.Interrupt, syscall.SIGTERM)
+	go func() {
+		<-sigc
+		cancel()
+	}()
+	args := append([]string{"serve"}, os.Args[1:]...)
+	err := cli.Run(ctx, args, os.Stdout, os.Stderr)
+	os.Exit(cli.ExitCode(err))
+}
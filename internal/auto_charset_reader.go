@@ -4,11 +4,60 @@ import (
 	"fmt"
 	"io"
 	"strings"
+	"sync"
 
+	"golang.org/x/text/encoding"
 	"golang.org/x/text/encoding/charmap"
 	"golang.org/x/text/transform"
 )
 
+var (
+	charsetsMu sync.RWMutex
+	// charsets maps the lower-case names of encodings to their decoders.
+	charsets = make(map[string]encoding.Encoding)
+)
+
+func init() {
+	for enc, names := range map[encoding.Encoding][]string{
+		charmap.CodePage437: {"ibm code page 437", "cp437", "cp-437"},
+		charmap.CodePage866: {"ibm code page 866", "cp866", "cp-866"},
+		// ISO-8859-1 is decoded as its superset windows-1252, as files
+		// declared as ISO-8859-1 often contain e.g. the euro sign
+		charmap.Windows1252: {"iso88591", "iso 8859-1", "iso8859-1", "iso-8859-1", "latin1", "windows1252", "windows-1252", "cp1252"},
+		charmap.ISO8859_2:   {"iso88592", "iso 8859-2", "iso8859-2", "iso-8859-2", "latin2"},
+		charmap.ISO8859_3:   {"iso88593", "iso 8859-3", "iso8859-3", "iso-8859-3"},
+		charmap.ISO8859_4:   {"iso88594", "iso 8859-4", "iso8859-4", "iso-8859-4"},
+		charmap.ISO8859_5:   {"iso88595", "iso 8859-5", "iso8859-5", "iso-8859-5"},
+		charmap.ISO8859_6:   {"iso88596", "iso 8859-6", "iso8859-6", "iso-8859-6"},
+		charmap.ISO8859_7:   {"iso88597", "iso 8859-7", "iso8859-7", "iso-8859-7"},
+		charmap.ISO8859_8:   {"iso88598", "iso 8859-8", "iso8859-8", "iso-8859-8"},
+		charmap.ISO8859_10:  {"iso885910", "iso 8859-10", "iso8859-10", "iso-8859-10"},
+		charmap.ISO8859_13:  {"iso885913", "iso 8859-13", "iso8859-13", "iso-8859-13"},
+		charmap.ISO8859_14:  {"iso885914", "iso 8859-14", "iso8859-14", "iso-8859-14"},
+		charmap.ISO8859_15:  {"iso885915", "iso 8859-15", "iso8859-15", "iso-8859-15"},
+		charmap.ISO8859_16:  {"iso885916", "iso 8859-16", "iso8859-16", "iso-8859-16"},
+		charmap.Windows1250: {"windows1250", "windows-1250", "cp1250"},
+		charmap.Windows1251: {"windows1251", "windows-1251", "cp1251"},
+		charmap.Windows1254: {"windows1254", "windows-1254", "cp1254"},
+		charmap.Windows1257: {"windows1257", "windows-1257", "cp1257"},
+		charmap.KOI8R:       {"koi8-r", "koi8r", "koi8_r"},
+		charmap.Macintosh:   {"macintosh", "mac", "macroman", "x-mac-roman"},
+	} {
+		for _, name := range names {
+			charsets[name] = enc
+		}
+	}
+}
+
+// RegisterCharset makes AutoCharsetReader decode the encoding with the
+// given name, which is case-insensitive, with enc. It replaces the
+// decoding of an encoding that is already registered.
+func RegisterCharset(name string, enc encoding.Encoding) {
+	charsetsMu.Lock()
+	charsets[strings.ToLower(name)] = enc
+	charsetsMu.Unlock()
+}
+
 // AutoCharsetReader returns a charset reader for XML decoding.
 func AutoCharsetReader(encoding string, r io.Reader) (io.Reader, error) {
 	enc := strings.ToLower(encoding)
@@ -16,40 +65,17 @@ func AutoCharsetReader(encoding string, r io.Reader) (io.Reader, error) {
 	if enc == "" || enc == "utf-8" || enc == "utf8" {
 		return r, nil
 	}
+	if enc == "utf-16" || enc == "utf16" || enc == "utf-16le" || enc == "utf-16be" {
+		// The input has been converted to UTF-8 by DecodeBOM already, as
+		// the XML declaration could not be read otherwise
+		return r, nil
+	}
 
-	switch enc {
-	case "ibm code page 437", "cp437", "cp-437":
-		return transform.NewReader(r, charmap.CodePage437.NewDecoder()), nil
-	case "ibm code page 866", "cp866", "cp-866":
-		return transform.NewReader(r, charmap.CodePage866.NewDecoder()), nil
-	case "iso88591", "iso 8859-1", "iso8859-1", "iso-8859-1":
-		return transform.NewReader(r, charmap.Windows1252.NewDecoder()), nil
-	case "iso88592", "iso 8859-2", "iso8859-2", "iso-8859-2":
-		return transform.NewReader(r, charmap.ISO8859_2.NewDecoder()), nil
-	case "iso88593", "iso 8859-3", "iso8859-3", "iso-8859-3":
-		return transform.NewReader(r, charmap.ISO8859_3.NewDecoder()), nil
-	case "iso88594", "iso 8859-4", "iso8859-4", "iso-8859-4":
-		return transform.NewReader(r, charmap.ISO8859_4.NewDecoder()), nil
-	case "iso88595", "iso 8859-5", "iso8859-5", "iso-8859-5":
-		return transform.NewReader(r, charmap.ISO8859_5.NewDecoder()), nil
-	case "iso88596", "iso 8859-6", "iso8859-6", "iso-8859-6":
-		return transform.NewReader(r, charmap.ISO8859_6.NewDecoder()), nil
-	case "iso88597", "iso 8859-7", "iso8859-7", "iso-8859-7":
-		return transform.NewReader(r, charmap.ISO8859_7.NewDecoder()), nil
-	case "iso88598", "iso 8859-8", "iso8859-8", "iso-8859-8":
-		return transform.NewReader(r, charmap.ISO8859_8.NewDecoder()), nil
-	case "iso885910", "iso 8859-10", "iso8859-10", "iso-8859-10":
-		return transform.NewReader(r, charmap.ISO8859_10.NewDecoder()), nil
-	case "iso885913", "iso 8859-13", "iso8859-13", "iso-8859-13":
-		return transform.NewReader(r, charmap.ISO8859_13.NewDecoder()), nil
-	case "iso885914", "iso 8859-14", "iso8859-14", "iso-8859-14":
-		return transform.NewReader(r, charmap.ISO8859_14.NewDecoder()), nil
-	case "iso885915", "iso 8859-15", "iso8859-15", "iso-8859-15":
-		return transform.NewReader(r, charmap.ISO8859_15.NewDecoder()), nil
-	case "iso885916", "iso 8859-16", "iso8859-16", "iso-8859-16":
-		return transform.NewReader(r, charmap.ISO8859_16.NewDecoder()), nil
-	case "windows1252", "windows-1252":
-		return transform.NewReader(r, charmap.Windows1252.NewDecoder()), nil
+	charsetsMu.RLock()
+	e, ok := charsets[enc]
+	charsetsMu.RUnlock()
+	if ok {
+		return transform.NewReader(r, e.NewDecoder()), nil
 	}
 
 	return nil, fmt.Errorf("bmecat: unknown encoding: %s", encoding)
@@ -0,0 +1,51 @@
+package internal
+
+import (
+	"bufio"
+	"bytes"
+	"io"
+
+	"golang.org/x/text/encoding/unicode"
+	"golang.org/x/text/transform"
+)
+
+var (
+	bomUTF8    = []byte{0xef, 0xbb, 0xbf}
+	bomUTF16LE = []byte{0xff, 0xfe}
+	bomUTF16BE = []byte{0xfe, 0xff}
+	// Start of an XML declaration in UTF-16 without a byte order mark
+	declUTF16LE = []byte{'<', 0, '?', 0}
+	declUTF16BE = []byte{0, '<', 0, '?'}
+)
+
+// NeedsBOMDecoding returns true if an XML document starting with prefix
+// must be passed through DecodeBOM, i.e. if it starts with a byte order
+// mark or is encoded in UTF-16. prefix should be at least 4 bytes long.
+func NeedsBOMDecoding(prefix []byte) bool {
+	return bytes.HasPrefix(prefix, bomUTF8) ||
+		bytes.HasPrefix(prefix, bomUTF16LE) ||
+		bytes.HasPrefix(prefix, bomUTF16BE) ||
+		bytes.HasPrefix(prefix, declUTF16LE) ||
+		bytes.HasPrefix(prefix, declUTF16BE)
+}
+
+// DecodeBOM returns a reader that strips the byte order mark of UTF-8
+// input and converts UTF-16 input, with or without a byte order mark,
+// to UTF-8. Other input is returned as is. Excel-driven tooling often
+// produces such files, which encoding/xml cannot read otherwise. The
+// XML declaration of UTF-16 input still says so, which is why
+// AutoCharsetReader accepts UTF-16 as is.
+func DecodeBOM(r io.Reader) io.Reader {
+	br := bufio.NewReader(r)
+	prefix, _ := br.Peek(4)
+	switch {
+	case bytes.HasPrefix(prefix, bomUTF8):
+		br.Discard(len(bomUTF8))
+		return br
+	case bytes.HasPrefix(prefix, bomUTF16LE), bytes.HasPrefix(prefix, declUTF16LE):
+		return transform.NewReader(br, unicode.UTF16(unicode.LittleEndian, unicode.UseBOM).NewDecoder())
+	case bytes.HasPrefix(prefix, bomUTF16BE), bytes.HasPrefix(prefix, declUTF16BE):
+		return transform.NewReader(br, unicode.UTF16(unicode.BigEndian, unicode.UseBOM).NewDecoder())
+	}
+	return br
+}
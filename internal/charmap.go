@@ -0,0 +1,28 @@
+package internal
+
+import (
+	"strings"
+
+	"golang.org/x/text/encoding/charmap"
+)
+
+// Charmap returns the single-byte encoding with the given name, which is
+// case-insensitive, for encoding output. Unlike AutoCharsetReader, it
+// returns the strict ISO-8859-1 for "ISO-8859-1", as the bytes of the
+// additional characters of windows-1252 are control characters in
+// ISO-8859-1.
+func Charmap(name string) (*charmap.Charmap, bool) {
+	enc := strings.ToLower(name)
+	switch enc {
+	case "iso88591", "iso 8859-1", "iso8859-1", "iso-8859-1", "latin1":
+		return charmap.ISO8859_1, true
+	}
+	charsetsMu.RLock()
+	e, ok := charsets[enc]
+	charsetsMu.RUnlock()
+	if !ok {
+		return nil, false
+	}
+	cm, ok := e.(*charmap.Charmap)
+	return cm, ok
+}
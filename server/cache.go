@@ -0,0 +1,471 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pkg/errors"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// ErrNotFound is returned by Cache when there is no catalog with the
+// given name.
+var ErrNotFound = errors.New("catalog not found")
+
+// ErrInvalidName is returned by Cache.Put for invalid catalog names.
+var ErrInvalidName = errors.New("invalid catalog name")
+
+// CatalogError is returned by Cache.Put for catalogs that cannot be
+// read, e.g. because they are not well-formed XML or have no HEADER.
+type CatalogError struct {
+	Err error
+}
+
+func (e *CatalogError) Error() string {
+	return e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *CatalogError) Unwrap() error {
+	return e.Err
+}
+
+// catalogError wraps err in a CatalogError, unless err is an I/O error
+// or an error of the context.
+func catalogError(err error) error {
+	var perr *os.PathError
+	if errors.As(err, &perr) || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return err
+	}
+	return &CatalogError{Err: err}
+}
+
+// nameRe are the valid catalog names. They are used as file names in
+// the cache directory.
+var nameRe = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,127}$`)
+
+// CatalogInfo summarizes a catalog, mostly from its HEADER.
+type CatalogInfo struct {
+	Name                 string    `json:"name"`
+	Transaction          string    `json:"transaction"`
+	PreviousVersion      int       `json:"prev_version,omitempty"`
+	CatalogID            string    `json:"catalog_id"`
+	CatalogVersion       string    `json:"catalog_version"`
+	CatalogName          string    `json:"catalog_name,omitempty"`
+	Language             string    `json:"language,omitempty"`
+	Currency             string    `json:"currency,omitempty"`
+	Supplier             string    `json:"supplier,omitempty"`
+	Articles             int       `json:"articles"`
+	CatalogGroups        int       `json:"catalog_groups"`
+	ClassificationGroups int       `json:"classification_groups"`
+	Valid                bool      `json:"valid"`
+	Size                 int64     `json:"size"`
+	Created              time.Time `json:"created"`
+}
+
+// GroupNode is a catalog group in the category tree of a catalog.
+type GroupNode struct {
+	ID          string       `json:"id"`
+	Name        string       `json:"name"`
+	Description string       `json:"description,omitempty"`
+	Type        string       `json:"type,omitempty"`
+	Order       int          `json:"order,omitempty"`
+	Children    []*GroupNode `json:"children,omitempty"`
+}
+
+// ValidationReport is the result of validating a catalog against the
+// embedded structural model of its transaction, see bmecat12.ValidateDTD.
+// It is not a validation against the official BMEcat DTD.
+type ValidationReport struct {
+	Valid  bool                        `json:"valid"`
+	Errors []*bmecat12.ValidationError `json:"errors,omitempty"`
+}
+
+// snapshot is what the Cache keeps of a catalog, next to the catalog file
+// and its index. It is stored as JSON, so the catalog doesn't need to be
+// parsed again when the cache is reopened.
+type snapshot struct {
+	Info       *CatalogInfo      `json:"info"`
+	EANs       map[string]string `json:"eans,omitempty"`
+	Groups     []*GroupNode      `json:"groups,omitempty"`
+	Validation *ValidationReport `json:"validation"`
+}
+
+// Catalog is a catalog in the cache.
+type Catalog struct {
+	snapshot
+	ra *bmecat12.RandomAccessReader
+}
+
+// Info returns the summary of the catalog.
+func (c *Catalog) Info() *CatalogInfo {
+	return c.snapshot.Info
+}
+
+// Groups returns the category tree of the catalog.
+func (c *Catalog) Groups() []*GroupNode {
+	return c.snapshot.Groups
+}
+
+// Validation returns the validation report of the catalog.
+func (c *Catalog) Validation() *ValidationReport {
+	return c.snapshot.Validation
+}
+
+// Article returns the article with the given SUPPLIER_AID. It returns
+// bmecat12.ErrArticleNotFound if there is no such article.
+func (c *Catalog) Article(supplierAID string) (*bmecat12.Article, error) {
+	return c.ra.Article(supplierAID)
+}
+
+// ArticleByEAN returns the article with the given EAN. It returns
+// bmecat12.ErrArticleNotFound if there is no such article.
+func (c *Catalog) ArticleByEAN(ean string) (*bmecat12.Article, error) {
+	aid, found := c.EANs[ean]
+	if !found {
+		return nil, bmecat12.ErrArticleNotFound
+	}
+	return c.ra.Article(aid)
+}
+
+// Cache keeps catalogs in a directory: the catalog file, its index for
+// random access, see bmecat12.OpenRandomAccess, and a snapshot with the
+// header information, the EANs, the category tree, and the validation
+// report. It is safe for concurrent use.
+type Cache struct {
+	dir string
+
+	mu       sync.RWMutex
+	catalogs map[string]*Catalog
+}
+
+// OpenCache opens the cache in dir, creating the directory if necessary,
+// and loads the catalogs found there.
+func OpenCache(dir string) (*Cache, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to create cache directory")
+	}
+	c := &Cache{dir: dir, catalogs: make(map[string]*Catalog)}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range files {
+		name := strings.TrimSuffix(filepath.Base(file), ".json")
+		if !nameRe.MatchString(name) {
+			continue
+		}
+		cat, err := c.load(name)
+		if err != nil {
+			c.Close()
+			return nil, errors.Wrapf(err, "bmecat/server: unable to load catalog %s", name)
+		}
+		c.catalogs[name] = cat
+	}
+	return c, nil
+}
+
+// load opens a catalog of the cache directory.
+func (c *Cache) load(name string) (*Catalog, error) {
+	data, err := ioutil.ReadFile(c.snapshotFile(name))
+	if err != nil {
+		return nil, err
+	}
+	cat := &Catalog{}
+	if err := json.Unmarshal(data, &cat.snapshot); err != nil {
+		return nil, err
+	}
+	if cat.snapshot.Info == nil {
+		return nil, errors.New("snapshot without info")
+	}
+	if cat.ra, err = bmecat12.OpenRandomAccess(c.catalogFile(name)); err != nil {
+		return nil, err
+	}
+	return cat, nil
+}
+
+func (c *Cache) catalogFile(name string) string {
+	return filepath.Join(c.dir, name+".xml")
+}
+
+func (c *Cache) snapshotFile(name string) string {
+	return filepath.Join(c.dir, name+".json")
+}
+
+// Names returns the names of the catalogs, sorted.
+func (c *Cache) Names() []string {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	names := make([]string, 0, len(c.catalogs))
+	for name := range c.catalogs {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// View calls f with the catalog of the given name. The catalog must not
+// be used after f returns, as it may be replaced or deleted. View returns
+// ErrNotFound if there is no such catalog.
+func (c *Cache) View(name string, f func(*Catalog) error) error {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	cat, found := c.catalogs[name]
+	if !found {
+		return ErrNotFound
+	}
+	return f(cat)
+}
+
+// Put reads the catalog from r and adds it to the cache, replacing the
+// catalog with the same name, if any. Names must start with a letter or
+// digit, followed by letters, digits, dots, dashes, and underscores.
+// Catalogs that cannot be read are rejected with a CatalogError;
+// catalogs that violate the DTD are accepted, see Catalog.Validation.
+func (c *Cache) Put(ctx context.Context, name string, r io.Reader) (*CatalogInfo, error) {
+	if !nameRe.MatchString(name) {
+		return nil, ErrInvalidName
+	}
+
+	// Copy the catalog into the cache directory first, as the Reader
+	// requires an io.ReadSeeker
+	tmp, err := ioutil.TempFile(c.dir, name+".*.upload")
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to create file")
+	}
+	var ra *bmecat12.RandomAccessReader
+	defer func() {
+		if tmp != nil {
+			if ra != nil {
+				ra.Close()
+			}
+			tmp.Close()
+			os.Remove(tmp.Name())
+			os.Remove(bmecat12.IndexFilename(tmp.Name()))
+		}
+	}()
+	size, err := io.Copy(tmp, r)
+	if err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to write file")
+	}
+
+	snap, err := newSnapshot(ctx, name, tmp)
+	if err != nil {
+		return nil, err
+	}
+	snap.Info.Size = size
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return nil, err
+	}
+	if err := tmp.Close(); err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to write file")
+	}
+
+	// Open the new catalog before touching the old one, so the old one
+	// stays in place if the new one cannot be indexed. The index keeps
+	// matching the catalog after the rename, as it preserves the
+	// modification time.
+	if ra, err = bmecat12.OpenRandomAccess(tmp.Name()); err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := os.Rename(bmecat12.IndexFilename(tmp.Name()), bmecat12.IndexFilename(c.catalogFile(name))); err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to write index")
+	}
+	if err := os.Rename(tmp.Name(), c.catalogFile(name)); err != nil {
+		return nil, errors.Wrap(err, "bmecat/server: unable to write file")
+	}
+	tmp = nil
+	if err := ioutil.WriteFile(c.snapshotFile(name), data, 0644); err != nil {
+		ra.Close()
+		return nil, errors.Wrap(err, "bmecat/server: unable to write snapshot")
+	}
+	cat := &Catalog{snapshot: *snap, ra: ra}
+	old, found := c.catalogs[name]
+	c.catalogs[name] = cat
+	if found {
+		old.ra.Close()
+	}
+	return cat.Info(), nil
+}
+
+// Delete removes the catalog with the given name and its files. It
+// returns ErrNotFound if there is no such catalog.
+func (c *Cache) Delete(name string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cat, found := c.catalogs[name]
+	if !found {
+		return ErrNotFound
+	}
+	delete(c.catalogs, name)
+	err := cat.ra.Close()
+	for _, file := range []string{c.snapshotFile(name), c.catalogFile(name), bmecat12.IndexFilename(c.catalogFile(name))} {
+		if rerr := os.Remove(file); err == nil && !os.IsNotExist(rerr) {
+			err = rerr
+		}
+	}
+	return err
+}
+
+// Close closes the catalogs. The files are kept.
+func (c *Cache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var err error
+	for name, cat := range c.catalogs {
+		if cerr := cat.ra.Close(); err == nil {
+			err = cerr
+		}
+		delete(c.catalogs, name)
+	}
+	return err
+}
+
+// newSnapshot reads the catalog from f and returns its snapshot.
+func newSnapshot(ctx context.Context, name string, f *os.File) (*snapshot, error) {
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	h := &snapshotHandler{eans: make(map[string]string)}
+	if err := bmecat12.NewReader(f).Do(ctx, h); err != nil {
+		return nil, catalogError(err)
+	}
+	if h.header == nil {
+		return nil, &CatalogError{Err: errors.New("bmecat/server: catalog has no HEADER")}
+	}
+	ids := make(map[string]bool, len(h.groups))
+	for _, g := range h.groups {
+		if ids[g.ID] {
+			return nil, &CatalogError{Err: errors.Errorf("bmecat/server: duplicate GROUP_ID %q", g.ID)}
+		}
+		ids[g.ID] = true
+	}
+
+	info := &CatalogInfo{
+		Name:                 name,
+		Transaction:          h.header.Transaction.String(),
+		PreviousVersion:      h.header.PreviousVersion,
+		Articles:             h.header.NumberOfArticles,
+		CatalogGroups:        h.header.NumberOfCatalogGroups,
+		ClassificationGroups: h.header.NumberOfClassificationGroups,
+		Created:              time.Now().UTC(),
+	}
+	if cat := h.header.Catalog; cat != nil {
+		info.CatalogID = cat.ID
+		info.CatalogVersion = cat.Version
+		info.CatalogName = cat.Name
+		info.Language = cat.Language
+		info.Currency = cat.Currency
+	}
+	if s := h.header.Supplier; s != nil {
+		info.Supplier = s.Name
+	}
+
+	if _, err := f.Seek(0, io.SeekStart); err != nil {
+		return nil, err
+	}
+	report := &ValidationReport{Valid: true}
+	if err := bmecat12.ValidateDTD(f); err != nil {
+		verrs, ok := err.(bmecat12.ValidationErrors)
+		if !ok {
+			return nil, catalogError(err)
+		}
+		report.Valid, report.Errors = false, verrs
+	}
+	info.Valid = report.Valid
+
+	return &snapshot{
+		Info:       info,
+		EANs:       h.eans,
+		Groups:     groupTree(h.groups),
+		Validation: report,
+	}, nil
+}
+
+// snapshotHandler collects what goes into a snapshot.
+type snapshotHandler struct {
+	header *bmecat12.Header
+	groups []*bmecat12.CatalogGroup
+	eans   map[string]string
+}
+
+func (h *snapshotHandler) HandleHeader(header *bmecat12.Header) error {
+	h.header = header
+	return nil
+}
+
+func (h *snapshotHandler) HandleCatalogGroup(g *bmecat12.CatalogGroup) error {
+	h.groups = append(h.groups, g)
+	return nil
+}
+
+func (h *snapshotHandler) HandleArticle(a *bmecat12.Article) error {
+	if a.Details != nil && a.Details.EAN != "" {
+		if _, dup := h.eans[a.Details.EAN]; !dup {
+			h.eans[a.Details.EAN] = a.SupplierAID
+		}
+	}
+	return nil
+}
+
+// groupTree returns the catalog groups as a tree. Groups without a
+// parent, or with an unknown parent, are roots. Siblings are sorted by
+// GROUP_ORDER, then in document order.
+func groupTree(groups []*bmecat12.CatalogGroup) []*GroupNode {
+	nodes := make(map[string]*GroupNode, len(groups))
+	for _, g := range groups {
+		nodes[g.ID] = &GroupNode{
+			ID:          g.ID,
+			Name:        g.Name,
+			Description: g.Description,
+			Type:        g.Type,
+			Order:       g.Order,
+		}
+	}
+	var roots []*GroupNode
+	for _, g := range groups {
+		node := nodes[g.ID]
+		if g.ParentID != nil && *g.ParentID != g.ID {
+			if parent, found := nodes[*g.ParentID]; found {
+				parent.Children = append(parent.Children, node)
+				continue
+			}
+		}
+		roots = append(roots, node)
+	}
+	// Each node is visited once, so a cycle cannot make the walk, or the
+	// JSON encoding of the tree, recurse without end
+	visited := make(map[*GroupNode]bool, len(nodes))
+	var sortNodes func([]*GroupNode) []*GroupNode
+	sortNodes = func(nodes []*GroupNode) []*GroupNode {
+		kept := nodes[:0]
+		for _, n := range nodes {
+			if !visited[n] {
+				visited[n] = true
+				kept = append(kept, n)
+			}
+		}
+		sort.SliceStable(kept, func(i, j int) bool {
+			return kept[i].Order < kept[j].Order
+		})
+		for _, n := range kept {
+			n.Children = sortNodes(n.Children)
+		}
+		return kept
+	}
+	return sortNodes(roots)
+}
@@ -0,0 +1,276 @@
+// Package server implements a small catalog service: it keeps BMEcat
+// catalogs in a Cache and serves their header information, articles,
+// category trees, and validation reports via a REST API.
+//
+//	GET    /catalogs                          list the catalogs
+//	PUT    /catalogs/{name}                   upload a catalog
+//	GET    /catalogs/{name}                   header information
+//	DELETE /catalogs/{name}                   remove a catalog
+//	GET    /catalogs/{name}/articles/{aid}    article by SUPPLIER_AID
+//	GET    /catalogs/{name}/articles?ean=...  article by EAN
+//	GET    /catalogs/{name}/groups            category tree
+//	GET    /catalogs/{name}/validation        validation report
+//
+// All responses are JSON. Errors are returned as {"error": "..."}.
+//
+// The server does not authenticate requests, so anyone who can reach it
+// can upload and remove catalogs. Use WithReadOnly to disable PUT and
+// DELETE, or run the server behind a proxy that authenticates them.
+package server
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/olivere/bmecat/bmecat12"
+)
+
+// Server serves the catalogs of a Cache. It implements http.Handler.
+type Server struct {
+	cache     *Cache
+	maxUpload int64
+	readOnly  bool
+}
+
+// Option is the signature of options to pass into New.
+type Option func(*Server)
+
+// WithMaxUpload limits the size of uploaded catalogs. The default of 0
+// means no limit.
+func WithMaxUpload(n int64) Option {
+	return func(s *Server) {
+		s.maxUpload = n
+	}
+}
+
+// WithReadOnly disables the endpoints that upload and remove catalogs.
+// They respond with 405 Method Not Allowed.
+func WithReadOnly() Option {
+	return func(s *Server) {
+		s.readOnly = true
+	}
+}
+
+// New creates a new Server for the catalogs of cache.
+func New(cache *Cache, options ...Option) *Server {
+	s := &Server{cache: cache}
+	for _, o := range options {
+		o(s)
+	}
+	return s
+}
+
+// ServeHTTP routes the request to the endpoints of the REST API.
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	path, err := splitPath(r.URL.EscapedPath())
+	if err != nil || len(path) == 0 || path[0] != "catalogs" {
+		writeError(w, http.StatusNotFound, "not found")
+		return
+	}
+	switch {
+	case len(path) == 1:
+		s.route(w, r, map[string]http.HandlerFunc{"GET": s.list})
+	case len(path) == 2:
+		name := path[1]
+		handlers := map[string]http.HandlerFunc{
+			"GET": func(w http.ResponseWriter, r *http.Request) { s.info(w, r, name) },
+		}
+		if !s.readOnly {
+			handlers["PUT"] = func(w http.ResponseWriter, r *http.Request) { s.put(w, r, name) }
+			handlers["DELETE"] = func(w http.ResponseWriter, r *http.Request) { s.delete(w, r, name) }
+		}
+		s.route(w, r, handlers)
+	case len(path) == 3 && path[2] == "articles":
+		name := path[1]
+		s.route(w, r, map[string]http.HandlerFunc{
+			"GET": func(w http.ResponseWriter, r *http.Request) { s.articleByEAN(w, r, name) },
+		})
+	case len(path) == 4 && path[2] == "articles":
+		name, aid := path[1], path[3]
+		s.route(w, r, map[string]http.HandlerFunc{
+			"GET": func(w http.ResponseWriter, r *http.Request) { s.article(w, r, name, aid) },
+		})
+	case len(path) == 3 && path[2] == "groups":
+		name := path[1]
+		s.route(w, r, map[string]http.HandlerFunc{
+			"GET": func(w http.ResponseWriter, r *http.Request) { s.groups(w, r, name) },
+		})
+	case len(path) == 3 && path[2] == "validation":
+		name := path[1]
+		s.route(w, r, map[string]http.HandlerFunc{
+			"GET": func(w http.ResponseWriter, r *http.Request) { s.validation(w, r, name) },
+		})
+	default:
+		writeError(w, http.StatusNotFound, "not found")
+	}
+}
+
+// route calls the handler for the method of the request.
+func (s *Server) route(w http.ResponseWriter, r *http.Request, handlers map[string]http.HandlerFunc) {
+	h, found := handlers[r.Method]
+	if !found {
+		var allow []string
+		for method := range handlers {
+			allow = append(allow, method)
+		}
+		sort.Strings(allow)
+		w.Header().Set("Allow", strings.Join(allow, ", "))
+		writeError(w, http.StatusMethodNotAllowed, "method not allowed")
+		return
+	}
+	h(w, r)
+}
+
+func (s *Server) list(w http.ResponseWriter, r *http.Request) {
+	infos := make([]*CatalogInfo, 0)
+	for _, name := range s.cache.Names() {
+		s.cache.View(name, func(cat *Catalog) error {
+			infos = append(infos, cat.Info())
+			return nil
+		})
+	}
+	writeJSON(w, http.StatusOK, infos)
+}
+
+func (s *Server) info(w http.ResponseWriter, r *http.Request, name string) {
+	s.view(w, name, func(cat *Catalog) (interface{}, error) {
+		return cat.Info(), nil
+	})
+}
+
+func (s *Server) put(w http.ResponseWriter, r *http.Request, name string) {
+	body := &uploadReader{r: r.Body}
+	if s.maxUpload > 0 {
+		body.r = http.MaxBytesReader(w, r.Body, s.maxUpload)
+	}
+	info, err := s.cache.Put(r.Context(), name, body)
+	var cerr *CatalogError
+	switch {
+	case errors.Is(err, ErrInvalidName):
+		writeError(w, http.StatusBadRequest, err.Error())
+	case err != nil && body.err != nil && s.maxUpload > 0 && body.n >= s.maxUpload:
+		writeError(w, http.StatusRequestEntityTooLarge, body.err.Error())
+	case err != nil && body.err != nil:
+		writeError(w, http.StatusBadRequest, body.err.Error())
+	case errors.As(err, &cerr):
+		writeError(w, http.StatusUnprocessableEntity, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusCreated, info)
+	}
+}
+
+func (s *Server) delete(w http.ResponseWriter, r *http.Request, name string) {
+	switch err := s.cache.Delete(name); {
+	case errors.Is(err, ErrNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		w.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func (s *Server) article(w http.ResponseWriter, r *http.Request, name, aid string) {
+	s.view(w, name, func(cat *Catalog) (interface{}, error) {
+		return cat.Article(aid)
+	})
+}
+
+func (s *Server) articleByEAN(w http.ResponseWriter, r *http.Request, name string) {
+	ean := r.URL.Query().Get("ean")
+	if ean == "" {
+		writeError(w, http.StatusBadRequest, "missing ean parameter")
+		return
+	}
+	s.view(w, name, func(cat *Catalog) (interface{}, error) {
+		return cat.ArticleByEAN(ean)
+	})
+}
+
+func (s *Server) groups(w http.ResponseWriter, r *http.Request, name string) {
+	s.view(w, name, func(cat *Catalog) (interface{}, error) {
+		groups := cat.Groups()
+		if groups == nil {
+			groups = make([]*GroupNode, 0)
+		}
+		return groups, nil
+	})
+}
+
+func (s *Server) validation(w http.ResponseWriter, r *http.Request, name string) {
+	s.view(w, name, func(cat *Catalog) (interface{}, error) {
+		return cat.Validation(), nil
+	})
+}
+
+// view writes the result of f for the catalog of the given name.
+func (s *Server) view(w http.ResponseWriter, name string, f func(*Catalog) (interface{}, error)) {
+	var v interface{}
+	err := s.cache.View(name, func(cat *Catalog) error {
+		var err error
+		v, err = f(cat)
+		return err
+	})
+	switch {
+	case errors.Is(err, ErrNotFound), errors.Is(err, bmecat12.ErrArticleNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case err != nil:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	default:
+		writeJSON(w, http.StatusOK, v)
+	}
+}
+
+// uploadReader reads the body of an upload, keeping the number of bytes
+// read and the first error other than io.EOF. It tells errors of the
+// client apart from errors of the cache.
+type uploadReader struct {
+	r   io.Reader
+	n   int64
+	err error
+}
+
+func (r *uploadReader) Read(p []byte) (int, error) {
+	n, err := r.r.Read(p)
+	r.n += int64(n)
+	if err != nil && err != io.EOF && r.err == nil {
+		r.err = err
+	}
+	return n, err
+}
+
+// splitPath splits the escaped path into its unescaped segments, so that
+// SUPPLIER_AIDs may contain escaped slashes.
+func splitPath(escaped string) ([]string, error) {
+	var path []string
+	for _, seg := range strings.Split(strings.Trim(escaped, "/"), "/") {
+		if seg == "" {
+			continue
+		}
+		s, err := url.PathUnescape(seg)
+		if err != nil {
+			return nil, err
+		}
+		path = append(path, s)
+	}
+	return path, nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json; charset=utf-8")
+	w.WriteHeader(status)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, msg string) {
+	writeJSON(w, status, map[string]string{"error": msg})
+}
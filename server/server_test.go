@@ -0,0 +1,267 @@
+package server_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/olivere/bmecat/server"
+)
+
+const catalogDoc = `<?xml version="1.0" encoding="UTF-8"?>
+<BMECAT version="1.2" xmlns="http://www.bmecat.org/bmecat/1.2/bmecat_new_catalog">
+  <HEADER>
+    <CATALOG>
+      <LANGUAGE>deu</LANGUAGE>
+      <CATALOG_ID>CAT1</CATALOG_ID>
+      <CATALOG_VERSION>1.0</CATALOG_VERSION>
+      <CURRENCY>EUR</CURRENCY>
+    </CATALOG>
+    <SUPPLIER>
+      <SUPPLIER_NAME>Acme</SUPPLIER_NAME>
+    </SUPPLIER>
+  </HEADER>
+  <T_NEW_CATALOG>
+    <CATALOG_GROUP_SYSTEM>
+      <CATALOG_STRUCTURE type="root"><GROUP_ID>1</GROUP_ID><GROUP_NAME>Root</GROUP_NAME><PARENT_ID>0</PARENT_ID></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="leaf"><GROUP_ID>3</GROUP_ID><GROUP_NAME>Drills</GROUP_NAME><PARENT_ID>1</PARENT_ID><GROUP_ORDER>2</GROUP_ORDER></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="leaf"><GROUP_ID>2</GROUP_ID><GROUP_NAME>Saws</GROUP_NAME><PARENT_ID>1</PARENT_ID><GROUP_ORDER>1</GROUP_ORDER></CATALOG_STRUCTURE>
+    </CATALOG_GROUP_SYSTEM>
+    <ARTICLE>
+      <SUPPLIER_AID>1000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Drill</DESCRIPTION_SHORT><EAN>4000000000001</EAN></ARTICLE_DETAILS>
+    </ARTICLE>
+    <ARTICLE>
+      <SUPPLIER_AID>A/2000</SUPPLIER_AID>
+      <ARTICLE_DETAILS><DESCRIPTION_SHORT>Saw</DESCRIPTION_SHORT></ARTICLE_DETAILS>
+    </ARTICLE>
+  </T_NEW_CATALOG>
+</BMECAT>`
+
+func newTestServer(t *testing.T) (*httptest.Server, string) {
+	t.Helper()
+	dir, err := ioutil.TempDir("", "bmecat-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	cache, err := server.OpenCache(dir)
+	if err != nil {
+		os.RemoveAll(dir)
+		t.Fatal(err)
+	}
+	ts := httptest.NewServer(server.New(cache))
+	t.Cleanup(func() {
+		ts.Close()
+		cache.Close()
+		os.RemoveAll(dir)
+	})
+	return ts, dir
+}
+
+func do(t *testing.T, method, url, body string, wantStatus int, v interface{}) {
+	t.Helper()
+	req, err := http.NewRequest(method, url, strings.NewReader(body))
+	if err != nil {
+		t.Fatal(err)
+	}
+	res, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer res.Body.Close()
+	data, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := wantStatus, res.StatusCode; want != have {
+		t.Fatalf("%s %s: want status %d, have %d: %s", method, url, want, have, data)
+	}
+	if v != nil {
+		if err := json.Unmarshal(data, v); err != nil {
+			t.Fatalf("%s %s: %v: %s", method, url, err, data)
+		}
+	}
+}
+
+func TestServer(t *testing.T) {
+	ts, dir := newTestServer(t)
+
+	var info server.CatalogInfo
+	do(t, "PUT", ts.URL+"/catalogs/acme", catalogDoc, http.StatusCreated, &info)
+	if want, have := "CAT1", info.CatalogID; want != have {
+		t.Fatalf("want CatalogID=%q, have %q", want, have)
+	}
+	if want, have := 2, info.Articles; want != have {
+		t.Fatalf("want Articles=%d, have %d", want, have)
+	}
+	if want, have := "Acme", info.Supplier; want != have {
+		t.Fatalf("want Supplier=%q, have %q", want, have)
+	}
+
+	var infos []*server.CatalogInfo
+	do(t, "GET", ts.URL+"/catalogs", "", http.StatusOK, &infos)
+	if want, have := 1, len(infos); want != have {
+		t.Fatalf("want %d catalogs, have %d", want, have)
+	}
+	do(t, "GET", ts.URL+"/catalogs/acme", "", http.StatusOK, &info)
+	if want, have := "T_NEW_CATALOG", info.Transaction; want != have {
+		t.Fatalf("want Transaction=%q, have %q", want, have)
+	}
+
+	var article struct {
+		SupplierAID string
+		Details     struct{ DescriptionShort string }
+	}
+	do(t, "GET", ts.URL+"/catalogs/acme/articles/1000", "", http.StatusOK, &article)
+	if want, have := "Drill", article.Details.DescriptionShort; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	do(t, "GET", ts.URL+"/catalogs/acme/articles/A%2F2000", "", http.StatusOK, &article)
+	if want, have := "A/2000", article.SupplierAID; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	do(t, "GET", ts.URL+"/catalogs/acme/articles?ean=4000000000001", "", http.StatusOK, &article)
+	if want, have := "1000", article.SupplierAID; want != have {
+		t.Fatalf("want %q, have %q", want, have)
+	}
+	do(t, "GET", ts.URL+"/catalogs/acme/articles/9999", "", http.StatusNotFound, nil)
+	do(t, "GET", ts.URL+"/catalogs/acme/articles?ean=0", "", http.StatusNotFound, nil)
+	do(t, "GET", ts.URL+"/catalogs/acme/articles", "", http.StatusBadRequest, nil)
+
+	var groups []*server.GroupNode
+	do(t, "GET", ts.URL+"/catalogs/acme/groups", "", http.StatusOK, &groups)
+	if want, have := 1, len(groups); want != have {
+		t.Fatalf("want %d root groups, have %d", want, have)
+	}
+	if want, have := 2, len(groups[0].Children); want != have {
+		t.Fatalf("want %d children, have %d", want, have)
+	}
+	if want, have := "Saws", groups[0].Children[0].Name; want != have {
+		t.Fatalf("want first child %q, have %q", want, have)
+	}
+
+	var report server.ValidationReport
+	do(t, "GET", ts.URL+"/catalogs/acme/validation", "", http.StatusOK, &report)
+	if want, have := info.Valid, report.Valid; want != have {
+		t.Fatalf("want Valid=%v, have %v", want, have)
+	}
+
+	// The catalogs survive a restart
+	cache, err := server.OpenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want, have := []string{"acme"}, cache.Names(); len(have) != 1 || want[0] != have[0] {
+		t.Fatalf("want %v, have %v", want, have)
+	}
+	cache.Close()
+
+	do(t, "DELETE", ts.URL+"/catalogs/acme", "", http.StatusNoContent, nil)
+	do(t, "GET", ts.URL+"/catalogs/acme", "", http.StatusNotFound, nil)
+	do(t, "DELETE", ts.URL+"/catalogs/acme", "", http.StatusNotFound, nil)
+}
+
+func TestServerErrors(t *testing.T) {
+	ts, _ := newTestServer(t)
+
+	do(t, "PUT", ts.URL+"/catalogs/..", catalogDoc, http.StatusBadRequest, nil)
+	do(t, "PUT", ts.URL+"/catalogs/a%20b", catalogDoc, http.StatusBadRequest, nil)
+	do(t, "PUT", ts.URL+"/catalogs/broken", "<BMECAT><HEADER>", http.StatusUnprocessableEntity, nil)
+	do(t, "GET", ts.URL+"/catalogs/broken", "", http.StatusNotFound, nil)
+
+	// Duplicate GROUP_IDs that form a cycle: R, A->R, B->A, A->B
+	cyclic := strings.Replace(catalogDoc, `<CATALOG_STRUCTURE type="leaf"><GROUP_ID>3</GROUP_ID>`,
+		`<CATALOG_STRUCTURE type="node"><GROUP_ID>A</GROUP_ID><GROUP_NAME>A</GROUP_NAME><PARENT_ID>1</PARENT_ID></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="node"><GROUP_ID>B</GROUP_ID><GROUP_NAME>B</GROUP_NAME><PARENT_ID>A</PARENT_ID></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="node"><GROUP_ID>A</GROUP_ID><GROUP_NAME>A</GROUP_NAME><PARENT_ID>B</PARENT_ID></CATALOG_STRUCTURE>
+      <CATALOG_STRUCTURE type="leaf"><GROUP_ID>3</GROUP_ID>`, 1)
+	do(t, "PUT", ts.URL+"/catalogs/cyclic", cyclic, http.StatusUnprocessableEntity, nil)
+	do(t, "GET", ts.URL+"/catalogs/cyclic", "", http.StatusNotFound, nil)
+	do(t, "POST", ts.URL+"/catalogs", "", http.StatusMethodNotAllowed, nil)
+	do(t, "GET", ts.URL+"/other", "", http.StatusNotFound, nil)
+}
+
+func TestServerPutErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := server.OpenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	ts := httptest.NewServer(server.New(cache, server.WithMaxUpload(100)))
+	defer ts.Close()
+	do(t, "PUT", ts.URL+"/catalogs/large", catalogDoc, http.StatusRequestEntityTooLarge, nil)
+
+	// Errors of the cache are not errors of the catalog
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	do(t, "PUT", ts.URL+"/catalogs/small", "<BMECAT/>", http.StatusInternalServerError, nil)
+
+	ro := httptest.NewServer(server.New(cache, server.WithReadOnly()))
+	defer ro.Close()
+	do(t, "PUT", ro.URL+"/catalogs/cat1", catalogDoc, http.StatusMethodNotAllowed, nil)
+	do(t, "DELETE", ro.URL+"/catalogs/cat1", "", http.StatusMethodNotAllowed, nil)
+	do(t, "GET", ro.URL+"/catalogs/cat1", "", http.StatusNotFound, nil)
+}
+
+func TestCachePutKeepsCatalogOnError(t *testing.T) {
+	dir, err := ioutil.TempDir("", "bmecat-server")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cache, err := server.OpenCache(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer cache.Close()
+
+	ctx := context.Background()
+	if _, err := cache.Put(ctx, "cat1", strings.NewReader(catalogDoc)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A gzip-compressed catalog can be read, but not indexed
+	var buf bytes.Buffer
+	zw := gzip.NewWriter(&buf)
+	zw.Write([]byte(catalogDoc))
+	zw.Close()
+	if _, err := cache.Put(ctx, "cat1", &buf); err == nil {
+		t.Fatal("want error for gzip-compressed catalog, have nil")
+	}
+
+	err = cache.View("cat1", func(cat *server.Catalog) error {
+		a, err := cat.Article("1000")
+		if err != nil {
+			return err
+		}
+		if want, have := "Drill", a.Details.DescriptionShort; want != have {
+			t.Fatalf("want DESCRIPTION_SHORT %q, have %q", want, have)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, err := filepath.Glob(filepath.Join(dir, "*.upload*"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) > 0 {
+		t.Fatalf("want temporary files to be removed, have %v", files)
+	}
+}